@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/rag"
+	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/util"
+	"github.com/spf13/cobra"
+)
+
+var askCmd = &cobra.Command{
+	Use:   "ask [question]",
+	Short: "Answer a question from the index with an LLM.",
+	Long: `Searches the index for the question and has the LLM configured in the llm section write an
+answer from the best chunks, citing them as [1], [2]... The sources are listed after the answer.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if AppConfig == nil {
+			cfgErr := util.NewError("Configuration not loaded before ask command")
+			util.LogError(util.Logger, cfgErr)
+			return cfgErr
+		}
+		question := strings.Join(args, " ")
+		topK, _ := cmd.Flags().GetInt("top-k")
+		mode, _ := cmd.Flags().GetString("mode")
+		filterFlag, _ := cmd.Flags().GetString("filter")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		expand, _ := cmd.Flags().GetString("expand")
+		variants, _ := cmd.Flags().GetStringArray("variant")
+		adaptive, _ := cmd.Flags().GetBool("adaptive")
+
+		mode, _ = AppConfig.Search.ForMode(mode)
+		if !search.ValidMode(mode) {
+			return util.NewError(fmt.Sprintf("Invalid --mode %q. Supported modes: hybrid, lexical, vector", mode))
+		}
+		if topK < 0 || topK > 100 {
+			return util.NewError("--top-k must be between 1 and 100")
+		}
+		if !search.ValidExpand(expand) {
+			return util.NewError(fmt.Sprintf("Invalid --expand %q. Supported values: none, terms, hyde, multi", expand))
+		}
+		if len(variants) > search.MaxQueryVariants {
+			return util.NewError(fmt.Sprintf("At most %d --variant flags are allowed", search.MaxQueryVariants))
+		}
+		filter, err := search.ParseFilter(filterFlag)
+		if err != nil {
+			return util.WrapError(err, "Invalid --filter", slog.String("filter", filterFlag))
+		}
+
+		model, err := rag.NewChatModel(AppConfig.LLM)
+		if err != nil {
+			return util.WrapError(err, "LLM is not configured")
+		}
+		searcher, err := search.NewSearcher(AppConfig)
+		if err != nil {
+			wrappedErr := util.WrapError(err, "Failed to initialize searcher")
+			util.LogError(util.Logger, wrappedErr)
+			return wrappedErr
+		}
+
+		searcher.WithRewriter(rag.NewRewriter(model))
+
+		opts := search.Options{Filter: filter, Mode: mode, Expand: expand, Queries: variants}
+		if adaptive {
+			opts.Adaptive = search.AdaptiveDrop(AppConfig.Search)
+		}
+		answer, err := rag.NewAnswerer(AppConfig.LLM, searcher, model).
+			Answer(context.Background(), question, topK, opts)
+		if err != nil {
+			return util.WrapError(err, "Failed to answer the question", slog.String("question", question))
+		}
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(answer)
+		}
+		return printAnswer(os.Stdout, answer)
+	},
+}
+
+// printAnswer writes the answer followed by its sources, cited ones marked
+// with an asterisk.
+func printAnswer(w io.Writer, a *rag.Answer) error {
+	if _, err := fmt.Fprintf(w, "%s\n", a.Answer); err != nil {
+		return err
+	}
+	if len(a.Sources) == 0 {
+		return nil
+	}
+	cited := make(map[int]bool, len(a.Citations))
+	for _, n := range a.Citations {
+		cited[n] = true
+	}
+	fmt.Fprintln(w, "\nSources:")
+	for _, src := range a.Sources {
+		mark := " "
+		if cited[src.N] {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "%s[%d] %s\n", mark, src.N, src.Path)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// bulkBatchSize is the embedding batch size used by index --bulk: four
+// times the configured one, at most 512 but never less than configured.
+func bulkBatchSize(configured int) int {
+	n := configured * 4
+	if n > 512 {
+		n = 512
+	}
+	if n < configured {
+		n = configured
+	}
+	return n
+}
+
+// finishBulkIndex saves the indexes built by index --bulk and reports what
+// was written.
+func finishBulkIndex(mgr *pipeline.Manager, start time.Time) error {
+	stats, err := mgr.FinishBulk(context.Background())
+	slog.Info("Bulk indexes saved", "files", stats.Files, "chunks", stats.Chunks,
+		"elapsed", time.Since(start).Round(time.Second).String())
+	if err != nil {
+		return util.WrapError(err, "Failed to save the bulk-built indexes")
+	}
+	return nil
+}
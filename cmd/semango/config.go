@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/util"
+	"github.com/spf13/cobra"
+)
+
+// exitConfig is the exit status for an invalid configuration (EX_CONFIG).
+const exitConfig = 78
+
+// loadConfig loads the configuration named by the --config, --schema and
+// --set flags of cmd, and returns it with its path.
+func loadConfig(cmd *cobra.Command) (*config.Config, string, error) {
+	configPath, _ := cmd.Flags().GetString("config")
+	schemaPath, _ = cmd.Flags().GetString("schema")
+	configOverrides, _ = cmd.Flags().GetStringArray("set")
+	slog.Debug("Loading configuration", "path", configPath, "schema", schemaPath)
+	cfg, err := config.Load(configPath, schemaPath, configOverrides...)
+	return cfg, configPath, err
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and validate the configuration.",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration.",
+	Long: `Prints the configuration in effect, as YAML: semango.yml with the SEMANGO_* environment variables
+and --set flags applied. Passwords in URLs and settings named like secrets are shown as REDACTED;
+settings such as server.auth.token_env name a variable and are shown as they are.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if AppConfig == nil {
+			cfgErr := util.NewError("Configuration not loaded before config show command")
+			util.LogError(util.Logger, cfgErr)
+			return cfgErr
+		}
+		out, err := AppConfig.RedactedYAML()
+		if err != nil {
+			return util.WrapError(err, "Failed to encode configuration")
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration against the schema.",
+	Long: `Loads the configuration, with the SEMANGO_* environment variables and --set flags applied, and
+validates it against the CUE schema without indexing or serving anything. Exits 0 when it is valid
+and 78 when it is not.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, configPath, err := loadConfig(cmd)
+		if err != nil {
+			var unknownFieldErr *config.ErrUnknownField
+			if errors.As(err, &unknownFieldErr) {
+				fmt.Fprintf(os.Stderr, "%s: unknown field: %v\n", configPath, unknownFieldErr.Err)
+			} else {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", configPath, err)
+			}
+			os.Exit(exitConfig)
+		}
+		fmt.Printf("%s is valid\n", configPath)
+		return nil
+	},
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/omarkamali/semango/internal/doctor"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that this machine can run the configuration.",
+	Long: `Loads the configuration and checks the environment it needs: the API keys of the configured
+providers, the ONNX runtime for local models, FAISS support in this binary, and that the index
+directories and the model cache can be written. Exits 78 if the configuration is invalid and 1 if a
+check fails; warnings do not change the exit status.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		cfg, configPath, err := loadConfig(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "configuration %s: %v\n", configPath, err)
+			os.Exit(exitConfig)
+		}
+
+		checks := doctor.Run(cfg)
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(checks); err != nil {
+				return err
+			}
+		} else {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(tw, "%s\tconfiguration %s\tvalid\n", "OK", configPath)
+			for _, c := range checks {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", strings.ToUpper(string(c.Status)), c.Name, c.Detail)
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+		}
+		if doctor.Failed(checks) {
+			os.Exit(1)
+		}
+		return nil
+	},
+}
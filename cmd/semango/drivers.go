@@ -0,0 +1,5 @@
+package main
+
+// The PostgreSQL driver for vector_store.backend: pgvector, registered with
+// database/sql as "pgx", the default vector_store.driver.
+import _ "github.com/jackc/pgx/v5/stdlib"
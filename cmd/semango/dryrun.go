@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/ingest/source"
+	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// Reasons index --dry-run gives for skipped files besides those of
+// pipeline.FilePlan.
+const (
+	skipExcluded    = "excluded"
+	skipNotIncluded = "not included"
+	skipFailed      = "failed to load"
+)
+
+// skippedExamples is how many paths are listed per reason of skipped files.
+const skippedExamples = 5
+
+// indexPlan is the report of index --dry-run.
+type indexPlan struct {
+	Files   int                    `json:"files"`
+	Bytes   int64                  `json:"bytes"`
+	Chunks  int                    `json:"chunks"`
+	Tokens  int                    `json:"tokens"`
+	Loaders map[string]*loaderPlan `json:"loaders"`
+	Costs   []embeddingCost        `json:"costs"`
+	Skipped skipList               `json:"skipped,omitempty"`
+}
+
+// loaderPlan is what the files of one loader would add.
+type loaderPlan struct {
+	Files  int   `json:"files"`
+	Bytes  int64 `json:"bytes"`
+	Chunks int   `json:"chunks"`
+	Tokens int   `json:"tokens"`
+}
+
+// embeddingCost is the estimated cost of embedding the planned chunks
+// with one of the configured models.
+type embeddingCost struct {
+	Space    string   `json:"space,omitempty"`
+	Provider string   `json:"provider"`
+	Model    string   `json:"model"`
+	USD      *float64 `json:"usd"` // nil when the price is not known
+}
+
+// skipped counts the files left out for one reason.
+type skipped struct {
+	Count    int      `json:"count"`
+	Examples []string `json:"examples"`
+}
+
+// skipList counts skipped files by reason.
+type skipList map[string]*skipped
+
+func (l skipList) add(reason, path string) {
+	s := l[reason]
+	if s == nil {
+		s = &skipped{}
+		l[reason] = s
+	}
+	s.Count++
+	if len(s.Examples) < skippedExamples {
+		s.Examples = append(s.Examples, path)
+	}
+}
+
+// print prints the counts by reason, each with its examples.
+func (l skipList) print(w io.Writer) {
+	reasons := make([]string, 0, len(l))
+	for r := range l {
+		reasons = append(reasons, r)
+	}
+	sort.Strings(reasons)
+	for _, r := range reasons {
+		s := l[r]
+		fmt.Fprintf(w, "  %s: %d\n", r, s.Count)
+		for _, p := range s.Examples {
+			fmt.Fprintf(w, "    %s\n", p)
+		}
+		if more := s.Count - len(s.Examples); more > 0 {
+			fmt.Fprintf(w, "    and %d more\n", more)
+		}
+	}
+}
+
+// planIndex crawls sources and works out what indexing them with cfg would
+// add, without embedding or writing anything.
+func planIndex(ctx context.Context, cfg *config.Config, sources []source.Source) (*indexPlan, error) {
+	planner := pipeline.NewPlanner(cfg)
+	if err := planner.Err(); err != nil {
+		return nil, err
+	}
+	plan := &indexPlan{Loaders: map[string]*loaderPlan{}, Skipped: skipList{}}
+	for _, src := range sources {
+		if local, ok := src.(*source.Local); ok {
+			err := local.Skipped(func(path, pattern string) {
+				if pattern == "" {
+					plan.Skipped.add(skipNotIncluded, path)
+				} else {
+					plan.Skipped.add(skipExcluded, path+" ("+pattern+")")
+				}
+			})
+			if err != nil {
+				return nil, util.WrapError(err, "Failed to list excluded files")
+			}
+		}
+		err := src.Walk(ctx, func(relPath, absPath string) error {
+			fp, err := planner.PlanFile(ctx, relPath, absPath)
+			if err != nil {
+				plan.Skipped.add(skipFailed, relPath+": "+err.Error())
+				return nil
+			}
+			if fp.Skipped != "" {
+				plan.Skipped.add(fp.Skipped, relPath)
+				return nil
+			}
+			var size int64
+			if fi, err := os.Stat(absPath); err == nil {
+				size = fi.Size()
+			}
+			lp := plan.Loaders[fp.Loader]
+			if lp == nil {
+				lp = &loaderPlan{}
+				plan.Loaders[fp.Loader] = lp
+			}
+			lp.Files++
+			lp.Bytes += size
+			lp.Chunks += fp.Chunks
+			lp.Tokens += fp.Tokens
+			plan.Files++
+			plan.Bytes += size
+			plan.Chunks += fp.Chunks
+			plan.Tokens += fp.Tokens
+			return nil
+		})
+		if err != nil {
+			return nil, util.WrapError(err, "Failed to crawl source", slog.String("source", src.Name()))
+		}
+	}
+
+	plan.Costs = append(plan.Costs, embeddingCostOf("", cfg.Embedding, plan.Tokens))
+	names := make([]string, 0, len(cfg.Embedding.Spaces))
+	for name := range cfg.Embedding.Spaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ec, _ := cfg.Embedding.ForSpace(name)
+		plan.Costs = append(plan.Costs, embeddingCostOf(name, ec, plan.Tokens))
+	}
+	return plan, nil
+}
+
+// embeddingCostOf estimates the cost of embedding tokens with ec. Local
+// models cost nothing.
+func embeddingCostOf(space string, ec config.EmbeddingConfig, tokens int) embeddingCost {
+	c := embeddingCost{Space: space, Provider: ec.Provider, Model: ec.Model}
+	if c.Provider == "" {
+		c.Provider = "openai"
+	}
+	if c.Provider == "openai" && c.Model == "" {
+		c.Model = "text-embedding-3-large"
+	}
+	if c.Provider == "local" {
+		c.Model = ec.LocalModelPath
+		zero := 0.0
+		c.USD = &zero
+	} else if price, ok := ingest.OpenAIPrice(ec.Model); ok {
+		usd := float64(tokens) / 1e6 * price
+		c.USD = &usd
+	}
+	return c
+}
+
+// printIndexPlan prints plan as JSON or as a table per loader followed by
+// the costs and the skipped files.
+func printIndexPlan(plan *indexPlan, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "LOADER\tFILES\tSIZE\tCHUNKS\tTOKENS (EST.)\n")
+	loaders := make([]string, 0, len(plan.Loaders))
+	for name := range plan.Loaders {
+		loaders = append(loaders, name)
+	}
+	sort.Slice(loaders, func(i, j int) bool {
+		a, b := plan.Loaders[loaders[i]], plan.Loaders[loaders[j]]
+		if a.Files != b.Files {
+			return a.Files > b.Files
+		}
+		return loaders[i] < loaders[j]
+	})
+	for _, name := range loaders {
+		lp := plan.Loaders[name]
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%d\n", name, lp.Files, formatByteSize(lp.Bytes), lp.Chunks, lp.Tokens)
+	}
+	fmt.Fprintf(tw, "total\t%d\t%s\t%d\t%d\n", plan.Files, formatByteSize(plan.Bytes), plan.Chunks, plan.Tokens)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println("\nEstimated embedding cost:")
+	for _, c := range plan.Costs {
+		model := c.Provider + "/" + c.Model
+		if c.Space != "" {
+			model = "space " + c.Space + ": " + model
+		}
+		switch {
+		case c.USD == nil:
+			fmt.Printf("  %s: price not known\n", model)
+		case c.Provider == "local":
+			fmt.Printf("  %s: none (local model)\n", model)
+		default:
+			fmt.Printf("  %s: $%.2f\n", model, *c.USD)
+		}
+	}
+
+	if len(plan.Skipped) == 0 {
+		fmt.Println("\nNo files skipped.")
+		return nil
+	}
+	fmt.Println("\nSkipped:")
+	plan.Skipped.print(os.Stdout)
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+// errorReportFile is the name of the report of the last index run in the
+// index directory.
+const errorReportFile = "index-errors.json"
+
+// failedExamples is how many failed files the summary of an index run
+// lists; the report file has them all.
+const failedExamples = 20
+
+// indexIssues collects the files an index run failed to index or left out,
+// for the report written when it ends.
+type indexIssues struct {
+	Failed  []fileIssue `json:"failed"`
+	Skipped []fileIssue `json:"skipped"`
+}
+
+// fileIssue is a file in the report of an index run.
+type fileIssue struct {
+	Path   string `json:"path"`
+	Stage  string `json:"stage,omitempty"`  // of failed files, e.g. pipeline.StageEmbed
+	Reason string `json:"reason,omitempty"` // of skipped files, e.g. pipeline.SkipTooLarge
+	Error  string `json:"error,omitempty"`
+}
+
+// errorReportPath returns where index runs into the indexes whose lexical
+// index lives at lexicalIndexPath keep their report.
+func errorReportPath(lexicalIndexPath string) string {
+	return filepath.Join(storage.IndexDir(lexicalIndexPath), errorReportFile)
+}
+
+// fail records err of indexing relPath. A pipeline.FileError names the
+// files that failed with it, which in bulk mode include files indexed
+// before relPath; other errors are recorded for relPath unless it is "".
+func (r *indexIssues) fail(relPath string, err error) {
+	stage, paths := "", []string{relPath}
+	var fe *pipeline.FileError
+	if errors.As(err, &fe) {
+		stage, paths = fe.Stage, fe.Paths
+	} else if relPath == "" {
+		return
+	}
+	for _, p := range paths {
+		r.Failed = append(r.Failed, fileIssue{Path: p, Stage: stage, Error: err.Error()})
+	}
+}
+
+// skip records relPath as left out for reason; see pipeline.Manager.OnSkip.
+func (r *indexIssues) skip(relPath, reason string) {
+	r.Skipped = append(r.Skipped, fileIssue{Path: relPath, Reason: reason})
+}
+
+// write saves the report as JSON at path, replacing that of the last run.
+func (r *indexIssues) write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// print prints a summary of the report to w: the failed files with their
+// stage and error, then the skipped files by reason. It prints nothing for
+// a run without either.
+func (r *indexIssues) print(w io.Writer, path string) error {
+	if len(r.Failed) == 0 && len(r.Skipped) == 0 {
+		return nil
+	}
+	if len(r.Failed) > 0 {
+		fmt.Fprintf(w, "\nFailed to index %d files (all of them in %s):\n", len(r.Failed), path)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "  STAGE\tPATH\tERROR\n")
+		for i, f := range r.Failed {
+			if i == failedExamples {
+				fmt.Fprintf(tw, "  \tand %d more\t\n", len(r.Failed)-i)
+				break
+			}
+			stage := f.Stage
+			if stage == "" {
+				stage = "-"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", stage, f.Path, f.Error)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(r.Skipped) > 0 {
+		fmt.Fprintf(w, "\nSkipped %d files:\n", len(r.Skipped))
+		l := skipList{}
+		for _, s := range r.Skipped {
+			l.add(s.Reason, s.Path)
+		}
+		l.print(w)
+	}
+	return nil
+}
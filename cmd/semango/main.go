@@ -5,18 +5,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"syscall"
+	"text/tabwriter"
 
-	"github.com/blevesearch/go-faiss"
 	"github.com/omarkamali/semango/internal/api"
 	"github.com/omarkamali/semango/internal/config"
 	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/ingest/source"
 	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/rag"
 	"github.com/omarkamali/semango/internal/search"
 	"github.com/omarkamali/semango/internal/storage"
 	"github.com/omarkamali/semango/internal/util"
@@ -32,6 +36,14 @@ var (
 
 var AppConfig *config.Config // Global config instance
 
+// schemaPath is the --schema flag: a CUE schema file that replaces the one
+// built into the binary, empty for the built-in one.
+var schemaPath string
+
+// configOverrides are the --set flags, key=value settings that take
+// precedence over the configuration file and the environment.
+var configOverrides []string
+
 var rootCmd = &cobra.Command{
 	Use:   "semango",
 	Short: "Semango is a semantic search engine.",
@@ -42,22 +54,35 @@ var rootCmd = &cobra.Command{
 			slog.Debug("Skipping configuration loading for init command or its subcommands")
 			return nil
 		}
+		if cmd.Name() == "suggest-config" { // may create the configuration file
+			slog.Debug("Skipping configuration loading for suggest-config command")
+			return nil
+		}
+		if cmd.Name() == "quickstart" { // writes and loads its own configuration
+			slog.Debug("Skipping configuration loading for quickstart command")
+			return nil
+		}
+		if cmd == configValidateCmd || cmd == doctorCmd { // report configuration errors themselves
+			slog.Debug("Skipping configuration loading", "command", cmd.Name())
+			return nil
+		}
 
-		configPath, _ := cmd.Flags().GetString("config")
-		slog.Debug("Loading configuration", "path", configPath)
-		loadedCfg, err := config.Load(configPath, config.DefaultCueSchemaPath)
+		loadedCfg, configPath, err := loadConfig(cmd)
 		if err != nil {
 			wrappedErr := util.WrapError(err, "Failed to load configuration", slog.String("config_path", configPath))
 			var unknownFieldErr *config.ErrUnknownField
 			if errors.As(err, &unknownFieldErr) {
 				util.LogError(util.Logger, util.WrapError(wrappedErr, "Configuration contains unknown fields. Exit 78."))
-				os.Exit(78)
+				os.Exit(exitConfig)
 			} else {
 				util.LogError(util.Logger, wrappedErr)
 				os.Exit(1)
 			}
 		}
 		AppConfig = loadedCfg // Store loaded config globally
+		if err := util.SetLogLevel(loadedCfg.LogLevel); err != nil {
+			return util.WrapError(err, "Invalid log_level")
+		}
 		slog.Info("Configuration loaded and validated successfully")
 		return nil
 	},
@@ -69,9 +94,16 @@ var rootCmd = &cobra.Command{
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize a new Semango configuration file.",
-	Long:  `Creates a new semango.yml configuration file in the current directory with default values.`,
+	Long: `Creates a new semango.yml configuration file in the current directory with default values.
+
+With --interactive it asks for the embedding provider and model, the kinds of files to index and
+the server port instead, offering the local models already in the model cache, noting an
+OPENAI_API_KEY in the environment and listing the kinds of files found in the current directory.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		configPath, _ := cmd.Flags().GetString("file")
+		if interactive, _ := cmd.Flags().GetBool("interactive"); interactive {
+			return runInitWizard(configPath)
+		}
 		if err := config.WriteDefaultConfig(configPath); err != nil {
 			wrappedErr := util.WrapError(err, "Failed to write default config", slog.String("path", configPath))
 			util.LogError(util.Logger, wrappedErr)
@@ -93,50 +125,65 @@ var serverCmd = &cobra.Command{
 			return cfgErr
 		}
 
-		slog.Info("Starting Semango server...", "host", AppConfig.Server.Host, "port", AppConfig.Server.Port)
-
-		// Initialize searcher with real search capabilities
-		searcher, err := search.NewSearcher(AppConfig)
-		if err != nil {
-			wrappedErr := util.WrapError(err, "Failed to initialize searcher")
-			util.LogError(util.Logger, wrappedErr)
-			return wrappedErr
-		}
-
-		// Create API server with nil UI filesystem (will use fallback)
-		server := api.NewServer(AppConfig, searcher, nil)
-
-		// Create context for graceful shutdown
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-
-		// Handle shutdown signals
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		configPath, _ := cmd.Flags().GetString("config")
+		force, _ := cmd.Flags().GetBool("force")
+		return runServer(AppConfig, configPath, force)
+	},
+}
 
-		go func() {
-			<-sigChan
-			slog.Info("Received shutdown signal, stopping server...")
-			cancel()
-		}()
+// runServer serves cfg's indexes until SIGINT or SIGTERM. On SIGHUP, and on
+// changes with server.watch_config, it reloads the configuration file at
+// configPath. With force, vector indexes built with another embedding model
+// are searched and written with a warning instead of refused.
+func runServer(cfg *config.Config, configPath string, force bool) error {
+	slog.Info("Starting Semango server...", "host", cfg.Server.Host, "port", cfg.Server.Port)
+
+	// Initialize searcher with real search capabilities
+	searcher, err := search.NewSearcher(cfg)
+	if err != nil {
+		wrappedErr := util.WrapError(err, "Failed to initialize searcher")
+		util.LogError(util.Logger, wrappedErr)
+		return wrappedErr
+	}
+	if force {
+		searcher.AllowModelMismatch()
+	}
 
-		// Start server
-		if err := server.Start(ctx); err != nil {
-			wrappedErr := util.WrapError(err, "Server failed to start")
-			util.LogError(util.Logger, wrappedErr)
-			return wrappedErr
-		}
+	// Create API server with nil UI filesystem (will use fallback)
+	api.Version = version
+	server := api.NewServer(cfg, searcher, nil)
+
+	// Create context for graceful shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Handle shutdown signals
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		<-sigChan
+		slog.Info("Received shutdown signal, stopping server...")
+		cancel()
+	}()
+	go reloadOnChange(ctx, server, configPath, cfg.Server.WatchConfig)
+
+	// Start server
+	if err := server.Start(ctx); err != nil {
+		wrappedErr := util.WrapError(err, "Server failed to start")
+		util.LogError(util.Logger, wrappedErr)
+		return wrappedErr
+	}
 
-		slog.Info("Server stopped gracefully")
-		return nil
-	},
+	slog.Info("Server stopped gracefully")
+	return nil
 }
 
 var indexCmd = &cobra.Command{
 	Use:   "index",
 	Short: "Index files based on the configuration.",
-	Long:  `Crawls the filesystem according to the include/exclude patterns in semango.yml and processes files for indexing.`,
-	RunE: func(cmd *cobra.Command, args []string) error {
+	Long:  `Crawls the configured sources (by default the working directory, filtered by the include/exclude patterns in semango.yml) and processes files for indexing.`,
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		if AppConfig == nil {
 			// This is a programming error or an issue with command setup, should not happen if PersistentPreRunE works.
 			// Using NewError as there's no underlying specific Go error to wrap here.
@@ -144,7 +191,12 @@ var indexCmd = &cobra.Command{
 			util.LogError(util.Logger, cfgErr)
 			return cfgErr
 		}
-		slog.Info("Starting indexing process...", "files_config", AppConfig.Files)
+		collection, _ := cmd.Flags().GetString("collection")
+		cfg, err := collectionConfig(collection)
+		if err != nil {
+			return err
+		}
+		slog.Info("Starting indexing process...", "files_config", cfg.Files, "collection", collection)
 
 		rootDir, err := os.Getwd()
 		if err != nil {
@@ -153,13 +205,54 @@ var indexCmd = &cobra.Command{
 			return wrappedErr
 		}
 
-		filePathChan := make(chan string, 100)
-		errChan := make(chan error, 1)
+		since, _ := cmd.Flags().GetString("since")
+		if since != "" && len(cfg.Sources) > 0 {
+			return util.NewError("--since only works when indexing the working directory, not with a sources section")
+		}
+		recreate, _ := cmd.Flags().GetBool("recreate")
+		if recreate && since != "" {
+			return util.NewError("--recreate rebuilds the vector index from every file and cannot be combined with --since")
+		}
+		rebuild, _ := cmd.Flags().GetBool("rebuild")
+		if rebuild && (since != "" || recreate) {
+			return util.NewError("--rebuild builds new indexes from every file and cannot be combined with --since or --recreate")
+		}
+		if rebuild && storage.IsRemoteVectorStore(cfg.VectorStore) {
+			return util.NewError(fmt.Sprintf("--rebuild swaps index directories on disk; vectors in the %s vector store would be overwritten in place", cfg.VectorStore.Backend))
+		}
+		bulk, _ := cmd.Flags().GetBool("bulk")
+		if bulk && since != "" {
+			return util.NewError("--bulk is for building an index from scratch and cannot be combined with --since")
+		}
+		batchSize := cfg.Embedding.BatchSize
+		if bulk {
+			batchSize = bulkBatchSize(batchSize)
+		}
+
+		sources, err := source.FromConfig(cfg)
+		if err != nil {
+			wrappedErr := util.WrapError(err, "Invalid sources configuration")
+			util.LogError(util.Logger, wrappedErr)
+			return wrappedErr
+		}
+
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+			if since != "" {
+				return util.NewError("--dry-run plans indexing every file and cannot be combined with --since")
+			}
+			plan, err := planIndex(context.Background(), cfg, sources)
+			if err != nil {
+				util.LogError(util.Logger, err)
+				return err
+			}
+			asJSON, _ := cmd.Flags().GetBool("json")
+			return printIndexPlan(plan, asJSON)
+		}
 
 		// Initialize embedder with proper validation
 		var embedder ingest.Embedder
 		{
-			prov := AppConfig.Embedding.Provider
+			prov := cfg.Embedding.Provider
 			switch prov {
 			case "openai", "": // default to openai
 				apiKey := os.Getenv("OPENAI_API_KEY")
@@ -168,9 +261,10 @@ var indexCmd = &cobra.Command{
 				}
 				openCfg := ingest.OpenAIConfig{
 					APIKey:     apiKey,
-					Model:      AppConfig.Embedding.Model,
-					BatchSize:  AppConfig.Embedding.BatchSize,
-					Concurrent: AppConfig.Embedding.Concurrent,
+					Model:      cfg.Embedding.Model,
+					BatchSize:  batchSize,
+					Concurrent: cfg.Embedding.Concurrent,
+					Dimensions: cfg.Embedding.Dimensions,
 				}
 				e, err := ingest.NewOpenAIEmbedder(openCfg)
 				if err != nil {
@@ -178,14 +272,19 @@ var indexCmd = &cobra.Command{
 				}
 				embedder = e
 			case "local":
-				if AppConfig.Embedding.LocalModelPath == "" {
+				if cfg.Embedding.Dimensions > 0 {
+					return util.NewError("embedding.dimensions is only supported by the openai provider's text-embedding-3 models")
+				}
+				if cfg.Embedding.LocalModelPath == "" {
 					return util.NewError("Local model path is required for local embedder provider")
 				}
 				localCfg := ingest.LocalEmbedderConfig{
-					ModelPath: AppConfig.Embedding.LocalModelPath,
-					CacheDir:  AppConfig.Embedding.ModelCacheDir,
-					BatchSize: AppConfig.Embedding.BatchSize,
+					ModelPath: cfg.Embedding.LocalModelPath,
+					CacheDir:  cfg.Embedding.ModelCacheDir,
+					BatchSize: batchSize,
 					MaxLength: 512, // Default max length
+					Sessions:  cfg.Embedding.Concurrent,
+					Threads:   cfg.Embedding.LocalThreads,
 				}
 				// Validate configuration
 				if err := ingest.ValidateModelConfig(localCfg); err != nil {
@@ -201,39 +300,199 @@ var indexCmd = &cobra.Command{
 			}
 		}
 
-		mgr := pipeline.NewManager(AppConfig, embedder)
+		spaces, err := ingest.NewSpaceEmbedders(cfg.Embedding)
+		if err != nil {
+			util.LogError(util.Logger, err)
+			return err
+		}
+		// A server on the indexes reads the progress from the served
+		// directory, also during a rebuild.
+		progressPath := pipeline.ProgressPath(cfg.Lexical.IndexPath)
+		reportPath, _ := cmd.Flags().GetString("error-report")
+		if reportPath == "" {
+			reportPath = errorReportPath(cfg.Lexical.IndexPath)
+		}
+		failOnError, _ := cmd.Flags().GetBool("fail-on-error")
+		var rebuilt *storage.IndexRebuild
+		if rebuild {
+			rebuilt, err = storage.StartRebuild(cfg.Lexical.IndexPath, cfg.VectorIndexPath())
+			if err != nil {
+				return util.WrapError(err, "Failed to start rebuilding the indexes")
+			}
+			// The served indexes stay untouched until Swap.
+			next := *cfg
+			next.Lexical.IndexPath = rebuilt.Path(cfg.Lexical.IndexPath)
+			next.Vector.IndexPath = rebuilt.Path(cfg.VectorIndexPath())
+			cfg = &next
+			// Until the swap, a failed run leaves nothing behind.
+			defer func() {
+				if rebuilt != nil {
+					rebuilt.Abort()
+				}
+			}()
+			slog.Info("Rebuilding the indexes next to the served ones", "dir", rebuilt.Dir, "generation_dir", rebuilt.Staging)
+		}
+
+		issues := &indexIssues{}
+		mgr := pipeline.NewManager(cfg, embedder).WithSpaces(spaces).OnSkip(issues.skip)
+		if force, _ := cmd.Flags().GetBool("force"); force {
+			mgr.AllowModelMismatch()
+		}
+		if collection != "" {
+			// Files past the collection's quota fail like unreadable ones.
+			mgr.WithQuota(collection, AppConfig.Collections[collection].Quota)
+		}
+		if err := mgr.Err(); err != nil {
+			util.LogError(util.Logger, err)
+			return err
+		}
 
-		go ingest.Crawl(AppConfig.Files, filePathChan, errChan)
+		paths := []string{storage.SpaceIndexPath(cfg.VectorIndexPath(), "")}
+		for name := range spaces {
+			paths = append(paths, storage.SpaceIndexPath(cfg.VectorIndexPath(), name))
+		}
+		if recreate && storage.IsRemoteVectorStore(cfg.VectorStore) {
+			slog.Warn("--recreate keeps the vectors in the vector store, which re-indexing overwrites; drop its collections to start over, e.g. for a model of another dimension",
+				"backend", cfg.VectorStore.Backend, "collection", storage.VectorCollection(cfg.VectorStore, ""))
+			// The vectors are re-embedded with the configured model.
+			for _, p := range paths {
+				if err := storage.RemoveIndexModel(p); err != nil {
+					return util.WrapError(err, "Failed to reset the vector index's model fingerprint", slog.String("path", p))
+				}
+			}
+		} else if recreate {
+			for _, p := range paths {
+				moved, err := storage.RecreateVectorIndex(p)
+				if err != nil {
+					return util.WrapError(err, "Failed to move the vector index aside", slog.String("path", p))
+				}
+				if moved != "" {
+					slog.Info("Moved the vector index aside, rebuilding it", "path", p, "moved_to", moved)
+				}
+			}
+		}
 
 		var filesProcessedCount int
+		counted := sources
+		if since != "" {
+			counted = nil // git lists the files to index
+		}
+		progress := newIndexTracker(context.Background(), progressPath, counted, mgr.ChunksIndexed)
+		defer func() { progress.Finish(err) }()
+		defer func() {
+			if werr := issues.write(reportPath); werr != nil {
+				util.LogError(util.Logger, util.WrapError(werr, "Failed to write the index error report", slog.String("path", reportPath)))
+			}
+			if perr := issues.print(os.Stdout, reportPath); perr != nil {
+				slog.Warn("Failed to print the index error report", "err", perr)
+			}
+		}()
+		if bulk {
+			// Queue two full batches per concurrent embedding request.
+			concurrent := cfg.Embedding.Concurrent
+			if concurrent < 1 {
+				concurrent = 1
+			}
+			if err := mgr.StartBulk(context.Background(), batchSize*concurrent*2); err != nil {
+				return util.WrapError(err, "Failed to open the indexes for bulk indexing")
+			}
+			found := progress.Progress()
+			slog.Info("Bulk indexing: indexes are saved when indexing completes",
+				"files", found.FilesTotal, "bytes", formatByteSize(found.BytesTotal), "embedding_batch_size", batchSize)
+		}
+		finishBulk := func() error {
+			if !bulk {
+				return nil
+			}
+			bulk = false
+			err := finishBulkIndex(mgr, progress.Progress().StartedAt)
+			issues.fail("", err)
+			return err
+		}
+		defer finishBulk()
+
+		if since != "" {
+			n, err := indexChangedSince(context.Background(), cfg, mgr, issues, rootDir, since)
+			if err != nil {
+				wrappedErr := util.WrapError(err, "Failed to index files changed since revision", slog.String("since", since))
+				util.LogError(util.Logger, wrappedErr)
+				return wrappedErr
+			}
+			filesProcessedCount = n
+		} else {
+			for _, src := range sources {
+				crawlerError := src.Walk(context.Background(), func(relPath, absPath string) error {
+					var size int64
+					if fi, err := os.Stat(absPath); err == nil {
+						size = fi.Size()
+					}
+					defer progress.FileDone(size)
+					if err := mgr.ProcessFile(context.Background(), relPath, absPath); err != nil {
+						if errors.Is(err, storage.ErrCorruptIndex) || errors.Is(err, storage.ErrModelMismatch) || errors.Is(err, storage.ErrDimensionMismatch) {
+							return err // every other file would fail the same way
+						}
+						util.LogError(util.Logger, util.WrapError(err, "Failed to process file", slog.String("path", relPath)))
+						issues.fail(relPath, err)
+						return nil
+					}
+					filesProcessedCount++
+					return nil
+				})
+				if crawlerError != nil {
+					finalErr := util.WrapError(crawlerError, "Indexing failed due to crawler error", slog.String("source", src.Name()))
+					util.LogError(util.Logger, finalErr)
+					return finalErr
+				}
+			}
+		}
+
+		slog.Debug("Sources crawled.", "files_crawled_count", filesProcessedCount)
+		if err := reindexDedupOrphans(context.Background(), mgr, issues, rootDir); err != nil {
+			return err
+		}
 
-		for relPath := range filePathChan {
-			absPath := filepath.Join(rootDir, relPath)
-			if err := mgr.ProcessFile(context.Background(), relPath, absPath); err != nil {
-				util.LogError(util.Logger, util.WrapError(err, "Failed to process file", slog.String("path", relPath)))
-				continue
+		// Commit history goes to the default index only.
+		if cfg.Git.History && collection == "" {
+			if err := mgr.IndexGitHistory(context.Background(), rootDir); err != nil {
+				util.LogError(util.Logger, util.WrapError(err, "Failed to index git history"))
 			}
-			filesProcessedCount++
 		}
 
-		slog.Debug("filePathChan closed.", "files_crawled_count", filesProcessedCount)
+		if err := finishBulk(); err != nil {
+			util.LogError(util.Logger, err)
+			return err
+		}
+		slog.Info("Indexing process completed.", "files_processed", filesProcessedCount,
+			"files_failed", len(issues.Failed), "files_skipped", len(issues.Skipped))
+		if failOnError && len(issues.Failed) > 0 {
+			// Not swapped in: a rebuild that lost files is left out.
+			return util.NewError(fmt.Sprintf("%d files failed to index (--fail-on-error)", len(issues.Failed)),
+				slog.String("report", reportPath))
+		}
 
-		var crawlerError error
-		select {
-		case err := <-errChan:
+		if rebuilt != nil {
+			r := rebuilt
+			rebuilt = nil // kept after a failed swap; the next rebuild prunes it
+			previous, err := r.Swap()
 			if err != nil {
-				crawlerError = err
+				wrappedErr := util.WrapError(err, "Failed to swap in the rebuilt indexes", slog.String("generation_dir", r.Staging))
+				util.LogError(util.Logger, wrappedErr)
+				return wrappedErr
 			}
-		default:
+			slog.Info("Swapped in the rebuilt indexes; running servers search them within seconds",
+				"dir", r.Dir, "generation_dir", r.Staging, "previous", previous)
 		}
 
-		if crawlerError != nil {
-			finalErr := util.WrapError(crawlerError, "Indexing failed due to crawler error")
-			util.LogError(util.Logger, finalErr)
-			return finalErr
+		if push, _ := cmd.Flags().GetString("push"); push != "" {
+			endpoint, _ := cmd.Flags().GetString("endpoint")
+			m := storage.BundleManifest{Dimension: embedder.Dimension(), SemangoVersion: version}
+			if err := pipeline.PushIndex(context.Background(), cfg, push, endpoint, m); err != nil {
+				wrappedErr := util.WrapError(err, "Failed to push index bundle", slog.String("dest", push))
+				util.LogError(util.Logger, wrappedErr)
+				return wrappedErr
+			}
+			slog.Info("Index bundle pushed", "dest", push)
 		}
-
-		slog.Info("Indexing process completed.", "files_processed", filesProcessedCount)
 		return nil
 	},
 }
@@ -241,137 +500,318 @@ var indexCmd = &cobra.Command{
 var searchCmd = &cobra.Command{
 	Use:   "search [query]",
 	Short: "Search indexed text content.",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Runs a query against the local indexes with the same ranking as the server and prints
+the results as a table (the default), JSON or JSON Lines.`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if AppConfig == nil {
 			cfgErr := util.NewError("Configuration not loaded before search command")
 			util.LogError(util.Logger, cfgErr)
 			return cfgErr
 		}
-		query := args[0]
-		size := 10 // TODO: Make this configurable via flag or config
-		bleveIdx, err := storage.OpenOrCreateBleveIndex(AppConfig.Lexical.IndexPath)
+		query := strings.Join(args, " ")
+		topK, _ := cmd.Flags().GetInt("top-k")
+		mode, _ := cmd.Flags().GetString("mode")
+		filterFlag, _ := cmd.Flags().GetString("filter")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		asJSONL, _ := cmd.Flags().GetBool("jsonl")
+		parents, _ := cmd.Flags().GetBool("parents")
+		window, _ := cmd.Flags().GetInt("window")
+		groupBy, _ := cmd.Flags().GetString("group-by")
+		maxPerDoc, _ := cmd.Flags().GetInt("max-per-doc")
+		syntax, _ := cmd.Flags().GetString("lexical-syntax")
+		boostFlags, _ := cmd.Flags().GetStringArray("boost")
+		space, _ := cmd.Flags().GetString("space")
+		asOf, _ := cmd.Flags().GetString("as-of")
+		expand, _ := cmd.Flags().GetString("expand")
+		variants, _ := cmd.Flags().GetStringArray("variant")
+		collections, _ := cmd.Flags().GetStringArray("collection")
+		force, _ := cmd.Flags().GetBool("force")
+		maxText, _ := cmd.Flags().GetInt("max-text")
+		adaptive, _ := cmd.Flags().GetBool("adaptive")
+		if maxText < 0 {
+			return util.NewError("--max-text must not be negative")
+		}
+		if window < 0 || window > search.MaxWindow {
+			return util.NewError(fmt.Sprintf("--window must be between 0 and %d", search.MaxWindow))
+		}
+		if !search.ValidGroupBy(groupBy) {
+			return util.NewError(fmt.Sprintf("Invalid --group-by %q. Supported values: document", groupBy))
+		}
+		if maxPerDoc < 0 {
+			return util.NewError("--max-per-doc must not be negative")
+		}
+		if !search.ValidLexicalSyntax(syntax) {
+			return util.NewError(fmt.Sprintf("Invalid --lexical-syntax %q. Supported values: simple, advanced", syntax))
+		}
+		if syntax == search.SyntaxAdvanced {
+			if _, err := storage.ParseLexicalQuery(query); err != nil {
+				return util.WrapError(err, "Invalid query", slog.String("query", query))
+			}
+		}
+		if len(collections) > 0 && asOf != "" {
+			return util.NewError("--as-of searches a snapshot of the default index and cannot be combined with --collection")
+		}
+		collection := ""
+		if len(collections) == 1 {
+			collection = collections[0]
+		}
+		cfg, err := collectionConfig(collection)
 		if err != nil {
-			wrappedErr := util.WrapError(err, "Failed to open Bleve index", slog.String("path", AppConfig.Lexical.IndexPath))
-			util.LogError(util.Logger, wrappedErr)
-			return wrappedErr
+			return err
 		}
-		defer bleveIdx.Close()
 
-		// Perform lexical search
-		lexHits, err := bleveIdx.SearchText(query, size)
+		mode, modeDefaults := cfg.Search.ForMode(mode)
+		if !cmd.Flags().Changed("max-text") {
+			maxText = cfg.Search.MaxTextLength
+		}
+		if !cmd.Flags().Changed("top-k") {
+			topK = modeDefaults.TopK
+		}
+		if topK <= 0 {
+			return util.NewError("--top-k must be positive")
+		}
+		if !search.ValidMode(mode) {
+			return util.NewError(fmt.Sprintf("Invalid --mode %q. Supported modes: hybrid, lexical, vector", mode))
+		}
+		filter, err := search.ParseFilter(filterFlag)
+		if err != nil {
+			return util.WrapError(err, "Invalid --filter", slog.String("filter", filterFlag))
+		}
+		boosts, err := search.ParseBoosts(boostFlags)
 		if err != nil {
-			return util.WrapError(err, "Lexical search failed")
+			return util.WrapError(err, "Invalid --boost")
 		}
 
-		// Vector index path same as indexing
-		faissPath := filepath.Join("semango", "index", "faiss.index")
-		// Initialize embedder (same logic as indexCmd)
-		var embedder ingest.Embedder
-		{
-			prov := AppConfig.Embedding.Provider
-			switch prov {
-			case "openai", "": // default to openai
-				apiKey := os.Getenv("OPENAI_API_KEY")
-				if apiKey == "" {
-					return util.NewError("OpenAI API key is required but not found in OPENAI_API_KEY environment variable")
-				}
-				openCfg := ingest.OpenAIConfig{
-					APIKey:     apiKey,
-					Model:      AppConfig.Embedding.Model,
-					BatchSize:  AppConfig.Embedding.BatchSize,
-					Concurrent: AppConfig.Embedding.Concurrent,
-				}
-				e, err := ingest.NewOpenAIEmbedder(openCfg)
-				if err != nil {
-					return util.WrapError(err, "Failed to create OpenAI embedder")
-				}
-				embedder = e
-			case "local":
-				if AppConfig.Embedding.LocalModelPath == "" {
-					return util.NewError("Local model path is required for local embedder provider")
-				}
-				localCfg := ingest.LocalEmbedderConfig{
-					ModelPath: AppConfig.Embedding.LocalModelPath,
-					CacheDir:  AppConfig.Embedding.ModelCacheDir,
-					BatchSize: AppConfig.Embedding.BatchSize,
-					MaxLength: 512, // Default max length
-				}
-				// Validate configuration
-				if err := ingest.ValidateModelConfig(localCfg); err != nil {
-					return util.WrapError(err, "Invalid local embedder configuration")
-				}
-				e, err := ingest.NewLocalEmbedder(localCfg)
-				if err != nil {
-					return util.WrapError(err, "Failed to create local embedder")
-				}
-				embedder = e
-			default:
-				return util.NewError(fmt.Sprintf("Unsupported embedder provider: %s. Supported providers: openai, local", prov))
+		if !search.ValidExpand(expand) {
+			return util.NewError(fmt.Sprintf("Invalid --expand %q. Supported values: none, terms, hyde, multi", expand))
+		}
+		if len(variants) > search.MaxQueryVariants {
+			return util.NewError(fmt.Sprintf("At most %d --variant flags are allowed", search.MaxQueryVariants))
+		}
+
+		var rewriter search.QueryRewriter
+		if expand == search.ExpandHyDE || expand == search.ExpandMulti {
+			model, err := rag.NewChatModel(cfg.LLM)
+			if err != nil {
+				return util.WrapError(err, "--expand "+expand+" needs an LLM")
+			}
+			rewriter = rag.NewRewriter(model)
+		}
+		opts := search.Options{Filter: filter, Mode: mode, Parents: parents, Window: window, Boosts: boosts, Space: space, Expand: expand, Queries: variants,
+			GroupBy: groupBy, MaxChunksPerDoc: maxPerDoc, LexicalSyntax: syntax}
+		if adaptive {
+			opts.Adaptive = search.AdaptiveDrop(cfg.Search)
+		}
+		if len(collections) > 1 {
+			fed, err := federation(collections, rewriter, force)
+			if err != nil {
+				return err
+			}
+			page, err := fed.SearchPage(context.Background(), query, 0, topK, opts)
+			if err != nil {
+				return util.WrapError(err, "Search failed", slog.String("query", query))
 			}
+			return printResults(page.Results, maxText, asJSON, asJSONL)
 		}
 
-		queryVecs, err := embedder.Embed(context.Background(), []string{query})
+		searcher, err := search.NewSearcher(cfg)
 		if err != nil {
-			return util.WrapError(err, "Embedding query failed")
+			wrappedErr := util.WrapError(err, "Failed to initialize searcher")
+			util.LogError(util.Logger, wrappedErr)
+			return wrappedErr
 		}
-		vecIdx, err := storage.NewFaissVectorIndex(context.Background(), faissPath, embedder.Dimension(), faiss.MetricInnerProduct)
-		if err != nil {
-			return util.WrapError(err, "Opening vector index failed")
-		}
-		defer vecIdx.Close()
-		vecResults, _ := vecIdx.Search(context.Background(), queryVecs[0], size)
-
-		// Build JSON structure
-		type hit struct {
-			ID    string  `json:"id"`
-			Score float32 `json:"score"`
-			Text  string  `json:"text"`
-		}
-		type output struct {
-			Lexical []hit `json:"lexical"`
-			Vector  []hit `json:"vector"`
-		}
-
-		out := output{}
-		for _, h := range lexHits {
-			preview := ""
-			if doc, err := bleveIdx.GetDocument(h.ID); err == nil && doc != nil {
-				for _, f := range doc.Fields {
-					if f.Name() == "text" {
-						val := string(f.Value())
-						if len(val) > 80 {
-							preview = val[:77] + "..."
-						} else {
-							preview = val
-						}
-						break
-					}
-				}
+		if rewriter != nil {
+			searcher.WithRewriter(rewriter)
+		}
+		if force {
+			searcher.AllowModelMismatch()
+		}
+		if asOf != "" {
+			dir, err := os.MkdirTemp("", "semango-snapshot-*")
+			if err != nil {
+				return err
 			}
-			out.Lexical = append(out.Lexical, hit{ID: h.ID, Score: float32(h.Score), Text: preview})
-		}
-		for _, vr := range vecResults {
-			fullText := ""
-			if doc, err := bleveIdx.GetDocument(vr.ID); err == nil && doc != nil {
-				for _, f := range doc.Fields {
-					if f.Name() == "text" {
-						fullText = string(f.Value())
-						break
-					}
-				}
+			defer os.RemoveAll(dir)
+			m, paths, err := pipeline.OpenSnapshot(context.Background(), cfg, asOf, "", dir)
+			if err != nil {
+				return util.WrapError(err, "Failed to open snapshot", slog.String("as_of", asOf))
+			}
+			if err := pipeline.CheckEmbedding(cfg, m); err != nil && mode != search.ModeLexical {
+				return util.WrapError(err, "Snapshot cannot be searched with vectors; use --mode lexical", slog.String("as_of", asOf))
 			}
-			out.Vector = append(out.Vector, hit{ID: vr.ID, Score: vr.Score, Text: fullText})
+			slog.Info("Searching index snapshot", "as_of", asOf, "generation", m.Generation.Number, "built_at", m.CreatedAt)
+			searcher = searcher.AsOf(paths)
+		}
+		results, err := searcher.Search(context.Background(), query, topK, opts)
+		if err != nil {
+			return util.WrapError(err, "Search failed", slog.String("query", query))
+		}
+		return printResults(results, maxText, asJSON, asJSONL)
+	},
+}
+
+// federation returns a search across the named collections, each with its
+// own searcher and configured weight. force allows searching vector indexes
+// built with another embedding model.
+func federation(names []string, rewriter search.QueryRewriter, force bool) (search.Federation, error) {
+	fed := make(search.Federation, 0, len(names))
+	for _, name := range names {
+		cfg, err := collectionConfig(name)
+		if err != nil {
+			return nil, err
+		}
+		searcher, err := search.NewSearcher(cfg)
+		if err != nil {
+			return nil, util.WrapError(err, "Failed to initialize searcher", slog.String("collection", name))
+		}
+		if rewriter != nil {
+			searcher.WithRewriter(rewriter)
 		}
+		if force {
+			searcher.AllowModelMismatch()
+		}
+		fed = append(fed, search.Member{Collection: name, Searcher: searcher, Weight: AppConfig.Collections[name].Weight})
+	}
+	return fed, nil
+}
 
+// printResults writes results to stdout as JSON, JSON Lines or a table,
+// with their text cut to maxText characters.
+func printResults(results []search.Result, maxText int, asJSON, asJSONL bool) error {
+	truncateResults(results, maxText)
+	switch {
+	case asJSON:
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
-		_ = enc.Encode(out)
+		return enc.Encode(results)
+	case asJSONL:
+		enc := json.NewEncoder(os.Stdout)
+		for _, r := range results {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
 		return nil
-	},
+	default:
+		return printResultsTable(os.Stdout, results)
+	}
+}
+
+// truncateResults cuts the text of results, and of the chunks nested in
+// them, to maxText characters.
+func truncateResults(results []search.Result, maxText int) {
+	for i := range results {
+		results[i].Text, results[i].Truncated = util.Truncate(results[i].Text, maxText)
+		truncateResults(results[i].Chunks, maxText)
+	}
+}
+
+// printResultsTable writes one line per result with a single-line snippet of
+// its text around the best highlight. Grouped results note how many more
+// chunks of the document matched.
+func printResultsTable(w io.Writer, results []search.Result) error {
+	if len(results) == 0 {
+		_, err := fmt.Fprintln(w, "No results.")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tSCORE\tPATH\tTEXT")
+	for i, r := range results {
+		preview := strings.Join(strings.Fields(r.Snippet(80)), " ")
+		path := r.Path
+		if r.Collection != "" {
+			path = r.Collection + ":" + path
+		}
+		if len(r.Chunks) > 0 {
+			path += fmt.Sprintf(" (+%d)", len(r.Chunks))
+		}
+		fmt.Fprintf(tw, "%d\t%.4f\t%s\t%s\n", i+1, r.Score, path, preview)
+	}
+	return tw.Flush()
+}
+
+// indexChangedSince re-indexes only the files changed since the git revision
+// since, as listed by git: deleted files are removed from the indexes and
+// changed ones are replaced. It returns the number of files processed;
+// those that fail are recorded in issues.
+func indexChangedSince(ctx context.Context, cfg *config.Config, mgr *pipeline.Manager, issues *indexIssues, rootDir, since string) (int, error) {
+	changes, err := ingest.ChangedSince(ctx, rootDir, since)
+	if err != nil {
+		return 0, err
+	}
+	slog.Info("Indexing files changed since revision", "since", since, "changed", len(changes))
+
+	ignore := ingest.NewIgnoreMatcher(rootDir, cfg.Files.RespectGitignore)
+	processed := 0
+	for _, ch := range changes {
+		if !ingest.PathIncluded(cfg.Files.Include, cfg.Files.Exclude, ch.Path) {
+			continue
+		}
+		if _, ok := ignore.Match(ch.Path, false); ok {
+			continue
+		}
+		// Drop the old chunks first: the new version may have fewer.
+		if err := mgr.RemovePath(ctx, ch.Path); err != nil {
+			if errors.Is(err, storage.ErrCorruptIndex) {
+				return processed, err
+			}
+			util.LogError(util.Logger, util.WrapError(err, "Failed to remove stale chunks", slog.String("path", ch.Path)))
+			continue
+		}
+		if ch.Deleted {
+			processed++
+			continue
+		}
+		if err := mgr.ProcessFile(ctx, ch.Path, filepath.Join(rootDir, ch.Path)); err != nil {
+			if errors.Is(err, storage.ErrCorruptIndex) {
+				return processed, err
+			}
+			util.LogError(util.Logger, util.WrapError(err, "Failed to process file", slog.String("path", ch.Path)))
+			issues.fail(ch.Path, err)
+			continue
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// reindexDedupOrphans indexes again the files whose duplicate chunks lost
+// their canonical copy during this run, so that they are searchable again.
+// Files that are not on disk under rootDir, such as those of remote
+// sources, are logged instead, and those that fail are recorded in issues.
+func reindexDedupOrphans(ctx context.Context, mgr *pipeline.Manager, issues *indexIssues, rootDir string) error {
+	for _, relPath := range mgr.TakeDedupOrphans() {
+		absPath := filepath.Join(rootDir, relPath)
+		if _, err := os.Stat(absPath); err != nil {
+			slog.Warn("Duplicate chunks lost their canonical copy; index the file again to restore them", "path", relPath)
+			continue
+		}
+		if err := mgr.ProcessFile(ctx, relPath, absPath); err != nil {
+			if errors.Is(err, storage.ErrCorruptIndex) {
+				return err
+			}
+			util.LogError(util.Logger, util.WrapError(err, "Failed to process file", slog.String("path", relPath)))
+			issues.fail(relPath, err)
+		}
+	}
+	return nil
 }
 
 // Helper function to check if a string is in a slice
+// collectionConfig returns the configuration of the named collection, or
+// AppConfig when name is empty.
+func collectionConfig(name string) (*config.Config, error) {
+	if name == "" {
+		return AppConfig, nil
+	}
+	cfg, ok := AppConfig.ForCollection(name)
+	if !ok {
+		return nil, util.NewError(fmt.Sprintf("Unknown collection %q. Define it under collections in semango.yml", name))
+	}
+	return cfg, nil
+}
+
 func stringInSlice(a string, list []string) bool {
 	for _, b := range list {
 		if b == a {
@@ -381,14 +821,6 @@ func stringInSlice(a string, list []string) bool {
 	return false
 }
 
-// Helper function to truncate strings for logging
-func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	return s[:maxLen-3] + "..."
-}
-
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print version information",
@@ -409,8 +841,93 @@ func init() {
 	rootCmd.AddCommand(searchCmd)
 	rootCmd.AddCommand(serverCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(modelsCmd)
+	rootCmd.AddCommand(pullIndexCmd)
+	rootCmd.AddCommand(quickstartCmd)
+	rootCmd.AddCommand(askCmd)
+	rootCmd.AddCommand(suggestConfigCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(quantizeCmd)
+	rootCmd.AddCommand(tableCmd)
+	configCmd.AddCommand(configShowCmd, configValidateCmd)
+	snapshotCmd.AddCommand(snapshotCreateCmd, snapshotListCmd, snapshotRestoreCmd)
+	tableCmd.AddCommand(tableListCmd, tableQueryCmd)
+	modelsCmd.AddCommand(modelsGCCmd)
 	initCmd.Flags().StringP("file", "f", config.DefaultConfigPath, "Path to write the configuration file")
+	initCmd.Flags().BoolP("interactive", "i", false, "Ask for the provider, model, file types and port instead of writing the defaults")
+	indexCmd.Flags().String("since", "", "Only re-index files changed since this git revision (commit, tag or branch), including uncommitted changes")
+	searchCmd.Flags().IntP("top-k", "k", 0, "Number of results to return (default from search.<mode>.top_k, else 10)")
+	searchCmd.Flags().String("mode", "", "Retrieval mode: hybrid, lexical or vector (default from search.default_mode, else hybrid)")
+	searchCmd.Flags().String("filter", "", `Metadata filter of key:value terms, e.g. 'source:EmailLoader lang:de'`)
+	searchCmd.Flags().Bool("parents", false, "Return the parent section of each matched chunk (requires files.parent_chunk_size)")
+	searchCmd.Flags().Int("window", 0, "Widen each result to up to this many chunks before and after it in its document")
+	searchCmd.Flags().String("group-by", "", "'document' returns one result per file, its best chunk, with its other matching chunks nested")
+	searchCmd.Flags().Int("max-per-doc", 0, "Keep at most this many chunks of a file, counting nested ones (0 keeps all)")
+	searchCmd.Flags().String("lexical-syntax", "", `'advanced' reads "phrases", +required and -excluded terms, key:value and fuzzy~ terms in the query for lexical matching`)
+	searchCmd.Flags().StringArray("boost", nil, "Multiply the score of results whose path matches a pattern, e.g. 'docs/**=1.5' (repeatable)")
+	searchCmd.Flags().String("space", "", "Vector space to search: a name from embedding.spaces, or 'default'")
+	searchCmd.Flags().String("expand", "", "Rewrite the query before retrieval: 'terms' adds terms from the top lexical matches, 'hyde' embeds a hypothetical answer from the llm section's model, 'multi' also searches variants it writes")
+	searchCmd.Flags().StringArray("variant", nil, "Also search this variant of the query and merge the results (repeatable)")
+	searchCmd.Flags().StringArray("collection", nil, "Search this collection from the collections section instead of the default index; repeat it to search several collections and merge their results")
+	searchCmd.Flags().String("as-of", "", "Search an index snapshot read-only: a snapshot name, a bundle file, or an s3://, gs:// or http(s):// URL")
+	searchCmd.Flags().Bool("adaptive", false, "Return results only down to the first steep score drop (search.adaptive_drop); --top-k still caps the count")
+	searchCmd.Flags().Int("max-text", 0, "Cut the text of each result to this many characters (default from search.max_text_length, else the whole chunk)")
+	searchCmd.Flags().Bool("json", false, "Print results as a JSON array")
+	searchCmd.Flags().Bool("jsonl", false, "Print results as JSON Lines, one result per line")
+	searchCmd.Flags().Bool("table", false, "Print results as a table (the default)")
+	searchCmd.Flags().Bool("force", false, "Search vector indexes built with another embedding model instead of refusing, with a warning")
+	searchCmd.MarkFlagsMutuallyExclusive("json", "jsonl", "table")
+	indexCmd.Flags().String("collection", "", "Index this collection from the collections section instead of the default index")
+	indexCmd.Flags().Bool("recreate", false, "Move the vector indexes aside (to <file>.corrupt-<time>) and rebuild them from every file, e.g. after a corruption error")
+	indexCmd.Flags().Bool("rebuild", false, "Build new indexes from every file next to the served ones, then swap them in atomically, so servers keep searching the old indexes until the new ones are complete")
+	indexCmd.Flags().Bool("dry-run", false, "Report what would be indexed, per loader, with estimated chunks, embedding tokens and cost and the files skipped, without embedding or writing anything")
+	indexCmd.Flags().Bool("json", false, "Print the --dry-run report as JSON")
+	indexCmd.Flags().Bool("bulk", false, "Build a new index as fast as possible: embed many files per request and save the indexes once at the end, with progress and an estimated time remaining")
+	indexCmd.Flags().Bool("fail-on-error", false, "Exit with an error if any file failed to index, after writing the error report; a --rebuild is then not swapped in")
+	indexCmd.Flags().String("error-report", "", "Write the JSON report of failed and skipped files here (default index-errors.json in the index directory)")
+	indexCmd.Flags().String("push", "", "Upload the finished index as a bundle to this s3://, gs:// or http(s):// URL")
+	indexCmd.Flags().String("endpoint", "", "S3-compatible endpoint for s3:// --push URLs (e.g. a MinIO server)")
+	indexCmd.Flags().Bool("force", false, "Add to vector indexes built with another embedding model instead of refusing, mixing vectors that do not compare")
+	serverCmd.Flags().Bool("force", false, "Search and write vector indexes built with another embedding model instead of refusing, with a warning")
+	askCmd.Flags().IntP("top-k", "k", 0, "Number of chunks to answer from (default from llm.top_k, else 8)")
+	askCmd.Flags().String("mode", "", "Retrieval mode: hybrid, lexical or vector (default from search.default_mode, else hybrid)")
+	askCmd.Flags().String("filter", "", `Metadata filter of key:value terms, e.g. 'source:EmailLoader lang:de'`)
+	askCmd.Flags().String("expand", "", "Rewrite the question before retrieval: 'terms', 'hyde' or 'multi'")
+	askCmd.Flags().StringArray("variant", nil, "Also search this query, e.g. a sub-question, for sources (repeatable)")
+	askCmd.Flags().Bool("adaptive", false, "Answer only from the sources above the first steep score drop among the top-k (search.adaptive_drop)")
+	askCmd.Flags().Bool("json", false, "Print the answer and its sources as JSON")
+	pullIndexCmd.Flags().Bool("force", false, "Install the bundle even if it was built with a different embedding model")
+	pullIndexCmd.Flags().String("endpoint", "", "S3-compatible endpoint for s3:// URLs (e.g. a MinIO server)")
+	snapshotCmd.PersistentFlags().String("collection", "", "Use the snapshots of this collection from the collections section instead of the default index")
+	snapshotListCmd.Flags().Bool("verify", false, "Read every snapshot in full and check its checksums")
+	snapshotListCmd.Flags().Bool("json", false, "Print the snapshots as JSON")
+	snapshotRestoreCmd.Flags().String("dir", "", "Restore into this empty directory instead of replacing the local indexes")
+	snapshotRestoreCmd.Flags().Bool("force", false, "Restore even if the snapshot was built with a different embedding model")
+	statsCmd.Flags().String("collection", "", "Show the indexes of this collection from the collections section instead of the default index")
+	statsCmd.Flags().Bool("json", false, "Print the statistics as JSON")
+	reportCmd.Flags().Bool("json", false, "Print the report as JSON")
+	reportCmd.Flags().Bool("html", false, "Print the report as an HTML page")
+	reportCmd.Flags().Bool("save", false, "Also write the report to the report directory")
+	doctorCmd.Flags().Bool("json", false, "Print the checks as JSON")
+	quantizeCmd.Flags().String("compression", "", "Compression to convert to: none, float16, int8 or pq (default vector.compression)")
+	quantizeCmd.Flags().String("collection", "", "Convert the indexes of this collection from the collections section instead of the default index")
+	tableCmd.PersistentFlags().String("collection", "", "Use the datasets of this collection from the collections section instead of the default index")
+	tableListCmd.Flags().Bool("json", false, "Print the datasets as JSON")
+	tableQueryCmd.Flags().Int("max-rows", 100, "Print at most this many rows of the result")
+	tableQueryCmd.Flags().Bool("json", false, "Print the result as JSON: columns, rows and the datasets read")
+	suggestConfigCmd.Flags().Bool("write", false, "Set the suggested files settings in the configuration file")
+	suggestConfigCmd.Flags().Bool("json", false, "Print the scan and suggestions as JSON")
+	quickstartCmd.Flags().Bool("reindex", false, "Rebuild the index even if one exists")
+	quickstartCmd.Flags().Bool("no-serve", false, "Set up and index, but do not start the server")
+	modelsGCCmd.Flags().String("max-size", "", "Evict least recently used models until the cache is below this size (e.g. 2GB)")
+	modelsGCCmd.Flags().Bool("dry-run", false, "List what would be removed without deleting anything")
 	rootCmd.PersistentFlags().StringP("config", "c", config.DefaultConfigPath, "Path to the configuration file")
+	rootCmd.PersistentFlags().StringArray("set", nil, "Override a setting, e.g. --set server.port=9000 (repeatable); takes precedence over the file and SEMANGO_* environment variables")
+	rootCmd.PersistentFlags().String("schema", config.DefaultCueSchemaPath, "CUE schema file to validate the configuration against instead of the built-in one")
 }
 
 func Execute() {
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/util"
+	"github.com/spf13/cobra"
+)
+
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "Manage locally cached embedding models.",
+}
+
+var modelsGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove incomplete and least recently used cached models.",
+	Long: `Deletes interrupted or corrupted model downloads from the model cache and, with --max-size,
+evicts the least recently used models until the cache fits. Models referenced by the configuration
+are never evicted.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if AppConfig == nil {
+			cfgErr := util.NewError("Configuration not loaded before models gc command")
+			util.LogError(util.Logger, cfgErr)
+			return cfgErr
+		}
+		maxSizeFlag, _ := cmd.Flags().GetString("max-size")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		maxBytes, err := parseByteSize(maxSizeFlag)
+		if err != nil {
+			return util.WrapError(err, "Invalid --max-size", slog.String("value", maxSizeFlag))
+		}
+
+		cacheDir := AppConfig.Embedding.ModelCacheDir
+		if cacheDir == "" {
+			cacheDir = ingest.DefaultModelCacheDir()
+		}
+		keep := []string{AppConfig.Embedding.LocalModelPath}
+		for _, l := range AppConfig.Embedding.Languages {
+			if l.LocalModelPath != "" {
+				keep = append(keep, l.LocalModelPath)
+			}
+		}
+
+		res, err := ingest.GCModelCache(cacheDir, ingest.ModelGCOptions{MaxBytes: maxBytes, Keep: keep, DryRun: dryRun})
+		if err != nil {
+			return util.WrapError(err, "Model cache garbage collection failed", slog.String("cache_dir", cacheDir))
+		}
+
+		verb := "Removed"
+		if dryRun {
+			verb = "Would remove"
+		}
+		for _, e := range res.Removed {
+			reason := "least recently used"
+			if !e.Complete {
+				reason = e.Problem
+			}
+			fmt.Printf("%s %s (%s, %s)\n", verb, e.Name, formatByteSize(e.Size), reason)
+		}
+		var kept int64
+		for _, e := range res.Kept {
+			kept += e.Size
+		}
+		fmt.Printf("%s %d model(s), freeing %s; %d model(s) using %s remain in %s\n",
+			verb, len(res.Removed), formatByteSize(res.Freed), len(res.Kept), formatByteSize(kept), cacheDir)
+		return nil
+	},
+}
+
+// parseByteSize parses sizes such as "500MB", "2GiB" or "1048576". Units are
+// binary (1KB = 1024 bytes); an empty string means no limit.
+func parseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+	units := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"TIB", 1 << 40}, {"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+		{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+	}
+	mult := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s, mult = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+func formatByteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/omarkamali/semango/internal/ingest/source"
+	"github.com/omarkamali/semango/internal/pipeline"
+)
+
+// progressInterval is how often index runs log their progress when the
+// output is not a terminal.
+const progressInterval = 10 * time.Second
+
+// progressBarInterval is how often the progress bar is redrawn.
+const progressBarInterval = 200 * time.Millisecond
+
+// progressBarWidth is the number of cells of the progress bar.
+const progressBarWidth = 30
+
+// newIndexTracker follows an index run: it counts the files of sources,
+// then reports the progress as a bar on stderr if that is a terminal and
+// as log lines every progressInterval otherwise, and keeps it at path for
+// the server. Remote sources are not counted, since listing them means
+// downloading them; with any among sources no total or time left is known.
+func newIndexTracker(ctx context.Context, path string, sources []source.Source, chunks func() int64) *pipeline.ProgressTracker {
+	interval, report := progressInterval, logProgress
+	if isTerminal(os.Stderr) {
+		interval, report = progressBarInterval, drawProgress(os.Stderr)
+	}
+	t := pipeline.NewProgressTracker(path, interval, chunks, report)
+	if files, bytes, ok := countFiles(ctx, sources); ok {
+		t.Found(files, bytes)
+	}
+	return t
+}
+
+// countFiles counts the files of local sources and their total size. ok is
+// false if sources include a remote one.
+func countFiles(ctx context.Context, sources []source.Source) (files int, bytes int64, ok bool) {
+	for _, src := range sources {
+		if _, ok := src.(*source.Local); !ok {
+			return 0, 0, false
+		}
+		err := src.Walk(ctx, func(_, absPath string) error {
+			files++
+			if fi, err := os.Stat(absPath); err == nil {
+				bytes += fi.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, 0, false
+		}
+	}
+	return files, bytes, true
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// logProgress logs p as a structured log line.
+func logProgress(p pipeline.Progress) {
+	if p.State != pipeline.ProgressRunning {
+		return // the index command logs the outcome
+	}
+	attrs := []any{
+		"files", p.Files,
+		"chunks", p.Chunks,
+		"files_per_sec", p.FilesPerSecond,
+		"chunks_per_sec", p.ChunksPerSecond,
+		"elapsed", p.UpdatedAt.Sub(p.StartedAt).Round(time.Second).String(),
+	}
+	if p.FilesTotal > 0 {
+		attrs = append(attrs, "total_files", p.FilesTotal)
+	}
+	if p.ETASeconds != nil {
+		attrs = append(attrs, "eta", (time.Duration(*p.ETASeconds) * time.Second).String())
+	}
+	slog.Info("Indexing progress", attrs...)
+}
+
+// drawProgress returns a reporter that redraws a progress bar on the last
+// line of w, ending the line once the run is over.
+func drawProgress(w io.Writer) func(pipeline.Progress) {
+	return func(p pipeline.Progress) {
+		var line strings.Builder
+		if p.BytesTotal > 0 {
+			done := float64(p.Bytes) / float64(p.BytesTotal)
+			if done > 1 {
+				done = 1
+			}
+			cells := int(done * progressBarWidth)
+			fmt.Fprintf(&line, "[%s%s] %3.0f%% %d/%d files", strings.Repeat("=", cells),
+				strings.Repeat(" ", progressBarWidth-cells), done*100, p.Files, p.FilesTotal)
+		} else {
+			fmt.Fprintf(&line, "%d files", p.Files)
+		}
+		fmt.Fprintf(&line, ", %d chunks, %.1f files/s", p.Chunks, p.FilesPerSecond)
+		if p.ETASeconds != nil {
+			fmt.Fprintf(&line, ", %s left", time.Duration(*p.ETASeconds)*time.Second)
+		}
+		end := ""
+		if p.State != pipeline.ProgressRunning {
+			end = "\n"
+		}
+		// \033[K clears what is left of a longer previous line.
+		fmt.Fprintf(w, "\r%s\033[K%s", line.String(), end)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/util"
+	"github.com/spf13/cobra"
+)
+
+var pullIndexCmd = &cobra.Command{
+	Use:   "pull-index <url>",
+	Short: "Download a prebuilt index bundle.",
+	Long: `Downloads an index bundle published with 'semango index --push' from an s3://, gs:// or
+http(s):// URL and replaces the local lexical and vector indexes with it. Bundles built with a
+different embedding provider or model are refused unless --force is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if AppConfig == nil {
+			cfgErr := util.NewError("Configuration not loaded before pull-index command")
+			util.LogError(util.Logger, cfgErr)
+			return cfgErr
+		}
+		force, _ := cmd.Flags().GetBool("force")
+		endpoint, _ := cmd.Flags().GetString("endpoint")
+
+		m, err := pipeline.PullIndex(context.Background(), AppConfig, args[0], endpoint, force)
+		if err != nil {
+			return util.WrapError(err, "Failed to pull index bundle", slog.String("url", args[0]))
+		}
+		slog.Info("Index bundle installed",
+			"generation", m.Generation.Number,
+			"built_at", m.CreatedAt,
+			"embedding_model", m.EmbeddingModel,
+			"semango_version", m.SemangoVersion)
+		return nil
+	},
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+
+	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
+	"github.com/spf13/cobra"
+)
+
+var quantizeCmd = &cobra.Command{
+	Use:   "quantize",
+	Short: "Convert the vector indexes to another compression.",
+	Long: `Converts the vector index of the default model and those of embedding.spaces to the compression
+given with --compression, or to vector.compression: none (float32), float16, int8 or pq. Index runs
+convert indexes to vector.compression on their next write anyway; this does it now, e.g. to try a
+compression before configuring it. int8 and pq are trained on the index's vectors and need some
+thousands of them. Converting back to a larger encoding does not restore the precision lost; run
+'semango index --recreate' for that.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if AppConfig == nil {
+			cfgErr := util.NewError("Configuration not loaded before quantize command")
+			util.LogError(util.Logger, cfgErr)
+			return cfgErr
+		}
+		collection, _ := cmd.Flags().GetString("collection")
+		cfg, err := collectionConfig(collection)
+		if err != nil {
+			return err
+		}
+		if storage.IsRemoteVectorStore(cfg.VectorStore) {
+			return util.NewError(fmt.Sprintf("Vectors are kept in %s, which configures quantization itself", cfg.VectorStore.Backend))
+		}
+		vec := cfg.Vector
+		if c, _ := cmd.Flags().GetString("compression"); c != "" {
+			vec.Compression = c
+		}
+		switch vec.Compression {
+		case storage.CompressionNone, storage.CompressionFloat16, storage.CompressionInt8, storage.CompressionPQ:
+		case "":
+			return util.NewError("No compression given: pass --compression or set vector.compression")
+		default:
+			return util.NewError(fmt.Sprintf("Unknown compression %q: use none, float16, int8 or pq", vec.Compression))
+		}
+
+		spaces := []string{""}
+		for name := range cfg.Embedding.Spaces {
+			spaces = append(spaces, name)
+		}
+		sort.Strings(spaces[1:])
+		ctx := context.Background()
+		for _, space := range spaces {
+			path := storage.SpaceIndexPath(cfg.VectorIndexPath(), space)
+			before := fileSize(path)
+			from, err := storage.QuantizeVectorIndex(ctx, path, vec)
+			if errors.Is(err, storage.ErrNoVectorIndex) {
+				slog.Info("No vector index to convert", "path", path)
+				continue
+			}
+			if err != nil {
+				return util.WrapError(err, "Failed to convert vector index", slog.String("path", path))
+			}
+			fmt.Printf("%s: %s -> %s, %s -> %s\n", path, from, vec.Compression, formatByteSize(before), formatByteSize(fileSize(path)))
+		}
+		if cfg.Vector.Compression != "" && cfg.Vector.Compression != vec.Compression {
+			slog.Warn("The next index run converts the indexes back to vector.compression; set it to keep this compression",
+				"vector.compression", cfg.Vector.Compression, "converted_to", vec.Compression)
+		}
+		return nil
+	},
+}
+
+// fileSize returns the size of the file at path, 0 if it is missing.
+func fileSize(path string) int64 {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/ingest/source"
+	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/quickstart"
+	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
+	"github.com/spf13/cobra"
+)
+
+var quickstartCmd = &cobra.Command{
+	Use:   "quickstart [dir]",
+	Short: "Index a demo corpus with the local embedder and start the server.",
+	Long: `Sets up a working Semango instance in dir (default: semango-quickstart): writes a sample
+semango.yml and a small demo corpus, indexes it with a local embedding model (downloaded on first
+use, no API key needed) and starts the server on localhost. Running it again reuses the existing
+configuration and index; --reindex rebuilds the index.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "semango-quickstart"
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		reindex, _ := cmd.Flags().GetBool("reindex")
+		noServe, _ := cmd.Flags().GetBool("no-serve")
+
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return util.WrapError(err, "Failed to create quickstart directory", slog.String("dir", dir))
+		}
+		// Index paths are relative to the working directory.
+		if err := os.Chdir(dir); err != nil {
+			return util.WrapError(err, "Failed to enter quickstart directory", slog.String("dir", dir))
+		}
+
+		if _, err := os.Stat(config.DefaultConfigPath); errors.Is(err, os.ErrNotExist) {
+			if err := config.WriteConfig(config.DefaultConfigPath, quickstart.Config()); err != nil {
+				return util.WrapError(err, "Failed to write quickstart config")
+			}
+			slog.Info("Sample configuration written", "path", config.DefaultConfigPath)
+		}
+		n, err := quickstart.WriteCorpus(".")
+		if err != nil {
+			return util.WrapError(err, "Failed to write demo corpus")
+		}
+		if n > 0 {
+			slog.Info("Demo corpus written", "dir", quickstart.CorpusDir, "files", n)
+		}
+
+		schemaPath, _ = cmd.Flags().GetString("schema")
+		configOverrides, _ = cmd.Flags().GetStringArray("set")
+		cfg, err := config.Load(config.DefaultConfigPath, schemaPath, configOverrides...)
+		if err != nil {
+			return util.WrapError(err, "Failed to load quickstart config")
+		}
+		AppConfig = cfg
+
+		gen, err := storage.ReadGeneration(storage.GenerationPath(cfg.Lexical.IndexPath))
+		if err != nil {
+			return util.WrapError(err, "Failed to read index generation")
+		}
+		if gen.Number == 0 || reindex {
+			files, err := indexQuickstart(context.Background(), cfg)
+			if err != nil {
+				return err
+			}
+			slog.Info("Demo corpus indexed", "files", files)
+		} else {
+			slog.Info("Reusing existing index, pass --reindex to rebuild it", "generation", gen.Number)
+		}
+
+		fmt.Println()
+		fmt.Printf("Semango is set up in %s. Try:\n\n", dir)
+		for _, q := range quickstart.SampleQueries {
+			fmt.Printf("  semango search %q\n", q)
+		}
+		if noServe {
+			fmt.Printf("\nStart the server with: cd %s && semango server\n\n", dir)
+			return nil
+		}
+		fmt.Printf("\nor open http://%s:%d in your browser. Press Ctrl+C to stop.\n\n", cfg.Server.Host, cfg.Server.Port)
+		return runServer(cfg, config.DefaultConfigPath, false)
+	},
+}
+
+// indexQuickstart indexes the sources of cfg, here the demo corpus, and
+// returns the number of files indexed.
+func indexQuickstart(ctx context.Context, cfg *config.Config) (int, error) {
+	slog.Info("Loading the local embedding model, the first run downloads it", "model", cfg.Embedding.LocalModelPath)
+	embedder, err := ingest.NewEmbedderFromConfig(cfg.Embedding)
+	if err != nil {
+		return 0, util.WrapError(err, "Failed to create embedder")
+	}
+	sources, err := source.FromConfig(cfg)
+	if err != nil {
+		return 0, util.WrapError(err, "Invalid sources configuration")
+	}
+	mgr := pipeline.NewManager(cfg, embedder)
+	if err := mgr.Err(); err != nil {
+		return 0, err
+	}
+	files := 0
+	for _, src := range sources {
+		err := src.Walk(ctx, func(relPath, absPath string) error {
+			if err := mgr.ProcessFile(ctx, relPath, absPath); err != nil {
+				return util.WrapError(err, "Failed to process file", slog.String("path", relPath))
+			}
+			files++
+			return nil
+		})
+		if err != nil {
+			return files, util.WrapError(err, "Indexing the demo corpus failed", slog.String("source", src.Name()))
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/omarkamali/semango/internal/api"
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// configPollInterval is how often server.watch_config checks the
+// configuration file for changes.
+const configPollInterval = 2 * time.Second
+
+// reloadOnChange reloads the configuration file at path into server on
+// SIGHUP and, with watch set, whenever the file's modification time or
+// size changes, until ctx is done. A file that fails to load or changes
+// settings that cannot be reloaded is logged and the running settings stay.
+func reloadOnChange(ctx context.Context, server *api.Server, path string, watch bool) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	last, _ := os.Stat(path)
+	if watch {
+		ticker := time.NewTicker(configPollInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			slog.Info("Received SIGHUP, reloading configuration", "path", path)
+		case <-tick:
+			info, err := os.Stat(path)
+			if err != nil || (last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()) {
+				continue
+			}
+			last = info
+			slog.Info("Configuration file changed, reloading", "path", path)
+		}
+
+		next, err := config.Load(path, schemaPath, configOverrides...)
+		if err != nil {
+			util.LogError(util.Logger, util.WrapError(err, "Configuration not reloaded", slog.String("path", path)))
+			continue
+		}
+		if err := server.Reload(ctx, next); err != nil {
+			util.LogError(util.Logger, util.WrapError(err, "Configuration not reloaded", slog.String("path", path)))
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/omarkamali/semango/internal/report"
+	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/util"
+	"github.com/spf13/cobra"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Build an index health report.",
+	Long: `Builds a health report of the indexes: what they hold, orphaned vectors, and how they grew since
+the last report saved to the report directory (report.dir, else reports/ next to the lexical index).
+Reports built here carry no request statistics; a running server with report.interval set saves
+reports that do, and serves the current one at /api/v1/report.
+
+With --save the report is written to the report directory as JSON and HTML, next to the older ones.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if AppConfig == nil {
+			cfgErr := util.NewError("Configuration not loaded before report command")
+			util.LogError(util.Logger, cfgErr)
+			return cfgErr
+		}
+		asJSON, _ := cmd.Flags().GetBool("json")
+		asHTML, _ := cmd.Flags().GetBool("html")
+		save, _ := cmd.Flags().GetBool("save")
+
+		stats, err := search.IndexStats(context.Background(), AppConfig)
+		if err != nil {
+			return util.WrapError(err, "Failed to read index statistics", slog.String("index", AppConfig.Lexical.IndexPath))
+		}
+		dir := report.Dir(AppConfig)
+		prev, err := report.Latest(dir)
+		if err != nil {
+			slog.Warn("Previous health report unreadable; growth left out", "error", err)
+		}
+		r := report.New(time.Now(), stats, prev, nil)
+
+		if save {
+			path, err := report.Save(dir, r)
+			if err != nil {
+				return util.WrapError(err, "Failed to save health report", slog.String("dir", dir))
+			}
+			fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+		}
+		switch {
+		case asJSON:
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(r)
+		case asHTML:
+			return r.WriteHTML(os.Stdout)
+		}
+		if err := printStats(stats); err != nil {
+			return err
+		}
+		if g := r.Growth; g != nil {
+			fmt.Printf("\nSince %s: %+d files, %+d chunks, %+d vectors, %+d bytes\n",
+				g.Since.Local().Format("2006-01-02 15:04:05 MST"), g.Documents, g.Chunks, g.Vectors, g.IndexBytes)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Create, list and restore index snapshots.",
+	Long: `Snapshots are index bundles kept in the snapshot directory (snapshots/ next to the lexical
+index). Each holds the lexical index, the default vector index with its ID mapping, the generation
+stamp and a manifest with a SHA-256 checksum of every file. The server and 'semango search --as-of'
+can search them by name.`,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Pack the current indexes into a named snapshot.",
+	Long: `Writes the indexes into <snapshot dir>/<name>.tar.gz. The name defaults to the index
+generation and the time, e.g. g42-20240501T120000Z. Existing snapshots are never overwritten, and
+the snapshot fails rather than mixing states if the index is written to while it is packed.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := snapshotConfig(cmd)
+		if err != nil {
+			return err
+		}
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		name, m, err := pipeline.CreateSnapshot(cfg, name, storage.BundleManifest{SemangoVersion: version})
+		if err != nil {
+			return util.WrapError(err, "Failed to create snapshot")
+		}
+		slog.Info("Snapshot created", "name", name, "generation", m.Generation.Number, "files", len(m.Files), "dir", pipeline.SnapshotDir(cfg))
+		fmt.Println(name)
+		return nil
+	},
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List snapshots, newest first.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := snapshotConfig(cmd)
+		if err != nil {
+			return err
+		}
+		verify, _ := cmd.Flags().GetBool("verify")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		snaps, err := pipeline.ListSnapshots(cfg, verify)
+		if err != nil {
+			return util.WrapError(err, "Failed to list snapshots", slog.String("dir", pipeline.SnapshotDir(cfg)))
+		}
+		bad := 0
+		for _, s := range snaps {
+			if s.Err != nil {
+				bad++
+			}
+		}
+
+		if asJSON {
+			type entry struct {
+				pipeline.SnapshotInfo
+				Error string `json:"error,omitempty"`
+			}
+			out := make([]entry, len(snaps))
+			for i, s := range snaps {
+				out[i] = entry{SnapshotInfo: s}
+				if s.Err != nil {
+					out[i].Error = s.Err.Error()
+				}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(out); err != nil {
+				return err
+			}
+		} else if len(snaps) == 0 {
+			fmt.Printf("No snapshots in %s.\n", pipeline.SnapshotDir(cfg))
+		} else {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tGENERATION\tCREATED\tSIZE\tMODEL\tSTATUS")
+			for _, s := range snaps {
+				status := "ok"
+				if s.Err != nil {
+					status = s.Err.Error()
+				} else if verify && s.Manifest.Files == nil {
+					status = "ok (no checksums)"
+				}
+				created := "-"
+				if !s.Manifest.CreatedAt.IsZero() {
+					created = s.Manifest.CreatedAt.Format("2006-01-02 15:04:05")
+				}
+				fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n", s.Name, s.Manifest.Generation.Number, created,
+					formatByteSize(s.Size), s.Manifest.EmbeddingProvider+"/"+s.Manifest.EmbeddingModel, status)
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+		}
+		if bad > 0 {
+			return util.NewError(fmt.Sprintf("%d snapshot(s) are damaged", bad))
+		}
+		return nil
+	},
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <name|file>",
+	Short: "Replace the indexes with a snapshot, or restore it into a directory.",
+	Long: `Checks the checksums of the snapshot (a name from 'snapshot list' or a bundle file) and
+replaces the local lexical and vector indexes with it. With --dir the indexes are restored into an
+empty directory instead, as <dir>/bleve and <dir>/faiss.index, leaving the local ones alone.
+Snapshots built with a different embedding provider or model are refused unless --force is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := snapshotConfig(cmd)
+		if err != nil {
+			return err
+		}
+		dir, _ := cmd.Flags().GetString("dir")
+		force, _ := cmd.Flags().GetBool("force")
+
+		m, err := pipeline.RestoreSnapshot(cfg, args[0], dir, force)
+		if err != nil {
+			return util.WrapError(err, "Failed to restore snapshot", slog.String("snapshot", args[0]))
+		}
+		slog.Info("Snapshot restored",
+			"snapshot", args[0],
+			"generation", m.Generation.Number,
+			"built_at", m.CreatedAt,
+			"embedding_model", m.EmbeddingModel)
+		if dir != "" {
+			fmt.Printf("Restored the lexical index into %s and the vector index into %s.\n",
+				filepath.Join(dir, "bleve"), storage.VectorIndexPath(dir, ""))
+		}
+		return nil
+	},
+}
+
+// snapshotConfig returns the configuration of the --collection flag's
+// collection, or the loaded configuration.
+func snapshotConfig(cmd *cobra.Command) (*config.Config, error) {
+	if AppConfig == nil {
+		cfgErr := util.NewError("Configuration not loaded before snapshot command")
+		util.LogError(util.Logger, cfgErr)
+		return nil, cfgErr
+	}
+	collection, _ := cmd.Flags().GetString("collection")
+	return collectionConfig(collection)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show what the indexes hold.",
+	Long: `Counts the files and chunks in the lexical index, by modality and by file extension, the
+vectors in each vector index, the size of the indexes on disk, the embedding model and dimension,
+how vectors are compressed and what that costs in accuracy, and the generation and time of the last
+index run. Every chunk is read once, so this takes a moment on large indexes. No embedding model is
+loaded.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if AppConfig == nil {
+			cfgErr := util.NewError("Configuration not loaded before stats command")
+			util.LogError(util.Logger, cfgErr)
+			return cfgErr
+		}
+		collection, _ := cmd.Flags().GetString("collection")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		cfg, err := collectionConfig(collection)
+		if err != nil {
+			return err
+		}
+
+		stats, err := search.IndexStats(context.Background(), cfg)
+		if err != nil {
+			return util.WrapError(err, "Failed to read index statistics", slog.String("index", cfg.Lexical.IndexPath))
+		}
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(stats)
+		}
+		return printStats(stats)
+	},
+}
+
+// printStats prints stats as a list of labelled values.
+func printStats(stats *search.Stats) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Files:\t%d\n", stats.TotalDocuments)
+	fmt.Fprintf(tw, "Chunks:\t%d\n", stats.TotalChunks)
+	for _, k := range sortedKeys(stats.ChunksByModality) {
+		fmt.Fprintf(tw, "  %s:\t%d\n", k, stats.ChunksByModality[k])
+	}
+	fmt.Fprintf(tw, "Vectors:\t%d\n", stats.Vectors)
+	spaces := make([]string, 0, len(stats.SpaceVectors))
+	for name := range stats.SpaceVectors {
+		spaces = append(spaces, name)
+	}
+	sort.Strings(spaces)
+	for _, name := range spaces {
+		fmt.Fprintf(tw, "  space %s:\t%d\n", name, stats.SpaceVectors[name])
+	}
+	if stats.OrphanedVectors > 0 {
+		fmt.Fprintf(tw, "  orphaned:\t%d (run semango index --recreate to drop them)\n", stats.OrphanedVectors)
+	}
+	fmt.Fprintf(tw, "Extensions:\t\n")
+	for _, k := range sortedKeys(stats.FilesByExtension) {
+		ext := k
+		if ext == "" {
+			ext = "(none)"
+		}
+		fmt.Fprintf(tw, "  %s:\t%d\n", ext, stats.FilesByExtension[k])
+	}
+	fmt.Fprintf(tw, "Index size:\t%s (lexical %s, vectors %s)\n", formatByteSize(stats.IndexSize),
+		formatByteSize(stats.LexicalIndexSize), formatByteSize(stats.VectorIndexSize))
+	fmt.Fprintf(tw, "Embedding model:\t%s/%s\n", stats.EmbeddingProvider, stats.EmbeddingModel)
+	if configured := stats.EmbeddingProvider + "/" + stats.EmbeddingModel; stats.IndexedWith != "" && stats.IndexedWith != configured {
+		fmt.Fprintf(tw, "Indexed with:\t%s (run semango index --recreate to re-embed with %s)\n", stats.IndexedWith, configured)
+	}
+	fmt.Fprintf(tw, "Dimension:\t%d\n", stats.Dimension)
+	if stats.Compression != "" {
+		fmt.Fprintf(tw, "Compression:\t%s, %s per vector (%s)\n", stats.Compression,
+			formatByteSize(stats.BytesPerVector), storage.CompressionTradeoff(stats.Compression))
+	}
+	indexed := "never"
+	if stats.IndexedAt != nil {
+		indexed = stats.IndexedAt.Format("2006-01-02 15:04:05 MST")
+	}
+	fmt.Fprintf(tw, "Last indexed:\t%s (generation %d)\n", indexed, stats.Generation)
+	return tw.Flush()
+}
+
+// sortedKeys returns the keys of counts, most counted first.
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/omarkamali/semango/internal/suggest"
+	"github.com/omarkamali/semango/internal/util"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var suggestConfigCmd = &cobra.Command{
+	Use:   "suggest-config [dir]",
+	Short: "Recommend include/exclude patterns and chunking for a directory.",
+	Long: `Scans dir (default: the current directory), reports its files by extension and prints
+recommended files settings for semango.yml: include patterns for the file types Semango can read,
+exclude patterns for dependency, build and generated files found, and chunk sizes suited to the
+files. --write sets them in the configuration file (see --config), creating it if needed; the rest
+of the file is left as it is.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		write, _ := cmd.Flags().GetBool("write")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		report, err := suggest.Scan(dir)
+		if err != nil {
+			return util.WrapError(err, "Failed to scan directory", slog.String("dir", dir))
+		}
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		} else if err := printSuggestion(os.Stdout, report); err != nil {
+			return err
+		}
+
+		if write {
+			configPath, _ := cmd.Flags().GetString("config")
+			if err := report.WriteConfig(configPath); err != nil {
+				return util.WrapError(err, "Failed to write configuration", slog.String("path", configPath))
+			}
+			slog.Info("Suggested files settings written", "path", configPath)
+		}
+		return nil
+	},
+}
+
+// printSuggestion writes the file type distribution of r and its
+// recommended files section as YAML.
+func printSuggestion(w io.Writer, r *suggest.Report) error {
+	fmt.Fprintf(w, "%d files, %s\n\n", r.Files, formatByteSize(r.Bytes))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "EXT\tKIND\tFILES\tSIZE")
+	for _, e := range r.Extensions {
+		ext, kind := e.Ext, e.Kind
+		if ext == "" {
+			ext = "(none)"
+		}
+		if kind == "" {
+			kind = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", ext, kind, e.Files, formatByteSize(e.Bytes))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(r.Skipped) > 0 {
+		fmt.Fprintln(w, "\nSkipped directories:")
+		for _, d := range r.Skipped {
+			fmt.Fprintf(w, "  %s\n", d)
+		}
+	}
+
+	var opt []string
+	for _, e := range r.Extensions {
+		if e.Kind == suggest.KindVideo || e.Kind == suggest.KindArchive {
+			opt = append(opt, "**/*"+e.Ext)
+		}
+	}
+	var section struct {
+		Files struct {
+			Include      []string `yaml:"include"`
+			Exclude      []string `yaml:"exclude"`
+			ChunkSize    int      `yaml:"chunk_size"`
+			ChunkOverlap int      `yaml:"chunk_overlap"`
+		} `yaml:"files"`
+	}
+	section.Files.Include, section.Files.Exclude = r.Include, r.Exclude
+	section.Files.ChunkSize, section.Files.ChunkOverlap = r.ChunkSize, r.ChunkOverlap
+	fmt.Fprintf(w, "\nSuggested settings (chunking: %s):\n\n", r.ChunkReason)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(section); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if len(opt) > 0 {
+		fmt.Fprintf(w, "\nVideo and archives are left out; add %s to files.include to index them.\n", strings.Join(opt, ", "))
+	}
+	return nil
+}
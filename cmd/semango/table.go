@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest/tabular"
+	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/util"
+	"github.com/spf13/cobra"
+)
+
+var tableCmd = &cobra.Command{
+	Use:   "table",
+	Short: "List and query the indexed tabular files with SQL.",
+	Long: `Search finds the dataset, SQL answers the precise question: the CSV, TSV, JSON, Parquet, Avro,
+ORC and Excel files and SQLite tables in the index can be queried with read-only SQL. Each is a table named
+after its file (or SQLite table), e.g. orders for data/orders.csv; 'semango table list' shows the
+names. The files are read from disk when queried, so they must still be there.`,
+}
+
+var tableListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the indexed datasets, their table names and columns.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := tableConfig(cmd)
+		if err != nil {
+			return err
+		}
+		asJSON, _ := cmd.Flags().GetBool("json")
+		summaries, datasets, err := indexedDatasets(context.Background(), cfg)
+		if err != nil {
+			return err
+		}
+		type entry struct {
+			tabular.Dataset
+			Schema string `json:"schema"`
+		}
+		out := make([]entry, len(datasets))
+		for i, d := range datasets {
+			out[i] = entry{Dataset: d, Schema: summaries[i].Schema}
+		}
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(out)
+		}
+		if len(out) == 0 {
+			fmt.Println("No tabular files are indexed.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "TABLE\tPATH\tCOLUMNS")
+		for _, e := range out {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Table, e.Path, e.Schema)
+		}
+		return tw.Flush()
+	},
+}
+
+var tableQueryCmd = &cobra.Command{
+	Use:   "query <sql>",
+	Short: "Run a read-only SQL query over the indexed datasets.",
+	Long: `Runs a single SELECT statement over the datasets it names, e.g.
+
+  semango table query "SELECT customer, SUM(amount) FROM orders WHERE amount > 1000 GROUP BY customer"
+
+The datasets are loaded into an in-memory SQLite database, up to a million rows each, redacting the
+columns tabular.redact and tabular.detect_pii name as the index does. Columns detected as numeric
+compare as numbers; empty values are NULL. A dataset can also be named by its quoted path, e.g.
+"data/orders.csv".`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := tableConfig(cmd)
+		if err != nil {
+			return err
+		}
+		maxRows, _ := cmd.Flags().GetInt("max-rows")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		ctx := context.Background()
+		_, datasets, err := indexedDatasets(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		res, err := tabular.Query(ctx, args[0], datasets, cfg.Tabular, maxRows)
+		if err != nil {
+			return util.WrapError(err, "Query failed")
+		}
+		for _, p := range res.Partial {
+			slog.Warn("Only the first rows of a dataset were queried", "path", p, "rows", tabular.MaxQueryRows)
+		}
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(res)
+		}
+		return printQueryResult(res)
+	},
+}
+
+// tableConfig returns the configuration of the collection named by the
+// --collection flag, or the default one.
+func tableConfig(cmd *cobra.Command) (*config.Config, error) {
+	if AppConfig == nil {
+		cfgErr := util.NewError("Configuration not loaded before table command")
+		util.LogError(util.Logger, cfgErr)
+		return nil, cfgErr
+	}
+	collection, _ := cmd.Flags().GetString("collection")
+	return collectionConfig(collection)
+}
+
+// indexedDatasets lists the tabular datasets in the indexes of cfg, their
+// files being relative to the working directory like local sources.
+func indexedDatasets(ctx context.Context, cfg *config.Config) ([]search.DatasetSummary, []tabular.Dataset, error) {
+	summaries, err := search.ListDatasets(ctx, cfg)
+	if err != nil {
+		return nil, nil, util.WrapError(err, "Failed to list indexed datasets", slog.String("index", cfg.Lexical.IndexPath))
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		return nil, nil, err
+	}
+	paths := make([]string, len(summaries))
+	for i, s := range summaries {
+		paths[i] = s.Path
+	}
+	return summaries, tabular.NewDatasets(root, paths), nil
+}
+
+// printQueryResult prints res as a table, NULL values as NULL.
+func printQueryResult(res *tabular.QueryResult) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(res.Columns, "\t"))
+	for _, row := range res.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			if v == nil {
+				cells[i] = "NULL"
+			} else {
+				// Tabs and newlines would break the columns.
+				cells[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(fmt.Sprint(v))
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if res.Truncated {
+		fmt.Printf("(first %d rows; raise --max-rows for more)\n", len(res.Rows))
+	}
+	return nil
+}
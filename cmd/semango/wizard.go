@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/util"
+	"github.com/omarkamali/semango/internal/wizard"
+)
+
+// runInitWizard asks the questions of `semango init --interactive` on the
+// terminal and writes the resulting configuration to configPath.
+func runInitWizard(configPath string) error {
+	p := wizard.NewPrompter(os.Stdin, os.Stdout)
+	if _, err := os.Stat(configPath); err == nil {
+		if !p.Confirm(configPath+" exists. Overwrite it?", false) {
+			return nil
+		}
+	}
+
+	fmt.Println("Looking at this directory and the model cache...")
+	env, err := wizard.Detect(".", config.ExpandEnv(config.GetDefaultConfig().Embedding.ModelCacheDir), ingest.DefaultModelCacheDir())
+	if err != nil {
+		return util.WrapError(err, "Failed to inspect the environment")
+	}
+	cfg := wizard.Run(p, env)
+	if err := config.WriteConfig(configPath, cfg); err != nil {
+		wrappedErr := util.WrapError(err, "Failed to write config", slog.String("path", configPath))
+		util.LogError(util.Logger, wrappedErr)
+		return wrappedErr
+	}
+	// Load what was written, so that a file every command would refuse is
+	// reported here.
+	if _, err := config.Load(configPath, schemaPath); err != nil {
+		return util.WrapError(err, "The written configuration is invalid", slog.String("path", configPath))
+	}
+	fmt.Printf("\nWrote %s. Next: semango index, then semango server (or semango doctor to check the setup).\n", configPath)
+	return nil
+}
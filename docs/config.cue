@@ -6,23 +6,57 @@ package config
 #Config: {
 	embedding: #EmbeddingConfig
 	lexical:   #LexicalConfig
+	vector?:   #VectorConfig
+	vector_store?: #VectorStoreConfig // Where vectors are kept; FAISS index files by default
 	reranker:  #RerankerConfig
 	hybrid:    #HybridConfig
+	search?:   #SearchConfig
 	files:     #FilesConfig
 	server:    #ServerConfig
 	plugins?:  [...string] // Optional, list of strings
 	ui:        #UIConfig
 	mcp:       #MCPConfig
 	tabular:   #TabularConfig
+	links?:    #LinksConfig
+	media?:    #MediaConfig
+	git?:      #GitConfig
+	llm?:      #LLMConfig // Chat model for answers grounded in search results
+	report?:   #ReportConfig // Index health reports written by the server
+	sources?:  [...#SourceConfig] // Where to index from; defaults to the working directory
+	collections?: [=~"^[A-Za-z0-9_-]+$"]: #CollectionConfig // Further named corpora, each with its own indexes
+	log_level?: *"" | "debug" | "info" | "warn" | "error" // Default: "" (debug); reloaded by a running server
 }
 
 #EmbeddingConfig: {
 	provider:         string | *"local" | "openai" | "cohere" | "voyage" // Default: local
 	model:            string // Example: text-embedding-3-large
 	local_model_path: string | *"models/e5-small.gguf" // Default: models/e5-small.gguf
+	dimensions:       int & >=0 | *0 // Shorten text-embedding-3 vectors to this many dimensions, e.g. 256, 512 or 1024; 0 = the model's full dimension
 	batch_size:       int & >=1 & <=512 | *48 // Default: 48
 	concurrent:       int & >=1 | *4          // Default: 4
 	model_cache_dir:  string // Removed default from here, as it's in semango.yml
+	local_threads:    int & >=0 | *0 // Intra-op threads per local ONNX session; 0 splits cores across `concurrent` sessions
+	languages?: [string]: #LanguageEmbeddingConfig // Per-language query embedders, keyed by ISO 639-1 code
+	spaces?: [=~"^[A-Za-z0-9_-]+$" & !="default"]: #SpaceEmbeddingConfig // Extra vector spaces written alongside the default one
+}
+
+// Overrides for embedding queries that carry a matching lang hint. Empty
+// fields inherit from #EmbeddingConfig; the model must share its vector space.
+#LanguageEmbeddingConfig: {
+	provider:         *"" | "local" | "openai" | "cohere" | "voyage"
+	model:            string | *""
+	local_model_path: string | *""
+}
+
+// A named vector space for comparing embedding models. Its vectors are
+// dual-written to faiss-<name>.index next to the default index and searched
+// when a request selects the space. Empty fields inherit from #EmbeddingConfig,
+// except dimensions.
+#SpaceEmbeddingConfig: {
+	provider:         *"" | "local" | "openai" | "cohere" | "voyage"
+	model:            string | *""
+	local_model_path: string | *""
+	dimensions:       int & >=0 | *0 // Shortened text-embedding-3 dimension of this space; 0 = the model's full dimension
 }
 
 #LexicalConfig: {
@@ -30,6 +64,22 @@ package config
 	index_path: string // Removed default from here
 	bm25_k1:    float  | *1.2                      // Default: 1.2
 	bm25_b:     float  | *0.75                     // Default: 0.75
+	code_token_filter: *"" | "split_identifiers" | "none" // Default: "" (split_identifiers); splits getUserByID into get/user/by/id for code chunks
+	analyzers?: [=~"^(text|path|meta\\..+)$"]: "standard" | "en" | "code" // Analyzer per field; standard by default, en stems English, code splits camelCase/snake_case. Changes rebuild the lexical index
+}
+
+#VectorConfig: {
+	index_path:       string | *""                                  // FAISS index file of the default vector space; "" = ./semango/index/faiss.index. Its .ids.json map and the faiss-<space>.index files of embedding.spaces go next to it
+	compression:      *"" | "none" | "float16" | "int8" | "pq"      // How vector indexes store vectors, none being float32; writes convert indexes to it. "" keeps that of existing indexes and creates float32 ones. int8 and pq trade accuracy for size
+	pq_subquantizers: int & >=0 | *0                                // Bytes per vector with pq; must divide the dimension. 0 = about dimension/8
+}
+
+#VectorStoreConfig: {
+	backend:     *"" | "faiss" | "qdrant" | "pgvector" | "milvus" // "" = faiss, a pure-Go index in builds without CGO on linux/amd64; the others are vector databases
+	url:         string | *""                                    // Qdrant or Milvus HTTP address (e.g. http://localhost:6333), or the PostgreSQL connection URL for pgvector; ${VAR} is expanded
+	api_key_env: string | *""                                    // Env var holding the Qdrant API key or the Milvus token
+	driver:      string | *""                                    // database/sql driver for pgvector; "" = pgx, which semango links
+	collection:  =~"^[A-Za-z0-9_-]*$" | *""                      // Collection or table of the default vector space; "" = semango. Spaces and collections append _<name>
 }
 
 #RerankerConfig: {
@@ -47,10 +97,40 @@ package config
 }
 
 #FilesConfig: {
-	include: [...string] | *["**/*.md", "**/*.go", "**/*.{png,jpg,jpeg}", "**/*.pdf", "**/*.csv", "**/*.json", "**/*.jsonl", "**/*.parquet"]
+	include: [...string] | *["**/*.md", "**/*.go", "**/*.{png,jpg,jpeg}", "**/*.pdf", "**/*.{docx,odt,rtf}", "**/*.epub", "**/*.{eml,mbox}", "**/*.ipynb", "**/*.csv", "**/*.json", "**/*.jsonl", "**/*.parquet"]
 	exclude: [...string] | *[".git/**", "node_modules/**", "vendor/**"]
 	chunk_size: int | *1000
 	chunk_overlap: int | *200
+	chunking: *"" | "fixed" | "sentence" | "semantic" // How text is cut: fixed size, whole sentences, or sentences grouped by embedding similarity; "" = fixed
+	semantic_percentile: int & >=0 & <=100 | *0 // Semantic chunking breaks where neighbouring sentences differ more than at this percentile; 0 = 95
+	archive_max_bytes: int & >=0 | *0 // Total bytes extracted per archive; 0 = 500 MiB
+	archive_max_depth: int & >=0 | *0 // Nesting depth of archives in archives; 0 = 3
+	parent_chunk_size: int & >=0 | *0 // Bytes per parent section returned by `parents` searches; 0 = off
+	post_processors?: [...#PostProcessorConfig] // Run on each document's chunks before embedding, in order
+	rules?: [...#FileRule] // Per-path loader and chunking; every matching rule applies, later ones win
+	dedup: *"" | "exact" | "near" // Index one copy of duplicated chunks; search results list the other paths as aliases
+	dedup_distance: int & >=0 & <=3 | *0 // Bits two SimHashes of near duplicates may differ in; 0 = 3
+	detect_language: bool | *false // Detect the language of text chunks without a "lang" and index them with its analyzer
+	respect_gitignore: bool | *false // Leave out what .gitignore files ignore; semango init turns it on. .semangoignore files are always read
+	follow_symlinks: bool | *false // Walk into symlinked directories whose target was not crawled already; symlinked files are always indexed
+	skip_hidden: bool | *false // Leave out files and directories whose name starts with a dot
+	max_depth: int & >=0 | *0 // Directory levels crawled, 1 = only the files of the root; 0 = no limit
+	max_file_size: int & >=0 | *0 // Skip files larger than this many bytes; 0 = no limit (code files: 5 MiB)
+}
+
+#FileRule: {
+	path:          string & !="" // Path pattern relative to the root, e.g. "docs/**" or "**/*.go"
+	loader:        string | *"" // text, markdown, latex, rst, code, pdf, image, office, epub, email, notebook, config, schema, hcl, video, csv, json, parquet, avro, orc, sqlite, excel or archive
+	chunking:      *"" | "fixed" | "sentence" | "semantic" // Overrides files.chunking
+	chunk_size:    int & >=0 | *0 // Overrides files.chunk_size; 0 keeps it
+	chunk_overlap: int & >=0 | *0 // Overrides files.chunk_overlap; 0 keeps it
+	strip_imports: bool | *false // Code loader: leave import statements out of chunks
+}
+
+#PostProcessorConfig: {
+	name:        string & !="" // trim_boilerplate, breadcrumbs, set_meta, redact_secrets or a custom registered name
+	modalities?: [...string] // Only process chunks of these modalities, e.g. ["text"]; default all
+	options?: [string]: string // Processor options, e.g. pattern for trim_boilerplate
 }
 
 #ServerConfig: {
@@ -59,11 +139,30 @@ package config
 	auth: #AuthConfig
 	tls_cert?: string // Optional
 	tls_key?: string  // Optional, added based on common practice
+	probe_interval: int & >=0 | *0 // Seconds between embedder health probes; 0 = 60
+	probe_failures: int & >=0 | *0 // Consecutive probe failures before /api/v1/ready reports 503; 0 = 3
+	access_log?: #AccessLogConfig
+	rate_limit?: #RateLimitConfig
+	trusted_proxies?: [...string] // IPs or CIDRs of reverse proxies whose X-Forwarded-For / X-Real-IP headers are trusted; none by default
+	search_timeout: int & >=0 | *0 // Seconds a search may run, including the wait for a slot, before it fails with 504; 0 = no limit
+	max_concurrent_searches: int & >=0 | *0 // Searches run at once; others wait for a slot. 0 = no limit
+	watch_config: bool | *false // Reload the configuration file when it changes, like SIGHUP
 }
 
 #AuthConfig: {
-	type:      string | *"token"          // Default: token
-	token_env: string | *"SEMANGO_TOKENS" // Default: SEMANGO_TOKENS
+	type:             string | *"token"          // "token" or "none"; token auth is off while token_env holds no tokens
+	token_env:        string | *"SEMANGO_TOKENS" // Default: SEMANGO_TOKENS
+	admin_token_env?: string                     // Env var with the tokens of admin endpoints (PUT /api/v1/embedder); unset = admin endpoints disabled
+}
+
+#AccessLogConfig: {
+	sample_rate:    number & >=0 & <=1 | *0 // Fraction of requests written to the access log; 0 = all. 5xx responses are always logged
+	redact_queries: bool | *false           // Log a hash of the query text instead of the text itself
+}
+
+#RateLimitConfig: {
+	rps:   number & >=0 | *0 // Requests per second per bearer token, or per client IP without one; 0 = no limit
+	burst: int & >=0 | *0    // Requests allowed at once before throttling; 0 = rps rounded up
 }
 
 #UIConfig: {
@@ -75,8 +174,105 @@ package config
 }
 
 #TabularConfig: {
-	max_rows_embedded: int & >=1 | *50000
-	sampling:          string | *"random" | "stratified"
-	min_text_tokens:   int & >=1 | *5
-	delimiter?:        string | *","  // CSV delimiter; "\t" for TSV
-} 
\ No newline at end of file
+	max_rows_embedded:  int & >=1 | *50000
+	sampling:           string | *"random" | "stratified" | "all" // "all" embeds every row, ignoring max_rows_embedded
+	min_text_tokens:    int & >=1 | *5
+	delimiter?:         string | *","  // CSV delimiter; "\t" for TSV
+	numbers_in_text:    bool | *false // Add numeric and date values to the embedded text of rows
+	redact?:            [...#ColumnRedaction] // Columns of personal data to hide before rows become text and metadata
+	detect_pii:         *"" | "mask" | "hash" | "drop" // For columns mostly of emails, phone numbers or SSNs; init writes "mask"
+	hash_salt:          string | *"" // Hashed with the values of hashed columns
+	max_rows_per_sheet: int & >=0 | *0 // Rows embedded from each spreadsheet sheet; 0 shares max_rows_embedded between the sheets
+	sampling_seed:      int | *0 // Seed of random sampling; 0 seeds it with a hash of each file's rows
+}
+
+#ColumnRedaction: {
+	columns: [...string & !=""] // Names or patterns, e.g. "*email*", regardless of case
+	action:  *"" | "mask" | "hash" | "drop" // "" masks: [REDACTED]
+}
+
+#LinksConfig: {
+	template: string | *"" // e.g. "vscode://file/{abs_path}:{line}"; empty disables links
+	root:     string | *"" // Base directory for {abs_path}; defaults to the working directory
+	branch:   string | *"" // Value for {branch}; defaults to "main"
+}
+
+// External tools used by the video loader (.mp4, .mkv, .webm).
+#MediaConfig: {
+	ffmpeg_path:       string | *""       // Defaults to "ffmpeg" on PATH
+	whisper_path:      string | *""       // whisper.cpp CLI; defaults to "whisper-cli" on PATH
+	whisper_model:     string | *""       // ggml model file; empty disables transcription
+	whisper_language:  string | *""       // Spoken language; defaults to "auto"
+	segment_seconds:   int & >=0 | *0     // Transcript window per chunk; 0 means 30
+	keyframes:         bool | *false      // Emit image representations of keyframes
+	keyframe_interval: int & >=0 | *0     // Seconds between keyframes; 0 means 60
+}
+
+#SearchConfig: {
+	default_mode: *"" | "hybrid" | "lexical" | "vector" // Mode for queries that set none; "" = hybrid
+	hybrid:       #SearchModeConfig
+	lexical:      #SearchModeConfig // Lexical-only queries skip embedding entirely
+	vector:       #SearchModeConfig
+	max_text_length: int & >=0 | *0 // Characters of text per result in API and CLI output; 0 = whole chunk
+	snippet_length: int & >=0 | *0 // Characters of the snippet API results carry instead of their chunk; 0 = 240
+	include_full_text: bool | *false // Return the chunk text of API results as if every request set include_full_text
+	quality_prior: number & >=0 & <=1 | *0 // Weight of the ingest-time chunk quality score in ranking; 0 = ignored
+	adaptive_drop: number & >=0 & <=1 | *0 // Score drop, as a fraction of the top score, that ends an adaptive search; 0 = 0.2
+}
+
+#SearchModeConfig: {
+	top_k: int & >=0 | *0 // Results for queries that set no top_k; 0 = 10
+}
+
+#GitConfig: {
+	history:        bool | *false     // Index commit messages when run inside a git repository
+	diffs:          bool | *false     // Also index each commit's diff
+	max_commits:    int & >=0 | *0    // Most recent commits to index; 0 = 1000
+	max_diff_bytes: int & >=0 | *0    // Diff bytes kept per commit; 0 = 8000
+}
+
+#LLMConfig: {
+	provider:    *"" | "openai"         // "" = openai; any OpenAI-compatible API works via base_url
+	model:       string | *""           // Chat model, e.g. gpt-4o-mini; "" disables answers
+	base_url:    string | *""           // "" = https://api.openai.com/v1; e.g. http://localhost:11434/v1 for Ollama
+	api_key_env: string | *""           // Env var with the API key; "" = OPENAI_API_KEY
+	max_context: int & >=0 | *0         // Tokens of source text packed into a prompt; 0 = 6000
+	top_k:       int & >=0 & <=100 | *0 // Chunks retrieved per question; 0 = 8
+	max_tokens:  int & >=0 | *0         // Answer length limit; 0 = provider default
+}
+
+#ReportConfig: {
+	interval: int & >=0 | *0 // Hours between reports, e.g. 24; 0 = no reports
+	dir:      string | *""   // "" = reports/ next to lexical.index_path
+}
+
+#SourceConfig: {
+	type:           "local" | "s3" | "gcs" | "sitemap"
+	path:           string | *""      // local: directory to crawl; "" = working directory
+	bucket:         string | *""      // s3/gcs: bucket name
+	prefix:         string | *""      // s3/gcs: only keys under this prefix
+	region:         string | *""      // s3: "" = $AWS_REGION or us-east-1
+	endpoint:       string | *""      // s3: S3-compatible endpoint (MinIO, R2); gcs uses storage.googleapis.com
+	access_key_env: string | *""      // Env var with the access key; "" = AWS_ACCESS_KEY_ID (s3) or GCS_HMAC_ACCESS_KEY (gcs)
+	secret_key_env: string | *""      // Env var with the secret; "" = AWS_SECRET_ACCESS_KEY (s3) or GCS_HMAC_SECRET (gcs)
+	url:            string | *""      // sitemap: URL of sitemap.xml
+	max_pages:      int & >=0 | *0    // sitemap: pages to fetch; 0 = 1000
+	include?:       [...string]       // Globs over keys or URL paths; default files.include (local, s3, gcs)
+	exclude?:       [...string]       // Globs to skip; default files.exclude (local, s3, gcs)
+}
+
+#CollectionConfig: {
+	include?:   [...string]          // Default files.include
+	exclude?:   [...string]          // Default files.exclude
+	sources?:   [...#SourceConfig]   // Default the top-level sources
+	embedding?: #SpaceEmbeddingConfig // Model of the collection; empty fields inherit from embedding
+	index_dir:  string | *""         // Lexical and vector indexes; "" = semango/collections/<name>
+	weight:     number & >=0 | *0    // Share of the fused score in searches across collections; 0 = 1
+	quota?:     #QuotaConfig         // Limits of the collection, e.g. for a tenant of a shared server
+}
+
+#QuotaConfig: {
+	max_documents: int & >=0 | *0    // Distinct paths; writes of new paths beyond it fail with 413. 0 = no limit
+	max_bytes:     int & >=0 | *0    // Size of the indexes on disk; writes once it is reached fail with 413. 0 = no limit
+	max_qps:       number & >=0 | *0 // Searches per second over all clients; more get 429. 0 = no limit
+}
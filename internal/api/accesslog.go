@@ -0,0 +1,84 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// Gin context keys handlers use to pass details to the access log.
+const (
+	queryKey   = "semango.query"
+	resultsKey = "semango.results"
+)
+
+// accessLog writes one JSON line per request through the request's logger,
+// so it carries the request_id set by requestLogging. It replaces
+// gin.Logger, whose plain-text lines could not be parsed with the rest of
+// the log.
+//
+// With cfg.SampleRate between 0 and 1 only that fraction of requests is
+// logged; server errors are logged regardless. With cfg.RedactQueries the
+// query text is left out and only its hash is logged, which still lets
+// repeated queries be grouped.
+func accessLog(cfg config.AccessLogConfig) gin.HandlerFunc {
+	sample := func() bool { return true }
+	if cfg.SampleRate > 0 && cfg.SampleRate < 1 {
+		sample = func() bool { return rand.Float64() < cfg.SampleRate }
+	}
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		if status < http.StatusInternalServerError && !sample() {
+			return
+		}
+		attrs := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"client_ip", c.ClientIP(),
+			"bytes", c.Writer.Size(),
+		}
+		if who := c.GetString(principalKey); who != "" {
+			attrs = append(attrs, "principal", who)
+		}
+		if q, ok := c.Get(queryKey); ok {
+			q := q.(string)
+			attrs = append(attrs, "query_hash", shortHash(q))
+			if !cfg.RedactQueries {
+				attrs = append(attrs, "query", q)
+			}
+		}
+		if n, ok := c.Get(resultsKey); ok {
+			attrs = append(attrs, "results", n)
+		}
+		util.FromContext(c.Request.Context()).Info("HTTP request", attrs...)
+	}
+}
+
+// noteQuery records the query text of a search or answer request for the
+// access log.
+func noteQuery(c *gin.Context, query string) {
+	c.Set(queryKey, query)
+}
+
+// noteResults records how many results a request returned for the access
+// log.
+func noteResults(c *gin.Context, n int) {
+	c.Set(resultsKey, n)
+}
+
+// shortHash returns the first 16 hex digits of the SHA-256 of s.
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+)
+
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("SEMANGO_TEST_TOKENS", "secret")
+
+	serve := func(cfg config.AccessLogConfig, status int, auth string) map[string]any {
+		var buf bytes.Buffer
+		s := &Server{auth: newTokenAuth(config.AuthConfig{Type: "token", TokenEnv: "SEMANGO_TEST_TOKENS"})}
+		r := gin.New()
+		r.Use(requestLogging(slog.New(slog.NewJSONHandler(&buf, nil))))
+		r.Use(accessLog(cfg))
+		r.GET("/search", s.requireToken(), func(c *gin.Context) {
+			noteQuery(c, c.Query("q"))
+			noteResults(c, 3)
+			c.Status(status)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/search?q=private+matter", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		if buf.Len() == 0 {
+			return nil
+		}
+		var line map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+			t.Fatalf("access log line %q is not JSON: %v", buf.String(), err)
+		}
+		return line
+	}
+
+	line := serve(config.AccessLogConfig{}, http.StatusOK, "secret")
+	if line == nil {
+		t.Fatal("request not logged")
+	}
+	for key, want := range map[string]any{
+		"msg": "HTTP request", "method": "GET", "path": "/search", "status": float64(200),
+		"query": "private matter", "query_hash": shortHash("private matter"),
+		"principal": "token:" + shortHash("secret"), "results": float64(3),
+	} {
+		if line[key] != want {
+			t.Errorf("%s = %v, want %v", key, line[key], want)
+		}
+	}
+	if line["request_id"] == nil {
+		t.Error("access log line does not carry the request ID")
+	}
+
+	line = serve(config.AccessLogConfig{RedactQueries: true}, http.StatusOK, "secret")
+	if _, ok := line["query"]; ok {
+		t.Errorf("query text logged despite redact_queries: %v", line)
+	}
+	if line["query_hash"] != shortHash("private matter") {
+		t.Errorf("query_hash = %v, want it kept when redacting", line["query_hash"])
+	}
+
+	line = serve(config.AccessLogConfig{}, http.StatusOK, "wrong")
+	if line["status"] != float64(http.StatusUnauthorized) || line["principal"] != nil {
+		t.Errorf("rejected request logged as %v", line)
+	}
+
+	// A tiny sample rate drops practically every successful request but
+	// never a server error.
+	rare := config.AccessLogConfig{SampleRate: 1e-12}
+	for i := 0; i < 20; i++ {
+		if line := serve(rare, http.StatusOK, "secret"); line != nil {
+			t.Fatalf("sampled-out request logged: %v", line)
+		}
+	}
+	if line := serve(rare, http.StatusInternalServerError, "secret"); line == nil {
+		t.Error("server error not logged while sampling")
+	}
+}
+
+func TestTokenAuthPrincipal(t *testing.T) {
+	var a *tokenAuth
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if who, ok := a.principal(req); !ok || who != anonymousPrincipal {
+		t.Errorf("without auth: got %q, %v", who, ok)
+	}
+
+	t.Setenv("SEMANGO_TEST_TOKENS", "one, two")
+	a = newTokenAuth(config.AuthConfig{TokenEnv: "SEMANGO_TEST_TOKENS"})
+	req.Header.Set("Authorization", "Bearer two")
+	who, ok := a.principal(req)
+	if !ok || !strings.HasPrefix(who, "token:") || strings.Contains(who, "two") {
+		t.Errorf("valid token: got %q, %v", who, ok)
+	}
+	req.Header.Set("Authorization", "Bearer one")
+	if other, _ := a.principal(req); other == who {
+		t.Error("different tokens should have different principals")
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/rag"
+	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// questionAnswerer is the part of rag.Answerer used by the answer API.
+type questionAnswerer interface {
+	Answer(ctx context.Context, question string, topK int, opts search.Options) (*rag.Answer, error)
+}
+
+// AnswerRequest represents the POST /api/v1/answer request body
+type AnswerRequest struct {
+	Question string `json:"question" binding:"required"`
+	TopK     int    `json:"top_k,omitempty"` // Chunks to answer from; defaults to llm.top_k
+	Filter   string `json:"filter,omitempty"`
+	Lang     string `json:"lang,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Mode     string `json:"mode,omitempty"`
+	Space    string `json:"space,omitempty"`
+	Expand   string `json:"expand,omitempty"` // Query expansion: "terms", "hyde" or "multi"
+	// Queries are further search queries for the question, e.g.
+	// sub-questions; their results are merged with the question's.
+	Queries []string `json:"queries,omitempty"`
+	// Adaptive answers only from the chunks above the first steep score
+	// drop among the top_k, leaving weakly related ones out of the prompt.
+	Adaptive bool `json:"adaptive,omitempty"`
+}
+
+// AnswerResponse represents the POST /api/v1/answer response
+type AnswerResponse struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+	// Sources are the chunks the model was given, numbered as cited in the
+	// answer; Citations lists the numbers the answer actually cites.
+	Sources    []rag.Source `json:"sources"`
+	Citations  []int        `json:"citations"`
+	Model      string       `json:"model,omitempty"`
+	Generation uint64       `json:"generation"`
+	Took       string       `json:"took"`
+}
+
+// handleAnswer answers a question from the index: it runs a search for the
+// question and has the configured LLM write an answer citing the results.
+func (s *Server) handleAnswer(c *gin.Context) {
+	start := time.Now()
+
+	if s.answerer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "answering is not configured: set llm.model"})
+		return
+	}
+	var req AnswerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Question) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "question is required"})
+		return
+	}
+	noteQuery(c, req.Question)
+
+	// Validate the search part like a search request.
+	gen := s.indexGeneration()
+	plan, reqErr := s.planSearch(SearchRequest{
+		Query: req.Question, TopK: req.TopK, Filter: req.Filter, Lang: req.Lang,
+		Path: req.Path, Mode: req.Mode, Space: req.Space, Expand: req.Expand,
+		Queries: req.Queries, Adaptive: req.Adaptive,
+	}, gen, nil)
+	if reqErr != nil {
+		c.JSON(reqErr.status, reqErr.body())
+		return
+	}
+	topK := 0
+	if req.TopK > 0 {
+		topK = plan.req.TopK
+	}
+
+	// The search and the LLM call both count against the search limits.
+	var ans *rag.Answer
+	err := s.withSearchSlot(c.Request.Context(), func(ctx context.Context) (err error) {
+		ans, err = s.answerer.Answer(ctx, req.Question, topK, plan.opts)
+		return err
+	})
+	if errors.Is(err, rag.ErrModel) {
+		util.FromContext(c.Request.Context()).Error("Answer failed", "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "the LLM did not answer"})
+		return
+	}
+	if err != nil {
+		searchFailed(c, err)
+		return
+	}
+	noteResults(c, len(ans.Sources))
+	c.JSON(http.StatusOK, AnswerResponse{
+		Question:   req.Question,
+		Answer:     ans.Answer,
+		Sources:    ans.Sources,
+		Citations:  ans.Citations,
+		Model:      ans.Model,
+		Generation: gen.Number,
+		Took:       time.Since(start).String(),
+	})
+}
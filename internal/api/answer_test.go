@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/rag"
+	"github.com/omarkamali/semango/internal/search"
+)
+
+type fakeAnswerer struct {
+	topK int
+	opts search.Options
+	err  error
+}
+
+func (f *fakeAnswerer) Answer(_ context.Context, question string, topK int, opts search.Options) (*rag.Answer, error) {
+	f.topK, f.opts = topK, opts
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &rag.Answer{
+		Answer:    "Roll back with harbor deploy --rollback [1].",
+		Sources:   []rag.Source{{N: 1, ID: "deploy#0", Path: "docs/deployments.md", Text: "..."}},
+		Citations: []int{1},
+		Model:     "fake",
+	}, nil
+}
+
+func newAnswerRouter(a questionAnswerer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	s := &Server{config: &config.Config{}, logger: slog.Default()}
+	if a != nil {
+		s.answerer = a
+	}
+	r := gin.New()
+	r.POST("/api/v1/answer", s.handleAnswer)
+	return r
+}
+
+func postAnswer(r *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/answer", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAnswer(t *testing.T) {
+	if w := postAnswer(newAnswerRouter(nil), `{"question":"how do I roll back?"}`); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("without an LLM: status %d, want 503", w.Code)
+	}
+
+	fake := &fakeAnswerer{}
+	r := newAnswerRouter(fake)
+	w := postAnswer(r, `{"question":"how do I roll back?","filter":"lang:en"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var resp AnswerResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Question != "how do I roll back?" || len(resp.Sources) != 1 || resp.Citations[0] != 1 || resp.Model != "fake" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if fake.topK != 0 || fake.opts.Filter["lang"] != "en" {
+		t.Errorf("answerer got top_k %d and options %+v", fake.topK, fake.opts)
+	}
+
+	if w := postAnswer(r, `{"question":"q","top_k":500,"expand":"terms"}`); w.Code != http.StatusOK || fake.topK != 100 || fake.opts.Expand != "terms" {
+		t.Errorf("top_k 500: status %d, answerer got %d and options %+v", w.Code, fake.topK, fake.opts)
+	}
+
+	for _, body := range []string{`{}`, `{"question":"  "}`, `{"question":"q","mode":"fuzzy"}`,
+		`{"question":"q","expand":"synonyms"}`, `{"question":"q","expand":"hyde"}`, `{"question":"q","expand":"multi"}`,
+		`{"question":"q","queries":["a",""]}`, `{"question":"q","queries":["1","2","3","4","5","6","7","8","9","10"]}`} {
+		if w := postAnswer(r, body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", body, w.Code)
+		}
+	}
+
+	fake.err = fmt.Errorf("%w: upstream timeout", rag.ErrModel)
+	if w := postAnswer(r, `{"question":"q"}`); w.Code != http.StatusBadGateway {
+		t.Errorf("model failure: status %d, want 502", w.Code)
+	}
+	fake.err = errors.New("index closed")
+	if w := postAnswer(r, `{"question":"q"}`); w.Code != http.StatusInternalServerError {
+		t.Errorf("retrieval failure: status %d, want 500", w.Code)
+	}
+}
+
+// slowAnswerer answers once ctx is done, like an LLM call that outlasts the
+// search timeout.
+type slowAnswerer struct{}
+
+func (slowAnswerer) Answer(ctx context.Context, _ string, _ int, _ search.Options) (*rag.Answer, error) {
+	<-ctx.Done()
+	return nil, fmt.Errorf("%w: %w", rag.ErrModel, ctx.Err())
+}
+
+func TestAnswer_SearchLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := &Server{config: &config.Config{}, logger: slog.Default(), answerer: slowAnswerer{}}
+	s.searchLimits = newSearchLimits(config.ServerConfig{MaxConcurrentSearches: 1})
+	s.searchLimits.timeout = 20 * time.Millisecond
+	r := gin.New()
+	r.POST("/api/v1/answer", s.handleAnswer)
+
+	if w := postAnswer(r, `{"question":"q"}`); w.Code != http.StatusGatewayTimeout {
+		t.Errorf("answer past the search timeout: status %d, want 504", w.Code)
+	}
+	// The slot was given back.
+	if len(s.searchLimits.slots) != 0 {
+		t.Errorf("%d search slots held after the answer", len(s.searchLimits.slots))
+	}
+}
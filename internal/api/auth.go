@@ -0,0 +1,132 @@
+package api
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+)
+
+// tokenAuth checks bearer tokens against the comma-separated list in the
+// environment variable named by server.auth.token_env. It guards the REST
+// and gRPC APIs alike. With no tokens configured, or auth.type "none",
+// every request is allowed, so local setups work without credentials.
+type tokenAuth struct {
+	tokens [][]byte
+}
+
+// newTokenAuth reads the tokens for cfg once, at startup.
+func newTokenAuth(cfg config.AuthConfig) *tokenAuth {
+	if cfg.Type == "none" {
+		return nil
+	}
+	if cfg.Type != "" && cfg.Type != "token" {
+		slog.Warn("Unknown server.auth.type, falling back to token auth", "type", cfg.Type)
+	}
+	a := &tokenAuth{tokens: readTokens(cfg.TokenEnv)}
+	if len(a.tokens) == 0 {
+		slog.Info("No API tokens configured, authentication is disabled", "token_env", cfg.TokenEnv)
+		return nil
+	}
+	return a
+}
+
+// newAdminAuth reads the admin tokens for cfg once, at startup. Unlike
+// newTokenAuth, it returns nil when there are none, and a nil admin auth
+// refuses everything: admin endpoints are off unless tokens are set.
+func newAdminAuth(cfg config.AuthConfig) *tokenAuth {
+	tokens := readTokens(cfg.AdminTokenEnv)
+	if len(tokens) == 0 {
+		return nil
+	}
+	return &tokenAuth{tokens: tokens}
+}
+
+// readTokens returns the comma-separated tokens in the environment variable
+// env.
+func readTokens(env string) [][]byte {
+	if env == "" {
+		return nil
+	}
+	var tokens [][]byte
+	for _, tok := range strings.Split(os.Getenv(env), ",") {
+		if tok = strings.TrimSpace(tok); tok != "" {
+			tokens = append(tokens, []byte(tok))
+		}
+	}
+	return tokens
+}
+
+// anonymousPrincipal is the principal of requests served without auth.
+const anonymousPrincipal = "anonymous"
+
+// principalKey is the gin context key requireToken stores the principal
+// under, for the access log, rate limits and idempotency keys.
+const principalKey = "semango.principal"
+
+// principal returns who r authenticated as and whether it carries one of
+// the configured tokens. Tokens have no names, so the principal is a short
+// hash of the token: stable across requests but useless to an attacker
+// reading the logs. A nil tokenAuth allows everything as anonymous.
+func (a *tokenAuth) principal(r *http.Request) (string, bool) {
+	if a == nil {
+		return anonymousPrincipal, true
+	}
+	tok, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return "", false
+	}
+	allowed := 0
+	for _, t := range a.tokens {
+		allowed |= subtle.ConstantTimeCompare([]byte(tok), t)
+	}
+	if allowed != 1 {
+		return "", false
+	}
+	return "token:" + shortHash(tok), true
+}
+
+// allow reports whether r carries one of the configured tokens.
+func (a *tokenAuth) allow(r *http.Request) bool {
+	_, ok := a.principal(r)
+	return ok
+}
+
+// requireToken rejects REST requests without a valid bearer token.
+func (s *Server) requireToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		who, ok := s.auth.principal(c.Request)
+		if !ok {
+			c.Header("WWW-Authenticate", `Bearer realm="semango"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing or invalid bearer token"})
+			return
+		}
+		c.Set(principalKey, who)
+		c.Next()
+	}
+}
+
+// requireAdmin guards the endpoints that change the running server, such
+// as switching its embedder. They take a bearer token from
+// server.auth.admin_token_env rather than an API token, and answer 403
+// when no admin tokens are configured, as by default.
+func (s *Server) requireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.admin == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin endpoints are disabled; set server.auth.admin_token_env to enable them"})
+			return
+		}
+		who, ok := s.admin.principal(c.Request)
+		if !ok {
+			c.Header("WWW-Authenticate", `Bearer realm="semango-admin"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing or invalid admin token"})
+			return
+		}
+		c.Set(principalKey, "admin:"+strings.TrimPrefix(who, "token:"))
+		c.Next()
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("SEMANGO_TEST_TOKENS", "user")
+	t.Setenv("SEMANGO_TEST_ADMIN_TOKENS", "root")
+	cfg := config.AuthConfig{TokenEnv: "SEMANGO_TEST_TOKENS"}
+	s := &Server{auth: newTokenAuth(cfg), admin: newAdminAuth(cfg)}
+	r := gin.New()
+	r.PUT("/embedder", s.requireAdmin(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	put := func(token string) int {
+		req := httptest.NewRequest(http.MethodPut, "/embedder", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Admin endpoints are off by default, even with API tokens set.
+	if code := put("user"); code != http.StatusForbidden {
+		t.Errorf("without admin_token_env: %d, want 403", code)
+	}
+
+	cfg.AdminTokenEnv = "SEMANGO_TEST_ADMIN_TOKENS"
+	s.admin = newAdminAuth(cfg)
+	for token, want := range map[string]int{"root": http.StatusOK, "user": http.StatusUnauthorized, "": http.StatusUnauthorized} {
+		if code := put(token); code != want {
+			t.Errorf("token %q: %d, want %d", token, code, want)
+		}
+	}
+
+	// Without API tokens the API is open, but admin endpoints are not.
+	s.auth = nil
+	if code := put(""); code != http.StatusUnauthorized {
+		t.Errorf("anonymous request with auth disabled: %d, want 401", code)
+	}
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+// Search results only change when the indexes or the ranking settings do,
+// so search responses carry an ETag derived from the index generation, the
+// settings epoch and the normalised request, plus the later of the
+// generation's timestamp and the last settings change as Last-Modified. A
+// polling client that sends them back via If-None-Match / If-Modified-Since
+// gets a 304 without the search being run again. Index reads such as stats
+// and the list of tables get the same treatment via readValidators.
+
+// settingsEpoch counts the changes made while the server runs to what
+// searches return for the same index: reloaded hybrid or reranker settings
+// and embedder switches.
+type settingsEpoch struct {
+	mu sync.Mutex
+	n  uint64
+	at time.Time // of the last change
+}
+
+// bump records a change.
+func (e *settingsEpoch) bump() {
+	e.mu.Lock()
+	e.n++
+	e.at = time.Now()
+	e.mu.Unlock()
+}
+
+// validators returns the epoch, and g with its timestamp moved to the last
+// change when that is later, for the validators of a search at generation g.
+func (e *settingsEpoch) validators(g storage.Generation) (uint64, storage.Generation) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.at.After(g.UpdatedAt) {
+		g.UpdatedAt = e.at
+	}
+	return e.n, g
+}
+
+// indexGeneration reads the current generation of the configured indexes.
+// Errors are logged and reported as the zero generation, which disables
+// Last-Modified but keeps ETags usable.
+func (s *Server) indexGeneration() storage.Generation {
+	return s.generationOf(s.config)
+}
+
+// generationOf reads the current generation of the indexes of cfg, the
+// server's configuration or a collection's, like indexGeneration.
+func (s *Server) generationOf(cfg *config.Config) storage.Generation {
+	g, err := storage.ReadGeneration(storage.GenerationPath(cfg.Lexical.IndexPath))
+	if err != nil {
+		s.logger.Warn("Failed to read index generation", "error", err)
+	}
+	return g
+}
+
+// fresh reports whether generation g satisfies a client's minimum generation
+// and minimum write time; zero values impose no requirement.
+func fresh(g storage.Generation, minGeneration uint64, minIndexedAt time.Time) bool {
+	if g.Number < minGeneration {
+		return false
+	}
+	return minIndexedAt.IsZero() || !g.UpdatedAt.Before(minIndexedAt)
+}
+
+// generationTime returns the write time of g for JSON bodies, or nil for an
+// index that has never been written.
+func generationTime(g storage.Generation) *time.Time {
+	if g.UpdatedAt.IsZero() {
+		return nil
+	}
+	t := g.UpdatedAt.UTC()
+	return &t
+}
+
+// searchETag derives a weak validator for req at generation g and settings
+// epoch. It is weak because responses to the same search differ in timing
+// fields.
+func searchETag(g storage.Generation, epoch uint64, req SearchRequest) string {
+	key, _ := json.Marshal(struct {
+		Generation uint64 `json:"g"`
+		UpdatedAt  int64  `json:"t"`
+		Epoch      uint64 `json:"e"`
+		SearchRequest
+	}{g.Number, g.UpdatedAt.UnixNano(), epoch, req})
+	sum := sha256.Sum256(key)
+	return fmt.Sprintf(`W/"g%d-%s"`, g.Number, hex.EncodeToString(sum[:8]))
+}
+
+// readETag derives a weak validator for a read of resource, such as the
+// stats of a collection, whose response only changes with the index
+// generation and the settings epoch.
+func readETag(g storage.Generation, epoch uint64, resource string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d\x00%d\x00%d\x00%s", g.Number, g.UpdatedAt.UnixNano(), epoch, resource)))
+	return fmt.Sprintf(`W/"g%d-%s"`, g.Number, hex.EncodeToString(sum[:8]))
+}
+
+// readValidators sets the validators of a read of resource from the indexes
+// of cfg and, when the client's copy is still current, answers 304 and
+// returns true.
+func (s *Server) readValidators(c *gin.Context, cfg *config.Config, resource string) bool {
+	epoch, g := s.epoch.validators(s.generationOf(cfg))
+	etag := readETag(g, epoch, resource)
+	setCacheHeaders(c, etag, g)
+	if notModified(c.Request, etag, g) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// setCacheHeaders sets the validators for a response. Cache-Control asks
+// caches to revalidate every time, since the index can change at any moment.
+func setCacheHeaders(c *gin.Context, etag string, g storage.Generation) {
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+	if !g.UpdatedAt.IsZero() {
+		c.Header("Last-Modified", g.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+	c.Header("X-Index-Generation", fmt.Sprint(g.Number))
+	c.Header("Cache-Control", "no-cache")
+}
+
+// notModified evaluates If-None-Match and If-Modified-Since (RFC 9110 §13.2.2)
+// against the current validators. If-Modified-Since is ignored when
+// If-None-Match is present.
+func notModified(r *http.Request, etag string, g storage.Generation) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || g.UpdatedAt.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	return !g.UpdatedAt.Truncate(time.Second).After(t)
+}
+
+// etagMatches implements the weak comparison used by If-None-Match.
+func etagMatches(header, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+func TestSearchGet_NotModified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	indexPath := filepath.Join(t.TempDir(), "index", "bleve")
+	gen, err := storage.BumpGeneration(storage.GenerationPath(indexPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.Lexical.IndexPath = indexPath
+	// No searcher: a conditional hit must be answered without searching.
+	s := &Server{config: cfg, logger: slog.Default()}
+	r := gin.New()
+	r.GET("/api/v1/search", s.handleSearchGet)
+
+	req := SearchRequest{Query: "hello", TopK: 10}
+	etag := searchETag(gen, 0, req)
+
+	get := func(header, value string) *httptest.ResponseRecorder {
+		hr := httptest.NewRequest(http.MethodGet, "/api/v1/search?q=hello", nil)
+		hr.Header.Set(header, value)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, hr)
+		return w
+	}
+
+	w := get("If-None-Match", `"other", `+etag)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for matching ETag, got %d", w.Code)
+	}
+	if w.Header().Get("ETag") != etag || w.Header().Get("X-Index-Generation") != "1" {
+		t.Errorf("unexpected validators: %v", w.Header())
+	}
+
+	// The mode is part of the cache key: a lexical search is a different result.
+	if searchETag(gen, 0, SearchRequest{Query: "hello", TopK: 10, Mode: "lexical"}) == etag {
+		t.Error("ETag must depend on the search mode")
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=hello&mode=fuzzy", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown mode, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=hello&boost=tests/**%3D0", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a zero boost, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=hello&space=candidate", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown vector space, got %d", w.Code)
+	}
+
+	w = get("If-Modified-Since", gen.UpdatedAt.Add(time.Second).Format(http.TimeFormat))
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for If-Modified-Since after the last index write, got %d", w.Code)
+	}
+
+	// So do reloaded ranking settings and embedder switches. HTTP dates
+	// have a resolution of a second; make the change a minute later.
+	s.epoch.bump()
+	s.epoch.at = gen.UpdatedAt.Add(time.Minute)
+	epoch, validated := s.epoch.validators(gen)
+	current := searchETag(gen, epoch, req)
+	if epoch != 1 || current == etag {
+		t.Fatalf("ETag must change with the settings epoch, now %d", epoch)
+	}
+	stale := httptest.NewRequest(http.MethodGet, "/", nil)
+	stale.Header.Set("If-None-Match", etag)
+	if notModified(stale, current, validated) {
+		t.Error("ETag from before a settings change must not match")
+	}
+	stale = httptest.NewRequest(http.MethodGet, "/", nil)
+	stale.Header.Set("If-Modified-Since", gen.UpdatedAt.Add(time.Second).Format(http.TimeFormat))
+	if notModified(stale, current, validated) {
+		t.Error("If-Modified-Since before a settings change must not match")
+	}
+	if w := get("If-None-Match", current); w.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for the ETag of the current epoch, got %d", w.Code)
+	}
+
+	// Indexing changes the generation, so the old ETag no longer matches.
+	next, err := storage.BumpGeneration(storage.GenerationPath(indexPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if searchETag(next, 0, req) == etag {
+		t.Fatal("ETag must change with the index generation")
+	}
+	if notModified(httptest.NewRequest(http.MethodGet, "/", nil), etag, next) {
+		t.Error("request without validators must not be answered with 304")
+	}
+	stale = httptest.NewRequest(http.MethodGet, "/", nil)
+	stale.Header.Set("If-None-Match", etag)
+	if notModified(stale, searchETag(next, 0, req), next) {
+		t.Error("stale ETag must not match")
+	}
+}
+
+func TestSearch_MinFreshness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	indexPath := filepath.Join(t.TempDir(), "index", "bleve")
+	gen, err := storage.BumpGeneration(storage.GenerationPath(indexPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.Lexical.IndexPath = indexPath
+	s := &Server{config: cfg, logger: slog.Default()}
+	r := gin.New()
+	r.GET("/api/v1/search", s.handleSearchGet)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=hello&"+query, nil))
+		return w
+	}
+
+	future := url.QueryEscape(gen.UpdatedAt.Add(time.Minute).Format(time.RFC3339Nano))
+	for _, q := range []string{"min_generation=2", "min_indexed_at=" + future} {
+		w := get(q)
+		if w.Code != http.StatusConflict {
+			t.Errorf("%s: status %d, want 409", q, w.Code)
+		}
+		if w.Header().Get("X-Index-Generation") != "1" {
+			t.Errorf("%s: missing generation header: %v", q, w.Header())
+		}
+	}
+	if w := get("min_indexed_at=yesterday"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid timestamp: status %d, want 400", w.Code)
+	}
+
+	if !fresh(gen, 1, gen.UpdatedAt) || !fresh(gen, 0, time.Time{}) {
+		t.Error("the current generation must satisfy requirements it meets")
+	}
+}
+
+func TestIndexReads_NotModified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	indexPath := filepath.Join(t.TempDir(), "index", "bleve")
+	gen, err := storage.BumpGeneration(storage.GenerationPath(indexPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.Lexical.IndexPath = indexPath
+	// No searcher: a conditional hit must be answered without reading the index.
+	s := &Server{config: cfg, logger: slog.Default()}
+	r := gin.New()
+	r.GET("/api/v1/stats", s.handleStats)
+	r.GET("/api/v1/tables", s.handleListTables)
+
+	for path, resource := range map[string]string{"/api/v1/stats": "stats\x00", "/api/v1/tables": "tables\x00"} {
+		etag := readETag(gen, 0, resource)
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("If-None-Match", etag)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusNotModified || w.Header().Get("ETag") != etag {
+			t.Errorf("%s: expected 304 with ETag %s, got %d %v", path, etag, w.Code, w.Header())
+		}
+	}
+	if readETag(gen, 0, "stats\x00") == readETag(gen, 0, "stats\x00docs") {
+		t.Error("ETag must depend on the collection")
+	}
+	next, err := storage.BumpGeneration(storage.GenerationPath(indexPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readETag(next, 0, "stats\x00") == readETag(gen, 0, "stats\x00") {
+		t.Error("ETag must change with the index generation")
+	}
+}
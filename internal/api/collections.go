@@ -0,0 +1,160 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+// openCollections prepares a searcher and a document ingester for every
+// collection in the configuration, and a limiter for those with a max_qps
+// quota. Collections with the default embedding settings share the default
+// searcher's embedders; others load their own. A collection whose embedder
+// cannot be created is logged and left out.
+func (s *Server) openCollections(rewriter search.QueryRewriter) {
+	if len(s.config.Collections) == 0 || s.searcher == nil {
+		return
+	}
+	s.collections = make(map[string]*search.Searcher, len(s.config.Collections))
+	s.collectionIngesters = make(map[string]documentIndexer, len(s.config.Collections))
+	s.collectionLimits = map[string]*rateLimiter{}
+	for name, coll := range s.config.Collections {
+		cfg, _ := s.config.ForCollection(name)
+		var searcher *search.Searcher
+		if coll.Embedding == (config.SpaceEmbeddingConfig{}) {
+			searcher = s.searcher.WithConfig(cfg)
+		} else {
+			var err error
+			if searcher, err = search.NewSearcher(cfg); err != nil {
+				slog.Error("Collection cannot be searched", "collection", name, "error", err)
+				continue
+			}
+			if rewriter != nil {
+				searcher.WithRewriter(rewriter)
+			}
+			if s.searcher.ModelMismatchAllowed() {
+				searcher.AllowModelMismatch()
+			}
+		}
+		s.collections[name] = searcher
+
+		mgr := pipeline.NewManager(cfg, searcher.Embedder()).WithSpaces(searcher.SpaceEmbedders()).WithQuota(name, coll.Quota)
+		if s.searcher.ModelMismatchAllowed() {
+			mgr.AllowModelMismatch()
+		}
+		s.collectionIngesters[name] = mgr
+		if limiter := newRateLimiter(config.RateLimitConfig{RPS: coll.Quota.MaxQPS}); limiter != nil {
+			s.collectionLimits[name] = limiter
+		}
+	}
+}
+
+// collectionSource returns the generation of the named collection's
+// indexes, for a request with collection.
+func (s *Server) collectionSource(name, asOf string) (storage.Generation, *requestError) {
+	if asOf != "" {
+		return storage.Generation{}, &requestError{status: http.StatusBadRequest, msg: "as_of searches a snapshot of the default index and cannot be combined with collection"}
+	}
+	cfg, ok := s.config.ForCollection(name)
+	if !ok || s.collections[name] == nil {
+		return storage.Generation{}, &requestError{status: http.StatusBadRequest, msg: fmt.Sprintf("unknown collection %q", name)}
+	}
+	return s.generationOf(cfg), nil
+}
+
+// collectionSearcher returns the searcher of the named collection, checking
+// that it has vector space space.
+func (s *Server) collectionSearcher(name, space string) (*search.Searcher, *requestError) {
+	searcher := s.collections[name]
+	if searcher == nil {
+		return nil, &requestError{status: http.StatusBadRequest, msg: fmt.Sprintf("unknown collection %q", name)}
+	}
+	// Collections with a model of their own have no vector spaces.
+	if space != "" && space != search.DefaultSpace && searcher.SpaceEmbedders()[space] == nil {
+		return nil, &requestError{status: http.StatusBadRequest, msg: fmt.Sprintf("collection %q has no vector space %q", name, space)}
+	}
+	return searcher, nil
+}
+
+// federationSource is collectionSource for a request with collections.
+// The generation number is the sum of the collections' numbers, which grows
+// whenever one of them is written, and its time is the latest write, so
+// cursors and cache validators notice a change to any of them.
+func (s *Server) federationSource(names []string, asOf string) (storage.Generation, *requestError) {
+	var gen storage.Generation
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			return storage.Generation{}, &requestError{status: http.StatusBadRequest, msg: fmt.Sprintf("collection %q given twice", name)}
+		}
+		seen[name] = true
+		g, err := s.collectionSource(name, asOf)
+		if err != nil {
+			return storage.Generation{}, err
+		}
+		gen.Number += g.Number
+		if g.UpdatedAt.After(gen.UpdatedAt) {
+			gen.UpdatedAt = g.UpdatedAt
+		}
+	}
+	return gen, nil
+}
+
+// federation returns a search across the named collections, weighted by
+// their configured weights.
+func (s *Server) federation(names []string, space string) (search.Federation, *requestError) {
+	fed := make(search.Federation, len(names))
+	for i, name := range names {
+		searcher, err := s.collectionSearcher(name, space)
+		if err != nil {
+			return nil, err
+		}
+		fed[i] = search.Member{Collection: name, Searcher: searcher, Weight: s.config.Collections[name].Weight}
+	}
+	return fed, nil
+}
+
+// collectionIngester returns the document ingester of the named
+// collection, or the default one for "".
+func (s *Server) collectionIngester(name string) (documentIndexer, *requestError) {
+	if name == "" {
+		return s.ingester, nil
+	}
+	ingester := s.collectionIngesters[name]
+	if ingester == nil {
+		return nil, &requestError{status: http.StatusBadRequest, msg: fmt.Sprintf("unknown collection %q", name)}
+	}
+	return ingester, nil
+}
+
+// collectionQPS takes a search from the max_qps quota of each collection
+// req searches. A collection over its quota is reported with 429 and the
+// time until it has room for another search.
+func (s *Server) collectionQPS(req SearchRequest) *requestError {
+	names := req.Collections
+	if len(names) == 0 && req.Collection != "" {
+		names = []string{req.Collection}
+	}
+	for _, name := range names {
+		limiter := s.collectionLimits[name]
+		if limiter == nil {
+			continue
+		}
+		if ok, retry := limiter.allow(""); !ok {
+			max := s.config.Collections[name].Quota.MaxQPS
+			return &requestError{
+				status:     http.StatusTooManyRequests,
+				msg:        fmt.Sprintf("collection %q is at its max_qps quota of %g searches per second", name, max),
+				extra:      gin.H{"collection": name, "limit": "max_qps", "max": max},
+				retryAfter: retry,
+			}
+		}
+	}
+	return nil
+}
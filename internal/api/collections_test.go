@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+func TestSearchCollection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	cfg := &config.Config{}
+	cfg.Lexical.IndexPath = filepath.Join(dir, "index", "bleve")
+	cfg.Collections = map[string]config.CollectionConfig{
+		"docs": {IndexDir: filepath.Join(dir, "docs")},
+		"code": {IndexDir: filepath.Join(dir, "code"), Weight: 2},
+	}
+
+	// The default index is at generation 1, the docs collection at 2.
+	storage.BumpGeneration(storage.GenerationPath(cfg.Lexical.IndexPath))
+	docs, _ := cfg.ForCollection("docs")
+	storage.BumpGeneration(storage.GenerationPath(docs.Lexical.IndexPath))
+	storage.BumpGeneration(storage.GenerationPath(docs.Lexical.IndexPath))
+	docsGen, _ := storage.ReadGeneration(storage.GenerationPath(docs.Lexical.IndexPath))
+
+	s := &Server{config: cfg, logger: slog.Default(), searcher: search.NewSearcherWithEmbedder(cfg, nil)}
+	s.openCollections(nil)
+	r := gin.New()
+	r.GET("/api/v1/search", s.handleSearchGet)
+	get := func(url, etag string) *httptest.ResponseRecorder {
+		hr := httptest.NewRequest(http.MethodGet, url, nil)
+		if etag != "" {
+			hr.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, hr)
+		return w
+	}
+
+	etag := searchETag(docsGen, 0, SearchRequest{Query: "hello", TopK: 10, Collection: "docs"})
+	w := get("/api/v1/search?q=hello&collection=docs", etag)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for the collection's ETag, got %d: %s", w.Code, w.Body)
+	}
+	if got := w.Header().Get("X-Index-Generation"); got != "2" {
+		t.Errorf("X-Index-Generation = %q, want 2", got)
+	}
+
+	// Searching both collections is at the sum of their generations, so a
+	// write to either changes it.
+	code, _ := cfg.ForCollection("code")
+	storage.BumpGeneration(storage.GenerationPath(code.Lexical.IndexPath))
+	fedGen, err := s.federationSource([]string{"docs", "code"}, "")
+	if err != nil || fedGen.Number != 3 {
+		t.Fatalf("federated generation = %d, %v; want 3", fedGen.Number, err)
+	}
+	etag = searchETag(fedGen, 0, SearchRequest{Query: "hello", TopK: 10, Collections: []string{"docs", "code"}})
+	if w := get("/api/v1/search?q=hello&collection=docs&collection=code", etag); w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for the federated ETag, got %d: %s", w.Code, w.Body)
+	}
+	fed, rerr := s.federation([]string{"docs", "code"}, "")
+	if rerr != nil || len(fed) != 2 || fed[1].Weight != 2 {
+		t.Errorf("federation = %+v, %v", fed, rerr)
+	}
+
+	for _, url := range []string{
+		"/api/v1/search?q=hello&collection=tickets",
+		"/api/v1/search?q=hello&collection=docs&as_of=v1",
+		"/api/v1/search?q=hello&collection=docs&space=candidate",
+		"/api/v1/search?q=hello&collection=docs&collection=tickets",
+		"/api/v1/search?q=hello&collection=docs&collection=docs",
+		"/api/v1/search?q=hello&collection=docs&collection=code&as_of=v1",
+	} {
+		if w := get(url, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", url, w.Code)
+		}
+	}
+}
+
+func TestSearchCollection_MaxQPS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	cfg := &config.Config{}
+	cfg.Lexical.IndexPath = filepath.Join(dir, "index", "bleve")
+	cfg.Collections = map[string]config.CollectionConfig{
+		"docs": {IndexDir: filepath.Join(dir, "docs"), Quota: config.QuotaConfig{MaxQPS: 1}},
+		"code": {IndexDir: filepath.Join(dir, "code")},
+	}
+	s := &Server{config: cfg, logger: slog.Default(), searcher: search.NewSearcherWithEmbedder(cfg, nil)}
+	s.openCollections(nil)
+	r := gin.New()
+	r.GET("/api/v1/search", s.handleSearchGet)
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+
+	docs, _ := cfg.ForCollection("docs")
+	etag := searchETag(s.generationOf(docs), 0, SearchRequest{Query: "hello", TopK: 10, Collection: "docs"})
+	hr := httptest.NewRequest(http.MethodGet, "/api/v1/search?q=hello&collection=docs", nil)
+	hr.Header.Set("If-None-Match", etag)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, hr)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("first search: expected 304, got %d: %s", w.Code, w.Body)
+	}
+
+	for _, url := range []string{
+		"/api/v1/search?q=hello&collection=docs",
+		"/api/v1/search?q=hello&collection=code&collection=docs",
+	} {
+		w := get(url)
+		if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+			t.Fatalf("%s: expected 429 with Retry-After 1, got %d %q: %s", url, w.Code, w.Header().Get("Retry-After"), w.Body)
+		}
+		var body struct {
+			Error, Collection, Limit string
+			Max                      float64
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if body.Collection != "docs" || body.Limit != "max_qps" || body.Max != 1 || !strings.Contains(body.Error, `"docs"`) {
+			t.Errorf("%s: unexpected error body %s", url, w.Body)
+		}
+	}
+	if err := s.collectionQPS(SearchRequest{Collection: "code"}); err != nil {
+		t.Errorf("a collection without max_qps was limited: %v", err.msg)
+	}
+}
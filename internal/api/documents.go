@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// documentIndexer is the part of pipeline.Manager used by the documents API.
+type documentIndexer interface {
+	ReplacePath(ctx context.Context, relPath string, reps []ingest.Representation) error
+}
+
+// DocumentRequest represents the POST /api/v1/documents request body
+type DocumentRequest struct {
+	Path string            `json:"path,omitempty"` // Logical path; defaults to "api/<hash of text>"
+	Text string            `json:"text"`
+	Meta map[string]string `json:"meta,omitempty"`
+	// Collection indexes the document into this collection from the
+	// collections section instead of the default index.
+	Collection string `json:"collection,omitempty"`
+}
+
+// DocumentResponse represents the POST /api/v1/documents response
+type DocumentResponse struct {
+	Path     string   `json:"path"`
+	Chunks   int      `json:"chunks"`
+	ChunkIDs []string `json:"chunk_ids"`
+	Took     string   `json:"took"`
+	// Generation is the index generation that includes the document; pass it
+	// as min_generation to make sure a search sees it.
+	Generation uint64 `json:"generation"`
+}
+
+// handleCreateDocument chunks, embeds and indexes a document sent in the
+// request body. Re-sending a document under the same path replaces all of its
+// chunks, including those past the end of a shorter new text. With an
+// Idempotency-Key header, a replayed request from the same principal returns
+// the original response without indexing again. A document that would take
+// its collection past its quota is refused with 413.
+func (s *Server) handleCreateDocument(c *gin.Context) {
+	start := time.Now()
+
+	if s.ingester == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "document ingestion is not available"})
+		return
+	}
+
+	raw, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var req DocumentRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body: " + err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "text is required"})
+		return
+	}
+	ingester, rerr := s.collectionIngester(req.Collection)
+	if rerr != nil {
+		rerr.write(c)
+		return
+	}
+
+	key := c.GetHeader("Idempotency-Key")
+	if len(key) > maxIdempotencyKeyLen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+		return
+	}
+	finished := false
+	if key != "" {
+		// Keys are scoped to the caller, so one client cannot replay or block
+		// another's request by guessing its key.
+		key = c.GetString(principalKey) + "\x00" + key
+		sum := sha256.Sum256(raw)
+		state, status, body := s.idempotency.begin(key, hex.EncodeToString(sum[:]))
+		switch state {
+		case idempotencyReplay:
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(status, "application/json; charset=utf-8", body)
+			return
+		case idempotencyInProgress:
+			c.JSON(http.StatusConflict, gin.H{"error": "a request with this Idempotency-Key is still being processed"})
+			return
+		case idempotencyMismatch:
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request body"})
+			return
+		}
+		// Release the key on every way out but success, panics included,
+		// so the client may retry.
+		defer func() {
+			if !finished {
+				s.idempotency.abort(key)
+			}
+		}()
+	}
+
+	path, ids, err := s.indexDocument(c.Request.Context(), ingester, req)
+	if err != nil {
+		var quota *pipeline.QuotaError
+		if errors.As(err, &quota) {
+			c.JSON(http.StatusRequestEntityTooLarge, quotaErrorBody(quota))
+			return
+		}
+		util.FromContext(c.Request.Context()).Error("Document ingestion failed", "path", path, "error", err)
+		if indexMismatch(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "document ingestion failed"})
+		return
+	}
+
+	gen := s.documentGeneration(req.Collection)
+	body, err := json.Marshal(DocumentResponse{
+		Path:       path,
+		Chunks:     len(ids),
+		ChunkIDs:   ids,
+		Took:       time.Since(start).String(),
+		Generation: gen.Number,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if key != "" {
+		s.idempotency.finish(key, http.StatusCreated, body)
+		finished = true
+	}
+	// The new generation tells clients which cached searches are now stale.
+	setCacheHeaders(c, "", gen)
+	c.Data(http.StatusCreated, "application/json; charset=utf-8", body)
+}
+
+// indexDocument chunks, embeds and indexes req.Text with ingester, the
+// one of req.Collection, under req.Path, or a path derived from the text
+// when it is empty, and returns the path and the chunk IDs. It is shared by
+// the REST and gRPC APIs.
+func (s *Server) indexDocument(ctx context.Context, ingester documentIndexer, req DocumentRequest) (string, []string, error) {
+	path := strings.TrimSpace(req.Path)
+	if path == "" {
+		sum := sha256.Sum256([]byte(req.Text))
+		path = "api/" + hex.EncodeToString(sum[:8])
+	}
+
+	reps := s.splitter.Split(path, req.Text)
+	ids := make([]string, len(reps))
+	for i := range reps {
+		reps[i].Meta["source"] = "api"
+		for k, v := range req.Meta {
+			// Loader-provided keys such as path and offset take precedence.
+			if _, reserved := reps[i].Meta[k]; !reserved {
+				reps[i].Meta[k] = v
+			}
+		}
+		ids[i] = reps[i].ID
+	}
+
+	s.ingestMu.Lock()
+	defer s.ingestMu.Unlock()
+	// Chunk IDs are positional, so a shorter new text must not leave the
+	// tail of the old one behind; a failure keeps the old one.
+	return path, ids, ingester.ReplacePath(ctx, path, reps)
+}
+
+// documentGeneration returns the generation of the indexes of the named
+// collection, or of the default ones for "", after a document was written.
+func (s *Server) documentGeneration(collection string) storage.Generation {
+	if cfg, ok := s.config.ForCollection(collection); ok {
+		return s.generationOf(cfg)
+	}
+	return s.indexGeneration()
+}
+
+// quotaErrorBody is the JSON error body for a write refused by a
+// collection's quota.
+func quotaErrorBody(e *pipeline.QuotaError) gin.H {
+	return gin.H{"error": e.Error(), "collection": e.Collection, "limit": e.Limit, "max": e.Max}
+}
+
+// indexMismatch reports whether err refuses a write because the vector
+// index was built with another embedding model or dimension, which the
+// client can only fix by changing the configuration.
+func indexMismatch(err error) bool {
+	return errors.Is(err, search.ErrModelMismatch) || errors.Is(err, search.ErrDimensionMismatch)
+}
@@ -0,0 +1,258 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/pipeline"
+)
+
+type fakeIndexer struct {
+	calls int
+	fail  error
+	panic bool
+	reps  []ingest.Representation
+	paths map[string][]ingest.Representation // indexed chunks, by path
+}
+
+func (f *fakeIndexer) ReplacePath(ctx context.Context, relPath string, reps []ingest.Representation) error {
+	f.calls++
+	if f.panic {
+		f.panic = false
+		panic("indexer bug")
+	}
+	if f.fail != nil {
+		err := f.fail
+		f.fail = nil
+		return err
+	}
+	f.reps = reps
+	if f.paths == nil {
+		f.paths = map[string][]ingest.Representation{}
+	}
+	f.paths[relPath] = reps
+	return nil
+}
+
+func newDocumentsRouter(idx *fakeIndexer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	s := &Server{
+		config:      &config.Config{},
+		logger:      slog.Default(),
+		ingester:    idx,
+		splitter:    ingest.NewTextLoader(1000, 0),
+		idempotency: newIdempotencyStore(idempotencyTTL),
+	}
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.POST("/api/v1/documents", func(c *gin.Context) {
+		if who := c.GetHeader("X-Test-Principal"); who != "" {
+			c.Set(principalKey, who)
+		}
+	}, s.handleCreateDocument)
+	return r
+}
+
+func postDocument(r *gin.Engine, key, body string, principal ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/documents", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	if len(principal) > 0 {
+		req.Header.Set("X-Test-Principal", principal[0])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateDocument_IdempotencyKeyReplay(t *testing.T) {
+	idx := &fakeIndexer{}
+	r := newDocumentsRouter(idx)
+	body := `{"path":"notes/a.txt","text":"hello world","meta":{"team":"search","path":"ignored"}}`
+
+	first := postDocument(r, "key-1", body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", first.Code, first.Body.String())
+	}
+	var resp DocumentResponse
+	if err := json.Unmarshal(first.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Chunks != 1 || resp.ChunkIDs[0] != ingest.ChunkID("notes/a.txt", "text", 0) {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if m := idx.reps[0].Meta; m["team"] != "search" || m["path"] != "notes/a.txt" || m["source"] != "api" {
+		t.Errorf("unexpected chunk meta: %v", m)
+	}
+
+	replay := postDocument(r, "key-1", body)
+	if replay.Code != http.StatusCreated || replay.Body.String() != first.Body.String() {
+		t.Errorf("replay returned %d %s, want original response", replay.Code, replay.Body.String())
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("replayed response should be marked")
+	}
+	if idx.calls != 1 {
+		t.Errorf("replay must not index again, got %d calls", idx.calls)
+	}
+
+	if w := postDocument(r, "key-1", `{"path":"notes/a.txt","text":"changed"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reusing a key with a different body: expected 422, got %d", w.Code)
+	}
+}
+
+func TestCreateDocument_DeterministicIDsAndRetryAfterFailure(t *testing.T) {
+	idx := &fakeIndexer{fail: errors.New("embedder timeout")}
+	r := newDocumentsRouter(idx)
+	body := `{"text":"some posted text"}`
+
+	if w := postDocument(r, "key-2", body); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	// A failed attempt releases the key so the client can retry.
+	w := postDocument(r, "key-2", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("retry: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var first DocumentResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &first)
+	if !strings.HasPrefix(first.Path, "api/") {
+		t.Errorf("expected derived api/ path, got %q", first.Path)
+	}
+
+	// Without a key the request is processed again but lands on the same chunks.
+	w = postDocument(r, "", body)
+	var second DocumentResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &second)
+	if second.Path != first.Path || second.ChunkIDs[0] != first.ChunkIDs[0] {
+		t.Errorf("expected identical path and chunk IDs, got %+v vs %+v", second, first)
+	}
+	if idx.calls != 3 {
+		t.Errorf("expected 3 indexing calls, got %d", idx.calls)
+	}
+}
+
+func TestCreateDocument_IdempotencyKeyPerPrincipal(t *testing.T) {
+	idx := &fakeIndexer{}
+	r := newDocumentsRouter(idx)
+	body := `{"path":"notes/a.txt","text":"hello world"}`
+
+	if w := postDocument(r, "shared", body, "token:aaaa"); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", w.Code)
+	}
+	// Another principal using the same key gets its own request processed,
+	// not a replay of the first one.
+	w := postDocument(r, "shared", `{"path":"notes/b.txt","text":"other"}`, "token:bbbb")
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("other principal: got %d replayed=%q", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	if idx.calls != 2 {
+		t.Errorf("expected 2 indexing calls, got %d", idx.calls)
+	}
+}
+
+func TestCreateDocument_ReplacesPreviousChunks(t *testing.T) {
+	idx := &fakeIndexer{}
+	r := newDocumentsRouter(idx)
+	postDocument(r, "", `{"path":"notes/a.txt","text":"first version"}`)
+	postDocument(r, "", `{"path":"notes/a.txt","text":"second"}`)
+	if got := idx.paths["notes/a.txt"]; len(got) != 1 || got[0].Text != "second" {
+		t.Errorf("each POST should replace the chunks of the path, got %+v", got)
+	}
+}
+
+func TestCreateDocument_FailedReplaceKeepsPreviousChunks(t *testing.T) {
+	idx := &fakeIndexer{}
+	r := newDocumentsRouter(idx)
+	postDocument(r, "", `{"path":"notes/a.txt","text":"first version"}`)
+	idx.fail = errors.New("embedder timeout")
+	if w := postDocument(r, "", `{"path":"notes/a.txt","text":"second"}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	if got := idx.paths["notes/a.txt"]; len(got) != 1 || got[0].Text != "first version" {
+		t.Errorf("a failed re-POST must keep the previous version, got %+v", got)
+	}
+}
+
+func TestCreateDocument_PanicReleasesIdempotencyKey(t *testing.T) {
+	idx := &fakeIndexer{panic: true}
+	r := newDocumentsRouter(idx)
+	body := `{"path":"notes/a.txt","text":"hello world"}`
+
+	if w := postDocument(r, "key-3", body); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 from the recovered panic, got %d", w.Code)
+	}
+	if w := postDocument(r, "key-3", body); w.Code != http.StatusCreated {
+		t.Errorf("retry after a panic: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestIdempotencyStore_InProgressDeadline(t *testing.T) {
+	store := newIdempotencyStore(idempotencyTTL)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	if state, _, _ := store.begin("k", "fp"); state != idempotencyNew {
+		t.Fatalf("expected a new reservation, got %v", state)
+	}
+	if state, _, _ := store.begin("k", "fp"); state != idempotencyInProgress {
+		t.Fatalf("expected the key to be in progress, got %v", state)
+	}
+	// A request that never finished nor aborted stops holding its key.
+	now = now.Add(idempotencyDeadline + time.Second)
+	if state, _, _ := store.begin("k", "fp"); state != idempotencyNew {
+		t.Errorf("expected the stale reservation to be released, got %v", state)
+	}
+}
+
+func TestCreateDocument_CollectionQuota(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	idx := &fakeIndexer{}
+	s := &Server{
+		config:      &config.Config{Collections: map[string]config.CollectionConfig{"team": {}}},
+		logger:      slog.Default(),
+		ingester:    idx,
+		splitter:    ingest.NewTextLoader(1000, 0),
+		idempotency: newIdempotencyStore(idempotencyTTL),
+	}
+	quota := &pipeline.QuotaError{Collection: "team", Limit: "max_documents", Max: 2}
+	team := &fakeIndexer{fail: &pipeline.FileError{Stage: pipeline.StageIndex, Paths: []string{"notes/a.txt"}, Err: quota}}
+	s.collectionIngesters = map[string]documentIndexer{"team": team}
+	r := gin.New()
+	r.POST("/api/v1/documents", s.handleCreateDocument)
+
+	w := postDocument(r, "key-4", `{"path":"notes/a.txt","text":"hello","collection":"team"}`)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Error, Collection, Limit string
+		Max                      int64
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Collection != "team" || body.Limit != "max_documents" || body.Max != 2 || !strings.Contains(body.Error, `"team"`) {
+		t.Errorf("unexpected error body %s", w.Body)
+	}
+	if idx.calls != 0 || team.calls != 1 {
+		t.Errorf("expected the collection's ingester only, got %d and %d calls", idx.calls, team.calls)
+	}
+	// The refusal releases the key, and the document lands in the collection.
+	if w := postDocument(r, "key-4", `{"path":"notes/a.txt","text":"hello","collection":"team"}`); w.Code != http.StatusCreated {
+		t.Errorf("retry: expected 201, got %d: %s", w.Code, w.Body)
+	}
+	if w := postDocument(r, "", `{"text":"hello","collection":"wiki"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown collection: expected 400, got %d", w.Code)
+	}
+}
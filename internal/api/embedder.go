@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// EmbedderRequest is the PUT /api/v1/embedder request body. Empty fields
+// keep their current value.
+type EmbedderRequest struct {
+	Provider       string `json:"provider,omitempty"` // "openai" or "local"
+	Model          string `json:"model,omitempty"`
+	LocalModelPath string `json:"local_model_path,omitempty"`
+}
+
+// EmbedderResponse describes the embedder searches and indexing use.
+type EmbedderResponse struct {
+	Provider  string `json:"provider"`
+	Model     string `json:"model"`
+	Dimension int    `json:"dimension"`
+}
+
+// handleGetEmbedder reports the current embedder.
+func (s *Server) handleGetEmbedder(c *gin.Context) {
+	if s.searcher == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "search is not available"})
+		return
+	}
+	c.JSON(http.StatusOK, s.embedderResponse())
+}
+
+// handleSetEmbedder switches the embedder of the searcher, of the
+// collections sharing it and of document ingestion without a restart. The
+// new model must produce vectors of the dimension of the existing vector
+// indexes and, unless the server runs with --force, be the model their
+// fingerprints name; otherwise nothing changes and 409 explains how to
+// migrate. The switch lasts until the server restarts, so semango.yml
+// should be updated as well. It is an admin route, served only with a token
+// from server.auth.admin_token_env.
+func (s *Server) handleSetEmbedder(c *gin.Context) {
+	if s.searcher == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "search is not available"})
+		return
+	}
+	var req EmbedderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body: " + err.Error()})
+		return
+	}
+	if req == (EmbedderRequest{}) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider, model or local_model_path is required"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	s.embedderMu.Lock()
+	defer s.embedderMu.Unlock()
+	ec := s.searcher.EmbeddingConfig()
+	if req.Provider != "" {
+		ec.Provider = req.Provider
+	}
+	if req.Model != "" {
+		ec.Model = req.Model
+	}
+	if req.LocalModelPath != "" {
+		ec.LocalModelPath = req.LocalModelPath
+	}
+	if err := s.searcher.SetEmbedding(ctx, ec, s.sharedCollectionConfigs()...); err != nil {
+		if errors.Is(err, search.ErrDimensionMismatch) || errors.Is(err, search.ErrModelMismatch) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		// Load errors name model files and paths on the server; they go to
+		// the log only.
+		util.FromContext(ctx).Warn("Embedder switch refused", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the embedder could not be loaded; see the server log for the cause"})
+		return
+	}
+
+	// Indexing must not run with half-switched embedders.
+	s.ingestMu.Lock()
+	if mgr, ok := s.ingester.(*pipeline.Manager); ok {
+		mgr.SetEmbedders(ec, s.searcher.Embedder(), s.searcher.SpaceEmbedders())
+	}
+	for name, ingester := range s.collectionIngesters {
+		mgr, ok := ingester.(*pipeline.Manager)
+		if ok && s.config.Collections[name].Embedding == (config.SpaceEmbeddingConfig{}) {
+			mgr.SetEmbedders(ec, s.searcher.Embedder(), s.searcher.SpaceEmbedders())
+		}
+	}
+	s.ingestMu.Unlock()
+	s.pages.clear() // rankings of the old model must not be paged on
+	s.epoch.bump()  // nor revalidated
+	if s.probe != nil {
+		s.probe.setEmbedder(s.searcher.Embedder(), ec.Provider)
+		s.probe.check(ctx)
+	}
+	c.JSON(http.StatusOK, s.embedderResponse())
+}
+
+// sharedCollectionConfigs returns the configurations of the collections
+// searched with the default embedder.
+func (s *Server) sharedCollectionConfigs() []*config.Config {
+	var cfgs []*config.Config
+	for name, coll := range s.config.Collections {
+		if coll.Embedding != (config.SpaceEmbeddingConfig{}) || s.collections[name] == nil {
+			continue
+		}
+		if cfg, ok := s.config.ForCollection(name); ok {
+			cfgs = append(cfgs, cfg)
+		}
+	}
+	return cfgs
+}
+
+func (s *Server) embedderResponse() EmbedderResponse {
+	provider, model := s.searcher.EmbeddingConfig().Identity()
+	return EmbedderResponse{Provider: provider, Model: model, Dimension: s.searcher.Embedder().Dimension()}
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/util"
+	semangov1 "github.com/omarkamali/semango/pkg/proto/semango/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The gRPC service of pkg/proto/semango/v1/semango.proto is served by
+// grpc-go from the stubs generated by protoc-gen-go-grpc. Its calls are
+// routed through the REST router, so they share the port (HTTP/2 without
+// TLS through h2c), the request IDs and access log, one Searcher and one
+// auth layer.
+
+const grpcServiceName = "semango.v1.SemangoService"
+
+// grpcService implements semangov1.SemangoServiceServer on a Server.
+type grpcService struct {
+	semangov1.UnimplementedSemangoServiceServer
+	s *Server
+}
+
+// newGRPCServer returns the gRPC server of s, with the service registered.
+func (s *Server) newGRPCServer() *grpc.Server {
+	srv := grpc.NewServer(grpc.UnaryInterceptor(s.grpcAuth))
+	semangov1.RegisterSemangoServiceServer(srv, &grpcService{s: s})
+	return srv
+}
+
+// ginContextKey is the context key of the gin context of a gRPC call.
+type ginContextKey struct{}
+
+// serveGRPC serves POST /semango.v1.SemangoService/<method> with grpc-go.
+// The call's context carries the gin context, where handlers record the
+// principal and the query for the access log.
+func (s *Server) serveGRPC(c *gin.Context) {
+	if !strings.HasPrefix(c.GetHeader("Content-Type"), "application/grpc") {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "expected a gRPC request (Content-Type application/grpc)"})
+		return
+	}
+	ctx := context.WithValue(c.Request.Context(), ginContextKey{}, c)
+	s.grpcServer.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+}
+
+// ginContext returns the gin context of the gRPC call with context ctx.
+func ginContext(ctx context.Context) *gin.Context {
+	return ctx.Value(ginContextKey{}).(*gin.Context)
+}
+
+// grpcAuth checks the bearer token of every call but Health, which needs
+// none like /api/v1/ready, and applies the rate limit.
+func (s *Server) grpcAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if info.FullMethod != semangov1.SemangoService_Health_FullMethodName {
+		c := ginContext(ctx)
+		who, ok := s.auth.principal(c.Request)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+		}
+		c.Set(principalKey, who)
+		if s.limited(c) {
+			return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		}
+	}
+	return handler(ctx, req)
+}
+
+// grpcStatus maps a requestError to a gRPC status error.
+func grpcStatus(e *requestError) error {
+	code := codes.Internal
+	switch e.status {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusConflict:
+		code = codes.FailedPrecondition
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case http.StatusTooManyRequests, http.StatusRequestEntityTooLarge:
+		code = codes.ResourceExhausted
+	}
+	return status.Error(code, e.msg)
+}
+
+// Search mirrors POST /api/v1/search.
+func (g *grpcService) Search(ctx context.Context, r *semangov1.SearchRequest) (*semangov1.SearchResponse, error) {
+	s, c := g.s, ginContext(ctx)
+	if strings.TrimSpace(r.GetQuery()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	if s.searcher == nil {
+		return nil, status.Error(codes.Unavailable, "search is not available")
+	}
+	start := time.Now()
+	noteQuery(c, r.GetQuery())
+	req := SearchRequest{
+		Query:         r.GetQuery(),
+		TopK:          int(r.GetTopK()),
+		Filter:        r.GetFilter(),
+		Lang:          r.GetLang(),
+		Path:          r.GetPath(),
+		Mode:          r.GetMode(),
+		Parents:       r.GetParents(),
+		Window:        int(r.GetWindow()),
+		Boosts:        r.GetBoosts(),
+		MinGeneration: r.GetMinGeneration(),
+		Offset:        int(r.GetOffset()),
+		Space:         r.GetSpace(),
+		AsOf:          r.GetAsOf(),
+		Expand:        r.GetExpand(),
+		Queries:       r.GetQueries(),
+		Collection:    r.GetCollection(),
+		Collections:   r.GetCollections(),
+		MaxTextLength: int(r.GetMaxTextLength()),
+		Adaptive:      r.GetAdaptive(),
+		AdaptiveFull:  r.GetAdaptiveFull(),
+
+		GroupBy:         r.GetGroupBy(),
+		MaxChunksPerDoc: int(r.GetMaxChunksPerDoc()),
+		IncludeFullText: r.GetIncludeFullText(),
+		SnippetLength:   int(r.GetSnippetLength()),
+		LexicalSyntax:   r.GetLexicalSyntax(),
+	}
+	gen, snap, rerr := s.searchSource(ctx, req)
+	if rerr != nil {
+		return nil, grpcStatus(rerr)
+	}
+	plan, rerr := s.planSearch(req, gen, snap)
+	if rerr != nil {
+		return nil, grpcStatus(rerr)
+	}
+	res, err := s.runSearch(ctx, plan, start)
+	switch {
+	case errors.Is(err, errSearchTimeout):
+		return nil, status.Error(codes.DeadlineExceeded, "search timed out")
+	case errors.Is(err, errSearchCanceled):
+		return nil, status.Error(codes.Canceled, "search canceled")
+	case errors.Is(err, search.ErrDimensionMismatch), errors.Is(err, search.ErrModelMismatch):
+		util.FromContext(ctx).Error("Search failed", "error", err)
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		util.FromContext(ctx).Error("Search failed", "error", err)
+		return nil, status.Error(codes.Internal, "search failed")
+	}
+	noteResults(c, len(res.Results))
+
+	out := &semangov1.SearchResponse{
+		Mode:            res.Mode,
+		Space:           res.Space,
+		AsOf:            res.AsOf,
+		Generation:      res.Generation,
+		Offset:          int32(res.Offset),
+		TotalCandidates: int32(res.TotalCandidates),
+		Warnings:        res.Warnings,
+		Cutoff:          int32(res.Cutoff),
+		Results:         make([]*semangov1.SearchResult, len(res.Results)),
+	}
+	for i, r := range res.Results {
+		out.Results[i] = grpcResult(r)
+	}
+	return out, nil
+}
+
+// grpcResult converts an API search result, and the chunks nested in it,
+// to its gRPC message.
+func grpcResult(r SearchResult) *semangov1.SearchResult {
+	out := &semangov1.SearchResult{
+		Rank:          int32(r.Rank),
+		Id:            r.ID,
+		Score:         r.Score,
+		LexicalScore:  r.LexicalScore,
+		SemanticScore: r.SemanticScore,
+		Modality:      r.Modality,
+		Path:          r.Document.Path,
+		Text:          r.Chunk,
+		Meta:          r.Document.Meta,
+		Link:          r.Link,
+		Truncated:     r.Truncated,
+		Collection:    r.Collection,
+		Window:        r.Window,
+		Aliases:       r.Aliases,
+		Snippet:       r.Snippet,
+	}
+	for _, c := range r.Chunks {
+		out.Chunks = append(out.Chunks, grpcResult(c))
+	}
+	return out
+}
+
+// Index mirrors POST /api/v1/documents.
+func (g *grpcService) Index(ctx context.Context, r *semangov1.IndexRequest) (*semangov1.IndexResponse, error) {
+	s := g.s
+	if s.ingester == nil {
+		return nil, status.Error(codes.Unavailable, "document ingestion is not available")
+	}
+	if strings.TrimSpace(r.GetText()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "text is required")
+	}
+	ingester, rerr := s.collectionIngester(r.GetCollection())
+	if rerr != nil {
+		return nil, grpcStatus(rerr)
+	}
+	req := DocumentRequest{Path: r.GetPath(), Text: r.GetText(), Meta: r.GetMeta(), Collection: r.GetCollection()}
+	path, ids, err := s.indexDocument(ctx, ingester, req)
+	if err != nil {
+		var quota *pipeline.QuotaError
+		if errors.As(err, &quota) {
+			return nil, status.Error(codes.ResourceExhausted, quota.Error())
+		}
+		util.FromContext(ctx).Error("Document ingestion failed", "path", path, "error", err)
+		if indexMismatch(err) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Error(codes.Internal, "document ingestion failed")
+	}
+	return &semangov1.IndexResponse{Path: path, ChunkIds: ids, Generation: s.documentGeneration(req.Collection).Number}, nil
+}
+
+// Stats mirrors GET /api/v1/stats.
+func (g *grpcService) Stats(ctx context.Context, r *semangov1.StatsRequest) (*semangov1.StatsResponse, error) {
+	s := g.s
+	if s.searcher == nil {
+		return nil, status.Error(codes.Unavailable, "stats are not available")
+	}
+	searcher := s.searcher
+	if name := r.GetCollection(); name != "" {
+		cs, rerr := s.collectionSearcher(name, "")
+		if rerr != nil {
+			return nil, grpcStatus(rerr)
+		}
+		searcher = cs
+	}
+	stats, err := searcher.GetStats(ctx)
+	if err != nil {
+		util.FromContext(ctx).Error("Failed to get stats", "error", err)
+		return nil, status.Error(codes.Internal, "failed to get stats")
+	}
+	out := &semangov1.StatsResponse{
+		TotalDocuments:    int64(stats.TotalDocuments),
+		TotalChunks:       int64(stats.TotalChunks),
+		IndexSizeBytes:    stats.IndexSize,
+		Vectors:           stats.Vectors,
+		SpaceVectors:      stats.SpaceVectors,
+		ChunksByModality:  int64Counts(stats.ChunksByModality),
+		FilesByExtension:  int64Counts(stats.FilesByExtension),
+		LexicalIndexBytes: stats.LexicalIndexSize,
+		VectorIndexBytes:  stats.VectorIndexSize,
+		EmbeddingProvider: stats.EmbeddingProvider,
+		EmbeddingModel:    stats.EmbeddingModel,
+		Dimension:         int32(stats.Dimension),
+		Generation:        stats.Generation,
+	}
+	if stats.IndexedAt != nil {
+		out.IndexedAt = stats.IndexedAt.Format(time.RFC3339)
+	}
+	return out, nil
+}
+
+func int64Counts(counts map[string]int) map[string]int64 {
+	out := make(map[string]int64, len(counts))
+	for k, v := range counts {
+		out[k] = int64(v)
+	}
+	return out
+}
+
+// Health mirrors /api/v1/ready.
+func (g *grpcService) Health(context.Context, *semangov1.HealthRequest) (*semangov1.HealthResponse, error) {
+	s := g.s
+	if s.probe == nil {
+		return &semangov1.HealthResponse{Status: "ready", EmbedderReady: true}, nil
+	}
+	st := s.probe.Status()
+	resp := &semangov1.HealthResponse{Status: "ready", EmbedderReady: st.Ready, EmbedderError: st.LastError}
+	if !st.Ready {
+		resp.Status = "unavailable"
+	}
+	return resp, nil
+}
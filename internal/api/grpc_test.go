@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+	semangov1 "github.com/omarkamali/semango/pkg/proto/semango/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newGRPCTestServer serves a Server without a searcher on a local port and
+// returns its router and a client connected to it.
+func newGRPCTestServer(t *testing.T, tokens string) (*gin.Engine, *grpc.ClientConn) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("SEMANGO_TEST_TOKENS", tokens)
+	cfg := &config.Config{}
+	cfg.Lexical.IndexPath = filepath.Join(t.TempDir(), "bleve")
+	s := &Server{
+		config: cfg,
+		logger: slog.Default(),
+		auth:   newTokenAuth(config.AuthConfig{Type: "token", TokenEnv: "SEMANGO_TEST_TOKENS"}),
+		router: gin.New(),
+	}
+	s.setupRoutes()
+	ts := httptest.NewServer(s.httpHandler())
+	t.Cleanup(ts.Close)
+	conn, err := grpc.NewClient(ts.Listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return s.router, conn
+}
+
+// withToken sends token as the bearer token of the calls made with ctx.
+func withToken(ctx context.Context, token string) context.Context {
+	if token == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestGRPC_Health(t *testing.T) {
+	_, conn := newGRPCTestServer(t, "secret")
+	resp, err := semangov1.NewSemangoServiceClient(conn).Health(context.Background(), &semangov1.HealthRequest{})
+	if err != nil {
+		t.Fatalf("Health without a token: %v", err)
+	}
+	if resp.GetStatus() != "ready" || !resp.GetEmbedderReady() {
+		t.Errorf("unexpected health response: %v", resp)
+	}
+}
+
+func TestGRPC_Statuses(t *testing.T) {
+	r, conn := newGRPCTestServer(t, "secret, other")
+	client := semangov1.NewSemangoServiceClient(conn)
+	ctx := context.Background()
+	tests := []struct {
+		name, token string
+		call        func(context.Context) error
+		want        codes.Code
+	}{
+		{"no token", "", func(ctx context.Context) error {
+			_, err := client.Stats(ctx, &semangov1.StatsRequest{})
+			return err
+		}, codes.Unauthenticated},
+		{"wrong token", "guess", func(ctx context.Context) error {
+			_, err := client.Search(ctx, &semangov1.SearchRequest{Query: "x"})
+			return err
+		}, codes.Unauthenticated},
+		{"no searcher", "other", func(ctx context.Context) error {
+			_, err := client.Stats(ctx, &semangov1.StatsRequest{})
+			return err
+		}, codes.Unavailable},
+		{"empty query", "secret", func(ctx context.Context) error {
+			_, err := client.Search(ctx, &semangov1.SearchRequest{})
+			return err
+		}, codes.InvalidArgument},
+		{"unknown method", "secret", func(ctx context.Context) error {
+			return conn.Invoke(ctx, "/"+grpcServiceName+"/Delete", &semangov1.StatsRequest{}, &semangov1.StatsResponse{})
+		}, codes.Unimplemented},
+	}
+	for _, tt := range tests {
+		if got := status.Code(tt.call(withToken(ctx, tt.token))); got != tt.want {
+			t.Errorf("%s: code %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	hr := httptest.NewRequest(http.MethodPost, "/"+grpcServiceName+"/Health", nil)
+	hr.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, hr)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("non-gRPC request: status %d, want 415", w.Code)
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	r, _ := newGRPCTestServer(t, "secret")
+	get := func(path, token string) int {
+		hr := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			hr.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, hr)
+		return w.Code
+	}
+	if code := get("/api/v1/search?q=x", ""); code != http.StatusUnauthorized {
+		t.Errorf("search without token: status %d, want 401", code)
+	}
+	if code := get("/api/v1/search?q=x&mode=fuzzy", "secret"); code != http.StatusBadRequest {
+		t.Errorf("search with token: status %d, want it to reach the handler (400)", code)
+	}
+	if code := get("/api/v1/health", ""); code != http.StatusOK {
+		t.Errorf("health without token: status %d, want 200", code)
+	}
+
+	// Without configured tokens the API stays open.
+	if a := newTokenAuth(config.AuthConfig{Type: "token", TokenEnv: "SEMANGO_UNSET_TOKENS"}); a != nil {
+		t.Error("expected auth to be disabled without tokens")
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// idempotencyTTL is how long a completed response is kept for replay.
+const idempotencyTTL = 24 * time.Hour
+
+// idempotencyDeadline is how long a key stays reserved for a request that
+// neither finished nor aborted, e.g. because its server crashed mid-way,
+// before a retry may run it again.
+const idempotencyDeadline = 10 * time.Minute
+
+// maxIdempotencyKeyLen bounds the Idempotency-Key header.
+const maxIdempotencyKeyLen = 255
+
+type idempotencyState int
+
+const (
+	idempotencyNew        idempotencyState = iota // key reserved, process the request
+	idempotencyReplay                             // completed earlier, replay the stored response
+	idempotencyInProgress                         // the first request is still running
+	idempotencyMismatch                           // key reused with a different request body
+)
+
+type idempotencyEntry struct {
+	fingerprint string
+	done        bool
+	status      int
+	body        []byte
+	expires     time.Time
+}
+
+// idempotencyStore remembers responses to requests sent with an
+// Idempotency-Key header so that a client retrying after a timeout gets the
+// original result instead of triggering the work a second time. Entries live
+// in memory and expire after ttl.
+type idempotencyStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]*idempotencyEntry
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]*idempotencyEntry{},
+	}
+}
+
+// begin reserves key, until idempotencyDeadline, for a request whose body
+// hashes to fingerprint. For a replay it also returns the stored status and
+// body.
+func (s *idempotencyStore) begin(key, fingerprint string) (idempotencyState, int, []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for k, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+
+	e, ok := s.entries[key]
+	switch {
+	case !ok:
+		s.entries[key] = &idempotencyEntry{fingerprint: fingerprint, expires: now.Add(idempotencyDeadline)}
+		return idempotencyNew, 0, nil
+	case e.fingerprint != fingerprint:
+		return idempotencyMismatch, 0, nil
+	case !e.done:
+		return idempotencyInProgress, 0, nil
+	default:
+		return idempotencyReplay, e.status, e.body
+	}
+}
+
+// finish records the response for key so later retries can replay it.
+func (s *idempotencyStore) finish(key string, status int, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok {
+		e.done = true
+		e.status = status
+		e.body = body
+		e.expires = s.now().Add(s.ttl)
+	}
+}
+
+// abort releases key after a failed request so the client may retry it.
+func (s *idempotencyStore) abort(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
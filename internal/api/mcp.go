@@ -0,0 +1,325 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// Version is the Semango version reported to MCP clients; the CLI sets it
+// from its build information.
+var Version = "dev"
+
+// mcpPath is where the MCP endpoint is served, over the Streamable HTTP
+// transport: each JSON-RPC message is POSTed and answered with a JSON body.
+const mcpPath = "/mcp"
+
+// mcpProtocolVersions are the MCP revisions the endpoint speaks, newest
+// first. A client asking for another one is offered the newest.
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// mcpPageSize is how many documents resources/list returns per page.
+const mcpPageSize = 100
+
+// mcpDocumentScheme prefixes the URI of every indexed document, followed by
+// its path, e.g. semango://documents/docs/guide.md.
+const mcpDocumentScheme = "semango://documents/"
+
+// JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+	mcpNotFound       = -32002 // resource not found, as defined by MCP
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// mcpTool is a tool as listed by tools/list.
+type mcpTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// mcpResource is a document as listed by resources/list.
+type mcpResource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType"`
+}
+
+// mcpContent is one block of tool output.
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// mcpSearchTool searches the index, like POST /api/v1/search.
+var mcpSearchTool = mcpTool{
+	Name:        "search",
+	Description: "Hybrid lexical and semantic search over the indexed documents. Returns the best matching chunks with their path and the URI of the document, which can be read as a resource.",
+	InputSchema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query":  map[string]any{"type": "string", "description": "What to search for"},
+			"top_k":  map[string]any{"type": "integer", "description": "Number of results, default 10"},
+			"filter": map[string]any{"type": "string", "description": "Metadata filter of key:value terms, e.g. source:EmailLoader"},
+			"path":   map[string]any{"type": "string", "description": "Search within this document only"},
+			"mode":   map[string]any{"type": "string", "enum": []string{search.ModeHybrid, search.ModeLexical, search.ModeVector}},
+		},
+		"required": []string{"query"},
+	},
+}
+
+// handleMCP serves the Model Context Protocol endpoint: the search tool and
+// the indexed documents as resources that agents can list and read.
+// Notifications are acknowledged with 202 and no body.
+func (s *Server) handleMCP(c *gin.Context) {
+	var req rpcRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"),
+			Error: &rpcError{rpcParseError, "invalid JSON-RPC message: " + err.Error()}})
+		return
+	}
+	if req.ID == nil {
+		c.Status(http.StatusAccepted)
+		return
+	}
+	resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		resp.Error = &rpcError{rpcInvalidRequest, "expected a JSON-RPC 2.0 request"}
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	result, err := s.callMCP(c, req.Method, req.Params)
+	if err != nil {
+		var rerr *rpcError
+		if !errors.As(err, &rerr) {
+			util.FromContext(c.Request.Context()).Error("MCP request failed", "method", req.Method, "error", err)
+			rerr = &rpcError{rpcInternalError, "internal error"}
+		}
+		resp.Error = rerr
+	} else {
+		resp.Result = result
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// callMCP runs one MCP method.
+func (s *Server) callMCP(c *gin.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(params, &p)
+		version := mcpProtocolVersions[0]
+		for _, v := range mcpProtocolVersions {
+			if v == p.ProtocolVersion {
+				version = v
+			}
+		}
+		return gin.H{
+			"protocolVersion": version,
+			"capabilities":    gin.H{"tools": gin.H{}, "resources": gin.H{}},
+			"serverInfo":      gin.H{"name": "semango", "version": Version},
+		}, nil
+	case "ping":
+		return gin.H{}, nil
+	case "tools/list":
+		return gin.H{"tools": []mcpTool{mcpSearchTool}}, nil
+	case "tools/call":
+		return s.mcpCallTool(c, params)
+	case "resources/list":
+		return s.mcpListResources(c, params)
+	case "resources/templates/list":
+		return gin.H{"resourceTemplates": []gin.H{{
+			"uriTemplate": mcpDocumentScheme + "{+path}",
+			"name":        "Indexed document",
+			"description": "The text of an indexed document, by its path relative to the indexed directory",
+			"mimeType":    "text/plain",
+		}}}, nil
+	case "resources/read":
+		return s.mcpReadResource(c, params)
+	default:
+		return nil, &rpcError{rpcMethodNotFound, "unknown method " + method}
+	}
+}
+
+// mcpCallTool runs the search tool. Invalid arguments and failed searches
+// are reported as tool errors, so the model sees them, rather than as
+// protocol errors.
+func (s *Server) mcpCallTool(c *gin.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		Name      string `json:"name"`
+		Arguments struct {
+			Query  string `json:"query"`
+			TopK   int    `json:"top_k"`
+			Filter string `json:"filter"`
+			Path   string `json:"path"`
+			Mode   string `json:"mode"`
+		} `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{rpcInvalidParams, err.Error()}
+	}
+	if p.Name != mcpSearchTool.Name {
+		return nil, &rpcError{rpcInvalidParams, "unknown tool " + p.Name}
+	}
+	toolError := func(msg string) any {
+		return gin.H{"content": []mcpContent{{Type: "text", Text: msg}}, "isError": true}
+	}
+	a := p.Arguments
+	if strings.TrimSpace(a.Query) == "" {
+		return toolError("query is required"), nil
+	}
+	if s.searcher == nil {
+		return toolError("search is not available"), nil
+	}
+
+	start := time.Now()
+	noteQuery(c, a.Query)
+	// The chunks are the context the client asked for, not a preview.
+	req := SearchRequest{Query: a.Query, TopK: a.TopK, Filter: a.Filter, Path: a.Path, Mode: a.Mode, IncludeFullText: true}
+	gen, snap, rerr := s.searchSource(c.Request.Context(), req)
+	if rerr != nil {
+		return toolError(rerr.msg), nil
+	}
+	plan, rerr := s.planSearch(req, gen, snap)
+	if rerr != nil {
+		return toolError(rerr.msg), nil
+	}
+	res, err := s.runSearch(c.Request.Context(), plan, start)
+	if errors.Is(err, errSearchTimeout) {
+		return toolError("search timed out"), nil
+	}
+	if err != nil {
+		util.FromContext(c.Request.Context()).Error("Search failed", "error", err)
+		return toolError("search failed"), nil
+	}
+	noteResults(c, len(res.Results))
+
+	var b strings.Builder
+	if len(res.Results) == 0 {
+		b.WriteString("No results.")
+	}
+	for _, r := range res.Results {
+		fmt.Fprintf(&b, "%d. %s (score %.3f, %s)\n%s\n\n", r.Rank, r.Document.Path, r.Score, mcpDocumentURI(r.Document.Path), r.Chunk)
+	}
+	return gin.H{
+		"content":           []mcpContent{{Type: "text", Text: strings.TrimSpace(b.String())}},
+		"structuredContent": res,
+	}, nil
+}
+
+// mcpListResources lists the indexed documents a page at a time. The
+// cursor is opaque to clients; it encodes the last path of the page.
+func (s *Server) mcpListResources(c *gin.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		Cursor string `json:"cursor"`
+	}
+	json.Unmarshal(params, &p)
+	after := ""
+	if p.Cursor != "" {
+		b, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+		if err != nil {
+			return nil, &rpcError{rpcInvalidParams, "invalid cursor"}
+		}
+		after = string(b)
+	}
+	if s.searcher == nil {
+		return gin.H{"resources": []mcpResource{}}, nil
+	}
+	docs, next, err := s.searcher.ListDocuments(c.Request.Context(), after, mcpPageSize)
+	if err != nil {
+		return nil, err
+	}
+	resources := make([]mcpResource, len(docs))
+	for i, d := range docs {
+		resources[i] = mcpResource{
+			URI:         mcpDocumentURI(d.Path),
+			Name:        d.Path,
+			Description: fmt.Sprintf("%s document, %d chunks", d.Modality, d.Chunks),
+			MimeType:    "text/plain",
+		}
+	}
+	out := gin.H{"resources": resources}
+	if next != "" {
+		out["nextCursor"] = base64.RawURLEncoding.EncodeToString([]byte(next))
+	}
+	return out, nil
+}
+
+// mcpReadResource returns the text of one document, rebuilt from its
+// indexed chunks.
+func (s *Server) mcpReadResource(c *gin.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{rpcInvalidParams, err.Error()}
+	}
+	path, ok := mcpDocumentPath(p.URI)
+	if !ok {
+		return nil, &rpcError{rpcInvalidParams, "not a document URI: " + p.URI}
+	}
+	if s.searcher == nil {
+		return nil, &rpcError{mcpNotFound, "resource not found: " + p.URI}
+	}
+	doc, err := s.searcher.ReadDocument(c.Request.Context(), path)
+	if errors.Is(err, search.ErrDocumentNotFound) {
+		return nil, &rpcError{mcpNotFound, "resource not found: " + p.URI}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{"contents": []gin.H{{"uri": p.URI, "mimeType": "text/plain", "text": doc.Text}}}, nil
+}
+
+// mcpDocumentURI returns the resource URI of the document at path. Each
+// path segment is escaped, so the slashes stay readable.
+func mcpDocumentURI(path string) string {
+	return mcpDocumentScheme + (&url.URL{Path: path}).EscapedPath()
+}
+
+// mcpDocumentPath is the inverse of mcpDocumentURI.
+func mcpDocumentPath(uri string) (string, bool) {
+	rest, ok := strings.CutPrefix(uri, mcpDocumentScheme)
+	if !ok || rest == "" {
+		return "", false
+	}
+	path, err := url.PathUnescape(rest)
+	return path, err == nil
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMCP_Protocol(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := &Server{}
+	r := gin.New()
+	r.POST(mcpPath, s.handleMCP)
+
+	call := func(body string) (int, rpcResponse, map[string]any) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, mcpPath, strings.NewReader(body)))
+		var resp rpcResponse
+		var result map[string]any
+		if w.Body.Len() > 0 {
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("%s: invalid response %q", body, w.Body.String())
+			}
+			b, _ := json.Marshal(resp.Result)
+			json.Unmarshal(b, &result)
+		}
+		return w.Code, resp, result
+	}
+
+	_, resp, result := call(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`)
+	if string(resp.ID) != "1" || result["protocolVersion"] != "2025-03-26" {
+		t.Errorf("initialize: id %s, result %v", resp.ID, result)
+	}
+	if _, _, result = call(`{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`); result["protocolVersion"] != mcpProtocolVersions[0] {
+		t.Errorf("unsupported version should be answered with the newest, got %v", result["protocolVersion"])
+	}
+	if code, _, _ := call(`{"jsonrpc":"2.0","method":"notifications/initialized"}`); code != http.StatusAccepted {
+		t.Errorf("notification: status %d, want 202", code)
+	}
+
+	_, _, result = call(`{"jsonrpc":"2.0","id":"t","method":"tools/list"}`)
+	if tools, _ := result["tools"].([]any); len(tools) != 1 || tools[0].(map[string]any)["name"] != "search" {
+		t.Errorf("tools/list: %v", result)
+	}
+	_, _, result = call(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"search","arguments":{}}}`)
+	if result["isError"] != true {
+		t.Errorf("search without a query should be a tool error: %v", result)
+	}
+	if _, resp, _ = call(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"delete"}}`); resp.Error == nil || resp.Error.Code != rpcInvalidParams {
+		t.Errorf("unknown tool: %+v", resp.Error)
+	}
+
+	_, _, result = call(`{"jsonrpc":"2.0","id":5,"method":"resources/list"}`)
+	if res, ok := result["resources"].([]any); !ok || len(res) != 0 {
+		t.Errorf("resources/list without an index: %v", result)
+	}
+	if _, resp, _ = call(`{"jsonrpc":"2.0","id":6,"method":"resources/list","params":{"cursor":"%%"}}`); resp.Error == nil || resp.Error.Code != rpcInvalidParams {
+		t.Errorf("invalid cursor: %+v", resp.Error)
+	}
+	if _, resp, _ = call(`{"jsonrpc":"2.0","id":7,"method":"resources/read","params":{"uri":"semango://documents/missing.md"}}`); resp.Error == nil || resp.Error.Code != mcpNotFound {
+		t.Errorf("missing document: %+v", resp.Error)
+	}
+	if _, resp, _ = call(`{"jsonrpc":"2.0","id":8,"method":"resources/read","params":{"uri":"file:///etc/passwd"}}`); resp.Error == nil || resp.Error.Code != rpcInvalidParams {
+		t.Errorf("foreign URI: %+v", resp.Error)
+	}
+
+	if _, resp, _ = call(`{"jsonrpc":"2.0","id":9,"method":"sampling/createMessage"}`); resp.Error == nil || resp.Error.Code != rpcMethodNotFound {
+		t.Errorf("unknown method: %+v", resp.Error)
+	}
+	if code, resp, _ := call(`{not json`); code != http.StatusBadRequest || resp.Error == nil || resp.Error.Code != rpcParseError {
+		t.Errorf("malformed message: %d %+v", code, resp.Error)
+	}
+}
+
+func TestMCPDocumentURI(t *testing.T) {
+	for _, path := range []string{"docs/guide.md", "notes/a b#1.txt", "archive.zip!/inner/x.md", "../shared/ü.md"} {
+		uri := mcpDocumentURI(path)
+		if strings.ContainsAny(strings.TrimPrefix(uri, mcpDocumentScheme), " #") {
+			t.Errorf("URI %q is not escaped", uri)
+		}
+		if got, ok := mcpDocumentPath(uri); !ok || got != path {
+			t.Errorf("round trip of %q: got %q, %v", path, got, ok)
+		}
+	}
+	if _, ok := mcpDocumentPath(mcpDocumentScheme); ok {
+		t.Error("URI without a path accepted")
+	}
+}
@@ -0,0 +1,502 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/ingest/tabular"
+	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/report"
+	"github.com/omarkamali/semango/internal/search"
+)
+
+// apiRoute describes an /api/v1 endpoint once, for both the router and the
+// OpenAPI document served at /api/v1/openapi.json, so the two cannot drift.
+// Request and response bodies are given as values of their Go types; their
+// schemas are derived from the json struct tags.
+type apiRoute struct {
+	Method      string
+	Path        string // relative to /api/v1, in gin syntax
+	Summary     string
+	Description string
+	Handler     gin.HandlerFunc
+	Public      bool // served without a bearer token
+	Admin       bool // needs an admin token rather than an API token
+	Params      []apiParam
+	Body        any // request body, nil when there is none
+	Responses   []apiResponse
+}
+
+// apiParam is a query or header parameter.
+type apiParam struct {
+	Name        string
+	In          string // "query" or "header"
+	Type        string // JSON schema type of one value
+	Description string
+	Required    bool
+	Repeated    bool
+}
+
+// apiResponse is a documented response. Body is nil for empty responses.
+type apiResponse struct {
+	Status      int
+	Description string
+	Body        any
+	ContentType string // defaults to application/json
+}
+
+// ErrorResponse is the body of error responses.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// QuotaErrorResponse is the body of errors for requests refused by a
+// collection's quota.
+type QuotaErrorResponse struct {
+	Error      string  `json:"error"`
+	Collection string  `json:"collection"`
+	Limit      string  `json:"limit"` // max_documents, max_bytes or max_qps
+	Max        float64 `json:"max"`
+}
+
+// HealthResponse is the body of /api/v1/health and /api/v1/ready.
+type HealthResponse struct {
+	Status   string          `json:"status"`
+	Time     *time.Time      `json:"time,omitempty"`
+	Embedder *EmbedderStatus `json:"embedder,omitempty"`
+}
+
+var (
+	errBadRequest   = apiResponse{Status: http.StatusBadRequest, Description: "Invalid request", Body: ErrorResponse{}}
+	errUnauthorized = apiResponse{Status: http.StatusUnauthorized, Description: "Missing or invalid bearer token", Body: ErrorResponse{}}
+	errAdminOff     = apiResponse{Status: http.StatusForbidden, Description: "Admin endpoints are disabled: server.auth.admin_token_env holds no tokens", Body: ErrorResponse{}}
+	errInternal     = apiResponse{Status: http.StatusInternalServerError, Description: "Internal error", Body: ErrorResponse{}}
+
+	// errTooManyRequests is added to every authenticated route, as all of
+	// them are rate limited; searches also count against the max_qps quota
+	// of their collections.
+	errTooManyRequests = apiResponse{Status: http.StatusTooManyRequests, Description: "Rate limit or a collection's max_qps quota exceeded; retry after the number of seconds in the Retry-After header", Body: ErrorResponse{}}
+)
+
+// apiRoutes lists every /api/v1 endpoint.
+func (s *Server) apiRoutes() []apiRoute {
+	searchResponses := []apiResponse{
+		{Status: http.StatusOK, Description: "Ranked results", Body: SearchResponse{}},
+		errBadRequest,
+		errUnauthorized,
+		{Status: http.StatusConflict, Description: "The index is older than min_generation or min_indexed_at, or changed since the cursor was issued", Body: ErrorResponse{}},
+		errInternal,
+		{Status: http.StatusGatewayTimeout, Description: "The search took longer than server.search_timeout", Body: ErrorResponse{}},
+	}
+	return []apiRoute{
+		{
+			Method: http.MethodGet, Path: "/search", Handler: s.handleSearchGet,
+			Summary:     "Search (cacheable)",
+			Description: "Takes the search request from the query string. Responses carry an ETag and Last-Modified for the index generation, and conditional requests get 304 while the index is unchanged.",
+			Params: []apiParam{
+				{Name: "q", In: "query", Type: "string", Required: true, Repeated: true, Description: "Query text; further q parameters are query variants whose results are merged"},
+				{Name: "top_k", In: "query", Type: "integer", Description: "Number of results, at most 100"},
+				{Name: "filter", In: "query", Type: "string", Description: "Metadata filter of key:value terms"},
+				{Name: "lang", In: "query", Type: "string", Description: "Language hint, e.g. de or pt-BR, or auto to detect it from the query"},
+				{Name: "path", In: "query", Type: "string", Description: "Search within this document only"},
+				{Name: "mode", In: "query", Type: "string", Description: "hybrid, lexical or vector"},
+				{Name: "lexical_syntax", In: "query", Type: "string", Description: "advanced reads \"phrases\", +required and -excluded terms, key:value and fuzzy~ terms in q"},
+				{Name: "parents", In: "query", Type: "boolean", Description: "Return parent sections instead of chunks"},
+				{Name: "window", In: "query", Type: "integer", Description: "Widen each result to this many chunks before and after it, at most 10"},
+				{Name: "group_by", In: "query", Type: "string", Description: "document returns one result per document with its other matching chunks nested"},
+				{Name: "max_chunks_per_doc", In: "query", Type: "integer", Description: "Keep at most this many chunks of a document, counting nested ones"},
+				{Name: "boost", In: "query", Type: "string", Repeated: true, Description: "Score multiplier by path pattern, as pattern=factor"},
+				{Name: "space", In: "query", Type: "string", Description: "Vector space from embedding.spaces"},
+				{Name: "offset", In: "query", Type: "integer", Description: "Number of results to skip"},
+				{Name: "cursor", In: "query", Type: "string", Description: "next_cursor of the previous page"},
+				{Name: "min_generation", In: "query", Type: "integer", Description: "Fail with 409 below this index generation"},
+				{Name: "min_indexed_at", In: "query", Type: "string", Description: "Fail with 409 if the index was last written before this RFC 3339 time"},
+				{Name: "expand", In: "query", Type: "string", Description: "Query expansion: none, terms, hyde or multi (hyde and multi need an LLM)"},
+				{Name: "as_of", In: "query", Type: "string", Description: "Search this snapshot from the snapshot directory instead of the live index"},
+				{Name: "max_text_length", In: "query", Type: "integer", Description: "Cut each result's chunk to this many characters"},
+				{Name: "include_full_text", In: "query", Type: "boolean", Description: "Return each result's chunk text, not only a snippet"},
+				{Name: "snippet_length", In: "query", Type: "integer", Description: "Characters per result snippet"},
+				{Name: "adaptive", In: "query", Type: "boolean", Description: "Return results only down to the first steep score drop"},
+				{Name: "adaptive_full", In: "query", Type: "boolean", Description: "Like adaptive, but keep the results below the drop and only report it as cutoff"},
+				{Name: "collection", In: "query", Type: "string", Repeated: true, Description: "Search this collection from the collections section instead of the default index; repeat it to search several collections and merge their results"},
+				{Name: "If-None-Match", In: "header", Type: "string", Description: "ETag of a previous response"},
+			},
+			Responses: append(searchResponses, apiResponse{Status: http.StatusNotModified, Description: "The index has not changed"}),
+		},
+		{
+			Method: http.MethodPost, Path: "/search", Handler: s.handleSearch,
+			Summary: "Search",
+			Body:    SearchRequest{}, Responses: searchResponses,
+		},
+		{
+			Method: http.MethodPost, Path: "/documents", Handler: s.handleCreateDocument,
+			Summary:     "Index a document",
+			Description: "Chunks, embeds and indexes the text. Re-sending a path replaces its chunks.",
+			Params: []apiParam{
+				{Name: "Idempotency-Key", In: "header", Type: "string", Description: "Makes retries safe: a replay returns the original response"},
+			},
+			Body: DocumentRequest{},
+			Responses: []apiResponse{
+				{Status: http.StatusCreated, Description: "The document was indexed", Body: DocumentResponse{}},
+				errBadRequest,
+				errUnauthorized,
+				{Status: http.StatusConflict, Description: "A request with this Idempotency-Key is still being processed", Body: ErrorResponse{}},
+				{Status: http.StatusRequestEntityTooLarge, Description: "The collection is at its max_documents or max_bytes quota", Body: QuotaErrorResponse{}},
+				{Status: http.StatusUnprocessableEntity, Description: "The Idempotency-Key was used with a different body", Body: ErrorResponse{}},
+				errInternal,
+				{Status: http.StatusServiceUnavailable, Description: "Ingestion is not available", Body: ErrorResponse{}},
+			},
+		},
+		{
+			Method: http.MethodPost, Path: "/answer", Handler: s.handleAnswer,
+			Summary:     "Answer a question",
+			Description: "Searches the index for the question and has the configured LLM (see llm in the config) write an answer that cites the chunks it was given.",
+			Body:        AnswerRequest{},
+			Responses: []apiResponse{
+				{Status: http.StatusOK, Description: "Answer and sources", Body: AnswerResponse{}},
+				errBadRequest,
+				errUnauthorized,
+				errInternal,
+				{Status: http.StatusBadGateway, Description: "The LLM call failed", Body: ErrorResponse{}},
+				{Status: http.StatusServiceUnavailable, Description: "No LLM is configured", Body: ErrorResponse{}},
+				{Status: http.StatusGatewayTimeout, Description: "The search and answer took longer than server.search_timeout", Body: ErrorResponse{}},
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/stats", Handler: s.handleStats,
+			Summary:     "Index statistics",
+			Description: "Responses carry an ETag and Last-Modified for the index generation, and conditional requests get 304 while the index is unchanged.",
+			Params: []apiParam{
+				{Name: "collection", In: "query", Type: "string", Description: "Report on this collection from the collections section instead of the default index"},
+			},
+			Responses: []apiResponse{
+				{Status: http.StatusOK, Description: "Statistics", Body: search.Stats{}},
+				{Status: http.StatusNotModified, Description: "The index has not changed"},
+				errBadRequest,
+				errUnauthorized,
+				errInternal,
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/index/progress", Handler: s.handleIndexProgress,
+			Summary:     "Index run progress",
+			Description: "Progress of the running or last index run, as the index command records it next to the indexes: files and bytes done out of those found, chunks written, rates and the estimated time left.",
+			Params: []apiParam{
+				{Name: "collection", In: "query", Type: "string", Description: "Report on this collection from the collections section instead of the default index"},
+			},
+			Responses: []apiResponse{
+				{Status: http.StatusOK, Description: "Progress", Body: pipeline.Progress{}},
+				errBadRequest,
+				errUnauthorized,
+				{Status: http.StatusNotFound, Description: "No index run recorded", Body: ErrorResponse{}},
+				errInternal,
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/tables", Handler: s.handleListTables,
+			Summary:     "Indexed tabular datasets",
+			Description: "The CSV, TSV, JSON, Parquet and Excel files and SQLite tables in the index, with the table name SQL queries use for each and its columns. Conditional requests get 304 while the index is unchanged.",
+			Params: []apiParam{
+				{Name: "collection", In: "query", Type: "string", Description: "List the datasets of this collection from the collections section instead of the default index"},
+			},
+			Responses: []apiResponse{
+				{Status: http.StatusOK, Description: "Datasets", Body: TablesResponse{}},
+				{Status: http.StatusNotModified, Description: "The index has not changed"},
+				errBadRequest,
+				errUnauthorized,
+				errInternal,
+			},
+		},
+		{
+			Method: http.MethodPost, Path: "/tables/query", Handler: s.handleTableQuery,
+			Summary:     "Query tabular datasets with SQL",
+			Description: "Runs a single read-only SELECT statement over the indexed datasets it names, loaded from their files into an in-memory SQLite database with redacted columns redacted as in the index.",
+			Body:        TableQueryRequest{},
+			Responses: []apiResponse{
+				{Status: http.StatusOK, Description: "Columns and rows of the result, and the datasets read", Body: tabular.QueryResult{}},
+				{Status: http.StatusBadRequest, Description: "Invalid request, a statement other than a single SELECT, or an SQL error", Body: ErrorResponse{}},
+				errUnauthorized,
+				errInternal,
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/report", Handler: s.handleReport,
+			Summary:     "Index health report",
+			Description: "Index statistics, growth since the last report saved to the report directory, and the requests, error rate, zero-result searches, slowest queries and ranking cache hit rate this server saw since it started or last saved a report.",
+			Params: []apiParam{
+				{Name: "format", In: "query", Type: "string", Description: "json (the default) or html"},
+			},
+			Responses: []apiResponse{
+				{Status: http.StatusOK, Description: "Health report; an HTML page with format=html", Body: report.Report{}},
+				errBadRequest,
+				errUnauthorized,
+				errInternal,
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/embedder", Handler: s.handleGetEmbedder,
+			Summary: "Current embedder",
+			Responses: []apiResponse{
+				{Status: http.StatusOK, Description: "Provider, model and vector dimension", Body: EmbedderResponse{}},
+				errUnauthorized,
+				{Status: http.StatusServiceUnavailable, Description: "Search is not available", Body: ErrorResponse{}},
+			},
+		},
+		{
+			Method: http.MethodPut, Path: "/embedder", Handler: s.handleSetEmbedder, Admin: true,
+			Summary:     "Switch the embedder",
+			Description: "Loads the given provider or model, tries it, checks that its vectors have the dimension of the existing vector indexes and switches searches and document ingestion to it atomically. The switch lasts until the server restarts; update semango.yml to keep it. Needs a token from server.auth.admin_token_env, and is disabled without one.",
+			Body:        EmbedderRequest{},
+			Responses: []apiResponse{
+				{Status: http.StatusOK, Description: "The embedder now in use", Body: EmbedderResponse{}},
+				{Status: http.StatusBadRequest, Description: "Invalid request, or the embedder could not be loaded; the server log has the cause", Body: ErrorResponse{}},
+				{Status: http.StatusUnauthorized, Description: "Missing or invalid admin token", Body: ErrorResponse{}},
+				errAdminOff,
+				{Status: http.StatusConflict, Description: "The model's vector dimension does not match an existing vector index; the error says how to migrate", Body: ErrorResponse{}},
+				{Status: http.StatusServiceUnavailable, Description: "Search is not available", Body: ErrorResponse{}},
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/health", Handler: s.handleHealth, Public: true,
+			Summary: "Liveness",
+			Responses: []apiResponse{
+				{Status: http.StatusOK, Description: "The server is up", Body: HealthResponse{}},
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/ready", Handler: s.handleReady, Public: true,
+			Summary: "Readiness",
+			Responses: []apiResponse{
+				{Status: http.StatusOK, Description: "Searches can be served", Body: HealthResponse{}},
+				{Status: http.StatusServiceUnavailable, Description: "The embedder probe is failing", Body: HealthResponse{}},
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/openapi.json", Handler: s.handleOpenAPI, Public: true,
+			Summary: "This OpenAPI document",
+			Responses: []apiResponse{
+				{Status: http.StatusOK, Description: "OpenAPI 3 document", Body: map[string]any{}},
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/docs", Handler: handleSwaggerUI, Public: true,
+			Summary: "Swagger UI for this API",
+			Responses: []apiResponse{
+				{Status: http.StatusOK, Description: "HTML page", ContentType: "text/html"},
+			},
+		},
+	}
+}
+
+// handleOpenAPI serves the OpenAPI document generated from apiRoutes.
+func (s *Server) handleOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", s.openapi)
+}
+
+// swaggerUIPage loads Swagger UI from a CDN and points it at openapi.json.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Semango API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5.17.14/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5.17.14/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+func handleSwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
+
+// buildOpenAPI renders routes, mounted under basePath, as an OpenAPI 3.0
+// document.
+func buildOpenAPI(routes []apiRoute, basePath string) ([]byte, error) {
+	g := &schemaGen{defs: map[string]any{}}
+	paths := map[string]map[string]any{}
+	for _, r := range routes {
+		op := map[string]any{"summary": r.Summary}
+		if r.Description != "" {
+			op["description"] = r.Description
+		}
+		if !r.Public {
+			op["security"] = []any{map[string]any{"bearerAuth": []string{}}}
+		}
+		var params []any
+		for _, p := range r.Params {
+			schema := map[string]any{"type": p.Type}
+			if p.Repeated {
+				schema = map[string]any{"type": "array", "items": schema}
+			}
+			param := map[string]any{"name": p.Name, "in": p.In, "schema": schema}
+			if p.Description != "" {
+				param["description"] = p.Description
+			}
+			if p.Required {
+				param["required"] = true
+			}
+			params = append(params, param)
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+		if r.Body != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": g.schema(reflect.TypeOf(r.Body))}},
+			}
+		}
+		resps := r.Responses
+		if !r.Public {
+			resps = append(resps[:len(resps):len(resps)], errTooManyRequests)
+		}
+		responses := map[string]any{}
+		for _, resp := range resps {
+			out := map[string]any{"description": resp.Description}
+			if resp.Body != nil || resp.ContentType != "" {
+				ct := resp.ContentType
+				if ct == "" {
+					ct = "application/json"
+				}
+				schema := map[string]any{"type": "string"}
+				if resp.Body != nil {
+					schema = g.schema(reflect.TypeOf(resp.Body))
+				}
+				out["content"] = map[string]any{ct: map[string]any{"schema": schema}}
+			}
+			responses[strconv.Itoa(resp.Status)] = out
+		}
+		op["responses"] = responses
+
+		path := basePath + openAPIPath(r.Path)
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(r.Method)] = op
+	}
+
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Semango API",
+			"version":     "1",
+			"description": "Hybrid lexical and semantic search over your documents.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": g.defs,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// openAPIPath turns gin path parameters (:id) into OpenAPI ones ({id}).
+func openAPIPath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// schemaGen derives JSON schemas from Go types the way encoding/json
+// encodes them. Named structs become shared component schemas.
+type schemaGen struct {
+	defs map[string]any
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *schemaGen) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = map[string]any{} // placeholder for recursive types
+			g.defs[t.Name()] = g.object(t)
+		}
+		return ref
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return g.object(t)
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	default: // interfaces
+		return map[string]any{}
+	}
+}
+
+// object describes the fields of struct t. Fields without omitempty are
+// listed as required; embedded structs are flattened as encoding/json does.
+func (g *schemaGen) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+				walk(f.Type)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = g.schema(f.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+	}
+	walk(t)
+	obj := map[string]any{"type": "object", "properties": props}
+	if required != nil {
+		obj["required"] = required
+	}
+	return obj
+}
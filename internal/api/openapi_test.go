@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPI_CoversRoutes(t *testing.T) {
+	r, _ := newGRPCTestServer(t, "secret")
+
+	// The spec is public even with auth enabled.
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var doc struct {
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, route := range r.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/v1/") {
+			continue
+		}
+		op, ok := doc.Paths[openAPIPath(route.Path)][strings.ToLower(route.Method)]
+		if !ok {
+			t.Errorf("%s %s is not documented", route.Method, route.Path)
+			continue
+		}
+		_, secured := op["security"]
+		public := route.Path == "/api/v1/health" || route.Path == "/api/v1/ready" ||
+			route.Path == "/api/v1/openapi.json" || route.Path == "/api/v1/docs"
+		if secured == public {
+			t.Errorf("%s %s: security = %v, want %v", route.Method, route.Path, secured, !public)
+		}
+	}
+
+	req := doc.Components.Schemas["SearchRequest"]
+	if req == nil {
+		t.Fatal("SearchRequest schema missing")
+	}
+	if got, _ := json.Marshal(req["required"]); string(got) != `["query"]` {
+		t.Errorf("SearchRequest required = %s, want [\"query\"]", got)
+	}
+	props := req["properties"].(map[string]any)
+	boosts, _ := json.Marshal(props["boosts"])
+	if string(boosts) != `{"additionalProperties":{"type":"number"},"type":"object"}` {
+		t.Errorf("boosts schema = %s", boosts)
+	}
+	if _, ok := doc.Components.Schemas["SearchResult"]; !ok {
+		t.Error("SearchResult schema missing")
+	}
+}
+
+func TestOpenAPI_SwaggerUI(t *testing.T) {
+	r, _ := newGRPCTestServer(t, "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/docs", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `url: "openapi.json"`) {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+}
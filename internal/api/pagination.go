@@ -0,0 +1,196 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/omarkamali/semango/internal/search"
+)
+
+// Paging: the ranking behind a search is kept for pageCacheTTL, so follow-up
+// pages are cut from the list the first page came from. When a page reaches
+// past the cached ranking, the search is re-run deeper and only the new
+// candidates are appended; results already served keep their ranks, so pages
+// never repeat or skip a result while the entry lives. After it expires, the
+// search is re-ranked deterministically for the requested depth.
+
+const (
+	pageCacheTTL        = 5 * time.Minute
+	pageCacheMaxEntries = 100
+	// maxPageDepth bounds offset+top_k, i.e. how deep a client can page.
+	maxPageDepth = 1000
+)
+
+// pageCursor is the state behind an opaque next_cursor.
+type pageCursor struct {
+	Offset     int    `json:"o"`
+	Generation uint64 `json:"g"`
+	Key        string `json:"k"` // pageKey of the search it belongs to
+}
+
+func encodeCursor(c pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errors.New("malformed cursor")
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.Key == "" || c.Offset < 0 {
+		return c, errors.New("malformed cursor")
+	}
+	return c, nil
+}
+
+// pageKey identifies the ranking a page is cut from: the request without its
+// paging and freshness fields. mode is the resolved search mode.
+func pageKey(req SearchRequest, mode string) string {
+	req.TopK, req.Offset, req.Cursor = 0, 0, ""
+	req.MaxTextLength, req.SnippetLength, req.IncludeFullText = 0, 0, false // cut after ranking
+	req.MinGeneration, req.MinIndexedAt = 0, ""
+	req.Mode = mode
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// rankedList is a cached ranking of the first len(results) candidates.
+type rankedList struct {
+	results    []search.Result
+	total      int
+	warnings   []string
+	cutoff     int
+	generation uint64
+	expires    time.Time
+}
+
+// page returns up to limit results from rank offset.
+func (l *rankedList) page(offset, limit int) search.Page {
+	if offset > len(l.results) {
+		offset = len(l.results)
+	}
+	end := offset + limit
+	if end > len(l.results) {
+		end = len(l.results)
+	}
+	return search.Page{Results: l.results[offset:end], Total: l.total, Warnings: l.warnings, Cutoff: l.cutoff}
+}
+
+// extend appends the candidates of a deeper ranking that l does not hold yet.
+func (l *rankedList) extend(deeper search.Page) {
+	seen := make(map[string]bool, len(l.results))
+	for _, r := range l.results {
+		seen[r.ID] = true
+	}
+	for _, r := range deeper.Results {
+		if !seen[r.ID] {
+			l.results = append(l.results, r)
+		}
+	}
+	if deeper.Total > l.total {
+		l.total = deeper.Total
+	}
+	l.warnings = deeper.Warnings
+	l.cutoff = deeper.Cutoff
+}
+
+// pageCache holds recent rankings by pageKey.
+type pageCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	max     int
+	now     func() time.Time
+	entries map[string]*rankedList
+}
+
+func newPageCache(ttl time.Duration, max int) *pageCache {
+	return &pageCache{ttl: ttl, max: max, now: time.Now, entries: map[string]*rankedList{}}
+}
+
+// get returns the live ranking for key at index generation gen, if any.
+func (pc *pageCache) get(key string, gen uint64) (*rankedList, bool) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	l, ok := pc.entries[key]
+	if !ok || l.generation != gen || pc.now().After(l.expires) {
+		return nil, false
+	}
+	return l, true
+}
+
+// put stores l under key, evicting expired entries and, when still full, the
+// entry closest to expiry.
+func (pc *pageCache) put(key string, l *rankedList) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	now := pc.now()
+	l.expires = now.Add(pc.ttl)
+	for k, e := range pc.entries {
+		if now.After(e.expires) {
+			delete(pc.entries, k)
+		}
+	}
+	if _, ok := pc.entries[key]; !ok && len(pc.entries) >= pc.max {
+		oldest := ""
+		for k, e := range pc.entries {
+			if oldest == "" || e.expires.Before(pc.entries[oldest].expires) {
+				oldest = k
+			}
+		}
+		delete(pc.entries, oldest)
+	}
+	pc.entries[key] = l
+}
+
+// clear drops every ranking, e.g. when the embedder changed.
+func (pc *pageCache) clear() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	clear(pc.entries)
+}
+
+// searchPage returns limit results from rank offset for req, run by
+// searcher. Searches
+// within a path are sorted by position per page, so they are re-ranked for
+// every page instead of being cached.
+func (s *Server) searchPage(ctx context.Context, searcher search.Pager, req SearchRequest, key string, gen uint64, offset, limit int, opts search.Options) (search.Page, error) {
+	if opts.Path != "" {
+		return searcher.SearchPage(ctx, req.Query, offset, limit, opts)
+	}
+	need := offset + limit
+	l, ok := s.pages.get(key, gen)
+	if ok && (len(l.results) >= need || len(l.results) == l.total) {
+		s.activity.Cache(true)
+		return l.page(offset, limit), nil
+	}
+	s.activity.Cache(false)
+
+	depth := need
+	if ok && 2*len(l.results) > depth {
+		depth = 2 * len(l.results) // grow geometrically to save round trips
+	}
+	if depth > maxPageDepth {
+		depth = maxPageDepth
+	}
+	deeper, err := searcher.SearchPage(ctx, req.Query, 0, depth, opts)
+	if err != nil {
+		return search.Page{}, err
+	}
+	if !ok {
+		l = &rankedList{generation: gen}
+	}
+	// A copy keeps pages already handed out immutable.
+	next := &rankedList{results: append([]search.Result(nil), l.results...), total: l.total, warnings: l.warnings, cutoff: l.cutoff, generation: gen}
+	next.extend(deeper)
+	s.pages.put(key, next)
+	return next.page(offset, limit), nil
+}
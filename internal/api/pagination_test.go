@@ -0,0 +1,92 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/omarkamali/semango/internal/search"
+)
+
+func results(ids ...string) []search.Result {
+	out := make([]search.Result, len(ids))
+	for i, id := range ids {
+		out[i] = search.Result{ID: id}
+	}
+	return out
+}
+
+func ids(rs []search.Result) []string {
+	out := make([]string, len(rs))
+	for i, r := range rs {
+		out[i] = r.ID
+	}
+	return out
+}
+
+func TestPageCursor(t *testing.T) {
+	req := SearchRequest{Query: "retry logic", Filter: "lang:go", TopK: 10}
+	key := pageKey(req, "hybrid")
+	paged := req
+	paged.TopK, paged.Offset, paged.MinGeneration, paged.MaxTextLength = 25, 40, 7, 100
+	if pageKey(paged, "hybrid") != key {
+		t.Error("paging, freshness and output fields must not change the page key")
+	}
+	if pageKey(req, "lexical") == key {
+		t.Error("the mode must change the page key")
+	}
+
+	c := pageCursor{Offset: 10, Generation: 3, Key: key}
+	got, err := decodeCursor(encodeCursor(c))
+	if err != nil || got != c {
+		t.Errorf("round trip = %+v, %v", got, err)
+	}
+	for _, bad := range []string{"!!", "e30", encodeCursor(pageCursor{Offset: -1, Key: "k"})} {
+		if _, err := decodeCursor(bad); err == nil {
+			t.Errorf("decodeCursor(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestRankedList_ExtendKeepsServedRanks(t *testing.T) {
+	l := &rankedList{results: results("a", "b"), total: 4}
+	// The deeper ranking moved c ahead of b; b must keep its rank.
+	l.extend(search.Page{Results: results("a", "c", "b", "d"), Total: 9})
+	if got := ids(l.results); len(got) != 4 || got[0] != "a" || got[1] != "b" || got[2] != "c" || got[3] != "d" {
+		t.Errorf("extended ranking = %v", got)
+	}
+	if l.total != 9 {
+		t.Errorf("total = %d", l.total)
+	}
+	if p := l.page(3, 10); len(p.Results) != 1 || p.Results[0].ID != "d" {
+		t.Errorf("last page = %v", ids(p.Results))
+	}
+	if p := l.page(10, 10); len(p.Results) != 0 {
+		t.Errorf("page past the end = %v", ids(p.Results))
+	}
+}
+
+func TestPageCache(t *testing.T) {
+	now := time.Unix(1000, 0)
+	pc := newPageCache(time.Minute, 2)
+	pc.now = func() time.Time { return now }
+
+	pc.put("a", &rankedList{generation: 1})
+	if _, ok := pc.get("a", 2); ok {
+		t.Error("an entry from another index generation must not be used")
+	}
+	if _, ok := pc.get("a", 1); !ok {
+		t.Fatal("expected a cache hit")
+	}
+
+	now = now.Add(10 * time.Second)
+	pc.put("b", &rankedList{generation: 1})
+	pc.put("c", &rankedList{generation: 1})
+	if _, ok := pc.get("a", 1); ok {
+		t.Error("the entry closest to expiry should have been evicted")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := pc.get("c", 1); ok {
+		t.Error("expired entries must not be used")
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// probeCanary is the text embedded by each probe.
+const probeCanary = "semango embedder health check"
+
+// EmbedderStatus is the outcome of the latest embedder probes, reported by
+// /api/v1/health and /api/v1/ready.
+type EmbedderStatus struct {
+	Ready               bool      `json:"ready"`
+	LastCheck           time.Time `json:"last_check,omitempty"`
+	LastSuccess         time.Time `json:"last_success,omitempty"`
+	Latency             string    `json:"latency,omitempty"` // Of the last successful probe
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastError           string    `json:"last_error,omitempty"`
+}
+
+// embedderProbe periodically embeds a canary string so that an unavailable
+// or slow provider is noticed before user queries fail. Readiness is lost
+// after a number of consecutive failures and restored by the next success.
+type embedderProbe struct {
+	embedder  ingest.Embedder
+	provider  string
+	interval  time.Duration
+	threshold int
+	timeout   time.Duration
+	metrics   util.MetricsCollector
+
+	mu       sync.RWMutex
+	status   EmbedderStatus
+	switches int // by setEmbedder, to drop probes of a replaced embedder
+}
+
+// newEmbedderProbe returns a probe running every interval seconds that
+// reports not ready after failures consecutive failures. Zero values select
+// 60 seconds and 3 failures.
+func newEmbedderProbe(embedder ingest.Embedder, provider string, interval, failures int) *embedderProbe {
+	if interval <= 0 {
+		interval = 60
+	}
+	if failures <= 0 {
+		failures = 3
+	}
+	p := &embedderProbe{
+		embedder:  embedder,
+		provider:  provider,
+		interval:  time.Duration(interval) * time.Second,
+		threshold: failures,
+		metrics:   util.DefaultMetrics,
+	}
+	// A probe may take at most the interval, and no longer than 30s.
+	p.timeout = min(p.interval, 30*time.Second)
+	return p
+}
+
+// Run probes immediately and then every interval until ctx is done.
+func (p *embedderProbe) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check runs one probe and updates the status and metrics.
+func (p *embedderProbe) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	p.mu.RLock()
+	embedder, provider, switches := p.embedder, p.provider, p.switches
+	p.mu.RUnlock()
+
+	start := time.Now()
+	vecs, err := embedder.Embed(ctx, []string{probeCanary})
+	latency := time.Since(start)
+	if err == nil && (len(vecs) != 1 || len(vecs[0]) != embedder.Dimension()) {
+		err = fmt.Errorf("embedder returned %d vectors, want 1 of dimension %d", len(vecs), embedder.Dimension())
+	}
+
+	labels := map[string]string{"provider": provider}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.switches != switches {
+		return // switched while probing; the next probe checks the new one
+	}
+	p.status.LastCheck = start.UTC()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return // shutting down
+		}
+		p.status.ConsecutiveFailures++
+		p.status.LastError = err.Error()
+		p.metrics.IncCounter("semango_embedder_probe_failures_total", labels)
+		if p.status.Ready && p.status.ConsecutiveFailures >= p.threshold {
+			slog.Error("Embedder is unavailable, marking server not ready", "provider", provider,
+				"failures", p.status.ConsecutiveFailures, "error", err)
+		} else {
+			slog.Warn("Embedder probe failed", "provider", provider, "failures", p.status.ConsecutiveFailures, "error", err)
+		}
+		if p.status.ConsecutiveFailures >= p.threshold {
+			p.status.Ready = false
+		}
+	} else {
+		if !p.status.Ready && !p.status.LastSuccess.IsZero() {
+			slog.Info("Embedder recovered, marking server ready", "provider", provider, "latency", latency)
+		}
+		p.status.Ready = true
+		p.status.LastSuccess = start.UTC()
+		p.status.Latency = latency.String()
+		p.status.ConsecutiveFailures = 0
+		p.status.LastError = ""
+		p.metrics.ObserveHistogram("semango_embedder_probe_latency_seconds", latency.Seconds(), labels)
+	}
+	up := 0.0
+	if p.status.Ready {
+		up = 1
+	}
+	p.metrics.SetGauge("semango_embedder_up", up, labels)
+}
+
+// setEmbedder makes the probe check embedder of provider from now on.
+func (p *embedderProbe) setEmbedder(embedder ingest.Embedder, provider string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.embedder, p.provider = embedder, provider
+	p.switches++
+}
+
+// Status returns the current probe status. Until the first probe completes
+// the embedder is reported as not ready.
+func (p *embedderProbe) Status() EmbedderStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.status
+}
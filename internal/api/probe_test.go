@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// flakyEmbedder fails while fail is set.
+type flakyEmbedder struct {
+	fail error
+}
+
+func (f *flakyEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if f.fail != nil {
+		return nil, f.fail
+	}
+	return [][]float32{make([]float32, f.Dimension())}, nil
+}
+
+func (f *flakyEmbedder) Dimension() int { return 4 }
+
+// recordingMetrics keeps the last gauge value and counts failures.
+type recordingMetrics struct {
+	util.NoopMetrics
+	failures int
+	up       float64
+}
+
+func (m *recordingMetrics) IncCounter(name string, labels map[string]string) { m.failures++ }
+
+func (m *recordingMetrics) SetGauge(name string, value float64, labels map[string]string) {
+	m.up = value
+}
+
+func TestEmbedderProbe_ReadinessFlips(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	emb := &flakyEmbedder{}
+	metrics := &recordingMetrics{}
+	p := newEmbedderProbe(emb, "openai", 0, 2)
+	p.metrics = metrics
+	s := &Server{probe: p}
+	r := gin.New()
+	r.GET("/api/v1/ready", s.handleReady)
+	ready := func() int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/ready", nil))
+		return w.Code
+	}
+	ctx := context.Background()
+
+	if code := ready(); code != http.StatusServiceUnavailable {
+		t.Fatalf("before the first probe: status %d, want 503", code)
+	}
+	p.check(ctx)
+	if code := ready(); code != http.StatusOK || metrics.up != 1 {
+		t.Fatalf("after a successful probe: status %d, up %v", code, metrics.up)
+	}
+
+	emb.fail = errors.New("connection refused")
+	p.check(ctx)
+	if code := ready(); code != http.StatusOK {
+		t.Fatalf("one failure below the threshold: status %d, want 200", code)
+	}
+	p.check(ctx)
+	st := p.Status()
+	if code := ready(); code != http.StatusServiceUnavailable || st.ConsecutiveFailures != 2 || st.LastError != "connection refused" {
+		t.Fatalf("after 2 failures: status %d, %+v", code, st)
+	}
+	if metrics.failures != 2 || metrics.up != 0 {
+		t.Errorf("metrics: failures %d, up %v", metrics.failures, metrics.up)
+	}
+
+	emb.fail = nil
+	p.check(ctx)
+	if st := p.Status(); !st.Ready || st.ConsecutiveFailures != 0 || st.LastError != "" || st.Latency == "" {
+		t.Errorf("after recovery: %+v", st)
+	}
+}
+
+// switchingEmbedder switches the probe to next while it is being probed.
+type switchingEmbedder struct {
+	flakyEmbedder
+	probe *embedderProbe
+	next  *flakyEmbedder
+}
+
+func (e *switchingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.probe.setEmbedder(e.next, "local")
+	return e.flakyEmbedder.Embed(ctx, texts)
+}
+
+func TestEmbedderProbe_SetEmbedder(t *testing.T) {
+	old := &switchingEmbedder{flakyEmbedder: flakyEmbedder{fail: errors.New("quota exceeded")}, next: &flakyEmbedder{}}
+	p := newEmbedderProbe(old, "openai", 0, 1)
+	p.metrics = &recordingMetrics{}
+	old.probe = p
+	ctx := context.Background()
+
+	// The failure of the replaced embedder is not recorded.
+	p.check(ctx)
+	if st := p.Status(); st.ConsecutiveFailures != 0 || !st.LastCheck.IsZero() {
+		t.Fatalf("probe of a replaced embedder recorded: %+v", st)
+	}
+	p.check(ctx)
+	if st := p.Status(); !st.Ready {
+		t.Errorf("after probing the new embedder: %+v", st)
+	}
+}
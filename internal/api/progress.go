@@ -0,0 +1,35 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// handleIndexProgress serves the progress of the last index run into the
+// default index or the collection named by the collection parameter, as
+// the index command keeps it next to the indexes.
+func (s *Server) handleIndexProgress(c *gin.Context) {
+	cfg := s.config
+	if name := c.Query("collection"); name != "" {
+		var ok bool
+		if cfg, ok = s.config.ForCollection(name); !ok {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("unknown collection %q", name)})
+			return
+		}
+	}
+	p, ok, err := pipeline.ReadProgress(pipeline.ProgressPath(cfg.Lexical.IndexPath))
+	if err != nil {
+		util.FromContext(c.Request.Context()).Error("Failed to read index progress", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to read index progress"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "no index run recorded"})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
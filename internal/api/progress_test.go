@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/pipeline"
+)
+
+func TestIndexProgress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.Lexical.IndexPath = filepath.Join(t.TempDir(), "index", "bleve")
+	s := &Server{config: cfg, logger: slog.Default()}
+	r := gin.New()
+	r.GET("/api/v1/index/progress", s.handleIndexProgress)
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+
+	if w := get("/api/v1/index/progress"); w.Code != http.StatusNotFound {
+		t.Fatalf("before any run: %d", w.Code)
+	}
+	if w := get("/api/v1/index/progress?collection=nope"); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown collection: %d", w.Code)
+	}
+
+	tr := pipeline.NewProgressTracker(pipeline.ProgressPath(cfg.Lexical.IndexPath), time.Hour, func() int64 { return 7 }, nil)
+	tr.Found(4, 400)
+	tr.FileDone(100)
+	var p pipeline.Progress
+	// The tracker writes when the run starts and then once per interval.
+	w := get("/api/v1/index/progress")
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &p) != nil {
+		t.Fatalf("while running: %d %s", w.Code, w.Body)
+	}
+	if p.State != pipeline.ProgressRunning || p.Files != 0 {
+		t.Errorf("while running: %+v", p)
+	}
+
+	tr.Finish(errors.New("embedder down"))
+	w = get("/api/v1/index/progress")
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &p) != nil {
+		t.Fatalf("after the run: %d %s", w.Code, w.Body)
+	}
+	if p.State != pipeline.ProgressFailed || p.Error != "embedder down" || p.Files != 1 || p.Bytes != 100 ||
+		p.FilesTotal != 4 || p.Chunks != 7 || p.ETASeconds != nil {
+		t.Errorf("after the run: %+v", p)
+	}
+}
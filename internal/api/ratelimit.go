@@ -0,0 +1,125 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/util"
+	"golang.org/x/time/rate"
+)
+
+// rateLimitSweepInterval is how often idle clients are forgotten.
+const rateLimitSweepInterval = time.Minute
+
+// rateLimiter keeps a token bucket per client, keyed by the bearer token's
+// principal or, for requests without a token, the client IP. Buckets live in
+// memory; a client idle long enough for its bucket to refill is forgotten,
+// which is indistinguishable from keeping it.
+type rateLimiter struct {
+	mu      sync.Mutex
+	limit   rate.Limit
+	burst   int
+	idle    time.Duration // time for an empty bucket to refill
+	now     func() time.Time
+	swept   time.Time
+	clients map[string]*rateClient
+}
+
+type rateClient struct {
+	lim  *rate.Limiter
+	seen time.Time
+}
+
+// newRateLimiter returns the limiter for cfg, or nil when cfg.RPS is 0.
+func newRateLimiter(cfg config.RateLimitConfig) *rateLimiter {
+	if cfg.RPS <= 0 {
+		return nil
+	}
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(cfg.RPS))
+	}
+	return &rateLimiter{
+		limit:   rate.Limit(cfg.RPS),
+		burst:   burst,
+		idle:    time.Duration(float64(burst) / cfg.RPS * float64(time.Second)),
+		now:     time.Now,
+		clients: map[string]*rateClient{},
+	}
+}
+
+// allow takes a token from key's bucket. If the bucket is empty it returns
+// false and how long until the next token.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.swept) >= rateLimitSweepInterval {
+		for k, c := range l.clients {
+			if now.Sub(c.seen) > l.idle {
+				delete(l.clients, k)
+			}
+		}
+		l.swept = now
+	}
+
+	c, ok := l.clients[key]
+	if !ok {
+		c = &rateClient{lim: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = c
+	}
+	c.seen = now
+	r := c.lim.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// rateLimitKey identifies the client of c: its token principal, set by
+// requireToken, or its IP when it authenticated with none.
+func rateLimitKey(c *gin.Context) string {
+	if who := c.GetString(principalKey); who != "" && who != anonymousPrincipal {
+		return who
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// limited takes a token for the client of c. When the client is over its
+// limit it sets Retry-After, counts the rejection and returns true; the
+// caller then answers with its protocol's "too many requests" error.
+func (s *Server) limited(c *gin.Context) bool {
+	limiter := s.limiter.Load()
+	if limiter == nil {
+		return false
+	}
+	key := rateLimitKey(c)
+	ok, retry := limiter.allow(key)
+	if ok {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+	util.DefaultMetrics.IncCounter("semango_rate_limited_total", map[string]string{"path": c.FullPath()})
+	util.FromContext(c.Request.Context()).Warn("Rate limit exceeded", "client", key, "retry_after", retry.String())
+	return true
+}
+
+// rateLimit rejects REST requests of clients over server.rate_limit with
+// 429 Too Many Requests. It runs after requireToken so that clients with a
+// token are limited by token rather than by IP.
+func (s *Server) rateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.limited(c) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
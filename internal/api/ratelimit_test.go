@@ -0,0 +1,141 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+)
+
+func TestRateLimiter(t *testing.T) {
+	if newRateLimiter(config.RateLimitConfig{}) != nil {
+		t.Fatal("rate limiting should be off without rps")
+	}
+
+	l := newRateLimiter(config.RateLimitConfig{RPS: 2, Burst: 3})
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("a"); !ok {
+			t.Fatalf("request %d within the burst was limited", i+1)
+		}
+	}
+	ok, retry := l.allow("a")
+	if ok || retry != 500*time.Millisecond {
+		t.Fatalf("request over the burst: allowed %v, retry %v; want limited, 500ms", ok, retry)
+	}
+	if ok, _ := l.allow("b"); !ok {
+		t.Error("clients should have separate buckets")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.allow("a"); !ok {
+		t.Error("a refilled token was not granted")
+	}
+
+	// Idle clients are forgotten on the next sweep.
+	now = now.Add(rateLimitSweepInterval)
+	l.allow("c")
+	if _, ok := l.clients["a"]; ok || len(l.clients) != 1 {
+		t.Errorf("idle clients kept: %v", l.clients)
+	}
+
+	if l := newRateLimiter(config.RateLimitConfig{RPS: 0.5}); l.burst != 1 {
+		t.Errorf("default burst for rps 0.5 = %d, want 1", l.burst)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("SEMANGO_TEST_TOKENS", "one,two")
+	s := &Server{auth: newTokenAuth(config.AuthConfig{TokenEnv: "SEMANGO_TEST_TOKENS"})}
+	s.limiter.Store(newRateLimiter(config.RateLimitConfig{RPS: 1, Burst: 1}))
+	r := gin.New()
+	r.GET("/search", s.requireToken(), s.rateLimit(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	get := func(token, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/search", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("one", "10.0.0.1"); w.Code != http.StatusOK {
+		t.Fatalf("first request: %d", w.Code)
+	}
+	w := get("one", "10.0.0.2")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("second request with the same token from another IP: %d, Retry-After %q; want 429, 1",
+			w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := get("two", "10.0.0.1"); w.Code != http.StatusOK {
+		t.Errorf("another token from the same IP: %d, want its own limit", w.Code)
+	}
+	if w := get("wrong", "10.0.0.3"); w.Code != http.StatusUnauthorized {
+		t.Errorf("invalid token: %d, want 401 before rate limiting", w.Code)
+	}
+
+	// Without auth, clients are told apart by IP.
+	s.auth = nil
+	if w := get("", "10.0.0.4"); w.Code != http.StatusOK {
+		t.Errorf("first anonymous request: %d", w.Code)
+	}
+	if w := get("", "10.0.0.4"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("second anonymous request from the same IP: %d, want 429", w.Code)
+	}
+	if w := get("", "10.0.0.5"); w.Code != http.StatusOK {
+		t.Errorf("anonymous request from another IP: %d", w.Code)
+	}
+}
+
+func TestRateLimit_ForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := &Server{}
+	s.limiter.Store(newRateLimiter(config.RateLimitConfig{RPS: 1, Burst: 1}))
+	newGet := func(trusted []string) func(forwardedFor string) int {
+		r, err := newRouter(trusted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.GET("/search", s.rateLimit(), func(c *gin.Context) { c.Status(http.StatusOK) })
+		return func(forwardedFor string) int {
+			req := httptest.NewRequest(http.MethodGet, "/search", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			return w.Code
+		}
+	}
+
+	// A client cannot get a fresh bucket by making up its address.
+	get := newGet(nil)
+	if code := get("203.0.113.1"); code != http.StatusOK {
+		t.Fatalf("first request: %d", code)
+	}
+	if code := get("203.0.113.2"); code != http.StatusTooManyRequests {
+		t.Errorf("request with a spoofed X-Forwarded-For: %d, want 429", code)
+	}
+
+	// Behind a trusted proxy, the forwarded address is the client's.
+	get = newGet([]string{"10.0.0.0/8"})
+	if code := get("203.0.113.3"); code != http.StatusOK {
+		t.Errorf("first client behind the proxy: %d", code)
+	}
+	if code := get("203.0.113.4"); code != http.StatusOK {
+		t.Errorf("second client behind the proxy: %d, want its own bucket", code)
+	}
+	if code := get("203.0.113.4"); code != http.StatusTooManyRequests {
+		t.Errorf("second client again: %d, want 429", code)
+	}
+
+	if _, err := newRouter([]string{"not an ip"}); err == nil {
+		t.Error("an invalid trusted proxy was accepted")
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// indexPollInterval is how often the server checks whether semango index
+// --rebuild swapped in a new generation of the indexes.
+const indexPollInterval = 2 * time.Second
+
+// Reload applies the reloadable settings of next, typically the
+// configuration file read again after SIGHUP: the hybrid fusion and
+// reranker settings, server.rate_limit and log_level. If next changes
+// anything else, such as the embedding model, it fails and nothing changes
+// (see config.Config.CheckReload). Each setting is switched atomically;
+// requests in flight finish with the old one.
+func (s *Server) Reload(ctx context.Context, next *config.Config) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	if err := s.config.CheckReload(next); err != nil {
+		return err
+	}
+	prev := s.applied
+	var changed []string
+	if next.LogLevel != prev.LogLevel {
+		if err := util.SetLogLevel(next.LogLevel); err != nil {
+			return err
+		}
+		changed = append(changed, "log_level")
+	}
+	if next.Hybrid != prev.Hybrid || next.Reranker != prev.Reranker {
+		if s.searcher != nil {
+			s.searcher.SetTuning(next.Hybrid, next.Reranker)
+		}
+		// Collections with their own embedder have their own settings.
+		for _, c := range s.collections {
+			c.SetTuning(next.Hybrid, next.Reranker)
+		}
+		s.pages.clear() // rankings with the old weights must not be paged on
+		s.epoch.bump()  // nor revalidated
+		if next.Hybrid != prev.Hybrid {
+			changed = append(changed, "hybrid")
+		}
+		if next.Reranker != prev.Reranker {
+			changed = append(changed, "reranker")
+		}
+	}
+	if next.Server.RateLimit != prev.Server.RateLimit {
+		// Buckets start full again under the new limits.
+		s.limiter.Store(newRateLimiter(next.Server.RateLimit))
+		changed = append(changed, "server.rate_limit")
+	}
+	s.applied = next
+
+	if len(changed) == 0 {
+		util.FromContext(ctx).Info("Configuration reloaded, nothing changed")
+		return nil
+	}
+	util.FromContext(ctx).Info("Configuration reloaded", "changed", changed)
+	return nil
+}
+
+// watchIndexes reloads the searchers whose index directory was pointed at
+// another generation, until ctx is done.
+func (s *Server) watchIndexes(ctx context.Context) {
+	ticker := time.NewTicker(indexPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reloadIndexes(ctx)
+		}
+	}
+}
+
+// reloadIndexes has the default searcher and those of the collections
+// resolve their index directories again (see search.Searcher.Reload), and
+// drops the cached rankings if one of them changed.
+func (s *Server) reloadIndexes(ctx context.Context) {
+	swapped := false
+	if s.searcher.Reload() {
+		util.FromContext(ctx).Info("Searching a new generation of the indexes", "dir", storage.ResolveIndexDir(storage.IndexDir(s.config.Lexical.IndexPath)))
+		swapped = true
+	}
+	for name, c := range s.collections {
+		if c.Reload() {
+			util.FromContext(ctx).Info("Searching a new generation of the indexes", "collection", name)
+			swapped = true
+		}
+	}
+	if swapped {
+		s.pages.clear() // rankings of the old indexes must not be paged on
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+func TestReload(t *testing.T) {
+	t.Cleanup(func() { util.LogLevel.Set(slog.LevelDebug) })
+	cfg := config.GetDefaultConfig()
+	s := NewServer(cfg, nil, nil)
+	s.searcher = search.NewSearcherWithEmbedder(cfg, nil)
+	ctx := context.Background()
+	if s.limiter.Load() != nil {
+		t.Fatal("rate limiting should start off")
+	}
+
+	next := config.GetDefaultConfig()
+	next.Hybrid = config.HybridConfig{VectorWeight: 0.2, LexicalWeight: 0.8, Fusion: "rrf"}
+	next.Server.RateLimit = config.RateLimitConfig{RPS: 10}
+	next.LogLevel = "warn"
+	if err := s.Reload(ctx, next); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if got := s.searcher.Hybrid(); got != next.Hybrid {
+		t.Errorf("hybrid settings after reload: %+v, want %+v", got, next.Hybrid)
+	}
+	if l := s.limiter.Load(); l == nil || l.burst != 10 {
+		t.Errorf("rate limiter after reload: %+v", l)
+	}
+	if util.LogLevel.Level() != slog.LevelWarn {
+		t.Errorf("log level after reload: %v", util.LogLevel.Level())
+	}
+	if cfg.Hybrid == next.Hybrid {
+		t.Error("Reload changed the startup configuration")
+	}
+	if epoch, _ := s.epoch.validators(storage.Generation{}); epoch != 1 {
+		t.Errorf("settings epoch after reloading hybrid settings: %d, want 1", epoch)
+	}
+
+	// A new embedding model needs re-indexing; nothing may change.
+	bad := config.GetDefaultConfig()
+	bad.Embedding.LocalModelPath = "models/all-MiniLM-L6-v2"
+	bad.LogLevel = "error"
+	if err := s.Reload(ctx, bad); err == nil {
+		t.Fatal("reloading a new embedding model succeeded")
+	}
+	if s.searcher.Hybrid() != next.Hybrid || util.LogLevel.Level() != slog.LevelWarn || s.limiter.Load() == nil {
+		t.Error("a refused reload changed settings")
+	}
+
+	// Reloading the startup configuration turns rate limiting off again.
+	if err := s.Reload(ctx, config.GetDefaultConfig()); err != nil {
+		t.Fatal(err)
+	}
+	if s.limiter.Load() != nil || s.searcher.Hybrid() != cfg.Hybrid {
+		t.Error("settings not restored")
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/report"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// recordActivity counts every request, and the query, latency and result
+// count of searches, for the health report. Handlers mark searches with
+// noteQuery and noteResults, as for the access log.
+func (s *Server) recordActivity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		s.activity.Request(c.Writer.Status())
+		q, ok := c.Get(queryKey)
+		if !ok {
+			return
+		}
+		if n, ok := c.Get(resultsKey); ok {
+			s.activity.Search(q.(string), time.Since(start), n.(int))
+		}
+	}
+}
+
+// healthReport builds the health report of the default index as of now,
+// with the activity a period returns. take starts a new activity period.
+func (s *Server) healthReport(ctx context.Context, take bool) (*report.Report, error) {
+	stats, err := s.searcher.GetStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := report.Latest(report.Dir(s.config))
+	if err != nil {
+		util.FromContext(ctx).Warn("Previous health report unreadable; growth left out", "error", err)
+	}
+	activity := s.activity.Snapshot
+	if take {
+		activity = s.activity.Take
+	}
+	return report.New(time.Now(), stats, prev, activity()), nil
+}
+
+// handleReport serves the health report of the default index, as JSON or,
+// with format=html, as an HTML page. It does not start a new period.
+func (s *Server) handleReport(c *gin.Context) {
+	r, err := s.healthReport(c.Request.Context(), false)
+	if err != nil {
+		util.FromContext(c.Request.Context()).Error("Failed to build health report", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build health report"})
+		return
+	}
+	switch c.Query("format") {
+	case "", "json":
+		c.JSON(http.StatusOK, r)
+	case "html":
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusOK)
+		if err := r.WriteHTML(c.Writer); err != nil {
+			util.FromContext(c.Request.Context()).Error("Failed to render health report", "error", err)
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or html"})
+	}
+}
+
+// writeReports saves a health report to the report directory every
+// interval until ctx is done. Each report covers the activity since the
+// previous one.
+func (s *Server) writeReports(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	dir := report.Dir(s.config)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		r, err := s.healthReport(ctx, true)
+		if err != nil {
+			slog.Error("Failed to build health report", "error", err)
+			continue
+		}
+		path, err := report.Save(dir, r)
+		if err != nil {
+			slog.Error("Failed to save health report", "dir", dir, "error", err)
+			continue
+		}
+		slog.Info("Wrote health report", "path", path)
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// requestIDHeader carries the request ID in both directions: a client or
+// proxy may set it, otherwise one is generated.
+const requestIDHeader = "X-Request-ID"
+
+const maxRequestIDLen = 128
+
+// requestLogging gives each request an ID and stores a logger tagged with it
+// in the request context, so the log lines of the search, embedder and index
+// calls made for the request all carry the same request_id.
+func requestLogging(base *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header(requestIDHeader, id)
+		ctx := util.WithLogger(c.Request.Context(), base.With("request_id", id))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// validRequestID accepts IDs of up to maxRequestIDLen letters, digits and
+// "-._:", which covers UUIDs and the trace IDs of common proxies while
+// keeping log lines clean.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '.' || r == '_' || r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+func TestRequestLogging(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	r := gin.New()
+	r.Use(requestLogging(slog.New(slog.NewJSONHandler(&buf, nil))))
+	r.GET("/ping", func(c *gin.Context) {
+		util.FromContext(c.Request.Context()).Info("handled")
+		c.Status(http.StatusNoContent)
+	})
+
+	get := func(id string) string {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		if id != "" {
+			req.Header.Set(requestIDHeader, id)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		got := w.Header().Get(requestIDHeader)
+		if !strings.Contains(buf.String(), `"request_id":"`+got+`"`) {
+			t.Errorf("log line %q does not carry request_id %q", buf.String(), got)
+		}
+		return got
+	}
+
+	if got := get("trace-42:a.b_c"); got != "trace-42:a.b_c" {
+		t.Errorf("client request ID not kept: got %q", got)
+	}
+	for _, id := range []string{"", "has space", "x\ny", strings.Repeat("a", maxRequestIDLen+1)} {
+		if got := get(id); got == id || len(got) != 16 {
+			t.Errorf("request ID %q: got %q, want a generated one", id, got)
+		}
+	}
+	if get("") == get("") {
+		t.Error("generated request IDs should differ")
+	}
+}
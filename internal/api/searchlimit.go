@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// statusClientClosedRequest is logged for searches whose client went away
+// before the response, as nginx does; the client never sees it.
+const statusClientClosedRequest = 499
+
+var (
+	// errSearchTimeout is returned by withSearchSlot when the search ran
+	// past server.search_timeout.
+	errSearchTimeout = errors.New("search timed out")
+	// errSearchCanceled is returned by withSearchSlot when the client
+	// disconnected during the search.
+	errSearchCanceled = errors.New("search canceled by the client")
+)
+
+// searchLimits bounds the searches a server runs at once and the time each
+// may take, from server.max_concurrent_searches and server.search_timeout.
+type searchLimits struct {
+	slots   chan struct{} // nil for no limit
+	timeout time.Duration // 0 for no limit
+}
+
+func newSearchLimits(cfg config.ServerConfig) searchLimits {
+	var l searchLimits
+	if cfg.MaxConcurrentSearches > 0 {
+		l.slots = make(chan struct{}, cfg.MaxConcurrentSearches)
+	}
+	if cfg.SearchTimeout > 0 {
+		l.timeout = time.Duration(cfg.SearchTimeout) * time.Second
+	}
+	return l
+}
+
+// withSearchSlot runs fn once a search slot is free, with ctx limited to
+// the search timeout. ctx is the request's, so a client that disconnects
+// cancels the embedder and index calls made with it. When the deadline
+// passes or the client leaves before fn succeeds, the error is
+// errSearchTimeout or errSearchCanceled instead of fn's.
+func (s *Server) withSearchSlot(ctx context.Context, fn func(context.Context) error) error {
+	if s.searchLimits.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.searchLimits.timeout)
+		defer cancel()
+	}
+	if s.searchLimits.slots != nil {
+		select {
+		case s.searchLimits.slots <- struct{}{}:
+			defer func() { <-s.searchLimits.slots }()
+		case <-ctx.Done():
+			return searchContextError(ctx)
+		}
+	}
+	if err := fn(ctx); err != nil {
+		if ctx.Err() != nil {
+			return searchContextError(ctx)
+		}
+		return err
+	}
+	return nil
+}
+
+// searchContextError tells a search timeout from a client that left.
+func searchContextError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		util.DefaultMetrics.IncCounter("semango_search_timeouts_total", nil)
+		return errSearchTimeout
+	}
+	return errSearchCanceled
+}
+
+// searchFailed answers a REST search that returned err: 504 when it timed
+// out, nothing when the client has left, and 500 otherwise.
+func searchFailed(c *gin.Context, err error) {
+	logger := util.FromContext(c.Request.Context())
+	switch {
+	case errors.Is(err, errSearchTimeout):
+		logger.Warn("Search timed out", "error", err)
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "search timed out"})
+	case errors.Is(err, errSearchCanceled):
+		logger.Info("Search canceled, the client disconnected")
+		c.AbortWithStatus(statusClientClosedRequest)
+	case errors.Is(err, search.ErrDimensionMismatch), errors.Is(err, search.ErrModelMismatch):
+		// The configuration does not match the index: the error says how
+		// to fix it.
+		logger.Error("Search failed", "error", err)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logger.Error("Search failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "search failed"})
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+)
+
+func TestWithSearchSlot(t *testing.T) {
+	s := &Server{searchLimits: newSearchLimits(config.ServerConfig{MaxConcurrentSearches: 1})}
+	ctx := context.Background()
+
+	boom := errors.New("boom")
+	if err := s.withSearchSlot(ctx, func(context.Context) error { return boom }); err != boom {
+		t.Errorf("a failing search returned %v, want its error", err)
+	}
+
+	// A search past the timeout is cut off, and its slot is freed.
+	s.searchLimits.timeout = 20 * time.Millisecond
+	err := s.withSearchSlot(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != errSearchTimeout {
+		t.Errorf("slow search returned %v, want errSearchTimeout", err)
+	}
+
+	// With the only slot taken, the next search times out waiting.
+	started, release, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		s.withSearchSlot(ctx, func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	ran := false
+	if err := s.withSearchSlot(ctx, func(context.Context) error { ran = true; return nil }); err != errSearchTimeout || ran {
+		t.Errorf("search over the concurrency limit: %v, ran %v; want errSearchTimeout without running", err, ran)
+	}
+	close(release)
+	<-done
+
+	// A client that leaves cancels the search.
+	s.searchLimits = newSearchLimits(config.ServerConfig{})
+	cctx, cancel := context.WithCancel(ctx)
+	err = s.withSearchSlot(cctx, func(ctx context.Context) error {
+		cancel()
+		return ctx.Err()
+	})
+	if err != errSearchCanceled {
+		t.Errorf("canceled search returned %v, want errSearchCanceled", err)
+	}
+}
+
+func TestSearchFailed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tt := range []struct {
+		err  error
+		want int
+	}{
+		{errSearchTimeout, http.StatusGatewayTimeout},
+		{errSearchCanceled, statusClientClosedRequest},
+		{errors.New("boom"), http.StatusInternalServerError},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/search", nil)
+		searchFailed(c, tt.err)
+		if w.Code != tt.want {
+			t.Errorf("searchFailed(%v) = %d, want %d", tt.err, w.Code, tt.want)
+		}
+	}
+}
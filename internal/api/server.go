@@ -7,13 +7,26 @@ import (
 	"io"
 	"io/fs"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/rag"
+	"github.com/omarkamali/semango/internal/report"
 	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/storage"
 	"github.com/omarkamali/semango/internal/util"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
 )
 
 //go:embed all:ui
@@ -26,13 +39,118 @@ type Server struct {
 	router   *gin.Engine
 	logger   *slog.Logger
 	uiFS     fs.FS
+
+	// Document ingestion (POST /api/v1/documents)
+	ingester    documentIndexer
+	splitter    *ingest.TextLoader
+	ingestMu    sync.Mutex // index writes are not safe to run concurrently
+	idempotency *idempotencyStore
+
+	probe *embedderProbe // nil without a searcher
+	auth  *tokenAuth     // nil when authentication is disabled
+	admin *tokenAuth     // nil when admin endpoints are disabled
+	pages *pageCache     // rankings behind paged searches
+
+	snapshots snapshotStore // unpacked snapshots for as_of searches
+
+	collections map[string]*search.Searcher // by name, from the collections section
+	// The collections' document ingesters, and limiters for those with a
+	// max_qps quota, by name.
+	collectionIngesters map[string]documentIndexer
+	collectionLimits    map[string]*rateLimiter
+
+	answerer questionAnswerer // nil unless llm.model is set
+
+	embedderMu sync.Mutex // serializes PUT /api/v1/embedder
+
+	limiter      atomic.Pointer[rateLimiter] // nil unless server.rate_limit is set
+	searchLimits searchLimits
+
+	reloadMu sync.Mutex     // serializes Reload
+	applied  *config.Config // the configuration last applied by Reload
+	epoch    settingsEpoch  // in search validators; see caching.go
+
+	activity *report.Recorder // requests and searches for the health report
+
+	openapi []byte // served at /api/v1/openapi.json, generated from apiRoutes
+
+	grpcServer *grpc.Server // serves the gRPC API through the router
 }
 
 // SearchRequest represents the search API request
 type SearchRequest struct {
 	Query  string `json:"query" binding:"required"`
 	TopK   int    `json:"top_k,omitempty"`
-	Filter string `json:"filter,omitempty"`
+	Filter string `json:"filter,omitempty"` // Metadata filter, e.g. `source:EmailLoader lang:de`
+	Lang   string `json:"lang,omitempty"`   // Language hint, e.g. "de" or "pt-BR", or "auto"
+	Path   string `json:"path,omitempty"`   // Search within this document only; results come in document order
+	Mode   string `json:"mode,omitempty"`   // "hybrid", "lexical" or "vector"; defaults to search.default_mode
+	// Parents returns each matched chunk's parent section instead of the
+	// chunk (see files.parent_chunk_size).
+	Parents bool `json:"parents,omitempty"`
+	// Window widens each result to up to this many chunks before and after
+	// it in its document, at most 10.
+	Window int `json:"window,omitempty"`
+	// Boosts multiply the score of results whose path matches a pattern,
+	// e.g. {"docs/**": 1.5, "tests/**": 0.5}.
+	Boosts map[string]float64 `json:"boosts,omitempty"`
+	// Space selects the vector space (see embedding.spaces); defaults to
+	// the default model's.
+	Space string `json:"space,omitempty"`
+	// Expand rewrites the query before retrieval: "terms" adds terms from
+	// the top lexical matches, "hyde" embeds a hypothetical answer written
+	// by the configured LLM instead of the query, and "multi" has the LLM
+	// write variants of the query that are searched alongside it.
+	Expand string `json:"expand,omitempty"`
+	// Queries are further variants of the query, e.g. sub-questions, each
+	// searched on its own; the rankings are merged with Reciprocal Rank
+	// Fusion.
+	Queries []string `json:"queries,omitempty"`
+
+	// Freshness requirements: the search fails with 409 if the index is at
+	// an older generation or was last written before MinIndexedAt (RFC 3339).
+	MinGeneration uint64 `json:"min_generation,omitempty"`
+	MinIndexedAt  string `json:"min_indexed_at,omitempty"`
+
+	// Paging: either skip Offset results, or continue from the next_cursor
+	// of a previous response. offset+top_k may be at most 1000.
+	Offset int    `json:"offset,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+
+	// AsOf searches the named snapshot from the snapshot directory instead
+	// of the live index, read-only.
+	AsOf string `json:"as_of,omitempty"`
+	// Collection searches the named collection from the collections
+	// section instead of the default index.
+	Collection string `json:"collection,omitempty"`
+	// Collections searches several collections at once and merges their
+	// results, weighted by collections.<name>.weight.
+	Collections []string `json:"collections,omitempty"`
+	// MaxTextLength cuts each result's chunk to this many characters;
+	// defaults to search.max_text_length.
+	MaxTextLength int `json:"max_text_length,omitempty"`
+	// IncludeFullText returns each result's chunk text in chunk, cut to
+	// max_text_length; without it results carry only a snippet. Defaults
+	// to search.include_full_text.
+	IncludeFullText bool `json:"include_full_text,omitempty"`
+	// SnippetLength is the length of each result's snippet in characters;
+	// defaults to search.snippet_length.
+	SnippetLength int `json:"snippet_length,omitempty"`
+	// Adaptive returns results only down to the first steep score drop
+	// (see search.adaptive_drop); top_k still caps the count. With
+	// AdaptiveFull the results below it are kept and only Cutoff marks it.
+	Adaptive     bool `json:"adaptive,omitempty"`
+	AdaptiveFull bool `json:"adaptive_full,omitempty"`
+	// GroupBy "document" returns one result per document, its best chunk,
+	// with the document's other matching chunks nested in chunks.
+	GroupBy string `json:"group_by,omitempty"`
+	// MaxChunksPerDoc keeps at most this many chunks of a document,
+	// counting nested ones; 0 keeps all.
+	MaxChunksPerDoc int `json:"max_chunks_per_doc,omitempty"`
+	// LexicalSyntax "advanced" reads the query for lexical matching as
+	// "phrases", +required and -excluded terms, key:value metadata matches
+	// and fuzzy~ terms; "simple", the default, matches its words.
+	LexicalSyntax string `json:"lexical_syntax,omitempty"`
 }
 
 // SearchResponse represents the search API response
@@ -40,19 +158,64 @@ type SearchResponse struct {
 	Results []SearchResult `json:"results"`
 	Query   string         `json:"query"`
 	TopK    int            `json:"top_k"`
+	Lang    string         `json:"lang,omitempty"`
+	Path    string         `json:"path,omitempty"`
+	Mode    string         `json:"mode"`
+	Space   string         `json:"space,omitempty"`
+	AsOf    string         `json:"as_of,omitempty"`
 	Took    string         `json:"took"`
+
+	// The index generation the results were read from, also sent as the
+	// X-Index-Generation and Last-Modified headers.
+	Generation uint64     `json:"generation"`
+	IndexedAt  *time.Time `json:"indexed_at,omitempty"`
+
+	// Offset is the rank before the first result. TotalCandidates counts
+	// the candidates ranked so far, which grows as deeper pages are read;
+	// NextCursor is set while more results may follow.
+	Offset          int    `json:"offset"`
+	TotalCandidates int    `json:"total_candidates"`
+	NextCursor      string `json:"next_cursor,omitempty"`
+
+	// Warnings say how the results fall short of the request, e.g. a
+	// hybrid search answered from the lexical index alone because the
+	// vector index is missing.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Cutoff is set by adaptive searches: the number of results, counted
+	// from rank 1, above the score drop-off.
+	Cutoff int `json:"cutoff,omitempty"`
 }
 
 // SearchResult represents a single search result
 type SearchResult struct {
 	Rank          int                    `json:"rank"`
+	ID            string                 `json:"id"` // Chunk ID
 	Score         float64                `json:"score"`
 	LexicalScore  float64                `json:"lexical_score"`
 	SemanticScore float64                `json:"semantic_score"`
 	Modality      string                 `json:"modality"`
 	Document      DocumentInfo           `json:"document"`
-	Chunk         string                 `json:"chunk"`
+	Chunk         string                 `json:"chunk,omitempty"` // Only with include_full_text
 	Highlights    map[string]interface{} `json:"highlights,omitempty"`
+	Link          string                 `json:"link,omitempty"`
+	// Snippet is the part of the chunk around its best highlight, or its
+	// start, cut to snippet_length.
+	Snippet string `json:"snippet"`
+	// Truncated is set when Chunk was cut to max_text_length. Highlights
+	// refer to the whole chunk, not to Snippet.
+	Truncated bool `json:"truncated,omitempty"`
+	// Collection is set in searches across collections.
+	Collection string `json:"collection,omitempty"`
+	// Window lists the chunk IDs of Chunk, in document order, when the
+	// request asked for a window.
+	Window []string `json:"window,omitempty"`
+	// Aliases lists the paths of duplicates of the chunk that files.dedup
+	// left out of the index.
+	Aliases []string `json:"aliases,omitempty"`
+	// Chunks are the document's further matching chunks, best first, when
+	// the request grouped by document. Their rank is 0.
+	Chunks []SearchResult `json:"chunks,omitempty"`
 }
 
 // DocumentInfo represents document metadata
@@ -71,21 +234,82 @@ func NewServer(config *config.Config, searcher *search.Searcher, uiFS fs.FS) *Se
 		}
 	}
 
-	return &Server{
-		config:   config,
-		searcher: searcher,
-		uiFS:     uiFS,
+	srv := &Server{
+		config:       config,
+		searcher:     searcher,
+		uiFS:         uiFS,
+		splitter:     ingest.NewTextLoader(config.Files.ChunkSize, config.Files.ChunkOverlap),
+		idempotency:  newIdempotencyStore(idempotencyTTL),
+		pages:        newPageCache(pageCacheTTL, pageCacheMaxEntries),
+		auth:         newTokenAuth(config.Server.Auth),
+		admin:        newAdminAuth(config.Server.Auth),
+		searchLimits: newSearchLimits(config.Server),
+		applied:      config,
+		activity:     report.NewRecorder(config.Server.AccessLog.RedactQueries),
+	}
+	srv.limiter.Store(newRateLimiter(config.Server.RateLimit))
+	if searcher != nil {
+		mgr := pipeline.NewManager(config, searcher.Embedder()).WithSpaces(searcher.SpaceEmbedders())
+		if searcher.ModelMismatchAllowed() {
+			mgr.AllowModelMismatch()
+		}
+		srv.ingester = mgr
+		srv.splitter.SetChunking(mgr.Chunking())
+		if err := mgr.Err(); err != nil {
+			slog.Error("Documents cannot be ingested through the API", "error", err)
+		}
+		srv.probe = newEmbedderProbe(searcher.Embedder(), config.Embedding.Provider,
+			config.Server.ProbeInterval, config.Server.ProbeFailures)
+		var rewriter search.QueryRewriter
+		if config.LLM.Model != "" {
+			if model, err := rag.NewChatModel(config.LLM); err != nil {
+				slog.Warn("Answering and LLM query expansion are disabled", "error", err)
+			} else {
+				srv.answerer = rag.NewAnswerer(config.LLM, searcher, model)
+				rewriter = rag.NewRewriter(model)
+				searcher.WithRewriter(rewriter)
+			}
+		}
+		srv.openCollections(rewriter)
 	}
+	return srv
 }
 
 // setupRoutes configures all API routes
 func (s *Server) setupRoutes() {
 	// API routes
-	api := s.router.Group("/api/v1")
-	{
-		api.POST("/search", s.handleSearch)
-		api.GET("/health", s.handleHealth)
-		api.GET("/stats", s.handleStats)
+	// Health checks and the API docs stay open; the rest needs a token, or
+	// an admin token for admin routes, and is rate limited.
+	const basePath = "/api/v1"
+	api := s.router.Group(basePath)
+	authed := api.Group("", s.requireToken(), s.rateLimit())
+	admin := api.Group("", s.requireAdmin(), s.rateLimit())
+	routes := s.apiRoutes()
+	for _, r := range routes {
+		switch {
+		case r.Public:
+			api.Handle(r.Method, r.Path, r.Handler)
+		case r.Admin:
+			admin.Handle(r.Method, r.Path, r.Handler)
+		default:
+			authed.Handle(r.Method, r.Path, r.Handler)
+		}
+	}
+	doc, err := buildOpenAPI(routes, basePath)
+	if err != nil {
+		s.logger.Error("Failed to build the OpenAPI document", "error", err)
+	}
+	s.openapi = doc
+
+	// gRPC shares the port; it checks tokens itself to report gRPC statuses.
+	s.grpcServer = s.newGRPCServer()
+	s.router.POST("/"+grpcServiceName+"/:method", s.serveGRPC)
+
+	// MCP clients connect to /mcp with the same tokens as the REST API. No
+	// server-initiated stream is offered, which GET answers with 405.
+	if s.config.MCP.Enabled {
+		s.router.POST(mcpPath, s.requireToken(), s.rateLimit(), s.handleMCP)
+		s.router.GET(mcpPath, func(c *gin.Context) { c.Status(http.StatusMethodNotAllowed) })
 	}
 
 	// Serve embedded UI
@@ -183,70 +407,542 @@ func (s *Server) handleFallbackUI(c *gin.Context) {
 
 // handleSearch handles the search API endpoint
 func (s *Server) handleSearch(c *gin.Context) {
-	start := time.Now()
-
 	var req SearchRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	s.search(c, req, false)
+}
+
+// handleSearchGet is the cacheable form of the search endpoint: the request
+// is taken from the query string (q, top_k, filter, lang, path, mode,
+// parents, window, boost, space, expand, offset, cursor, min_generation,
+// min_indexed_at, as_of, collection, max_text_length, include_full_text,
+// snippet_length, adaptive, adaptive_full, group_by, max_chunks_per_doc,
+// lexical_syntax) and conditional
+// requests are answered with 304 while the index is unchanged.
+// Further q parameters are query variants; several collection parameters
+// search those collections together.
+func (s *Server) handleSearchGet(c *gin.Context) {
+	req := SearchRequest{
+		Query:  c.Query("q"),
+		Filter: c.Query("filter"),
+		Lang:   c.Query("lang"),
+		Path:   c.Query("path"),
+		Mode:   c.Query("mode"),
+		Space:  c.Query("space"),
+		Expand: c.Query("expand"),
+
+		GroupBy:       c.Query("group_by"),
+		LexicalSyntax: c.Query("lexical_syntax"),
+		MinIndexedAt:  c.Query("min_indexed_at"),
+		Cursor:        c.Query("cursor"),
+		AsOf:          c.Query("as_of"),
+		Collection:    c.Query("collection"),
+	}
+	if req.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	if qs := c.QueryArray("q"); len(qs) > 1 {
+		req.Queries = qs[1:]
+	}
+	if cs := c.QueryArray("collection"); len(cs) > 1 {
+		req.Collection, req.Collections = "", cs
+	}
+	if v := c.Query("top_k"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid top_k"})
+			return
+		}
+		req.TopK = n
+	}
+	if v := c.Query("max_text_length"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid max_text_length"})
+			return
+		}
+		req.MaxTextLength = n
+	}
+	if v := c.Query("snippet_length"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid snippet_length"})
+			return
+		}
+		req.SnippetLength = n
+	}
+	if v := c.Query("include_full_text"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid include_full_text"})
+			return
+		}
+		req.IncludeFullText = b
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+			return
+		}
+		req.Offset = n
+	}
+	if v := c.Query("parents"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid parents"})
+			return
+		}
+		req.Parents = b
+	}
+	if v := c.Query("window"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid window"})
+			return
+		}
+		req.Window = n
+	}
+	if v := c.Query("max_chunks_per_doc"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid max_chunks_per_doc"})
+			return
+		}
+		req.MaxChunksPerDoc = n
+	}
+	if v := c.Query("adaptive"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid adaptive"})
+			return
+		}
+		req.Adaptive = b
+	}
+	if v := c.Query("adaptive_full"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid adaptive_full"})
+			return
+		}
+		req.AdaptiveFull = b
+	}
+	if terms := c.QueryArray("boost"); len(terms) > 0 {
+		boosts, err := search.ParseBoosts(terms)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.Boosts = boosts
+	}
+	if v := c.Query("min_generation"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid min_generation"})
+			return
+		}
+		req.MinGeneration = n
+	}
+	s.search(c, req, true)
+}
+
+// search runs req and writes the response. With conditional set, a request
+// whose validators match the current index generation gets a 304.
+func (s *Server) search(c *gin.Context, req SearchRequest, conditional bool) {
+	start := time.Now()
+	noteQuery(c, req.Query)
+
+	gen, snap, err := s.searchSource(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(err.status, err.body())
+		return
+	}
+	plan, err := s.planSearch(req, gen, snap)
+	if err != nil {
+		if err.status == http.StatusConflict {
+			setCacheHeaders(c, "", gen)
+		}
+		err.write(c)
+		return
+	}
+
+	epoch, validated := s.epoch.validators(gen)
+	etag := searchETag(gen, epoch, plan.req)
+	if conditional && notModified(c.Request, etag, validated) {
+		setCacheHeaders(c, etag, validated)
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	response, runErr := s.runSearch(c.Request.Context(), plan, start)
+	if runErr != nil {
+		searchFailed(c, runErr)
+		return
+	}
+
+	setCacheHeaders(c, etag, validated)
+	noteResults(c, len(response.Results))
+	c.JSON(http.StatusOK, response)
+}
+
+// requestError is a rejected request and the HTTP status to report it with.
+// extra is added to the JSON error body, and a retryAfter above 0 is sent
+// as Retry-After.
+type requestError struct {
+	status     int
+	msg        string
+	extra      gin.H
+	retryAfter time.Duration
+}
+
+func (e *requestError) body() gin.H {
+	body := gin.H{"error": e.msg}
+	for k, v := range e.extra {
+		body[k] = v
+	}
+	return body
+}
+
+// write answers c with e.
+func (e *requestError) write(c *gin.Context) {
+	if e.retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(e.retryAfter.Seconds()))))
+	}
+	c.JSON(e.status, e.body())
+}
+
+// searchPlan is a validated search request, ready to run.
+type searchPlan struct {
+	req      SearchRequest // with defaults applied and lang normalized
+	searcher search.Pager
+	mode     string
+	opts     search.Options
+	gen      storage.Generation
+	key      string // pageKey of the ranking
+	offset   int
+	text     textOptions
+}
+
+// planSearch validates req against index generation gen, of snap when the
+// request names a snapshot, and applies the configured defaults. It is
+// shared by the REST and gRPC APIs.
+func (s *Server) planSearch(req SearchRequest, gen storage.Generation, snap *snapshot) (*searchPlan, *requestError) {
+	badRequest := func(msg string) *requestError {
+		return &requestError{status: http.StatusBadRequest, msg: msg}
+	}
+
+	mode, modeDefaults := s.config.Search.ForMode(req.Mode)
+	if !search.ValidMode(mode) {
+		return nil, badRequest("invalid mode: expected hybrid, lexical or vector")
+	}
 
 	// Set default top_k if not provided
 	if req.TopK <= 0 {
-		req.TopK = 10
+		req.TopK = modeDefaults.TopK
 	}
 	if req.TopK > 100 {
 		req.TopK = 100 // Limit to prevent abuse
 	}
 
-	// Perform search
-	results, err := s.searcher.Search(c.Request.Context(), req.Query, req.TopK)
+	lang := ingest.NormalizeLang(req.Lang)
+	if req.Lang == search.LangAuto {
+		lang = search.LangAuto
+	} else if req.Lang != "" && lang == "" {
+		return nil, badRequest("invalid lang: expected a language code such as \"en\" or \"pt-BR\", or auto")
+	}
+
+	filter, err := search.ParseFilter(req.Filter)
 	if err != nil {
-		s.logger.Error("Search failed", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "search failed"})
-		return
+		return nil, badRequest("invalid filter: " + err.Error())
+	}
+	if _, ok := filter["path"]; ok && req.Path != "" {
+		return nil, badRequest("path given both as a parameter and in filter")
 	}
 
-	// Convert results to API format
-	apiResults := make([]SearchResult, len(results))
-	for i, result := range results {
-		apiResults[i] = SearchResult{
-			Rank:          i + 1,
-			Score:         result.Score,
-			LexicalScore:  result.LexicalScore,
-			SemanticScore: result.SemanticScore,
-			Modality:      result.Modality,
-			Document: DocumentInfo{
-				Path: result.Path,
-				Meta: result.Meta,
-			},
-			Chunk:      result.Text,
-			Highlights: result.Highlights,
+	if err := search.ValidateBoosts(req.Boosts); err != nil {
+		return nil, badRequest(err.Error())
+	}
+	if _, ok := s.config.Embedding.Spaces[req.Space]; req.Space != "" && req.Space != search.DefaultSpace && !ok {
+		return nil, badRequest(fmt.Sprintf("unknown vector space %q", req.Space))
+	}
+	if req.Window < 0 || req.Window > search.MaxWindow {
+		return nil, badRequest(fmt.Sprintf("window must be between 0 and %d", search.MaxWindow))
+	}
+	if !search.ValidGroupBy(req.GroupBy) {
+		return nil, badRequest("invalid group_by: expected document")
+	}
+	if req.MaxChunksPerDoc < 0 {
+		return nil, badRequest("max_chunks_per_doc must not be negative")
+	}
+	if !search.ValidLexicalSyntax(req.LexicalSyntax) {
+		return nil, badRequest("invalid lexical_syntax: expected simple or advanced")
+	}
+	if req.LexicalSyntax == search.SyntaxAdvanced {
+		for _, q := range append([]string{req.Query}, req.Queries...) {
+			if _, err := storage.ParseLexicalQuery(q); err != nil {
+				return nil, badRequest("invalid query: " + err.Error())
+			}
+		}
+	}
+	if req.MaxTextLength < 0 {
+		return nil, badRequest("max_text_length must not be negative")
+	}
+	if req.SnippetLength < 0 {
+		return nil, badRequest("snippet_length must not be negative")
+	}
+	text := textOptions{
+		maxText:  req.MaxTextLength,
+		snippet:  req.SnippetLength,
+		fullText: req.IncludeFullText || s.config.Search.IncludeFullText,
+	}
+	if text.maxText == 0 {
+		text.maxText = s.config.Search.MaxTextLength
+	}
+	if text.snippet == 0 {
+		text.snippet = s.config.Search.SnippetLength
+	}
+	if !search.ValidExpand(req.Expand) {
+		return nil, badRequest("invalid expand: expected none, terms, hyde or multi")
+	}
+	if (req.Expand == search.ExpandHyDE || req.Expand == search.ExpandMulti) && (s.searcher == nil || !s.searcher.CanRewrite()) {
+		return nil, badRequest("expand " + req.Expand + " needs an LLM: set llm.model")
+	}
+	if len(req.Queries) > search.MaxQueryVariants {
+		return nil, badRequest(fmt.Sprintf("at most %d query variants are allowed", search.MaxQueryVariants))
+	}
+	for _, q := range req.Queries {
+		if strings.TrimSpace(q) == "" {
+			return nil, badRequest("query variants must not be empty")
+		}
+	}
+	var searcher search.Pager = s.searcher
+	if len(req.Collections) > 0 {
+		fed, err := s.federation(req.Collections, req.Space)
+		if err != nil {
+			return nil, err
+		}
+		searcher = fed
+	} else if req.Collection != "" {
+		cs, err := s.collectionSearcher(req.Collection, req.Space)
+		if err != nil {
+			return nil, err
+		}
+		searcher = cs
+	}
+	if snap != nil {
+		if req.Space != "" && req.Space != search.DefaultSpace {
+			return nil, badRequest("snapshots only hold the default vector space")
+		}
+		if snap.embeddingErr != nil && mode != search.ModeLexical {
+			return nil, badRequest("snapshot cannot be searched with vectors: " + snap.embeddingErr.Error() + "; use mode lexical")
 		}
+		searcher = snap.searcher
+	}
+
+	var minIndexedAt time.Time
+	if req.MinIndexedAt != "" {
+		if minIndexedAt, err = time.Parse(time.RFC3339Nano, req.MinIndexedAt); err != nil {
+			return nil, badRequest("invalid min_indexed_at: expected an RFC 3339 timestamp")
+		}
+	}
+
+	req.Lang = lang
+	if !fresh(gen, req.MinGeneration, minIndexedAt) {
+		return nil, &requestError{
+			status: http.StatusConflict,
+			msg:    "index is older than requested",
+			extra:  gin.H{"generation": gen.Number, "indexed_at": generationTime(gen)},
+		}
+	}
+
+	key := pageKey(req, mode)
+	offset := req.Offset
+	if req.Cursor != "" {
+		if req.Offset != 0 {
+			return nil, badRequest("offset and cursor cannot be combined")
+		}
+		cur, err := decodeCursor(req.Cursor)
+		if err != nil || cur.Key != key {
+			return nil, badRequest("invalid cursor: it does not belong to this search")
+		}
+		if cur.Generation != gen.Number {
+			return nil, &requestError{status: http.StatusConflict, msg: "the index changed since the cursor was issued; start again from the first page"}
+		}
+		offset = cur.Offset
+	}
+	if offset < 0 || offset+req.TopK > maxPageDepth {
+		return nil, badRequest(fmt.Sprintf("offset must not be negative and offset + top_k at most %d", maxPageDepth))
+	}
+
+	var adaptive float64
+	if req.Adaptive || req.AdaptiveFull {
+		adaptive = search.AdaptiveDrop(s.config.Search)
+	}
+	// Only valid searches count against max_qps.
+	if err := s.collectionQPS(req); err != nil {
+		return nil, err
+	}
+
+	return &searchPlan{
+		req:      req,
+		searcher: searcher,
+		mode:     mode,
+		opts: search.Options{
+			Lang:            lang,
+			Filter:          filter,
+			Path:            req.Path,
+			Mode:            mode,
+			Parents:         req.Parents,
+			Window:          req.Window,
+			Boosts:          req.Boosts,
+			Space:           req.Space,
+			Expand:          req.Expand,
+			Queries:         req.Queries,
+			Adaptive:        adaptive,
+			AdaptiveFull:    req.AdaptiveFull,
+			GroupBy:         req.GroupBy,
+			MaxChunksPerDoc: req.MaxChunksPerDoc,
+			LexicalSyntax:   req.LexicalSyntax,
+		},
+		gen:    gen,
+		key:    key,
+		offset: offset,
+		text:   text,
+	}, nil
+}
+
+// runSearch performs a planned search within the server's search limits.
+// start is when the request arrived, for the reported duration.
+func (s *Server) runSearch(ctx context.Context, p *searchPlan, start time.Time) (SearchResponse, error) {
+	req, offset := p.req, p.offset
+	var page search.Page
+	err := s.withSearchSlot(ctx, func(ctx context.Context) (err error) {
+		page, err = s.searchPage(ctx, p.searcher, req, p.key, p.gen.Number, offset, req.TopK, p.opts)
+		return err
+	})
+	if err != nil {
+		return SearchResponse{}, err
+	}
+
+	// Convert results to API format
+	apiResults := make([]SearchResult, len(page.Results))
+	for i, result := range page.Results {
+		apiResults[i] = apiResult(result, p.text)
+		apiResults[i].Rank = offset + i + 1
 	}
 
 	response := SearchResponse{
 		Results: apiResults,
 		Query:   req.Query,
 		TopK:    req.TopK,
+		Lang:    req.Lang,
+		Path:    req.Path,
+		Mode:    p.mode,
+		Space:   req.Space,
+		AsOf:    req.AsOf,
 		Took:    time.Since(start).String(),
+
+		Generation: p.gen.Number,
+		IndexedAt:  generationTime(p.gen),
+
+		Offset:          offset,
+		TotalCandidates: page.Total,
+		Warnings:        page.Warnings,
+		Cutoff:          page.Cutoff,
 	}
+	if next := offset + len(page.Results); len(page.Results) == req.TopK && next < page.Total && next < maxPageDepth {
+		response.NextCursor = encodeCursor(pageCursor{Offset: next, Generation: p.gen.Number, Key: p.key})
+	}
+	return response, nil
+}
 
-	c.JSON(http.StatusOK, response)
+// textOptions say how much of the text of results a response carries.
+type textOptions struct {
+	maxText  int  // characters of chunk text per result, 0 for all
+	snippet  int  // characters per snippet, 0 for search.DefaultSnippetLength
+	fullText bool // return the chunk text besides the snippet
 }
 
-// handleHealth handles the health check endpoint
+// apiResult converts a search result, and the chunks nested in it, to the
+// API format with a snippet of its text and, when text.fullText is set,
+// the text cut to text.maxText characters.
+func apiResult(result search.Result, text textOptions) SearchResult {
+	r := SearchResult{
+		ID:            result.ID,
+		Score:         result.Score,
+		LexicalScore:  result.LexicalScore,
+		SemanticScore: result.SemanticScore,
+		Modality:      result.Modality,
+		Document: DocumentInfo{
+			Path: result.Path,
+			Meta: result.Meta,
+		},
+		Highlights: result.Highlights,
+		Link:       result.Link,
+		Collection: result.Collection,
+		Window:     result.Window,
+		Aliases:    result.Aliases,
+	}
+	r.Snippet = result.Snippet(text.snippet)
+	if text.fullText {
+		r.Chunk, r.Truncated = util.Truncate(result.Text, text.maxText)
+	}
+	for _, c := range result.Chunks {
+		r.Chunks = append(r.Chunks, apiResult(c, text))
+	}
+	return r
+}
+
+// handleHealth handles the health check endpoint. It reports liveness only;
+// the embedder status is included for information.
 func (s *Server) handleHealth(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status": "healthy",
-		"time":   time.Now().UTC(),
-	})
+	now := time.Now().UTC()
+	body := HealthResponse{Status: "healthy", Time: &now}
+	if s.probe != nil {
+		st := s.probe.Status()
+		body.Embedder = &st
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// handleReady reports whether searches can be served: it returns 503 while
+// the embedder probe is failing, so load balancers route traffic elsewhere.
+func (s *Server) handleReady(c *gin.Context) {
+	if s.probe == nil {
+		c.JSON(http.StatusOK, HealthResponse{Status: "ready"})
+		return
+	}
+	st := s.probe.Status()
+	if !st.Ready {
+		c.JSON(http.StatusServiceUnavailable, HealthResponse{Status: "unavailable", Embedder: &st})
+		return
+	}
+	c.JSON(http.StatusOK, HealthResponse{Status: "ready", Embedder: &st})
 }
 
-// handleStats handles the stats endpoint
+// handleStats handles the stats endpoint, for the default index or the
+// collection named by the collection parameter.
 func (s *Server) handleStats(c *gin.Context) {
-	stats, err := s.searcher.GetStats(c.Request.Context())
+	searcher, cfg := s.searcher, s.config
+	if name := c.Query("collection"); name != "" {
+		cs, rerr := s.collectionSearcher(name, "")
+		if rerr != nil {
+			c.JSON(rerr.status, rerr.body())
+			return
+		}
+		searcher = cs
+		cfg, _ = s.config.ForCollection(name)
+	}
+	if s.readValidators(c, cfg, "stats\x00"+c.Query("collection")) {
+		return
+	}
+	stats, err := searcher.GetStats(c.Request.Context())
 	if err != nil {
+		util.FromContext(c.Request.Context()).Error("Failed to get stats", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get stats"})
 		return
 	}
@@ -259,23 +955,38 @@ func (s *Server) Start(ctx context.Context) error {
 	// Set Gin mode to release by default
 	gin.SetMode(gin.ReleaseMode)
 
-	router := gin.New()
+	router, err := newRouter(s.config.Server.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	s.router = router
+	s.logger = util.Logger
 
 	// Add middleware
-	router.Use(gin.Logger())
+	router.Use(requestLogging(s.logger))
+	router.Use(accessLog(s.config.Server.AccessLog))
+	router.Use(s.recordActivity())
 	router.Use(gin.Recovery())
 	router.Use(corsMiddleware())
-
-	s.router = router
-	s.logger = util.Logger
 	s.setupRoutes()
 
+	if s.probe != nil {
+		go s.probe.Run(ctx)
+	}
+	if s.searcher != nil {
+		go s.watchIndexes(ctx)
+	}
+	if h := s.config.Report.Interval; h > 0 && s.searcher != nil {
+		go s.writeReports(ctx, time.Duration(h)*time.Hour)
+	}
+
 	addr := fmt.Sprintf("%s:%d", s.config.Server.Host, s.config.Server.Port)
 	slog.Info("Starting HTTP server", "address", addr)
 
 	server := &http.Server{
 		Addr:    addr,
-		Handler: s.router,
+		Handler: s.httpHandler(),
 	}
 
 	// Start server in a goroutine
@@ -293,7 +1004,27 @@ func (s *Server) Start(ctx context.Context) error {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	return server.Shutdown(shutdownCtx)
+	err = server.Shutdown(shutdownCtx)
+	s.snapshots.close()
+	return err
+}
+
+// newRouter returns an empty router that takes the client IP from
+// X-Forwarded-For and X-Real-IP only for requests from the trusted proxies,
+// given as IPs or CIDRs. Trusting every peer, gin's default, would let any
+// client pick the IP its rate limit bucket is keyed on.
+func newRouter(trustedProxies []string) (*gin.Engine, error) {
+	router := gin.New()
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid server.trusted_proxies: %w", err)
+	}
+	return router, nil
+}
+
+// httpHandler serves the router over HTTP/1.1 and, through h2c, over
+// HTTP/2 without TLS, which gRPC clients require.
+func (s *Server) httpHandler() http.Handler {
+	return h2c.NewHandler(s.router, &http2.Server{})
 }
 
 // corsMiddleware adds CORS headers
@@ -301,7 +1032,8 @@ func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Request-ID")
+		c.Header("Access-Control-Expose-Headers", "X-Request-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
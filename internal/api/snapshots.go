@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// snapshot is an index bundle from the snapshot directory, unpacked for
+// read-only searches with as_of.
+type snapshot struct {
+	searcher *search.Searcher
+	manifest storage.BundleManifest
+	// embeddingErr is set when the snapshot was embedded with another model
+	// than the server's, so only lexical searches make sense.
+	embeddingErr error
+
+	dir     string
+	modTime time.Time
+	size    int64
+}
+
+// snapshotStore unpacks snapshots on first use and keeps them until the
+// server stops. A snapshot file that changes is unpacked again. The zero
+// value is ready to use.
+type snapshotStore struct {
+	mu     sync.Mutex
+	byName map[string]*snapshot
+}
+
+// searchSource returns the index generation to search and, for a request
+// with as_of, the snapshot holding it. A request naming a collection
+// searches that collection's indexes, one naming several all of theirs.
+func (s *Server) searchSource(ctx context.Context, req SearchRequest) (storage.Generation, *snapshot, *requestError) {
+	asOf := req.AsOf
+	if len(req.Collections) > 0 {
+		if req.Collection != "" {
+			return storage.Generation{}, nil, &requestError{status: http.StatusBadRequest, msg: "collection and collections cannot be combined"}
+		}
+		gen, err := s.federationSource(req.Collections, asOf)
+		return gen, nil, err
+	}
+	if req.Collection != "" {
+		gen, err := s.collectionSource(req.Collection, asOf)
+		return gen, nil, err
+	}
+	if asOf == "" {
+		return s.indexGeneration(), nil, nil
+	}
+	if s.searcher == nil {
+		return storage.Generation{}, nil, &requestError{status: http.StatusServiceUnavailable, msg: "search is not available"}
+	}
+	snap, err := s.snapshots.open(ctx, s, asOf)
+	if err != nil {
+		return storage.Generation{}, nil, err
+	}
+	return snap.manifest.Generation, snap, nil
+}
+
+// open returns the snapshot named name, unpacking it if needed. Only names
+// of bundles in the snapshot directory are accepted, never paths or URLs.
+func (st *snapshotStore) open(ctx context.Context, s *Server, name string) (*snapshot, *requestError) {
+	unknown := &requestError{status: http.StatusBadRequest, msg: fmt.Sprintf("unknown snapshot %q", name)}
+	if !pipeline.ValidSnapshotName(name) {
+		return nil, unknown
+	}
+	file := filepath.Join(pipeline.SnapshotDir(s.config), name+".tar.gz")
+	info, err := os.Stat(file)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, unknown
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.byName == nil {
+		st.byName = make(map[string]*snapshot)
+	}
+	if snap, ok := st.byName[name]; ok {
+		if snap.modTime.Equal(info.ModTime()) && snap.size == info.Size() {
+			return snap, nil
+		}
+		// Searches still running on the old copy keep their open files.
+		os.RemoveAll(snap.dir)
+		delete(st.byName, name)
+	}
+
+	dir, err := os.MkdirTemp("", "semango-snapshot-*")
+	if err != nil {
+		util.FromContext(ctx).Error("Failed to unpack snapshot", "snapshot", name, "error", err)
+		return nil, &requestError{status: http.StatusInternalServerError, msg: "failed to open snapshot"}
+	}
+	m, paths, err := pipeline.OpenSnapshot(ctx, s.config, file, "", dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		util.FromContext(ctx).Error("Failed to unpack snapshot", "snapshot", name, "error", err)
+		return nil, &requestError{status: http.StatusInternalServerError, msg: "failed to open snapshot"}
+	}
+	snap := &snapshot{
+		searcher:     s.searcher.AsOf(paths),
+		manifest:     m,
+		embeddingErr: pipeline.CheckEmbedding(s.config, m),
+		dir:          dir,
+		modTime:      info.ModTime(),
+		size:         info.Size(),
+	}
+	st.byName[name] = snap
+	util.FromContext(ctx).Info("Opened index snapshot", "snapshot", name, "generation", m.Generation.Number, "built_at", m.CreatedAt)
+	return snap, nil
+}
+
+// close removes every unpacked snapshot.
+func (st *snapshotStore) close() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for name, snap := range st.byName {
+		os.RemoveAll(snap.dir)
+		delete(st.byName, name)
+	}
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+func TestSearchAsOfSnapshot(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	cfg := &config.Config{}
+	cfg.Lexical.IndexPath = filepath.Join(dir, "index", "bleve")
+
+	// A snapshot taken at generation 2; the live index is at generation 3.
+	live := pipeline.BundlePaths(cfg)
+	os.MkdirAll(live.Lexical, 0755)
+	os.WriteFile(filepath.Join(live.Lexical, "index_meta.json"), []byte(`{"storage":"scorch"}`), 0644)
+	storage.BumpGeneration(storage.GenerationPath(live.Lexical))
+	storage.BumpGeneration(storage.GenerationPath(live.Lexical))
+	os.MkdirAll(pipeline.SnapshotDir(cfg), 0755)
+	f, err := os.Create(filepath.Join(pipeline.SnapshotDir(cfg), "v1.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.WriteBundle(f, live, storage.BundleManifest{EmbeddingProvider: "openai"}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	snapGen, _ := storage.ReadGeneration(storage.GenerationPath(live.Lexical))
+	storage.BumpGeneration(storage.GenerationPath(live.Lexical))
+
+	s := &Server{config: cfg, logger: slog.Default(), searcher: &search.Searcher{}}
+	defer s.snapshots.close()
+	r := gin.New()
+	r.GET("/api/v1/search", s.handleSearchGet)
+	get := func(url, etag string) *httptest.ResponseRecorder {
+		hr := httptest.NewRequest(http.MethodGet, url, nil)
+		if etag != "" {
+			hr.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, hr)
+		return w
+	}
+
+	// Validators are those of the snapshot's generation, not the live one.
+	// A matching conditional request is answered without searching.
+	etag := searchETag(snapGen, 0, SearchRequest{Query: "hello", TopK: 10, AsOf: "v1"})
+	w := get("/api/v1/search?q=hello&as_of=v1", etag)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for the snapshot's ETag, got %d: %s", w.Code, w.Body)
+	}
+	if got := w.Header().Get("X-Index-Generation"); got != "2" {
+		t.Errorf("X-Index-Generation = %q, want 2", got)
+	}
+	snap := s.snapshots.byName["v1"]
+	if snap == nil {
+		t.Fatal("snapshot was not kept open")
+	}
+
+	for _, name := range []string{"v2", "../v1", "v1.tar.gz"} {
+		if w := get("/api/v1/search?q=hello&as_of="+name, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("as_of=%s: expected 400, got %d", name, w.Code)
+		}
+	}
+	if w := get("/api/v1/search?q=hello&as_of=v1&space=candidate", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a vector space in a snapshot, got %d", w.Code)
+	}
+
+	s.snapshots.close()
+	if _, err := os.Stat(snap.dir); !os.IsNotExist(err) {
+		t.Errorf("unpacked snapshot not removed: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest/tabular"
+	"github.com/omarkamali/semango/internal/search"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// maxTableQueryRows caps max_rows of table queries.
+const maxTableQueryRows = 10000
+
+// TableInfo is an indexed dataset in the GET /api/v1/tables response.
+type TableInfo struct {
+	tabular.Dataset
+	Schema string `json:"schema"` // columns and their kinds
+}
+
+// TablesResponse is the body of GET /api/v1/tables.
+type TablesResponse struct {
+	Tables []TableInfo `json:"tables"`
+}
+
+// TableQueryRequest is the body of POST /api/v1/tables/query.
+type TableQueryRequest struct {
+	SQL        string `json:"sql"`                  // a single SELECT statement
+	MaxRows    int    `json:"max_rows,omitempty"`   // rows returned; default 100, at most 10000
+	Collection string `json:"collection,omitempty"` // query the datasets of this collection
+}
+
+// tableConfig returns the configuration of the named collection, or the
+// server's for "", writing a 400 response for an unknown one.
+func (s *Server) tableConfig(c *gin.Context, collection string) (*config.Config, bool) {
+	if collection == "" {
+		return s.config, true
+	}
+	cfg, ok := s.config.ForCollection(collection)
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("unknown collection %q", collection)})
+	}
+	return cfg, ok
+}
+
+// indexedDatasets lists the tabular datasets in the indexes of cfg, their
+// files being relative to the working directory like local sources.
+func indexedDatasets(c *gin.Context, cfg *config.Config) ([]search.DatasetSummary, []tabular.Dataset, bool) {
+	summaries, err := search.ListDatasets(c.Request.Context(), cfg)
+	if err != nil {
+		util.FromContext(c.Request.Context()).Error("Failed to list indexed datasets", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to list indexed datasets"})
+		return nil, nil, false
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return nil, nil, false
+	}
+	paths := make([]string, len(summaries))
+	for i, sum := range summaries {
+		paths[i] = sum.Path
+	}
+	return summaries, tabular.NewDatasets(root, paths), true
+}
+
+// handleListTables lists the indexed tabular datasets with the table names
+// queries use.
+func (s *Server) handleListTables(c *gin.Context) {
+	cfg, ok := s.tableConfig(c, c.Query("collection"))
+	if !ok {
+		return
+	}
+	if s.readValidators(c, cfg, "tables\x00"+c.Query("collection")) {
+		return
+	}
+	summaries, datasets, ok := indexedDatasets(c, cfg)
+	if !ok {
+		return
+	}
+	resp := TablesResponse{Tables: make([]TableInfo, len(datasets))}
+	for i, d := range datasets {
+		resp.Tables[i] = TableInfo{Dataset: d, Schema: summaries[i].Schema}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleTableQuery runs a read-only SQL query over the indexed tabular
+// datasets; see tabular.Query.
+func (s *Server) handleTableQuery(c *gin.Context) {
+	var req TableQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid JSON body: " + err.Error()})
+		return
+	}
+	if req.SQL == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "sql is required"})
+		return
+	}
+	if req.MaxRows <= 0 {
+		req.MaxRows = 100
+	}
+	if req.MaxRows > maxTableQueryRows {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("max_rows cannot exceed %d", maxTableQueryRows)})
+		return
+	}
+	cfg, ok := s.tableConfig(c, req.Collection)
+	if !ok {
+		return
+	}
+	_, datasets, ok := indexedDatasets(c, cfg)
+	if !ok {
+		return
+	}
+	res, err := tabular.Query(c.Request.Context(), req.SQL, datasets, cfg.Tabular, req.MaxRows)
+	if errors.Is(err, tabular.ErrInvalidQuery) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		util.FromContext(c.Request.Context()).Error("Table query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omarkamali/semango/internal/config"
+)
+
+func TestTableQueryValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := &Server{config: &config.Config{}, logger: slog.Default()}
+	r := gin.New()
+	r.POST("/api/v1/tables/query", s.handleTableQuery)
+	r.GET("/api/v1/tables", s.handleListTables)
+
+	for _, body := range []string{
+		`{`,
+		`{"sql": ""}`,
+		`{"sql": "SELECT 1", "max_rows": 100000}`,
+		`{"sql": "SELECT * FROM orders", "collection": "nope"}`,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/tables/query", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d %s", body, w.Code, w.Body)
+		}
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/tables?collection=nope", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown collection: %d", w.Code)
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	// "cuelang.org/go/cue/load" // No longer needed
 	cueErrors "cuelang.org/go/cue/errors"
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
 )
@@ -24,14 +25,103 @@ import (
 type Config struct {
 	Embedding EmbeddingConfig `yaml:"embedding"`
 	Lexical   LexicalConfig   `yaml:"lexical"`
+	Vector    VectorConfig    `yaml:"vector"`
+	// VectorStore selects where vectors are kept: FAISS index files, the
+	// default, or a vector database.
+	VectorStore VectorStoreConfig `yaml:"vector_store"`
 	Reranker  RerankerConfig  `yaml:"reranker"`
 	Hybrid    HybridConfig    `yaml:"hybrid"`
+	Search    SearchConfig    `yaml:"search"`
 	Files     FilesConfig     `yaml:"files"`
 	Server    ServerConfig    `yaml:"server"`
 	Plugins   []string        `yaml:"plugins"`
 	UI        UIConfig        `yaml:"ui"`
 	MCP       MCPConfig       `yaml:"mcp"`
 	Tabular   TabularConfig   `yaml:"tabular"`
+	Links     LinksConfig     `yaml:"links"`
+	Media     MediaConfig     `yaml:"media"`
+	Git       GitConfig       `yaml:"git"`
+	LLM       LLMConfig       `yaml:"llm"`
+	Report    ReportConfig    `yaml:"report"`
+	Sources   []SourceConfig  `yaml:"sources"`
+	// Collections are further named corpora, each indexed and searched on
+	// its own; see ForCollection.
+	Collections map[string]CollectionConfig `yaml:"collections"`
+	// LogLevel is "debug", "info", "warn" or "error"; empty means debug.
+	LogLevel string `yaml:"log_level"`
+}
+
+// CollectionConfig defines a named corpus in the collections section, e.g.
+// "docs" or "tickets". Empty fields inherit from the top-level settings.
+type CollectionConfig struct {
+	Include []string       `yaml:"include" cue:"include"`
+	Exclude []string       `yaml:"exclude" cue:"exclude"`
+	Sources []SourceConfig `yaml:"sources" cue:"sources"`
+	// Embedding selects the collection's model, like a vector space.
+	Embedding SpaceEmbeddingConfig `yaml:"embedding" cue:"embedding"`
+	// IndexDir holds the collection's lexical and vector indexes; it
+	// defaults to semango/collections/<name>.
+	IndexDir string `yaml:"index_dir" cue:"index_dir"`
+	// Weight scales the collection's results in searches spanning several
+	// collections; 0 counts as 1.
+	Weight float64 `yaml:"weight" cue:"weight"`
+	// Quota limits the collection, e.g. for a tenant of a shared server.
+	Quota QuotaConfig `yaml:"quota" cue:"quota"`
+}
+
+// QuotaConfig matches the 'quota' sub-section of a collection. Zero leaves
+// a limit off.
+type QuotaConfig struct {
+	MaxDocuments int     `yaml:"max_documents" cue:"max_documents"` // Distinct paths indexed
+	MaxBytes     int64   `yaml:"max_bytes" cue:"max_bytes"`         // Size of the indexes on disk
+	MaxQPS       float64 `yaml:"max_qps" cue:"max_qps"`             // Searches per second, over all clients
+}
+
+// ForCollection returns the configuration of the named collection: c with
+// the collection's files, sources, embedding model and index location
+// applied. The second result reports whether the collection is configured.
+func (c *Config) ForCollection(name string) (*Config, bool) {
+	coll, ok := c.Collections[name]
+	if !ok {
+		return c, false
+	}
+	out := *c
+	out.Collections = nil
+	if len(coll.Include) > 0 {
+		out.Files.Include = coll.Include
+	}
+	if len(coll.Exclude) > 0 {
+		out.Files.Exclude = coll.Exclude
+	}
+	if len(coll.Sources) > 0 {
+		out.Sources = coll.Sources
+	}
+	if e := coll.Embedding; e != (SpaceEmbeddingConfig{}) {
+		out.Embedding = c.Embedding.override(e.Provider, e.Model, e.LocalModelPath)
+	}
+	dir := coll.IndexDir
+	if dir == "" {
+		dir = filepath.Join("semango", "collections", name)
+	}
+	out.Lexical.IndexPath = filepath.Join(dir, "bleve")
+	out.Vector.IndexPath = filepath.Join(dir, DefaultVectorIndexFile)
+	out.VectorStore.Collection = c.VectorStore.CollectionName() + "_" + name
+	return &out, true
+}
+
+// DefaultVectorIndexFile is the file name of the default vector index in an
+// index directory.
+const DefaultVectorIndexFile = "faiss.index"
+
+// VectorIndexPath returns the file of the default vector space's FAISS
+// index: vector.index_path, or semango/index/faiss.index when it is unset.
+// The indexes of other spaces are kept next to it; see
+// storage.SpaceIndexPath.
+func (c *Config) VectorIndexPath() string {
+	if c.Vector.IndexPath == "" {
+		return filepath.Join("semango", "index", DefaultVectorIndexFile)
+	}
+	return c.Vector.IndexPath
 }
 
 // EmbeddingConfig matches the 'embedding' section of semango.yml
@@ -39,9 +129,99 @@ type EmbeddingConfig struct {
 	Provider       string `yaml:"provider" cue:"provider"`
 	Model          string `yaml:"model" cue:"model"`
 	LocalModelPath string `yaml:"local_model_path" cue:"local_model_path"`
+	// Dimensions shortens the vectors of OpenAI text-embedding-3 models to
+	// this many dimensions (Matryoshka truncation); 0 keeps the model's
+	// full dimension. Indexes must be built with the value queries use.
+	Dimensions     int    `yaml:"dimensions" cue:"dimensions"`
 	BatchSize      int    `yaml:"batch_size" cue:"batch_size"`
 	Concurrent     int    `yaml:"concurrent" cue:"concurrent"`
 	ModelCacheDir  string `yaml:"model_cache_dir" cue:"model_cache_dir"`
+	// LocalThreads is the number of intra-op threads per ONNX session for the
+	// local provider, which runs Concurrent sessions in parallel. 0 divides the
+	// CPU cores evenly between sessions.
+	LocalThreads int `yaml:"local_threads" cue:"local_threads"`
+	// Languages maps a query language hint (e.g. "de") to an alternative
+	// model used to embed queries in that language. It must produce vectors
+	// in the same space as the default model.
+	Languages map[string]LanguageEmbeddingConfig `yaml:"languages" cue:"languages"`
+	// Spaces are additional models whose vectors are written next to the
+	// default ones (dual-write), each into a vector index of its own. A
+	// search can select a space by name, so a new model can be evaluated on
+	// the live corpus before cutting over.
+	Spaces map[string]SpaceEmbeddingConfig `yaml:"spaces" cue:"spaces"`
+}
+
+// SpaceEmbeddingConfig selects the model of a named vector space. Empty
+// fields inherit from the default embedding settings, except Dimensions:
+// a space uses its model's full dimension unless it sets its own.
+type SpaceEmbeddingConfig struct {
+	Provider       string `yaml:"provider" cue:"provider"`
+	Model          string `yaml:"model" cue:"model"`
+	LocalModelPath string `yaml:"local_model_path" cue:"local_model_path"`
+	Dimensions     int    `yaml:"dimensions" cue:"dimensions"`
+}
+
+// LanguageEmbeddingConfig overrides parts of EmbeddingConfig for one language.
+// Empty fields inherit from the default embedding settings.
+type LanguageEmbeddingConfig struct {
+	Provider       string `yaml:"provider" cue:"provider"`
+	Model          string `yaml:"model" cue:"model"`
+	LocalModelPath string `yaml:"local_model_path" cue:"local_model_path"`
+}
+
+// ForLanguage returns the embedding settings for queries hinted as lang, with
+// any per-language overrides applied. The second result reports whether an
+// override exists.
+func (e EmbeddingConfig) ForLanguage(lang string) (EmbeddingConfig, bool) {
+	override, ok := e.Languages[lang]
+	if !ok {
+		return e, false
+	}
+	return e.override(override.Provider, override.Model, override.LocalModelPath), true
+}
+
+// ForSpace returns the embedding settings of the named vector space. The
+// second result reports whether the space is configured.
+func (e EmbeddingConfig) ForSpace(name string) (EmbeddingConfig, bool) {
+	space, ok := e.Spaces[name]
+	if !ok {
+		return e, false
+	}
+	out := e.override(space.Provider, space.Model, space.LocalModelPath)
+	out.Dimensions = space.Dimensions
+	return out, true
+}
+
+// Identity names the model that produces the vectors: the provider
+// ("openai" when unset) and the model, or for the local provider the model
+// file name, so machines with different cache directories still match.
+func (e EmbeddingConfig) Identity() (provider, model string) {
+	provider = e.Provider
+	if provider == "" {
+		provider = "openai"
+	}
+	if provider == "local" {
+		return provider, filepath.Base(e.LocalModelPath)
+	}
+	return provider, e.Model
+}
+
+// override returns e with the non-empty arguments applied and without
+// per-language or per-space settings.
+func (e EmbeddingConfig) override(provider, model, localModelPath string) EmbeddingConfig {
+	out := e
+	out.Languages = nil
+	out.Spaces = nil
+	if provider != "" {
+		out.Provider = provider
+	}
+	if model != "" {
+		out.Model = model
+	}
+	if localModelPath != "" {
+		out.LocalModelPath = localModelPath
+	}
+	return out
 }
 
 // LexicalConfig matches the 'lexical' section of semango.yml
@@ -50,6 +230,59 @@ type LexicalConfig struct {
 	IndexPath string  `yaml:"index_path" cue:"index_path"`
 	BM25K1    float64 `yaml:"bm25_k1" cue:"bm25_k1"`
 	BM25B     float64 `yaml:"bm25_b" cue:"bm25_b"`
+	// CodeTokenFilter is "split_identifiers" (default) or "none".
+	CodeTokenFilter string `yaml:"code_token_filter" cue:"code_token_filter"`
+	// Analyzers maps "text", "path" or "meta.<key>" to the analyzer of the
+	// field: "standard" (default), "en" or "code". Changing them rebuilds
+	// the lexical index on the next indexing run.
+	Analyzers map[string]string `yaml:"analyzers" cue:"analyzers"`
+}
+
+// VectorConfig matches the 'vector' section of semango.yml
+type VectorConfig struct {
+	// IndexPath is the FAISS index file of the default vector space; its ID
+	// map and the indexes of embedding.spaces are kept next to it.
+	IndexPath string `yaml:"index_path" cue:"index_path"`
+	// Compression is how vector indexes store vectors: "none" for float32,
+	// "float16", "int8" (scalar quantization) or "pq" (product
+	// quantization, FAISS only). Writes convert indexes to it; "" keeps
+	// the compression of existing indexes and creates float32 ones.
+	Compression string `yaml:"compression" cue:"compression"`
+	// PQSubquantizers is the number of bytes per vector with "pq"; it must
+	// divide the dimension. 0 picks about one byte per eight dimensions.
+	PQSubquantizers int `yaml:"pq_subquantizers" cue:"pq_subquantizers"`
+}
+
+// DefaultVectorStoreCollection names the collection, or table, of the
+// default vector space in a vector database when vector_store.collection is
+// unset.
+const DefaultVectorStoreCollection = "semango"
+
+// VectorStoreConfig matches the 'vector_store' section of semango.yml.
+type VectorStoreConfig struct {
+	// Backend is "faiss" (the default when empty), "qdrant", "pgvector" or
+	// "milvus".
+	Backend string `yaml:"backend" cue:"backend"`
+	// URL is the HTTP address of a Qdrant or Milvus server, or the
+	// PostgreSQL connection string for pgvector.
+	URL string `yaml:"url" cue:"url"`
+	// APIKeyEnv names the environment variable holding the Qdrant API key or
+	// the Milvus token.
+	APIKeyEnv string `yaml:"api_key_env" cue:"api_key_env"`
+	// Driver is the database/sql driver used for pgvector; "" means "pgx".
+	Driver string `yaml:"driver" cue:"driver"`
+	// Collection names the collection, or table, of the default vector
+	// space; other spaces and collections append "_<name>" to it.
+	Collection string `yaml:"collection" cue:"collection"`
+}
+
+// CollectionName returns vector_store.collection, or
+// DefaultVectorStoreCollection when it is unset.
+func (v VectorStoreConfig) CollectionName() string {
+	if v.Collection == "" {
+		return DefaultVectorStoreCollection
+	}
+	return v.Collection
 }
 
 // RerankerConfig matches the 'reranker' section of semango.yml
@@ -68,12 +301,101 @@ type HybridConfig struct {
 	Fusion        string  `yaml:"fusion" cue:"fusion"`
 }
 
+// SearchConfig matches the 'search' section: defaults for queries that do
+// not set a mode or result count themselves.
+type SearchConfig struct {
+	DefaultMode string           `yaml:"default_mode" cue:"default_mode"` // "hybrid" (default), "lexical" or "vector"
+	Hybrid      SearchModeConfig `yaml:"hybrid" cue:"hybrid"`
+	Lexical     SearchModeConfig `yaml:"lexical" cue:"lexical"`
+	Vector      SearchModeConfig `yaml:"vector" cue:"vector"`
+	// MaxTextLength cuts the text of each result to this many characters
+	// in API and CLI output; 0 returns it whole.
+	MaxTextLength int `yaml:"max_text_length" cue:"max_text_length"`
+	// SnippetLength is the length, in characters, of the snippet API
+	// results carry in place of their chunk text; 0 means 240.
+	SnippetLength int `yaml:"snippet_length" cue:"snippet_length"`
+	// IncludeFullText returns the chunk text of API results, cut to
+	// MaxTextLength, as if every request set include_full_text.
+	IncludeFullText bool `yaml:"include_full_text" cue:"include_full_text"`
+	// QualityPrior, from 0 to 1, is how much the "quality" score chunks
+	// get at indexing time counts in ranking: a chunk's score is scaled by
+	// 1 - QualityPrior*(1-quality). 0 ignores quality.
+	QualityPrior float64 `yaml:"quality_prior" cue:"quality_prior"`
+	// AdaptiveDrop is the smallest score drop, as a fraction of the top
+	// score, at which adaptive searches stop returning results; 0 uses
+	// 0.2.
+	AdaptiveDrop float64 `yaml:"adaptive_drop" cue:"adaptive_drop"`
+}
+
+// SearchModeConfig holds the defaults for queries run in one search mode.
+type SearchModeConfig struct {
+	TopK int `yaml:"top_k" cue:"top_k"` // Results returned when the query sets none, defaults to 10
+}
+
+// ForMode resolves the mode of a query that asked for requested ("" for no
+// preference) and returns it with that mode's defaults. Unknown modes are
+// returned as is, for the searcher to reject.
+func (s SearchConfig) ForMode(requested string) (string, SearchModeConfig) {
+	mode := requested
+	if mode == "" {
+		mode = s.DefaultMode
+	}
+	if mode == "" {
+		mode = "hybrid"
+	}
+	var mc SearchModeConfig
+	switch mode {
+	case "hybrid":
+		mc = s.Hybrid
+	case "lexical":
+		mc = s.Lexical
+	case "vector":
+		mc = s.Vector
+	}
+	if mc.TopK <= 0 {
+		mc.TopK = 10
+	}
+	return mode, mc
+}
+
 // TabularConfig matches the 'tabular' section of semango.yml
 type TabularConfig struct {
 	MaxRowsEmbedded int    `yaml:"max_rows_embedded" cue:"max_rows_embedded"`
 	Sampling        string `yaml:"sampling" cue:"sampling"`
 	MinTextTokens   int    `yaml:"min_text_tokens" cue:"min_text_tokens"`
 	Delimiter       string `yaml:"delimiter" cue:"delimiter"`
+	// NumbersInText adds the values of numeric and datetime columns to the
+	// embedded text of rows, which otherwise only holds text columns.
+	NumbersInText bool `yaml:"numbers_in_text" cue:"numbers_in_text"`
+	// Redact hides columns of personal data before rows become text and
+	// metadata; the first entry naming a column decides how.
+	Redact []ColumnRedaction `yaml:"redact" cue:"redact"`
+	// DetectPII is how columns not named in Redact are redacted when most
+	// of their values are email addresses, phone numbers or US social
+	// security numbers: "mask", "hash" or "drop"; "" leaves them as they are.
+	DetectPII string `yaml:"detect_pii" cue:"detect_pii"`
+	// HashSalt is hashed with the values of hashed columns, so that short
+	// ones such as phone numbers cannot be found by hashing every candidate.
+	HashSalt string `yaml:"hash_salt" cue:"hash_salt"`
+	// MaxRowsPerSheet caps the rows embedded from each sheet of a
+	// spreadsheet; 0 shares MaxRowsEmbedded between the sheets, so that a
+	// large sheet cannot crowd out the others.
+	MaxRowsPerSheet int `yaml:"max_rows_per_sheet" cue:"max_rows_per_sheet"`
+	// SamplingSeed seeds random sampling; 0 seeds it with a hash of the
+	// rows of each file, so that the same rows are embedded until the file
+	// changes.
+	SamplingSeed int64 `yaml:"sampling_seed" cue:"sampling_seed"`
+}
+
+// ColumnRedaction is an entry of tabular.redact.
+type ColumnRedaction struct {
+	// Columns are column names or patterns such as "*email*", matched
+	// regardless of case.
+	Columns []string `yaml:"columns" cue:"columns"`
+	// Action is "mask" (or "") to replace values with [REDACTED], "hash" to
+	// replace them with a hash, which still matches equal values in
+	// filters, or "drop" to leave the column out.
+	Action string `yaml:"action" cue:"action"`
 }
 
 // FilesConfig matches the 'files' section of semango.yml
@@ -82,6 +404,99 @@ type FilesConfig struct {
 	Exclude      []string `yaml:"exclude" cue:"exclude"`
 	ChunkSize    int      `yaml:"chunk_size" cue:"chunk_size"`
 	ChunkOverlap int      `yaml:"chunk_overlap" cue:"chunk_overlap"`
+	// Chunking is how text is cut into chunks: "fixed" (or "") every
+	// ChunkSize bytes at a word boundary, "sentence" into whole sentences up
+	// to ChunkSize, "semantic" also between the neighbouring sentences whose
+	// embeddings differ more than at the SemanticPercentile-th percentile of
+	// the text (0 = 95).
+	Chunking           string `yaml:"chunking" cue:"chunking"`
+	SemanticPercentile int    `yaml:"semantic_percentile" cue:"semantic_percentile"`
+	// Limits for archives (.zip, .tar.gz, ...); 0 selects the default.
+	ArchiveMaxBytes int64 `yaml:"archive_max_bytes" cue:"archive_max_bytes"`
+	ArchiveMaxDepth int   `yaml:"archive_max_depth" cue:"archive_max_depth"`
+	// ParentChunkSize groups consecutive chunks into parent sections of up to
+	// this many bytes, which searches can return instead of the matched
+	// chunk. 0 disables parents.
+	ParentChunkSize int `yaml:"parent_chunk_size" cue:"parent_chunk_size"`
+	// PostProcessors rewrite each document's chunks, in order, between the
+	// loader and the embedder.
+	PostProcessors []PostProcessorConfig `yaml:"post_processors" cue:"post_processors"`
+	// Rules override the loader and chunking of the files matching their
+	// path, see RuleFor.
+	Rules []FileRule `yaml:"rules" cue:"rules"`
+	// Dedup indexes a single copy of duplicated chunks: "exact" of chunks
+	// whose text only differs in case and white space, "near" also of long
+	// chunks whose SimHashes are at most DedupDistance bits apart (0 = 3).
+	// "" indexes every chunk.
+	Dedup         string `yaml:"dedup" cue:"dedup"`
+	DedupDistance int    `yaml:"dedup_distance" cue:"dedup_distance"`
+	// DetectLanguage sets the "lang" metadata of text chunks without one to
+	// the language detected in the chunk or its document, so they are also
+	// indexed with the analyzer for that language.
+	DetectLanguage bool `yaml:"detect_language" cue:"detect_language"`
+	// RespectGitignore leaves out the files .gitignore files ignore, in the
+	// crawled directory and below. .semangoignore files are always read.
+	RespectGitignore bool `yaml:"respect_gitignore" cue:"respect_gitignore"`
+	// FollowSymlinks walks into symlinked directories, unless their target
+	// was crawled already, which also stops symlink loops. Symlinked files
+	// are always indexed.
+	FollowSymlinks bool `yaml:"follow_symlinks" cue:"follow_symlinks"`
+	// SkipHidden leaves out files and directories whose name starts with a
+	// dot.
+	SkipHidden bool `yaml:"skip_hidden" cue:"skip_hidden"`
+	// MaxDepth is how many directory levels are crawled: 1 only crawls the
+	// files of the root. 0 sets no limit.
+	MaxDepth int `yaml:"max_depth" cue:"max_depth"`
+	// MaxFileSize is the size in bytes above which files are skipped; 0
+	// sets no limit. Code files are skipped above 5 MiB regardless.
+	MaxFileSize int64 `yaml:"max_file_size" cue:"max_file_size"`
+}
+
+// FileRule is an entry of files.rules. Zero values leave the setting as
+// chosen by the extension and the files section.
+type FileRule struct {
+	Path         string `yaml:"path" cue:"path"`                   // Pattern of paths relative to the root, e.g. "docs/**" or "**/*.go"
+	Loader       string `yaml:"loader" cue:"loader"`               // e.g. "code" or "markdown"
+	Chunking     string `yaml:"chunking" cue:"chunking"`           // Overrides files.chunking
+	ChunkSize    int    `yaml:"chunk_size" cue:"chunk_size"`       // Overrides files.chunk_size
+	ChunkOverlap int    `yaml:"chunk_overlap" cue:"chunk_overlap"` // Overrides files.chunk_overlap
+	StripImports bool   `yaml:"strip_imports" cue:"strip_imports"` // Code loader: leave import statements out of chunks
+}
+
+// RuleFor merges the rules matching path, in order, so that later rules
+// override the settings of earlier ones. ok is false when none matches.
+func (f FilesConfig) RuleFor(path string) (rule FileRule, ok bool) {
+	path = filepath.ToSlash(path)
+	for _, r := range f.Rules {
+		if match, _ := doublestar.Match(r.Path, path); !match {
+			continue
+		}
+		ok = true
+		rule.Path = r.Path
+		if r.Loader != "" {
+			rule.Loader = r.Loader
+		}
+		if r.Chunking != "" {
+			rule.Chunking = r.Chunking
+		}
+		if r.ChunkSize > 0 {
+			rule.ChunkSize = r.ChunkSize
+		}
+		if r.ChunkOverlap > 0 {
+			rule.ChunkOverlap = r.ChunkOverlap
+		}
+		rule.StripImports = rule.StripImports || r.StripImports
+	}
+	return rule, ok
+}
+
+// PostProcessorConfig is an entry of files.post_processors.
+type PostProcessorConfig struct {
+	Name string `yaml:"name" cue:"name"` // e.g. "trim_boilerplate", "breadcrumbs" or "set_meta"
+	// Modalities limits the processor to chunks of these modalities, e.g.
+	// ["text"]; empty applies it to every chunk.
+	Modalities []string          `yaml:"modalities" cue:"modalities"`
+	Options    map[string]string `yaml:"options" cue:"options"`
 }
 
 // ServerConfig matches the 'server' section of semango.yml
@@ -91,12 +506,52 @@ type ServerConfig struct {
 	Auth    AuthConfig `yaml:"auth" cue:"auth"`
 	TLSCert string     `yaml:"tls_cert" cue:"tls_cert"`
 	TLSCKey string     `yaml:"tls_key" cue:"tls_key"` // Note: spec.md mentions tls_cert only, but key is usually needed.
+
+	// The embedder is probed in the background so provider outages show up
+	// in /api/v1/ready before users hit them.
+	ProbeInterval int `yaml:"probe_interval" cue:"probe_interval"` // Seconds between probes, defaults to 60
+	ProbeFailures int `yaml:"probe_failures" cue:"probe_failures"` // Consecutive failures before not ready, defaults to 3
+
+	AccessLog AccessLogConfig `yaml:"access_log" cue:"access_log"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" cue:"rate_limit"`
+
+	// TrustedProxies lists the IPs or CIDRs of reverse proxies whose
+	// X-Forwarded-For and X-Real-IP headers give the client IP, e.g. for
+	// per-IP rate limits. Headers from other peers are ignored.
+	TrustedProxies []string `yaml:"trusted_proxies" cue:"trusted_proxies"`
+
+	// Searches over MaxConcurrentSearches wait for a running one to finish;
+	// the wait counts against SearchTimeout.
+	SearchTimeout         int `yaml:"search_timeout" cue:"search_timeout"`                   // Seconds before a search fails with 504; 0 = no limit
+	MaxConcurrentSearches int `yaml:"max_concurrent_searches" cue:"max_concurrent_searches"` // 0 = no limit
+
+	// WatchConfig reloads the configuration file when it changes, as SIGHUP
+	// does; see Config.CheckReload for what can change.
+	WatchConfig bool `yaml:"watch_config" cue:"watch_config"`
+}
+
+// AccessLogConfig matches the 'access_log' sub-section of 'server'. Every
+// HTTP request is logged as one JSON line unless SampleRate is set.
+type AccessLogConfig struct {
+	SampleRate    float64 `yaml:"sample_rate" cue:"sample_rate"`       // Fraction of requests logged, 0 logs all; errors are always logged
+	RedactQueries bool    `yaml:"redact_queries" cue:"redact_queries"` // Log only a hash of the query text
+}
+
+// RateLimitConfig matches the 'rate_limit' sub-section of 'server'. Each
+// bearer token, or each client IP for requests without one, gets a token
+// bucket of Burst requests refilled at RPS per second.
+type RateLimitConfig struct {
+	RPS   float64 `yaml:"rps" cue:"rps"`     // Sustained requests per second per client, 0 disables rate limiting
+	Burst int     `yaml:"burst" cue:"burst"` // Requests allowed at once, defaults to RPS rounded up
 }
 
 // AuthConfig matches the 'auth' sub-section of 'server'
 type AuthConfig struct {
 	Type     string `yaml:"type" cue:"type"`
 	TokenEnv string `yaml:"token_env" cue:"token_env"`
+	// AdminTokenEnv names the variable holding the tokens of admin
+	// endpoints such as PUT /api/v1/embedder; they are disabled when unset.
+	AdminTokenEnv string `yaml:"admin_token_env" cue:"admin_token_env"`
 }
 
 // UIConfig matches the 'ui' section
@@ -109,6 +564,80 @@ type MCPConfig struct {
 	Enabled bool `yaml:"enabled" cue:"enabled"`
 }
 
+// LinksConfig matches the 'links' section. Template is expanded for every
+// search result, e.g. "vscode://file/{abs_path}:{line}" or
+// "https://github.com/org/repo/blob/{branch}/{path}#L{line}".
+type LinksConfig struct {
+	Template string `yaml:"template" cue:"template"`
+	Root     string `yaml:"root" cue:"root"`     // Base directory for {abs_path}; defaults to the working directory
+	Branch   string `yaml:"branch" cue:"branch"` // Value for {branch}; defaults to "main"
+}
+
+// MediaConfig matches the 'media' section. Video files are processed with
+// external tools: ffmpeg extracts the audio track and keyframes, and
+// whisper.cpp transcribes the audio. Zero values fall back to defaults.
+type MediaConfig struct {
+	FFmpegPath       string `yaml:"ffmpeg_path" cue:"ffmpeg_path"`             // Defaults to "ffmpeg" on PATH
+	WhisperPath      string `yaml:"whisper_path" cue:"whisper_path"`           // Defaults to "whisper-cli" on PATH
+	WhisperModel     string `yaml:"whisper_model" cue:"whisper_model"`         // ggml model file; empty disables transcription
+	WhisperLanguage  string `yaml:"whisper_language" cue:"whisper_language"`   // Spoken language, defaults to "auto"
+	SegmentSeconds   int    `yaml:"segment_seconds" cue:"segment_seconds"`     // Transcript window per representation, defaults to 30
+	Keyframes        bool   `yaml:"keyframes" cue:"keyframes"`                 // Also emit image representations of keyframes
+	KeyframeInterval int    `yaml:"keyframe_interval" cue:"keyframe_interval"` // Seconds between keyframes, defaults to 60
+}
+
+// GitConfig matches the 'git' section. When indexing inside a git repository,
+// commit messages (and optionally diffs) can be indexed alongside the files.
+type GitConfig struct {
+	History      bool `yaml:"history" cue:"history"`               // Index commit messages
+	Diffs        bool `yaml:"diffs" cue:"diffs"`                   // Also index each commit's diff
+	MaxCommits   int  `yaml:"max_commits" cue:"max_commits"`       // Most recent commits to index, defaults to 1000
+	MaxDiffBytes int  `yaml:"max_diff_bytes" cue:"max_diff_bytes"` // Diff bytes kept per commit, defaults to 8000
+}
+
+// ReportConfig matches the 'report' section: the index health reports a
+// running server writes.
+type ReportConfig struct {
+	Interval int    `yaml:"interval" cue:"interval"` // Hours between reports; 0 disables them
+	Dir      string `yaml:"dir" cue:"dir"`           // Defaults to reports/ next to the lexical index
+}
+
+// LLMConfig matches the 'llm' section. It configures the chat model that
+// answers questions from search results (POST /api/v1/answer and
+// `semango ask`); answers are disabled while Model is empty.
+type LLMConfig struct {
+	Provider   string `yaml:"provider" cue:"provider"`       // "openai", for any OpenAI-compatible API (the default)
+	Model      string `yaml:"model" cue:"model"`             // Chat model, e.g. "gpt-4o-mini"
+	BaseURL    string `yaml:"base_url" cue:"base_url"`       // API base URL; defaults to https://api.openai.com/v1
+	APIKeyEnv  string `yaml:"api_key_env" cue:"api_key_env"` // Env var holding the API key, defaults to OPENAI_API_KEY
+	MaxContext int    `yaml:"max_context" cue:"max_context"` // Tokens of source text per prompt, defaults to 6000
+	TopK       int    `yaml:"top_k" cue:"top_k"`             // Chunks retrieved per question, defaults to 8
+	MaxTokens  int    `yaml:"max_tokens" cue:"max_tokens"`   // Answer length limit; 0 leaves it to the provider
+}
+
+// SourceConfig is one entry of the 'sources' list. Without sources, the
+// working directory is crawled using the files section.
+type SourceConfig struct {
+	Type string `yaml:"type" cue:"type"` // "local", "s3", "gcs" or "sitemap"
+
+	Path string `yaml:"path" cue:"path"` // local: directory to crawl, defaults to "."
+
+	Bucket       string `yaml:"bucket" cue:"bucket"`                 // s3/gcs
+	Prefix       string `yaml:"prefix" cue:"prefix"`                 // s3/gcs: only keys under this prefix
+	Region       string `yaml:"region" cue:"region"`                 // s3: defaults to AWS_REGION or us-east-1
+	Endpoint     string `yaml:"endpoint" cue:"endpoint"`             // s3: S3-compatible endpoint, e.g. a MinIO URL
+	AccessKeyEnv string `yaml:"access_key_env" cue:"access_key_env"` // Env var holding the access key ID
+	SecretKeyEnv string `yaml:"secret_key_env" cue:"secret_key_env"` // Env var holding the secret key
+
+	URL      string `yaml:"url" cue:"url"`             // sitemap: sitemap.xml URL
+	MaxPages int    `yaml:"max_pages" cue:"max_pages"` // sitemap: pages to fetch, defaults to 1000
+
+	// Include/Exclude filter keys or URL paths; they default to the files
+	// section for local and bucket sources.
+	Include []string `yaml:"include" cue:"include"`
+	Exclude []string `yaml:"exclude" cue:"exclude"`
+}
+
 // ErrUnknownField is a custom error type for unknown configuration fields.
 type ErrUnknownField struct {
 	Err error
@@ -124,7 +653,10 @@ func (e *ErrUnknownField) Unwrap() error {
 
 // DefaultConfigPath is the default path for the configuration file.
 const DefaultConfigPath = "semango.yml"
-const DefaultCueSchemaPath = "docs/config.cue"
+
+// DefaultCueSchemaPath selects the CUE schema built into the binary. Load
+// takes the path of a schema file instead to override it.
+const DefaultCueSchemaPath = ""
 
 // expandWithDefault expands a string like "${VAR:=default_value}" or "$VAR".
 // If VAR is set, its value is used. Otherwise, default_value is used.
@@ -141,6 +673,12 @@ func expandPath(path string) string {
 	return path
 }
 
+// ExpandEnv expands ${VAR:=default}, $VAR and a leading ~ in s, as Load does
+// for paths in the configuration.
+func ExpandEnv(s string) string {
+	return expandWithDefault(s)
+}
+
 func expandWithDefault(s string) string {
 	result := envVarWithDefaultRegex.ReplaceAllStringFunc(s, func(match string) string {
 		expandedSimple := os.ExpandEnv(match)
@@ -174,7 +712,14 @@ func expandWithDefault(s string) string {
 }
 
 // Load attempts to load configuration from the given path and validates it against the CUE schema.
-func Load(configPath string, cueSchemaPath string) (*Config, error) {
+// An empty cueSchemaPath selects the schema embedded in the binary; a schema file given
+// instead must exist.
+//
+// Settings are layered: the file, then SEMANGO_* environment variables (see EnvVar), then
+// overrides given as key=value, e.g. from the --set flag. Without a file at
+// DefaultConfigPath the defaults of GetDefaultConfig take its place, so that a container
+// can be configured from the environment alone.
+func Load(configPath string, cueSchemaPath string, overrides ...string) (*Config, error) {
     // Load environment variables from file if available.
     // Priority: SEMANGO_ENV_FILE (if set) > .env (if present in working directory)
     if customEnv := os.Getenv("SEMANGO_ENV_FILE"); customEnv != "" {
@@ -187,29 +732,35 @@ func Load(configPath string, cueSchemaPath string) (*Config, error) {
 	if configPath == "" {
 		configPath = DefaultConfigPath
 	}
-	if cueSchemaPath == "" {
-		cueSchemaPath = DefaultCueSchemaPath
-	}
-
-	var schemaBytes []byte
+	schemaBytes := embeddedCueSchema
 	if cueSchemaPath != "" {
-		if b, err := os.ReadFile(cueSchemaPath); err == nil {
-			schemaBytes = b
-		} else {
-			schemaBytes = embeddedCueSchema
+		b, err := os.ReadFile(cueSchemaPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CUE schema %s: %w", cueSchemaPath, err)
 		}
+		schemaBytes = b
 	} else {
-		schemaBytes = embeddedCueSchema
+		cueSchemaPath = "built-in config_schema.cue"
 	}
 
+	var cfg Config
 	yamlData, err := os.ReadFile(configPath)
-	if err != nil {
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(yamlData, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal YAML data from %s: %w", configPath, err)
+		}
+	case os.IsNotExist(err) && configPath == DefaultConfigPath:
+		cfg = *GetDefaultConfig()
+	default:
 		return nil, fmt.Errorf("failed to read config file %s: %w", configPath, err)
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(yamlData, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal YAML data from %s: %w", configPath, err)
+	if err := cfg.ApplyEnv(); err != nil {
+		return nil, fmt.Errorf("invalid environment override: %w", err)
+	}
+	if err := cfg.ApplyOverrides(overrides); err != nil {
+		return nil, fmt.Errorf("invalid override: %w", err)
 	}
 
 	ctx := cuecontext.New()
@@ -257,6 +808,15 @@ func Load(configPath string, cueSchemaPath string) (*Config, error) {
 
 	cfg.Embedding.ModelCacheDir = expandWithDefault(cfg.Embedding.ModelCacheDir)
 	cfg.Lexical.IndexPath = expandWithDefault(cfg.Lexical.IndexPath)
+	cfg.Vector.IndexPath = expandWithDefault(cfg.Vector.IndexPath)
+	cfg.VectorStore.URL = expandWithDefault(cfg.VectorStore.URL)
+	for name, coll := range cfg.Collections {
+		coll.IndexDir = expandWithDefault(coll.IndexDir)
+		cfg.Collections[name] = coll
+	}
+	cfg.Links.Root = expandWithDefault(cfg.Links.Root)
+	cfg.Media.WhisperModel = expandWithDefault(cfg.Media.WhisperModel)
+	cfg.Report.Dir = expandWithDefault(cfg.Report.Dir)
 
 	return &cfg, nil
 }
@@ -274,10 +834,14 @@ func GetDefaultConfig() *Config {
 			ModelCacheDir:  "${SEMANGO_MODEL_DIR:=~/.cache/semango}",
 		},
 		Lexical: LexicalConfig{
-			Enabled:   true,
-			IndexPath: "./semango/index/bleve",
-			BM25K1:    1.2,
-			BM25B:     0.75,
+			Enabled:         true,
+			IndexPath:       "./semango/index/bleve",
+			BM25K1:          1.2,
+			BM25B:           0.75,
+			CodeTokenFilter: "split_identifiers",
+		},
+		Vector: VectorConfig{
+			IndexPath: "./semango/index/faiss.index",
 		},
 		Reranker: RerankerConfig{
 			Enabled:            false,
@@ -292,10 +856,11 @@ func GetDefaultConfig() *Config {
 			Fusion:        "linear",
 		},
 		Files: FilesConfig{
-			Include:      []string{"**/*.md", "**/*.go", "**/*.{png,jpg,jpeg}", "**/*.pdf", "**/*.csv", "**/*.json", "**/*.jsonl", "**/*.parquet"},
-			Exclude:      []string{".git/**", "node_modules/**", "vendor/**"},
-			ChunkSize:    1000,
-			ChunkOverlap: 200,
+			Include:          []string{"**/*.md", "**/*.go", "**/*.{png,jpg,jpeg}", "**/*.pdf", "**/*.{docx,odt,rtf}", "**/*.epub", "**/*.{eml,mbox}", "**/*.ipynb", "**/*.csv", "**/*.json", "**/*.jsonl", "**/*.parquet"},
+			Exclude:          []string{".git/**", "node_modules/**", "vendor/**"},
+			ChunkSize:        1000,
+			ChunkOverlap:     200,
+			RespectGitignore: true,
 		},
 		Server: ServerConfig{
 			Host: "0.0.0.0",
@@ -322,6 +887,7 @@ func GetDefaultConfig() *Config {
 			Sampling:        "random",
 			MinTextTokens:   5,
 			Delimiter:       "",
+			DetectPII:       "mask",
 		},
 	}
 }
@@ -329,15 +895,19 @@ func GetDefaultConfig() *Config {
 // WriteDefaultConfig writes the default configuration to the specified path.
 // If the path is empty, it uses DefaultConfigPath.
 func WriteDefaultConfig(configPath string) error {
+	return WriteConfig(configPath, GetDefaultConfig())
+}
+
+// WriteConfig writes cfg as YAML to the specified path. If the path is empty,
+// it uses DefaultConfigPath.
+func WriteConfig(configPath string, cfg *Config) error {
 	if configPath == "" {
 		configPath = DefaultConfigPath
 	}
 
-	cfg := GetDefaultConfig()
-
 	data, err := yaml.Marshal(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to marshal default config: %w", err)
+		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
 	// Ensure directory exists
@@ -349,7 +919,7 @@ func WriteDefaultConfig(configPath string) error {
 	}
 
 	if err := os.WriteFile(configPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write default config to %s: %w", configPath, err)
+		return fmt.Errorf("failed to write config to %s: %w", configPath, err)
 	}
 	return nil
 }
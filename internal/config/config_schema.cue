@@ -7,30 +7,74 @@ package config
 #Config: {
 	embedding: #EmbeddingConfig
 	lexical:   #LexicalConfig
+	vector?:   #VectorConfig
+	vector_store?: #VectorStoreConfig
 	reranker:   #RerankerConfig
 	hybrid:    #HybridConfig
+	search?:   #SearchConfig
 	files:     #FilesConfig
 	server:    #ServerConfig
 	plugins?:  [...string]
 	ui:        #UIConfig
 	mcp:       #MCPConfig
 	tabular:   #TabularConfig
+	links?:    #LinksConfig
+	media?:    #MediaConfig
+	git?:      #GitConfig
+	llm?:      #LLMConfig
+	report?:   #ReportConfig
+	sources?:  [...#SourceConfig]
+	collections?: [=~"^[A-Za-z0-9_-]+$"]: #CollectionConfig
+	log_level?: *"" | "debug" | "info" | "warn" | "error"
 }
 
 #EmbeddingConfig: {
 	provider:         string | *"local" | "openai" | "cohere" | "voyage"
 	model:            string
 	local_model_path: string | *"models/e5-small.gguf"
+	dimensions:       int & >=0 | *0
 	batch_size:       int & >=1 & <=512 | *48
 	concurrent:       int & >=1 | *4
 	model_cache_dir:  string
+	local_threads:    int & >=0 | *0
+	languages?: [string]: #LanguageEmbeddingConfig
+	spaces?: [=~"^[A-Za-z0-9_-]+$" & !="default"]: #SpaceEmbeddingConfig
+}
+
+#LanguageEmbeddingConfig: {
+	provider:         *"" | "local" | "openai" | "cohere" | "voyage"
+	model:            string | *""
+	local_model_path: string | *""
+}
+
+#SpaceEmbeddingConfig: {
+	provider:         *"" | "local" | "openai" | "cohere" | "voyage"
+	model:            string | *""
+	local_model_path: string | *""
+	dimensions:       int & >=0 | *0
 }
 
 #LexicalConfig: {
-	enabled:    bool | *true
-	index_path: string
-	bm25_k1:    float  | *1.2
-	bm25_b:     float  | *0.75
+	enabled:           bool | *true
+	index_path:        string
+	bm25_k1:           float  | *1.2
+	bm25_b:            float  | *0.75
+	code_token_filter: *"" | "split_identifiers" | "none"
+	analyzers?: [=~"^(text|path|meta\\..+)$"]: "standard" | "en" | "code"
+}
+
+#VectorConfig: {
+	index_path:       string | *""
+	compression:      *"" | "none" | "float16" | "int8" | "pq"
+	pq_subquantizers: int & >=0 | *0
+}
+
+#VectorStoreConfig: {
+	backend:     *"" | "faiss" | "qdrant" | "pgvector" | "milvus"
+	url:         string | *""
+	api_key_env: string | *""
+	driver:      string | *""
+	collection:  =~"^[A-Za-z0-9_-]*$" | *""
 }
 
 #RerankerConfig: {
@@ -48,10 +92,40 @@ package config
 }
 
 #FilesConfig: {
-	include: [...string] | *["**/*.md", "**/*.go", "**/*.{png,jpg,jpeg}", "**/*.pdf", "**/*.csv", "**/*.json", "**/*.jsonl", "**/*.parquet"]
+	include: [...string] | *["**/*.md", "**/*.go", "**/*.{png,jpg,jpeg}", "**/*.pdf", "**/*.{docx,odt,rtf}", "**/*.epub", "**/*.{eml,mbox}", "**/*.ipynb", "**/*.csv", "**/*.json", "**/*.jsonl", "**/*.parquet"]
 	exclude: [...string] | *[".git/**", "node_modules/**", "vendor/**"]
 	chunk_size: int | *1000
 	chunk_overlap: int | *200
+	chunking: *"" | "fixed" | "sentence" | "semantic"
+	semantic_percentile: int & >=0 & <=100 | *0
+	archive_max_bytes: int & >=0 | *0
+	archive_max_depth: int & >=0 | *0
+	parent_chunk_size: int & >=0 | *0
+	post_processors?: [...#PostProcessorConfig]
+	rules?: [...#FileRule]
+	dedup: *"" | "exact" | "near"
+	dedup_distance: int & >=0 & <=3 | *0
+	detect_language: bool | *false
+	respect_gitignore: bool | *false
+	follow_symlinks: bool | *false
+	skip_hidden: bool | *false
+	max_depth: int & >=0 | *0
+	max_file_size: int & >=0 | *0
+}
+
+#FileRule: {
+	path:          string & !=""
+	loader:        string | *""
+	chunking:      *"" | "fixed" | "sentence" | "semantic"
+	chunk_size:    int & >=0 | *0
+	chunk_overlap: int & >=0 | *0
+	strip_imports: bool | *false
+}
+
+#PostProcessorConfig: {
+	name:        string & !=""
+	modalities?: [...string]
+	options?: [string]: string
 }
 
 #ServerConfig: {
@@ -60,11 +134,30 @@ package config
 	auth: #AuthConfig
 	tls_cert?: string
 	tls_key?: string
+	probe_interval: int & >=0 | *0
+	probe_failures: int & >=0 | *0
+	access_log?: #AccessLogConfig
+	rate_limit?: #RateLimitConfig
+	trusted_proxies?: [...string]
+	search_timeout: int & >=0 | *0
+	max_concurrent_searches: int & >=0 | *0
+	watch_config: bool | *false
 }
 
 #AuthConfig: {
-	type:      string | *"token"
-	token_env: string | *"SEMANGO_TOKENS"
+	type:             string | *"token"
+	token_env:        string | *"SEMANGO_TOKENS"
+	admin_token_env?: string
+}
+
+#AccessLogConfig: {
+	sample_rate:    number & >=0 & <=1 | *0
+	redact_queries: bool | *false
+}
+
+#RateLimitConfig: {
+	rps:   number & >=0 | *0
+	burst: int & >=0 | *0
 }
 
 #UIConfig: {
@@ -76,8 +169,104 @@ package config
 }
 
 #TabularConfig: {
-	max_rows_embedded: int & >=1 | *50000
-	sampling:          string | *"random" | "stratified"
-	min_text_tokens:   int & >=1 | *5
-	delimiter?:        string | *","  // for CSV/TSV; "\t" for TSV
-} 
\ No newline at end of file
+	max_rows_embedded:  int & >=1 | *50000
+	sampling:           string | *"random" | "stratified" | "all"
+	min_text_tokens:    int & >=1 | *5
+	delimiter?:         string | *","  // for CSV/TSV; "\t" for TSV
+	numbers_in_text:    bool | *false
+	redact?:            [...#ColumnRedaction]
+	detect_pii:         *"" | "mask" | "hash" | "drop"
+	hash_salt:          string | *""
+	max_rows_per_sheet: int & >=0 | *0
+	sampling_seed:      int | *0
+}
+
+#ColumnRedaction: {
+	columns: [...string & !=""]
+	action:  *"" | "mask" | "hash" | "drop"
+}
+
+#LinksConfig: {
+	template: string | *""
+	root:     string | *""
+	branch:   string | *""
+}
+
+#MediaConfig: {
+	ffmpeg_path:       string | *""
+	whisper_path:      string | *""
+	whisper_model:     string | *""
+	whisper_language:  string | *""
+	segment_seconds:   int & >=0 | *0
+	keyframes:         bool | *false
+	keyframe_interval: int & >=0 | *0
+}
+
+#SearchConfig: {
+	default_mode: *"" | "hybrid" | "lexical" | "vector"
+	hybrid:       #SearchModeConfig
+	lexical:      #SearchModeConfig
+	vector:       #SearchModeConfig
+	max_text_length: int & >=0 | *0
+	snippet_length: int & >=0 | *0
+	include_full_text: bool | *false
+	quality_prior: number & >=0 & <=1 | *0
+	adaptive_drop: number & >=0 & <=1 | *0
+}
+
+#SearchModeConfig: {
+	top_k: int & >=0 | *0
+}
+
+#GitConfig: {
+	history:        bool | *false
+	diffs:          bool | *false
+	max_commits:    int & >=0 | *0
+	max_diff_bytes: int & >=0 | *0
+}
+
+#LLMConfig: {
+	provider:    *"" | "openai"
+	model:       string | *""
+	base_url:    string | *""
+	api_key_env: string | *""
+	max_context: int & >=0 | *0
+	top_k:       int & >=0 & <=100 | *0
+	max_tokens:  int & >=0 | *0
+}
+
+#ReportConfig: {
+	interval: int & >=0 | *0
+	dir:      string | *""
+}
+
+#SourceConfig: {
+	type:           "local" | "s3" | "gcs" | "sitemap"
+	path:           string | *""
+	bucket:         string | *""
+	prefix:         string | *""
+	region:         string | *""
+	endpoint:       string | *""
+	access_key_env: string | *""
+	secret_key_env: string | *""
+	url:            string | *""
+	max_pages:      int & >=0 | *0
+	include?:       [...string]
+	exclude?:       [...string]
+}
+
+#CollectionConfig: {
+	include?:   [...string]
+	exclude?:   [...string]
+	sources?:   [...#SourceConfig]
+	embedding?: #SpaceEmbeddingConfig
+	index_dir:  string | *""
+	weight:     number & >=0 | *0
+	quota?:     #QuotaConfig
+}
+
+#QuotaConfig: {
+	max_documents: int & >=0 | *0
+	max_bytes:     int & >=0 | *0
+	max_qps:       number & >=0 | *0
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the names of the environment variables that override
+// settings: SEMANGO_ and the setting's key in upper case with dots replaced
+// by underscores, e.g. SEMANGO_SERVER_PORT for server.port.
+const EnvPrefix = "SEMANGO_"
+
+// setting is a configuration value that can be overridden by key.
+type setting struct {
+	index []int // field index path from Config
+	kind  reflect.Kind
+}
+
+// settings maps the dotted key of every overridable setting to its field:
+// strings, booleans, numbers and lists of strings. Maps and lists of
+// sections, such as collections and sources, are left to the file.
+var settings = func() map[string]setting {
+	m := map[string]setting{}
+	var walk func(t reflect.Type, prefix string, index []int)
+	walk = func(t reflect.Type, prefix string, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			key := prefix + name
+			idx := append(append([]int(nil), index...), i)
+			switch f.Type.Kind() {
+			case reflect.Struct:
+				walk(f.Type, key+".", idx)
+			case reflect.Slice:
+				if f.Type.Elem().Kind() == reflect.String {
+					m[key] = setting{index: idx, kind: reflect.Slice}
+				}
+			case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+				m[key] = setting{index: idx, kind: f.Type.Kind()}
+			}
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "", nil)
+	return m
+}()
+
+// Keys returns the keys of the settings that can be overridden with Set or
+// environment variables, sorted.
+func Keys() []string {
+	keys := make([]string, 0, len(settings))
+	for k := range settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// EnvVar returns the environment variable that overrides the setting key.
+func EnvVar(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// Set overrides the setting key, e.g. "server.port", with value. Lists of
+// strings are given comma-separated.
+func (c *Config) Set(key, value string) error {
+	s, ok := settings[key]
+	if !ok {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	f := reflect.ValueOf(c).Elem().FieldByIndex(s.index)
+	switch s.kind {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a boolean", key, value)
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not an integer", key, value)
+		}
+		f.SetInt(n)
+	case reflect.Float64:
+		x, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a number", key, value)
+		}
+		f.SetFloat(x)
+	case reflect.Slice:
+		var list []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				list = append(list, v)
+			}
+		}
+		f.Set(reflect.ValueOf(list))
+	}
+	return nil
+}
+
+// ApplyEnv overrides the settings that have an environment variable set,
+// see EnvVar. Other SEMANGO_ variables, such as SEMANGO_TOKENS, are left
+// alone.
+func (c *Config) ApplyEnv() error {
+	for _, key := range Keys() {
+		if value, ok := os.LookupEnv(EnvVar(key)); ok {
+			if err := c.Set(key, value); err != nil {
+				return fmt.Errorf("%s: %w", EnvVar(key), err)
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyOverrides sets each key=value of overrides, in order, as given with
+// the --set flag.
+func (c *Config) ApplyOverrides(overrides []string) error {
+	for _, o := range overrides {
+		key, value, ok := strings.Cut(o, "=")
+		if !ok {
+			return fmt.Errorf("override %q is not key=value", o)
+		}
+		if err := c.Set(strings.TrimSpace(key), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
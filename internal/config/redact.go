@@ -0,0 +1,99 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secrets in the output of RedactedYAML.
+const redacted = "REDACTED"
+
+// secretWords mark settings and URL query parameters that hold secrets when
+// one of the words of their name, split at underscores and dashes, is among
+// them. Settings ending in _env name a variable rather than hold its value
+// and are shown.
+var secretWords = map[string]bool{
+	"password": true, "passwd": true, "secret": true, "token": true, "apikey": true,
+	"signature": true, "credential": true, "credentials": true,
+}
+
+// RedactedYAML returns c as YAML with secrets replaced by REDACTED: the
+// passwords and secret query parameters of URLs, and the values of
+// settings named like secrets.
+func (c *Config) RedactedYAML() ([]byte, error) {
+	var doc yaml.Node
+	if err := doc.Encode(c); err != nil {
+		return nil, err
+	}
+	redactNode(&doc)
+	return yaml.Marshal(&doc)
+}
+
+func redactNode(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if value.Kind == yaml.ScalarNode && value.Value != "" && isSecret(key.Value) {
+				value.Value = redacted
+				continue
+			}
+			redactNode(value)
+		}
+		return
+	}
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" {
+		n.Value = redactURL(n.Value)
+		return
+	}
+	for _, child := range n.Content {
+		redactNode(child)
+	}
+}
+
+func isSecret(name string) bool {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool { return r == '_' || r == '-' })
+	if len(words) == 0 || words[len(words)-1] == "env" {
+		return false
+	}
+	for i, w := range words {
+		if secretWords[w] || (w == "key" && i > 0 && words[i-1] == "api") {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL hides the password and secret query parameters of s if it is
+// a URL, and returns other strings unchanged.
+func redactURL(s string) string {
+	if !strings.Contains(s, "://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	changed := false
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+		changed = true
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		for name := range q {
+			if isSecret(name) || strings.EqualFold(name, "key") || strings.EqualFold(name, "sig") {
+				q.Set(name, redacted)
+				changed = true
+			}
+		}
+		if changed {
+			u.RawQuery = q.Encode()
+		}
+	}
+	if !changed {
+		return s
+	}
+	return u.String()
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// reindexSections are the top-level sections that decide what is in the
+// indexes; changing them only takes effect after re-indexing.
+var reindexSections = map[string]bool{
+	"embedding": true, "lexical": true, "vector": true, "vector_store": true, "files": true, "sources": true,
+	"collections": true, "tabular": true, "media": true, "git": true,
+}
+
+// CheckReload reports whether a running server configured with c can
+// switch to next without a restart. Only hybrid, reranker,
+// server.rate_limit and log_level may differ; the error for any other
+// change names the sections and says whether they need re-indexing or a
+// restart.
+func (c *Config) CheckReload(next *Config) error {
+	a, b := *c, *next
+	a.Hybrid, b.Hybrid = HybridConfig{}, HybridConfig{}
+	a.Reranker, b.Reranker = RerankerConfig{}, RerankerConfig{}
+	a.Server.RateLimit, b.Server.RateLimit = RateLimitConfig{}, RateLimitConfig{}
+	a.LogLevel, b.LogLevel = "", ""
+
+	var reindex, restart []string
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < va.NumField(); i++ {
+		if reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			continue
+		}
+		name := strings.Split(va.Type().Field(i).Tag.Get("yaml"), ",")[0]
+		if reindexSections[name] {
+			reindex = append(reindex, name)
+		} else {
+			restart = append(restart, name)
+		}
+	}
+
+	var msgs []string
+	if len(reindex) > 0 {
+		msgs = append(msgs, fmt.Sprintf("%s changed, which needs re-indexing: run semango index --recreate and restart the server "+
+			"(PUT /api/v1/embedder switches to a model of the same dimension without a restart)", strings.Join(reindex, ", ")))
+	}
+	if len(restart) > 0 {
+		msgs = append(msgs, fmt.Sprintf("%s changed, which needs a restart", strings.Join(restart, ", ")))
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("cannot reload the configuration: %s; only hybrid, reranker, server.rate_limit and log_level are reloaded",
+			strings.Join(msgs, "; "))
+	}
+	return nil
+}
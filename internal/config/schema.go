@@ -3,7 +3,8 @@ package config
 import _ "embed"
 
 // embeddedCueSchema holds the compiled-in CUE schema so that the binary
-// does not rely on an external docs/config.cue file at runtime.
+// does not rely on an external docs/config.cue file at runtime. Load uses it
+// unless it is given a schema file.
 //
 //go:embed config_schema.cue
 var embeddedCueSchema []byte
@@ -0,0 +1,323 @@
+// Package doctor checks that the environment can run a configuration: that
+// the API keys it needs are set, that the ONNX runtime and FAISS are
+// available, that the index and model cache directories are writable, that
+// cached models match their checksums and that the vector indexes were built
+// with the configured models.
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+// Status is the outcome of a Check.
+type Status string
+
+const (
+	OK   Status = "ok"
+	Warn Status = "warn" // works, but with less than the configuration asks for
+	Fail Status = "fail" // indexing or search will fail
+)
+
+// Check is the result of one check.
+type Check struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// Probes of the environment, replaced in tests.
+var (
+	checkONNXRuntime = ingest.CheckONNXRuntime
+	faissSupported   = storage.FaissSupported
+)
+
+// Run checks the environment for cfg, including its collections.
+func Run(cfg *config.Config) []Check {
+	var checks []Check
+	checks = append(checks, apiKeys(cfg)...)
+	checks = append(checks, onnxRuntime(cfg), faiss())
+	for _, dir := range indexDirs(cfg) {
+		checks = append(checks, writable("index directory", dir))
+	}
+	checks = append(checks, indexModels(cfg)...)
+	if usesLocalModels(cfg) {
+		dir := cfg.Embedding.ModelCacheDir
+		if dir == "" {
+			dir = ingest.DefaultModelCacheDir()
+		}
+		checks = append(checks, writable("model cache", dir))
+		checks = append(checks, cachedModels(cfg, dir)...)
+	}
+	return checks
+}
+
+// Failed reports whether any check failed.
+func Failed(checks []Check) bool {
+	for _, c := range checks {
+		if c.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+// embeddingConfigs returns the embedding settings cfg uses: the default
+// model, vector spaces, language models and those of collections.
+func embeddingConfigs(cfg *config.Config) []config.EmbeddingConfig {
+	ecs := []config.EmbeddingConfig{cfg.Embedding}
+	for name := range cfg.Embedding.Spaces {
+		ec, _ := cfg.Embedding.ForSpace(name)
+		ecs = append(ecs, ec)
+	}
+	for lang := range cfg.Embedding.Languages {
+		ec, _ := cfg.Embedding.ForLanguage(lang)
+		ecs = append(ecs, ec)
+	}
+	for _, name := range collectionNames(cfg) {
+		coll, _ := cfg.ForCollection(name)
+		ecs = append(ecs, coll.Embedding)
+	}
+	return ecs
+}
+
+func collectionNames(cfg *config.Config) []string {
+	names := make([]string, 0, len(cfg.Collections))
+	for name := range cfg.Collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func usesLocalModels(cfg *config.Config) bool {
+	for _, ec := range embeddingConfigs(cfg) {
+		if ec.Provider == "local" {
+			return true
+		}
+	}
+	return false
+}
+
+// apiKeys checks the environment variables holding the keys of the
+// providers and services cfg uses.
+func apiKeys(cfg *config.Config) []Check {
+	var checks []Check
+	need := func(name, env string, status Status, why string) {
+		if os.Getenv(env) != "" {
+			checks = append(checks, Check{Name: name, Status: OK, Detail: env + " is set"})
+			return
+		}
+		checks = append(checks, Check{Name: name, Status: status, Detail: env + " is not set; " + why})
+	}
+
+	for _, ec := range embeddingConfigs(cfg) {
+		if ec.Provider == "openai" || ec.Provider == "" {
+			need("OpenAI embeddings", "OPENAI_API_KEY", Fail, "embedding with "+ec.Model+" will fail")
+			break
+		}
+	}
+	if cfg.LLM.Model != "" {
+		env := cfg.LLM.APIKeyEnv
+		if env == "" {
+			env = "OPENAI_API_KEY"
+		}
+		need("LLM", env, Fail, "answers and LLM query expansion are disabled")
+	}
+	if cfg.Server.Auth.Type != "none" && cfg.Server.Auth.TokenEnv != "" {
+		need("API tokens", cfg.Server.Auth.TokenEnv, Warn, "the server accepts requests without a token")
+	}
+	for _, sc := range cfg.Sources {
+		if sc.Type != "s3" && sc.Type != "gcs" {
+			continue
+		}
+		accessEnv, secretEnv := "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"
+		if sc.Type == "gcs" {
+			accessEnv, secretEnv = "GCS_HMAC_ACCESS_KEY", "GCS_HMAC_SECRET"
+		}
+		if sc.AccessKeyEnv != "" {
+			accessEnv = sc.AccessKeyEnv
+		}
+		if sc.SecretKeyEnv != "" {
+			secretEnv = sc.SecretKeyEnv
+		}
+		name := fmt.Sprintf("%s://%s credentials", strings.Replace(sc.Type, "gcs", "gs", 1), sc.Bucket)
+		if os.Getenv(accessEnv) == "" || os.Getenv(secretEnv) == "" {
+			checks = append(checks, Check{Name: name, Status: Warn,
+				Detail: fmt.Sprintf("%s or %s is not set; requests are sent anonymously", accessEnv, secretEnv)})
+		} else {
+			checks = append(checks, Check{Name: name, Status: OK, Detail: accessEnv + " and " + secretEnv + " are set"})
+		}
+	}
+	return checks
+}
+
+// onnxRuntime checks that the ONNX runtime loads when a local model is in
+// use.
+func onnxRuntime(cfg *config.Config) Check {
+	c := Check{Name: "ONNX runtime"}
+	if !usesLocalModels(cfg) {
+		c.Status, c.Detail = OK, "not needed: no local embedding model is configured"
+		return c
+	}
+	if err := checkONNXRuntime(); err != nil {
+		c.Status, c.Detail = Fail, err.Error()+"; install ONNX Runtime and make its shared library loadable"
+		return c
+	}
+	c.Status, c.Detail = OK, "loaded"
+	return c
+}
+
+func faiss() Check {
+	if faissSupported {
+		return Check{Name: "FAISS", Status: OK, Detail: "built in"}
+	}
+	return Check{Name: "FAISS", Status: Warn,
+		Detail: "this binary was built without CGO on linux/amd64; vectors are kept in the pure-Go flat index, which scans every vector per search"}
+}
+
+// indexDirs returns the directories of the indexes of cfg and its
+// collections, without duplicates.
+func indexDirs(cfg *config.Config) []string {
+	seen := map[string]bool{}
+	var dirs []string
+	add := func(c *config.Config) {
+		for _, p := range []string{c.Lexical.IndexPath, c.VectorIndexPath()} {
+			if p == "" {
+				continue
+			}
+			// The lexical index is a directory, the vector index a file; both
+			// are created inside their parent.
+			dir := filepath.Dir(filepath.Clean(p))
+			if !seen[dir] {
+				seen[dir] = true
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	add(cfg)
+	for _, name := range collectionNames(cfg) {
+		coll, _ := cfg.ForCollection(name)
+		add(coll)
+	}
+	return dirs
+}
+
+// indexModels checks that the vector indexes of cfg and its collections
+// were built with the configured models, as far as their fingerprints tell;
+// indexes without one are not checked.
+func indexModels(cfg *config.Config) []Check {
+	var checks []Check
+	check := func(c *config.Config) {
+		spaces := []string{""}
+		for name := range c.Embedding.Spaces {
+			spaces = append(spaces, name)
+		}
+		sort.Strings(spaces[1:])
+		for _, space := range spaces {
+			path := storage.SpaceIndexPath(c.VectorIndexPath(), space)
+			stored, ok, err := storage.ReadIndexModel(path)
+			if !ok && err == nil {
+				continue
+			}
+			check := Check{Name: "index model " + path}
+			ec := c.Embedding
+			if space != "" {
+				ec, _ = ec.ForSpace(space)
+			}
+			provider, model := ec.Identity()
+			switch {
+			case err != nil:
+				check.Status, check.Detail = Fail, err.Error()
+			case stored.Provider != provider || stored.Model != model || ec.Dimensions > 0 && stored.Dimension != ec.Dimensions:
+				check.Status = Fail
+				check.Detail = fmt.Sprintf("built with %s, the configuration uses %s/%s; run semango index --recreate", stored, provider, model)
+			default:
+				check.Status, check.Detail = OK, "built with "+stored.String()
+			}
+			checks = append(checks, check)
+		}
+	}
+	check(cfg)
+	for _, name := range collectionNames(cfg) {
+		coll, _ := cfg.ForCollection(name)
+		check(coll)
+	}
+	return checks
+}
+
+// cachedModels verifies the SHA-256 checksums of the local models cfg uses
+// that are in the model cache dir. Loading a model only compares file
+// sizes, so this is where silent corruption shows up.
+func cachedModels(cfg *config.Config, dir string) []Check {
+	var checks []Check
+	seen := map[string]bool{}
+	for _, ec := range embeddingConfigs(cfg) {
+		model := ec.LocalModelPath
+		if ec.Provider != "local" || model == "" || seen[model] {
+			continue
+		}
+		seen[model] = true
+		err := ingest.VerifyCachedModel(dir, model)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // not downloaded yet, or a model directory outside the cache
+		}
+		c := Check{Name: "cached model " + model}
+		switch {
+		case errors.Is(err, ingest.ErrModelNotVerified):
+			c.Status, c.Detail = Warn, err.Error()
+		case err != nil:
+			c.Status = Fail
+			c.Detail = fmt.Sprintf("%v; delete %s to download it again", err, filepath.Join(dir, ingest.ModelCacheDirName(model)))
+		default:
+			c.Status, c.Detail = OK, "checksums match"
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// writable checks that dir takes new files or, if it does not exist yet,
+// that its nearest existing parent does, so that it can be created. Nothing
+// is left behind.
+func writable(name, dir string) Check {
+	c := Check{Name: name + " " + dir}
+	existing := filepath.Clean(dir)
+	for {
+		info, err := os.Stat(existing)
+		if err == nil {
+			if !info.IsDir() {
+				c.Status, c.Detail = Fail, existing+" is not a directory"
+				return c
+			}
+			break
+		}
+		parent := filepath.Dir(existing)
+		if !os.IsNotExist(err) || parent == existing {
+			c.Status, c.Detail = Fail, err.Error()
+			return c
+		}
+		existing = parent
+	}
+	f, err := os.CreateTemp(existing, ".semango-doctor-*")
+	if err != nil {
+		c.Status, c.Detail = Fail, err.Error()
+		return c
+	}
+	f.Close()
+	os.Remove(f.Name())
+	c.Status, c.Detail = OK, "writable"
+	if existing != filepath.Clean(dir) {
+		c.Detail = "does not exist yet; can be created in " + existing
+	}
+	return c
+}
@@ -0,0 +1,126 @@
+package doctor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+func statuses(checks []Check) map[string]Status {
+	m := map[string]Status{}
+	for _, c := range checks {
+		m[c.Name] = c.Status
+	}
+	return m
+}
+
+func TestRun(t *testing.T) {
+	oldONNX, oldFaiss := checkONNXRuntime, faissSupported
+	t.Cleanup(func() { checkONNXRuntime, faissSupported = oldONNX, oldFaiss })
+	checkONNXRuntime = func() error { return errors.New("libonnxruntime.so: cannot open shared object file") }
+	faissSupported = false
+
+	dir := t.TempDir()
+	cfg := config.GetDefaultConfig()
+	cfg.Embedding.Provider = "local"
+	cfg.Embedding.ModelCacheDir = filepath.Join(dir, "models")
+	cfg.Lexical.IndexPath = filepath.Join(dir, "data", "bleve")
+	cfg.Vector.IndexPath = filepath.Join(dir, "data", "faiss.index")
+	cfg.Embedding.Spaces = map[string]config.SpaceEmbeddingConfig{"remote": {Provider: "openai", Model: "text-embedding-3-small"}}
+	cfg.Server.Auth = config.AuthConfig{Type: "token", TokenEnv: "DOCTOR_TEST_TOKENS"}
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("DOCTOR_TEST_TOKENS", "abc")
+
+	checks := Run(cfg)
+	got := statuses(checks)
+	want := map[string]Status{
+		"OpenAI embeddings": Fail,
+		"API tokens":        OK,
+		"ONNX runtime":      Fail,
+		"FAISS":             Warn,
+		"index directory " + filepath.Join(dir, "data"): OK,
+		"model cache " + cfg.Embedding.ModelCacheDir:    OK,
+	}
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("%s: %q, want %q (checks: %+v)", name, got[name], status, checks)
+		}
+	}
+	if !Failed(checks) {
+		t.Error("Failed reported no failure")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "data")); !os.IsNotExist(err) {
+		t.Error("the check created the index directory")
+	}
+}
+
+func TestIndexModels(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.GetDefaultConfig()
+	cfg.Embedding.Provider = "openai"
+	cfg.Embedding.Model = "text-embedding-3-small"
+	cfg.Vector.IndexPath = filepath.Join(dir, "faiss.index")
+	cfg.Embedding.Spaces = map[string]config.SpaceEmbeddingConfig{"large": {Model: "text-embedding-3-large"}}
+	if checks := indexModels(cfg); len(checks) != 0 {
+		t.Errorf("indexes without fingerprints were checked: %+v", checks)
+	}
+
+	space := storage.SpaceIndexPath(cfg.Vector.IndexPath, "large")
+	storage.WriteIndexModel(cfg.Vector.IndexPath, storage.IndexModel{Provider: "openai", Model: "text-embedding-3-small", Dimension: 1536})
+	storage.WriteIndexModel(space, storage.IndexModel{Provider: "local", Model: "all-MiniLM-L6-v2", Dimension: 384})
+	got := statuses(indexModels(cfg))
+	if got["index model "+cfg.Vector.IndexPath] != OK || got["index model "+space] != Fail {
+		t.Errorf("checks = %v", got)
+	}
+}
+
+func TestWritable(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if c := writable("dir", dir); c.Status != OK || c.Detail != "writable" {
+		t.Errorf("existing directory: %+v", c)
+	}
+	if c := writable("dir", filepath.Join(file, "sub")); c.Status != Fail {
+		t.Errorf("directory below a file: %+v", c)
+	}
+	if os.Getuid() != 0 {
+		ro := filepath.Join(dir, "ro")
+		if err := os.Mkdir(ro, 0o555); err != nil {
+			t.Fatal(err)
+		}
+		if c := writable("dir", filepath.Join(ro, "index")); c.Status != Fail {
+			t.Errorf("read-only parent: %+v", c)
+		}
+	}
+}
+
+func TestCachedModels(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.GetDefaultConfig()
+	cfg.Embedding.Provider = "local"
+	cfg.Embedding.LocalModelPath = "all-MiniLM-L6-v2-onnx"
+	if checks := cachedModels(cfg, dir); len(checks) != 0 {
+		t.Errorf("a model not downloaded yet was checked: %+v", checks)
+	}
+
+	model := filepath.Join(dir, ingest.ModelCacheDirName(cfg.Embedding.LocalModelPath))
+	os.MkdirAll(model, 0755)
+	os.WriteFile(filepath.Join(model, "model.onnx"), []byte("onnx"), 0644)
+	os.WriteFile(filepath.Join(model, "tokenizer.json"), []byte("{}"), 0644)
+	name := "cached model " + cfg.Embedding.LocalModelPath
+	if got := statuses(cachedModels(cfg, dir)); got[name] != Warn {
+		t.Errorf("a cache without manifest: %v", got)
+	}
+	os.WriteFile(filepath.Join(model, ".semango-model.json"), []byte(`{"files":{"model.onnx":{"size":4,"sha256":"00"},"tokenizer.json":{"size":2,"sha256":"00"}}}`), 0644)
+	if got := statuses(cachedModels(cfg, dir)); got[name] != Fail {
+		t.Errorf("a checksum mismatch: %v", got)
+	}
+}
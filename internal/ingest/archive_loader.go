@@ -0,0 +1,315 @@
+package ingest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+)
+
+// ArchiveSeparator joins an archive's path and the path of a file inside it,
+// e.g. "docs.zip!/guide/readme.md".
+const ArchiveSeparator = "!/"
+
+// ArchiveLimits bound the work done unpacking a single archive. Zero values
+// select the defaults.
+type ArchiveLimits struct {
+	MaxEntryBytes int64 // Largest file extracted; bigger entries are skipped
+	MaxTotalBytes int64 // Total bytes extracted from an archive, nested ones included
+	MaxFiles      int   // Number of entries extracted
+	MaxDepth      int   // Nesting depth of archives inside archives
+}
+
+func (l ArchiveLimits) withDefaults() ArchiveLimits {
+	if l.MaxEntryBytes <= 0 {
+		l.MaxEntryBytes = 50 << 20
+	}
+	if l.MaxTotalBytes <= 0 {
+		l.MaxTotalBytes = 500 << 20
+	}
+	if l.MaxFiles <= 0 {
+		l.MaxFiles = 10000
+	}
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = 3
+	}
+	return l
+}
+
+// ArchiveLoader unpacks .zip, .tar, .tar.gz/.tgz and single-file .gz archives
+// and routes every contained file to the loader registered for its
+// extension. Representations keep the loader's chunking and metadata, with
+// paths of the form "archive.zip!/inner/path". Files without a loader are
+// skipped, and archives inside archives are opened up to MaxDepth levels.
+type ArchiveLoader struct {
+	resolve func(ext string) Loader
+	limits  ArchiveLimits
+}
+
+// NewArchiveLoader returns an ArchiveLoader that looks up loaders for
+// contained files through resolve, usually the pipeline's loader registry.
+func NewArchiveLoader(resolve func(ext string) Loader, limits ArchiveLimits) *ArchiveLoader {
+	return &ArchiveLoader{resolve: resolve, limits: limits.withDefaults()}
+}
+
+func (al *ArchiveLoader) Extensions() []string {
+	return []string{".zip", ".tar", ".tgz", ".gz"}
+}
+
+// archiveBudget tracks the limits shared by an archive and its nested archives.
+type archiveBudget struct {
+	bytes int64
+	files int
+}
+
+// errArchiveBudget stops extraction once an archive-wide limit is reached.
+var errArchiveBudget = errors.New("archive limit reached")
+
+func (al *ArchiveLoader) Load(ctx context.Context, relPath string, absPath string) ([]Representation, error) {
+	slog.Info("Loading archive file", "relative_path", relPath, "absolute_path", absPath)
+
+	tmpDir, err := os.MkdirTemp("", "semango-archive-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	budget := &archiveBudget{}
+	reps, err := al.load(ctx, relPath, absPath, tmpDir, 1, budget)
+	if err == errArchiveBudget {
+		slog.Warn("Archive exceeds limits, indexed partially", "path", relPath,
+			"files", budget.files, "bytes", budget.bytes)
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("archive %s: %w", relPath, err)
+	}
+	for i := range reps {
+		if reps[i].Meta == nil {
+			reps[i].Meta = map[string]string{}
+		}
+		reps[i].Meta["archive"] = relPath
+	}
+	slog.Debug("Created", "files", budget.files, "chunks", len(reps), "relPath", relPath)
+	return reps, nil
+}
+
+// load walks one archive, which may itself be an entry of an outer archive.
+func (al *ArchiveLoader) load(ctx context.Context, relPath, absPath, tmpDir string, depth int, budget *archiveBudget) ([]Representation, error) {
+	var reps []Representation
+	visit := func(name string, size int64, open func() (io.ReadCloser, error)) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name = cleanArchivePath(name)
+		if name == "" {
+			return nil
+		}
+		innerRel := relPath + ArchiveSeparator + name
+		ext := path.Ext(name)
+		nested := isArchiveName(name)
+		var loader Loader
+		if !nested {
+			if loader = al.resolve(ext); loader == nil {
+				return nil
+			}
+		} else if depth >= al.limits.MaxDepth {
+			slog.Warn("Skipping nested archive beyond max depth", "path", innerRel)
+			return nil
+		}
+		if size > al.limits.MaxEntryBytes {
+			slog.Warn("Skipping archive entry larger than limit", "path", innerRel, "size", size)
+			return nil
+		}
+		if budget.files >= al.limits.MaxFiles {
+			return errArchiveBudget
+		}
+
+		tmpPath, err := al.extract(open, tmpDir, ext, budget)
+		if err != nil {
+			if err == errEntryTooLarge {
+				slog.Warn("Skipping archive entry larger than limit", "path", innerRel)
+				return nil
+			}
+			return err
+		}
+		defer os.Remove(tmpPath)
+		budget.files++
+
+		var got []Representation
+		if nested {
+			got, err = al.load(ctx, innerRel, tmpPath, tmpDir, depth+1, budget)
+		} else {
+			got, err = loader.Load(ctx, innerRel, tmpPath)
+		}
+		if err != nil {
+			if err == errArchiveBudget || ctx.Err() != nil {
+				reps = append(reps, got...)
+				return err
+			}
+			// One unreadable file should not drop the rest of the archive.
+			slog.Warn("Failed to load archive entry", "path", innerRel, "error", err)
+			return nil
+		}
+		reps = append(reps, got...)
+		return nil
+	}
+
+	// Nested archives are extracted under temporary names, so the type is
+	// taken from the logical path, which keeps suffixes such as ".tar.gz".
+	lower := strings.ToLower(relPath)
+	var err error
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		err = walkZip(absPath, visit)
+	case strings.HasSuffix(lower, ".tar"):
+		err = walkTar(absPath, false, visit)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		err = walkTar(absPath, true, visit)
+	case strings.HasSuffix(lower, ".gz"):
+		err = walkGzip(absPath, path.Base(strings.TrimSuffix(relPath, path.Ext(relPath))), visit)
+	default:
+		err = fmt.Errorf("unsupported archive type")
+	}
+	return reps, err
+}
+
+var errEntryTooLarge = errors.New("archive entry too large")
+
+// extract copies one entry into a temporary file with the entry's extension,
+// since some loaders look at the file name. The actual number of bytes read
+// is checked, so entries lying about their size cannot exceed the limits.
+func (al *ArchiveLoader) extract(open func() (io.ReadCloser, error), tmpDir, ext string, budget *archiveBudget) (string, error) {
+	rc, err := open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	f, err := os.CreateTemp(tmpDir, "entry-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	limit := al.limits.MaxEntryBytes
+	if remaining := al.limits.MaxTotalBytes - budget.bytes; remaining < limit {
+		limit = remaining
+	}
+	n, err := io.Copy(f, io.LimitReader(rc, limit+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > limit {
+		if limit < al.limits.MaxEntryBytes {
+			err = errArchiveBudget
+		} else {
+			err = errEntryTooLarge
+		}
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	budget.bytes += n
+	return f.Name(), nil
+}
+
+type archiveVisitor func(name string, size int64, open func() (io.ReadCloser, error)) error
+
+func walkZip(absPath string, visit archiveVisitor) error {
+	zr, err := zip.OpenReader(absPath)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if err := visit(f.Name, int64(f.UncompressedSize64), f.Open); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkTar(absPath string, gzipped bool, visit archiveVisitor) error {
+	file, err := os.Open(absPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if gzipped {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue // directories, links and devices carry no content
+		}
+		open := func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }
+		if err := visit(hdr.Name, hdr.Size, open); err != nil {
+			return err
+		}
+	}
+}
+
+// walkGzip treats a plain .gz file as an archive holding one file, named
+// after the gzip header or, failing that, the archive without ".gz".
+func walkGzip(absPath, fallbackName string, visit archiveVisitor) error {
+	file, err := os.Open(absPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	name := gz.Name
+	if name == "" {
+		name = fallbackName
+	}
+	return visit(name, -1, func() (io.ReadCloser, error) { return io.NopCloser(gz), nil })
+}
+
+// cleanArchivePath normalises an entry name and rejects names escaping the
+// archive root, so the combined path stays inside "archive!/".
+func cleanArchivePath(name string) string {
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+	name = strings.TrimPrefix(name, "/")
+	if name == "." || name == "" {
+		return ""
+	}
+	return name
+}
+
+func isArchiveName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tgz", ".gz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package ingest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func tarGz(t *testing.T, files map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, body := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func newTestArchiveLoader(limits ArchiveLimits) *ArchiveLoader {
+	text := NewTextLoader(1000, 0)
+	return NewArchiveLoader(func(ext string) Loader {
+		if ext == ".txt" {
+			return text
+		}
+		return nil
+	}, limits)
+}
+
+func archivePaths(reps []Representation) []string {
+	var paths []string
+	for _, r := range reps {
+		paths = append(paths, r.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestArchiveLoader_NestedRouting(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bundle.zip")
+	writeZip(t, path, map[string]string{
+		"docs/readme.txt":  "archived readme",
+		"../../escape.txt": "entry names cannot leave the archive",
+		"image.bin":        "no loader for this",
+		"inner/logs.tar.gz": tarGz(t, map[string]string{
+			"app/log.txt": "nested tarball entry",
+		}),
+	})
+
+	reps, err := newTestArchiveLoader(ArchiveLimits{}).Load(context.Background(), "data/bundle.zip", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"data/bundle.zip!/docs/readme.txt",
+		"data/bundle.zip!/escape.txt",
+		"data/bundle.zip!/inner/logs.tar.gz!/app/log.txt",
+	}
+	if got := archivePaths(reps); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected paths: %v", got)
+	}
+	for _, r := range reps {
+		if r.Meta["archive"] != "data/bundle.zip" || r.Meta["path"] != r.Path {
+			t.Errorf("unexpected meta for %s: %v", r.Path, r.Meta)
+		}
+		if r.ID != ChunkID(r.Path, "text", 0) {
+			t.Errorf("chunk IDs should derive from the inner path, got %s", r.ID)
+		}
+	}
+
+	// With depth 1 the nested tarball is not opened.
+	reps, err = newTestArchiveLoader(ArchiveLimits{MaxDepth: 1}).Load(context.Background(), "data/bundle.zip", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reps) != 2 {
+		t.Errorf("expected nested archive to be skipped, got %v", archivePaths(reps))
+	}
+}
+
+func TestArchiveLoader_Limits(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "big.tar.gz")
+	content := tarGz(t, map[string]string{
+		"a.txt": strings.Repeat("a ", 100),
+		"b.txt": "small file",
+	})
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reps, err := newTestArchiveLoader(ArchiveLimits{MaxEntryBytes: 50}).Load(context.Background(), "big.tar.gz", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := archivePaths(reps); len(got) != 1 || got[0] != "big.tar.gz!/b.txt" {
+		t.Errorf("expected oversized entry to be skipped, got %v", got)
+	}
+
+	reps, err = newTestArchiveLoader(ArchiveLimits{MaxFiles: 1}).Load(context.Background(), "big.tar.gz", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reps) != 1 {
+		t.Errorf("expected extraction to stop after one file, got %v", archivePaths(reps))
+	}
+}
@@ -1,5 +1,7 @@
 package ingest
 
+import "strings"
+
 // Chunker defines the interface for text chunking strategies.
 type Chunker interface {
 	Chunk(text string) []string
@@ -10,4 +12,89 @@ type SimpleChunker struct{}
 
 func (s *SimpleChunker) Chunk(text string) []string {
 	return []string{text}
-} 
\ No newline at end of file
+}
+
+// Span is a chunk of text together with its byte offset in the source text.
+type Span struct {
+	Text  string
+	Start int
+}
+
+// FixedChunker splits text into chunks of roughly Size bytes, cutting on word
+// boundaries and repeating Overlap bytes between consecutive chunks.
+type FixedChunker struct {
+	Size    int
+	Overlap int
+}
+
+func (fc *FixedChunker) Chunk(text string) []string {
+	spans := fc.Spans(text)
+	out := make([]string, len(spans))
+	for i, s := range spans {
+		out[i] = s.Text
+	}
+	return out
+}
+
+// Spans is like Chunk but also reports where each chunk starts.
+func (fc *FixedChunker) Spans(text string) []Span {
+	size := fc.Size
+	ov := fc.Overlap
+	if size <= 0 || len(text) <= size {
+		return []Span{{Text: text, Start: 0}}
+	}
+
+	var spans []Span
+	start := 0
+	for start < len(text) {
+		end := start + size
+		if end > len(text) {
+			end = len(text)
+		}
+
+		// Adjust end to word boundary (don't cut words in half)
+		if end < len(text) {
+			// Look backwards for a word boundary (space, newline, punctuation)
+			for end > start && !isWordBoundary(text[end]) {
+				end--
+			}
+			// If we couldn't find a word boundary, use the original end
+			if end == start {
+				end = start + size
+			}
+		}
+
+		spans = append(spans, Span{Text: text[start:end], Start: start})
+
+		if end == len(text) {
+			break
+		}
+
+		// Calculate next start position with overlap, respecting word boundaries
+		nextStart := end - ov
+		if nextStart <= start {
+			nextStart = start + 1 // Ensure progress
+		}
+
+		// Adjust nextStart to word boundary
+		for nextStart < len(text) && !isWordBoundary(text[nextStart]) {
+			nextStart++
+		}
+
+		start = nextStart
+	}
+	return spans
+}
+
+// isWordBoundary checks if a character is a word boundary
+func isWordBoundary(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '.' || c == ',' || c == ';' || c == '!' || c == '?'
+}
+
+// lineAt returns the 1-based line number of byte offset off in text.
+func lineAt(text string, off int) int {
+	if off > len(text) {
+		off = len(text)
+	}
+	return strings.Count(text[:off], "\n") + 1
+}
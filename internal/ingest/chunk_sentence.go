@@ -0,0 +1,269 @@
+package ingest
+
+import (
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chunking strategies, as named in files.chunking.
+const (
+	ChunkFixed    = "fixed"
+	ChunkSentence = "sentence"
+	ChunkSemantic = "semantic"
+)
+
+// DefaultSemanticPercentile is the percentile of the distances between
+// neighbouring sentences above which semantic chunking starts a new chunk.
+const DefaultSemanticPercentile = 95
+
+// SpanChunker splits text into spans, as the chunkers of this package do.
+type SpanChunker interface {
+	Spans(text string) []Span
+}
+
+// Chunking selects how the loaders that split text cut their chunks.
+type Chunking struct {
+	Strategy string // ChunkFixed (or ""), ChunkSentence or ChunkSemantic
+	// Percentile is that of SemanticChunker; 0 selects
+	// DefaultSemanticPercentile.
+	Percentile int
+	// Embed embeds sentences for ChunkSemantic. Without it semantic
+	// chunking falls back to sentences.
+	Embed func(texts []string) ([][]float32, error)
+}
+
+// ChunkingSetter is implemented by the loaders that split text, so that
+// files.chunking can replace their fixed-size chunks.
+type ChunkingSetter interface {
+	SetChunking(Chunking)
+}
+
+// chunker returns the chunker of c for chunks of about size bytes.
+func (c Chunking) chunker(size, overlap int) SpanChunker {
+	switch c.Strategy {
+	case ChunkSentence:
+		return &SentenceChunker{Size: size, Overlap: overlap}
+	case ChunkSemantic:
+		return &SemanticChunker{Size: size, Percentile: c.Percentile, Embed: c.Embed}
+	}
+	return &FixedChunker{Size: size, Overlap: overlap}
+}
+
+// abbreviations end with a period that does not end a sentence.
+var abbreviations = []string{"e.g.", "i.e.", "etc.", "vs.", "cf.", "Mr.", "Mrs.", "Ms.", "Dr.", "Prof.", "St.", "No."}
+
+// SentenceSpans splits text into sentences. A sentence ends at ".", "!" or
+// "?", and any closing quotes or brackets, followed by white space and not
+// followed by a lower-case letter; at "。", "！" or "？"; or at a blank line.
+// The white space after a sentence belongs to it, so the spans cover text.
+func SentenceSpans(text string) []Span {
+	var spans []Span
+	start := 0
+	cut := func(end int) {
+		// Take the white space that follows along.
+		for end < len(text) {
+			r, n := utf8.DecodeRuneInString(text[end:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			end += n
+		}
+		if end > start {
+			spans = append(spans, Span{Text: text[start:end], Start: start})
+			start = end
+		}
+	}
+	for i := 0; i < len(text); {
+		r, n := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '。' || r == '！' || r == '？':
+			cut(i + n)
+			i = max(i+n, start)
+			continue
+		case r == '\n' && strings.HasPrefix(strings.TrimLeft(text[i+1:], " \t\r"), "\n"):
+			cut(i + n)
+			i = max(i+n, start)
+			continue
+		case r == '.' || r == '!' || r == '?':
+			end := i + n
+			for end < len(text) && strings.IndexByte(`"')]`, text[end]) >= 0 {
+				end++
+			}
+			if end < len(text) && !isSpaceByte(text[end]) {
+				break
+			}
+			if r == '.' && isAbbreviation(text[start:end]) {
+				break
+			}
+			if next := strings.TrimLeftFunc(text[end:], unicode.IsSpace); next != "" {
+				if nr, _ := utf8.DecodeRuneInString(next); unicode.IsLower(nr) {
+					break
+				}
+			}
+			cut(end)
+			i = max(end, start)
+			continue
+		}
+		i += n
+	}
+	if start < len(text) {
+		spans = append(spans, Span{Text: text[start:], Start: start})
+	}
+	return spans
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isAbbreviation reports whether s ends with an abbreviation or an
+// initial, such as the "J." of "J. Smith".
+func isAbbreviation(s string) bool {
+	for _, a := range abbreviations {
+		if strings.HasSuffix(s, a) && (len(s) == len(a) || !isLetter(s[len(s)-len(a)-1])) {
+			return true
+		}
+	}
+	return len(s) >= 2 && unicode.IsUpper(rune(s[len(s)-2])) && (len(s) == 2 || !isLetter(s[len(s)-3]))
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// SentenceChunker groups whole sentences into chunks of up to Size bytes,
+// repeating the last sentences of a chunk, up to Overlap bytes, at the start
+// of the next. Sentences longer than Size are cut like FixedChunker does.
+type SentenceChunker struct {
+	Size    int
+	Overlap int
+}
+
+// Spans splits text into chunks of sentences.
+func (sc *SentenceChunker) Spans(text string) []Span {
+	if sc.Size <= 0 || len(text) <= sc.Size {
+		return []Span{{Text: text, Start: 0}}
+	}
+	return groupSentences(text, splitLong(SentenceSpans(text), sc.Size), sc.Size, sc.Overlap, nil)
+}
+
+// SemanticChunker groups neighbouring sentences whose embeddings are alike
+// into chunks of up to Size bytes. A new chunk starts where the cosine
+// distance between a sentence and the next is above the Percentile-th
+// percentile of those distances in the text, so each text is cut at its own
+// largest changes of topic. Chunks do not overlap. When Embed is nil or
+// fails, sentences are grouped by size only, like SentenceChunker does.
+type SemanticChunker struct {
+	Size       int
+	Percentile int // 1..100; 0 selects DefaultSemanticPercentile
+	Embed      func(texts []string) ([][]float32, error)
+}
+
+// Spans splits text into chunks of related sentences.
+func (sc *SemanticChunker) Spans(text string) []Span {
+	sentences := SentenceSpans(text)
+	if sc.Size > 0 {
+		sentences = splitLong(sentences, sc.Size)
+	}
+	if len(sentences) < 3 || sc.Embed == nil {
+		return groupSentences(text, sentences, sc.Size, 0, nil)
+	}
+	texts := make([]string, len(sentences))
+	for i, s := range sentences {
+		texts[i] = s.Text
+	}
+	vecs, err := sc.Embed(texts)
+	if err != nil || len(vecs) != len(texts) {
+		slog.Warn("Embedding sentences failed; chunking by sentences", "error", err)
+		return groupSentences(text, sentences, sc.Size, 0, nil)
+	}
+	dist := make([]float64, len(vecs)-1)
+	for i := range dist {
+		dist[i] = 1 - cosine(vecs[i], vecs[i+1])
+	}
+	p := sc.Percentile
+	if p <= 0 || p > 100 {
+		p = DefaultSemanticPercentile
+	}
+	sorted := append([]float64(nil), dist...)
+	sort.Float64s(sorted)
+	cutoff := sorted[(len(sorted)-1)*p/100]
+	breaks := make([]bool, len(sentences))
+	for i, d := range dist {
+		breaks[i+1] = d > cutoff
+	}
+	return groupSentences(text, sentences, sc.Size, 0, breaks)
+}
+
+// splitLong cuts the sentences longer than size with a FixedChunker.
+func splitLong(sentences []Span, size int) []Span {
+	out := make([]Span, 0, len(sentences))
+	fc := &FixedChunker{Size: size}
+	for _, s := range sentences {
+		if len(s.Text) <= size {
+			out = append(out, s)
+			continue
+		}
+		for _, p := range fc.Spans(s.Text) {
+			out = append(out, Span{Text: p.Text, Start: s.Start + p.Start})
+		}
+	}
+	return out
+}
+
+// groupSentences joins consecutive sentences of text into spans of up to
+// size bytes (unlimited when size is 0), starting a new span before
+// sentence i when breaks[i] is set. Each span after the first starts with
+// the sentences ending the previous one that fit in overlap bytes.
+func groupSentences(text string, sentences []Span, size, overlap int, breaks []bool) []Span {
+	if len(sentences) == 0 {
+		return []Span{{Text: text, Start: 0}}
+	}
+	var spans []Span
+	first := 0 // first sentence of the current span
+	emit := func(last int) {
+		start, end := sentences[first].Start, sentences[last].Start+len(sentences[last].Text)
+		spans = append(spans, Span{Text: text[start:end], Start: start})
+	}
+	for i := 1; i < len(sentences); i++ {
+		start := sentences[first].Start
+		end := sentences[i].Start + len(sentences[i].Text)
+		if (size <= 0 || end-start <= size) && (breaks == nil || !breaks[i]) {
+			continue
+		}
+		emit(i - 1)
+		// Back up over the sentences that fit in the overlap, keeping room
+		// for sentence i.
+		next := i
+		for next > first+1 && overlap > 0 {
+			ovStart := sentences[next-1].Start
+			if sentences[i].Start-ovStart > overlap || (size > 0 && end-ovStart > size) {
+				break
+			}
+			next--
+		}
+		first = next
+	}
+	emit(len(sentences) - 1)
+	return spans
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		if i >= len(b) {
+			break
+		}
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,93 @@
+package ingest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func spanTexts(spans []Span) []string {
+	out := make([]string, len(spans))
+	for i, s := range spans {
+		out[i] = s.Text
+	}
+	return out
+}
+
+func TestSentenceSpans(t *testing.T) {
+	text := "Dr. Smith arrived, e.g. by train. He said \"hello.\" Then e.g. lunch was served! Was it good? yes it was.\n\nNew paragraph without a stop\nstill the same. 東京です。次です。"
+	want := []string{
+		"Dr. Smith arrived, e.g. by train. ",
+		"He said \"hello.\" ",
+		"Then e.g. lunch was served! ",
+		"Was it good? yes it was.\n\n",
+		"New paragraph without a stop\nstill the same. ",
+		"東京です。",
+		"次です。",
+	}
+	spans := SentenceSpans(text)
+	if got := spanTexts(spans); !reflect.DeepEqual(got, want) {
+		t.Fatalf("SentenceSpans =\n%q\nwant\n%q", got, want)
+	}
+	for _, s := range spans {
+		if text[s.Start:s.Start+len(s.Text)] != s.Text {
+			t.Errorf("span %q does not start at %d", s.Text, s.Start)
+		}
+	}
+}
+
+func TestSentenceChunker(t *testing.T) {
+	text := "One two three. Four five six. Seven eight nine. Ten eleven twelve."
+	got := spanTexts((&SentenceChunker{Size: 36, Overlap: 18}).Spans(text))
+	want := []string{
+		"One two three. Four five six. ",
+		"Four five six. Seven eight nine. ",
+		"Seven eight nine. Ten eleven twelve.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Spans =\n%q\nwant\n%q", got, want)
+	}
+
+	long := strings.Repeat("word ", 20) + "end."
+	for _, s := range (&SentenceChunker{Size: 30}).Spans(long) {
+		if len(s.Text) > 30 {
+			t.Errorf("chunk of %d bytes is over the size", len(s.Text))
+		}
+	}
+}
+
+func TestSemanticChunker(t *testing.T) {
+	// Sentences about cats and about taxes, embedded by topic.
+	text := "Cats purr. Cats nap. Cats hunt. Taxes are due. Taxes are high. Taxes are complex."
+	embed := func(texts []string) ([][]float32, error) {
+		vecs := make([][]float32, len(texts))
+		for i, s := range texts {
+			if strings.Contains(s, "Cats") {
+				vecs[i] = []float32{1, 0.1 * float32(i)}
+			} else {
+				vecs[i] = []float32{0, 1}
+			}
+		}
+		return vecs, nil
+	}
+	got := spanTexts((&SemanticChunker{Size: 1000, Embed: embed}).Spans(text))
+	want := []string{"Cats purr. Cats nap. Cats hunt. ", "Taxes are due. Taxes are high. Taxes are complex."}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Spans =\n%q\nwant\n%q", got, want)
+	}
+
+	// Without embeddings, sentences are grouped by size.
+	got = spanTexts((&SemanticChunker{Size: 40}).Spans(text))
+	if len(got) != 3 || strings.Join(got, "") != text {
+		t.Errorf("Spans without embeddings = %q", got)
+	}
+}
+
+func TestTextLoaderChunking(t *testing.T) {
+	l := NewTextLoader(25, 0)
+	l.SetChunking(Chunking{Strategy: ChunkSentence})
+	reps := l.Split("a.txt", "A short one. Another sentence here.")
+	if len(reps) != 2 || reps[1].Text != "Another sentence here." || reps[1].Meta["offset"] != "13" {
+		t.Errorf("got %+v", reps)
+	}
+}
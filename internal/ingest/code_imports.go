@@ -0,0 +1,96 @@
+package ingest
+
+import "strings"
+
+// importPrefixes start the import statements of each language, as
+// detectLanguage names it, after leading whitespace.
+var importPrefixes = map[string][]string{
+	"go":         {"import "},
+	"python":     {"import ", "from "},
+	"javascript": {"import "},
+	"typescript": {"import "},
+	"java":       {"import "},
+	"kotlin":     {"import "},
+	"scala":      {"import "},
+	"swift":      {"import "},
+	"rust":       {"use ", "extern crate "},
+	"c":          {"#include"},
+	"cpp":        {"#include"},
+	"csharp":     {"using "},
+	"php":        {"use ", "require", "include"},
+	"ruby":       {"require ", "require_relative "},
+}
+
+// stripImports removes the import statements of code in language, so that
+// chunks of code are about what the code does rather than what it uses.
+// Statements spanning several lines, such as Go import blocks, Python
+// parenthesized imports, JavaScript named imports and Rust use trees, are
+// removed whole. Code in other languages is returned unchanged.
+func stripImports(language, code string) string {
+	prefixes := importPrefixes[language]
+	if len(prefixes) == 0 {
+		return code
+	}
+	lines := strings.SplitAfter(code, "\n")
+	out := make([]string, 0, len(lines))
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if !isImport(language, line, prefixes) {
+			out = append(out, lines[i])
+			continue
+		}
+		// Skip the continuation lines of a statement left open.
+		for end := importEnd(language, line); end != "" && i+1 < len(lines); {
+			i++
+			if strings.Contains(lines[i], end) {
+				break
+			}
+		}
+	}
+	return strings.Join(out, "")
+}
+
+func isImport(language, line string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if !strings.HasPrefix(line, p) {
+			continue
+		}
+		switch language {
+		case "python":
+			// "from x import y", not a line of prose starting with "from".
+			return p == "import " || strings.Contains(line, " import ")
+		case "csharp":
+			// using directives, not using statements or declarations.
+			return strings.HasSuffix(line, ";") && !strings.Contains(line, "(") && !strings.HasPrefix(line, "using var ")
+		case "php":
+			return strings.HasSuffix(line, ";")
+		}
+		return true
+	}
+	return false
+}
+
+// importEnd returns the text that closes the import statement starting
+// with line when it continues on the next lines, or "" when line is the
+// whole statement.
+func importEnd(language, line string) string {
+	switch language {
+	case "go":
+		if strings.HasSuffix(line, "(") {
+			return ")"
+		}
+	case "python":
+		if strings.HasSuffix(line, "(") {
+			return ")"
+		}
+	case "javascript", "typescript":
+		if strings.HasSuffix(line, "{") || (strings.Contains(line, "{") && !strings.Contains(line, "}")) {
+			return "}"
+		}
+	case "rust":
+		if !strings.HasSuffix(line, ";") {
+			return ";"
+		}
+	}
+	return ""
+}
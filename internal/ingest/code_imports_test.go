@@ -0,0 +1,42 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStripImports(t *testing.T) {
+	cases := []struct{ language, code, want string }{
+		{"go", "package main\n\nimport \"fmt\"\nimport (\n\t\"os\"\n\tstr \"strings\"\n)\n\nfunc main() {}\n",
+			"package main\n\n\nfunc main() {}\n"},
+		{"python", "import os\nfrom a import (\n    b,\n    c,\n)\nfrom here we go\nx = 1\n",
+			"from here we go\nx = 1\n"},
+		{"typescript", "import x from 'x';\nimport {\n  a,\n  b,\n} from './ab';\nconst y = 2;\n",
+			"const y = 2;\n"},
+		{"rust", "use std::{\n    fs,\n    io,\n};\nuse std::fmt;\nfn main() {}\n",
+			"fn main() {}\n"},
+		{"csharp", "using System;\nusing (var f = Open()) {}\n", "using (var f = Open()) {}\n"},
+		{"unknown", "import x\n", "import x\n"},
+	}
+	for _, c := range cases {
+		if got := stripImports(c.language, c.code); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.language, got, c.want)
+		}
+	}
+}
+
+func TestCodeLoaderStripImports(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.py")
+	if err := os.WriteFile(path, []byte("import os\nprint(os.getcwd())\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reps, err := NewCodeLoader(true, 0).Load(context.Background(), "main.py", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reps) != 1 || reps[0].Text != "print(os.getcwd())\n" || reps[0].Meta["strip_imports"] != "true" {
+		t.Errorf("got %+v", reps)
+	}
+}
@@ -0,0 +1,347 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileLoader indexes YAML, TOML and INI files as flattened
+// "key.path: value" lines, much like tabular rows are rendered as
+// "column: value", so infrastructure repositories can be searched by
+// configuration keys and values. Lines are packed into chunks that keep
+// top-level sections together where they fit. Files that do not parse, such
+// as templated Helm charts, are indexed as plain text instead.
+type ConfigFileLoader struct {
+	chunkSize int
+}
+
+// NewConfigFileLoader returns a ConfigFileLoader packing up to chunkSize
+// characters of flattened keys into each representation.
+func NewConfigFileLoader(chunkSize int) *ConfigFileLoader {
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	return &ConfigFileLoader{chunkSize: chunkSize}
+}
+
+func (cl *ConfigFileLoader) Extensions() []string {
+	return []string{".yaml", ".yml", ".toml", ".ini", ".cfg"}
+}
+
+// configEntry is one flattened leaf value.
+type configEntry struct {
+	doc     int    // Document index for multi-document YAML
+	section string // Top-level key or INI section
+	key     string // Full key path, e.g. "spec.template.containers[0].image"
+	value   string
+	line    int // 1-based source line, 0 if unknown
+}
+
+func (e configEntry) text() string {
+	if e.value == "" {
+		return e.key + ":"
+	}
+	// Indent continuation lines so multi-line values stay attached to their key.
+	return e.key + ": " + strings.ReplaceAll(e.value, "\n", "\n  ")
+}
+
+func (cl *ConfigFileLoader) Load(ctx context.Context, relPath string, absPath string) ([]Representation, error) {
+	slog.Info("Loading config file", "relative_path", relPath, "absolute_path", absPath)
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		slog.Error("Failed to read file for ConfigFileLoader", "path", absPath, "error", err)
+		return nil, err
+	}
+
+	var (
+		format  string
+		entries []configEntry
+	)
+	switch strings.ToLower(filepath.Ext(relPath)) {
+	case ".yaml", ".yml":
+		format = "yaml"
+		entries, err = flattenYAML(data)
+	case ".toml":
+		format = "toml"
+		entries, err = flattenTOML(data)
+	default:
+		format = "ini"
+		entries, err = flattenINI(data)
+	}
+	if err != nil {
+		slog.Warn("Config file does not parse, indexing as plain text", "path", relPath, "format", format, "error", err)
+		return cl.plainText(relPath, format, string(data)), nil
+	}
+
+	reps := cl.pack(relPath, format, entries)
+	slog.Debug("Created", "keys", len(entries), "chunks", len(reps), "relPath", relPath)
+	return reps, nil
+}
+
+// pack groups entries into chunks of about chunkSize characters. A chunk never
+// spans two YAML documents, and a section starts a new chunk when it would
+// not fit in the current one.
+func (cl *ConfigFileLoader) pack(relPath, format string, entries []configEntry) []Representation {
+	type group struct{ start, end, size int }
+	var groups []group
+	for i, e := range entries {
+		n := len(e.text()) + 1
+		if len(groups) > 0 {
+			last := &groups[len(groups)-1]
+			prev := entries[last.end-1]
+			if prev.doc == e.doc && prev.section == e.section {
+				last.end, last.size = i+1, last.size+n
+				continue
+			}
+		}
+		groups = append(groups, group{start: i, end: i + 1, size: n})
+	}
+
+	var reps []Representation
+	var buf strings.Builder
+	var first *configEntry
+	flush := func() {
+		if buf.Len() == 0 {
+			return
+		}
+		meta := map[string]string{
+			"source":  "ConfigFileLoader",
+			"path":    relPath,
+			"format":  format,
+			"section": first.section,
+		}
+		if first.line > 0 {
+			meta["line"] = strconv.Itoa(first.line)
+		}
+		if format == "yaml" {
+			meta["document"] = strconv.Itoa(first.doc)
+		}
+		reps = append(reps, Representation{
+			ID:       ChunkID(relPath, "text", int64(len(reps))),
+			Path:     relPath,
+			Modality: "text",
+			Text:     strings.TrimRight(buf.String(), "\n"),
+			Meta:     meta,
+		})
+		buf.Reset()
+		first = nil
+	}
+	for _, g := range groups {
+		if first != nil && (first.doc != entries[g.start].doc || buf.Len()+g.size > cl.chunkSize) {
+			flush()
+		}
+		for i := g.start; i < g.end; i++ {
+			line := entries[i].text()
+			if first != nil && buf.Len()+len(line) > cl.chunkSize {
+				flush()
+			}
+			if first == nil {
+				first = &entries[i]
+			}
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+		}
+	}
+	flush()
+	return reps
+}
+
+// plainText is the fallback for files that do not parse.
+func (cl *ConfigFileLoader) plainText(relPath, format, text string) []Representation {
+	chunker := &FixedChunker{Size: cl.chunkSize}
+	var reps []Representation
+	for _, span := range chunker.Spans(text) {
+		if strings.TrimSpace(span.Text) == "" {
+			continue
+		}
+		reps = append(reps, Representation{
+			ID:       ChunkID(relPath, "text", int64(len(reps))),
+			Path:     relPath,
+			Modality: "text",
+			Text:     span.Text,
+			Meta: map[string]string{
+				"source": "ConfigFileLoader",
+				"path":   relPath,
+				"format": format,
+				"offset": strconv.Itoa(span.Start),
+			},
+		})
+	}
+	return reps
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func topSection(key string) string {
+	if i := strings.IndexAny(key, ".["); i > 0 {
+		return key[:i]
+	}
+	return key
+}
+
+// flattenYAML walks every document in file order, following aliases and
+// merge keys.
+func flattenYAML(data []byte) ([]configEntry, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var entries []configEntry
+	for doc := 0; ; doc++ {
+		var n yaml.Node
+		if err := dec.Decode(&n); err != nil {
+			if errors.Is(err, io.EOF) {
+				return entries, nil
+			}
+			return nil, err
+		}
+		walkYAML(&n, "", doc, &entries, 0)
+	}
+}
+
+// maxYAMLDepth guards against alias cycles.
+const maxYAMLDepth = 64
+
+func walkYAML(n *yaml.Node, prefix string, doc int, out *[]configEntry, depth int) {
+	if depth > maxYAMLDepth {
+		return
+	}
+	add := func(value string) {
+		*out = append(*out, configEntry{doc: doc, section: topSection(prefix), key: prefix, value: value, line: n.Line})
+	}
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			walkYAML(c, prefix, doc, out, depth+1)
+		}
+	case yaml.AliasNode:
+		walkYAML(n.Alias, prefix, doc, out, depth+1)
+	case yaml.MappingNode:
+		if len(n.Content) == 0 && prefix != "" {
+			add("{}")
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if k.Value == "<<" { // merge key: inline the merged mapping(s)
+				walkYAML(v, prefix, doc, out, depth+1)
+				continue
+			}
+			walkYAML(v, joinKey(prefix, k.Value), doc, out, depth+1)
+		}
+	case yaml.SequenceNode:
+		if len(n.Content) == 0 {
+			add("[]")
+		}
+		for i, c := range n.Content {
+			walkYAML(c, fmt.Sprintf("%s[%d]", prefix, i), doc, out, depth+1)
+		}
+	case yaml.ScalarNode:
+		if n.Tag == "!!null" {
+			add("")
+			return
+		}
+		add(strings.TrimRight(n.Value, "\n")) // block scalars keep their final newline
+	}
+}
+
+// flattenTOML flattens a TOML document. Keys are sorted since decoding into
+// a map loses file order.
+func flattenTOML(data []byte) ([]configEntry, error) {
+	var doc map[string]interface{}
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var entries []configEntry
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		add := func(value string) {
+			entries = append(entries, configEntry{section: topSection(prefix), key: prefix, value: value})
+		}
+		switch t := v.(type) {
+		case map[string]interface{}:
+			if len(t) == 0 && prefix != "" {
+				add("{}")
+			}
+			keys := make([]string, 0, len(t))
+			for k := range t {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(joinKey(prefix, k), t[k])
+			}
+		case []interface{}:
+			if len(t) == 0 {
+				add("[]")
+			}
+			for i, item := range t {
+				walk(fmt.Sprintf("%s[%d]", prefix, i), item)
+			}
+		case time.Time:
+			add(t.Format(time.RFC3339))
+		default:
+			add(fmt.Sprint(t))
+		}
+	}
+	walk("", doc)
+	return entries, nil
+}
+
+// flattenINI parses INI files: "[section]" headers, "key = value" or
+// "key: value" pairs, ";" and "#" comments and indented continuation lines.
+func flattenINI(data []byte) ([]configEntry, error) {
+	var entries []configEntry
+	section := ""
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		raw := sc.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated section header", lineNo)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if (raw[0] == ' ' || raw[0] == '\t') && len(entries) > 0 && entries[len(entries)-1].section == section {
+			last := &entries[len(entries)-1]
+			last.value = strings.TrimSpace(last.value + "\n" + line)
+			continue
+		}
+		key, value := line, ""
+		if i := strings.IndexAny(line, "=:"); i >= 0 {
+			key, value = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		}
+		if key == "" {
+			return nil, fmt.Errorf("line %d: missing key", lineNo)
+		}
+		entries = append(entries, configEntry{
+			section: section,
+			key:     joinKey(section, key),
+			value:   strings.Trim(value, `"`),
+			line:    lineNo,
+		})
+	}
+	return entries, sc.Err()
+}
@@ -0,0 +1,105 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func loadConfigFile(t *testing.T, name, content string, chunkSize int) []Representation {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reps, err := NewConfigFileLoader(chunkSize).Load(context.Background(), name, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reps
+}
+
+func TestConfigFileLoader_YAML(t *testing.T) {
+	yml := `defaults: &defaults
+  replicas: 2
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  <<: {replicas: 3}
+  template:
+    containers:
+      - image: nginx:1.27
+        args: []
+    notes: |
+      first line
+      second line
+`
+	reps := loadConfigFile(t, "deploy.yaml", yml, 1000)
+	if len(reps) != 2 {
+		t.Fatalf("expected one chunk per document, got %d: %+v", len(reps), reps)
+	}
+	if reps[0].Text != "defaults.replicas: 2" || reps[0].Meta["document"] != "0" {
+		t.Errorf("unexpected first document: %q %v", reps[0].Text, reps[0].Meta)
+	}
+	want := strings.Join([]string{
+		"apiVersion: apps/v1",
+		"kind: Deployment",
+		"metadata.name: web",
+		"spec.replicas: 3",
+		"spec.template.containers[0].image: nginx:1.27",
+		"spec.template.containers[0].args: []",
+		"spec.template.notes: first line",
+		"  second line",
+	}, "\n")
+	if reps[1].Text != want {
+		t.Errorf("unexpected flattening:\n%s\nwant:\n%s", reps[1].Text, want)
+	}
+	if m := reps[1].Meta; m["document"] != "1" || m["line"] != "4" || m["format"] != "yaml" || m["source"] != "ConfigFileLoader" {
+		t.Errorf("unexpected meta: %v", m)
+	}
+}
+
+func TestConfigFileLoader_SectionsAndFormats(t *testing.T) {
+	toml := `title = "service"
+
+[database]
+host = "db.internal"
+ports = [5432, 5433]
+
+[server]
+host = "0.0.0.0"
+`
+	// A small chunk size forces a split, which must fall on a section boundary.
+	reps := loadConfigFile(t, "app.toml", toml, 80)
+	var texts []string
+	for _, r := range reps {
+		texts = append(texts, r.Text)
+	}
+	want := []string{
+		"database.host: db.internal\ndatabase.ports[0]: 5432\ndatabase.ports[1]: 5433",
+		"server.host: 0.0.0.0\ntitle: service",
+	}
+	if strings.Join(texts, "|") != strings.Join(want, "|") {
+		t.Errorf("unexpected TOML chunks: %q", texts)
+	}
+	if reps[0].Meta["section"] != "database" {
+		t.Errorf("expected section metadata, got %v", reps[0].Meta)
+	}
+
+	ini := "; comment\nroot = 1\n[user]\nname = Ada\nemail: ada@example.com\n  (work)\n"
+	reps = loadConfigFile(t, "settings.ini", ini, 1000)
+	if len(reps) != 1 || reps[0].Text != "root: 1\nuser.name: Ada\nuser.email: ada@example.com\n  (work)" {
+		t.Errorf("unexpected INI flattening: %+v", reps)
+	}
+
+	// Templated YAML does not parse and is indexed as plain text.
+	reps = loadConfigFile(t, "values.yaml", "image: {{ .Values.image }\n", 1000)
+	if len(reps) != 1 || !strings.Contains(reps[0].Text, "{{ .Values.image }") {
+		t.Errorf("expected plain-text fallback, got %+v", reps)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"io/fs"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -15,11 +16,9 @@ import (
 // a single error to errChan if the walk terminates due to an error or initial setup fails.
 // It always closes filePathChan.
 func Crawl(cfg config.FilesConfig, filePathChan chan<- string, errChan chan<- error) {
-	defer close(filePathChan)
-
-	slog.Info("Starting filesystem crawl...", "include", cfg.Include, "exclude", cfg.Exclude)
 	rootDir, err := os.Getwd()
 	if err != nil {
+		defer close(filePathChan)
 		slog.Error("Failed to get working directory for crawl", "error", err)
 		select {
 		case errChan <- err:
@@ -28,80 +27,239 @@ func Crawl(cfg config.FilesConfig, filePathChan chan<- string, errChan chan<- er
 		}
 		return
 	}
+	CrawlDir(rootDir, cfg, filePathChan, errChan)
+}
+
+// CrawlDir is Crawl rooted at rootDir instead of the working directory. Paths
+// sent to filePathChan are relative to rootDir.
+func CrawlDir(rootDir string, cfg config.FilesConfig, filePathChan chan<- string, errChan chan<- error) {
+	defer close(filePathChan)
+
+	slog.Info("Starting filesystem crawl...", "root", rootDir, "include", cfg.Include, "exclude", cfg.Exclude, "respect_gitignore", cfg.RespectGitignore)
 	slog.Debug("Crawling directory", "root", rootDir)
 
-	walkErr := filepath.WalkDir(rootDir, func(absPath string, d fs.DirEntry, err error) error {
+	c := newCrawler(rootDir, cfg)
+	c.found = func(relPath string) {
+		slog.Debug("Found matching file for processing", "file_path", relPath)
+		filePathChan <- relPath // Send the relative path
+	}
+	c.skipped = func(relPath, reason string) {
+		if reason != "" { // not only left out by the include patterns
+			slog.Debug("Skipping path", "path", relPath, "reason", reason)
+		}
+	}
+	walkErr := c.walk(rootDir, "")
+
+	if walkErr != nil {
+		slog.Error("Filesystem walk ended with an error", "error", walkErr)
+		select {
+		case errChan <- walkErr:
+		default:
+			slog.Warn("errChan full/blocked sending walkErr from WalkDir")
+		}
+	} else {
+		slog.Info("Filesystem walk completed successfully.")
+	}
+}
+
+// SkippedFiles walks rootDir like CrawlDir and calls skipped for what
+// CrawlDir leaves out: each directory it does not walk into, once, with a
+// trailing slash, and each file that matches an exclude pattern, an ignore
+// file or no include pattern or is hidden. pattern is the exclude pattern
+// or the ignore file and line that matched, another reason, such as
+// "hidden", or "" for files no include pattern matches.
+func SkippedFiles(rootDir string, cfg config.FilesConfig, skipped func(relPath, pattern string)) error {
+	c := newCrawler(rootDir, cfg)
+	c.skipped = skipped
+	return c.walk(rootDir, "")
+}
+
+// Reasons the crawler gives for paths it skips besides patterns.
+const (
+	skipHidden      = "hidden"
+	skipDepth       = "deeper than files.max_depth"
+	skipSymlink     = "symlink to a directory"
+	skipLinkVisited = "symlink to a directory crawled already"
+	skipBrokenLink  = "broken symlink"
+)
+
+// crawler walks a directory tree for CrawlDir and SkippedFiles.
+type crawler struct {
+	cfg    config.FilesConfig
+	ignore *IgnoreMatcher
+	// trees are the real paths of the directories walked: the root and the
+	// targets of the symlinks followed. A symlink into one of them is not
+	// followed, as its files are crawled already or it loops.
+	trees   []string
+	found   func(relPath string)
+	skipped func(relPath, reason string)
+}
+
+func newCrawler(rootDir string, cfg config.FilesConfig) *crawler {
+	c := &crawler{cfg: cfg, ignore: NewIgnoreMatcher(rootDir, cfg.RespectGitignore)}
+	root, err := filepath.EvalSymlinks(rootDir)
+	if err != nil {
+		root = rootDir
+	}
+	c.trees = []string{root}
+	return c
+}
+
+func (c *crawler) skip(relPath, reason string) {
+	if c.skipped != nil {
+		c.skipped(relPath, reason)
+	}
+}
+
+// walk walks dir, whose files are found under the slash-separated path
+// base ("" for the root).
+func (c *crawler) walk(dir, base string) error {
+	return filepath.WalkDir(dir, func(absPath string, d fs.DirEntry, err error) error {
 		if err != nil {
 			slog.Warn("Error accessing path during walk", "path", absPath, "error", err)
 			return err // Propagate error, WalkDir might stop or skip based on this.
 		}
-
-		// Get path relative to rootDir for matching, as patterns are usually relative.
-		relPath, err := filepath.Rel(rootDir, absPath)
+		relPath, err := filepath.Rel(dir, absPath)
 		if err != nil {
-			slog.Error("Failed to get relative path", "absPath", absPath, "rootDir", rootDir, "error", err)
+			slog.Error("Failed to get relative path", "absPath", absPath, "rootDir", dir, "error", err)
 			return err // Cannot proceed with this path if relative path fails
 		}
-		normalizedPath := filepath.ToSlash(relPath) // Use forward slashes for matching
-
-		slog.Debug("WalkDir processing", "absPath", absPath, "relPath", normalizedPath, "isDir", d.IsDir())
-
-		if d.IsDir() {
-			if relPath == "." { // Skip processing for the root itself, just continue walk
-				return nil
-			}
-			// Check if this directory should be excluded
-			for _, excludePattern := range cfg.Exclude {
-				patternToCheck := excludePattern
-				if strings.HasSuffix(patternToCheck, "/**") {
-					patternToCheck = strings.TrimSuffix(patternToCheck, "/**")
-				}
-				if matched, _ := doublestar.Match(patternToCheck, normalizedPath); matched {
-					if excludePattern == patternToCheck || strings.HasSuffix(excludePattern, "/**") {
-						slog.Debug("Excluding directory due to pattern", "dir_path", normalizedPath, "pattern", excludePattern)
-						return filepath.SkipDir // Use filepath.SkipDir with WalkDir
-					}
-				}
-			}
-			return nil // Directory not excluded, continue walking
+		if relPath == "." { // Skip processing for the root itself, just continue walk
+			return nil
 		}
-
-		// It's a file. Check exclude patterns.
-		for _, excludePattern := range cfg.Exclude {
-			if matched, _ := doublestar.Match(excludePattern, normalizedPath); matched {
-				slog.Debug("Excluding file due to pattern", "file_path", normalizedPath, "pattern", excludePattern)
-				return nil
+		// Use forward slashes for matching, as patterns are relative.
+		p := path.Join(base, filepath.ToSlash(relPath))
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			return c.symlink(absPath, p)
+		case d.IsDir():
+			if !c.enterDir(p, d.Name()) {
+				return filepath.SkipDir
 			}
+		default:
+			c.file(p, d.Name())
 		}
+		return nil
+	})
+}
 
-		// File is not excluded. Check include patterns.
-		included := false
-		if len(cfg.Include) == 0 {
-			included = true
-		} else {
-			for _, includePattern := range cfg.Include {
-				if matched, _ := doublestar.Match(includePattern, normalizedPath); matched {
-					included = true
-					break
-				}
-			}
+// enterDir reports whether the directory at p is walked into, reporting
+// it as skipped if not.
+func (c *crawler) enterDir(p, name string) bool {
+	reason, skip := c.dirSkipped(p, name)
+	if skip {
+		c.skip(p+"/", reason)
+	}
+	return !skip
+}
+
+// dirSkipped returns why the directory at p is not walked into, if it is
+// not.
+func (c *crawler) dirSkipped(p, name string) (string, bool) {
+	if pattern, ok := excludedDir(c.cfg.Exclude, p); ok {
+		return pattern, true
+	}
+	if rule, ok := c.ignore.match(p, true); ok {
+		return rule, true
+	}
+	if c.cfg.SkipHidden && strings.HasPrefix(name, ".") {
+		return skipHidden, true
+	}
+	if c.cfg.MaxDepth > 0 && strings.Count(p, "/")+1 >= c.cfg.MaxDepth {
+		return skipDepth, true // its files would be deeper
+	}
+	return "", false
+}
+
+// excludedDir returns the exclude pattern that excludes the directory at
+// the slash-separated path p with everything in it, if one does: the
+// pattern of the directory itself or of everything below it ("dir/**").
+func excludedDir(exclude []string, p string) (string, bool) {
+	for _, excludePattern := range exclude {
+		if matched, _ := doublestar.Match(strings.TrimSuffix(excludePattern, "/**"), p); matched {
+			return excludePattern, true
 		}
+	}
+	return "", false
+}
 
-		if included {
-			slog.Debug("Found matching file for processing", "file_path", normalizedPath)
-			filePathChan <- normalizedPath // Send the relative path
+// file reports the file at p as found or skipped.
+func (c *crawler) file(p, name string) {
+	for _, excludePattern := range c.cfg.Exclude {
+		if matched, _ := doublestar.Match(excludePattern, p); matched {
+			c.skip(p, excludePattern)
+			return
 		}
+	}
+	if rule, ok := c.ignore.match(p, false); ok {
+		c.skip(p, rule)
+		return
+	}
+	if c.cfg.SkipHidden && strings.HasPrefix(name, ".") {
+		c.skip(p, skipHidden)
+		return
+	}
+	if !PathIncluded(c.cfg.Include, nil, p) {
+		c.skip(p, "")
+		return
+	}
+	if c.found != nil {
+		c.found(p)
+	}
+}
+
+// symlink handles the symlink at p: one to a file is a file, one to a
+// directory is walked only with files.follow_symlinks and if its target
+// is not in a tree walked already.
+func (c *crawler) symlink(absPath, p string) error {
+	name := path.Base(p)
+	fi, err := os.Stat(absPath)
+	if err != nil {
+		c.skip(p, skipBrokenLink)
 		return nil
-	})
+	}
+	if !fi.IsDir() {
+		c.file(p, name)
+		return nil
+	}
+	if !c.cfg.FollowSymlinks {
+		c.skip(p+"/", skipSymlink)
+		return nil
+	}
+	if !c.enterDir(p, name) {
+		return nil // not filepath.SkipDir, which would skip the rest of the parent
+	}
+	target, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		c.skip(p, skipBrokenLink)
+		return nil
+	}
+	for _, tree := range c.trees {
+		if target == tree || strings.HasPrefix(target, tree+string(filepath.Separator)) {
+			c.skip(p+"/", skipLinkVisited)
+			return nil
+		}
+	}
+	c.trees = append(c.trees, target)
+	return c.walk(target, p)
+}
 
-	if walkErr != nil {
-		slog.Error("Filesystem walk ended with an error", "error", walkErr)
-		select {
-		case errChan <- walkErr:
-		default:
-			slog.Warn("errChan full/blocked sending walkErr from WalkDir")
+// PathIncluded reports whether a slash-separated path matches one of the
+// include patterns (or include is empty) and none of the exclude patterns.
+func PathIncluded(include, exclude []string, p string) bool {
+	for _, excludePattern := range exclude {
+		if matched, _ := doublestar.Match(excludePattern, p); matched {
+			slog.Debug("Excluding file due to pattern", "file_path", p, "pattern", excludePattern)
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, includePattern := range include {
+		if matched, _ := doublestar.Match(includePattern, p); matched {
+			return true
 		}
-	} else {
-		slog.Info("Filesystem walk completed successfully.")
 	}
+	return false
 }
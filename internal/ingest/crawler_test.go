@@ -0,0 +1,98 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+// crawl returns the sorted paths CrawlDir finds under dir.
+func crawl(t *testing.T, dir string, files config.FilesConfig) []string {
+	t.Helper()
+	paths := make(chan string, 10)
+	errs := make(chan error, 1)
+	go CrawlDir(dir, files, paths, errs)
+	var got []string
+	for p := range paths {
+		got = append(got, p)
+	}
+	select {
+	case err := <-errs:
+		t.Fatalf("CrawlDir: %v", err)
+	default:
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestCrawlDirSymlinks(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "tree")
+	outside := filepath.Join(root, "outside")
+	writeTree(t, dir, map[string]string{"a.md": "a", "sub/b.md": "b"})
+	writeTree(t, outside, map[string]string{"c.md": "c"})
+	for link, target := range map[string]string{
+		"tree/loop":        ".",          // back to the root: a loop
+		"tree/sub/again":   "../sub",     // a directory crawled already
+		"tree/ext":         "../outside", // out of the tree
+		"tree/file.md":     "a.md",
+		"tree/dangling.md": "missing.md",
+		"outside/back":     "../tree", // from the followed tree back in
+	} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+
+	files := config.FilesConfig{Include: []string{"**/*.md"}}
+	if got, want := crawl(t, dir, files), []string{"a.md", "file.md", "sub/b.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("without follow_symlinks: %v, want %v", got, want)
+	}
+	files.FollowSymlinks = true
+	if got, want := crawl(t, dir, files), []string{"a.md", "ext/c.md", "file.md", "sub/b.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("with follow_symlinks: %v, want %v", got, want)
+	}
+
+	skipped := map[string]string{}
+	if err := SkippedFiles(dir, files, func(relPath, reason string) { skipped[relPath] = reason }); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"dangling.md": skipBrokenLink,
+		"ext/back/":   skipLinkVisited,
+		"loop/":       skipLinkVisited,
+		"sub/again/":  skipLinkVisited,
+	}
+	if !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped %v, want %v", skipped, want)
+	}
+}
+
+func TestCrawlDirHiddenAndDepth(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a.md":           "a",
+		".hidden.md":     "hidden file",
+		".github/b.md":   "hidden directory",
+		"one/c.md":       "c",
+		"one/two/d.md":   "d",
+		"one/two/x/e.md": "e",
+	})
+	files := config.FilesConfig{Include: []string{"**/*.md"}}
+	if got := crawl(t, dir, files); len(got) != 6 {
+		t.Errorf("no limits: %v", got)
+	}
+	files.SkipHidden = true
+	files.MaxDepth = 2
+	if got, want := crawl(t, dir, files), []string{"a.md", "one/c.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("skip_hidden, max_depth 2: %v, want %v", got, want)
+	}
+	files.MaxDepth = 1
+	if got, want := crawl(t, dir, files), []string{"a.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("max_depth 1: %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package ingest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"strings"
+)
+
+// minNearWords is the number of words a chunk needs for near-duplicate
+// detection; SimHashes of shorter texts collide too easily.
+const minNearWords = 20
+
+// Fingerprint identifies the text of a chunk for deduplication.
+type Fingerprint struct {
+	// Hash is the SHA-256 of the text lower-cased with runs of white space
+	// collapsed, so reflowed copies match.
+	Hash string
+	// SimHash is a 64-bit SimHash of the word 3-grams of the text: texts
+	// differing in a few words differ in a few bits.
+	SimHash uint64
+	// Near is set when the text is long enough for SimHash to tell near
+	// duplicates apart.
+	Near bool
+}
+
+// FingerprintText returns the fingerprint of text.
+func FingerprintText(text string) Fingerprint {
+	words := strings.Fields(strings.ToLower(text))
+	sum := sha256.Sum256([]byte(strings.Join(words, " ")))
+	return Fingerprint{Hash: hex.EncodeToString(sum[:]), SimHash: simHash(words), Near: len(words) >= minNearWords}
+}
+
+// simHash sums the FNV-1a hashes of the word 3-grams of words bit by bit,
+// each bit voting +1 when set and -1 otherwise, and keeps the bits with a
+// positive total.
+func simHash(words []string) uint64 {
+	var votes [64]int
+	n := 3
+	if len(words) < n {
+		n = len(words)
+	}
+	for i := 0; i+n <= len(words) && n > 0; i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+n], " ")))
+		v := h.Sum64()
+		for b := 0; b < 64; b++ {
+			if v&(1<<b) != 0 {
+				votes[b]++
+			} else {
+				votes[b]--
+			}
+		}
+	}
+	var out uint64
+	for b, v := range votes {
+		if v > 0 {
+			out |= 1 << b
+		}
+	}
+	return out
+}
@@ -0,0 +1,30 @@
+package ingest
+
+import (
+	"math/bits"
+	"strings"
+	"testing"
+)
+
+func TestFingerprintText(t *testing.T) {
+	a := FingerprintText("Hello,   World\n")
+	if b := FingerprintText("hello, world"); a.Hash != b.Hash {
+		t.Error("case and white space changed the hash")
+	}
+	if a.Near {
+		t.Error("short text marked for near-duplicate detection")
+	}
+
+	long := strings.Repeat("semango indexes files for hybrid lexical and semantic search ", 5)
+	x, y := FingerprintText(long), FingerprintText(strings.Replace(long, "hybrid", "fused", 1))
+	if !x.Near || x.Hash == y.Hash {
+		t.Fatalf("long texts: near %v, equal hashes %v", x.Near, x.Hash == y.Hash)
+	}
+	if d := bits.OnesCount64(x.SimHash ^ y.SimHash); d > 3 {
+		t.Errorf("one changed word moved the SimHash by %d bits", d)
+	}
+	other := FingerprintText(strings.Repeat("an unrelated paragraph about cooking pasta with garlic and oil ", 5))
+	if d := bits.OnesCount64(x.SimHash ^ other.SimHash); d < 10 {
+		t.Errorf("unrelated texts are %d bits apart", d)
+	}
+}
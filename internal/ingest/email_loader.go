@@ -0,0 +1,375 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// EmailLoader indexes single messages (.eml) and mbox archives (.mbox), one
+// representation per message. The body prefers text/plain over text/html;
+// subject, sender, recipients, date, message ID and a thread ID (the root of
+// the References chain) are recorded as metadata.
+type EmailLoader struct{}
+
+// NewEmailLoader returns an EmailLoader.
+func NewEmailLoader() *EmailLoader { return &EmailLoader{} }
+
+func (el *EmailLoader) Extensions() []string {
+	return []string{".eml", ".mbox"}
+}
+
+// maxMIMEDepth bounds recursion into nested multiparts.
+const maxMIMEDepth = 10
+
+var mailWordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+func (el *EmailLoader) Load(ctx context.Context, relPath string, absPath string) ([]Representation, error) {
+	slog.Info("Loading email file", "relative_path", relPath, "absolute_path", absPath)
+
+	f, err := os.Open(absPath)
+	if err != nil {
+		slog.Error("Failed to open email file", "path", absPath, "error", err)
+		return nil, err
+	}
+	defer f.Close()
+
+	var reps []Representation
+	add := func(raw []byte, index int) {
+		rep, err := emailRepresentation(raw, relPath, index)
+		if err != nil {
+			slog.Warn("Skipping unparsable email message", "path", relPath, "index", index, "error", err)
+			return
+		}
+		if rep != nil {
+			reps = append(reps, *rep)
+		}
+	}
+
+	if strings.EqualFold(filepath.Ext(absPath), ".mbox") {
+		n := 0
+		err = splitMbox(f, func(raw []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			add(raw, n)
+			n++
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("read mbox %s: %w", relPath, err)
+		}
+	} else {
+		raw, err := io.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+		add(raw, 0)
+	}
+	slog.Debug("Created", "messages", len(reps), "relPath", relPath)
+	return reps, nil
+}
+
+// splitMbox calls fn with each message of an mbox archive. A message starts
+// at a "From " line that opens the file or follows a blank line; mboxrd
+// ">From " escaping is undone.
+func splitMbox(r io.Reader, fn func([]byte) error) error {
+	br := bufio.NewReader(r)
+	var msg bytes.Buffer
+	prevBlank := true
+	flush := func() error {
+		if len(bytes.TrimSpace(msg.Bytes())) == 0 {
+			msg.Reset()
+			return nil
+		}
+		err := fn(bytes.Clone(msg.Bytes()))
+		msg.Reset()
+		return err
+	}
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if prevBlank && bytes.HasPrefix(line, []byte("From ")) {
+				if err := flush(); err != nil {
+					return err
+				}
+			} else {
+				if unquoted := bytes.TrimLeft(line, ">"); len(unquoted) < len(line) && bytes.HasPrefix(unquoted, []byte("From ")) {
+					line = line[1:]
+				}
+				msg.Write(line)
+			}
+			prevBlank = len(bytes.TrimRight(line, "\r\n")) == 0
+		}
+		if err == io.EOF {
+			return flush()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// mailBody collects the readable parts of a MIME message.
+type mailBody struct {
+	plain       []string
+	html        []string
+	attachments []string
+}
+
+func emailRepresentation(raw []byte, relPath string, index int) (*Representation, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	h := msg.Header
+
+	var body mailBody
+	if err := readMailPart(h.Get("Content-Type"), h.Get("Content-Transfer-Encoding"), msg.Body, &body, 0); err != nil {
+		slog.Debug("Partial MIME parse", "path", relPath, "index", index, "error", err)
+	}
+	text := strings.Join(body.plain, "\n\n")
+	if strings.TrimSpace(text) == "" {
+		parts := make([]string, len(body.html))
+		for i, hp := range body.html {
+			parts[i] = HTMLToText(hp)
+		}
+		text = strings.Join(parts, "\n\n")
+	}
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+
+	meta := map[string]string{
+		"source":        "EmailLoader",
+		"path":          relPath,
+		"message_index": strconv.Itoa(index),
+	}
+	subject := decodeMailHeader(h.Get("Subject"))
+	setNonEmpty(meta, "subject", subject)
+	setNonEmpty(meta, "from", mailAddresses(h.Get("From")))
+	setNonEmpty(meta, "to", mailAddresses(h.Get("To")))
+	setNonEmpty(meta, "cc", mailAddresses(h.Get("Cc")))
+	if d, err := mail.ParseDate(h.Get("Date")); err == nil {
+		meta["date"] = d.UTC().Format(time.RFC3339)
+	}
+	messageID := trimMessageID(h.Get("Message-Id"))
+	setNonEmpty(meta, "message_id", messageID)
+	setNonEmpty(meta, "in_reply_to", trimMessageID(h.Get("In-Reply-To")))
+	thread := messageID
+	if refs := strings.Fields(h.Get("References")); len(refs) > 0 {
+		thread = trimMessageID(refs[0])
+	} else if irt := trimMessageID(h.Get("In-Reply-To")); irt != "" {
+		thread = irt
+	}
+	setNonEmpty(meta, "thread_id", thread)
+	if len(body.attachments) > 0 {
+		meta["attachments"] = strings.Join(body.attachments, ", ")
+	}
+
+	if subject == "" && text == "" {
+		return nil, nil
+	}
+	var sb strings.Builder
+	if subject != "" {
+		sb.WriteString("Subject: " + subject + "\n")
+	}
+	if from := meta["from"]; from != "" {
+		sb.WriteString("From: " + from + "\n")
+	}
+	if sb.Len() > 0 && text != "" {
+		sb.WriteByte('\n')
+	}
+	sb.WriteString(text)
+
+	return &Representation{
+		ID:       ChunkID(relPath, "text", int64(index)),
+		Path:     relPath,
+		Modality: "text",
+		Text:     sb.String(),
+		Meta:     meta,
+	}, nil
+}
+
+// readMailPart decodes one MIME entity into out, recursing into multiparts.
+func readMailPart(contentType, transferEncoding string, r io.Reader, out *mailBody, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "" {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	r = decodeTransferEncoding(r, transferEncoding)
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxMIMEDepth || params["boundary"] == "" {
+			return nil
+		}
+		mr := multipart.NewReader(r, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			disposition, dparams, _ := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+			_, cparams, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+			name := dparams["filename"]
+			if name == "" {
+				name = cparams["name"]
+			}
+			if disposition == "attachment" || (name != "" && !strings.HasPrefix(p.Header.Get("Content-Type"), "text/")) {
+				if name == "" {
+					name = "unnamed"
+				}
+				out.attachments = append(out.attachments, decodeMailHeader(name))
+				continue
+			}
+			if err := readMailPart(p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p, out, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch mediaType {
+	case "text/plain", "text/html":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		text := decodeCharset(data, params["charset"])
+		if mediaType == "text/plain" {
+			out.plain = append(out.plain, text)
+		} else {
+			out.html = append(out.html, text)
+		}
+	}
+	return nil
+}
+
+func decodeTransferEncoding(r io.Reader, encoding string) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: r})
+	default:
+		return r
+	}
+}
+
+// base64Cleaner drops the line breaks and padding whitespace that MIME
+// allows inside base64 bodies.
+type base64Cleaner struct{ r io.Reader }
+
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	j := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+			p[j] = b
+			j++
+		}
+	}
+	return j, err
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, err
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// decodeCharset converts data to UTF-8, leaving it as-is for unknown or
+// already-UTF-8 charsets.
+func decodeCharset(data []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8", "us-ascii":
+		return string(data)
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return string(data)
+	}
+	out, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(out)
+}
+
+func decodeMailHeader(v string) string {
+	if d, err := mailWordDecoder.DecodeHeader(v); err == nil {
+		v = d
+	}
+	return strings.TrimSpace(v)
+}
+
+// mailAddresses renders an address list header as "Name <addr>, ...".
+func mailAddresses(v string) string {
+	if strings.TrimSpace(v) == "" {
+		return ""
+	}
+	parser := &mail.AddressParser{WordDecoder: mailWordDecoder}
+	list, err := parser.ParseList(v)
+	if err != nil {
+		return decodeMailHeader(v)
+	}
+	parts := make([]string, len(list))
+	for i, a := range list {
+		if a.Name != "" {
+			parts[i] = a.Name + " <" + a.Address + ">"
+		} else {
+			parts[i] = a.Address
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func trimMessageID(v string) string {
+	return strings.Trim(strings.TrimSpace(v), "<>")
+}
+
+func setNonEmpty(meta map[string]string, key, value string) {
+	if value != "" {
+		meta[key] = value
+	}
+}
+
+var (
+	reHTMLDrop  = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)\s*>`)
+	reHTMLBreak = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|tr|h[1-6]|blockquote)\s*>`)
+	reHTMLTag   = regexp.MustCompile(`(?s)<[^>]*>`)
+	reBlankRuns = regexp.MustCompile(`\n[ \t]*(\n[ \t]*)+`)
+)
+
+// HTMLToText is a lightweight HTML-to-text conversion for email bodies and
+// crawled web pages.
+func HTMLToText(s string) string {
+	s = reHTMLDrop.ReplaceAllString(s, "")
+	s = reHTMLBreak.ReplaceAllString(s, "\n")
+	s = reHTMLTag.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.Join(strings.Fields(l), " ")
+	}
+	return strings.TrimSpace(reBlankRuns.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
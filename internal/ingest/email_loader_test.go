@@ -0,0 +1,117 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmailLoader_EML(t *testing.T) {
+	msg := "From: =?UTF-8?Q?Ren=C3=A9e?= <renee@example.com>\r\n" +
+		"To: Bob <bob@example.com>, carol@example.com\r\n" +
+		"Subject: =?UTF-8?B?UXVhcnRlcmx5IHJlcG9ydA==?=\r\n" +
+		"Date: Mon, 04 Mar 2024 09:30:00 +0100\r\n" +
+		"Message-ID: <m2@example.com>\r\n" +
+		"In-Reply-To: <m1@example.com>\r\n" +
+		"References: <m0@example.com> <m1@example.com>\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=outer\r\n" +
+		"\r\n" +
+		"--outer\r\n" +
+		"Content-Type: multipart/alternative; boundary=inner\r\n" +
+		"\r\n" +
+		"--inner\r\n" +
+		"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+		"Content-Transfer-Encoding: quoted-printable\r\n" +
+		"\r\n" +
+		"Numbers are up =E9verywhere.\r\n" +
+		"--inner\r\n" +
+		"Content-Type: text/html\r\n" +
+		"\r\n" +
+		"<p>HTML version</p>\r\n" +
+		"--inner--\r\n" +
+		"--outer\r\n" +
+		"Content-Type: application/pdf; name=report.pdf\r\n" +
+		"Content-Disposition: attachment; filename=report.pdf\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"JVBERi0=\r\n" +
+		"--outer--\r\n"
+	file := filepath.Join(t.TempDir(), "mail.eml")
+	if err := os.WriteFile(file, []byte(msg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reps, err := NewEmailLoader().Load(context.Background(), "mail.eml", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reps) != 1 {
+		t.Fatalf("expected 1 representation, got %d", len(reps))
+	}
+	r := reps[0]
+	if !strings.Contains(r.Text, "Numbers are up éverywhere.") || strings.Contains(r.Text, "HTML version") {
+		t.Errorf("expected the plain text body, got %q", r.Text)
+	}
+	want := map[string]string{
+		"subject":     "Quarterly report",
+		"from":        "Renée <renee@example.com>",
+		"to":          "Bob <bob@example.com>, carol@example.com",
+		"date":        "2024-03-04T08:30:00Z",
+		"message_id":  "m2@example.com",
+		"in_reply_to": "m1@example.com",
+		"thread_id":   "m0@example.com",
+		"attachments": "report.pdf",
+	}
+	for k, v := range want {
+		if r.Meta[k] != v {
+			t.Errorf("meta[%q] = %q, want %q", k, r.Meta[k], v)
+		}
+	}
+}
+
+func TestEmailLoader_Mbox(t *testing.T) {
+	mbox := `From alice@example.com Mon Mar  4 09:00:00 2024
+From: alice@example.com
+Subject: Lunch?
+Message-ID: <a1@example.com>
+
+Shall we meet at noon?
+>From the kitchen, with love.
+
+From bob@example.com Mon Mar  4 09:05:00 2024
+From: bob@example.com
+Subject: Re: Lunch?
+Message-ID: <b1@example.com>
+In-Reply-To: <a1@example.com>
+Content-Type: text/html
+
+<html><head><style>p{}</style></head><body><p>Sure&nbsp;thing</p><p>See you</p></body></html>
+`
+	file := filepath.Join(t.TempDir(), "inbox.mbox")
+	if err := os.WriteFile(file, []byte(mbox), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reps, err := NewEmailLoader().Load(context.Background(), "inbox.mbox", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reps) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(reps))
+	}
+	if !strings.Contains(reps[0].Text, "From the kitchen") || strings.Contains(reps[0].Text, ">From") {
+		t.Errorf("mboxrd escaping not undone: %q", reps[0].Text)
+	}
+	if !strings.HasSuffix(reps[1].Text, "Sure thing\nSee you") || strings.Contains(reps[1].Text, "p{}") {
+		t.Errorf("unexpected HTML-derived body: %q", reps[1].Text)
+	}
+	if reps[0].Meta["thread_id"] != "a1@example.com" || reps[1].Meta["thread_id"] != "a1@example.com" {
+		t.Errorf("messages should share a thread: %q, %q", reps[0].Meta["thread_id"], reps[1].Meta["thread_id"])
+	}
+	if reps[1].Meta["message_index"] != "1" || reps[0].ID == reps[1].ID {
+		t.Errorf("unexpected IDs/meta: %v", reps[1].Meta)
+	}
+}
@@ -0,0 +1,72 @@
+package ingest
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// NewEmbedderFromConfig builds the embedder selected by cfg.Provider.
+func NewEmbedderFromConfig(cfg config.EmbeddingConfig) (Embedder, error) {
+	switch cfg.Provider {
+	case "openai", "": // default to openai
+		apiKey := os.Getenv("OPENAI_API_KEY")
+		if apiKey == "" {
+			return nil, util.NewError("OpenAI API key is required but not found in OPENAI_API_KEY environment variable")
+		}
+		e, err := NewOpenAIEmbedder(OpenAIConfig{
+			APIKey:     apiKey,
+			Model:      cfg.Model,
+			BatchSize:  cfg.BatchSize,
+			Concurrent: cfg.Concurrent,
+			Dimensions: cfg.Dimensions,
+		})
+		if err != nil {
+			return nil, util.WrapError(err, "Failed to create OpenAI embedder")
+		}
+		return e, nil
+	case "local":
+		if cfg.Dimensions > 0 {
+			return nil, util.NewError("embedding.dimensions is only supported by the openai provider's text-embedding-3 models")
+		}
+		if cfg.LocalModelPath == "" {
+			return nil, util.NewError("Local model path is required for local embedder provider")
+		}
+		localCfg := LocalEmbedderConfig{
+			ModelPath: cfg.LocalModelPath,
+			CacheDir:  cfg.ModelCacheDir,
+			BatchSize: cfg.BatchSize,
+			MaxLength: 512, // Default max length
+			Sessions:  cfg.Concurrent,
+			Threads:   cfg.LocalThreads,
+		}
+		if err := ValidateModelConfig(localCfg); err != nil {
+			return nil, util.WrapError(err, "Invalid local embedder configuration")
+		}
+		e, err := NewLocalEmbedder(localCfg)
+		if err != nil {
+			return nil, util.WrapError(err, "Failed to create local embedder")
+		}
+		return e, nil
+	default:
+		return nil, util.NewError(fmt.Sprintf("Unsupported embedder provider: %s. Supported providers: openai, local", cfg.Provider))
+	}
+}
+
+// NewSpaceEmbedders builds the embedder of every vector space configured
+// under cfg.Spaces, keyed by space name.
+func NewSpaceEmbedders(cfg config.EmbeddingConfig) (map[string]Embedder, error) {
+	spaces := make(map[string]Embedder, len(cfg.Spaces))
+	for name := range cfg.Spaces {
+		spaceCfg, _ := cfg.ForSpace(name)
+		e, err := NewEmbedderFromConfig(spaceCfg)
+		if err != nil {
+			return nil, util.WrapError(err, "Failed to create embedder for vector space", slog.String("space", name))
+		}
+		spaces[name] = e
+	}
+	return spaces, nil
+}
@@ -0,0 +1,419 @@
+package ingest
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// EPUBLoader indexes ebooks chapter by chapter. Chapters follow the reading
+// order of the OPF spine and are titled from the table of contents (EPUB 3 nav
+// document or EPUB 2 NCX). Book metadata (title, author, language, ...) is
+// attached to every chunk; chapters longer than chunkSize are split further.
+type EPUBLoader struct {
+	chunkSize int
+	overlap   int
+	chunking  Chunking
+}
+
+// NewEPUBLoader returns an EPUBLoader with chunk configuration.
+func NewEPUBLoader(chunkSize, overlap int) *EPUBLoader {
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	if overlap < 0 {
+		overlap = 0
+	}
+	return &EPUBLoader{chunkSize: chunkSize, overlap: overlap}
+}
+
+// SetChunking replaces the fixed-size chunks of el with c.
+func (el *EPUBLoader) SetChunking(c Chunking) { el.chunking = c }
+
+func (el *EPUBLoader) Extensions() []string {
+	return []string{".epub"}
+}
+
+// epubPackage is the subset of the OPF package document we need.
+type epubPackage struct {
+	Metadata struct {
+		Titles     []string `xml:"title"`
+		Creators   []string `xml:"creator"`
+		Languages  []string `xml:"language"`
+		Publishers []string `xml:"publisher"`
+		Dates      []string `xml:"date"`
+	} `xml:"metadata"`
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine struct {
+		TOC      string `xml:"toc,attr"`
+		ItemRefs []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+type epubChapter struct {
+	title string
+	text  string
+}
+
+func (el *EPUBLoader) Load(ctx context.Context, relPath string, absPath string) ([]Representation, error) {
+	slog.Info("Loading epub file", "relative_path", relPath, "absolute_path", absPath)
+
+	zr, err := zip.OpenReader(absPath)
+	if err != nil {
+		slog.Error("Failed to open epub", "path", absPath, "error", err)
+		return nil, err
+	}
+	defer zr.Close()
+
+	opfPath, err := epubRootfile(&zr.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("epub %s: %w", relPath, err)
+	}
+	var pkg epubPackage
+	if err := decodeZipXML(findZipFile(&zr.Reader, opfPath), &pkg); err != nil {
+		return nil, fmt.Errorf("epub %s: parse package document: %w", relPath, err)
+	}
+
+	base := path.Dir(opfPath)
+	hrefs := map[string]string{} // manifest id -> zip path
+	var navPath string
+	for _, item := range pkg.Manifest {
+		p := epubResolve(base, item.Href)
+		hrefs[item.ID] = p
+		if strings.Contains(" "+item.Properties+" ", " nav ") {
+			navPath = p
+		}
+	}
+
+	// Chapter titles from the table of contents, keyed by zip path.
+	titles := map[string]string{}
+	if navPath != "" {
+		epubNavTitles(findZipFile(&zr.Reader, navPath), path.Dir(navPath), titles)
+	} else if ncx := hrefs[pkg.Spine.TOC]; ncx != "" {
+		epubNCXTitles(findZipFile(&zr.Reader, ncx), path.Dir(ncx), titles)
+	}
+
+	bookMeta := map[string]string{}
+	setFirst := func(key string, vals []string) {
+		for _, v := range vals {
+			if v = strings.TrimSpace(v); v != "" {
+				bookMeta[key] = v
+				return
+			}
+		}
+	}
+	setFirst("title", pkg.Metadata.Titles)
+	setFirst("publisher", pkg.Metadata.Publishers)
+	setFirst("published", pkg.Metadata.Dates)
+	var authors []string
+	for _, c := range pkg.Metadata.Creators {
+		if c = strings.TrimSpace(c); c != "" {
+			authors = append(authors, c)
+		}
+	}
+	if len(authors) > 0 {
+		bookMeta["author"] = strings.Join(authors, ", ")
+	}
+	for _, l := range pkg.Metadata.Languages {
+		if lang := NormalizeLang(l); lang != "" {
+			bookMeta["lang"] = lang
+			break
+		}
+	}
+
+	var chapters []epubChapter
+	for _, ref := range pkg.Spine.ItemRefs {
+		p := hrefs[ref.IDRef]
+		if p == "" || p == navPath {
+			continue
+		}
+		f := findZipFile(&zr.Reader, p)
+		if f == nil {
+			slog.Warn("EPUB spine item missing from archive", "path", relPath, "item", p)
+			continue
+		}
+		heading, text, err := epubChapterText(f)
+		if err != nil {
+			slog.Warn("Skipping unreadable EPUB chapter", "path", relPath, "item", p, "error", err)
+			continue
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		title := titles[p]
+		if title == "" {
+			title = heading
+		}
+		if title == "" {
+			title = "Chapter " + strconv.Itoa(len(chapters)+1)
+		}
+		chapters = append(chapters, epubChapter{title: title, text: text})
+	}
+
+	chunker := el.chunking.chunker(el.chunkSize, el.overlap)
+	var reps []Representation
+	for i, ch := range chapters {
+		for _, span := range chunker.Spans(ch.text) {
+			if strings.TrimSpace(span.Text) == "" {
+				continue
+			}
+			meta := map[string]string{
+				"source":        "EPUBLoader",
+				"path":          relPath,
+				"chapter":       ch.title,
+				"chapter_index": strconv.Itoa(i + 1),
+				"offset":        strconv.Itoa(span.Start),
+			}
+			for k, v := range bookMeta {
+				meta[k] = v
+			}
+			reps = append(reps, Representation{
+				ID:       ChunkID(relPath, "text", int64(len(reps))),
+				Path:     relPath,
+				Modality: "text",
+				Text:     span.Text,
+				Meta:     meta,
+			})
+		}
+	}
+	slog.Debug("Created", "chapters", len(chapters), "chunks", len(reps), "relPath", relPath)
+	return reps, nil
+}
+
+// epubRootfile reads META-INF/container.xml to find the OPF package document.
+func epubRootfile(zr *zip.Reader) (string, error) {
+	var container struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := decodeZipXML(findZipFile(zr, "META-INF/container.xml"), &container); err != nil {
+		return "", fmt.Errorf("read container.xml: %w", err)
+	}
+	for _, rf := range container.Rootfiles {
+		if rf.FullPath != "" {
+			return rf.FullPath, nil
+		}
+	}
+	return "", fmt.Errorf("container.xml lists no rootfile")
+}
+
+// epubResolve turns a manifest or TOC href into a path inside the archive.
+func epubResolve(base, href string) string {
+	href, _, _ = strings.Cut(href, "#")
+	if u, err := url.PathUnescape(href); err == nil {
+		href = u
+	}
+	return path.Clean(path.Join(base, href))
+}
+
+// epubNavTitles reads <a href> labels from an EPUB 3 navigation document.
+func epubNavTitles(f *zip.File, base string, titles map[string]string) {
+	dec, closer, err := openXHTML(f)
+	if err != nil {
+		return
+	}
+	defer closer.Close()
+
+	var (
+		href  string
+		label strings.Builder
+	)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "a" {
+				href = xmlAttr(t, "href")
+				label.Reset()
+			}
+		case xml.CharData:
+			if href != "" {
+				label.Write(t)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "a" && href != "" {
+				addTOCTitle(titles, epubResolve(base, href), label.String())
+				href = ""
+			}
+		}
+	}
+}
+
+// epubNCXTitles reads navPoint labels from an EPUB 2 NCX file.
+func epubNCXTitles(f *zip.File, base string, titles map[string]string) {
+	var ncx struct {
+		Points []ncxPoint `xml:"navMap>navPoint"`
+	}
+	if err := decodeZipXML(f, &ncx); err != nil {
+		return
+	}
+	var walk func([]ncxPoint)
+	walk = func(points []ncxPoint) {
+		for _, p := range points {
+			addTOCTitle(titles, epubResolve(base, p.Content.Src), p.Label)
+			walk(p.Children)
+		}
+	}
+	walk(ncx.Points)
+}
+
+type ncxPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	Children []ncxPoint `xml:"navPoint"`
+}
+
+// addTOCTitle keeps the first (outermost) title seen for a chapter file.
+func addTOCTitle(titles map[string]string, p, label string) {
+	label = strings.Join(strings.Fields(label), " ")
+	if label == "" {
+		return
+	}
+	if _, ok := titles[p]; !ok {
+		titles[p] = label
+	}
+}
+
+// epubBlockElements end a line of text when they close.
+var epubBlockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "blockquote": true,
+	"section": true, "article": true, "pre": true, "dt": true, "dd": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// epubChapterText extracts readable text from an XHTML content document and
+// returns its first heading (or <title>) alongside.
+func epubChapterText(f *zip.File) (string, string, error) {
+	dec, closer, err := openXHTML(f)
+	if err != nil {
+		return "", "", err
+	}
+	defer closer.Close()
+
+	var (
+		sb       strings.Builder
+		line     strings.Builder
+		heading  string
+		docTitle string
+		inBody   bool
+		capture  string // element whose text is being captured as a title
+		captured strings.Builder
+	)
+	endLine := func() {
+		if l := strings.Join(strings.Fields(line.String()), " "); l != "" {
+			sb.WriteString(l)
+			sb.WriteByte('\n')
+		}
+		line.Reset()
+	}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			switch name {
+			case "script", "style":
+				if err := dec.Skip(); err != nil {
+					return "", "", err
+				}
+				continue
+			case "body":
+				inBody = true
+			case "title":
+				if !inBody && docTitle == "" {
+					capture = name
+					captured.Reset()
+				}
+			case "h1", "h2", "h3":
+				if inBody && heading == "" {
+					capture = name
+					captured.Reset()
+				}
+			}
+			if epubBlockElements[name] {
+				endLine()
+			}
+		case xml.CharData:
+			if capture != "" {
+				captured.Write(t)
+			}
+			if inBody {
+				line.Write(t)
+			}
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			if name == capture {
+				text := strings.Join(strings.Fields(captured.String()), " ")
+				if name == "title" {
+					docTitle = text
+				} else {
+					heading = text
+				}
+				capture = ""
+			}
+			if epubBlockElements[name] || name == "body" {
+				endLine()
+			}
+		}
+	}
+	endLine()
+	if heading == "" {
+		heading = docTitle
+	}
+	return heading, sb.String(), nil
+}
+
+// openXHTML returns a lenient XML decoder for (X)HTML content that may use
+// HTML entities or unclosed void elements.
+func openXHTML(f *zip.File) (*xml.Decoder, io.Closer, error) {
+	if f == nil {
+		return nil, nil, fmt.Errorf("file not found in archive")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	dec := xml.NewDecoder(rc)
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+	return dec, rc, nil
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	if f == nil {
+		return fmt.Errorf("file not found in archive")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
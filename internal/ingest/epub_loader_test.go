@@ -0,0 +1,77 @@
+package ingest
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEPUBLoader_Chapters(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "book.epub")
+	writeZip(t, file, map[string]string{
+		"mimetype":               "application/epub+zip",
+		"META-INF/container.xml": `<container><rootfiles><rootfile full-path="OEBPS/content.opf"/></rootfiles></container>`,
+		"OEBPS/content.opf": `<package xmlns:dc="http://purl.org/dc/elements/1.1/">
+<metadata><dc:title>The Sea</dc:title><dc:creator>Jane Roe</dc:creator><dc:creator>John Doe</dc:creator><dc:language>en-GB</dc:language></metadata>
+<manifest>
+<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+<item id="c1" href="text/chapter%201.xhtml" media-type="application/xhtml+xml"/>
+<item id="c2" href="text/c2.xhtml" media-type="application/xhtml+xml"/>
+</manifest>
+<spine><itemref idref="nav"/><itemref idref="c1"/><itemref idref="c2"/></spine>
+</package>`,
+		"OEBPS/nav.xhtml": `<html><body><nav><ol><li><a href="text/chapter%201.xhtml#start">Departure</a></li></ol></nav></body></html>`,
+		"OEBPS/text/chapter 1.xhtml": `<html><head><title>ignored</title><style>p{}</style></head>
+<body><h1>One</h1><p>The ship left&nbsp;the harbour.</p><script>var x;</script><p>Gulls followed.</p></body></html>`,
+		"OEBPS/text/c2.xhtml": `<html><body><h2>Landfall</h2><p>An island appeared.<br>Everyone cheered.</p></body></html>`,
+	})
+
+	reps, err := NewEPUBLoader(1000, 0).Load(context.Background(), "book.epub", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reps) != 2 {
+		t.Fatalf("expected one chunk per chapter, got %d", len(reps))
+	}
+
+	first, second := reps[0], reps[1]
+	if first.Meta["chapter"] != "Departure" || first.Meta["chapter_index"] != "1" {
+		t.Errorf("unexpected first chapter meta: %v", first.Meta)
+	}
+	if !strings.Contains(first.Text, "The ship left the harbour.\nGulls followed.") || strings.Contains(first.Text, "var x") {
+		t.Errorf("unexpected first chapter text: %q", first.Text)
+	}
+	// No TOC entry: falls back to the first heading.
+	if second.Meta["chapter"] != "Landfall" || second.Meta["chapter_index"] != "2" {
+		t.Errorf("unexpected second chapter meta: %v", second.Meta)
+	}
+	if !strings.Contains(second.Text, "An island appeared.\nEveryone cheered.") {
+		t.Errorf("unexpected second chapter text: %q", second.Text)
+	}
+	for _, r := range reps {
+		if r.Meta["title"] != "The Sea" || r.Meta["author"] != "Jane Roe, John Doe" || r.Meta["lang"] != "en" {
+			t.Errorf("missing book metadata: %v", r.Meta)
+		}
+	}
+}
+
+func TestEPUBLoader_NCX(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "old.epub")
+	writeZip(t, file, map[string]string{
+		"META-INF/container.xml": `<container><rootfiles><rootfile full-path="content.opf"/></rootfiles></container>`,
+		"content.opf": `<package><metadata><title>Old Book</title></metadata>
+<manifest><item id="ncx" href="toc.ncx"/><item id="a" href="a.html"/></manifest>
+<spine toc="ncx"><itemref idref="a"/></spine></package>`,
+		"toc.ncx": `<ncx><navMap><navPoint><navLabel><text>Prologue</text></navLabel><content src="a.html"/></navPoint></navMap></ncx>`,
+		"a.html":  `<html><body><p>Long ago.</p></body></html>`,
+	})
+
+	reps, err := NewEPUBLoader(1000, 0).Load(context.Background(), "old.epub", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reps) != 1 || reps[0].Meta["chapter"] != "Prologue" || reps[0].Meta["title"] != "Old Book" {
+		t.Fatalf("unexpected representations: %+v", reps)
+	}
+}
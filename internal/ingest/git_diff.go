@@ -0,0 +1,69 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// FileChange is a file that differs between a git revision and the working
+// tree.
+type FileChange struct {
+	Path    string // Slash-separated, relative to the directory given to ChangedSince
+	Deleted bool
+}
+
+// ChangedSince lists the files under dir that were added, modified or
+// deleted since ref, including uncommitted changes and untracked files that
+// are not ignored. Renames are reported as a deletion plus an addition.
+// Paths are relative to dir, and files outside it are left out, matching
+// what Crawl would find when run from dir.
+func ChangedSince(ctx context.Context, dir, ref string) ([]FileChange, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git not found on PATH")
+	}
+	if _, err := runGit(ctx, dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("unknown git revision %q", ref)
+	}
+	diff, err := runGit(ctx, dir, "diff", "--name-status", "--no-renames", "--relative", "-z", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := runGit(ctx, dir, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]bool{} // path -> deleted
+	fields := strings.Split(strings.TrimSuffix(string(diff), "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		changes[fields[i+1]] = fields[i] == "D"
+	}
+	for _, p := range strings.Split(string(untracked), "\x00") {
+		if p != "" {
+			changes[p] = false
+		}
+	}
+
+	out := make([]FileChange, 0, len(changes))
+	for p, deleted := range changes {
+		out = append(out, FileChange{Path: p, Deleted: deleted})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out, nil
+}
+
+// runGit runs git in dir and returns its standard output.
+func runGit(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, lastLine(stderr.Bytes()))
+	}
+	return out, nil
+}
@@ -0,0 +1,229 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+// GitPathPrefix marks representation paths that refer to commits rather than
+// files, e.g. "git:3f2a9c1d0b7e".
+const GitPathPrefix = "git:"
+
+// GitLoader indexes the history of a git repository: one representation per
+// commit holding its message, author, date and changed files, plus, with
+// diffs enabled, the patch split into further chunks. This makes questions
+// such as "when did we change the retry logic" answerable. It shells out to
+// the git CLI.
+type GitLoader struct {
+	chunkSize    int
+	diffs        bool
+	maxCommits   int
+	maxDiffBytes int
+}
+
+// NewGitLoader returns a GitLoader configured from the git section.
+func NewGitLoader(cfg config.GitConfig, chunkSize int) *GitLoader {
+	gl := &GitLoader{
+		chunkSize:    chunkSize,
+		diffs:        cfg.Diffs,
+		maxCommits:   cfg.MaxCommits,
+		maxDiffBytes: cfg.MaxDiffBytes,
+	}
+	if gl.chunkSize <= 0 {
+		gl.chunkSize = 1000
+	}
+	if gl.maxCommits <= 0 {
+		gl.maxCommits = 1000
+	}
+	if gl.maxDiffBytes <= 0 {
+		gl.maxDiffBytes = 8000
+	}
+	return gl
+}
+
+// gitCommit is one parsed entry of `git log`.
+type gitCommit struct {
+	hash, author, email string
+	date                time.Time
+	message             string
+	files               []string
+	diff                string
+}
+
+// Record and field separators in the `git log` format; neither can appear in
+// commit metadata.
+const (
+	gitRecordSep = "\x1e"
+	gitFieldSep  = "\x1f"
+)
+
+// LoadHistory returns representations for the most recent commits of the
+// repository containing dir. Outside a git repository, or without git
+// installed, it returns nothing.
+func (gl *GitLoader) LoadHistory(ctx context.Context, dir string) ([]Representation, error) {
+	slog.Info("Loading git history", "dir", dir, "max_commits", gl.maxCommits, "diffs", gl.diffs)
+
+	if _, err := exec.LookPath("git"); err != nil {
+		slog.Warn("Skipping git history: git not found on PATH")
+		return nil, nil
+	}
+	if err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--is-inside-work-tree").Run(); err != nil {
+		slog.Info("Skipping git history: not inside a git repository", "dir", dir)
+		return nil, nil
+	}
+
+	args := []string{"-C", dir, "log", "--no-color", "--no-merges", "-n", strconv.Itoa(gl.maxCommits),
+		"--format=" + gitRecordSep + strings.Join([]string{"%H", "%an", "%ae", "%aI", "%B"}, gitFieldSep) + gitFieldSep}
+	if gl.diffs {
+		args = append(args, "--patch", "--no-ext-diff")
+	} else {
+		args = append(args, "--name-only")
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// A repository without commits has no history to index.
+		if strings.Contains(stderr.String(), "does not have any commits") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("git log: %w: %s", err, lastLine(stderr.Bytes()))
+	}
+
+	commits := parseGitLog(string(out), gl.diffs)
+	var reps []Representation
+	for _, c := range commits {
+		reps = append(reps, gl.commitReps(c)...)
+	}
+	slog.Debug("Created", "commits", len(commits), "chunks", len(reps))
+	return reps, nil
+}
+
+// parseGitLog splits `git log` output produced with the format used by
+// LoadHistory. What follows the last field is the patch (with diffs) or the
+// list of changed files (with --name-only).
+func parseGitLog(out string, diffs bool) []gitCommit {
+	var commits []gitCommit
+	for _, record := range strings.Split(out, gitRecordSep) {
+		fields := strings.SplitN(record, gitFieldSep, 6)
+		if len(fields) < 6 {
+			continue
+		}
+		c := gitCommit{
+			hash:    strings.TrimSpace(fields[0]),
+			author:  fields[1],
+			email:   fields[2],
+			message: strings.TrimSpace(fields[4]),
+		}
+		c.date, _ = time.Parse(time.RFC3339, strings.TrimSpace(fields[3]))
+		rest := strings.TrimSpace(fields[5])
+		if diffs {
+			c.diff = rest
+			for _, line := range strings.Split(rest, "\n") {
+				if name, ok := strings.CutPrefix(line, "diff --git a/"); ok {
+					if i := strings.Index(name, " b/"); i >= 0 {
+						name = name[:i]
+					}
+					c.files = append(c.files, name)
+				}
+			}
+		} else if rest != "" {
+			c.files = strings.Split(rest, "\n")
+		}
+		commits = append(commits, c)
+	}
+	return commits
+}
+
+// maxCommitFiles caps the file list kept in metadata and the header.
+const maxCommitFiles = 50
+
+// commitReps renders a commit: the first representation carries the message
+// and header; diff chunks repeat the commit line so each is self-describing.
+func (gl *GitLoader) commitReps(c gitCommit) []Representation {
+	short := c.hash
+	if len(short) > 12 {
+		short = short[:12]
+	}
+	relPath := GitPathPrefix + short
+	subject, _, _ := strings.Cut(c.message, "\n")
+
+	files := c.files
+	if len(files) > maxCommitFiles {
+		files = append(files[:maxCommitFiles:maxCommitFiles], fmt.Sprintf("(+%d more)", len(c.files)-maxCommitFiles))
+	}
+	meta := func(kind string) map[string]string {
+		m := map[string]string{
+			"source":       "GitLoader",
+			"path":         relPath,
+			"kind":         kind,
+			"commit":       c.hash,
+			"author":       c.author,
+			"author_email": c.email,
+			"subject":      subject,
+		}
+		if !c.date.IsZero() {
+			m["date"] = c.date.UTC().Format(time.RFC3339)
+		}
+		if len(files) > 0 {
+			m["files"] = strings.Join(files, ",")
+		}
+		return m
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "commit %s\nAuthor: %s <%s>\n", short, c.author, c.email)
+	if !c.date.IsZero() {
+		fmt.Fprintf(&b, "Date: %s\n", c.date.Format("2006-01-02"))
+	}
+	b.WriteString("\n" + c.message + "\n")
+	if len(files) > 0 {
+		b.WriteString("\nFiles: " + strings.Join(files, ", ") + "\n")
+	}
+	reps := []Representation{{
+		ID:       ChunkID(relPath, "text", 0),
+		Path:     relPath,
+		Modality: "text",
+		Text:     strings.TrimSpace(b.String()),
+		Meta:     meta("message"),
+	}}
+
+	diff := c.diff
+	if diff == "" {
+		return reps
+	}
+	truncated := len(diff) > gl.maxDiffBytes
+	if truncated {
+		cut := gl.maxDiffBytes
+		if i := strings.LastIndexByte(diff[:cut], '\n'); i > 0 {
+			cut = i
+		}
+		diff = diff[:cut] + "\n[diff truncated]"
+	}
+	header := fmt.Sprintf("commit %s %s\n", short, subject)
+	chunker := &FixedChunker{Size: max(gl.chunkSize-len(header), gl.chunkSize/2)}
+	for _, span := range chunker.Spans(diff) {
+		m := meta("diff")
+		m["offset"] = strconv.Itoa(span.Start)
+		reps = append(reps, Representation{
+			ID:       ChunkID(relPath, "text", int64(len(reps))),
+			Path:     relPath,
+			Modality: "text",
+			Text:     header + span.Text,
+			Meta:     m,
+		})
+	}
+	if truncated {
+		reps[len(reps)-1].Meta[MetaTruncated] = "true"
+	}
+	return reps
+}
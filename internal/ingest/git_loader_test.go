@@ -0,0 +1,127 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=Ada", "-c", "user.email=ada@example.com"}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE=2024-03-04T10:00:00Z", "GIT_COMMITTER_DATE=2024-03-04T10:00:00Z")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, body string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("init", "-q")
+	write("client.go", "package client\n\nconst retries = 3\n")
+	run("add", ".")
+	run("commit", "-q", "-m", "Add HTTP client")
+	write("client.go", "package client\n\nconst retries = 5\n")
+	write("README.md", "docs\n")
+	run("add", ".")
+	run("commit", "-q", "-m", "Increase retry count\n\nBackoff was too aggressive for the upstream API.")
+	return dir
+}
+
+func TestGitLoader_History(t *testing.T) {
+	dir := gitRepo(t)
+
+	reps, err := NewGitLoader(config.GitConfig{}, 1000).LoadHistory(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reps) != 2 {
+		t.Fatalf("expected one representation per commit, got %d", len(reps))
+	}
+	latest := reps[0]
+	for _, want := range []string{"Author: Ada <ada@example.com>", "Date: 2024-03-04", "Increase retry count", "Backoff was too aggressive", "Files: README.md, client.go"} {
+		if !strings.Contains(latest.Text, want) {
+			t.Errorf("commit text missing %q:\n%s", want, latest.Text)
+		}
+	}
+	m := latest.Meta
+	if m["source"] != "GitLoader" || m["kind"] != "message" || m["author"] != "Ada" ||
+		m["date"] != "2024-03-04T10:00:00Z" || m["subject"] != "Increase retry count" || len(m["commit"]) != 40 {
+		t.Errorf("unexpected meta: %v", m)
+	}
+	if latest.Path != GitPathPrefix+m["commit"][:12] || m["path"] != latest.Path {
+		t.Errorf("unexpected path %q", latest.Path)
+	}
+
+	reps, err = NewGitLoader(config.GitConfig{Diffs: true, MaxCommits: 1}, 1000).LoadHistory(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reps) != 2 || reps[1].Meta["kind"] != "diff" {
+		t.Fatalf("expected message and diff for the latest commit, got %+v", reps)
+	}
+	if !strings.Contains(reps[1].Text, "+const retries = 5") || !strings.HasPrefix(reps[1].Text, "commit ") {
+		t.Errorf("unexpected diff chunk:\n%s", reps[1].Text)
+	}
+	if reps[0].Meta["files"] != "README.md,client.go" {
+		t.Errorf("expected files parsed from the patch, got %q", reps[0].Meta["files"])
+	}
+
+	reps, err = NewGitLoader(config.GitConfig{}, 1000).LoadHistory(context.Background(), t.TempDir())
+	if err != nil || len(reps) != 0 {
+		t.Errorf("outside a repository expected nothing, got %d reps, %v", len(reps), err)
+	}
+}
+
+func TestChangedSince(t *testing.T) {
+	dir := gitRepo(t)
+	ctx := context.Background()
+
+	changes, err := ChangedSince(ctx, dir, "HEAD~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FileChange{{Path: "README.md"}, {Path: "client.go"}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("since HEAD~1: got %+v, want %+v", changes, want)
+	}
+
+	// Uncommitted deletions and untracked files count; ignored files do not.
+	os.Remove(filepath.Join(dir, "client.go"))
+	os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "sub", "new.md"), []byte("new"), 0644)
+	os.WriteFile(filepath.Join(dir, "debug.log"), []byte("noise"), 0644)
+	os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*.log\n"), 0644)
+	changes, err = ChangedSince(ctx, dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []FileChange{{Path: ".gitignore"}, {Path: "client.go", Deleted: true}, {Path: "sub/new.md"}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("since HEAD: got %+v, want %+v", changes, want)
+	}
+
+	// Paths are relative to the directory, like the crawler's.
+	changes, err = ChangedSince(ctx, filepath.Join(dir, "sub"), "HEAD")
+	if err != nil || !reflect.DeepEqual(changes, []FileChange{{Path: "new.md"}}) {
+		t.Errorf("from sub/: got %+v, %v", changes, err)
+	}
+
+	if _, err := ChangedSince(ctx, dir, "no-such-ref"); err == nil {
+		t.Error("expected an error for an unknown revision")
+	}
+}
@@ -0,0 +1,164 @@
+package ingest
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// HCLLoader indexes Terraform and other HCL files one top-level block at a
+// time: each resource, data source, module, variable, output and so on
+// becomes a chunk with the comments above it, and records its block type,
+// labels and Terraform address in its metadata. Infrastructure teams can
+// then search for "the security group that opens port 5432" and land on
+// the resource. Attributes outside blocks, as in .tfvars files, make up a
+// chunk of their own. Files that do not parse are indexed as plain text.
+type HCLLoader struct {
+	chunkSize int
+}
+
+// NewHCLLoader returns an HCLLoader that cuts blocks longer than chunkSize
+// characters into several chunks.
+func NewHCLLoader(chunkSize int) *HCLLoader {
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	return &HCLLoader{chunkSize: chunkSize}
+}
+
+func (hl *HCLLoader) Extensions() []string {
+	return []string{".tf", ".tfvars", ".hcl"}
+}
+
+func (hl *HCLLoader) Load(ctx context.Context, relPath string, absPath string) ([]Representation, error) {
+	slog.Info("Loading HCL file", "relative_path", relPath, "absolute_path", absPath)
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		slog.Error("Failed to read file for HCLLoader", "path", absPath, "error", err)
+		return nil, err
+	}
+	src := string(data)
+
+	var reps []Representation
+	// add chunks text, found at offset off of src unless off is -1.
+	add := func(text string, off int, meta map[string]string) {
+		for _, span := range (&FixedChunker{Size: hl.chunkSize}).Spans(text) {
+			if strings.TrimSpace(span.Text) == "" {
+				continue
+			}
+			m := map[string]string{
+				"source": "HCLLoader",
+				"path":   relPath,
+			}
+			if off >= 0 {
+				m["line"] = strconv.Itoa(lineAt(src, off+span.Start))
+			}
+			for k, v := range meta {
+				m[k] = v
+			}
+			reps = append(reps, Representation{
+				ID:       ChunkID(relPath, "text", int64(len(reps))),
+				Path:     relPath,
+				Modality: "text",
+				Text:     strings.TrimSpace(span.Text),
+				Meta:     m,
+			})
+		}
+	}
+
+	f, diags := hclsyntax.ParseConfig(data, relPath, hcl.InitialPos)
+	if diags.HasErrors() {
+		slog.Warn("HCL file does not parse, indexing as plain text", "path", relPath, "error", diags.Error())
+		add(src, 0, nil)
+		return reps, nil
+	}
+	body, _ := f.Body.(*hclsyntax.Body)
+	if body == nil {
+		add(src, 0, nil)
+		return reps, nil
+	}
+
+	// The text between blocks makes up one chunk, whose lines only follow
+	// on those of the file if it all comes before the first.
+	type span struct{ start, end int }
+	blocks := make([]span, len(body.Blocks))
+	for i, b := range body.Blocks {
+		blocks[i] = span{hclLeadingComments(src, b.Range().Start.Byte), b.Range().End.Byte}
+	}
+	var gaps []string
+	restOff, prev := -1, 0
+	for _, b := range append(blocks, span{start: len(src)}) {
+		if gap := strings.TrimSpace(src[prev:b.start]); gap != "" {
+			if len(gaps) == 0 {
+				restOff = prev + strings.Index(src[prev:b.start], gap[:1])
+			} else {
+				restOff = -1
+			}
+			gaps = append(gaps, gap)
+		}
+		prev = b.end
+	}
+	if len(gaps) > 0 {
+		add(strings.Join(gaps, "\n\n"), restOff, map[string]string{"block_type": "attributes"})
+	}
+
+	for i, b := range body.Blocks {
+		add(src[blocks[i].start:blocks[i].end], blocks[i].start, hclBlockMeta(b))
+	}
+	slog.Debug("Created", "blocks", len(body.Blocks), "chunks", len(reps), "relPath", relPath)
+	return reps, nil
+}
+
+// hclBlockMeta returns the metadata of a top-level block: its type, its
+// labels as type and name (a resource "aws_instance" "web" has both, a
+// module "vpc" a name only), and the address Terraform refers to it by.
+func hclBlockMeta(b *hclsyntax.Block) map[string]string {
+	meta := map[string]string{"block_type": b.Type}
+	var typ, name string
+	switch len(b.Labels) {
+	case 0:
+		return meta
+	case 1:
+		name = b.Labels[0]
+	default:
+		typ, name = b.Labels[0], b.Labels[1]
+		meta["type"] = typ
+	}
+	meta["name"] = name
+	switch b.Type {
+	case "resource":
+		meta["address"] = typ + "." + name
+	case "data":
+		meta["address"] = "data." + typ + "." + name
+	case "module", "output":
+		meta["address"] = b.Type + "." + name
+	case "variable":
+		meta["address"] = "var." + name
+	}
+	return meta
+}
+
+// hclLeadingComments returns where the comment lines right above offset
+// start of src begin, start if there are none.
+func hclLeadingComments(src string, start int) int {
+	lineStart := strings.LastIndexByte(src[:start], '\n') + 1
+	if strings.TrimSpace(src[lineStart:start]) != "" {
+		return start
+	}
+	for lineStart > 0 {
+		prev := strings.LastIndexByte(src[:lineStart-1], '\n') + 1
+		line := strings.TrimSpace(src[prev : lineStart-1])
+		if !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "//") &&
+			!strings.HasPrefix(line, "/*") && !strings.HasPrefix(line, "*") {
+			break
+		}
+		start, lineStart = prev, prev
+	}
+	return start
+}
@@ -0,0 +1,100 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func loadHCL(t *testing.T, name, content string, chunkSize int) []Representation {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reps, err := NewHCLLoader(chunkSize).Load(context.Background(), name, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reps
+}
+
+func TestHCLLoader_Terraform(t *testing.T) {
+	tf := `terraform {
+  required_version = ">= 1.5"
+}
+
+variable "region" {
+  type    = string
+  default = "eu-west-1"
+}
+
+# Lets the application servers reach Postgres.
+# Only from inside the VPC.
+resource "aws_security_group" "db" {
+  name = "db"
+  ingress {
+    from_port   = 5432
+    to_port     = 5432
+    protocol    = "tcp"
+    cidr_blocks = [var.vpc_cidr]
+  }
+}
+
+data "aws_ami" "ubuntu" {
+  most_recent = true
+}
+
+module "vpc" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+
+output "db_sg_id" {
+  value = aws_security_group.db.id
+}
+`
+	reps := loadHCL(t, "main.tf", tf, 2000)
+	if len(reps) != 6 {
+		t.Fatalf("expected one chunk per block, got %d: %+v", len(reps), reps)
+	}
+	want := []map[string]string{
+		{"block_type": "terraform", "line": "1"},
+		{"block_type": "variable", "name": "region", "address": "var.region", "line": "5"},
+		{"block_type": "resource", "type": "aws_security_group", "name": "db", "address": "aws_security_group.db", "line": "10"},
+		{"block_type": "data", "type": "aws_ami", "name": "ubuntu", "address": "data.aws_ami.ubuntu"},
+		{"block_type": "module", "name": "vpc", "address": "module.vpc"},
+		{"block_type": "output", "name": "db_sg_id", "address": "output.db_sg_id", "line": "30"},
+	}
+	for i, w := range want {
+		for k, v := range w {
+			if reps[i].Meta[k] != v {
+				t.Errorf("chunk %d: meta %s = %q, want %q", i, k, reps[i].Meta[k], v)
+			}
+		}
+		if reps[i].Meta["source"] != "HCLLoader" || reps[i].Meta["path"] != "main.tf" {
+			t.Errorf("chunk %d: unexpected meta %v", i, reps[i].Meta)
+		}
+	}
+	sg := reps[2].Text
+	if !strings.HasPrefix(sg, "# Lets the application servers") || !strings.Contains(sg, "from_port   = 5432") || !strings.HasSuffix(sg, "}\n}") {
+		t.Errorf("resource chunk should run from its comments to its closing brace:\n%s", sg)
+	}
+	if _, ok := reps[0].Meta["name"]; ok {
+		t.Errorf("unlabelled block should have no name: %v", reps[0].Meta)
+	}
+}
+
+func TestHCLLoader_AttributesAndInvalid(t *testing.T) {
+	reps := loadHCL(t, "prod.tfvars", "region = \"eu-west-1\"\ninstance_count = 3\n", 1000)
+	if len(reps) != 1 || reps[0].Meta["block_type"] != "attributes" || reps[0].Meta["line"] != "1" ||
+		reps[0].Text != "region = \"eu-west-1\"\ninstance_count = 3" {
+		t.Errorf("unexpected tfvars chunks: %+v", reps)
+	}
+
+	reps = loadHCL(t, "broken.hcl", "job \"web\" {\n  group = {{ .Group }}\n", 1000)
+	if len(reps) != 1 || !strings.Contains(reps[0].Text, "{{ .Group }}") || reps[0].Meta["block_type"] != "" {
+		t.Errorf("a file that does not parse should be indexed as text: %+v", reps)
+	}
+}
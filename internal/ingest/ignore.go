@@ -0,0 +1,160 @@
+package ingest
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// SemangoIgnoreFile lists paths to leave out of the index, in .gitignore
+// syntax, in the directory it is in and below. It is read whether or not
+// files.respect_gitignore is set, after the .gitignore of its directory.
+const SemangoIgnoreFile = ".semangoignore"
+
+const gitIgnoreFile = ".gitignore"
+
+// ignoreRule is a line of an ignore file.
+type ignoreRule struct {
+	pattern  string
+	negate   bool // "!pattern" re-includes what earlier rules ignore
+	dirOnly  bool // "pattern/" only matches directories
+	anchored bool // a pattern with a slash is relative to the file's directory
+	source   string
+}
+
+// match reports whether the rule matches p, a path relative to the
+// directory of its ignore file.
+func (r ignoreRule) match(p string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if !r.anchored {
+		p = path.Base(p)
+	}
+	ok, _ := doublestar.Match(r.pattern, p)
+	return ok
+}
+
+// parseIgnoreLine parses a line of the ignore file source; ok is false
+// for blank lines and comments.
+func parseIgnoreLine(line, source string) (r ignoreRule, ok bool) {
+	line = strings.TrimSuffix(line, "\r")
+	if !strings.HasSuffix(line, `\ `) {
+		line = strings.TrimRight(line, " \t")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return r, false
+	}
+	r.source = source + ": " + line
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return r, false
+	}
+	r.pattern = line
+	return r, true
+}
+
+// IgnoreMatcher applies the .gitignore and .semangoignore files of a
+// directory tree the way git does: the rules of a file apply to its
+// directory and below, those of deeper files and later lines win, and
+// nothing inside an ignored directory can be re-included. Files are read
+// once, when first needed. Ignore files above the root, global ones and
+// .git/info/exclude are not read. It is not safe for concurrent use.
+type IgnoreMatcher struct {
+	root  string
+	names []string                // ignore files read in each directory, in order
+	dirs  map[string][]ignoreRule // by slash-separated directory, "" for the root
+}
+
+// NewIgnoreMatcher returns a matcher for the tree at root that reads
+// .semangoignore files, and .gitignore files too if gitignore is set.
+func NewIgnoreMatcher(root string, gitignore bool) *IgnoreMatcher {
+	m := &IgnoreMatcher{root: root, names: []string{SemangoIgnoreFile}, dirs: map[string][]ignoreRule{}}
+	if gitignore {
+		m.names = []string{gitIgnoreFile, SemangoIgnoreFile}
+	}
+	return m
+}
+
+// rules returns the rules of the ignore files in dir.
+func (m *IgnoreMatcher) rules(dir string) []ignoreRule {
+	if rules, ok := m.dirs[dir]; ok {
+		return rules
+	}
+	var rules []ignoreRule
+	for _, name := range m.names {
+		rel := path.Join(dir, name)
+		f, err := os.Open(filepath.Join(m.root, filepath.FromSlash(rel)))
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			if r, ok := parseIgnoreLine(sc.Text(), rel); ok {
+				rules = append(rules, r)
+			}
+		}
+		f.Close()
+	}
+	m.dirs[dir] = rules
+	return rules
+}
+
+// match applies the rules of the ignore files of the directories above
+// the slash-separated path p to it, without checking whether one of those
+// directories is ignored, as the crawler does not walk into them anyway.
+// rule is the ignore file and line that ignores p.
+func (m *IgnoreMatcher) match(p string, isDir bool) (rule string, ignored bool) {
+	dir := ""
+	for {
+		rel := p
+		if dir != "" {
+			rel = strings.TrimPrefix(p, dir+"/")
+		}
+		for _, r := range m.rules(dir) {
+			if r.match(rel, isDir) {
+				rule, ignored = r.source, !r.negate
+			}
+		}
+		next, _, found := strings.Cut(rel, "/")
+		if !found {
+			break
+		}
+		dir = path.Join(dir, next)
+	}
+	if !ignored {
+		rule = ""
+	}
+	return rule, ignored
+}
+
+// Match reports whether the slash-separated path p, relative to the root,
+// is ignored, itself or by one of its directories, and by which ignore
+// file and line.
+func (m *IgnoreMatcher) Match(p string, isDir bool) (rule string, ignored bool) {
+	for i := 0; i < len(p); i++ {
+		if p[i] == '/' {
+			if rule, ignored := m.match(p[:i], true); ignored {
+				return rule, true
+			}
+		}
+	}
+	return m.match(p, isDir)
+}
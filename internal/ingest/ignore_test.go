@@ -0,0 +1,121 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, body := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":          "# build output\n*.log\n!keep.log\nbuild/\n/dist\n.venv\n",
+		"docs/.gitignore":     "drafts/**\n\\#notes.md\n",
+		"docs/.semangoignore": "secret.md\n!important.log\n",
+	})
+	m := NewIgnoreMatcher(dir, true)
+	for _, tc := range []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"src/deep/app.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false}, // a file called build
+		{"src/build", true, true},
+		{"dist", true, true},
+		{"src/dist", true, false}, // anchored to the root
+		{".venv", true, true},
+		{"docs/drafts/a.md", false, true},
+		{"drafts/a.md", false, false},
+		{"docs/#notes.md", false, true},
+		{"docs/secret.md", false, true},
+		{"secret.md", false, false},
+		{"docs/important.log", false, false}, // re-included by a deeper file
+		{"build/out.md", false, true},        // inside an ignored directory
+		{"README.md", false, false},
+	} {
+		if _, got := m.Match(tc.path, tc.isDir); got != tc.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tc.path, tc.isDir, got, tc.want)
+		}
+	}
+	if rule, _ := m.Match("src/app.log", false); rule != ".gitignore: *.log" {
+		t.Errorf("rule = %q", rule)
+	}
+
+	// Without respect_gitignore only .semangoignore files apply.
+	m = NewIgnoreMatcher(dir, false)
+	if _, ok := m.Match("app.log", false); ok {
+		t.Error(".gitignore read with gitignore off")
+	}
+	if _, ok := m.Match("docs/secret.md", false); !ok {
+		t.Error(".semangoignore not read with gitignore off")
+	}
+}
+
+func TestCrawlDirIgnoreFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":               "node_modules/\n*.gen.md\n",
+		"a.md":                     "a",
+		"b.gen.md":                 "generated",
+		"node_modules/pkg/read.md": "dependency",
+		"sub/.semangoignore":       "private.md\n",
+		"sub/private.md":           "private",
+		"sub/c.md":                 "c",
+	})
+	crawl := func(files config.FilesConfig) []string {
+		paths := make(chan string, 10)
+		errs := make(chan error, 1)
+		go CrawlDir(dir, files, paths, errs)
+		var got []string
+		for p := range paths {
+			got = append(got, p)
+		}
+		sort.Strings(got)
+		return got
+	}
+	files := config.FilesConfig{Include: []string{"**/*.md"}, RespectGitignore: true}
+	if got, want := crawl(files), []string{"a.md", "sub/c.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("crawled %v, want %v", got, want)
+	}
+	files.RespectGitignore = false
+	if got, want := crawl(files), []string{"a.md", "b.gen.md", "node_modules/pkg/read.md", "sub/c.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("crawled without respect_gitignore %v, want %v", got, want)
+	}
+
+	files.RespectGitignore = true
+	skipped := map[string]string{}
+	if err := SkippedFiles(dir, files, func(relPath, pattern string) { skipped[relPath] = pattern }); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		".gitignore":         "",
+		"b.gen.md":           ".gitignore: *.gen.md",
+		"node_modules/":      ".gitignore: node_modules/",
+		"sub/.semangoignore": "",
+		"sub/private.md":     "sub/.semangoignore: private.md",
+	}
+	if !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped %v, want %v", skipped, want)
+	}
+}
@@ -0,0 +1,22 @@
+package ingest
+
+import "strings"
+
+// NormalizeLang reduces a language tag such as "de-CH" or "PT_br" to its
+// lower-case primary subtag ("de", "pt"). Anything that is not a 2-3 letter
+// code yields "".
+func NormalizeLang(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if len(tag) < 2 || len(tag) > 3 {
+		return ""
+	}
+	for _, c := range tag {
+		if c < 'a' || c > 'z' {
+			return ""
+		}
+	}
+	return tag
+}
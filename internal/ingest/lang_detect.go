@@ -0,0 +1,207 @@
+package ingest
+
+import (
+	"strings"
+	"unicode"
+)
+
+// detectSample bounds the bytes of text DetectLanguage looks at.
+const detectSample = 16 << 10
+
+// minDetectWords is the least number of words DetectLanguage decides on for
+// text in the Latin script, whose languages are told apart by their
+// function words.
+const minDetectWords = 8
+
+// functionWords lists frequent short words of the languages in the Latin
+// script that have a lexical analyzer. Words shared by several languages
+// count for each of them; the others decide.
+var functionWords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "as", "was", "on", "are", "this", "be", "by", "not", "or", "from", "have", "which", "you", "at", "an"},
+	"fr": {"le", "la", "les", "et", "des", "est", "un", "une", "du", "que", "qui", "dans", "pour", "pas", "sur", "au", "avec", "ce", "il", "elle", "sont", "ne", "se", "nous", "vous", "aux"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "ein", "eine", "zu", "den", "mit", "sich", "des", "auf", "für", "im", "dem", "von", "auch", "es", "wird", "sind", "oder", "wie", "bei"},
+	"es": {"el", "los", "las", "y", "que", "es", "en", "un", "una", "por", "con", "para", "del", "se", "no", "su", "lo", "como", "más", "pero", "al", "está", "son", "sus", "este"},
+	"pt": {"o", "os", "as", "e", "que", "é", "um", "uma", "não", "com", "para", "do", "da", "dos", "das", "em", "no", "na", "se", "por", "mais", "como", "mas", "ao", "são"},
+	"it": {"il", "lo", "gli", "le", "e", "che", "è", "di", "un", "una", "non", "per", "con", "del", "della", "sono", "nel", "alla", "si", "da", "come", "anche", "più", "ma", "questo"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "te", "zijn", "voor", "met", "die", "ook", "als", "aan", "er", "maar", "om", "bij", "door", "wordt", "naar", "ik"},
+	"sv": {"och", "att", "det", "som", "en", "är", "av", "för", "på", "med", "inte", "till", "den", "har", "de", "jag", "om", "ett", "var", "men", "så", "vi", "kan", "eller", "från"},
+	"da": {"og", "at", "det", "er", "en", "til", "på", "som", "med", "af", "for", "ikke", "den", "har", "de", "et", "der", "jeg", "var", "fra", "men", "kan", "om", "eller", "skal"},
+	"no": {"og", "i", "det", "er", "en", "til", "på", "som", "med", "av", "for", "ikke", "den", "har", "de", "et", "jeg", "var", "fra", "men", "kan", "om", "eller", "skal", "ble"},
+	"fi": {"ja", "on", "ei", "se", "että", "oli", "hän", "mutta", "kun", "tai", "joka", "niin", "ovat", "tämä", "myös", "sen", "kuin", "ole", "mitä", "jos", "olla", "sitä"},
+	"hu": {"a", "az", "és", "hogy", "nem", "is", "egy", "van", "meg", "de", "el", "ez", "csak", "már", "mint", "vagy", "volt", "ki", "fel", "még", "azt", "kell"},
+	"ro": {"și", "în", "de", "la", "că", "nu", "pe", "cu", "o", "un", "este", "sunt", "care", "din", "mai", "pentru", "se", "ce", "a", "lui", "ale", "sau", "dar"},
+	"tr": {"ve", "bir", "bu", "da", "de", "için", "ile", "ne", "olarak", "çok", "daha", "gibi", "ama", "var", "olan", "değil", "en", "kadar", "sonra", "her", "mi"},
+}
+
+// wordLangs maps each function word to its languages.
+var wordLangs = func() map[string][]string {
+	m := map[string][]string{}
+	for lang, words := range functionWords {
+		for _, w := range words {
+			m[w] = append(m[w], lang)
+		}
+	}
+	return m
+}()
+
+// DetectLanguage returns the ISO 639-1 code of the language text is
+// written in, or "" when it cannot tell. Scripts used by one language, or
+// by languages told apart by their letters (Arabic, Persian and Sorani
+// Kurdish; Chinese, Japanese and Korean), decide on their own; text in the
+// Latin script is matched against the function words of the languages with
+// a lexical analyzer and needs a few sentences to be recognised. Code,
+// numbers and short strings yield "".
+func DetectLanguage(text string) string {
+	if len(text) > detectSample {
+		text = text[:detectSample]
+	}
+	var letters, latin, arabic, cyrillic, greek, hebrew, devanagari, thai, han, kana, hangul int
+	var sorani, persian int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+			switch r {
+			case 'ڕ', 'ڵ', 'ۆ', 'ێ', 'ە':
+				sorani++
+			case 'پ', 'چ', 'ژ', 'گ', 'ی', 'ک':
+				persian++
+			}
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Greek, r):
+			greek++
+		case unicode.Is(unicode.Hebrew, r):
+			hebrew++
+		case unicode.Is(unicode.Devanagari, r):
+			devanagari++
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	dominant := func(n int) bool { return 2*n > letters }
+	switch {
+	case dominant(arabic):
+		switch {
+		case 20*sorani > arabic:
+			return "ckb"
+		case 20*persian > arabic:
+			return "fa"
+		}
+		return "ar"
+	case dominant(han + kana):
+		if 10*kana > han+kana {
+			return "ja"
+		}
+		return "zh"
+	case dominant(hangul):
+		return "ko"
+	case dominant(cyrillic):
+		return "ru"
+	case dominant(greek):
+		return "el"
+	case dominant(hebrew):
+		return "he"
+	case dominant(devanagari):
+		return "hi"
+	case dominant(thai):
+		return "th"
+	case dominant(latin):
+		return detectLatin(text)
+	}
+	return ""
+}
+
+// detectLatin returns the language whose function words text uses most, if
+// it uses them clearly more than any other language's.
+func detectLatin(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) < minDetectWords {
+		return ""
+	}
+	hits := map[string]int{}
+	for _, w := range words {
+		for _, lang := range wordLangs[w] {
+			hits[lang]++
+		}
+	}
+	best := ""
+	for lang, n := range hits {
+		if n > hits[best] || n == hits[best] && lang < best {
+			best = lang
+		}
+	}
+	first, second := hits[best], 0
+	for lang, n := range hits {
+		if lang != best {
+			second = max(second, n)
+		}
+	}
+	// Function words make up a good share of running text; a handful in
+	// a long list of identifiers decides nothing.
+	if 10*first < len(words) || 4*first < 5*second {
+		return ""
+	}
+	return best
+}
+
+// DetectLanguages sets the "lang" metadata of the text chunks of a
+// document that have none, leaving code alone: to the language of the
+// chunk when DetectLanguage can tell, otherwise to that of the document,
+// detected from the start of its text.
+func DetectLanguages(reps []Representation) {
+	var sample strings.Builder
+	var pending []int
+	for i, r := range reps {
+		if r.Modality != "text" || r.Text == "" || r.Meta["lang"] != "" || isCode(r.Meta) {
+			continue
+		}
+		pending = append(pending, i)
+		if sample.Len() < detectSample {
+			sample.WriteString(r.Text)
+			sample.WriteByte('\n')
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+	doc := DetectLanguage(sample.String())
+	for _, i := range pending {
+		lang := DetectLanguage(reps[i].Text)
+		if lang == "" {
+			lang = doc
+		}
+		if lang == "" {
+			continue
+		}
+		// Loaders may share one map between the chunks of a document.
+		meta := make(map[string]string, len(reps[i].Meta)+1)
+		for k, v := range reps[i].Meta {
+			meta[k] = v
+		}
+		meta["lang"] = lang
+		reps[i].Meta = meta
+	}
+}
+
+// isCode reports whether a chunk holds source code rather than prose.
+func isCode(meta map[string]string) bool {
+	return meta["source"] == "CodeLoader" || meta["cell_type"] == "code"
+}
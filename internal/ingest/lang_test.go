@@ -0,0 +1,64 @@
+package ingest
+
+import "testing"
+
+func TestNormalizeLang(t *testing.T) {
+	cases := map[string]string{
+		"de":      "de",
+		"pt-BR":   "pt",
+		"ZH_hant": "zh",
+		" en ":    "en",
+		"ckb":     "ckb",
+		"":        "",
+		"e":       "",
+		"german":  "",
+		"d3":      "",
+	}
+	for in, want := range cases {
+		if got := NormalizeLang(in); got != want {
+			t.Errorf("NormalizeLang(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	cases := map[string]string{
+		"The index is rebuilt when the analyzers change, and the vectors are kept as they are.":         "en",
+		"Le fichier est indexé dans la base, et les vecteurs sont conservés pour la recherche.":         "fr",
+		"Die Datei wird in den Index aufgenommen, und die Vektoren werden nicht neu berechnet.":         "de",
+		"El archivo se indexa en la base y los vectores se conservan para la búsqueda de los usuarios.": "es",
+		"يتم فهرسة الملف في قاعدة البيانات ويتم الاحتفاظ بالمتجهات":                                     "ar",
+		"این فایل در پایگاه داده نمایه می‌شود و بردارها نگه داشته می‌شوند":                              "fa",
+		"ファイルはインデックスに登録されます":                                                                            "ja",
+		"文件被索引到数据库中":                             "zh",
+		"Файл индексируется в базе данных":       "ru",
+		"getUserByID(ctx, id) returns user, err": "",
+		"1234 5678":                              "",
+	}
+	for text, want := range cases {
+		if got := DetectLanguage(text); got != want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestDetectLanguages(t *testing.T) {
+	shared := map[string]string{"path": "notes.md"}
+	reps := []Representation{
+		{Modality: "text", Text: "Le fichier est indexé dans la base, et les vecteurs sont conservés pour la recherche.", Meta: shared},
+		{Modality: "text", Text: "Voir aussi", Meta: shared},
+		{Modality: "text", Text: "Les résultats sont triés par pertinence, et la page affiche un extrait de chaque document.", Meta: shared},
+		{Modality: "text", Text: "The index is rebuilt when the analyzers change, and the vectors are kept as they are.", Meta: shared},
+		{Modality: "text", Text: "Der Text ist schon markiert.", Meta: map[string]string{"lang": "de"}},
+		{Modality: "text", Text: "func main() { fmt.Println(\"le la les et des\") }", Meta: map[string]string{"source": "CodeLoader"}},
+	}
+	DetectLanguages(reps)
+	for i, want := range []string{"fr", "fr", "fr", "en", "de", ""} {
+		if got := reps[i].Meta["lang"]; got != want {
+			t.Errorf("chunk %d: lang %q, want %q", i, got, want)
+		}
+	}
+	if _, ok := shared["lang"]; ok {
+		t.Error("the loader's shared metadata was changed")
+	}
+}
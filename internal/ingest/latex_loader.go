@@ -0,0 +1,343 @@
+package ingest
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// LaTeXLoader indexes LaTeX sources one section at a time, with the markup
+// stripped: commands are dropped and the text of their arguments kept,
+// comments are left out and verbatim environments kept as they are. Like
+// MarkdownLoader, every chunk records the breadcrumb of sectioning
+// commands it belongs to, and the \label names in its section, so that
+// papers and theses can be searched by what they say rather than by their
+// macros. The \title and \author of the document go into the metadata of
+// every chunk; the rest of the preamble is left out.
+type LaTeXLoader struct {
+	chunkSize int
+	overlap   int
+	chunking  Chunking
+}
+
+// NewLaTeXLoader returns a LaTeXLoader. Sections longer than chunkSize are
+// split further using the same word-boundary strategy as TextLoader.
+func NewLaTeXLoader(chunkSize, overlap int) *LaTeXLoader {
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	if overlap < 0 {
+		overlap = 0
+	}
+	return &LaTeXLoader{chunkSize: chunkSize, overlap: overlap}
+}
+
+// SetChunking replaces the fixed-size chunks of ll with c.
+func (ll *LaTeXLoader) SetChunking(c Chunking) { ll.chunking = c }
+
+func (ll *LaTeXLoader) Extensions() []string {
+	return []string{".tex", ".ltx"}
+}
+
+func (ll *LaTeXLoader) Load(ctx context.Context, relPath string, absPath string) ([]Representation, error) {
+	slog.Info("Loading LaTeX file", "relative_path", relPath, "absolute_path", absPath)
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		slog.Error("Failed to read file for LaTeXLoader", "path", absPath, "error", err)
+		return nil, err
+	}
+	doc := parseLaTeX(string(data))
+	reps := doc.representations(relPath, "LaTeXLoader", ll.chunking.chunker(ll.chunkSize, ll.overlap))
+	slog.Debug("Created", "sections", len(doc.headings), "chunks", len(reps), "relPath", relPath)
+	return reps, nil
+}
+
+// latexSections are the ranks of the sectioning commands.
+var latexSections = map[string]int{
+	"part": 0, "chapter": 1, "section": 2, "subsection": 3, "subsubsection": 4,
+	"paragraph": 5, "subparagraph": 6,
+}
+
+// latexDropped are the commands whose arguments are not text, by the
+// number of mandatory arguments they take.
+var latexDropped = map[string]int{
+	"documentclass": 1, "usepackage": 1, "RequirePackage": 1, "input": 1, "include": 1,
+	"includegraphics": 1, "bibliography": 1, "bibliographystyle": 1, "addbibresource": 1,
+	"graphicspath": 1, "hypersetup": 1, "pagestyle": 1, "thispagestyle": 1, "pagenumbering": 1,
+	"vspace": 1, "hspace": 1, "thanks": 1, "nocite": 1, "setcounter": 2, "setlength": 2, "addtolength": 2,
+	"newcommand": 2, "renewcommand": 2, "providecommand": 2, "DeclareMathOperator": 2,
+	"newenvironment": 3, "renewenvironment": 3, "newtheorem": 2,
+}
+
+// latexRefs are the cross-reference and citation commands, whose keys are
+// kept in brackets.
+var latexRefs = map[string]bool{
+	"ref": true, "eqref": true, "pageref": true, "autoref": true, "cref": true, "Cref": true,
+	"cite": true, "citep": true, "citet": true, "parencite": true, "textcite": true, "autocite": true,
+}
+
+// latexEnvArgs are the environments whose \begin takes arguments that are
+// not text, by their number.
+var latexEnvArgs = map[string]int{
+	"tabular": 1, "tabular*": 2, "tabularx": 2, "array": 1, "minipage": 1,
+	"multicols": 1, "thebibliography": 1, "wrapfigure": 2, "minted": 1,
+}
+
+// latexVerbatim are the environments whose content is kept as it is, or
+// left out for comment.
+var latexVerbatim = map[string]bool{
+	"verbatim": true, "verbatim*": true, "Verbatim": true, "lstlisting": true, "minted": true,
+	"comment": true,
+}
+
+// latexParser strips the markup of a LaTeX source line by line.
+type latexParser struct {
+	src      string
+	pos      int
+	cur      strings.Builder // the text of the current line
+	pending  int             // newlines read in arguments, yet to end lines
+	doc      markupDoc
+	docStart int // line of \begin{document}, -1 if none was read
+	done     bool
+}
+
+func parseLaTeX(src string) *markupDoc {
+	p := &latexParser{src: src, docStart: -1}
+	p.doc.meta = map[string]string{}
+	p.run()
+	// The preamble holds settings, not text.
+	for i := 0; i < p.docStart; i++ {
+		p.doc.lines[i] = ""
+	}
+	return &p.doc
+}
+
+// latexText returns the text of the LaTeX fragment s on one line.
+func latexText(s string) string {
+	p := &latexParser{src: s, docStart: -1}
+	p.run()
+	return collapseSpaces(strings.Join(p.doc.lines, " "))
+}
+
+func (p *latexParser) run() {
+	for p.pos < len(p.src) && !p.done {
+		switch c := p.src[p.pos]; c {
+		case '\n':
+			p.newline()
+			p.pos++
+		case '%':
+			if i := strings.IndexByte(p.src[p.pos:], '\n'); i >= 0 {
+				p.pos += i
+			} else {
+				p.pos = len(p.src)
+			}
+		case '{', '}', '$':
+			p.pos++
+		case '~', '&':
+			p.cur.WriteByte(' ')
+			p.pos++
+		case '\\':
+			p.command()
+			for ; p.pending > 0; p.pending-- {
+				p.newline()
+			}
+		default:
+			p.cur.WriteByte(c)
+			p.pos++
+		}
+	}
+	p.newline()
+}
+
+// line returns the index of the current line.
+func (p *latexParser) line() int { return len(p.doc.lines) }
+
+func (p *latexParser) newline() {
+	p.doc.lines = append(p.doc.lines, collapseSpaces(p.cur.String()))
+	p.cur.Reset()
+}
+
+// command reads the command at p.pos.
+func (p *latexParser) command() {
+	p.pos++
+	if p.pos >= len(p.src) {
+		return
+	}
+	if c := p.src[p.pos]; !isLetter(c) {
+		p.pos++
+		switch c {
+		case '\n':
+			p.newline()
+		case '\\', ',', ';', ':', '!', ' ':
+			p.cur.WriteByte(' ')
+		case '[', ']', '(', ')':
+			// Math delimiters.
+		default:
+			// Escaped characters such as \% and \&, and accents.
+			p.cur.WriteByte(c)
+		}
+		return
+	}
+	start := p.pos
+	for p.pos < len(p.src) && isLetter(p.src[p.pos]) {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	if p.pos < len(p.src) && p.src[p.pos] == '*' {
+		p.pos++
+	}
+
+	if rank, ok := latexSections[name]; ok {
+		line := p.line()
+		p.optArgs()
+		title := latexText(p.arg())
+		p.doc.headings = append(p.doc.headings, markupHeading{line: line, rank: rank, title: title})
+		p.cur.WriteString(" " + title + " ")
+		return
+	}
+	if latexRefs[name] {
+		p.optArgs()
+		p.cur.WriteString("[" + strings.TrimSpace(p.arg()) + "]")
+		return
+	}
+	if n, ok := latexDropped[name]; ok {
+		p.optArgs()
+		for i := 0; i < n; i++ {
+			p.arg()
+			p.optArgs()
+		}
+		return
+	}
+	switch name {
+	case "begin":
+		p.begin(strings.TrimSpace(p.arg()))
+	case "end":
+		if strings.TrimSpace(p.arg()) == "document" {
+			p.done = true
+		}
+	case "label":
+		for _, l := range strings.Split(p.arg(), ",") {
+			if l = strings.TrimSpace(l); l != "" {
+				p.doc.labels = append(p.doc.labels, markupLabel{line: p.line(), name: l})
+			}
+		}
+	case "title", "author", "date":
+		p.optArgs()
+		if v := latexText(p.arg()); v != "" && p.doc.meta != nil {
+			p.doc.meta[name] = v
+		}
+	case "and":
+		s := strings.TrimRight(p.cur.String(), " ")
+		p.cur.Reset()
+		p.cur.WriteString(s + ", ")
+	case "item":
+		p.cur.WriteString(" - ")
+	case "LaTeX", "TeX":
+		p.cur.WriteString(name)
+	case "ldots", "dots":
+		p.cur.WriteString("...")
+	case "maketitle", "tableofcontents", "newpage", "clearpage", "centering", "noindent", "par":
+		p.cur.WriteByte(' ')
+	default:
+		// The arguments of other commands, as \emph{...}, are text.
+		p.optArgs()
+	}
+}
+
+// begin reads the rest of the \begin of environment env.
+func (p *latexParser) begin(env string) {
+	if env == "document" {
+		p.docStart = p.line()
+		return
+	}
+	p.optArgs()
+	for i := 0; i < latexEnvArgs[env]; i++ {
+		p.arg()
+	}
+	if !latexVerbatim[env] {
+		return
+	}
+	body := p.src[p.pos:]
+	if i := strings.Index(body, `\end{`+env+`}`); i >= 0 {
+		body = body[:i]
+		p.pos += i + len(`\end{`+env+`}`)
+	} else {
+		p.pos = len(p.src)
+	}
+	if env == "comment" {
+		p.pending += strings.Count(body, "\n")
+		return
+	}
+	for ; p.pending > 0; p.pending-- {
+		p.newline()
+	}
+	lines := strings.Split(body, "\n")
+	for i, l := range lines {
+		if i > 0 {
+			p.newline()
+		}
+		p.cur.WriteString(l)
+	}
+}
+
+// arg reads a mandatory argument and returns its source: what is between
+// braces, or else the next command or character.
+func (p *latexParser) arg() string {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return ""
+	}
+	start := p.pos
+	switch p.src[p.pos] {
+	case '{':
+		return p.group('{', '}')
+	case '\\':
+		p.pos++
+		for p.pos < len(p.src) && isLetter(p.src[p.pos]) {
+			p.pos++
+		}
+		if p.pos == start+1 && p.pos < len(p.src) {
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// optArgs skips the optional arguments at p.pos.
+func (p *latexParser) optArgs() {
+	for p.pos < len(p.src) && p.src[p.pos] == '[' {
+		p.group('[', ']')
+	}
+}
+
+// group reads the text between open at p.pos and the close matching it,
+// and returns it. The lines it spans are ended after the command.
+func (p *latexParser) group(open, close byte) string {
+	depth := 0
+	start := p.pos + 1
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos++
+		case open:
+			depth++
+		case close:
+			if depth--; depth == 0 {
+				s := p.src[start:p.pos]
+				p.pos++
+				p.pending += strings.Count(s, "\n")
+				return s
+			}
+		}
+	}
+	p.pos = len(p.src)
+	s := p.src[start:]
+	p.pending += strings.Count(s, "\n")
+	return s
+}
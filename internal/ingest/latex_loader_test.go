@@ -0,0 +1,117 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func loadMarkup(t *testing.T, l Loader, name, content string) []Representation {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reps, err := l.Load(context.Background(), name, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reps
+}
+
+func TestLaTeXLoader_Sections(t *testing.T) {
+	tex := `\documentclass[11pt]{article}
+\usepackage{amsmath}
+\title{Sparse Retrieval \\ Revisited}
+\author{Ada Lovelace \and Alan Turing\thanks{Equal contribution.}}
+\begin{document}
+\maketitle
+
+\begin{abstract}
+We revisit \emph{sparse} retrieval.
+\end{abstract}
+
+\section{Introduction}\label{sec:intro}
+Dense models dominate~\cite{karpukhin2020}. % TODO: more citations
+See Section~\ref{sec:method} and 50\% of \textbf{the} results.
+
+\section*{Method}
+\label{sec:method}
+\subsection[Short]{Index
+  Construction}
+We build the index as in Equation~\eqref{eq:bm25}:
+\begin{equation}\label{eq:bm25}
+  s = \sum_i w_i
+\end{equation}
+\begin{verbatim}
+\section{not a section} % kept
+\end{verbatim}
+\begin{comment}
+Left out.
+\end{comment}
+Done.
+\end{document}
+Ignored after the end.
+`
+	reps := loadMarkup(t, NewLaTeXLoader(2000, 0), "paper.tex", tex)
+	if len(reps) != 3 {
+		t.Fatalf("expected 3 sections with text, got %d: %+v", len(reps), reps)
+	}
+	for _, r := range reps {
+		if r.Meta["title"] != "Sparse Retrieval Revisited" || r.Meta["author"] != "Ada Lovelace, Alan Turing" {
+			t.Errorf("unexpected document meta: %v", r.Meta)
+		}
+		if r.Meta["source"] != "LaTeXLoader" || r.Meta["kind"] != "section" {
+			t.Errorf("unexpected meta: %v", r.Meta)
+		}
+	}
+
+	if reps[0].Text != "We revisit sparse retrieval." || reps[0].Meta["line"] != "9" || reps[0].Meta["heading"] != "" {
+		t.Errorf("unexpected abstract chunk: %q %v", reps[0].Text, reps[0].Meta)
+	}
+
+	intro := reps[1]
+	want := "Introduction\nDense models dominate [karpukhin2020].\nSee Section [sec:method] and 50% of the results."
+	if intro.Text != want {
+		t.Errorf("intro text = %q, want %q", intro.Text, want)
+	}
+	if intro.Meta["heading"] != "Introduction" || intro.Meta["heading_level"] != "1" ||
+		intro.Meta["labels"] != "sec:intro" || intro.Meta["line"] != "12" {
+		t.Errorf("unexpected intro meta: %v", intro.Meta)
+	}
+
+	sub := reps[2]
+	if sub.Meta["breadcrumb"] != "Method > Index Construction" || sub.Meta["heading_level"] != "2" ||
+		sub.Meta["labels"] != "sec:method,eq:bm25" || sub.Meta["line"] != "18" {
+		t.Errorf("unexpected subsection meta: %v", sub.Meta)
+	}
+	for _, s := range []string{`\section{not a section} % kept`, "s = _i w_i", "Done."} {
+		if !strings.Contains(sub.Text, s) {
+			t.Errorf("subsection should contain %q:\n%s", s, sub.Text)
+		}
+	}
+	for _, s := range []string{"Left out", "Ignored", "Short"} {
+		if strings.Contains(sub.Text, s) {
+			t.Errorf("subsection should not contain %q:\n%s", s, sub.Text)
+		}
+	}
+}
+
+func TestLaTeXLoader_Fragment(t *testing.T) {
+	// Chapters \input by a main file have no preamble.
+	tex := "\\chapter{Results}\n\\label{ch:results}\nLong text here.\n"
+	reps := loadMarkup(t, NewLaTeXLoader(10, 0), "results.tex", tex)
+	if len(reps) < 2 {
+		t.Fatalf("expected the chapter to be split, got %+v", reps)
+	}
+	for _, r := range reps {
+		if r.Meta["heading"] != "Results" || r.Meta["heading_level"] != "1" || r.Meta["labels"] != "ch:results" {
+			t.Errorf("unexpected meta: %v", r.Meta)
+		}
+	}
+	if reps[0].Meta["line"] != "1" || reps[len(reps)-1].Meta["line"] != "3" {
+		t.Errorf("chunks should point at their lines: %+v", reps)
+	}
+}
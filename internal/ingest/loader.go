@@ -21,10 +21,11 @@ type Loader interface {
 type TextLoader struct {
 	chunkSize int
 	overlap   int
+	chunking  Chunking
 }
 
 func (tl *TextLoader) Extensions() []string {
-	return []string{".txt", ".md", ".go"} // Added .go
+	return []string{".txt", ".go"} // Added .go
 }
 
 // NewTextLoader returns a TextLoader with chunk configuration.
@@ -38,6 +39,9 @@ func NewTextLoader(chunkSize, overlap int) *TextLoader {
 	return &TextLoader{chunkSize: chunkSize, overlap: overlap}
 }
 
+// SetChunking replaces the fixed-size chunks of tl with c.
+func (tl *TextLoader) SetChunking(c Chunking) { tl.chunking = c }
+
 // Load now takes relPath and absPath.
 // relPath is used for ChunkID and stored in Representation.Path.
 // absPath is used to read the file content.
@@ -49,88 +53,33 @@ func (tl *TextLoader) Load(ctx context.Context, relPath string, absPath string)
 		slog.Error("Failed to read file for TextLoader", "path", absPath, "error", err)
 		return nil, err
 	}
-	textContent := string(contentBytes)
+	reps := tl.Split(relPath, string(contentBytes))
+	slog.Debug("Created", "chunks", len(reps), "relPath", relPath)
+	return reps, nil
+}
 
-	// Chunking with word boundaries
+// Split chunks text that is already in memory, e.g. documents posted to the
+// API. Chunk IDs depend only on relPath and chunk position, so splitting the
+// same text again yields the same IDs.
+func (tl *TextLoader) Split(relPath, textContent string) []Representation {
+	chunker := tl.chunking.chunker(tl.chunkSize, tl.overlap)
 	var reps []Representation
-	size := tl.chunkSize
-	ov := tl.overlap
-	if size <= 0 || len(textContent) <= size {
-		// Single chunk for small files
-		chunkID := ChunkID(relPath, "text", 0)
+	for i, span := range chunker.Spans(textContent) {
+		chunkID := ChunkID(relPath, "text", int64(i))
 		reps = append(reps, Representation{
 			ID:       chunkID,
 			Path:     relPath,
 			Modality: "text",
-			Text:     textContent,
+			Text:     span.Text,
 			Meta: map[string]string{
 				"source": "TextLoader",
-				"offset": "0",
+				"offset": strconv.Itoa(span.Start),
+				"line":   strconv.Itoa(lineAt(textContent, span.Start)),
 				"path":   relPath, // Explicitly store path in meta
 			},
 		})
-		return reps, nil
 	}
-
-	start := 0
-	offset := 0
-	for start < len(textContent) {
-		end := start + size
-		if end > len(textContent) {
-			end = len(textContent)
-		}
-
-		// Adjust end to word boundary (don't cut words in half)
-		if end < len(textContent) {
-			// Look backwards for a word boundary (space, newline, punctuation)
-			for end > start && !isWordBoundary(textContent[end]) {
-				end--
-			}
-			// If we couldn't find a word boundary, use the original end
-			if end == start {
-				end = start + size
-			}
-		}
-
-		chunk := textContent[start:end]
-		chunkID := ChunkID(relPath, "text", int64(offset))
-		reps = append(reps, Representation{
-			ID:       chunkID,
-			Path:     relPath,
-			Modality: "text",
-			Text:     chunk,
-			Meta: map[string]string{
-				"source": "TextLoader",
-				"offset": strconv.Itoa(start),
-				"path":   relPath, // Explicitly store path in meta
-			},
-		})
-
-		if end == len(textContent) {
-			break
-		}
-
-		// Calculate next start position with overlap, respecting word boundaries
-		nextStart := end - ov
-		if nextStart <= start {
-			nextStart = start + 1 // Ensure progress
-		}
-
-		// Adjust nextStart to word boundary
-		for nextStart < len(textContent) && !isWordBoundary(textContent[nextStart]) {
-			nextStart++
-		}
-
-		start = nextStart
-		offset++
-	}
-	slog.Debug("Created", "chunks", len(reps), "relPath", relPath)
-	return reps, nil
-}
-
-// isWordBoundary checks if a character is a word boundary
-func isWordBoundary(c byte) bool {
-	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '.' || c == ',' || c == ';' || c == '!' || c == '?'
+	return reps
 }
 
 // CodeLoader loads and parses code files using Tree-sitter.
@@ -184,8 +133,12 @@ func (cl *CodeLoader) Load(ctx context.Context, relPath string, absPath string)
 	language := cl.detectLanguage(relPath)
 	text := string(content)
 
+	if cl.stripImports {
+		text = stripImports(language, text)
+	}
+
 	// For now, implement as a basic text loader with language detection
-	// TODO: Implement full Tree-sitter parsing and import stripping
+	// TODO: Implement full Tree-sitter parsing
 	chunkID := ChunkID(relPath, "text", 0)
 
 	representation := Representation{
@@ -195,9 +148,10 @@ func (cl *CodeLoader) Load(ctx context.Context, relPath string, absPath string)
 		Text:     text,
 		Meta: map[string]string{
 			"language":      language,
-			"strip_imports": "false", // TODO: implement import stripping
+			"strip_imports": strconv.FormatBool(cl.stripImports),
 			"source":        "CodeLoader",
 			"file_size":     strconv.Itoa(len(content)),
+			"line":          "1",
 			"path":          relPath, // Explicitly store path in meta
 		},
 	}
@@ -3,15 +3,17 @@ package ingest
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"log/slog"
 	"math"
-	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/omarkamali/semango/internal/util"
 	"github.com/omarkamali/semango/pkg/semango"
@@ -29,7 +31,10 @@ type LocalEmbedder struct {
 	session       *onnxruntime_go.AdvancedSession
 	poolingConfig *PoolingConfig
 	outputName    string // Cached output name for the ONNX model
-	mu            sync.RWMutex
+	// sessions is a pool of inference sessions; each runs one batch at a time.
+	sessions    chan *onnxruntime_go.DynamicAdvancedSession
+	numSessions int
+	mu          sync.RWMutex
 }
 
 // LocalEmbedderConfig holds configuration for the local embedder.
@@ -39,6 +44,8 @@ type LocalEmbedderConfig struct {
 	BatchSize int    // Batch size for inference
 	MaxLength int    // Maximum sequence length
 	ModelName string // Specific model name (e.g., "all-MiniLM-L6-v2-onnx")
+	Sessions  int    // Number of ONNX sessions running batches in parallel (0 = auto)
+	Threads   int    // Intra-op threads per session (0 = share the CPU cores between sessions)
 }
 
 // Tokenizer handles text tokenization for sentence transformers.
@@ -85,8 +92,7 @@ func NewLocalEmbedder(config LocalEmbedderConfig) (*LocalEmbedder, error) {
 		config.MaxLength = 512 // Default max length
 	}
 	if config.CacheDir == "" {
-		homeDir, _ := os.UserHomeDir()
-		config.CacheDir = filepath.Join(homeDir, ".cache", "semango", "models")
+		config.CacheDir = DefaultModelCacheDir()
 	}
 
 	// Ensure cache directory exists
@@ -143,6 +149,12 @@ func NewLocalEmbedder(config LocalEmbedderConfig) (*LocalEmbedder, error) {
 	}
 	embedder.outputName = outputName
 
+	// Create the pool of inference sessions
+	if err := embedder.initSessionPool(config.Sessions, config.Threads); err != nil {
+		embedder.Close()
+		return nil, fmt.Errorf("failed to create inference sessions: %w", err)
+	}
+
 	return embedder, nil
 }
 
@@ -175,6 +187,10 @@ func isLocalPath(path string) bool {
 }
 
 // downloadONNXModel downloads a model from onnx-models organization on Hugging Face Hub.
+// A cached copy is reused if its files have the sizes recorded in its
+// manifest; hashing them on every start would be slow for large models, so
+// checksums are left to doctor. An incomplete download is discarded and
+// fetched again, while a copy cached before manifests existed is adopted.
 func (le *LocalEmbedder) downloadONNXModel(modelName, cacheDir string) (string, error) {
 	// Handle both "model-name-onnx" and "onnx-models/model-name-onnx" formats
 	var fullModelName string
@@ -184,69 +200,75 @@ func (le *LocalEmbedder) downloadONNXModel(modelName, cacheDir string) (string,
 		fullModelName = "onnx-models/" + modelName
 	}
 
-	modelDir := filepath.Join(cacheDir, strings.ReplaceAll(fullModelName, "/", "_"))
+	modelDir := filepath.Join(cacheDir, ModelCacheDirName(fullModelName))
 
-	// Check if model already exists
+	// Check if a complete copy of the model already exists
 	if _, err := os.Stat(modelDir); err == nil {
-		return modelDir, nil
+		manifest, err := verifyModelDir(modelDir, false)
+		if err == nil {
+			touchModelDir(modelDir, manifest)
+			return modelDir, nil
+		}
+		if errors.Is(err, errNoModelManifest) {
+			if _, aerr := adoptModelDir(modelDir, fullModelName); aerr == nil {
+				slog.Info("Adopted cached model without a manifest", "model", fullModelName, "dir", modelDir)
+				return modelDir, nil
+			}
+		}
+		slog.Warn("Cached model is incomplete or corrupted, downloading again", "model", fullModelName, "dir", modelDir, "reason", err)
+		if err := os.RemoveAll(modelDir); err != nil {
+			return "", fmt.Errorf("failed to remove invalid cached model: %w", err)
+		}
 	}
 
-	// Create model directory
-	if err := os.MkdirAll(modelDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create model directory: %w", err)
+	// Download into a staging directory so a crash never leaves a model that looks complete
+	stagingDir := modelDir + modelPartialExt
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return "", fmt.Errorf("failed to clear staging directory: %w", err)
 	}
-
-	// List of files to download for an ONNX sentence transformer model
-	files := []string{
-		"config.json",
-		"tokenizer.json",
-		"tokenizer_config.json",
-		"vocab.txt",
-		"model.onnx",
-		"1_Pooling/config.json",
-		"special_tokens_map.json",
+	if err := os.MkdirAll(stagingDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create model directory: %w", err)
 	}
 
 	baseURL := fmt.Sprintf("https://huggingface.co/%s/resolve/main", fullModelName)
+	manifest := &modelManifest{
+		Model:        fullModelName,
+		Files:        make(map[string]modelFile),
+		DownloadedAt: time.Now().UTC(),
+	}
+	manifest.LastUsed = manifest.DownloadedAt
 
-	for _, file := range files {
+	for _, file := range modelFiles {
 		url := fmt.Sprintf("%s/%s", baseURL, file)
-		localPath := filepath.Join(modelDir, file)
+		localPath := filepath.Join(stagingDir, filepath.FromSlash(file))
 
 		// Create subdirectories if needed
 		if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
 			return "", fmt.Errorf("failed to create directory for %s: %w", file, err)
 		}
 
-		if err := le.downloadFile(url, localPath); err != nil {
+		info, err := downloadModelFile(url, localPath)
+		if err != nil {
 			// Some files might not exist, continue with others
+			slog.Debug("Model file not downloaded", "file", file, "error", err)
+			_ = os.Remove(localPath)
 			continue
 		}
+		manifest.Files[file] = info
 	}
 
-	return modelDir, nil
-}
-
-// downloadFile downloads a file from URL to local path.
-func (le *LocalEmbedder) downloadFile(url, localPath string) error {
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
+	if err := checkRequiredModelFiles(manifest.Files); err != nil {
+		_ = os.RemoveAll(stagingDir)
+		return "", fmt.Errorf("download of %s is incomplete: %w", fullModelName, err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download %s: status %d", url, resp.StatusCode)
+	if err := writeModelManifest(stagingDir, manifest); err != nil {
+		return "", fmt.Errorf("failed to write model manifest: %w", err)
 	}
-
-	out, err := os.Create(localPath)
-	if err != nil {
-		return err
+	if err := os.Rename(stagingDir, modelDir); err != nil {
+		return "", fmt.Errorf("failed to move downloaded model into place: %w", err)
 	}
-	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
-	return err
+	return modelDir, nil
 }
 
 // loadTokenizer loads the tokenizer from the model directory.
@@ -542,7 +564,64 @@ func (le *LocalEmbedder) initONNXSession(modelDir string) (*onnxruntime_go.Advan
 	return session, nil
 }
 
-// Embed implements the Embedder interface.
+// initSessionPool creates the inference sessions used by Embed. With
+// sessions <= 0, one session is created per group of up to four cores; each
+// session gets an equal share of the cores as intra-op threads unless threads
+// is set explicitly.
+func (le *LocalEmbedder) initSessionPool(sessions, threads int) error {
+	cpus := runtime.NumCPU()
+	if sessions <= 0 {
+		sessions = (cpus + 3) / 4
+	}
+	if sessions > cpus {
+		sessions = cpus
+	}
+	if threads <= 0 {
+		threads = cpus / sessions
+		if threads < 1 {
+			threads = 1
+		}
+	}
+
+	options, err := onnxruntime_go.NewSessionOptions()
+	if err != nil {
+		return fmt.Errorf("failed to create session options: %w", err)
+	}
+	defer options.Destroy()
+	if err := options.SetIntraOpNumThreads(threads); err != nil {
+		return fmt.Errorf("failed to set intra-op threads: %w", err)
+	}
+	// Parallelism across batches comes from the pool, not from inter-op threads.
+	if err := options.SetInterOpNumThreads(1); err != nil {
+		return fmt.Errorf("failed to set inter-op threads: %w", err)
+	}
+
+	modelPath := filepath.Join(le.modelPath, "model.onnx")
+	pool := make(chan *onnxruntime_go.DynamicAdvancedSession, sessions)
+	for i := 0; i < sessions; i++ {
+		session, err := onnxruntime_go.NewDynamicAdvancedSession(
+			modelPath,
+			[]string{"input_ids", "attention_mask", "token_type_ids"},
+			[]string{le.outputName},
+			options,
+		)
+		if err != nil {
+			close(pool)
+			for s := range pool {
+				s.Destroy()
+			}
+			return err
+		}
+		pool <- session
+	}
+	le.sessions = pool
+	le.numSessions = sessions
+	slog.Debug("Created ONNX session pool", "sessions", sessions, "intra_op_threads", threads)
+	return nil
+}
+
+// Embed implements the Embedder interface. Batches are spread across the
+// session pool, so up to numSessions batches run at the same time.
 func (le *LocalEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
 	logger := util.FromContext(ctx)
 
@@ -550,23 +629,58 @@ func (le *LocalEmbedder) Embed(ctx context.Context, texts []string) ([][]float32
 		return [][]float32{}, nil
 	}
 
-	logger.Debug("Starting local embedding", "num_texts", len(texts), "model_path", le.modelPath)
+	logger.Debug("Starting local embedding", "num_texts", len(texts), "model_path", le.modelPath, "sessions", le.numSessions)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	allEmbeddings := make([][]float32, len(texts))
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		batchErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			batchErr = err
+			cancel()
+		})
+	}
 
 	// Process texts in batches
-	var allEmbeddings [][]float32
 	for i := 0; i < len(texts); i += le.batchSize {
 		end := i + le.batchSize
 		if end > len(texts) {
 			end = len(texts)
 		}
-		batch := texts[i:end]
 
-		embeddings, err := le.embedBatch(ctx, batch)
-		if err != nil {
-			return nil, fmt.Errorf("batch embedding failed: %w", err)
+		var session *onnxruntime_go.DynamicAdvancedSession
+		select {
+		case session = <-le.sessions:
+		case <-ctx.Done():
+			fail(ctx.Err())
+		}
+		if session == nil {
+			break
 		}
 
-		allEmbeddings = append(allEmbeddings, embeddings...)
+		wg.Add(1)
+		go func(start int, batch []string) {
+			defer wg.Done()
+			defer func() { le.sessions <- session }()
+
+			embeddings, err := le.embedBatch(ctx, session, batch)
+			if err != nil {
+				fail(fmt.Errorf("batch embedding failed: %w", err))
+				return
+			}
+			copy(allEmbeddings[start:], embeddings)
+		}(i, texts[i:end])
+	}
+	wg.Wait()
+
+	if batchErr != nil {
+		return nil, batchErr
 	}
 
 	logger.Debug("Local embedding completed", "num_texts", len(texts), "num_results", len(allEmbeddings))
@@ -574,7 +688,7 @@ func (le *LocalEmbedder) Embed(ctx context.Context, texts []string) ([][]float32
 }
 
 // embedBatch processes a batch of texts.
-func (le *LocalEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+func (le *LocalEmbedder) embedBatch(ctx context.Context, session *onnxruntime_go.DynamicAdvancedSession, texts []string) ([][]float32, error) {
 	// Tokenize texts
 	inputIDs, attentionMasks, err := le.tokenizeTexts(texts)
 	if err != nil {
@@ -582,7 +696,7 @@ func (le *LocalEmbedder) embedBatch(ctx context.Context, texts []string) ([][]fl
 	}
 
 	// Run ONNX inference
-	outputs, err := le.runInference(inputIDs, attentionMasks)
+	outputs, err := le.runInference(session, inputIDs, attentionMasks)
 	if err != nil {
 		return nil, fmt.Errorf("inference failed: %w", err)
 	}
@@ -683,7 +797,7 @@ func (t *Tokenizer) convertTokensToIDs(tokens []string) []int {
 }
 
 // runInference runs ONNX model inference.
-func (le *LocalEmbedder) runInference(inputIDs, attentionMasks [][]int64) ([][][]float32, error) {
+func (le *LocalEmbedder) runInference(session *onnxruntime_go.DynamicAdvancedSession, inputIDs, attentionMasks [][]int64) ([][][]float32, error) {
 	batchSize := len(inputIDs)
 	seqLength := len(inputIDs[0])
 
@@ -721,21 +835,6 @@ func (le *LocalEmbedder) runInference(inputIDs, attentionMasks [][]int64) ([][][
 	}
 	defer tokenTypeIDsTensor.Destroy()
 
-	// Create dynamic session using the detected output name
-	modelPath := le.modelPath + "/model.onnx"
-	fmt.Printf("DEBUG runInference: trying model path: %s with output name: %s\n", modelPath, le.outputName)
-
-	dynamicSession, err := onnxruntime_go.NewDynamicAdvancedSession(
-		modelPath,
-		[]string{"input_ids", "attention_mask", "token_type_ids"},
-		[]string{le.outputName},
-		nil,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create dynamic session: %w", err)
-	}
-	defer dynamicSession.Destroy()
-
 	// Create output tensor based on output type
 	var outputTensor *onnxruntime_go.Tensor[float32]
 	if le.outputName == "pooler_output" {
@@ -753,7 +852,7 @@ func (le *LocalEmbedder) runInference(inputIDs, attentionMasks [][]int64) ([][][
 	defer outputTensor.Destroy()
 
 	// Run inference
-	err = dynamicSession.Run(
+	err = session.Run(
 		[]onnxruntime_go.Value{inputIDsTensor, attentionMasksTensor, tokenTypeIDsTensor},
 		[]onnxruntime_go.Value{outputTensor},
 	)
@@ -914,6 +1013,12 @@ func (le *LocalEmbedder) Close() error {
 	if le.session != nil {
 		le.session.Destroy()
 	}
+	if le.sessions != nil {
+		for i := 0; i < le.numSessions; i++ {
+			(<-le.sessions).Destroy()
+		}
+		le.sessions = nil
+	}
 	return nil
 }
 
@@ -995,3 +1100,15 @@ func ValidateModelConfig(config LocalEmbedderConfig) error {
 
 // Ensure LocalEmbedder implements the Embedder interface.
 var _ semango.Embedder = (*LocalEmbedder)(nil)
+
+// CheckONNXRuntime loads the ONNX Runtime library the local embedder runs
+// models with, and reports why it cannot be loaded.
+func CheckONNXRuntime() error {
+	if onnxruntime_go.IsInitialized() {
+		return nil
+	}
+	if err := onnxruntime_go.InitializeEnvironment(); err != nil {
+		return fmt.Errorf("failed to initialize ONNX runtime: %w", err)
+	}
+	return nil
+}
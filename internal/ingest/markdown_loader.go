@@ -0,0 +1,327 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MarkdownLoader splits Markdown files into heading sections instead of
+// fixed-size windows. Every chunk records the heading breadcrumb it belongs to
+// (e.g. "Guide > Install > Linux"), frontmatter is moved into metadata, and
+// fenced code blocks are emitted as their own chunks.
+type MarkdownLoader struct {
+	chunkSize int
+	overlap   int
+	chunking  Chunking
+}
+
+// NewMarkdownLoader returns a MarkdownLoader. Sections longer than chunkSize
+// are split further using the same word-boundary strategy as TextLoader.
+func NewMarkdownLoader(chunkSize, overlap int) *MarkdownLoader {
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	if overlap < 0 {
+		overlap = 0
+	}
+	return &MarkdownLoader{chunkSize: chunkSize, overlap: overlap}
+}
+
+// SetChunking replaces the fixed-size chunks of ml with c.
+func (ml *MarkdownLoader) SetChunking(c Chunking) { ml.chunking = c }
+
+func (ml *MarkdownLoader) Extensions() []string {
+	return []string{".md", ".markdown"}
+}
+
+// mdBlock is a contiguous region of a Markdown document: either the prose of a
+// heading section or a single fenced code block.
+type mdBlock struct {
+	kind       string // "section" or "code"
+	breadcrumb []string
+	level      int
+	lang       string
+	text       string
+	start      int // byte offset within the parsed body
+}
+
+func (ml *MarkdownLoader) Load(ctx context.Context, relPath string, absPath string) ([]Representation, error) {
+	slog.Info("Loading markdown file", "relative_path", relPath, "absolute_path", absPath)
+
+	contentBytes, err := os.ReadFile(absPath)
+	if err != nil {
+		slog.Error("Failed to read file for MarkdownLoader", "path", absPath, "error", err)
+		return nil, err
+	}
+	content := string(contentBytes)
+
+	frontmatter, body, bodyOffset := splitFrontmatter(content)
+	fmMeta := map[string]string{}
+	if frontmatter != "" {
+		var fm map[string]interface{}
+		if err := yaml.Unmarshal([]byte(frontmatter), &fm); err != nil {
+			slog.Warn("Ignoring invalid markdown frontmatter", "path", relPath, "error", err)
+		} else {
+			flattenFrontmatter("frontmatter", fm, fmMeta)
+		}
+	}
+
+	chunker := ml.chunking.chunker(ml.chunkSize, ml.overlap)
+	var reps []Representation
+	for _, b := range parseMarkdownBlocks(body) {
+		for _, span := range chunker.Spans(b.text) {
+			if strings.TrimSpace(span.Text) == "" {
+				continue
+			}
+			offset := bodyOffset + b.start + span.Start
+			meta := map[string]string{
+				"source": "MarkdownLoader",
+				"offset": strconv.Itoa(offset),
+				"line":   strconv.Itoa(lineAt(content, offset)),
+				"path":   relPath,
+				"kind":   b.kind,
+			}
+			if len(b.breadcrumb) > 0 {
+				meta["heading"] = b.breadcrumb[len(b.breadcrumb)-1]
+				meta["heading_level"] = strconv.Itoa(b.level)
+				meta["breadcrumb"] = strings.Join(b.breadcrumb, " > ")
+			}
+			if b.lang != "" {
+				meta["language"] = b.lang
+			}
+			for k, v := range fmMeta {
+				meta[k] = v
+			}
+			reps = append(reps, Representation{
+				ID:       ChunkID(relPath, "text", int64(len(reps))),
+				Path:     relPath,
+				Modality: "text",
+				Text:     span.Text,
+				Meta:     meta,
+			})
+		}
+	}
+	slog.Debug("Created", "chunks", len(reps), "relPath", relPath)
+	return reps, nil
+}
+
+// splitFrontmatter separates a leading YAML frontmatter block ("---" fenced)
+// from the document body. It returns the raw frontmatter, the body and the
+// byte offset at which the body starts.
+func splitFrontmatter(content string) (string, string, int) {
+	first, rest, ok := strings.Cut(content, "\n")
+	if !ok || strings.TrimRight(first, "\r") != "---" {
+		return "", content, 0
+	}
+	offset := len(first) + 1
+	for pos := 0; pos < len(rest); {
+		line := rest[pos:]
+		next := len(rest)
+		if i := strings.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+			next = pos + i + 1
+		}
+		trimmed := strings.TrimRight(line, "\r")
+		if trimmed == "---" || trimmed == "..." {
+			return rest[:pos], rest[next:], offset + next
+		}
+		pos = next
+	}
+	// No closing fence: this was a horizontal rule, not frontmatter.
+	return "", content, 0
+}
+
+// flattenFrontmatter converts parsed YAML into flat string metadata. Nested
+// maps use dotted keys and scalar lists are joined with ", ".
+func flattenFrontmatter(prefix string, v interface{}, out map[string]string) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			flattenFrontmatter(prefix+"."+k, val[k], out)
+		}
+	case []interface{}:
+		parts := make([]string, 0, len(val))
+		for _, item := range val {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		out[prefix] = strings.Join(parts, ", ")
+	case nil:
+		// skip empty values
+	default:
+		out[prefix] = fmt.Sprint(val)
+	}
+}
+
+var (
+	reATXHeading = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	reFenceOpen  = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*([^`\\s]*)")
+	reSetextH1   = regexp.MustCompile(`^ {0,3}=+[ \t]*$`)
+	reSetextH2   = regexp.MustCompile(`^ {0,3}-+[ \t]*$`)
+	reListItem   = regexp.MustCompile(`^ {0,3}(?:[-*+]|\d+[.)])[ \t]`)
+)
+
+type mdLine struct {
+	text string // including the trailing newline, if any
+	off  int
+}
+
+// parseMarkdownBlocks walks the document line by line, tracking the heading
+// hierarchy and fenced code blocks.
+func parseMarkdownBlocks(body string) []mdBlock {
+	var (
+		blocks   []mdBlock
+		headings [6]string
+		level    int
+		buf      []mdLine
+		bufHead  int // leading heading lines in buf
+	)
+
+	breadcrumb := func() []string {
+		var crumbs []string
+		for _, h := range headings[:level] {
+			if h != "" {
+				crumbs = append(crumbs, h)
+			}
+		}
+		return crumbs
+	}
+	setHeading := func(lvl int, title string) {
+		headings[lvl-1] = title
+		for i := lvl; i < len(headings); i++ {
+			headings[i] = ""
+		}
+		level = lvl
+	}
+	flush := func() {
+		if len(buf) == 0 {
+			return
+		}
+		var sb strings.Builder
+		hasBody := false
+		for i, l := range buf {
+			sb.WriteString(l.text)
+			if i >= bufHead && strings.TrimSpace(l.text) != "" {
+				hasBody = true
+			}
+		}
+		// Headings without any prose of their own are carried by the
+		// breadcrumb of the sections below them.
+		if hasBody {
+			blocks = append(blocks, mdBlock{
+				kind:       "section",
+				breadcrumb: breadcrumb(),
+				level:      level,
+				text:       sb.String(),
+				start:      buf[0].off,
+			})
+		}
+		buf = nil
+		bufHead = 0
+	}
+
+	var (
+		inFence    bool
+		fenceMark  string
+		fenceLang  string
+		fenceStart int
+		fenceBuf   strings.Builder
+	)
+
+	for off := 0; off < len(body); {
+		end := strings.IndexByte(body[off:], '\n')
+		if end < 0 {
+			end = len(body)
+		} else {
+			end = off + end + 1
+		}
+		raw := body[off:end]
+		line := strings.TrimRight(raw, "\r\n")
+
+		if inFence {
+			if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, fenceMark) && strings.Trim(trimmed, fenceMark[:1]) == "" {
+				blocks = append(blocks, mdBlock{
+					kind:       "code",
+					breadcrumb: breadcrumb(),
+					level:      level,
+					lang:       fenceLang,
+					text:       fenceBuf.String(),
+					start:      fenceStart,
+				})
+				inFence = false
+				fenceBuf.Reset()
+			} else {
+				fenceBuf.WriteString(raw)
+			}
+			off = end
+			continue
+		}
+
+		if m := reFenceOpen.FindStringSubmatch(line); m != nil {
+			flush()
+			inFence = true
+			fenceMark = m[1]
+			fenceLang = strings.ToLower(m[2])
+			fenceStart = end
+			off = end
+			continue
+		}
+
+		if m := reATXHeading.FindStringSubmatch(line); m != nil {
+			flush()
+			setHeading(len(m[1]), strings.TrimSpace(m[2]))
+			buf = append(buf, mdLine{text: raw, off: off})
+			bufHead = 1
+			off = end
+			continue
+		}
+
+		// Setext headings underline the previous paragraph line.
+		if n := len(buf); n > 0 && (reSetextH1.MatchString(line) || reSetextH2.MatchString(line)) {
+			prev := strings.TrimSpace(buf[n-1].text)
+			if prev != "" && !reListItem.MatchString(buf[n-1].text) && (n == 1 || strings.TrimSpace(buf[n-2].text) == "") {
+				titleLine := buf[n-1]
+				buf = buf[:n-1]
+				flush()
+				lvl := 2
+				if reSetextH1.MatchString(line) {
+					lvl = 1
+				}
+				setHeading(lvl, prev)
+				buf = append(buf, titleLine, mdLine{text: raw, off: off})
+				bufHead = 2
+				off = end
+				continue
+			}
+		}
+
+		buf = append(buf, mdLine{text: raw, off: off})
+		off = end
+	}
+
+	if inFence {
+		// Unterminated fence: keep the content rather than dropping it.
+		blocks = append(blocks, mdBlock{
+			kind:       "code",
+			breadcrumb: breadcrumb(),
+			level:      level,
+			lang:       fenceLang,
+			text:       fenceBuf.String(),
+			start:      fenceStart,
+		})
+	}
+	flush()
+	return blocks
+}
@@ -0,0 +1,101 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownLoader_Sections(t *testing.T) {
+	doc := `---
+title: Setup Guide
+tags: [install, linux]
+---
+# Guide
+
+Intro paragraph.
+
+## Install
+
+Run the installer.
+
+` + "```bash\nmake install\n```" + `
+
+### Linux
+
+Use the package manager.
+
+Other Title
+-----------
+
+Setext section body.
+`
+	dir := t.TempDir()
+	file := filepath.Join(dir, "guide.md")
+	if err := os.WriteFile(file, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reps, err := NewMarkdownLoader(1000, 0).Load(context.Background(), "guide.md", file)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	type want struct {
+		kind       string
+		breadcrumb string
+		contains   string
+	}
+	expected := []want{
+		{"section", "Guide", "Intro paragraph."},
+		{"section", "Guide > Install", "Run the installer."},
+		{"code", "Guide > Install", "make install"},
+		{"section", "Guide > Install > Linux", "Use the package manager."},
+		{"section", "Guide > Other Title", "Setext section body."},
+	}
+	if len(reps) != len(expected) {
+		for _, r := range reps {
+			t.Logf("%q %q %q", r.Meta["kind"], r.Meta["breadcrumb"], r.Text)
+		}
+		t.Fatalf("expected %d chunks, got %d", len(expected), len(reps))
+	}
+	for i, w := range expected {
+		r := reps[i]
+		if r.Meta["kind"] != w.kind {
+			t.Errorf("chunk %d: expected kind %q, got %q", i, w.kind, r.Meta["kind"])
+		}
+		if r.Meta["breadcrumb"] != w.breadcrumb {
+			t.Errorf("chunk %d: expected breadcrumb %q, got %q", i, w.breadcrumb, r.Meta["breadcrumb"])
+		}
+		if !strings.Contains(r.Text, w.contains) {
+			t.Errorf("chunk %d: expected text to contain %q, got %q", i, w.contains, r.Text)
+		}
+		if r.Meta["frontmatter.title"] != "Setup Guide" {
+			t.Errorf("chunk %d: expected frontmatter title, got %q", i, r.Meta["frontmatter.title"])
+		}
+		if strings.Contains(r.Text, "title: Setup Guide") {
+			t.Errorf("chunk %d: frontmatter leaked into text", i)
+		}
+	}
+	if reps[2].Meta["language"] != "bash" {
+		t.Errorf("expected code fence language bash, got %q", reps[2].Meta["language"])
+	}
+	if reps[0].Meta["frontmatter.tags"] != "install, linux" {
+		t.Errorf("expected joined tags, got %q", reps[0].Meta["frontmatter.tags"])
+	}
+	if reps[1].Meta["line"] != "9" {
+		t.Errorf("expected Install section to start on line 9, got %q", reps[1].Meta["line"])
+	}
+}
+
+func TestMarkdownLoader_FenceHidesHeadings(t *testing.T) {
+	blocks := parseMarkdownBlocks("# Top\n\ntext\n\n~~~\n# not a heading\n~~~\n")
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 blocks, got %d", len(blocks))
+	}
+	if blocks[1].kind != "code" || !strings.Contains(blocks[1].text, "# not a heading") {
+		t.Errorf("expected fenced heading to stay inside code block, got %+v", blocks[1])
+	}
+}
@@ -0,0 +1,182 @@
+package ingest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// markupDoc is a LaTeX or reStructuredText document with its markup
+// stripped. It keeps one line of text per line of the source, so that
+// chunks can point at the line they start on.
+type markupDoc struct {
+	lines    []string // lines[i] is what is left of line i+1
+	headings []markupHeading
+	labels   []markupLabel
+	meta     map[string]string // document metadata, e.g. its title
+}
+
+// markupHeading starts a section on a line of a markupDoc.
+type markupHeading struct {
+	line  int // 0-based index in lines
+	rank  int // the lower, the higher up the hierarchy
+	title string
+}
+
+// markupLabel is a name cross-references point at, such as a LaTeX
+// \label or a reStructuredText target, set on a line of a markupDoc.
+type markupLabel struct {
+	line int
+	name string
+}
+
+// markupSection is the text of a heading section of a markupDoc.
+type markupSection struct {
+	breadcrumb []string
+	level      int
+	labels     []string
+	text       string
+	lines      []int // 1-based source line of each line of text
+}
+
+// sections splits d at its headings. Ranks become levels from 1 in the
+// order of those the document uses, so that an article starting at
+// \section has level 1 sections like a book starting at \chapter. Like
+// Markdown headings, headings without any text of their own are carried
+// by the breadcrumb of the sections below them, and their labels by the
+// next section.
+func (d *markupDoc) sections() []markupSection {
+	var ranks []int
+	for _, h := range d.headings {
+		if !containsInt(ranks, h.rank) {
+			ranks = append(ranks, h.rank)
+		}
+	}
+	sort.Ints(ranks)
+
+	var (
+		out     []markupSection
+		stack   []markupHeading // the headings above the current line
+		carried []string        // labels of sections without text
+	)
+	cut := func(start, end int) {
+		sec := markupSection{labels: carried}
+		for _, h := range stack {
+			sec.breadcrumb = append(sec.breadcrumb, h.title)
+		}
+		if len(stack) > 0 {
+			sec.level = sort.SearchInts(ranks, stack[len(stack)-1].rank) + 1
+		}
+		for _, l := range d.labels {
+			if l.line >= start && l.line < end {
+				sec.labels = append(sec.labels, l.name)
+			}
+		}
+		var sb strings.Builder
+		blank, hasBody := false, false
+		for i := start; i < end; i++ {
+			line := d.lines[i]
+			if line == "" {
+				blank = sb.Len() > 0
+				continue
+			}
+			if i != start || len(stack) == 0 {
+				hasBody = true
+			}
+			if blank {
+				sb.WriteString("\n")
+				sec.lines = append(sec.lines, 0)
+				blank = false
+			}
+			if sb.Len() > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(line)
+			sec.lines = append(sec.lines, i+1)
+		}
+		if hasBody {
+			sec.text = sb.String()
+			out = append(out, sec)
+			carried = nil
+		} else {
+			carried = sec.labels
+		}
+	}
+
+	start := 0
+	for _, h := range d.headings {
+		cut(start, h.line)
+		for len(stack) > 0 && stack[len(stack)-1].rank >= h.rank {
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, h)
+		start = h.line
+	}
+	cut(start, len(d.lines))
+	return out
+}
+
+// representations chunks the sections of d with chunker into the chunks
+// of the file at relPath, read by the loader called source.
+func (d *markupDoc) representations(relPath, source string, chunker SpanChunker) []Representation {
+	var reps []Representation
+	for _, sec := range d.sections() {
+		// offsets[i] is where line i of the text of sec starts.
+		offsets := []int{0}
+		for i, c := range sec.text {
+			if c == '\n' {
+				offsets = append(offsets, i+1)
+			}
+		}
+		for _, span := range chunker.Spans(sec.text) {
+			if strings.TrimSpace(span.Text) == "" {
+				continue
+			}
+			meta := map[string]string{
+				"source": source,
+				"path":   relPath,
+				"kind":   "section",
+			}
+			for i := sort.SearchInts(offsets, span.Start+1) - 1; i < len(sec.lines); i++ {
+				if sec.lines[i] > 0 {
+					meta["line"] = strconv.Itoa(sec.lines[i])
+					break
+				}
+			}
+			if len(sec.breadcrumb) > 0 {
+				meta["heading"] = sec.breadcrumb[len(sec.breadcrumb)-1]
+				meta["heading_level"] = strconv.Itoa(sec.level)
+				meta["breadcrumb"] = strings.Join(sec.breadcrumb, " > ")
+			}
+			if len(sec.labels) > 0 {
+				meta["labels"] = strings.Join(sec.labels, ",")
+			}
+			for k, v := range d.meta {
+				meta[k] = v
+			}
+			reps = append(reps, Representation{
+				ID:       ChunkID(relPath, "text", int64(len(reps))),
+				Path:     relPath,
+				Modality: "text",
+				Text:     strings.TrimSpace(span.Text),
+				Meta:     meta,
+			})
+		}
+	}
+	return reps
+}
+
+// collapseSpaces trims s and turns the runs of spaces and tabs in it into
+// one space.
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func containsInt(list []int, n int) bool {
+	for _, x := range list {
+		if x == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,439 @@
+package ingest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Downloaded models are staged in "<dir>.partial" and only renamed into place
+// once every file is on disk, together with a manifest of file sizes and
+// SHA-256 checksums. Loading a model only compares sizes; checksums are
+// verified by doctor. A model directory cached before manifests existed is
+// adopted by writing a manifest for the files it holds.
+const (
+	modelManifestName = ".semango-model.json"
+	modelPartialExt   = ".partial"
+	modelDirPrefix    = "onnx-models_"
+
+	// partialGracePeriod protects a download that another process is still
+	// writing from garbage collection.
+	partialGracePeriod = time.Hour
+)
+
+// modelFiles are the files of an ONNX sentence transformer model that are
+// downloaded when the repository has them.
+var modelFiles = []string{
+	"config.json",
+	"tokenizer.json",
+	"tokenizer_config.json",
+	"vocab.txt",
+	"model.onnx",
+	"1_Pooling/config.json",
+	"special_tokens_map.json",
+}
+
+// errNoModelManifest is returned by verifyModelDir for a directory without
+// a manifest.
+var errNoModelManifest = errors.New("no manifest, download did not complete")
+
+// ErrModelNotVerified is returned by VerifyCachedModel for a model cached
+// before manifests existed, which has no checksums to verify against yet.
+var ErrModelNotVerified = errors.New("cached before checksums were recorded; it is adopted the next time it is loaded")
+
+// requiredModelFiles must be present for a download to count as complete;
+// the tokenizer may come from either file.
+var requiredModelFiles = [][]string{{"model.onnx"}, {"tokenizer.json", "vocab.txt"}}
+
+// modelManifest records what a completed download contains.
+type modelManifest struct {
+	Model        string               `json:"model"`
+	Files        map[string]modelFile `json:"files"`
+	DownloadedAt time.Time            `json:"downloaded_at"`
+	LastUsed     time.Time            `json:"last_used"`
+}
+
+type modelFile struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// DefaultModelCacheDir is where models are cached when no directory is configured.
+func DefaultModelCacheDir() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".cache", "semango", "models")
+}
+
+// ModelCacheDirName returns the directory name a downloaded model is cached under.
+func ModelCacheDirName(model string) string {
+	if !strings.HasPrefix(model, "onnx-models/") {
+		model = "onnx-models/" + model
+	}
+	return strings.ReplaceAll(model, "/", "_")
+}
+
+func readModelManifest(dir string) (*modelManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, modelManifestName))
+	if err != nil {
+		return nil, err
+	}
+	var m modelManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid model manifest: %w", err)
+	}
+	return &m, nil
+}
+
+func writeModelManifest(dir string, m *modelManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, modelManifestName+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, modelManifestName))
+}
+
+// verifyModelDir checks a cached model against its manifest. With checksums
+// set, file contents are hashed as well; otherwise only sizes are compared.
+func verifyModelDir(dir string, checksums bool) (*modelManifest, error) {
+	m, err := readModelManifest(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errNoModelManifest
+		}
+		return nil, err
+	}
+	if err := checkRequiredModelFiles(m.Files); err != nil {
+		return nil, err
+	}
+	for name, want := range m.Files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if info.Size() != want.Size {
+			return nil, fmt.Errorf("%s: size %d, expected %d", name, info.Size(), want.Size)
+		}
+		if !checksums {
+			continue
+		}
+		sum, err := fileSHA256(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if sum != want.SHA256 {
+			return nil, fmt.Errorf("%s: checksum mismatch", name)
+		}
+	}
+	return m, nil
+}
+
+// presentModelFiles lists the modelFiles found in dir. With checksums set,
+// their SHA-256 is computed as well.
+func presentModelFiles(dir string, checksums bool) (map[string]modelFile, error) {
+	files := make(map[string]modelFile)
+	for _, name := range modelFiles {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		info, err := os.Stat(p)
+		if err != nil || !info.Mode().IsRegular() || info.Size() == 0 {
+			continue
+		}
+		f := modelFile{Size: info.Size()}
+		if checksums {
+			if f.SHA256, err = fileSHA256(p); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		files[name] = f
+	}
+	return files, checkRequiredModelFiles(files)
+}
+
+// adoptModelDir writes a manifest for a model directory cached by a version
+// that did not write one, so that it is reused instead of downloaded again.
+// The checksums recorded are those of the files as they are now.
+func adoptModelDir(dir, model string) (*modelManifest, error) {
+	files, err := presentModelFiles(dir, true)
+	if err != nil {
+		return nil, err
+	}
+	_, mtime := dirUsage(dir)
+	m := &modelManifest{
+		Model:        model,
+		Files:        files,
+		DownloadedAt: mtime.UTC(),
+		LastUsed:     time.Now().UTC(),
+	}
+	if err := writeModelManifest(dir, m); err != nil {
+		return nil, fmt.Errorf("failed to write model manifest: %w", err)
+	}
+	return m, nil
+}
+
+// VerifyCachedModel checks the cached copy of model under cacheDir against
+// the sizes and SHA-256 checksums in its manifest. The error wraps
+// fs.ErrNotExist when the model is not cached.
+func VerifyCachedModel(cacheDir, model string) error {
+	dir := filepath.Join(cacheDir, ModelCacheDirName(model))
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	if _, err := verifyModelDir(dir, true); err != nil {
+		if errors.Is(err, errNoModelManifest) {
+			if _, err := presentModelFiles(dir, false); err == nil {
+				return ErrModelNotVerified
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func checkRequiredModelFiles(files map[string]modelFile) error {
+	for _, alternatives := range requiredModelFiles {
+		found := false
+		for _, name := range alternatives {
+			if _, ok := files[name]; ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("missing %s", strings.Join(alternatives, " or "))
+		}
+	}
+	return nil
+}
+
+// touchModelDir records that a cached model was used, for LRU retention.
+func touchModelDir(dir string, m *modelManifest) {
+	m.LastUsed = time.Now().UTC()
+	if err := writeModelManifest(dir, m); err != nil {
+		slog.Debug("Failed to update model manifest", "dir", dir, "error", err)
+	}
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// downloadModelFile fetches url into localPath, returning its size and
+// checksum. A body shorter than Content-Length, or one whose SHA-256 differs
+// from a checksum ETag (as Hugging Face serves for LFS files), is an error.
+func downloadModelFile(url, localPath string) (modelFile, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return modelFile{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return modelFile{}, fmt.Errorf("failed to download %s: status %d", url, resp.StatusCode)
+	}
+
+	out, err := os.Create(localPath)
+	if err != nil {
+		return modelFile{}, err
+	}
+	defer out.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(out, h), resp.Body)
+	if err != nil {
+		return modelFile{}, err
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return modelFile{}, fmt.Errorf("incomplete download of %s: got %d of %d bytes", url, n, resp.ContentLength)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if want := checksumETag(resp.Header); want != "" && want != sum {
+		return modelFile{}, fmt.Errorf("checksum mismatch for %s", url)
+	}
+	if err := out.Close(); err != nil {
+		return modelFile{}, err
+	}
+	return modelFile{Size: n, SHA256: sum}, nil
+}
+
+// checksumETag returns the SHA-256 carried in an ETag header, if any.
+func checksumETag(h http.Header) string {
+	for _, key := range []string{"X-Linked-Etag", "Etag"} {
+		v := strings.TrimPrefix(h.Get(key), "W/")
+		v = strings.ToLower(strings.Trim(v, `"`))
+		if len(v) == sha256.Size*2 {
+			if _, err := hex.DecodeString(v); err == nil {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// ModelCacheEntry describes one model directory in the cache.
+type ModelCacheEntry struct {
+	Name     string
+	Path     string
+	Size     int64
+	LastUsed time.Time
+	Complete bool
+	Problem  string // Why the entry is incomplete
+}
+
+// ListModelCache reports the downloaded models under cacheDir, most recently
+// used first. Only directories created by the model downloader are listed, so
+// a cache directory shared with other data is safe to scan.
+func ListModelCache(cacheDir string) ([]ModelCacheEntry, error) {
+	dirents, err := os.ReadDir(cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []ModelCacheEntry
+	for _, d := range dirents {
+		if !d.IsDir() || !strings.HasPrefix(d.Name(), modelDirPrefix) {
+			continue
+		}
+		p := filepath.Join(cacheDir, d.Name())
+		e := ModelCacheEntry{Name: d.Name(), Path: p}
+		e.Size, e.LastUsed = dirUsage(p)
+		if strings.HasSuffix(d.Name(), modelPartialExt) {
+			e.Problem = "partial download"
+		} else if m, err := verifyModelDir(p, false); err != nil {
+			// A legacy cache with every required file is adopted when it is
+			// next loaded, so it must not be collected as incomplete.
+			if _, lerr := presentModelFiles(p, false); errors.Is(err, errNoModelManifest) && lerr == nil {
+				e.Complete = true
+			} else {
+				e.Problem = err.Error()
+			}
+		} else {
+			e.Complete = true
+			e.LastUsed = m.LastUsed
+			if e.LastUsed.IsZero() {
+				e.LastUsed = m.DownloadedAt
+			}
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LastUsed.After(entries[j].LastUsed) })
+	return entries, nil
+}
+
+// dirUsage returns the total size of the files under dir and the latest
+// modification time among them.
+func dirUsage(dir string) (int64, time.Time) {
+	var size int64
+	var latest time.Time
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() {
+			size += info.Size()
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return size, latest
+}
+
+// ModelGCOptions controls GCModelCache.
+type ModelGCOptions struct {
+	MaxBytes int64    // Total size to keep complete models under; 0 keeps all of them
+	Keep     []string // Model names that are never evicted, e.g. the configured ones
+	DryRun   bool     // Report what would be removed without deleting anything
+}
+
+// ModelGCResult lists what GCModelCache removed (or would remove).
+type ModelGCResult struct {
+	Removed []ModelCacheEntry
+	Freed   int64
+	Kept    []ModelCacheEntry
+}
+
+// GCModelCache removes incomplete downloads from cacheDir and then evicts the
+// least recently used complete models until the cache fits in opts.MaxBytes.
+func GCModelCache(cacheDir string, opts ModelGCOptions) (*ModelGCResult, error) {
+	entries, err := ListModelCache(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	keep := make(map[string]bool, len(opts.Keep))
+	for _, name := range opts.Keep {
+		keep[ModelCacheDirName(name)] = true
+	}
+
+	res := &ModelGCResult{}
+	remove := func(e ModelCacheEntry) error {
+		if !opts.DryRun {
+			if err := os.RemoveAll(e.Path); err != nil {
+				return fmt.Errorf("remove %s: %w", e.Path, err)
+			}
+		}
+		res.Removed = append(res.Removed, e)
+		res.Freed += e.Size
+		return nil
+	}
+
+	var complete []ModelCacheEntry
+	var total int64
+	for _, e := range entries {
+		switch {
+		case e.Complete:
+			complete = append(complete, e)
+			total += e.Size
+		case strings.HasSuffix(e.Name, modelPartialExt) && time.Since(e.LastUsed) < partialGracePeriod:
+			res.Kept = append(res.Kept, e)
+		default:
+			if err := remove(e); err != nil {
+				return res, err
+			}
+		}
+	}
+
+	// complete is ordered most recently used first; evict from the end.
+	for i := len(complete) - 1; i >= 0; i-- {
+		e := complete[i]
+		if opts.MaxBytes > 0 && total > opts.MaxBytes && !keep[e.Name] {
+			if err := remove(e); err != nil {
+				return res, err
+			}
+			total -= e.Size
+			continue
+		}
+		res.Kept = append(res.Kept, e)
+	}
+	return res, nil
+}
@@ -0,0 +1,181 @@
+package ingest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeCachedModel creates a complete cached model of roughly size bytes.
+func writeCachedModel(t *testing.T, cacheDir, name string, size int, lastUsed time.Time) string {
+	t.Helper()
+	dir := filepath.Join(cacheDir, ModelCacheDirName(name))
+	m := &modelManifest{Model: name, Files: map[string]modelFile{}, LastUsed: lastUsed}
+	for file, data := range map[string]string{
+		"model.onnx":     strings.Repeat("x", size),
+		"tokenizer.json": "{}",
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256([]byte(data))
+		m.Files[file] = modelFile{Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}
+	}
+	if err := writeModelManifest(dir, m); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestVerifyModelDir(t *testing.T) {
+	dir := writeCachedModel(t, t.TempDir(), "m", 10, time.Now())
+	if _, err := verifyModelDir(dir, true); err != nil {
+		t.Fatalf("expected valid model, got %v", err)
+	}
+
+	// Same size, different bytes: only the checksum catches it.
+	if err := os.WriteFile(filepath.Join(dir, "model.onnx"), []byte(strings.Repeat("y", 10)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyModelDir(dir, false); err != nil {
+		t.Errorf("size-only check should pass, got %v", err)
+	}
+	if _, err := verifyModelDir(dir, true); err == nil {
+		t.Error("expected checksum mismatch")
+	}
+
+	if err := os.Remove(filepath.Join(dir, modelManifestName)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyModelDir(dir, false); err == nil {
+		t.Error("a model without manifest must not be treated as complete")
+	}
+}
+
+func TestAdoptModelDir(t *testing.T) {
+	cacheDir := t.TempDir()
+	dir := writeCachedModel(t, cacheDir, "legacy", 10, time.Now())
+	if err := os.Remove(filepath.Join(dir, modelManifestName)); err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(VerifyCachedModel(cacheDir, "legacy"), ErrModelNotVerified) {
+		t.Error("a legacy cache should be reported as not verified")
+	}
+	entries, err := ListModelCache(cacheDir)
+	if err != nil || len(entries) != 1 || !entries[0].Complete {
+		t.Fatalf("a legacy cache with every required file should be complete: %+v %v", entries, err)
+	}
+
+	m, err := adoptModelDir(dir, "onnx-models/legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Files) != 2 || m.Files["model.onnx"].SHA256 == "" {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+	if err := VerifyCachedModel(cacheDir, "legacy"); err != nil {
+		t.Errorf("adopted cache should verify, got %v", err)
+	}
+
+	if _, err := adoptModelDir(t.TempDir(), "empty"); err == nil {
+		t.Error("a directory without the required files must not be adopted")
+	}
+	if err := VerifyCachedModel(cacheDir, "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("uncached model: got %v", err)
+	}
+}
+
+func TestDownloadModelFile_Validation(t *testing.T) {
+	body := "model bytes"
+	sum := sha256.Sum256([]byte(body))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Header().Set("X-Linked-Etag", `"`+hex.EncodeToString(sum[:])+`"`)
+			w.Write([]byte(body))
+		case "/corrupt":
+			w.Header().Set("X-Linked-Etag", `"`+strings.Repeat("0", 64)+`"`)
+			w.Write([]byte(body))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	info, err := downloadModelFile(srv.URL+"/ok", filepath.Join(dir, "ok"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != int64(len(body)) || info.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("unexpected file info: %+v", info)
+	}
+	if _, err := downloadModelFile(srv.URL+"/corrupt", filepath.Join(dir, "corrupt")); err == nil {
+		t.Error("expected checksum mismatch error")
+	}
+	if _, err := downloadModelFile(srv.URL+"/missing", filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for 404")
+	}
+}
+
+func TestGCModelCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	now := time.Now()
+	writeCachedModel(t, cacheDir, "old", 1000, now.Add(-72*time.Hour))
+	writeCachedModel(t, cacheDir, "configured", 1000, now.Add(-48*time.Hour))
+	writeCachedModel(t, cacheDir, "recent", 1000, now)
+
+	// An interrupted download from before manifests existed.
+	legacy := filepath.Join(cacheDir, ModelCacheDirName("legacy"))
+	if err := os.MkdirAll(legacy, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(legacy, "model.onnx"), []byte("trunc"), 0644)
+	// Unrelated data in a shared cache directory is never touched.
+	other := filepath.Join(cacheDir, "other-tool")
+	os.MkdirAll(other, 0755)
+
+	opts := ModelGCOptions{MaxBytes: 3000, Keep: []string{"onnx-models/configured"}, DryRun: true}
+	res, err := GCModelCache(cacheDir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Removed) != 2 {
+		t.Fatalf("expected 2 removals, got %+v", res.Removed)
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		t.Error("dry run must not delete anything")
+	}
+
+	opts.DryRun = false
+	res, err = GCModelCache(cacheDir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var removed []string
+	for _, e := range res.Removed {
+		removed = append(removed, e.Name)
+	}
+	if strings.Join(removed, ",") != "onnx-models_legacy,onnx-models_old" {
+		t.Errorf("unexpected removals: %v", removed)
+	}
+	for _, name := range []string{"configured", "recent"} {
+		if _, err := os.Stat(filepath.Join(cacheDir, ModelCacheDirName(name))); err != nil {
+			t.Errorf("%s should be kept: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Error("non-model directory was removed")
+	}
+}
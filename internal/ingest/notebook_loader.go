@@ -0,0 +1,236 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// NotebookLoader indexes Jupyter notebooks cell by cell instead of as one JSON
+// blob. Markdown and code cells become separate representations carrying the
+// cell index and language; textual outputs are appended to their code cell,
+// while images and other rich outputs (base64 payloads) are dropped.
+type NotebookLoader struct {
+	chunkSize int
+	overlap   int
+	chunking  Chunking
+}
+
+// NewNotebookLoader returns a NotebookLoader. Cells longer than chunkSize are
+// split further using the same word-boundary strategy as TextLoader.
+func NewNotebookLoader(chunkSize, overlap int) *NotebookLoader {
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	if overlap < 0 {
+		overlap = 0
+	}
+	return &NotebookLoader{chunkSize: chunkSize, overlap: overlap}
+}
+
+// SetChunking replaces the fixed-size chunks of nl with c.
+func (nl *NotebookLoader) SetChunking(c Chunking) { nl.chunking = c }
+
+func (nl *NotebookLoader) Extensions() []string {
+	return []string{".ipynb"}
+}
+
+// maxNotebookOutput caps the output text kept per code cell, so a cell that
+// prints a large table does not drown its source.
+const maxNotebookOutput = 2000
+
+// nbSource is a cell source or output text, stored either as one string or
+// as a list of lines.
+type nbSource string
+
+func (s *nbSource) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*s = nbSource(strings.Join(lines, ""))
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	*s = nbSource(str)
+	return nil
+}
+
+type nbOutput struct {
+	OutputType string   `json:"output_type"`
+	Text       nbSource `json:"text"`
+	// Data is decoded lazily: rich outputs such as application/json are objects.
+	Data   map[string]json.RawMessage `json:"data"`
+	EName  string                     `json:"ename"`
+	EValue string                     `json:"evalue"`
+}
+
+type nbCell struct {
+	CellType       string     `json:"cell_type"`
+	Source         nbSource   `json:"source"`
+	Input          nbSource   `json:"input"` // nbformat 3 code cells
+	Language       string     `json:"language"`
+	ExecutionCount *int       `json:"execution_count"`
+	PromptNumber   *int       `json:"prompt_number"` // nbformat 3
+	Outputs        []nbOutput `json:"outputs"`
+}
+
+type nbMetadata struct {
+	KernelSpec struct {
+		Language string `json:"language"`
+		Name     string `json:"name"`
+	} `json:"kernelspec"`
+	LanguageInfo struct {
+		Name string `json:"name"`
+	} `json:"language_info"`
+}
+
+type notebook struct {
+	Cells      []nbCell   `json:"cells"`
+	Metadata   nbMetadata `json:"metadata"`
+	Worksheets []struct {
+		Cells []nbCell `json:"cells"`
+	} `json:"worksheets"` // nbformat 3
+}
+
+// cellMagicLanguages maps IPython cell magics to the language of the cell body.
+var cellMagicLanguages = map[string]string{
+	"bash": "bash", "sh": "bash", "sql": "sql", "html": "html",
+	"javascript": "javascript", "js": "javascript", "latex": "latex",
+	"markdown": "markdown", "perl": "perl", "ruby": "ruby", "r": "r",
+}
+
+func (nl *NotebookLoader) Load(ctx context.Context, relPath string, absPath string) ([]Representation, error) {
+	slog.Info("Loading notebook file", "relative_path", relPath, "absolute_path", absPath)
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		slog.Error("Failed to read file for NotebookLoader", "path", absPath, "error", err)
+		return nil, err
+	}
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return nil, fmt.Errorf("notebook %s: %w", relPath, err)
+	}
+	cells := nb.Cells
+	for _, ws := range nb.Worksheets {
+		cells = append(cells, ws.Cells...)
+	}
+
+	kernelLang := strings.ToLower(nb.Metadata.KernelSpec.Language)
+	if kernelLang == "" {
+		kernelLang = strings.ToLower(nb.Metadata.LanguageInfo.Name)
+	}
+	if kernelLang == "" {
+		kernelLang = "python"
+	}
+
+	chunker := nl.chunking.chunker(nl.chunkSize, nl.overlap)
+	var reps []Representation
+	for i, cell := range cells {
+		var text, lang string
+		switch cell.CellType {
+		case "markdown", "heading":
+			text, lang = string(cell.Source), "markdown"
+		case "code":
+			text = string(cell.Source)
+			if text == "" {
+				text = string(cell.Input)
+			}
+			lang = codeCellLanguage(text, cell.Language, kernelLang)
+		default:
+			continue // raw cells are not rendered
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if cell.CellType == "code" {
+			if out := notebookOutputText(cell.Outputs); out != "" {
+				text = strings.TrimRight(text, "\n") + "\n\n# Output:\n" + out
+			}
+		}
+
+		for _, span := range chunker.Spans(text) {
+			if strings.TrimSpace(span.Text) == "" {
+				continue
+			}
+			meta := map[string]string{
+				"source":     "NotebookLoader",
+				"path":       relPath,
+				"cell_index": strconv.Itoa(i),
+				"cell_type":  cell.CellType,
+				"language":   lang,
+				"offset":     strconv.Itoa(span.Start),
+			}
+			if n := cell.ExecutionCount; n != nil {
+				meta["execution_count"] = strconv.Itoa(*n)
+			} else if n := cell.PromptNumber; n != nil {
+				meta["execution_count"] = strconv.Itoa(*n)
+			}
+			reps = append(reps, Representation{
+				ID:       ChunkID(relPath, "text", int64(len(reps))),
+				Path:     relPath,
+				Modality: "text",
+				Text:     span.Text,
+				Meta:     meta,
+			})
+		}
+	}
+	slog.Debug("Created", "cells", len(cells), "chunks", len(reps), "relPath", relPath)
+	return reps, nil
+}
+
+// codeCellLanguage resolves a code cell's language from a leading cell magic
+// such as "%%sql", the nbformat 3 per-cell language, or the kernel language.
+func codeCellLanguage(source, cellLang, kernelLang string) string {
+	first, _, _ := strings.Cut(strings.TrimLeft(source, " \t\n"), "\n")
+	if strings.HasPrefix(first, "%%") {
+		magic := strings.Fields(strings.TrimPrefix(first, "%%"))
+		if len(magic) > 0 {
+			if lang, ok := cellMagicLanguages[strings.ToLower(magic[0])]; ok {
+				return lang
+			}
+		}
+	}
+	if cellLang != "" {
+		return strings.ToLower(cellLang)
+	}
+	return kernelLang
+}
+
+// notebookOutputText collects the plain-text outputs of a code cell: streams,
+// text/plain results and error summaries. Images, HTML and other rich MIME
+// bundles are skipped.
+func notebookOutputText(outputs []nbOutput) string {
+	var parts []string
+	for _, o := range outputs {
+		switch o.OutputType {
+		case "stream":
+			parts = append(parts, string(o.Text))
+		case "execute_result", "display_data", "pyout":
+			var t nbSource
+			if raw, ok := o.Data["text/plain"]; ok && json.Unmarshal(raw, &t) == nil {
+				parts = append(parts, string(t))
+			} else if o.Text != "" { // nbformat 3 keeps text/plain in "text"
+				parts = append(parts, string(o.Text))
+			}
+		case "error", "pyerr":
+			parts = append(parts, o.EName+": "+o.EValue)
+		}
+	}
+	out := strings.TrimSpace(strings.Join(parts, "\n"))
+	if len(out) > maxNotebookOutput {
+		cut := maxNotebookOutput
+		for cut > 0 && !utf8.RuneStart(out[cut]) {
+			cut--
+		}
+		out = out[:cut] + "…"
+	}
+	return out
+}
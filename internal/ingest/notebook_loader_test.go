@@ -0,0 +1,66 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNotebookLoader(t *testing.T) {
+	nb := `{
+ "nbformat": 4,
+ "metadata": {"kernelspec": {"name": "python3", "language": "python"}},
+ "cells": [
+  {"cell_type": "markdown", "metadata": {}, "source": ["# Churn analysis\n", "Load the data first."]},
+  {"cell_type": "code", "execution_count": 3, "metadata": {}, "source": "df = load()\ndf.head()",
+   "outputs": [
+    {"output_type": "stream", "name": "stdout", "text": ["loaded 42 rows\n"]},
+    {"output_type": "display_data", "data": {"image/png": "iVBORw0KGgoAAAANSUhEUgAAAAEAAAAB", "text/plain": ["<Figure size 640x480>"]}},
+    {"output_type": "execute_result", "execution_count": 3, "data": {"application/json": {"rows": 42}, "text/html": "<table></table>"}}
+   ]},
+  {"cell_type": "code", "execution_count": null, "metadata": {}, "source": [], "outputs": []},
+  {"cell_type": "raw", "metadata": {}, "source": "ignored"},
+  {"cell_type": "code", "execution_count": 4, "metadata": {}, "source": "%%sql\nSELECT * FROM users", "outputs": []}
+ ]
+}`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "analysis.ipynb")
+	if err := os.WriteFile(path, []byte(nb), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reps, err := NewNotebookLoader(1000, 0).Load(context.Background(), "analysis.ipynb", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reps) != 3 {
+		t.Fatalf("expected 3 representations, got %d", len(reps))
+	}
+
+	md := reps[0]
+	if md.Meta["cell_type"] != "markdown" || md.Meta["cell_index"] != "0" || md.Meta["language"] != "markdown" {
+		t.Errorf("unexpected markdown meta: %v", md.Meta)
+	}
+	if md.Text != "# Churn analysis\nLoad the data first." {
+		t.Errorf("unexpected markdown text: %q", md.Text)
+	}
+
+	code := reps[1]
+	if code.Meta["cell_index"] != "1" || code.Meta["language"] != "python" || code.Meta["execution_count"] != "3" {
+		t.Errorf("unexpected code meta: %v", code.Meta)
+	}
+	for _, want := range []string{"df.head()", "loaded 42 rows", "<Figure size 640x480>"} {
+		if !strings.Contains(code.Text, want) {
+			t.Errorf("code cell missing %q: %q", want, code.Text)
+		}
+	}
+	if strings.Contains(code.Text, "iVBOR") || strings.Contains(code.Text, "<table>") {
+		t.Errorf("rich outputs should be skipped: %q", code.Text)
+	}
+
+	if sql := reps[2]; sql.Meta["cell_index"] != "4" || sql.Meta["language"] != "sql" {
+		t.Errorf("expected %%%%sql cell to be tagged sql: %v", sql.Meta)
+	}
+}
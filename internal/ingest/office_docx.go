@@ -0,0 +1,293 @@
+package ingest
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var reHeadingStyle = regexp.MustCompile(`(?i)^heading ?([1-9])$`)
+
+// parseDOCX reads word/document.xml, word/styles.xml and docProps/core.xml
+// from a Word document.
+func parseDOCX(path string) (*officeDoc, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("open docx: %w", err)
+	}
+	defer zr.Close()
+
+	styles, err := docxHeadingStyles(findZipFile(&zr.Reader, "word/styles.xml"))
+	if err != nil {
+		return nil, fmt.Errorf("parse docx styles: %w", err)
+	}
+	body := findZipFile(&zr.Reader, "word/document.xml")
+	if body == nil {
+		return nil, fmt.Errorf("docx: missing word/document.xml")
+	}
+	blocks, err := docxBlocks(body, styles)
+	if err != nil {
+		return nil, fmt.Errorf("parse docx body: %w", err)
+	}
+	props, err := readXMLProps(findZipFile(&zr.Reader, "docProps/core.xml"), map[string]string{
+		"title":          "title",
+		"subject":        "subject",
+		"creator":        "author",
+		"lastModifiedBy": "last_modified_by",
+		"keywords":       "keywords",
+		"description":    "description",
+		"created":        "created",
+		"modified":       "modified",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parse docx properties: %w", err)
+	}
+	return &officeDoc{blocks: blocks, props: props}, nil
+}
+
+// docxHeadingStyles maps paragraph style IDs to heading levels, using the
+// style's outline level or, failing that, its name ("heading 2", "Title").
+func docxHeadingStyles(f *zip.File) (map[string]int, error) {
+	styles := map[string]int{}
+	if f == nil {
+		return styles, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var (
+		id, name string
+		outline  = -1
+	)
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return styles, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "style":
+				id, name, outline = xmlAttr(t, "styleId"), "", -1
+			case "name":
+				name = xmlAttr(t, "val")
+			case "outlineLvl":
+				if n, err := strconv.Atoi(xmlAttr(t, "val")); err == nil {
+					outline = n
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local != "style" || id == "" {
+				continue
+			}
+			if lvl := headingLevel(name, outline); lvl > 0 {
+				styles[id] = lvl
+			}
+		}
+	}
+}
+
+// headingLevel derives a 1-based heading level from a style name and a
+// 0-based outline level (-1 when unset, 9 meaning body text).
+func headingLevel(name string, outline int) int {
+	if outline >= 0 && outline < 9 {
+		return outline + 1
+	}
+	if m := reHeadingStyle.FindStringSubmatch(strings.TrimSpace(name)); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	if strings.EqualFold(strings.TrimSpace(name), "title") {
+		return 1
+	}
+	return 0
+}
+
+// docxBlocks walks the document body. Text is only taken from <w:t> runs so
+// field instructions and deleted revisions are left out; paragraphs inside
+// tables become cell text.
+func docxBlocks(f *zip.File, styles map[string]int) ([]docBlock, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var (
+		blocks     []docBlock
+		para       strings.Builder
+		paraDepth  int
+		paraLevel  int
+		inText     bool
+		tableDepth int
+		rows       [][]string
+		row        []string
+		cell       strings.Builder
+	)
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return blocks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				if paraDepth == 0 {
+					para.Reset()
+					paraLevel = 0
+				}
+				paraDepth++
+			case "pStyle":
+				if paraDepth == 1 {
+					if lvl, ok := styles[xmlAttr(t, "val")]; ok {
+						paraLevel = lvl
+					} else {
+						paraLevel = headingLevel(xmlAttr(t, "val"), -1)
+					}
+				}
+			case "outlineLvl":
+				if n, err := strconv.Atoi(xmlAttr(t, "val")); err == nil && paraDepth == 1 {
+					paraLevel = headingLevel("", n)
+				}
+			case "t":
+				inText = true
+			case "tab", "br", "cr":
+				para.WriteByte(' ')
+			case "tbl":
+				tableDepth++
+				if tableDepth == 1 {
+					rows = nil
+				}
+			case "tr":
+				if tableDepth == 1 {
+					row = nil
+				}
+			case "tc":
+				if tableDepth == 1 {
+					cell.Reset()
+				}
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				paraDepth--
+				if paraDepth > 0 {
+					para.WriteByte(' ')
+					continue
+				}
+				if tableDepth > 0 {
+					cell.WriteString(para.String())
+					cell.WriteByte(' ')
+					continue
+				}
+				kind := "paragraph"
+				if paraLevel > 0 {
+					kind = "heading"
+				}
+				blocks = appendDocBlock(blocks, docBlock{kind: kind, level: paraLevel, text: para.String()})
+			case "tc":
+				if tableDepth == 1 {
+					row = append(row, strings.Join(strings.Fields(cell.String()), " "))
+				}
+			case "tr":
+				if tableDepth == 1 {
+					rows = append(rows, row)
+				}
+			case "tbl":
+				tableDepth--
+				if tableDepth == 0 {
+					blocks = appendDocBlock(blocks, docBlock{kind: "table", text: formatTable(rows)})
+				}
+			}
+		}
+	}
+}
+
+// readXMLProps collects the text of the named leaf elements, keyed by their
+// local name, into a property map. Repeated elements are joined with ", ".
+func readXMLProps(f *zip.File, names map[string]string) (map[string]string, error) {
+	props := map[string]string{}
+	if f == nil {
+		return props, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var (
+		key string
+		buf strings.Builder
+	)
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return props, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			key = names[t.Name.Local]
+			buf.Reset()
+		case xml.CharData:
+			if key != "" {
+				buf.Write(t)
+			}
+		case xml.EndElement:
+			if key == "" {
+				continue
+			}
+			if v := strings.TrimSpace(buf.String()); v != "" {
+				if prev, ok := props[key]; ok {
+					v = prev + ", " + v
+				}
+				props[key] = v
+			}
+			key = ""
+		}
+	}
+}
+
+func findZipFile(zr *zip.Reader, name string) *zip.File {
+	for _, f := range zr.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func xmlAttr(se xml.StartElement, local string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,199 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// OfficeLoader extracts paragraphs and tables from word-processor documents
+// (.docx, .odt, .rtf). Headings, recognised from paragraph styles or outline
+// levels, start a new section so chunks never straddle two headings. Tables
+// are emitted as their own chunks, and document properties (title, author,
+// modified date, ...) are copied into every chunk's metadata as "doc.<key>".
+type OfficeLoader struct {
+	chunkSize int
+	overlap   int
+	chunking  Chunking
+}
+
+// NewOfficeLoader returns an OfficeLoader. Sections longer than chunkSize are
+// split further using the same word-boundary strategy as TextLoader.
+func NewOfficeLoader(chunkSize, overlap int) *OfficeLoader {
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	if overlap < 0 {
+		overlap = 0
+	}
+	return &OfficeLoader{chunkSize: chunkSize, overlap: overlap}
+}
+
+// SetChunking replaces the fixed-size chunks of ol with c.
+func (ol *OfficeLoader) SetChunking(c Chunking) { ol.chunking = c }
+
+func (ol *OfficeLoader) Extensions() []string {
+	return []string{".docx", ".odt", ".rtf"}
+}
+
+// docBlock is one structural element of an office document.
+type docBlock struct {
+	kind  string // "heading", "paragraph" or "table"
+	level int    // heading level, 1-based
+	text  string
+}
+
+// officeDoc is the format-independent result of parsing an office document.
+type officeDoc struct {
+	blocks []docBlock
+	props  map[string]string
+}
+
+func (ol *OfficeLoader) Load(ctx context.Context, relPath string, absPath string) ([]Representation, error) {
+	slog.Info("Loading office document", "relative_path", relPath, "absolute_path", absPath)
+
+	var (
+		doc *officeDoc
+		err error
+	)
+	switch ext := strings.ToLower(filepath.Ext(absPath)); ext {
+	case ".docx":
+		doc, err = parseDOCX(absPath)
+	case ".odt":
+		doc, err = parseODT(absPath)
+	case ".rtf":
+		doc, err = parseRTF(absPath)
+	default:
+		return nil, fmt.Errorf("office loader: unsupported extension %q", ext)
+	}
+	if err != nil {
+		slog.Error("Failed to parse office document", "path", absPath, "error", err)
+		return nil, err
+	}
+
+	reps := ol.buildRepresentations(relPath, doc)
+	slog.Debug("Created", "chunks", len(reps), "relPath", relPath)
+	return reps, nil
+}
+
+// buildRepresentations groups paragraphs into heading sections and chunks
+// each section and table.
+func (ol *OfficeLoader) buildRepresentations(relPath string, doc *officeDoc) []Representation {
+	var (
+		reps     []Representation
+		headings [9]string
+		level    int
+		section  []string
+		hasBody  bool
+	)
+	breadcrumb := func() []string {
+		var crumbs []string
+		for _, h := range headings[:level] {
+			if h != "" {
+				crumbs = append(crumbs, h)
+			}
+		}
+		return crumbs
+	}
+	emit := func(kind, text string) {
+		chunker := ol.chunking.chunker(ol.chunkSize, ol.overlap)
+		crumbs := breadcrumb()
+		for _, span := range chunker.Spans(text) {
+			if strings.TrimSpace(span.Text) == "" {
+				continue
+			}
+			meta := map[string]string{
+				"source": "OfficeLoader",
+				"path":   relPath,
+				"kind":   kind,
+			}
+			if len(crumbs) > 0 {
+				meta["heading"] = crumbs[len(crumbs)-1]
+				meta["heading_level"] = strconv.Itoa(level)
+				meta["breadcrumb"] = strings.Join(crumbs, " > ")
+			}
+			for k, v := range doc.props {
+				meta["doc."+k] = v
+			}
+			reps = append(reps, Representation{
+				ID:       ChunkID(relPath, "text", int64(len(reps))),
+				Path:     relPath,
+				Modality: "text",
+				Text:     span.Text,
+				Meta:     meta,
+			})
+		}
+	}
+	flush := func() {
+		// A heading with no paragraphs of its own is carried by the
+		// breadcrumb of the sections below it.
+		if hasBody {
+			emit("section", strings.Join(section, "\n\n"))
+		}
+		section = nil
+		hasBody = false
+	}
+
+	for _, b := range doc.blocks {
+		switch b.kind {
+		case "heading":
+			flush()
+			lvl := b.level
+			if lvl < 1 {
+				lvl = 1
+			} else if lvl > len(headings) {
+				lvl = len(headings)
+			}
+			headings[lvl-1] = b.text
+			for i := lvl; i < len(headings); i++ {
+				headings[i] = ""
+			}
+			level = lvl
+			section = []string{b.text}
+		case "table":
+			flush()
+			emit("table", b.text)
+		default:
+			section = append(section, b.text)
+			hasBody = true
+		}
+	}
+	flush()
+	return reps
+}
+
+// appendDocBlock normalises whitespace and drops empty blocks.
+func appendDocBlock(blocks []docBlock, b docBlock) []docBlock {
+	if b.kind != "table" {
+		b.text = strings.Join(strings.Fields(b.text), " ")
+	} else {
+		b.text = strings.TrimSpace(b.text)
+	}
+	if b.text == "" {
+		return blocks
+	}
+	return append(blocks, b)
+}
+
+// formatTable renders table rows one per line with " | " between cells.
+func formatTable(rows [][]string) string {
+	var sb strings.Builder
+	for _, row := range rows {
+		empty := true
+		for _, c := range row {
+			if c != "" {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			continue
+		}
+		sb.WriteString(strings.Join(row, " | "))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
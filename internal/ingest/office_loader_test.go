@@ -0,0 +1,152 @@
+package ingest
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, body := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkOfficeChunks asserts the section/table layout shared by all formats.
+func checkOfficeChunks(t *testing.T, reps []Representation) {
+	t.Helper()
+	if len(reps) != 3 {
+		for _, r := range reps {
+			t.Logf("%s %q %v", r.ID, r.Text, r.Meta)
+		}
+		t.Fatalf("expected 3 chunks, got %d", len(reps))
+	}
+	if reps[0].Meta["kind"] != "section" || reps[0].Meta["breadcrumb"] != "Report" || !strings.Contains(reps[0].Text, "Opening remarks.") {
+		t.Errorf("unexpected first chunk: %q %v", reps[0].Text, reps[0].Meta)
+	}
+	if reps[1].Meta["breadcrumb"] != "Report > Results" || !strings.HasPrefix(reps[1].Text, "Results") || !strings.Contains(reps[1].Text, "Numbers improved.") {
+		t.Errorf("unexpected second chunk: %q %v", reps[1].Text, reps[1].Meta)
+	}
+	if reps[2].Meta["kind"] != "table" || !strings.Contains(reps[2].Text, "Region | Sales\nNorth | 42") {
+		t.Errorf("unexpected table chunk: %q %v", reps[2].Text, reps[2].Meta)
+	}
+	for _, r := range reps {
+		if r.Meta["doc.title"] != "Quarterly Report" || r.Meta["doc.author"] != "Ada Lovelace" {
+			t.Errorf("missing document properties on %s: %v", r.ID, r.Meta)
+		}
+	}
+}
+
+func TestOfficeLoader_DOCX(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "report.docx")
+	writeZip(t, file, map[string]string{
+		"word/styles.xml": `<w:styles xmlns:w="w">
+<w:style w:type="paragraph" w:styleId="Berschrift1"><w:name w:val="heading 1"/></w:style>
+<w:style w:type="paragraph" w:styleId="Sub"><w:name w:val="Sub"/><w:pPr><w:outlineLvl w:val="1"/></w:pPr></w:style>
+</w:styles>`,
+		"word/document.xml": `<w:document xmlns:w="w"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Berschrift1"/></w:pPr><w:r><w:t>Report</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Opening </w:t></w:r><w:r><w:instrText>PAGE</w:instrText><w:t>remarks.</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Sub"/></w:pPr><w:r><w:t>Results</w:t></w:r></w:p>
+<w:p><w:r><w:t>Numbers improved.</w:t></w:r></w:p>
+<w:tbl>
+<w:tr><w:tc><w:p><w:r><w:t>Region</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Sales</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>North</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>42</w:t></w:r></w:p></w:tc></w:tr>
+</w:tbl>
+</w:body></w:document>`,
+		"docProps/core.xml": `<cp:coreProperties xmlns:cp="cp" xmlns:dc="dc" xmlns:dcterms="dcterms">
+<dc:title>Quarterly Report</dc:title><dc:creator>Ada Lovelace</dc:creator>
+<dcterms:modified>2024-03-01T10:00:00Z</dcterms:modified>
+</cp:coreProperties>`,
+	})
+
+	reps, err := NewOfficeLoader(1000, 0).Load(context.Background(), "report.docx", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOfficeChunks(t, reps)
+	if reps[0].Meta["doc.modified"] != "2024-03-01T10:00:00Z" {
+		t.Errorf("modified = %q", reps[0].Meta["doc.modified"])
+	}
+	if strings.Contains(reps[0].Text, "PAGE") {
+		t.Errorf("field instruction leaked into text: %q", reps[0].Text)
+	}
+}
+
+func TestOfficeLoader_ODT(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "report.odt")
+	writeZip(t, file, map[string]string{
+		"content.xml": `<office:document-content xmlns:office="o" xmlns:text="t" xmlns:table="tb"><office:body><office:text>
+<text:h text:outline-level="1">Report</text:h>
+<text:p>Opening<text:s/>remarks.<text:note><text:note-body><text:p>Footnote</text:p></text:note-body></text:note></text:p>
+<text:h text:outline-level="2">Results</text:h>
+<text:p>Numbers <text:span>improved.</text:span></text:p>
+<table:table>
+<table:table-row><table:table-cell><text:p>Region</text:p></table:table-cell><table:table-cell><text:p>Sales</text:p></table:table-cell></table:table-row>
+<table:table-row><table:table-cell><text:p>North</text:p></table:table-cell><table:table-cell><text:p>42</text:p></table:table-cell></table:table-row>
+</table:table>
+</office:text></office:body></office:document-content>`,
+		"meta.xml": `<office:document-meta xmlns:office="o" xmlns:dc="dc" xmlns:meta="m"><office:meta>
+<dc:title>Quarterly Report</dc:title><meta:initial-creator>Ada Lovelace</meta:initial-creator><dc:creator>Bob</dc:creator>
+</office:meta></office:document-meta>`,
+	})
+
+	reps, err := NewOfficeLoader(1000, 0).Load(context.Background(), "report.odt", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOfficeChunks(t, reps)
+	if strings.Contains(reps[0].Text, "Footnote") {
+		t.Errorf("footnote leaked into text: %q", reps[0].Text)
+	}
+	if reps[0].Meta["doc.last_modified_by"] != "Bob" {
+		t.Errorf("last_modified_by = %q", reps[0].Meta["doc.last_modified_by"])
+	}
+}
+
+func TestOfficeLoader_RTF(t *testing.T) {
+	doc := `{\rtf1\ansi\ansicpg1252\deff0{\fonttbl{\f0 Times;}}
+{\stylesheet{\s0 Normal;}{\s1\outlinelevel0 heading 1;}{\s2 heading 2;}}
+{\info{\title Quarterly Report}{\author Ada Lovelace}{\revtim\yr2024\mo3\dy1\hr10\min5}}
+\pard\s1 Report\par
+\pard Opening {\*\bkmkstart x}remarks.\par
+\pard\s2 Results\par
+\pard Numbers improved.\par
+\trowd\cellx1000\cellx2000
+\pard\intbl Region\cell Sales\cell\row
+\pard\intbl North\cell 42\cell\row
+\pard\par
+}`
+	file := filepath.Join(t.TempDir(), "report.rtf")
+	if err := os.WriteFile(file, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reps, err := NewOfficeLoader(1000, 0).Load(context.Background(), "report.rtf", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOfficeChunks(t, reps)
+	if reps[0].Meta["doc.modified"] != "2024-03-01T10:05:00" {
+		t.Errorf("modified = %q", reps[0].Meta["doc.modified"])
+	}
+}
@@ -0,0 +1,154 @@
+package ingest
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parseODT reads content.xml and meta.xml from an OpenDocument text file.
+func parseODT(path string) (*officeDoc, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("open odt: %w", err)
+	}
+	defer zr.Close()
+
+	content := findZipFile(&zr.Reader, "content.xml")
+	if content == nil {
+		return nil, fmt.Errorf("odt: missing content.xml")
+	}
+	blocks, err := odtBlocks(content)
+	if err != nil {
+		return nil, fmt.Errorf("parse odt content: %w", err)
+	}
+	props, err := readXMLProps(findZipFile(&zr.Reader, "meta.xml"), map[string]string{
+		"title":           "title",
+		"subject":         "subject",
+		"initial-creator": "author",
+		"creator":         "last_modified_by",
+		"keyword":         "keywords",
+		"description":     "description",
+		"creation-date":   "created",
+		"date":            "modified",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parse odt metadata: %w", err)
+	}
+	if _, ok := props["author"]; !ok && props["last_modified_by"] != "" {
+		props["author"] = props["last_modified_by"]
+	}
+	return &officeDoc{blocks: blocks, props: props}, nil
+}
+
+// odtBlocks walks the office:text body. <text:h> carries its outline level
+// directly; footnotes, annotations and tracked deletions are skipped.
+func odtBlocks(f *zip.File) ([]docBlock, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var (
+		blocks     []docBlock
+		para       strings.Builder
+		paraDepth  int
+		paraLevel  int
+		tableDepth int
+		rows       [][]string
+		row        []string
+		cell       strings.Builder
+	)
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return blocks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "note", "annotation", "tracked-changes":
+				if err := dec.Skip(); err != nil {
+					return nil, err
+				}
+			case "p", "h":
+				if paraDepth == 0 {
+					para.Reset()
+					paraLevel = 0
+					if t.Name.Local == "h" {
+						paraLevel = 1
+						if n, err := strconv.Atoi(xmlAttr(t, "outline-level")); err == nil && n > 0 {
+							paraLevel = n
+						}
+					}
+				}
+				paraDepth++
+			case "s":
+				n, err := strconv.Atoi(xmlAttr(t, "c"))
+				if err != nil || n < 1 {
+					n = 1
+				}
+				para.WriteString(strings.Repeat(" ", n))
+			case "tab", "line-break":
+				para.WriteByte(' ')
+			case "table":
+				tableDepth++
+				if tableDepth == 1 {
+					rows = nil
+				}
+			case "table-row":
+				if tableDepth == 1 {
+					row = nil
+				}
+			case "table-cell":
+				if tableDepth == 1 {
+					cell.Reset()
+				}
+			}
+		case xml.CharData:
+			if paraDepth > 0 {
+				para.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p", "h":
+				paraDepth--
+				if paraDepth > 0 {
+					para.WriteByte(' ')
+					continue
+				}
+				if tableDepth > 0 {
+					cell.WriteString(para.String())
+					cell.WriteByte(' ')
+					continue
+				}
+				kind := "paragraph"
+				if paraLevel > 0 {
+					kind = "heading"
+				}
+				blocks = appendDocBlock(blocks, docBlock{kind: kind, level: paraLevel, text: para.String()})
+			case "table-cell":
+				if tableDepth == 1 {
+					row = append(row, strings.Join(strings.Fields(cell.String()), " "))
+				}
+			case "table-row":
+				if tableDepth == 1 {
+					rows = append(rows, row)
+				}
+			case "table":
+				tableDepth--
+				if tableDepth == 0 {
+					blocks = appendDocBlock(blocks, docBlock{kind: "table", text: formatTable(rows)})
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package ingest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// rtfSkipDestinations are groups whose content is not document text.
+var rtfSkipDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "listtable": true, "listoverridetable": true,
+	"revtbl": true, "rsidtbl": true, "generator": true, "pict": true, "object": true,
+	"header": true, "headerl": true, "headerr": true, "headerf": true,
+	"footer": true, "footerl": true, "footerr": true, "footerf": true,
+	"footnote": true, "fldinst": true, "nonshppict": true, "themedata": true,
+	"colorschememapping": true, "datastore": true, "latentstyles": true, "xmlnstbl": true,
+}
+
+// rtfInfoFields maps \info sub-destinations to property names.
+var rtfInfoFields = map[string]string{
+	"title":    "title",
+	"subject":  "subject",
+	"author":   "author",
+	"operator": "last_modified_by",
+	"keywords": "keywords",
+	"doccomm":  "description",
+	"creatim":  "created",
+	"revtim":   "modified",
+}
+
+// rtfTimeParts indexes the fields of a \creatim or \revtim group.
+var rtfTimeParts = map[string]int{"yr": 0, "mo": 1, "dy": 2, "hr": 3, "min": 4}
+
+// rtfSymbols maps control words that stand for a single character.
+var rtfSymbols = map[string]string{
+	"tab": " ", "line": " ", "emdash": "—", "endash": "–",
+	"lquote": "‘", "rquote": "’", "ldblquote": "“", "rdblquote": "”",
+	"bullet": "•", "emspace": " ", "enspace": " ", "qmspace": " ",
+}
+
+type rtfGroup struct {
+	dest string // "" for body text
+	uc   int    // bytes to skip after \uN
+}
+
+// rtfParser is a small RTF reader that understands just enough of the format
+// to recover paragraphs, table cells, heading levels and \info properties.
+type rtfParser struct {
+	data []byte
+	pos  int
+
+	stack      []rtfGroup
+	cur        rtfGroup
+	groupStart bool // no control word seen yet in the current group
+	skipBytes  int  // fallback characters still to drop after \uN
+
+	para    strings.Builder
+	level   int
+	inTable bool
+	cell    strings.Builder
+	row     []string
+	rows    [][]string
+	blocks  []docBlock
+	styles  map[int]int // stylesheet number -> heading level
+	style   rtfStyle    // stylesheet entry being read
+	info    map[string]*strings.Builder
+	times   map[string]*[5]int
+	curTime *[5]int
+}
+
+type rtfStyle struct {
+	num     int
+	outline int
+	name    strings.Builder
+}
+
+// parseRTF extracts paragraphs, tables and document info from an RTF file.
+func parseRTF(path string) (*officeDoc, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte(`{\rtf`)) {
+		return nil, fmt.Errorf("rtf: missing {\\rtf header")
+	}
+	p := &rtfParser{
+		data:   data,
+		cur:    rtfGroup{uc: 1},
+		styles: map[int]int{},
+		info:   map[string]*strings.Builder{},
+		times:  map[string]*[5]int{},
+	}
+	p.run()
+	p.endParagraph()
+	p.flushTable()
+
+	props := map[string]string{}
+	for k, b := range p.info {
+		if v := strings.TrimSpace(b.String()); v != "" {
+			props[k] = v
+		}
+	}
+	for k, t := range p.times {
+		if t[0] > 0 {
+			props[k] = fmt.Sprintf("%04d-%02d-%02dT%02d:%02d:00", t[0], t[1], t[2], t[3], t[4])
+		}
+	}
+	return &officeDoc{blocks: p.blocks, props: props}, nil
+}
+
+func (p *rtfParser) run() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch c {
+		case '{':
+			p.pos++
+			p.stack = append(p.stack, p.cur)
+			if p.cur.dest == "stylesheet" {
+				p.cur.dest = "style"
+				p.style = rtfStyle{outline: -1}
+			}
+			p.groupStart = true
+		case '}':
+			p.pos++
+			p.closeGroup()
+			if len(p.stack) == 0 {
+				return
+			}
+			p.cur = p.stack[len(p.stack)-1]
+			p.stack = p.stack[:len(p.stack)-1]
+			p.groupStart = false
+		case '\\':
+			p.pos++
+			p.controlSequence()
+		case '\r', '\n':
+			p.pos++
+		default:
+			p.pos++
+			p.text(string(charmap.Windows1252.DecodeByte(c)))
+		}
+	}
+}
+
+func (p *rtfParser) closeGroup() {
+	switch {
+	case p.cur.dest == "style":
+		name := strings.TrimSuffix(strings.TrimSpace(p.style.name.String()), ";")
+		if lvl := headingLevel(name, p.style.outline); lvl > 0 {
+			p.styles[p.style.num] = lvl
+		}
+	case strings.HasPrefix(p.cur.dest, "time:"):
+		p.curTime = nil
+	}
+}
+
+func (p *rtfParser) controlSequence() {
+	if p.pos >= len(p.data) {
+		return
+	}
+	c := p.data[p.pos]
+	if !isASCIILetter(c) {
+		p.pos++
+		switch c {
+		case '\'':
+			if p.pos+2 <= len(p.data) {
+				if b, err := strconv.ParseUint(string(p.data[p.pos:p.pos+2]), 16, 8); err == nil {
+					p.pos += 2
+					p.text(string(charmap.Windows1252.DecodeByte(byte(b))))
+				}
+			}
+		case '*':
+			// Ignorable destination that this reader does not know about.
+			p.cur.dest = "skip"
+		case '~':
+			p.text(" ")
+		case '_':
+			p.text("-")
+		case '\\', '{', '}':
+			p.text(string(rune(c)))
+		case '\r', '\n':
+			p.word("par", 0, false)
+		}
+		return
+	}
+
+	start := p.pos
+	for p.pos < len(p.data) && isASCIILetter(p.data[p.pos]) {
+		p.pos++
+	}
+	name := string(p.data[start:p.pos])
+	param, hasParam, neg := 0, false, false
+	if p.pos < len(p.data) && p.data[p.pos] == '-' {
+		neg = true
+		p.pos++
+	}
+	for p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '9' {
+		param = param*10 + int(p.data[p.pos]-'0')
+		hasParam = true
+		p.pos++
+	}
+	if neg {
+		param = -param
+	}
+	if p.pos < len(p.data) && p.data[p.pos] == ' ' {
+		p.pos++
+	}
+	p.word(name, param, hasParam)
+}
+
+func (p *rtfParser) word(name string, param int, hasParam bool) {
+	first := p.groupStart
+	p.groupStart = false
+	if p.cur.dest == "skip" {
+		return
+	}
+
+	// Destinations are only recognised as the first word of a group.
+	if first {
+		switch {
+		case rtfSkipDestinations[name]:
+			p.cur.dest = "skip"
+			return
+		case name == "stylesheet" || name == "info":
+			p.cur.dest = name
+			return
+		case p.cur.dest == "info" && rtfInfoFields[name] != "":
+			key := rtfInfoFields[name]
+			if name == "creatim" || name == "revtim" {
+				p.cur.dest = "time:" + key
+				p.curTime = &[5]int{}
+				p.times[key] = p.curTime
+			} else {
+				p.cur.dest = "info:" + key
+				p.info[key] = &strings.Builder{}
+			}
+			return
+		}
+	}
+
+	switch {
+	case p.cur.dest == "style":
+		switch name {
+		case "s":
+			p.style.num = param
+		case "outlinelevel":
+			p.style.outline = param
+		}
+		return
+	case strings.HasPrefix(p.cur.dest, "time:"):
+		if i, ok := rtfTimeParts[name]; ok && hasParam && p.curTime != nil {
+			p.curTime[i] = param
+		}
+		return
+	}
+
+	switch name {
+	case "u":
+		if param < 0 {
+			param += 65536
+		}
+		p.text(string(rune(param)))
+		p.skipBytes = p.cur.uc
+		return
+	case "uc":
+		p.cur.uc = param
+		return
+	}
+	if p.cur.dest != "" {
+		return
+	}
+
+	switch name {
+	case "par", "sect", "page":
+		p.endParagraph()
+	case "pard":
+		p.level = 0
+		p.inTable = false
+	case "intbl":
+		p.inTable = true
+	case "s":
+		p.level = p.styles[param]
+	case "outlinelevel":
+		p.level = headingLevel("", param)
+	case "cell":
+		p.cell.WriteString(p.para.String())
+		p.para.Reset()
+		p.row = append(p.row, strings.Join(strings.Fields(p.cell.String()), " "))
+		p.cell.Reset()
+	case "row":
+		p.rows = append(p.rows, p.row)
+		p.row = nil
+	default:
+		if s, ok := rtfSymbols[name]; ok {
+			p.text(s)
+		}
+	}
+}
+
+func (p *rtfParser) text(s string) {
+	if p.skipBytes > 0 {
+		p.skipBytes--
+		return
+	}
+	switch {
+	case p.cur.dest == "":
+		p.para.WriteString(s)
+	case p.cur.dest == "style":
+		p.style.name.WriteString(s)
+	case strings.HasPrefix(p.cur.dest, "info:"):
+		p.info[strings.TrimPrefix(p.cur.dest, "info:")].WriteString(s)
+	}
+}
+
+func (p *rtfParser) endParagraph() {
+	text := p.para.String()
+	p.para.Reset()
+	if p.inTable {
+		p.cell.WriteString(text)
+		p.cell.WriteByte(' ')
+		return
+	}
+	p.flushTable()
+	kind := "paragraph"
+	if p.level > 0 {
+		kind = "heading"
+	}
+	p.blocks = appendDocBlock(p.blocks, docBlock{kind: kind, level: p.level, text: text})
+}
+
+func (p *rtfParser) flushTable() {
+	if len(p.row) > 0 {
+		p.rows = append(p.rows, p.row)
+		p.row = nil
+	}
+	if len(p.rows) > 0 {
+		p.blocks = appendDocBlock(p.blocks, docBlock{kind: "table", text: formatTable(p.rows)})
+		p.rows = nil
+	}
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -3,6 +3,7 @@ package ingest
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,6 +19,7 @@ type OpenAIEmbedder struct {
 	client     *openai.Client
 	model      string
 	dimension  int
+	truncated  bool // dimension is requested with the dimensions parameter
 	batchSize  int
 	concurrent int
 	limiter    *rate.Limiter
@@ -32,6 +34,7 @@ type OpenAIConfig struct {
 	Concurrent int     // Number of concurrent API calls
 	RateLimit  float64 // Requests per second limit
 	BaseURL    string  // Optional OpenAI API base URL override (e.g. for local endpoints)
+	Dimensions int     // Shortened dimension of text-embedding-3 models; 0 for the full one
 }
 
 // NewOpenAIEmbedder creates a new OpenAI embedding provider.
@@ -64,11 +67,21 @@ func NewOpenAIEmbedder(config OpenAIConfig) (*OpenAIEmbedder, error) {
 	if dimension == 0 {
 		return nil, fmt.Errorf("unknown model dimension for model: %s", config.Model)
 	}
+	if config.Dimensions > 0 {
+		if !strings.HasPrefix(config.Model, "text-embedding-3-") {
+			return nil, fmt.Errorf("embedding.dimensions is only supported by text-embedding-3 models, not %s", config.Model)
+		}
+		if config.Dimensions > dimension {
+			return nil, fmt.Errorf("embedding.dimensions %d exceeds the %d dimensions of %s", config.Dimensions, dimension, config.Model)
+		}
+		dimension = config.Dimensions
+	}
 
 	return &OpenAIEmbedder{
 		client:     client,
 		model:      config.Model,
 		dimension:  dimension,
+		truncated:  config.Dimensions > 0,
 		batchSize:  config.BatchSize,
 		concurrent: config.Concurrent,
 		limiter:    rate.NewLimiter(rate.Limit(config.RateLimit), 1),
@@ -92,6 +105,28 @@ func getModelDimension(model string) int {
 	}
 }
 
+// OpenAIPrice returns OpenAI's list price in US dollars for embedding a
+// million tokens with model ("" for the default, text-embedding-3-large),
+// or false for a model whose price is not known. Prices change, so it is
+// for estimates such as index --dry-run only.
+func OpenAIPrice(model string) (float64, bool) {
+	switch model {
+	case "", "text-embedding-3-large":
+		return 0.13, true
+	case "text-embedding-3-small":
+		return 0.02, true
+	case "text-embedding-ada-002":
+		return 0.10, true
+	}
+	return 0, false
+}
+
+// EstimateTokens estimates the tokens text counts for as embedding input,
+// at about four bytes per token as for English text.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
 // Embed implements the Embedder interface.
 func (oe *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
 	logger := util.FromContext(ctx)
@@ -214,6 +249,10 @@ func (oe *OpenAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]f
 		Input: texts,
 		Model: openai.EmbeddingModel(oe.model),
 	}
+	if oe.truncated {
+		// The API shortens and renormalises the vectors.
+		req.Dimensions = oe.dimension
+	}
 
 	resp, err := oe.client.CreateEmbeddings(ctx, req)
 	if err != nil {
@@ -226,6 +265,9 @@ func (oe *OpenAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]f
 
 	results := make([][]float32, len(resp.Data))
 	for i, data := range resp.Data {
+		if len(data.Embedding) != oe.dimension {
+			return nil, fmt.Errorf("OpenAI returned a vector of dimension %d, expected %d", len(data.Embedding), oe.dimension)
+		}
 		// Convert []float64 to []float32
 		embedding := make([]float32, len(data.Embedding))
 		for j, val := range data.Embedding {
@@ -0,0 +1,65 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIEmbedderDimensions(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      string
+		dimensions int
+		want       int
+		wantErr    bool
+	}{
+		{name: "full dimension", model: "text-embedding-3-small", want: 1536},
+		{name: "shortened", model: "text-embedding-3-large", dimensions: 256, want: 256},
+		{name: "longer than the model", model: "text-embedding-3-large", dimensions: 4000, wantErr: true},
+		{name: "model without the parameter", model: "text-embedding-ada-002", dimensions: 512, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := NewOpenAIEmbedder(OpenAIConfig{APIKey: "test", Model: tt.model, Dimensions: tt.dimensions})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewOpenAIEmbedder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && e.Dimension() != tt.want {
+				t.Errorf("Dimension() = %d, want %d", e.Dimension(), tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAIEmbedderRequestsDimensions(t *testing.T) {
+	var requested int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input      []string `json:"input"`
+			Dimensions int      `json:"dimensions"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		requested = req.Dimensions
+		data := make([]map[string]interface{}, len(req.Input))
+		for i := range data {
+			data[i] = map[string]interface{}{"object": "embedding", "index": i, "embedding": make([]float32, req.Dimensions)}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"object": "list", "data": data})
+	}))
+	defer srv.Close()
+
+	e, err := NewOpenAIEmbedder(OpenAIConfig{APIKey: "test", Model: "text-embedding-3-small", Dimensions: 512, BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := e.Embed(context.Background(), []string{"hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requested != 512 || len(vectors[0]) != 512 {
+		t.Errorf("requested dimensions = %d, got vectors of %d", requested, len(vectors[0]))
+	}
+}
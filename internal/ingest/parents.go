@@ -0,0 +1,92 @@
+package ingest
+
+import "strconv"
+
+// parentSectionKeys are metadata keys that mark a document section. A change
+// in any of them starts a new parent, so a parent never spans two Markdown
+// headings or two EPUB chapters.
+var parentSectionKeys = []string{"breadcrumb", "chapter_index"}
+
+// AssignParents groups consecutive text chunks of a document into parent
+// sections of at most maxSize bytes and records the parent on each chunk as
+// "parent_id", "parent_offset" and "parent_end" metadata (byte offsets into
+// the source). Only the small chunks are embedded and indexed; the parent
+// text is rebuilt from them at query time, so parents cost no extra vectors.
+//
+// Chunks without an "offset" are left alone, as are all chunks when maxSize
+// is not positive. A chunk larger than maxSize becomes a parent of its own.
+func AssignParents(reps []Representation, maxSize int) {
+	if maxSize <= 0 {
+		return
+	}
+	var group []int // indexes into reps of the current parent's children
+	start, end := 0, 0
+	closeGroup := func() {
+		if len(group) == 0 {
+			return
+		}
+		first := reps[group[0]]
+		id := ChunkID(first.Path, "parent", int64(group[0]))
+		for _, i := range group {
+			reps[i].Meta["parent_id"] = id
+			reps[i].Meta["parent_offset"] = strconv.Itoa(start)
+			reps[i].Meta["parent_end"] = strconv.Itoa(end)
+		}
+		group = group[:0]
+	}
+
+	for i := range reps {
+		r := &reps[i]
+		off, err := strconv.Atoi(r.Meta["offset"])
+		if r.Modality != "text" || r.Text == "" || err != nil {
+			closeGroup()
+			continue
+		}
+		chunkEnd := off + len(r.Text)
+		if len(group) > 0 {
+			prev := reps[group[len(group)-1]]
+			if prev.Path != r.Path || !sameSection(prev.Meta, r.Meta) || off < start || chunkEnd-start > maxSize {
+				closeGroup()
+			}
+		}
+		if len(group) == 0 {
+			start, end = off, chunkEnd
+		}
+		if chunkEnd > end {
+			end = chunkEnd
+		}
+		group = append(group, i)
+	}
+	closeGroup()
+}
+
+func sameSection(a, b map[string]string) bool {
+	for _, k := range parentSectionKeys {
+		if a[k] != b[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// LinkNeighbours records on each chunk with text the IDs of the chunks with
+// text before and after it in the same document, as "prev_chunk" and
+// "next_chunk" metadata, so that searches can widen a result to the chunks
+// around it. Chunks are linked in the order the loader produced them.
+func LinkNeighbours(reps []Representation) {
+	prev := -1
+	for i := range reps {
+		r := &reps[i]
+		if r.Text == "" {
+			continue
+		}
+		if r.Meta == nil {
+			r.Meta = map[string]string{}
+		}
+		if prev >= 0 && reps[prev].Path == r.Path {
+			reps[prev].Meta["next_chunk"] = r.ID
+			r.Meta["prev_chunk"] = reps[prev].ID
+		}
+		prev = i
+	}
+}
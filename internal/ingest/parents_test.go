@@ -0,0 +1,84 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAssignParents(t *testing.T) {
+	doc := "# Intro\n\n" + strings.Repeat("alpha beta gamma ", 20) + "\n\n# Usage\n\n" + strings.Repeat("delta epsilon ", 10)
+	file := filepath.Join(t.TempDir(), "doc.md")
+	if err := os.WriteFile(file, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reps, err := NewMarkdownLoader(120, 20).Load(context.Background(), "doc.md", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssignParents(reps, 250)
+
+	parents := map[string][]Representation{}
+	var order []string
+	for _, r := range reps {
+		id := r.Meta["parent_id"]
+		if id == "" {
+			t.Fatalf("chunk at %s has no parent", r.Meta["offset"])
+		}
+		if _, ok := parents[id]; !ok {
+			order = append(order, id)
+		}
+		parents[id] = append(parents[id], r)
+	}
+	if len(order) < 3 {
+		t.Fatalf("expected the long section to be split into several parents, got %d parents", len(order))
+	}
+	for _, id := range order {
+		children := parents[id]
+		start, end := children[0].Meta["parent_offset"], children[0].Meta["parent_end"]
+		for _, c := range children {
+			if c.Meta["breadcrumb"] != children[0].Meta["breadcrumb"] {
+				t.Errorf("parent %s spans sections %q and %q", id, children[0].Meta["breadcrumb"], c.Meta["breadcrumb"])
+			}
+			if c.Meta["parent_offset"] != start || c.Meta["parent_end"] != end {
+				t.Errorf("children of %s disagree on the parent boundaries", id)
+			}
+		}
+		s, _ := strconv.Atoi(start)
+		e, _ := strconv.Atoi(end)
+		if e-s > 250 || !strings.Contains(doc[s:e], children[len(children)-1].Text) {
+			t.Errorf("parent %s boundaries [%d,%d) are wrong", id, s, e)
+		}
+	}
+
+	// Disabled, or chunks without offsets: nothing is assigned.
+	plain := []Representation{{Path: "a", Modality: "text", Text: "x", Meta: map[string]string{}}}
+	AssignParents(plain, 250)
+	if _, ok := plain[0].Meta["parent_id"]; ok {
+		t.Error("chunk without offset got a parent")
+	}
+}
+
+func TestLinkNeighbours(t *testing.T) {
+	reps := []Representation{
+		{ID: "a1", Path: "a", Text: "one", Meta: map[string]string{}},
+		{ID: "a2", Path: "a", Modality: "image"},
+		{ID: "a3", Path: "a", Text: "three"},
+		{ID: "b1", Path: "b", Text: "other", Meta: map[string]string{}},
+	}
+	LinkNeighbours(reps)
+	want := []map[string]string{
+		{"next_chunk": "a3"},
+		nil,
+		{"prev_chunk": "a1"},
+		{},
+	}
+	for i, r := range reps {
+		if r.Meta["prev_chunk"] != want[i]["prev_chunk"] || r.Meta["next_chunk"] != want[i]["next_chunk"] {
+			t.Errorf("%s: meta %v, want %v", r.ID, r.Meta, want[i])
+		}
+	}
+}
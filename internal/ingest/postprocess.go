@@ -0,0 +1,256 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+// PostProcessor rewrites the chunks of a document after its loader and
+// before the embedder, e.g. to trim boilerplate or add metadata. It may
+// change, drop or add chunks; chunks are never shared between documents.
+type PostProcessor interface {
+	Process(ctx context.Context, reps []Representation) ([]Representation, error)
+}
+
+// PostProcessorFunc adapts a function to PostProcessor.
+type PostProcessorFunc func(ctx context.Context, reps []Representation) ([]Representation, error)
+
+func (f PostProcessorFunc) Process(ctx context.Context, reps []Representation) ([]Representation, error) {
+	return f(ctx, reps)
+}
+
+// PostProcessorFactory builds a post-processor from the options given to
+// it under files.post_processors.
+type PostProcessorFactory func(options map[string]string) (PostProcessor, error)
+
+var (
+	postProcessorsMu sync.RWMutex
+	postProcessors   = map[string]PostProcessorFactory{}
+)
+
+// RegisterPostProcessor makes a post-processor available to
+// files.post_processors under name. Builds of Semango with custom ingest
+// steps call it from an init function. It panics if name is taken.
+func RegisterPostProcessor(name string, f PostProcessorFactory) {
+	postProcessorsMu.Lock()
+	defer postProcessorsMu.Unlock()
+	if _, dup := postProcessors[name]; dup {
+		panic("ingest: post-processor " + name + " registered twice")
+	}
+	postProcessors[name] = f
+}
+
+// PostProcessorNames returns the registered post-processor names, sorted.
+func PostProcessorNames() []string {
+	postProcessorsMu.RLock()
+	defer postProcessorsMu.RUnlock()
+	names := make([]string, 0, len(postProcessors))
+	for name := range postProcessors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Chain runs post-processors in order, each limited to its modalities.
+type Chain []chainStep
+
+type chainStep struct {
+	name       string
+	modalities map[string]bool // nil for all
+	p          PostProcessor
+}
+
+// NewChain builds the post-processors configured in cfgs.
+func NewChain(cfgs []config.PostProcessorConfig) (Chain, error) {
+	chain := make(Chain, 0, len(cfgs))
+	for _, c := range cfgs {
+		postProcessorsMu.RLock()
+		f, ok := postProcessors[c.Name]
+		postProcessorsMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown post-processor %q (available: %s)", c.Name, strings.Join(PostProcessorNames(), ", "))
+		}
+		p, err := f(c.Options)
+		if err != nil {
+			return nil, fmt.Errorf("post-processor %s: %w", c.Name, err)
+		}
+		step := chainStep{name: c.Name, p: p}
+		if len(c.Modalities) > 0 {
+			step.modalities = make(map[string]bool, len(c.Modalities))
+			for _, m := range c.Modalities {
+				step.modalities[m] = true
+			}
+		}
+		chain = append(chain, step)
+	}
+	return chain, nil
+}
+
+// Process runs reps through every step. A step limited to some modalities
+// sees only chunks of those; the others pass it unchanged, and the chunks
+// keep their order.
+func (c Chain) Process(ctx context.Context, reps []Representation) ([]Representation, error) {
+	for _, step := range c {
+		var err error
+		if step.modalities == nil {
+			reps, err = step.p.Process(ctx, reps)
+		} else {
+			reps, err = step.processSome(ctx, reps)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("post-processor %s: %w", step.name, err)
+		}
+	}
+	return reps, nil
+}
+
+// processSome runs the step on the chunks of its modalities, put back in
+// place of the first of them.
+func (s chainStep) processSome(ctx context.Context, reps []Representation) ([]Representation, error) {
+	var selected []Representation
+	first := -1
+	for i, r := range reps {
+		if s.modalities[r.Modality] {
+			if first < 0 {
+				first = i
+			}
+			selected = append(selected, r)
+		}
+	}
+	if first < 0 {
+		return reps, nil
+	}
+	processed, err := s.p.Process(ctx, selected)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Representation, 0, len(reps)-len(selected)+len(processed))
+	for i, r := range reps {
+		if i == first {
+			out = append(out, processed...)
+		}
+		if !s.modalities[r.Modality] {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func init() {
+	RegisterPostProcessor("trim_boilerplate", newBoilerplateTrimmer)
+	RegisterPostProcessor("breadcrumbs", newBreadcrumbs)
+	RegisterPostProcessor("set_meta", newMetaSetter)
+	RegisterPostProcessor("redact_secrets", newSecretRedactor)
+}
+
+// defaultBoilerplate matches lines such as copyright notices and page
+// footers.
+const defaultBoilerplate = `(?i)^\s*(copyright\b|©|\(c\) \d{4}|all rights reserved|confidential\b|page \d+( of \d+)?\s*$)`
+
+// newBoilerplateTrimmer removes the lines of chunk text matching the
+// "pattern" option, a regular expression (defaultBoilerplate if unset), and
+// drops chunks left blank. Trimmed chunks lose their "offset", which no
+// longer locates their text in the source.
+func newBoilerplateTrimmer(options map[string]string) (PostProcessor, error) {
+	pattern := options["pattern"]
+	if pattern == "" {
+		pattern = defaultBoilerplate
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return PostProcessorFunc(func(ctx context.Context, reps []Representation) ([]Representation, error) {
+		out := reps[:0]
+		for _, r := range reps {
+			if r.Text == "" {
+				out = append(out, r)
+				continue
+			}
+			lines := strings.Split(r.Text, "\n")
+			kept := lines[:0]
+			for _, line := range lines {
+				if !re.MatchString(line) {
+					kept = append(kept, line)
+				}
+			}
+			if len(kept) < len(lines) {
+				r.Text = strings.Join(kept, "\n")
+				if strings.TrimSpace(r.Text) == "" {
+					continue
+				}
+				r.Meta = withoutOffset(r.Meta)
+			}
+			out = append(out, r)
+		}
+		return out, nil
+	}), nil
+}
+
+// newBreadcrumbs prefixes chunk text with the document path and the
+// chunk's "breadcrumb" (its Markdown or DOCX headings), joined by the
+// "separator" option (" > " if unset), so the embedding knows where a
+// chunk sits. Like trimmed chunks, prefixed chunks lose their "offset".
+func newBreadcrumbs(options map[string]string) (PostProcessor, error) {
+	sep := options["separator"]
+	if sep == "" {
+		sep = " > "
+	}
+	return PostProcessorFunc(func(ctx context.Context, reps []Representation) ([]Representation, error) {
+		for i := range reps {
+			r := &reps[i]
+			if r.Text == "" {
+				continue
+			}
+			crumb := r.Path
+			if b := r.Meta["breadcrumb"]; b != "" {
+				crumb += sep + strings.ReplaceAll(b, " > ", sep)
+			}
+			r.Text = crumb + "\n\n" + r.Text
+			r.Meta = withoutOffset(r.Meta)
+		}
+		return reps, nil
+	}), nil
+}
+
+// newMetaSetter sets every option as a metadata key and value on each
+// chunk, without overwriting metadata the loader set, e.g. to tag chunks
+// with a team or product for filters.
+func newMetaSetter(options map[string]string) (PostProcessor, error) {
+	if len(options) == 0 {
+		return nil, fmt.Errorf("no metadata given in options")
+	}
+	return PostProcessorFunc(func(ctx context.Context, reps []Representation) ([]Representation, error) {
+		for i := range reps {
+			if reps[i].Meta == nil {
+				reps[i].Meta = make(map[string]string, len(options))
+			}
+			for k, v := range options {
+				if _, ok := reps[i].Meta[k]; !ok {
+					reps[i].Meta[k] = v
+				}
+			}
+		}
+		return reps, nil
+	}), nil
+}
+
+// withoutOffset returns a copy of meta without "offset", for chunks whose
+// text no longer starts at that byte of the source. Parent sections are
+// built from offsets, so such chunks get none.
+func withoutOffset(meta map[string]string) map[string]string {
+	out := make(map[string]string, len(meta))
+	for k, v := range meta {
+		if k != "offset" {
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package ingest
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+func TestChain(t *testing.T) {
+	chain, err := NewChain([]config.PostProcessorConfig{
+		{Name: "trim_boilerplate", Modalities: []string{"text"}},
+		{Name: "breadcrumbs", Modalities: []string{"text"}},
+		{Name: "set_meta", Options: map[string]string{"team": "docs", "source": "ignored"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reps := []Representation{
+		{Path: "guide.md", Modality: "text", Text: "Deploy with harbor.\nCopyright 2024 Example Inc.", Meta: map[string]string{"source": "MarkdownLoader", "offset": "0", "breadcrumb": "Guide > Deploy"}},
+		{Path: "guide.md", Modality: "image", Text: "Page 3 of 9", Meta: map[string]string{"source": "ImageLoader"}},
+		{Path: "guide.md", Modality: "text", Text: "All rights reserved.", Meta: map[string]string{"offset": "60"}},
+	}
+	got, err := chain.Process(context.Background(), reps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected the all-boilerplate chunk to be dropped, got %d chunks", len(got))
+	}
+	if want := "guide.md > Guide > Deploy\n\nDeploy with harbor."; got[0].Text != want {
+		t.Errorf("text = %q, want %q", got[0].Text, want)
+	}
+	if _, ok := got[0].Meta["offset"]; ok {
+		t.Error("a rewritten chunk should lose its offset")
+	}
+	// Text post-processors leave the image chunk alone; set_meta applies to
+	// all but keeps what the loader set.
+	if got[1].Text != "Page 3 of 9" {
+		t.Errorf("image chunk changed: %q", got[1].Text)
+	}
+	for _, r := range got {
+		if r.Meta["team"] != "docs" || r.Meta["source"] == "ignored" {
+			t.Errorf("meta = %v", r.Meta)
+		}
+	}
+}
+
+func TestNewChainErrors(t *testing.T) {
+	for _, cfgs := range [][]config.PostProcessorConfig{
+		{{Name: "summarize"}},
+		{{Name: "trim_boilerplate", Options: map[string]string{"pattern": "("}}},
+		{{Name: "set_meta"}},
+	} {
+		if _, err := NewChain(cfgs); err == nil {
+			t.Errorf("%+v: expected an error", cfgs)
+		}
+	}
+	if _, err := NewChain([]config.PostProcessorConfig{{Name: "summarize"}}); err == nil || !strings.Contains(err.Error(), "breadcrumbs") {
+		t.Errorf("unknown names should list the available ones, got %v", err)
+	}
+}
+
+func TestRegisterPostProcessor(t *testing.T) {
+	RegisterPostProcessor("test_upper", func(map[string]string) (PostProcessor, error) {
+		return PostProcessorFunc(func(ctx context.Context, reps []Representation) ([]Representation, error) {
+			for i := range reps {
+				reps[i].Text = strings.ToUpper(reps[i].Text)
+			}
+			return reps, nil
+		}), nil
+	})
+	chain, err := NewChain([]config.PostProcessorConfig{{Name: "test_upper"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := chain.Process(context.Background(), []Representation{{Text: "abc"}})
+	if !reflect.DeepEqual(got, []Representation{{Text: "ABC"}}) {
+		t.Errorf("got %+v", got)
+	}
+}
@@ -0,0 +1,147 @@
+package ingest
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Metadata keys read and written by quality scoring. Loaders that extract
+// text by OCR or cut content short set MetaOCRConfidence and MetaTruncated.
+const (
+	MetaQuality       = "quality"        // "0.00" to "1.00", set by ScoreQuality
+	MetaOCRConfidence = "ocr_confidence" // 0 to 1, or a percentage
+	MetaTruncated     = "truncated"      // "true" when the chunk's content was cut
+)
+
+// truncatedFactor scales the quality of truncated chunks.
+const truncatedFactor = 0.8
+
+// ScoreQuality sets MetaQuality on every chunk with text to its Quality,
+// keeping scores a loader or post-processor set itself.
+func ScoreQuality(reps []Representation) {
+	for i := range reps {
+		r := &reps[i]
+		if r.Text == "" {
+			continue
+		}
+		if _, ok := r.Meta[MetaQuality]; ok {
+			continue
+		}
+		if r.Meta == nil {
+			r.Meta = map[string]string{}
+		}
+		r.Meta[MetaQuality] = strconv.FormatFloat(Quality(r.Text, r.Meta), 'f', 2, 64)
+	}
+}
+
+// Quality rates how clean a chunk's text is, from 0 for noise to 1. Prose
+// and source code score 1; text with the character entropy of encoded data,
+// little whitespace (minified code), or mostly tokens that are not words
+// (OCR garbage) scores lower. Chinese, Japanese and Thai text, written
+// without spaces between words, is only rated by its tokens. The score is
+// multiplied by the chunk's MetaOCRConfidence, if any, and by
+// truncatedFactor if it is MetaTruncated.
+func Quality(text string, meta map[string]string) float64 {
+	q := tokenScore(text)
+	if !unspacedScript(text) {
+		q *= entropyScore(text) * whitespaceScore(text)
+	}
+	if c, err := strconv.ParseFloat(meta[MetaOCRConfidence], 64); err == nil && c >= 0 {
+		if c > 1 {
+			c /= 100
+		}
+		q *= math.Min(c, 1)
+	}
+	if meta[MetaTruncated] == "true" {
+		q *= truncatedFactor
+	}
+	return math.Round(q*100) / 100
+}
+
+// entropyScore is 1 for the character entropy of natural language and
+// code, falling to 0 for highly repetitive text and for random-looking
+// text such as base64 or hex dumps. Texts under 100 characters score 1, as
+// their entropy says little.
+func entropyScore(text string) float64 {
+	counts := map[rune]int{}
+	n := 0
+	for _, r := range text {
+		counts[r]++
+		n++
+	}
+	if n < 100 {
+		return 1
+	}
+	h := 0.0
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		h -= p * math.Log2(p)
+	}
+	switch {
+	case h < 3:
+		return clamp01((h - 1.5) / 1.5)
+	case h > 5.3:
+		return clamp01((6.1 - h) / 0.8)
+	}
+	return 1
+}
+
+// whitespaceScore is 1 when at least 8% of the characters are whitespace,
+// as in prose and formatted code, and falls to 0 for text without any,
+// such as minified code.
+func whitespaceScore(text string) float64 {
+	spaces, n := 0, 0
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			spaces++
+		}
+		n++
+	}
+	if n < 100 {
+		return 1
+	}
+	return clamp01(float64(spaces) / float64(n) / 0.08)
+}
+
+// tokenScore is 1 when at least 60% of the whitespace-separated tokens are
+// mostly letters and digits, and falls to 0 at 20%.
+func tokenScore(text string) float64 {
+	tokens := strings.Fields(text)
+	if len(tokens) < 10 {
+		return 1
+	}
+	good := 0
+	for _, t := range tokens {
+		letters, n := 0, 0
+		for _, r := range t {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				letters++
+			}
+			n++
+		}
+		if letters*2 >= n {
+			good++
+		}
+	}
+	return clamp01((float64(good)/float64(len(tokens)) - 0.2) / 0.4)
+}
+
+// unspacedScript reports whether over 30% of text is in scripts written
+// without spaces between words, whose entropy and whitespace differ from
+// those of spaced scripts.
+func unspacedScript(text string) bool {
+	unspaced, n := 0, 0
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai) {
+			unspaced++
+		}
+		n++
+	}
+	return unspaced*10 > n*3
+}
+
+func clamp01(x float64) float64 {
+	return math.Max(0, math.Min(1, x))
+}
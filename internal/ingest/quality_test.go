@@ -0,0 +1,58 @@
+package ingest
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestQuality(t *testing.T) {
+	prose := strings.Repeat("Semango indexes documents, code and data so they can be searched by meaning and by keyword. ", 5)
+	code := strings.Repeat("func (s *Server) Close() error {\n\treturn s.listener.Close()\n}\n\n", 5)
+	minified := strings.Repeat("function(a,b){return a.map(function(c){return c*b})}var x=1;", 5)
+	random := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("\x00\x9f\x13\xe2\x7a\xc4\x51\x08\xbd\x66\x2e\xf1", 30)))
+	ocr := strings.Repeat("~|l /\\ ;:' ,-_ )( .. |{ ^^ `l ", 8)
+	cjk := strings.Repeat("語義検索はテキストの意味で文書を探します。", 8)
+
+	tests := []struct {
+		name string
+		text string
+		meta map[string]string
+		min  float64
+		max  float64
+	}{
+		{"prose", prose, nil, 1, 1},
+		{"code", code, nil, 1, 1},
+		{"short", "ok", nil, 1, 1},
+		{"cjk", cjk, nil, 1, 1},
+		{"minified", minified, nil, 0, 0.7},
+		{"base64", random, nil, 0, 0.5},
+		{"ocr noise", ocr, nil, 0, 0.3},
+		{"ocr confidence", prose, map[string]string{MetaOCRConfidence: "0.5"}, 0.5, 0.5},
+		{"ocr percentage", prose, map[string]string{MetaOCRConfidence: "90"}, 0.9, 0.9},
+		{"truncated", prose, map[string]string{MetaTruncated: "true"}, truncatedFactor, truncatedFactor},
+	}
+	for _, tt := range tests {
+		if got := Quality(tt.text, tt.meta); got < tt.min || got > tt.max {
+			t.Errorf("%s: Quality = %v, want in [%v, %v]", tt.name, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestScoreQuality(t *testing.T) {
+	reps := []Representation{
+		{Text: "plain text"},
+		{Text: "kept", Meta: map[string]string{MetaQuality: "0.42"}},
+		{Modality: "image"},
+	}
+	ScoreQuality(reps)
+	if got := reps[0].Meta[MetaQuality]; got != "1.00" {
+		t.Errorf("quality = %q, want 1.00", got)
+	}
+	if got := reps[1].Meta[MetaQuality]; got != "0.42" {
+		t.Errorf("quality = %q, want the loader's 0.42", got)
+	}
+	if _, ok := reps[2].Meta[MetaQuality]; ok {
+		t.Error("chunk without text was scored")
+	}
+}
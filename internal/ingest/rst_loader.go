@@ -0,0 +1,304 @@
+package ingest
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// RSTLoader indexes reStructuredText documents, such as Sphinx
+// documentation, one section at a time with the markup stripped: section
+// adornments, comments, link targets and directive options are left out,
+// roles and inline markup are reduced to their text, and code blocks are
+// kept as they are. Like MarkdownLoader, every chunk records the breadcrumb
+// of titles it belongs to; it also records the labels (".. _name:"
+// targets) of its section, and the title of the document, its first
+// section title.
+type RSTLoader struct {
+	chunkSize int
+	overlap   int
+	chunking  Chunking
+}
+
+// NewRSTLoader returns an RSTLoader. Sections longer than chunkSize are
+// split further using the same word-boundary strategy as TextLoader.
+func NewRSTLoader(chunkSize, overlap int) *RSTLoader {
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	if overlap < 0 {
+		overlap = 0
+	}
+	return &RSTLoader{chunkSize: chunkSize, overlap: overlap}
+}
+
+// SetChunking replaces the fixed-size chunks of rl with c.
+func (rl *RSTLoader) SetChunking(c Chunking) { rl.chunking = c }
+
+func (rl *RSTLoader) Extensions() []string {
+	return []string{".rst", ".rest"}
+}
+
+func (rl *RSTLoader) Load(ctx context.Context, relPath string, absPath string) ([]Representation, error) {
+	slog.Info("Loading reStructuredText file", "relative_path", relPath, "absolute_path", absPath)
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		slog.Error("Failed to read file for RSTLoader", "path", absPath, "error", err)
+		return nil, err
+	}
+	doc := parseRST(string(data))
+	reps := doc.representations(relPath, "RSTLoader", rl.chunking.chunker(rl.chunkSize, rl.overlap))
+	slog.Debug("Created", "sections", len(doc.headings), "chunks", len(reps), "relPath", relPath)
+	return reps, nil
+}
+
+var (
+	reRSTTarget    = regexp.MustCompile("^\\.\\.\\s+_(`[^`]+`|[^:]+):\\s*(.*)$")
+	reRSTDirective = regexp.MustCompile(`^\.\.\s+([\w:+-]+)::\s*(.*)$`)
+	reRSTFootnote  = regexp.MustCompile(`^\.\.\s+(\[[^\]]+\].*)$`)
+	reRSTOption    = regexp.MustCompile(`^\s*:[\w-]+:`)
+
+	reRSTTitledRole = regexp.MustCompile("(?::[\\w:.+-]+:)?`([^`<]*[^`<\\s])\\s*<[^`>]*>`(?:__?)?")
+	reRSTRole       = regexp.MustCompile(":[\\w:.+-]+:`[~!]?([^`]*)`")
+	reRSTLiteral    = regexp.MustCompile("``([^`]+)``")
+	reRSTReference  = regexp.MustCompile("`([^`]+)`(?:__?)?")
+	reRSTStrong     = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	reRSTEmphasis   = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	reRSTFootRef    = regexp.MustCompile(`\s?\[(?:#[\w-]*|\*|\d+)\]_`)
+	reRSTWordRef    = regexp.MustCompile(`([A-Za-z0-9])__?(\s|[.,;:!?)]|$)`)
+	reRSTSubstRef   = regexp.MustCompile(`\|(\w[\w -]*\w|\w)\|_{0,2}`)
+)
+
+// rstAdmonitions are the directives whose content is a note of the kind
+// they are named after.
+var rstAdmonitions = map[string]string{
+	"note": "Note", "warning": "Warning", "tip": "Tip", "hint": "Hint", "important": "Important",
+	"caution": "Caution", "danger": "Danger", "attention": "Attention", "error": "Error",
+	"seealso": "See also", "todo": "Todo", "versionadded": "New in version",
+	"versionchanged": "Changed in version", "deprecated": "Deprecated since version",
+}
+
+// rstCode are the directives whose content is kept as it is.
+var rstCode = map[string]bool{
+	"code": true, "code-block": true, "sourcecode": true, "math": true, "parsed-literal": true,
+	"doctest": true, "testcode": true, "testoutput": true,
+}
+
+// rstDropped are the directives that hold no text.
+var rstDropped = map[string]bool{
+	"toctree": true, "raw": true, "index": true, "meta": true, "highlight": true, "include": true,
+	"literalinclude": true, "image": true, "contents": true, "sectnum": true, "autosummary": true,
+	"currentmodule": true, "default-role": true, "role": true,
+}
+
+// parseRST strips the markup of a reStructuredText source. Section levels
+// follow the order in which the document first uses each adornment style,
+// as in docutils.
+func parseRST(src string) *markupDoc {
+	lines := strings.Split(src, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, "\r \t")
+	}
+	doc := &markupDoc{lines: make([]string, len(lines)), meta: map[string]string{}}
+	var (
+		styles  []string // adornment styles by rank
+		labels  []string // targets waiting for the element they point at
+		literal bool     // the next indented block is a literal block
+	)
+	rank := func(style string) int {
+		for i, s := range styles {
+			if s == style {
+				return i
+			}
+		}
+		styles = append(styles, style)
+		return len(styles) - 1
+	}
+	heading := func(line, r int, title string) {
+		title = rstInline(strings.TrimSpace(title))
+		doc.headings = append(doc.headings, markupHeading{line: line, rank: r, title: title})
+		doc.lines[line] = title
+		if doc.meta["title"] == "" {
+			doc.meta["title"] = title
+		}
+	}
+	// flush sets the targets read so far on line.
+	flush := func(line int) {
+		for _, l := range labels {
+			doc.labels = append(doc.labels, markupLabel{line: line, name: l})
+		}
+		labels = nil
+	}
+	// block returns the end of the block indented under line i.
+	block := func(i int) int {
+		ind := rstIndent(lines[i])
+		end := i + 1
+		for j := i + 1; j < len(lines); j++ {
+			if lines[j] == "" {
+				continue
+			}
+			if rstIndent(lines[j]) <= ind {
+				break
+			}
+			end = j + 1
+		}
+		return end
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if line == "" {
+			i++
+			continue
+		}
+		if literal {
+			literal = false
+			if rstIndent(line) > 0 {
+				end := i
+				for j := i; j < len(lines) && (lines[j] == "" || rstIndent(lines[j]) > 0); j++ {
+					if lines[j] != "" {
+						end = j + 1
+					}
+				}
+				for ; i < end; i++ {
+					doc.lines[i] = strings.TrimSpace(lines[i])
+				}
+				continue
+			}
+		}
+		trimmed := strings.TrimSpace(line)
+
+		// A title between an overline and an underline.
+		if rstAdornment(line) && i+2 < len(lines) && lines[i+2] == line && lines[i+1] != "" {
+			flush(i + 1)
+			heading(i+1, rank("over"+line[:1]), lines[i+1])
+			i += 3
+			continue
+		}
+		// A title over an underline.
+		if rstIndent(line) == 0 && i+1 < len(lines) && rstAdornment(lines[i+1]) &&
+			utf8.RuneCountInString(lines[i+1]) >= utf8.RuneCountInString(trimmed) && (i == 0 || lines[i-1] == "") {
+			flush(i)
+			heading(i, rank(lines[i+1][:1]), line)
+			i += 2
+			continue
+		}
+		// A transition.
+		if rstAdornment(line) && len(line) >= 4 {
+			i++
+			continue
+		}
+
+		if trimmed == ".." || strings.HasPrefix(trimmed, ".. ") {
+			end := block(i)
+			if t := reRSTTarget.FindStringSubmatch(trimmed); t != nil {
+				// Internal targets point at the element after them;
+				// those with a URL are links.
+				if t[2] == "" {
+					labels = append(labels, strings.Trim(t[1], "`"))
+				}
+				i = end
+				continue
+			}
+			switch m := reRSTDirective.FindStringSubmatch(trimmed); {
+			case strings.HasPrefix(trimmed, ".. |"):
+				// A substitution definition.
+			case m != nil:
+				flush(i)
+				rstDirective(doc, lines, i, end, strings.ToLower(m[1]), m[2])
+			case reRSTFootnote.MatchString(trimmed):
+				flush(i)
+				doc.lines[i] = rstInline(reRSTFootnote.FindStringSubmatch(trimmed)[1])
+				for j := i + 1; j < end; j++ {
+					doc.lines[j] = rstInline(strings.TrimSpace(lines[j]))
+				}
+			default:
+				// A comment.
+			}
+			i = end
+			continue
+		}
+
+		flush(i)
+		text := rstInline(trimmed)
+		if strings.HasSuffix(text, "::") {
+			// "Example::" ends with a colon, a lone or spaced "::" with
+			// nothing.
+			literal = true
+			text = strings.TrimSuffix(text, ":")
+			if text == ":" || strings.HasSuffix(text, " :") {
+				text = strings.TrimSpace(strings.TrimSuffix(text, ":"))
+			}
+		}
+		doc.lines[i] = text
+		i++
+	}
+	return doc
+}
+
+// rstDirective strips the directive called name, with arguments args, on
+// lines[i:end].
+func rstDirective(doc *markupDoc, lines []string, i, end int, name, args string) {
+	if rstDropped[name] {
+		return
+	}
+	j := i + 1
+	for ; j < end && reRSTOption.MatchString(lines[j]); j++ {
+	}
+	switch {
+	case rstAdmonitions[name] != "":
+		doc.lines[i] = collapseSpaces(rstAdmonitions[name] + ": " + rstInline(args))
+	case name == "figure":
+		// Its argument is the path of the image, its content the caption.
+	case rstCode[name]:
+		ind := -1
+		for k := j; k < end; k++ {
+			if lines[k] != "" && (ind < 0 || rstIndent(lines[k]) < ind) {
+				ind = rstIndent(lines[k])
+			}
+		}
+		for k := j; k < end; k++ {
+			if lines[k] != "" {
+				doc.lines[k] = strings.TrimRight(lines[k][ind:], " ")
+			}
+		}
+		return
+	default:
+		doc.lines[i] = rstInline(args)
+	}
+	for ; j < end; j++ {
+		doc.lines[j] = rstInline(strings.TrimSpace(lines[j]))
+	}
+}
+
+// rstInline returns the text of a line of reStructuredText with its inline
+// markup stripped.
+func rstInline(s string) string {
+	s = reRSTTitledRole.ReplaceAllString(s, "$1")
+	s = reRSTRole.ReplaceAllString(s, "$1")
+	s = reRSTLiteral.ReplaceAllString(s, "$1")
+	s = reRSTReference.ReplaceAllString(s, "$1")
+	s = reRSTStrong.ReplaceAllString(s, "$1")
+	s = reRSTEmphasis.ReplaceAllString(s, "$1")
+	s = reRSTFootRef.ReplaceAllString(s, "")
+	s = reRSTWordRef.ReplaceAllString(s, "$1$2")
+	s = reRSTSubstRef.ReplaceAllString(s, "$1")
+	return collapseSpaces(s)
+}
+
+// rstAdornment reports whether line is a section adornment: a line of the
+// same punctuation character repeated.
+func rstAdornment(line string) bool {
+	if len(line) < 2 || !strings.ContainsRune("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", rune(line[0])) {
+		return false
+	}
+	return strings.Trim(line, line[:1]) == ""
+}
+
+func rstIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
@@ -0,0 +1,100 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRSTLoader_Sections(t *testing.T) {
+	rst := `.. Comment that is
+   left out.
+
+==========
+User Guide
+==========
+
+Semango indexes **everything** you give it, see :ref:` + "`install <install-label>`" + `.
+
+.. _install-label:
+
+Install
+=======
+
+Run ` + "``go install``" + ` from the ` + "`GitHub repository <https://github.com>`_::" + `
+
+    go install ./cmd/semango
+
+.. note::
+   Needs *Go 1.22* or later [#]_.
+
+.. toctree::
+   :maxdepth: 2
+
+   linux
+   macos
+
+Linux
+-----
+
+.. code-block:: bash
+   :caption: Install on Linux
+
+   apt install faiss
+
+Configure
+=========
+
+Set options in :file:` + "`semango.yml`" + `.
+
+.. _Python: https://python.org
+`
+	reps := loadMarkup(t, NewRSTLoader(2000, 0), "guide.rst", rst)
+	if len(reps) != 4 {
+		t.Fatalf("expected 4 sections, got %d: %+v", len(reps), reps)
+	}
+	for _, r := range reps {
+		if r.Meta["title"] != "User Guide" || r.Meta["source"] != "RSTLoader" {
+			t.Errorf("unexpected meta: %v", r.Meta)
+		}
+	}
+
+	want := []struct {
+		text, breadcrumb, level, labels, line string
+	}{
+		{"User Guide\n\nSemango indexes everything you give it, see install.", "User Guide", "1", "", "5"},
+		{"Install\n\nRun go install from the GitHub repository:\n\ngo install ./cmd/semango\n\nNote:\nNeeds Go 1.22 or later.",
+			"User Guide > Install", "2", "install-label", "12"},
+		{"Linux\n\napt install faiss", "User Guide > Install > Linux", "3", "", "28"},
+		{"Configure\n\nSet options in semango.yml.", "User Guide > Configure", "2", "", "36"},
+	}
+	for i, w := range want {
+		r := reps[i]
+		if r.Text != w.text {
+			t.Errorf("chunk %d text = %q, want %q", i, r.Text, w.text)
+		}
+		if r.Meta["breadcrumb"] != w.breadcrumb || r.Meta["heading_level"] != w.level ||
+			r.Meta["labels"] != w.labels || r.Meta["line"] != w.line {
+			t.Errorf("chunk %d: unexpected meta %v", i, r.Meta)
+		}
+	}
+	for _, r := range reps {
+		for _, s := range []string{"left out", "maxdepth", "macos", "python.org", "caption"} {
+			if strings.Contains(r.Text, s) {
+				t.Errorf("chunk should not contain %q:\n%s", s, r.Text)
+			}
+		}
+	}
+}
+
+func TestRSTInline(t *testing.T) {
+	for in, want := range map[string]string{
+		"Use :func:`~pkg.load` and :class:`Loader <pkg.Loader>`.":   "Use pkg.load and Loader.",
+		"See `the docs`_ and Sphinx_, or `here <https://x.org>`__.": "See the docs and Sphinx, or here.",
+		"A *stressed* and **strong** ``literal`` |version|.":        "A stressed and strong literal version.",
+		"snake_case_name stays":                                     "snake_case_name stays",
+	} {
+		if got := rstInline(in); got != want {
+			t.Errorf("rstInline(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package ingest
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SchemaFileLoader indexes Protocol Buffers (.proto) and Thrift (.thrift)
+// files one top-level definition at a time: each message, enum, service,
+// struct and so on becomes a chunk with the comments above it, and records
+// its symbol and the names of its fields, values and methods in its
+// metadata. API teams can then search schema repositories for "which
+// message carries the billing address" and land on the definition.
+// Everything outside definitions (syntax, package, imports, options,
+// typedefs and constants) makes up a chunk of its own.
+type SchemaFileLoader struct {
+	chunkSize int
+}
+
+// NewSchemaFileLoader returns a SchemaFileLoader that cuts definitions
+// longer than chunkSize characters into several chunks.
+func NewSchemaFileLoader(chunkSize int) *SchemaFileLoader {
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	return &SchemaFileLoader{chunkSize: chunkSize}
+}
+
+func (sl *SchemaFileLoader) Extensions() []string {
+	return []string{".proto", ".thrift"}
+}
+
+// schemaKinds are the keywords of the definitions chunked on, by format.
+var schemaKinds = map[string]map[string]bool{
+	"proto":  {"message": true, "enum": true, "service": true, "extend": true},
+	"thrift": {"struct": true, "union": true, "exception": true, "enum": true, "senum": true, "service": true},
+}
+
+// schemaDef is a top-level definition of a schema file.
+type schemaDef struct {
+	kind    string // its keyword, e.g. "message"
+	name    string
+	start   int // byte offset of its text, doc comment included
+	end     int
+	members []string // fields, enum values, methods and nested types
+}
+
+func (sl *SchemaFileLoader) Load(ctx context.Context, relPath string, absPath string) ([]Representation, error) {
+	slog.Info("Loading schema file", "relative_path", relPath, "absolute_path", absPath)
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		slog.Error("Failed to read file for SchemaFileLoader", "path", absPath, "error", err)
+		return nil, err
+	}
+	src := string(data)
+	format := "proto"
+	if strings.ToLower(filepath.Ext(relPath)) == ".thrift" {
+		format = "thrift"
+	}
+	pkg, defs := parseSchema(src, format)
+
+	var reps []Representation
+	// add chunks text, found at offset off of src unless off is -1.
+	add := func(text string, off int, meta map[string]string) {
+		for _, span := range (&FixedChunker{Size: sl.chunkSize}).Spans(text) {
+			if strings.TrimSpace(span.Text) == "" {
+				continue
+			}
+			m := map[string]string{
+				"source": "SchemaFileLoader",
+				"path":   relPath,
+				"format": format,
+			}
+			if off >= 0 {
+				m["line"] = strconv.Itoa(lineAt(src, off+span.Start))
+			}
+			if pkg != "" {
+				m["package"] = pkg
+			}
+			for k, v := range meta {
+				m[k] = v
+			}
+			reps = append(reps, Representation{
+				ID:       ChunkID(relPath, "text", int64(len(reps))),
+				Path:     relPath,
+				Modality: "text",
+				Text:     strings.TrimSpace(span.Text),
+				Meta:     m,
+			})
+		}
+	}
+
+	// The text between definitions makes up one chunk, whose lines only
+	// follow on those of the file if it all comes before the first.
+	var gaps []string
+	restOff, prev := -1, 0
+	for _, d := range append(defs, schemaDef{start: len(src)}) {
+		if gap := strings.TrimSpace(src[prev:d.start]); gap != "" {
+			if len(gaps) == 0 {
+				restOff = prev + strings.Index(src[prev:d.start], gap[:1])
+			} else {
+				restOff = -1
+			}
+			gaps = append(gaps, gap)
+		}
+		prev = d.end
+	}
+	if len(gaps) > 0 {
+		add(strings.Join(gaps, "\n\n"), restOff, map[string]string{"kind": "file"})
+	}
+
+	for _, d := range defs {
+		symbol := d.name
+		switch {
+		case format == "proto" && pkg != "" && d.kind != "extend":
+			symbol = pkg + "." + d.name
+		case format == "thrift":
+			// Other Thrift files refer to it by the name of this one.
+			symbol = strings.TrimSuffix(filepath.Base(relPath), filepath.Ext(relPath)) + "." + d.name
+		}
+		meta := map[string]string{"kind": d.kind, "symbol": symbol}
+		if len(d.members) > 0 {
+			meta["members"] = strings.Join(d.members, ",")
+		}
+		add(src[d.start:d.end], d.start, meta)
+	}
+	slog.Debug("Created", "definitions", len(defs), "chunks", len(reps), "relPath", relPath)
+	return reps, nil
+}
+
+// schemaToken is a token of a schema file: a comment, a string literal, a
+// word (an identifier, a number or a dotted name) or a punctuation mark.
+type schemaToken struct {
+	text string
+	off  int
+}
+
+func (t schemaToken) comment() bool {
+	return strings.HasPrefix(t.text, "//") || strings.HasPrefix(t.text, "/*") || strings.HasPrefix(t.text, "#")
+}
+
+func (t schemaToken) ident() bool {
+	c := t.text[0]
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func (t schemaToken) number() bool {
+	return t.text[0] >= '0' && t.text[0] <= '9' || t.text[0] == '-'
+}
+
+// scanSchema splits src into tokens. Thrift also has # line comments.
+func scanSchema(src string, hashComments bool) []schemaToken {
+	var toks []schemaToken
+	word := func(c byte) bool {
+		return c == '_' || c == '.' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+	}
+	for i := 0; i < len(src); {
+		c := src[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case strings.HasPrefix(src[i:], "//") || c == '#' && hashComments:
+			if j := strings.IndexByte(src[i:], '\n'); j >= 0 {
+				i += j
+			} else {
+				i = len(src)
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			if j := strings.Index(src[i+2:], "*/"); j >= 0 {
+				i += j + 4
+			} else {
+				i = len(src)
+			}
+		case c == '"' || c == '\'':
+			for i++; i < len(src) && src[i] != c; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+			i = min(i+1, len(src))
+		case word(c):
+			for i < len(src) && word(src[i]) {
+				i++
+			}
+		default:
+			i++
+		}
+		toks = append(toks, schemaToken{text: src[start:i], off: start})
+	}
+	return toks
+}
+
+// parseSchema returns the package of a schema file, the first Thrift
+// namespace for Thrift, and its top-level definitions.
+func parseSchema(src, format string) (string, []schemaDef) {
+	toks := scanSchema(src, format == "thrift")
+	var (
+		pkg  string
+		defs []schemaDef
+	)
+	// next returns the index of the first token after i that is not a
+	// comment, len(toks) if there is none.
+	next := func(i int) int {
+		for i++; i < len(toks) && toks[i].comment(); i++ {
+		}
+		return i
+	}
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		if t.comment() {
+			continue
+		}
+		n := next(i)
+		switch {
+		case t.text == "package" && format == "proto" && n < len(toks):
+			pkg = toks[n].text
+		case t.text == "namespace" && format == "thrift" && pkg == "":
+			if n2 := next(n); n2 < len(toks) {
+				pkg = toks[n2].text
+			}
+		case schemaKinds[format][t.text] && n < len(toks) && toks[n].ident():
+			// The body is the first block before the end of the statement.
+			open := n
+			for open < len(toks) && toks[open].text != "{" && toks[open].text != ";" {
+				open++
+			}
+			if open == len(toks) || toks[open].text == ";" {
+				continue
+			}
+			depth, end := 0, open
+			for ; end < len(toks); end++ {
+				switch toks[end].text {
+				case "{":
+					depth++
+				case "}":
+					depth--
+				}
+				if depth == 0 {
+					break
+				}
+			}
+			if end == len(toks) {
+				end-- // unterminated: the rest of the file
+			}
+			d := schemaDef{
+				kind:    t.text,
+				name:    toks[n].text,
+				start:   t.off,
+				end:     toks[end].off + len(toks[end].text),
+				members: schemaMembers(toks[open+1 : end]),
+			}
+			if a := next(end); a < len(toks) && (toks[a].text == ";" || toks[a].text == ",") {
+				d.end, end = toks[a].off+1, a
+			}
+			// Comments right above the keyword document the definition.
+			for j := i - 1; j >= 0 && toks[j].comment(); j-- {
+				if strings.Count(src[toks[j].off+len(toks[j].text):d.start], "\n") > 1 ||
+					len(defs) > 0 && toks[j].off < defs[len(defs)-1].end {
+					break
+				}
+				d.start = toks[j].off
+			}
+			defs = append(defs, d)
+			i = end
+		}
+	}
+	return pkg, defs
+}
+
+// schemaMembers returns the names declared in the body of a definition:
+// fields (including those of proto oneofs), enum values, service methods
+// and nested definitions. Options, reserved names and the bodies of nested
+// definitions are left out.
+func schemaMembers(body []schemaToken) []string {
+	var toks []schemaToken
+	for _, t := range body {
+		if !t.comment() {
+			toks = append(toks, t)
+		}
+	}
+	var (
+		members []string
+		blocks  []bool // open blocks, true for oneofs, whose fields count
+		nesting int    // of (, < and [
+		first   string // first word of the current statement
+	)
+	visible := func() bool {
+		for _, oneof := range blocks {
+			if !oneof {
+				return false
+			}
+		}
+		return nesting == 0
+	}
+	for i, t := range toks {
+		switch t.text {
+		case "{":
+			blocks = append(blocks, i >= 2 && toks[i-2].text == "oneof")
+			first = ""
+			continue
+		case "}":
+			if len(blocks) > 0 {
+				blocks = blocks[:len(blocks)-1]
+			}
+			first = ""
+			continue
+		case "(", "<", "[":
+			nesting++
+		case ")", ">", "]":
+			nesting = max(nesting-1, 0)
+		case ";", ",":
+			// Reserved ranges are listed with commas.
+			if nesting == 0 && (t.text == ";" || first != "reserved" && first != "extensions") {
+				first = ""
+			}
+			continue
+		}
+		if !visible() || !t.ident() {
+			continue
+		}
+		if first == "" {
+			first = t.text
+		}
+		if first == "option" || first == "reserved" || first == "extensions" ||
+			t.text == "returns" || t.text == "throws" {
+			continue
+		}
+		var after string
+		if i+1 < len(toks) {
+			after = toks[i+1].text
+		}
+		switch {
+		case after == "=" || after == ";" || after == "," || after == "(" || after == "{" || after == "}" || after == "":
+		case toks[i+1].number() && i+2 < len(toks) && toks[i+2].text == ":":
+			// a Thrift field without a separator, followed by the next one
+		default:
+			continue
+		}
+		members = append(members, t.text)
+	}
+	return members
+}
@@ -0,0 +1,160 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func loadSchemaFile(t *testing.T, name, content string, chunkSize int) []Representation {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reps, err := NewSchemaFileLoader(chunkSize).Load(context.Background(), name, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reps
+}
+
+func TestSchemaFileLoader_Proto(t *testing.T) {
+	proto := `syntax = "proto3";
+
+package billing.v1;
+
+import "google/api/annotations.proto";
+
+// Invoice is sent to the customer at the end of the month.
+// It carries the billing address.
+message Invoice {
+  string id = 1;
+  Address billing_address = 2 [deprecated = true];
+  map<string, int64> totals = 3;
+  oneof payment {
+    string card_token = 4;
+    string iban = 5;
+  }
+  message Line {
+    string sku = 1;
+  }
+  reserved 9 to 11, 20 to max;
+  option (my.opt) = { a: 1 };
+}
+
+enum Status {
+  option allow_alias = true;
+  STATUS_UNSPECIFIED = 0;
+  PAID = 1;
+}
+
+service Billing {
+  rpc GetInvoice(GetInvoiceRequest) returns (Invoice) {
+    option (google.api.http) = { get: "/v1/invoices/{id}" };
+  }
+  rpc StreamInvoices(stream Req) returns (stream Invoice);
+}
+`
+	reps := loadSchemaFile(t, "billing.proto", proto, 2000)
+	if len(reps) != 4 {
+		t.Fatalf("expected the preamble and three definitions, got %d: %+v", len(reps), reps)
+	}
+	if m := reps[0].Meta; m["kind"] != "file" || m["line"] != "1" || m["package"] != "billing.v1" ||
+		!strings.Contains(reps[0].Text, `import "google/api/annotations.proto";`) {
+		t.Errorf("unexpected preamble: %q %v", reps[0].Text, m)
+	}
+
+	inv := reps[1]
+	if !strings.HasPrefix(inv.Text, "// Invoice is sent") || !strings.HasSuffix(inv.Text, "option (my.opt) = { a: 1 };\n}") {
+		t.Errorf("definition should run from its doc comment to its closing brace:\n%s", inv.Text)
+	}
+	want := map[string]string{
+		"source":  "SchemaFileLoader",
+		"path":    "billing.proto",
+		"format":  "proto",
+		"package": "billing.v1",
+		"kind":    "message",
+		"symbol":  "billing.v1.Invoice",
+		"members": "id,billing_address,totals,payment,card_token,iban,Line",
+		"line":    "7",
+	}
+	for k, v := range want {
+		if inv.Meta[k] != v {
+			t.Errorf("meta %s = %q, want %q", k, inv.Meta[k], v)
+		}
+	}
+	if m := reps[2].Meta; m["symbol"] != "billing.v1.Status" || m["members"] != "STATUS_UNSPECIFIED,PAID" {
+		t.Errorf("unexpected enum meta: %v", m)
+	}
+	if m := reps[3].Meta; m["kind"] != "service" || m["members"] != "GetInvoice,StreamInvoices" || m["line"] != "30" {
+		t.Errorf("unexpected service meta: %v", m)
+	}
+}
+
+func TestSchemaFileLoader_Thrift(t *testing.T) {
+	thrift := `namespace go shop.orders
+namespace java com.shop.orders
+
+include "common.thrift"
+
+typedef i64 OrderId
+
+/** An order placed by a customer. */
+struct Order {
+  1: required OrderId id,
+  2: optional common.Address shipping_address (go.tag = "json:\"ship\"")
+  3: list<string> skus = []
+}
+
+# Raised for unknown orders.
+exception NotFound { 1: string message }
+
+service Orders extends common.Base {
+  Order getOrder(1: OrderId id) throws (1: NotFound nf),
+  oneway void ping()
+}
+`
+	reps := loadSchemaFile(t, "orders.thrift", thrift, 2000)
+	if len(reps) != 4 {
+		t.Fatalf("expected the preamble and three definitions, got %d: %+v", len(reps), reps)
+	}
+	if !strings.Contains(reps[0].Text, "typedef i64 OrderId") || reps[0].Meta["package"] != "shop.orders" {
+		t.Errorf("unexpected preamble: %q %v", reps[0].Text, reps[0].Meta)
+	}
+	cases := []struct{ kind, symbol, members, prefix string }{
+		{"struct", "orders.Order", "id,shipping_address,skus", "/** An order"},
+		{"exception", "orders.NotFound", "message", "# Raised"},
+		{"service", "orders.Orders", "getOrder,ping", "service Orders"},
+	}
+	for i, c := range cases {
+		r := reps[i+1]
+		if r.Meta["kind"] != c.kind || r.Meta["symbol"] != c.symbol || r.Meta["members"] != c.members || !strings.HasPrefix(r.Text, c.prefix) {
+			t.Errorf("definition %d: got %v %q, want %+v", i, r.Meta, r.Text, c)
+		}
+	}
+}
+
+func TestSchemaFileLoader_LongDefinition(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("message Big {\n")
+	for i := 0; i < 50; i++ {
+		b.WriteString("  string field_with_a_long_name_" + strings.Repeat("x", i%5) + " = 1;\n")
+	}
+	b.WriteString("}\n")
+	reps := loadSchemaFile(t, "big.proto", b.String(), 400)
+	if len(reps) < 3 {
+		t.Fatalf("expected the definition to be cut into several chunks, got %d", len(reps))
+	}
+	for i, r := range reps {
+		if r.Meta["symbol"] != "Big" || len(r.Text) > 400 {
+			t.Errorf("chunk %d: %d characters, meta %v", i, len(r.Text), r.Meta)
+		}
+		if line, _ := strconv.Atoi(r.Meta["line"]); i > 0 && line <= 1 {
+			t.Errorf("chunk %d should point past the first line, got %s", i, r.Meta["line"])
+		}
+	}
+}
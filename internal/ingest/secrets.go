@@ -0,0 +1,233 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// secretRule finds one kind of secret. If its pattern has a group named
+// "secret", only that group is masked, e.g. the value of an assignment.
+type secretRule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// secretRules are the built-in rules of redact_secrets.
+var secretRules = []secretRule{
+	{"private_key", regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----(?:[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----|[\s\S]*)`)},
+	{"aws_access_key", regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`)},
+	{"aws_secret_key", regexp.MustCompile(`(?i)aws_?(?:secret_?access_?key|secret_?key)["']?\s*[:=]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})\b`)},
+	{"github_token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`)},
+	{"slack_token", regexp.MustCompile(`\bxox[abeprs]-[A-Za-z0-9-]{10,}\b`)},
+	{"openai_key", regexp.MustCompile(`\bsk-(?:proj-|svcacct-)?[A-Za-z0-9_-]{20,}\b`)},
+	{"stripe_key", regexp.MustCompile(`\b[rs]k_(?:live|test)_[A-Za-z0-9]{16,}\b`)},
+	{"google_api_key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{"url_password", regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\s:/@]+:(?P<secret>[^\s@/]+)@`)},
+	// Quoted values only, so that password = os.Getenv("PASSWORD") stays.
+	{"assignment", regexp.MustCompile(`(?i)\b(?:password|passwd|pwd|secret|api_?key|apikey|access_?token|auth_?token|client_?secret|private_?key)["']?\s*(?::|=|:=|=>)\s*["'](?P<secret>[^"'\s]{8,})["']`)},
+}
+
+// SecretRuleNames returns the names of the built-in rules of
+// redact_secrets, for its "rules" option.
+func SecretRuleNames() []string {
+	names := make([]string, len(secretRules))
+	for i, r := range secretRules {
+		names[i] = r.name
+	}
+	return names
+}
+
+// highEntropyToken matches the strings the entropy rule looks at: runs of
+// base64 and hex digits and the URL-safe punctuation of tokens.
+var highEntropyToken = regexp.MustCompile(`[A-Za-z0-9+/=_-]+`)
+
+// Defaults of the entropy rule of redact_secrets. Random base64 and
+// alphanumeric tokens of that length have about 4.6 to 5 bits of entropy per
+// character, hex ones such as git commit IDs and UUIDs under 4.
+const (
+	defaultSecretEntropy   = 4.2
+	defaultSecretMinLength = 32
+)
+
+// secretRedactor masks secrets in chunk text; see newSecretRedactor.
+type secretRedactor struct {
+	rules     []secretRule
+	entropy   float64 // bits per character; 0 disables the entropy rule
+	minLength int
+	mask      string // "" for [REDACTED:<rule>]
+}
+
+// newSecretRedactor masks secrets in chunk text before it is embedded and
+// indexed: private keys, cloud and API tokens, passwords in URLs and
+// assignments (see secretRules), and long random-looking tokens. Options:
+// "rules", a comma-separated list of built-in rules to use ("none" for
+// none, all if unset); "pattern", a regular expression of further secrets,
+// masking its "secret" group if it has one; "entropy", the bits of Shannon
+// entropy per character from which a token is a secret (4.2 if unset, 0 to
+// turn the entropy rule off); "min_length", the length of tokens it checks
+// (32 if unset); and "mask", what replaces each secret ("[REDACTED:<rule>]"
+// if unset). Chunks with a secret lose their "offset", and a warning names
+// the file and the rules that matched, never the secrets.
+func newSecretRedactor(options map[string]string) (PostProcessor, error) {
+	r := &secretRedactor{entropy: defaultSecretEntropy, minLength: defaultSecretMinLength, mask: options["mask"]}
+	switch names := options["rules"]; names {
+	case "":
+		r.rules = append(r.rules, secretRules...)
+	case "none":
+	default:
+		for _, name := range strings.Split(names, ",") {
+			name = strings.TrimSpace(name)
+			n := len(r.rules)
+			for _, rule := range secretRules {
+				if rule.name == name {
+					r.rules = append(r.rules, rule)
+				}
+			}
+			if len(r.rules) == n {
+				return nil, fmt.Errorf("unknown rule %q (available: %s)", name, strings.Join(SecretRuleNames(), ", "))
+			}
+		}
+	}
+	if p := options["pattern"]; p != "" {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		r.rules = append(r.rules, secretRule{"pattern", re})
+	}
+	if v := options["entropy"]; v != "" {
+		e, err := strconv.ParseFloat(v, 64)
+		if err != nil || e < 0 {
+			return nil, fmt.Errorf("entropy must be a number of bits per character, not %q", v)
+		}
+		r.entropy = e
+	}
+	if v := options["min_length"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("min_length must be a positive number, not %q", v)
+		}
+		r.minLength = n
+	}
+	return r, nil
+}
+
+func (r *secretRedactor) Process(ctx context.Context, reps []Representation) ([]Representation, error) {
+	found := map[string]int{}
+	path := ""
+	for i := range reps {
+		rep := &reps[i]
+		if rep.Text == "" {
+			continue
+		}
+		text, n := r.redact(rep.Text, found)
+		if n == 0 {
+			continue
+		}
+		rep.Text = text
+		rep.Meta = withoutOffset(rep.Meta)
+		if path == "" {
+			path = rep.Path
+		}
+	}
+	if len(found) > 0 {
+		rules := make([]string, 0, len(found))
+		total := 0
+		for name, n := range found {
+			rules = append(rules, name)
+			total += n
+		}
+		sort.Strings(rules)
+		util.FromContext(ctx).Warn("Redacted secrets before indexing", "path", path, "secrets", total, "rules", strings.Join(rules, ","))
+	}
+	return reps, nil
+}
+
+// redact masks the secrets in text, counting them by rule in found, and
+// returns the text and the number of secrets masked.
+func (r *secretRedactor) redact(text string, found map[string]int) (string, int) {
+	total := 0
+	for _, rule := range r.rules {
+		group := rule.re.SubexpIndex("secret")
+		matches := rule.re.FindAllStringSubmatchIndex(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[0], m[1]
+			if group > 0 && m[2*group] >= 0 {
+				start, end = m[2*group], m[2*group+1]
+			}
+			if start < last || strings.HasPrefix(text[start:end], "[REDACTED") {
+				continue
+			}
+			b.WriteString(text[last:start])
+			b.WriteString(r.maskFor(rule.name))
+			last = end
+			found[rule.name]++
+			total++
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+	}
+	if r.entropy > 0 {
+		text = highEntropyToken.ReplaceAllStringFunc(text, func(tok string) string {
+			if len(tok) < r.minLength || !hasLetterAndDigit(tok) || shannonEntropy(tok) < r.entropy {
+				return tok
+			}
+			found["entropy"]++
+			total++
+			return r.maskFor("entropy")
+		})
+	}
+	return text, total
+}
+
+func (r *secretRedactor) maskFor(rule string) string {
+	if r.mask != "" {
+		return r.mask
+	}
+	return "[REDACTED:" + rule + "]"
+}
+
+// hasLetterAndDigit reports whether s has both, as generated tokens do and
+// words, identifiers and numbers do not.
+func hasLetterAndDigit(s string) bool {
+	var letter, digit bool
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digit = true
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			letter = true
+		}
+	}
+	return letter && digit
+}
+
+// shannonEntropy returns the entropy of the bytes of s in bits per byte.
+func shannonEntropy(s string) float64 {
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	var h float64
+	n := float64(len(s))
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / n
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
@@ -0,0 +1,37 @@
+package ingest
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// sniffLen is how many bytes Sniff reads, all that http.DetectContentType
+// looks at.
+const sniffLen = 512
+
+// Sniff detects the MIME type of the file at path from its first bytes,
+// with http.DetectContentType, and reports whether the file is binary:
+// anything but text/*, which covers files in UTF-8, UTF-16 with a byte
+// order mark and other encodings without control bytes. The type is
+// returned without parameters, e.g. "text/plain" or "application/pdf".
+func Sniff(path string) (mimeType string, binary bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", false, err
+	}
+	mimeType = http.DetectContentType(head[:n])
+	if t, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = t
+	}
+	return mimeType, !strings.HasPrefix(mimeType, "text/"), nil
+}
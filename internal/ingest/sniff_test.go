@@ -0,0 +1,36 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name, body string
+		mime       string
+		binary     bool
+	}{
+		{"README", "Plain notes without an extension.\n", "text/plain", false},
+		{"empty", "", "text/plain", false},
+		{"page.md", "<!DOCTYPE html><html><body>hi</body></html>", "text/html", false},
+		{"utf16.txt", "\xff\xfeh\x00i\x00", "text/plain", false},
+		{"logo.md", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png", true},
+		{"blob.txt", "\x00\x01\x02\x03binary", "application/octet-stream", true},
+		{"doc", "%PDF-1.7\n", "application/pdf", true},
+	} {
+		p := filepath.Join(dir, tc.name)
+		if err := os.WriteFile(p, []byte(tc.body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mime, binary, err := Sniff(p)
+		if err != nil || mime != tc.mime || binary != tc.binary {
+			t.Errorf("Sniff(%s) = %q, %v, %v; want %q, %v", tc.name, mime, binary, err, tc.mime, tc.binary)
+		}
+	}
+	if _, _, err := Sniff(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Sniff of a missing file succeeded")
+	}
+}
@@ -0,0 +1,346 @@
+package source
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+)
+
+// Bucket lists and downloads the objects of an S3 or S3-compatible bucket.
+// GCS buckets are read through their S3-compatible XML API with HMAC keys.
+// Requests are signed with AWS Signature Version 4; without credentials they
+// are sent unsigned, which works for public buckets.
+type Bucket struct {
+	scheme   string // "s3" or "gs", used in document paths
+	bucket   string
+	prefix   string
+	region   string
+	endpoint *url.URL // nil for AWS virtual-hosted URLs
+	creds    credentials
+	include  []string
+	exclude  []string
+}
+
+type credentials struct {
+	accessKey, secretKey, sessionToken string
+}
+
+// NewBucket returns a source for an "s3" or "gcs" entry of the sources
+// section. Object keys are matched against include and exclude.
+func NewBucket(sc config.SourceConfig, include, exclude []string) (*Bucket, error) {
+	if sc.Bucket == "" {
+		return nil, fmt.Errorf("%s source requires a bucket", sc.Type)
+	}
+	b := &Bucket{
+		scheme:  "s3",
+		bucket:  sc.Bucket,
+		prefix:  sc.Prefix,
+		region:  sc.Region,
+		include: include,
+		exclude: exclude,
+	}
+	endpoint := sc.Endpoint
+	accessEnv, secretEnv := "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"
+	if sc.Type == "gcs" {
+		b.scheme = "gs"
+		if endpoint == "" {
+			endpoint = "https://storage.googleapis.com"
+		}
+		if b.region == "" {
+			b.region = "auto"
+		}
+		accessEnv, secretEnv = "GCS_HMAC_ACCESS_KEY", "GCS_HMAC_SECRET"
+	} else {
+		b.creds.sessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if b.region == "" {
+		b.region = os.Getenv("AWS_REGION")
+	}
+	if b.region == "" {
+		b.region = "us-east-1"
+	}
+	if sc.AccessKeyEnv != "" {
+		accessEnv = sc.AccessKeyEnv
+	}
+	if sc.SecretKeyEnv != "" {
+		secretEnv = sc.SecretKeyEnv
+	}
+	b.creds.accessKey = os.Getenv(accessEnv)
+	b.creds.secretKey = os.Getenv(secretEnv)
+
+	if endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid endpoint %q", endpoint)
+		}
+		b.endpoint = u
+	}
+	return b, nil
+}
+
+// ParseObjectURL splits an "s3://bucket/key" or "gs://bucket/key" URL into a
+// bucket client and an object key. endpoint optionally points s3:// URLs at
+// an S3-compatible server; credentials come from the usual environment
+// variables.
+func ParseObjectURL(raw, endpoint string) (*Bucket, string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, "", err
+	}
+	typ := map[string]string{"s3": "s3", "gs": "gcs"}[u.Scheme]
+	key := strings.TrimPrefix(u.Path, "/")
+	if typ == "" || u.Host == "" || key == "" {
+		return nil, "", fmt.Errorf("invalid object URL %q, expected s3://bucket/key or gs://bucket/key", raw)
+	}
+	b, err := NewBucket(config.SourceConfig{Type: typ, Bucket: u.Host, Endpoint: endpoint}, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return b, key, nil
+}
+
+func (b *Bucket) Name() string {
+	return b.scheme + "://" + b.bucket + "/" + b.prefix
+}
+
+// objectURL builds the URL of key, or of the bucket itself for key "".
+// Custom endpoints use path-style addressing, which S3-compatible servers
+// and GCS support; AWS uses virtual-hosted URLs.
+func (b *Bucket) objectURL(key string, query url.Values) *url.URL {
+	u := &url.URL{Scheme: "https", Host: b.bucket + ".s3." + b.region + ".amazonaws.com", Path: "/" + key}
+	if b.endpoint != nil {
+		u = &url.URL{
+			Scheme: b.endpoint.Scheme,
+			Host:   b.endpoint.Host,
+			Path:   strings.TrimSuffix(b.endpoint.Path, "/") + "/" + b.bucket + "/" + key,
+		}
+	}
+	u.RawPath = encodePath(u.Path)
+	if query != nil {
+		u.RawQuery = encodeQuery(query)
+	}
+	return u
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (b *Bucket) Walk(ctx context.Context, visit VisitFunc) error {
+	slog.Info("Starting bucket crawl...", "source", b.Name(), "include", b.include, "exclude", b.exclude)
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}}
+		if b.prefix != "" {
+			q.Set("prefix", b.prefix)
+		}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		var page listBucketResult
+		if err := b.get(ctx, httpClient, b.objectURL("", q), func(body io.Reader) error {
+			return xml.NewDecoder(body).Decode(&page)
+		}); err != nil {
+			return fmt.Errorf("list %s: %w", b.Name(), err)
+		}
+
+		for _, obj := range page.Contents {
+			if strings.HasSuffix(obj.Key, "/") || !ingest.PathIncluded(b.include, b.exclude, obj.Key) {
+				continue
+			}
+			relPath := b.scheme + "://" + b.bucket + "/" + obj.Key
+			if obj.Size > MaxDownloadBytes {
+				slog.Warn("Skipping remote file larger than limit", "path", relPath, "size", obj.Size)
+				continue
+			}
+			err := b.get(ctx, httpClient, b.objectURL(obj.Key, nil), func(body io.Reader) error {
+				return visitDownload(relPath, extOf(obj.Key), body, visit)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// Get downloads the object key and passes its content to read. Unlike the
+// downloads made while walking, it is not size-limited or time-limited
+// beyond ctx.
+func (b *Bucket) Get(ctx context.Context, key string, read func(io.Reader) error) error {
+	return b.get(ctx, transferClient, b.objectURL(key, nil), read)
+}
+
+// Put uploads size bytes from body as the object key. The payload is sent
+// unsigned, so large files are streamed rather than hashed up front.
+func (b *Bucket) Put(ctx context.Context, key string, body io.Reader, size int64) error {
+	u := b.objectURL(key, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	resp, err := b.do(transferClient, req, unsignedPayload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("PUT %s: %s: %s", u.Redacted(), resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// get performs a signed GET and passes the body of a 200 response to read.
+func (b *Bucket) get(ctx context.Context, client *http.Client, u *url.URL, read func(io.Reader) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := b.do(client, req, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GET %s: %s: %s", u.Redacted(), resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return read(resp.Body)
+}
+
+// do signs req when credentials are configured and sends it with client.
+func (b *Bucket) do(client *http.Client, req *http.Request, payloadHash string) (*http.Response, error) {
+	if b.creds.accessKey != "" && b.creds.secretKey != "" {
+		signV4(req, b.creds, b.region, payloadHash, time.Now())
+	}
+	return client.Do(req)
+}
+
+const (
+	// emptyPayloadHash is the SHA-256 of an empty body, sent with every GET.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// unsignedPayload tells S3 that the body is not part of the signature.
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// signV4 adds AWS Signature Version 4 headers to req, whose body hashes to
+// payloadHash. All headers already set on req are signed along with the host.
+func signV4(req *http.Request, creds credentials, region, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		encodeQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + region + "/s3/aws4_request"
+	crHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(crHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.secretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// encodeQuery sorts and percent-encodes query parameters as SigV4 requires.
+func encodeQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// encodePath percent-encodes an object path, keeping the slashes.
+func encodePath(p string) string {
+	return uriEncode(p, false)
+}
+
+// uriEncode escapes everything but RFC 3986 unreserved characters, and "/"
+// unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,101 @@
+package source
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+)
+
+// Local crawls a directory with the include/exclude globs of the files
+// section.
+type Local struct {
+	root  string
+	files config.FilesConfig
+}
+
+// NewLocal returns a source for the directory root. An empty root is the
+// working directory, whose files keep their plain relative paths; files of
+// any other root are prefixed with it, e.g. "../docs/guide.md".
+func NewLocal(root string, files config.FilesConfig) *Local {
+	return &Local{root: root, files: files}
+}
+
+func (l *Local) Name() string {
+	if l.root == "" {
+		return "."
+	}
+	return l.root
+}
+
+// absRoot returns the directory crawled, as an absolute path.
+func (l *Local) absRoot() (string, error) {
+	dir := l.root
+	if dir == "" || dir == "." {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		dir = wd
+	}
+	return filepath.Abs(dir)
+}
+
+// logical returns the path files of the crawled directory are indexed under.
+func (l *Local) logical(relPath string) string {
+	if l.root != "" && l.root != "." {
+		return filepath.ToSlash(filepath.Join(l.root, relPath))
+	}
+	return relPath
+}
+
+func (l *Local) Walk(ctx context.Context, visit VisitFunc) error {
+	absRoot, err := l.absRoot()
+	if err != nil {
+		return err
+	}
+
+	filePathChan := make(chan string, 100)
+	errChan := make(chan error, 1)
+	go ingest.CrawlDir(absRoot, l.files, filePathChan, errChan)
+
+	var visitErr error
+	for relPath := range filePathChan {
+		if visitErr != nil {
+			continue // drain so the crawler can finish
+		}
+		if err := ctx.Err(); err != nil {
+			visitErr = err
+			continue
+		}
+		visitErr = visit(l.logical(relPath), filepath.Join(absRoot, relPath))
+	}
+	if visitErr != nil {
+		return visitErr
+	}
+	select {
+	case err := <-errChan:
+		return err
+	default:
+		return nil
+	}
+}
+
+// Skipped calls skipped for what Walk leaves out by the include and exclude
+// patterns, with the paths Walk would give them; see ingest.SkippedFiles.
+func (l *Local) Skipped(skipped func(path, pattern string)) error {
+	absRoot, err := l.absRoot()
+	if err != nil {
+		return err
+	}
+	return ingest.SkippedFiles(absRoot, l.files, func(relPath, pattern string) {
+		p := l.logical(relPath)
+		if strings.HasSuffix(relPath, "/") && !strings.HasSuffix(p, "/") {
+			p += "/" // a directory
+		}
+		skipped(p, pattern)
+	})
+}
@@ -0,0 +1,216 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/ingest"
+)
+
+// Sitemap crawls the pages listed in a sitemap.xml, following sitemap index
+// files. Only URLs on the sitemap's host are fetched. HTML pages are reduced
+// to their title and text; other documents, such as PDFs, are handed to the
+// loader for their extension. Document paths are the page URLs.
+type Sitemap struct {
+	url      *url.URL
+	maxPages int
+	include  []string
+	exclude  []string
+}
+
+// NewSitemap returns a source for the sitemap at rawURL fetching at most
+// maxPages pages (1000 if zero). include and exclude are globs over URL
+// paths without the leading slash, e.g. "docs/**".
+func NewSitemap(rawURL string, maxPages int, include, exclude []string) (*Sitemap, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("sitemap source requires an http(s) url, got %q", rawURL)
+	}
+	if maxPages <= 0 {
+		maxPages = 1000
+	}
+	return &Sitemap{url: u, maxPages: maxPages, include: include, exclude: exclude}, nil
+}
+
+func (s *Sitemap) Name() string { return s.url.String() }
+
+// sitemapDoc covers both <urlset> and <sitemapindex> documents.
+type sitemapDoc struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// maxSitemaps bounds the sitemap files read through index nesting.
+const maxSitemaps = 100
+
+func (s *Sitemap) Walk(ctx context.Context, visit VisitFunc) error {
+	slog.Info("Starting sitemap crawl...", "sitemap", s.url.String(), "max_pages", s.maxPages)
+	pages, err := s.pageURLs(ctx)
+	if err != nil {
+		return err
+	}
+	var visitErr error
+	trackVisit := func(relPath, localPath string) error {
+		visitErr = visit(relPath, localPath)
+		return visitErr
+	}
+	for _, page := range pages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.fetchPage(ctx, page, trackVisit); err != nil {
+			if visitErr != nil || ctx.Err() != nil {
+				return err
+			}
+			// One broken link should not stop the crawl.
+			slog.Warn("Failed to fetch page", "url", page, "error", err)
+		}
+	}
+	return nil
+}
+
+// pageURLs reads the sitemap tree breadth-first and returns up to maxPages
+// distinct, included page URLs.
+func (s *Sitemap) pageURLs(ctx context.Context) ([]string, error) {
+	queue := []string{s.url.String()}
+	seenMaps := map[string]bool{}
+	seenPages := map[string]bool{}
+	var pages []string
+	for len(queue) > 0 && len(pages) < s.maxPages {
+		next := queue[0]
+		queue = queue[1:]
+		if seenMaps[next] || len(seenMaps) >= maxSitemaps {
+			continue
+		}
+		seenMaps[next] = true
+
+		var doc sitemapDoc
+		err := s.get(ctx, next, func(resp *http.Response) error {
+			return decodeSitemap(resp.Body, &doc)
+		})
+		if err != nil {
+			if next == s.url.String() {
+				return nil, fmt.Errorf("sitemap %s: %w", next, err)
+			}
+			slog.Warn("Failed to read nested sitemap", "url", next, "error", err)
+			continue
+		}
+		for _, sm := range doc.Sitemaps {
+			if u, ok := s.sameHost(sm.Loc); ok {
+				queue = append(queue, u.String())
+			}
+		}
+		for _, p := range doc.URLs {
+			u, ok := s.sameHost(p.Loc)
+			if !ok || seenPages[u.String()] {
+				continue
+			}
+			if !ingest.PathIncluded(s.include, s.exclude, strings.TrimPrefix(u.Path, "/")) {
+				continue
+			}
+			seenPages[u.String()] = true
+			pages = append(pages, u.String())
+			if len(pages) == s.maxPages {
+				slog.Warn("Sitemap has more pages than max_pages, crawling the first ones", "max_pages", s.maxPages)
+				break
+			}
+		}
+	}
+	return pages, nil
+}
+
+// sameHost parses loc and reports whether it is on the sitemap's host.
+func (s *Sitemap) sameHost(loc string) (*url.URL, bool) {
+	u, err := url.Parse(strings.TrimSpace(loc))
+	if err != nil || !strings.EqualFold(u.Host, s.url.Host) || (u.Scheme != "http" && u.Scheme != "https") {
+		slog.Debug("Skipping sitemap entry outside the site", "loc", loc)
+		return nil, false
+	}
+	u.Fragment = ""
+	return u, true
+}
+
+// decodeSitemap parses a sitemap, gunzipping it first if needed (sitemap.xml.gz).
+func decodeSitemap(r io.Reader, doc *sitemapDoc) error {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return xml.NewDecoder(io.LimitReader(gz, MaxDownloadBytes)).Decode(doc)
+	}
+	return xml.NewDecoder(br).Decode(doc)
+}
+
+var reHTMLTitle = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// fetchPage downloads one page. HTML is converted to text and stored as a
+// .txt file; other content keeps the extension of the URL path.
+func (s *Sitemap) fetchPage(ctx context.Context, page string, visit VisitFunc) error {
+	return s.get(ctx, page, func(resp *http.Response) error {
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		switch {
+		case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+			raw, err := io.ReadAll(io.LimitReader(resp.Body, MaxDownloadBytes))
+			if err != nil {
+				return err
+			}
+			text := ingest.HTMLToText(string(raw))
+			if m := reHTMLTitle.FindSubmatch(raw); m != nil {
+				if title := strings.TrimSpace(html.UnescapeString(string(m[1]))); title != "" {
+					text = title + "\n\n" + text
+				}
+			}
+			if strings.TrimSpace(text) == "" {
+				return nil
+			}
+			return visitDownload(page, ".txt", strings.NewReader(text), visit)
+		default:
+			ext := extOf(resp.Request.URL.Path)
+			if ext == "" && strings.HasPrefix(mediaType, "text/") {
+				ext = ".txt"
+			}
+			if ext == "" {
+				slog.Debug("Skipping page with unknown content type", "url", page, "content_type", mediaType)
+				return nil
+			}
+			return visitDownload(page, ext, resp.Body, visit)
+		}
+	})
+}
+
+func (s *Sitemap) get(ctx context.Context, rawURL string, read func(*http.Response) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "semango")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	return read(resp)
+}
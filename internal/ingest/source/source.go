@@ -0,0 +1,112 @@
+// Package source provides the places semango indexes content from: the local
+// filesystem, S3-compatible and GCS buckets, and websites described by a
+// sitemap. Every source hands the pipeline a logical path, used as the
+// document path in the indexes, and a local file to load, so the loaders do
+// not need to know where content came from.
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+// VisitFunc is called for every file a source yields. relPath is the logical
+// path stored in the index ("docs/a.md", "s3://bucket/a.md",
+// "https://example.com/a"); localPath is a readable file with the same
+// extension as the content. Remote sources delete localPath once VisitFunc
+// returns. Returning an error stops the walk.
+type VisitFunc func(relPath, localPath string) error
+
+// Source enumerates the files to index.
+type Source interface {
+	// Name describes the source in logs, e.g. "s3://bucket/prefix".
+	Name() string
+	// Walk calls visit for each file until all files are visited, visit
+	// returns an error or ctx is done.
+	Walk(ctx context.Context, visit VisitFunc) error
+}
+
+// MaxDownloadBytes is the largest object or page fetched by remote sources;
+// bigger ones are skipped.
+const MaxDownloadBytes = 100 << 20
+
+// FromConfig builds the sources listed in the 'sources' section. Without any,
+// the working directory is crawled as before.
+func FromConfig(cfg *config.Config) ([]Source, error) {
+	if len(cfg.Sources) == 0 {
+		return []Source{NewLocal("", cfg.Files)}, nil
+	}
+	var sources []Source
+	for i, sc := range cfg.Sources {
+		files := cfg.Files
+		if len(sc.Include) > 0 {
+			files.Include = sc.Include
+		}
+		if len(sc.Exclude) > 0 {
+			files.Exclude = sc.Exclude
+		}
+		switch sc.Type {
+		case "local":
+			sources = append(sources, NewLocal(sc.Path, files))
+		case "s3", "gcs":
+			s, err := NewBucket(sc, files.Include, files.Exclude)
+			if err != nil {
+				return nil, fmt.Errorf("sources[%d]: %w", i, err)
+			}
+			sources = append(sources, s)
+		case "sitemap":
+			// File globs such as "**/*.md" rarely match page URLs, so the
+			// files section is not inherited here.
+			s, err := NewSitemap(sc.URL, sc.MaxPages, sc.Include, sc.Exclude)
+			if err != nil {
+				return nil, fmt.Errorf("sources[%d]: %w", i, err)
+			}
+			sources = append(sources, s)
+		default:
+			return nil, fmt.Errorf("sources[%d]: unknown source type %q", i, sc.Type)
+		}
+	}
+	return sources, nil
+}
+
+// httpClient is shared by the remote sources.
+var httpClient = &http.Client{Timeout: 2 * time.Minute}
+
+// transferClient has no overall timeout, for index bundles that can take
+// longer than httpClient allows to move; callers bound them with a context.
+var transferClient = &http.Client{}
+
+// visitDownload copies body into a temporary file with extension ext, calls
+// visit with it and removes it again. Bodies over MaxDownloadBytes are skipped.
+func visitDownload(relPath, ext string, body io.Reader, visit VisitFunc) error {
+	f, err := os.CreateTemp("", "semango-source-*"+ext)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	n, err := io.Copy(f, io.LimitReader(body, MaxDownloadBytes+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("download %s: %w", relPath, err)
+	}
+	if n > MaxDownloadBytes {
+		slog.Warn("Skipping remote file larger than limit", "path", relPath, "limit", MaxDownloadBytes)
+		return nil
+	}
+	return visit(relPath, f.Name())
+}
+
+// extOf returns the extension of the last element of a key or URL path.
+func extOf(p string) string {
+	return path.Ext(path.Base(p))
+}
@@ -0,0 +1,225 @@
+package tabular
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// AvroLoader reads Avro object container files (.avro). The columns and
+// their kinds come from the record schema embedded in the file rather than
+// from the values: numbers and decimals are numeric, dates and timestamps
+// datetime, enums and booleans categorical, bytes and fixed binary (left
+// out of rows), and strings, arrays, maps and nested records text, the
+// latter as JSON.
+type AvroLoader struct {
+	cfg config.TabularConfig
+}
+
+func NewAvroLoader(cfg config.TabularConfig) *AvroLoader { return &AvroLoader{cfg: cfg} }
+
+func (l *AvroLoader) Extensions() []string { return []string{".avro"} }
+
+func (l *AvroLoader) Load(ctx context.Context, relPath string, absPath string) ([]ingest.Representation, error) {
+	slog.Info("Loading Avro file", "relPath", relPath)
+	rows, schema, err := readAvro(absPath, readLimit(l.cfg))
+	if err != nil {
+		return nil, err
+	}
+	return BuildRepresentationsWithSchema(rows, schema, relPath, l.cfg)
+}
+
+// readRows reads up to limit records of the file.
+func (l *AvroLoader) readRows(absPath string, limit int) ([]map[string]string, error) {
+	rows, _, err := readAvro(absPath, limit)
+	return rows, err
+}
+
+// avroField is a field of the record schema of an Avro file.
+type avroField struct {
+	Column
+	logical string // logical type, e.g. "date"
+	scale   int    // of decimals
+	union   bool   // values come wrapped as {"type": value}
+}
+
+// readAvro reads up to limit records of the Avro file at absPath, and the
+// columns its schema declares.
+func readAvro(absPath string, limit int) ([]map[string]string, []Column, error) {
+	f, err := os.Open(absPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	ocf, err := goavro.NewOCFReader(bufio.NewReader(f))
+	if err != nil {
+		return nil, nil, err
+	}
+	fields, err := avroFields(ocf.Codec().Schema())
+	if err != nil {
+		return nil, nil, err
+	}
+	schema := make([]Column, len(fields))
+	for i, fd := range fields {
+		schema[i] = fd.Column
+	}
+
+	var rows []map[string]string
+	for ocf.Scan() && len(rows) < limit {
+		datum, err := ocf.Read()
+		if err != nil {
+			return nil, nil, err
+		}
+		record, ok := datum.(map[string]any)
+		if !ok {
+			continue
+		}
+		row := make(map[string]string, len(fields))
+		for _, fd := range fields {
+			if fd.Kind != KindBinary {
+				row[fd.Name] = fd.format(record[fd.Name])
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, schema, ocf.Err()
+}
+
+// avroFields returns the fields of the record schema of a file.
+func avroFields(schema string) ([]avroField, error) {
+	var record struct {
+		Type   any `json:"type"`
+		Fields []struct {
+			Name string          `json:"name"`
+			Type json.RawMessage `json:"type"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(schema), &record); err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+	if record.Type != "record" {
+		return nil, fmt.Errorf("Avro schema is not a record but %v", record.Type)
+	}
+	named := map[string]ColumnKind{}
+	fields := make([]avroField, len(record.Fields))
+	for i, f := range record.Fields {
+		var t any
+		if err := json.Unmarshal(f.Type, &t); err != nil {
+			return nil, fmt.Errorf("invalid type of field %s: %w", f.Name, err)
+		}
+		fields[i] = avroFieldOf(t, named)
+		fields[i].Name = f.Name
+	}
+	return fields, nil
+}
+
+// avroFieldOf works out the kind of a field of type t, recording the kinds
+// of the types it names in named for later fields that refer to them.
+func avroFieldOf(t any, named map[string]ColumnKind) avroField {
+	switch t := t.(type) {
+	case string:
+		return avroField{Column: Column{Kind: avroKind(t, named)}}
+	case []any:
+		var branches []any
+		for _, b := range t {
+			if b != "null" {
+				branches = append(branches, b)
+			}
+		}
+		fd := avroField{Column: Column{Kind: KindText}}
+		if len(branches) == 1 {
+			fd = avroFieldOf(branches[0], named)
+		}
+		fd.union = true
+		return fd
+	case map[string]any:
+		fd := avroField{Column: Column{Kind: KindText}}
+		switch fd.logical, _ = t["logicalType"].(string); fd.logical {
+		case "date", "timestamp-millis", "timestamp-micros", "local-timestamp-millis", "local-timestamp-micros":
+			fd.Kind = KindDateTime
+			return fd
+		case "decimal":
+			scale, _ := t["scale"].(float64)
+			fd.Kind, fd.scale = KindNumeric, int(scale)
+			return fd
+		}
+		typ, _ := t["type"].(string)
+		switch typ {
+		case "enum":
+			fd.Kind = KindCategorical
+		case "fixed":
+			fd.Kind = KindBinary
+		case "record", "array", "map":
+		default:
+			fd.Kind = avroKind(typ, named)
+		}
+		if name, ok := t["name"].(string); ok {
+			named[name] = fd.Kind
+		}
+		return fd
+	}
+	return avroField{Column: Column{Kind: KindText}}
+}
+
+// avroKind returns the kind of a primitive or named type.
+func avroKind(name string, named map[string]ColumnKind) ColumnKind {
+	switch name {
+	case "int", "long", "float", "double":
+		return KindNumeric
+	case "boolean":
+		return KindCategorical
+	case "bytes":
+		return KindBinary
+	}
+	if kind, ok := named[name]; ok {
+		return kind
+	}
+	return KindText
+}
+
+// format writes a value of the field, "" for null.
+func (fd avroField) format(v any) string {
+	if m, ok := v.(map[string]any); ok && fd.union && len(m) == 1 {
+		for _, inner := range m {
+			v = inner
+		}
+	}
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		if fd.logical == "date" {
+			return v.UTC().Format("2006-01-02")
+		}
+		return v.UTC().Format(time.RFC3339Nano)
+	case time.Duration:
+		return v.String()
+	case *big.Rat:
+		return v.FloatString(fd.scale)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -3,13 +3,14 @@ package tabular
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"hash/fnv"
+	"log/slog"
+	"math"
 	"math/rand"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
-	"time"
 
 	"github.com/omarkamali/semango/internal/config"
 	"github.com/omarkamali/semango/internal/ingest"
@@ -113,11 +114,43 @@ func isDateTime(s string) bool {
 // to config rules (sampling, token thresholds, etc.).
 // rows parameter MAY be truncated already (e.g. sampling pre-applied by loader).
 func BuildRepresentations(rows []map[string]string, relPath string, cfg config.TabularConfig) ([]ingest.Representation, error) {
+	return buildRepresentations(rows, nil, relPath, cfg)
+}
+
+// BuildRepresentationsWithSchema is BuildRepresentations for formats whose
+// files declare their columns, such as Avro and ORC: schema, in the order
+// of the file, gives the kinds of the columns instead of DetectSchema
+// guessing them from the values. A declared string can hold free text or
+// categories, so text columns DetectSchema finds categorical are made so.
+func BuildRepresentationsWithSchema(rows []map[string]string, schema []Column, relPath string, cfg config.TabularConfig) ([]ingest.Representation, error) {
+	if schema == nil {
+		schema = []Column{}
+	}
+	return buildRepresentations(rows, schema, relPath, cfg)
+}
+
+// buildRepresentations builds the representations of rows, detecting their
+// schema unless declared is not nil.
+func buildRepresentations(rows []map[string]string, declared []Column, relPath string, cfg config.TabularConfig) ([]ingest.Representation, error) {
 	if len(rows) == 0 {
 		return nil, nil
 	}
 
+	// Personal data is redacted before anything is built from the rows.
+	redacted := redactColumns(rows, cfg)
+	var redactedNames []string
+	for name := range redacted {
+		redactedNames = append(redactedNames, name)
+	}
+	sort.Strings(redactedNames)
+	if len(redactedNames) > 0 {
+		slog.Info("Redacted columns", "path", relPath, "columns", redactedNames)
+	}
+
 	schema := DetectSchema(rows)
+	if declared != nil {
+		schema = declaredSchema(declared, schema, redacted)
+	}
 
 	// compute schema hash – deterministic: join name+kind sorted
 	var sb strings.Builder
@@ -140,12 +173,16 @@ func BuildRepresentations(rows []map[string]string, relPath string, cfg config.T
 		var textParts []string
 		for _, col := range schema {
 			val := strings.TrimSpace(row[col.Name])
-			if val == "" {
-				continue
+			if val == "" || redacted[col.Name] != "" {
+				continue // masks and hashes mean nothing to the embedder
 			}
 			switch col.Kind {
 			case KindText, KindCategorical:
 				textParts = append(textParts, col.Name+": "+val)
+			case KindNumeric, KindDateTime:
+				if cfg.NumbersInText {
+					textParts = append(textParts, col.Name+": "+val)
+				}
 			}
 		}
 		joinedText := strings.Join(textParts, "\n")
@@ -170,11 +207,12 @@ func BuildRepresentations(rows []map[string]string, relPath string, cfg config.T
 		for k, v := range row {
 			rep.Meta["col."+k] = v
 		}
+		setRedacted(rep.Meta, redactedNames)
 		reps = append(reps, rep)
 	}
 
 	// sampling logic
-	if numRows > cfg.MaxRowsEmbedded {
+	if numRows > cfg.MaxRowsEmbedded && cfg.Sampling != SamplingAll {
 		step := float64(numRows) / float64(cfg.MaxRowsEmbedded)
 		switch cfg.Sampling {
 		case "stratified":
@@ -187,9 +225,10 @@ func BuildRepresentations(rows []map[string]string, relPath string, cfg config.T
 				}
 			}
 		default: // random
-			// reservoir sampling first MaxRowsEmbedded rows
-			importMathRandOnce()
-			selected := randPerm(numRows)[:cfg.MaxRowsEmbedded]
+			// Seeded by the rows, so that re-indexing an unchanged file
+			// embeds the same rows.
+			rng := rand.New(rand.NewSource(samplingSeed(rows, cfg)))
+			selected := rng.Perm(numRows)[:cfg.MaxRowsEmbedded]
 			sort.Ints(selected)
 			for _, i := range selected {
 				emitRow(i, rows[i])
@@ -202,7 +241,7 @@ func BuildRepresentations(rows []map[string]string, relPath string, cfg config.T
 	}
 
 	// file-level summary representation
-	summaryText := buildSummaryText(relPath, numRows, schema)
+	summaryText := buildSummaryText(relPath, numRows, schema, summarizeColumns(rows, schema, redacted))
 	reps = append(reps, ingest.Representation{
 		ID:       ingest.ChunkID(relPath, "table_file_summary", 0),
 		Path:     relPath,
@@ -215,6 +254,7 @@ func BuildRepresentations(rows []map[string]string, relPath string, cfg config.T
 			"path":   relPath,
 		},
 	})
+	setRedacted(reps[len(reps)-1].Meta, redactedNames)
 
 	// schema representation
 	schemaText := buildSchemaText(schema)
@@ -230,16 +270,51 @@ func BuildRepresentations(rows []map[string]string, relPath string, cfg config.T
 			"path":   relPath,
 		},
 	})
+	setRedacted(reps[len(reps)-1].Meta, redactedNames)
 
 	return reps, nil
 }
 
-func buildSummaryText(relPath string, numRows int, schema []Column) string {
+// declaredSchema returns the declared columns but for the dropped ones,
+// text columns being categorical when detected so.
+func declaredSchema(declared, detected []Column, redacted map[string]string) []Column {
+	categorical := map[string]bool{}
+	for _, c := range detected {
+		categorical[c.Name] = c.Kind == KindCategorical
+	}
+	var schema []Column
+	for _, c := range declared {
+		if redacted[c.Name] == RedactDrop {
+			continue
+		}
+		if c.Kind == KindText && categorical[c.Name] {
+			c.Kind = KindCategorical
+		}
+		schema = append(schema, c)
+	}
+	return schema
+}
+
+// setRedacted records the redacted columns, if any, in the "redacted"
+// metadata, e.g. "email,phone".
+func setRedacted(meta map[string]string, columns []string) {
+	if len(columns) > 0 {
+		meta["redacted"] = strings.Join(columns, ",")
+	}
+}
+
+// buildSummaryText describes the file, its columns and, a line each, the
+// range of its numeric and datetime columns.
+func buildSummaryText(relPath string, numRows int, schema []Column, stats []columnStats) string {
 	var cols []string
 	for _, c := range schema {
 		cols = append(cols, c.Name)
 	}
-	return "Tabular file " + relPath + " with " + strconv.Itoa(numRows) + " rows. Columns: " + strings.Join(cols, ", ")
+	text := "Tabular file " + relPath + " with " + strconv.Itoa(numRows) + " rows. Columns: " + strings.Join(cols, ", ")
+	for _, st := range stats {
+		text += "\n" + st.String()
+	}
+	return text
 }
 
 func buildSchemaText(schema []Column) string {
@@ -260,29 +335,47 @@ func columnKindString(k ColumnKind) string {
 		return "datetime"
 	case KindCategorical:
 		return "categorical"
+	case KindBinary:
+		return "binary"
 	default:
 		return "unknown"
 	}
 }
 
-// rand helpers – defer math/rand global usage until needed to keep deterministic tests unless called.
+// SamplingAll is the tabular.sampling that embeds every row of a file,
+// however many it has.
+const SamplingAll = "all"
 
-func importMathRandOnce() {
-	if rngInitDone {
-		return
+// readLimit is how many rows loaders read of a file: twice as many as are
+// embedded, for sampling to choose from, or every row with SamplingAll.
+func readLimit(cfg config.TabularConfig) int {
+	if cfg.Sampling == SamplingAll {
+		return math.MaxInt
 	}
-	rngInitOnce.Do(func() {
-		rand.Seed(time.Now().UnixNano())
-		rngInitDone = true
-	})
+	return cfg.MaxRowsEmbedded * 2
 }
 
-var (
-	rngInitOnce sync.Once
-	rngInitDone bool
-)
-
-func randPerm(n int) []int {
-	importMathRandOnce()
-	return rand.Perm(n)
+// samplingSeed returns tabular.sampling_seed, or else a hash of the rows,
+// in which the order of columns does not matter.
+func samplingSeed(rows []map[string]string, cfg config.TabularConfig) int64 {
+	if cfg.SamplingSeed != 0 {
+		return cfg.SamplingSeed
+	}
+	h := fnv.New64a()
+	var keys []string
+	for _, row := range rows {
+		keys = keys[:0]
+		for k := range row {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			h.Write([]byte(k))
+			h.Write([]byte{0})
+			h.Write([]byte(row[k]))
+			h.Write([]byte{0})
+		}
+		h.Write([]byte{'\n'})
+	}
+	return int64(h.Sum64())
 }
@@ -36,6 +36,16 @@ func (l *CSVLoader) Extensions() []string { return []string{".csv", ".tsv"} }
 
 func (l *CSVLoader) Load(ctx context.Context, relPath string, absPath string) ([]ingest.Representation, error) {
 	slog.Info("Loading CSV file", "relPath", relPath)
+	// read extra so sampling has enough, but safeguard memory
+	rows, err := l.readRows(relPath, absPath, readLimit(l.cfg))
+	if err != nil {
+		return nil, err
+	}
+	return BuildRepresentations(rows, relPath, l.cfg)
+}
+
+// readRows reads up to limit rows of the file, keyed by column name.
+func (l *CSVLoader) readRows(relPath, absPath string, limit int) ([]map[string]string, error) {
 	f, err := os.Open(absPath)
 	if err != nil {
 		return nil, err
@@ -49,9 +59,10 @@ func (l *CSVLoader) Load(ctx context.Context, relPath string, absPath string) ([
 	if err != nil {
 		return nil, err
 	}
+	// With ReuseRecord the next Read overwrites the header slice.
+	headers = append([]string(nil), headers...)
 
 	var rows []map[string]string
-	maxRows := l.cfg.MaxRowsEmbedded * 2 // read extra so sampling has enough, but safeguard memory
 
 	for i := 0; ; i++ {
 		record, err := r.Read()
@@ -73,10 +84,9 @@ func (l *CSVLoader) Load(ctx context.Context, relPath string, absPath string) ([
 			}
 		}
 		rows = append(rows, row)
-		if len(rows) >= maxRows {
+		if len(rows) >= limit {
 			break // don't keep reading huge files – later sampling or embed cap will handle
 		}
 	}
-
-	return BuildRepresentations(rows, relPath, l.cfg)
+	return rows, nil
 }
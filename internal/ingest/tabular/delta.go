@@ -0,0 +1,231 @@
+package tabular
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A reader of the Delta Lake transaction log, see
+// https://github.com/delta-io/delta/blob/master/PROTOCOL.md.
+
+var (
+	reDeltaCommit     = regexp.MustCompile(`^(\d{20})\.json$`)
+	reDeltaCheckpoint = regexp.MustCompile(`^(\d{20})\.checkpoint(?:\.\d+\.\d+)?\.parquet$`)
+)
+
+// deltaLog lists the files of a _delta_log directory by version.
+type deltaLog struct {
+	commits     map[int64]string
+	checkpoints map[int64][]string // parts of the checkpoint of each version
+	latest      int64
+}
+
+func readDeltaLog(dir string) (*deltaLog, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, "_delta_log"))
+	if err != nil {
+		return nil, err
+	}
+	log := &deltaLog{commits: map[int64]string{}, checkpoints: map[int64][]string{}, latest: -1}
+	for _, e := range entries {
+		name := e.Name()
+		if m := reDeltaCommit.FindStringSubmatch(name); m != nil {
+			v, _ := strconv.ParseInt(m[1], 10, 64)
+			log.commits[v] = name
+			log.latest = max(log.latest, v)
+		} else if m := reDeltaCheckpoint.FindStringSubmatch(name); m != nil {
+			v, _ := strconv.ParseInt(m[1], 10, 64)
+			log.checkpoints[v] = append(log.checkpoints[v], name)
+			log.latest = max(log.latest, v)
+		}
+	}
+	if log.latest < 0 {
+		return nil, fmt.Errorf("no commits in the Delta log of %s", dir)
+	}
+	return log, nil
+}
+
+// deltaAction is a line of a commit, or a row of a checkpoint; only the
+// actions that make up the snapshot are read.
+type deltaAction struct {
+	Add *struct {
+		Path            string             `json:"path"`
+		PartitionValues map[string]*string `json:"partitionValues"`
+		Stats           string             `json:"stats"`
+	} `json:"add"`
+	Remove *struct {
+		Path string `json:"path"`
+	} `json:"remove"`
+	MetaData *struct {
+		SchemaString     string   `json:"schemaString"`
+		PartitionColumns []string `json:"partitionColumns"`
+	} `json:"metaData"`
+}
+
+// readDelta reads the latest snapshot of the Delta table dir: the commits
+// from the first, or else from the latest checkpoint they follow on.
+func readDelta(dir string) (*lakeSnapshot, error) {
+	log, err := readDeltaLog(dir)
+	if err != nil {
+		return nil, err
+	}
+	// Commits first to latest follow on each other. Older ones may have
+	// been cleaned up, leaving the snapshot to start from a checkpoint.
+	first := log.latest + 1
+	for first > 0 && log.commits[first-1] != "" {
+		first--
+	}
+	start := int64(-1) // the version of that checkpoint
+	if first > 0 {
+		for v := log.latest; v >= first-1 && start < 0; v-- {
+			if len(log.checkpoints[v]) > 0 {
+				start = v
+			}
+		}
+		if start < 0 {
+			return nil, fmt.Errorf("the Delta log of %s misses commits before version %d and has no checkpoint after them", dir, first)
+		}
+	}
+
+	adds := map[string]*deltaAction{}
+	var meta *deltaAction
+	apply := func(a *deltaAction) {
+		switch {
+		case a.Add != nil:
+			adds[a.Add.Path] = a
+		case a.Remove != nil:
+			delete(adds, a.Remove.Path)
+		case a.MetaData != nil:
+			meta = a
+		}
+	}
+	if start >= 0 {
+		for _, name := range log.checkpoints[start] {
+			if err := readDeltaCheckpoint(filepath.Join(dir, "_delta_log", name), apply); err != nil {
+				return nil, fmt.Errorf("Delta checkpoint %s: %w", name, err)
+			}
+		}
+	}
+	for v := start + 1; v <= log.latest; v++ {
+		if err := readDeltaCommit(filepath.Join(dir, "_delta_log", log.commits[v]), apply); err != nil {
+			return nil, fmt.Errorf("Delta commit %s: %w", log.commits[v], err)
+		}
+	}
+	if meta == nil {
+		return nil, fmt.Errorf("the Delta log of %s has no table metadata", dir)
+	}
+
+	snap := &lakeSnapshot{
+		format:     FormatDelta,
+		version:    strconv.FormatInt(log.latest, 10),
+		partitions: meta.MetaData.PartitionColumns,
+	}
+	if snap.schema, err = deltaSchema(meta.MetaData.SchemaString); err != nil {
+		return nil, err
+	}
+	for p, a := range adds {
+		f := lakeFile{rel: p, format: "parquet", partition: map[string]string{}}
+		if u, err := url.PathUnescape(p); err == nil {
+			f.rel = u
+		}
+		f.path = lakePath(dir, "", f.rel)
+		for k, v := range a.Add.PartitionValues {
+			if v != nil {
+				f.partition[k] = *v
+			}
+		}
+		snap.files = append(snap.files, f)
+	}
+	sort.Slice(snap.files, func(i, j int) bool { return snap.files[i].rel < snap.files[j].rel })
+	snap.rows = deltaRows(adds)
+	return snap, nil
+}
+
+// readDeltaCommit applies the actions of a commit, a JSON object a line.
+func readDeltaCommit(name string, apply func(*deltaAction)) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	for {
+		var a deltaAction
+		err := dec.Decode(&a)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		apply(&a)
+	}
+}
+
+// readDeltaCheckpoint applies the actions of a checkpoint, Parquet rows
+// holding an action each in the column named after it.
+func readDeltaCheckpoint(name string, apply func(*deltaAction)) error {
+	rows, err := (&ParquetLoader{}).readRows(name, math.MaxInt)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		var a deltaAction
+		for col, v := range row {
+			if v == "" || v == "null" {
+				continue
+			}
+			// Column and field names are matched regardless of case.
+			if err := json.Unmarshal([]byte(`{"`+strings.ToLower(col)+`":`+v+`}`), &a); err != nil {
+				continue // an action not part of the snapshot
+			}
+		}
+		apply(&a)
+	}
+	return nil
+}
+
+// deltaSchema returns the columns of the schema of a Delta table, a JSON
+// struct type.
+func deltaSchema(schemaString string) ([]Column, error) {
+	var st struct {
+		Fields []struct {
+			Name string `json:"name"`
+			Type any    `json:"type"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(schemaString), &st); err != nil {
+		return nil, fmt.Errorf("invalid Delta table schema: %w", err)
+	}
+	schema := make([]Column, len(st.Fields))
+	for i, f := range st.Fields {
+		t, _ := f.Type.(string) // nested types are objects
+		schema[i] = Column{Name: f.Name, Kind: lakeKind(t)}
+	}
+	return schema, nil
+}
+
+// deltaRows returns the rows of the table from the statistics of its data
+// files, or -1 if some have none.
+func deltaRows(adds map[string]*deltaAction) int64 {
+	var n int64
+	for _, a := range adds {
+		var stats struct {
+			NumRecords *int64 `json:"numRecords"`
+		}
+		if json.Unmarshal([]byte(a.Add.Stats), &stats) != nil || stats.NumRecords == nil {
+			return -1
+		}
+		n += *stats.NumRecords
+	}
+	return n
+}
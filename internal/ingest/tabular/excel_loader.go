@@ -2,6 +2,11 @@ package tabular
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/omarkamali/semango/internal/config"
 	"github.com/omarkamali/semango/internal/ingest"
@@ -9,48 +14,188 @@ import (
 	"github.com/xuri/excelize/v2"
 )
 
-// ExcelLoader supports .xlsx/.xlsm and .ods files
+// maxSheetColumns bounds the columns read from a row, as the repeated
+// cells of .ods files can stand for a whole row of the sheet.
+const maxSheetColumns = 16384
 
+// ExcelLoader supports .xlsx/.xlsm, .xlsb and .ods spreadsheets. Each sheet
+// is a table of its own, whose first row with a value holds the headers;
+// its chunks carry the sheet name and, for rows, the row number in the
+// sheet.
 type ExcelLoader struct {
 	cfg config.TabularConfig
 }
 
 func NewExcelLoader(cfg config.TabularConfig) *ExcelLoader { return &ExcelLoader{cfg: cfg} }
 
-func (l *ExcelLoader) Extensions() []string { return []string{".xlsx", ".xlsm"} }
+func (l *ExcelLoader) Extensions() []string { return []string{".xlsx", ".xlsm", ".xlsb", ".ods"} }
 
 func (l *ExcelLoader) Load(ctx context.Context, relPath string, absPath string) ([]ingest.Representation, error) {
-	return l.loadXLSX(relPath, absPath)
+	perSheet := l.cfg.MaxRowsPerSheet
+	if perSheet <= 0 {
+		perSheet = l.cfg.MaxRowsEmbedded
+	}
+	limit := perSheet * 2
+	if l.cfg.Sampling == SamplingAll {
+		limit = 0
+	}
+	sheets, err := l.readSheets(absPath, limit)
+	if err != nil {
+		return nil, err
+	}
+	caps := l.sheetCaps(sheets)
+
+	var allReps []ingest.Representation
+	for i, sh := range sheets {
+		cfg := l.cfg
+		cfg.MaxRowsEmbedded = caps[i]
+		reps, err := BuildRepresentations(sh.rows, relPath, cfg)
+		if err != nil {
+			return nil, err
+		}
+		for _, rep := range reps {
+			// Chunk IDs only tell the rows of one sheet apart.
+			rep.ID = ingest.ChunkID(relPath+"#"+sh.name, rep.ID, 0)
+			rep.Meta["sheet"] = sh.name
+			switch {
+			case rep.Modality == "table_row":
+				idx, _ := strconv.Atoi(rep.Meta["row"])
+				rep.Meta["row"] = strconv.Itoa(sh.numbers[idx])
+			case rep.Meta["kind"] == "file_summary":
+				rep.Text = strings.Replace(rep.Text, "Tabular file ", "Sheet "+sh.name+" of spreadsheet ", 1)
+			}
+			allReps = append(allReps, rep)
+		}
+	}
+	return allReps, nil
 }
 
-func (l *ExcelLoader) loadXLSX(relPath, absPath string) ([]ingest.Representation, error) {
-	f, err := excelize.OpenFile(absPath)
+// sheetCaps returns how many rows of each sheet may be embedded:
+// tabular.max_rows_per_sheet, or else shares of tabular.max_rows_embedded,
+// the rows smaller sheets leave over going to the larger ones.
+func (l *ExcelLoader) sheetCaps(sheets []*sheet) []int {
+	caps := make([]int, len(sheets))
+	if l.cfg.MaxRowsPerSheet > 0 {
+		for i := range caps {
+			caps[i] = l.cfg.MaxRowsPerSheet
+		}
+		return caps
+	}
+	order := make([]int, len(sheets))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return len(sheets[order[a]].rows) < len(sheets[order[b]].rows) })
+	left := l.cfg.MaxRowsEmbedded
+	for k, i := range order {
+		caps[i] = max(left/(len(order)-k), 1)
+		left -= min(len(sheets[i].rows), caps[i])
+	}
+	return caps
+}
+
+// readRows reads the rows of the named sheet, or of every sheet if sheet is
+// "", up to limit rows unless it is 0.
+func (l *ExcelLoader) readRows(absPath, sheet string, limit int) ([]map[string]string, error) {
+	sheets, err := l.readSheets(absPath, limit)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	sheets := f.GetSheetList()
 	var all []map[string]string
 	for _, sh := range sheets {
-		rows, _ := f.GetRows(sh)
-		if len(rows) == 0 {
+		if sheet != "" && sh.name != sheet {
 			continue
 		}
-		headers := rows[0]
-		for _, row := range rows[1:] {
-			m := map[string]string{}
-			for i, cell := range row {
-				var key string
-				if i < len(headers) && headers[i] != "" {
-					key = headers[i]
-				} else {
-					col, _ := excelize.ColumnNumberToName(i + 1)
-					key = col
-				}
-				m[key] = cell
+		all = append(all, sh.rows...)
+		if sheet != "" {
+			return all, nil
+		}
+	}
+	if sheet != "" {
+		return nil, fmt.Errorf("no sheet %q", sheet)
+	}
+	if limit > 0 && len(all) > limit {
+		all = all[:limit]
+	}
+	return all, nil
+}
+
+// readSheets reads the sheets of the spreadsheet, up to limit rows each
+// unless it is 0.
+func (l *ExcelLoader) readSheets(absPath string, limit int) ([]*sheet, error) {
+	switch strings.ToLower(filepath.Ext(absPath)) {
+	case ".ods":
+		return readODS(absPath, limit)
+	case ".xlsb":
+		return readXLSB(absPath, limit)
+	}
+	f, err := excelize.OpenFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var sheets []*sheet
+	for _, name := range f.GetSheetList() {
+		rows, err := f.Rows(name)
+		if err != nil {
+			return nil, err
+		}
+		sh := &sheet{name: name}
+		for n := 1; !sh.full(limit) && rows.Next(); n++ {
+			cells, err := rows.Columns()
+			if err != nil {
+				rows.Close()
+				return nil, err
 			}
-			all = append(all, m)
+			sh.add(n, cells)
 		}
+		rows.Close()
+		sheets = append(sheets, sh)
 	}
-	return BuildRepresentations(all, relPath, l.cfg)
+	return sheets, nil
+}
+
+// sheet is a sheet of a spreadsheet being read.
+type sheet struct {
+	name    string
+	headers []string
+	rows    []map[string]string // keyed by the headers
+	numbers []int               // of rows in the sheet, from 1
+}
+
+// add adds the cells of row n of the sheet, skipping empty rows; the first
+// that is not holds the headers. Cells without a header are keyed by their
+// column letter, e.g. "C".
+func (sh *sheet) add(n int, cells []string) {
+	empty := true
+	for _, c := range cells {
+		if strings.TrimSpace(c) != "" {
+			empty = false
+			break
+		}
+	}
+	if empty {
+		return
+	}
+	if sh.headers == nil {
+		sh.headers = append([]string{}, cells...)
+		return
+	}
+	row := make(map[string]string, len(cells))
+	for i, cell := range cells {
+		var key string
+		if i < len(sh.headers) && sh.headers[i] != "" {
+			key = sh.headers[i]
+		} else {
+			key, _ = excelize.ColumnNumberToName(i + 1)
+		}
+		row[key] = cell
+	}
+	sh.rows = append(sh.rows, row)
+	sh.numbers = append(sh.numbers, n)
+}
+
+// full reports whether the sheet has limit rows, limit 0 meaning no limit.
+func (sh *sheet) full(limit int) bool {
+	return limit > 0 && len(sh.rows) >= limit
 }
@@ -0,0 +1,241 @@
+package tabular
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// A reader of Apache Iceberg tables, see https://iceberg.apache.org/spec/.
+
+// icebergMetadata is what is read of a table metadata file.
+type icebergMetadata struct {
+	Location          string `json:"location"`
+	CurrentSnapshotID *int64 `json:"current-snapshot-id"`
+	Snapshots         []struct {
+		SnapshotID   int64             `json:"snapshot-id"`
+		ManifestList string            `json:"manifest-list"`
+		Manifests    []string          `json:"manifests"` // of format version 1 tables without a list
+		Summary      map[string]string `json:"summary"`
+	} `json:"snapshots"`
+	CurrentSchemaID int             `json:"current-schema-id"`
+	Schemas         []icebergSchema `json:"schemas"`
+	Schema          *icebergSchema  `json:"schema"` // format version 1
+	DefaultSpecID   int             `json:"default-spec-id"`
+	PartitionSpecs  []struct {
+		SpecID int                     `json:"spec-id"`
+		Fields []icebergPartitionField `json:"fields"`
+	} `json:"partition-specs"`
+	PartitionSpec []icebergPartitionField `json:"partition-spec"` // format version 1
+}
+
+type icebergSchema struct {
+	SchemaID int `json:"schema-id"`
+	Fields   []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+		Type any    `json:"type"`
+	} `json:"fields"`
+}
+
+type icebergPartitionField struct {
+	Name      string `json:"name"`
+	Transform string `json:"transform"`
+	SourceID  int    `json:"source-id"`
+}
+
+// currentIcebergMetadata returns the name of the current metadata file of
+// the table dir: the version version-hint.text names, or else the latest.
+func currentIcebergMetadata(dir string) (string, error) {
+	meta := filepath.Join(dir, "metadata")
+	if b, err := os.ReadFile(filepath.Join(meta, "version-hint.text")); err == nil {
+		hint := strings.TrimSpace(string(b))
+		for _, name := range []string{"v" + hint + ".metadata.json", "v" + hint + ".gz.metadata.json", hint} {
+			if fi, err := os.Stat(filepath.Join(meta, name)); err == nil && !fi.IsDir() {
+				return name, nil
+			}
+		}
+	}
+	entries, err := os.ReadDir(meta)
+	if err != nil {
+		return "", err
+	}
+	current, latest := "", -1
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, ".metadata.json") {
+			continue
+		}
+		// v3.metadata.json, or 00003-<uuid>.metadata.json
+		digits := strings.TrimPrefix(name, "v")
+		if i := strings.IndexAny(digits, "-."); i > 0 {
+			digits = digits[:i]
+		}
+		if v, err := strconv.Atoi(digits); err == nil && v > latest {
+			current, latest = name, v
+		}
+	}
+	if current == "" {
+		return "", fmt.Errorf("no Iceberg metadata file in %s", meta)
+	}
+	return current, nil
+}
+
+// readIceberg reads the current snapshot of the Iceberg table dir. Delete
+// files are not applied, so rows deleted by them are read.
+func readIceberg(dir string) (*lakeSnapshot, error) {
+	name, err := currentIcebergMetadata(dir)
+	if err != nil {
+		return nil, err
+	}
+	var md icebergMetadata
+	if err := readIcebergMetadata(filepath.Join(dir, "metadata", name), &md); err != nil {
+		return nil, fmt.Errorf("Iceberg metadata %s: %w", name, err)
+	}
+
+	schema := md.Schema
+	for i := range md.Schemas {
+		if md.Schemas[i].SchemaID == md.CurrentSchemaID {
+			schema = &md.Schemas[i]
+		}
+	}
+	if schema == nil {
+		return nil, fmt.Errorf("Iceberg metadata %s has no current schema", name)
+	}
+	snap := &lakeSnapshot{format: FormatIceberg, version: "none", rows: 0}
+	names := map[int]string{}
+	for _, f := range schema.Fields {
+		t, _ := f.Type.(string) // nested types are objects
+		snap.schema = append(snap.schema, Column{Name: f.Name, Kind: lakeKind(t)})
+		names[f.ID] = f.Name
+	}
+	spec := md.PartitionSpec
+	for _, s := range md.PartitionSpecs {
+		if s.SpecID == md.DefaultSpecID {
+			spec = s.Fields
+		}
+	}
+	for _, f := range spec {
+		if f.Transform == "identity" {
+			snap.partitions = append(snap.partitions, names[f.SourceID])
+		} else {
+			snap.partitions = append(snap.partitions, f.Transform+"("+names[f.SourceID]+")")
+		}
+	}
+
+	if md.CurrentSnapshotID == nil || *md.CurrentSnapshotID < 0 {
+		return snap, nil // no data yet
+	}
+	snap.version = strconv.FormatInt(*md.CurrentSnapshotID, 10)
+	var manifests []string
+	found := false
+	for _, s := range md.Snapshots {
+		if s.SnapshotID != *md.CurrentSnapshotID {
+			continue
+		}
+		found, manifests = true, s.Manifests
+		if s.ManifestList != "" {
+			manifests = nil
+			err := readAvroRecords(lakePath(dir, md.Location, s.ManifestList), func(r map[string]any) {
+				// Manifests of delete files are left out.
+				if p, _ := r["manifest_path"].(string); p != "" && avroInt(r["content"]) == 0 {
+					manifests = append(manifests, p)
+				}
+			})
+			if err != nil {
+				return nil, fmt.Errorf("Iceberg manifest list: %w", err)
+			}
+		}
+		if n, err := strconv.ParseInt(s.Summary["total-records"], 10, 64); err == nil {
+			snap.rows = n
+		} else {
+			snap.rows = -1
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("Iceberg metadata %s lacks its current snapshot %d", name, *md.CurrentSnapshotID)
+	}
+
+	for _, m := range manifests {
+		err := readAvroRecords(lakePath(dir, md.Location, m), func(r map[string]any) {
+			// Entries of status 2 were deleted by the snapshot.
+			df, _ := r["data_file"].(map[string]any)
+			if avroInt(r["status"]) == 2 || df == nil || avroInt(df["content"]) != 0 {
+				return
+			}
+			p, _ := df["file_path"].(string)
+			format, _ := df["file_format"].(string)
+			snap.files = append(snap.files, lakeFile{
+				path:   lakePath(dir, md.Location, p),
+				rel:    strings.TrimPrefix(p, strings.TrimSuffix(md.Location, "/")+"/"),
+				format: strings.ToLower(format),
+			})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Iceberg manifest %s: %w", m, err)
+		}
+	}
+	return snap, nil
+}
+
+// readIcebergMetadata reads a metadata file, gzip-compressed if its name
+// says so.
+func readIcebergMetadata(name string, md *icebergMetadata) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(name, ".gz.metadata.json") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return json.NewDecoder(r).Decode(md)
+}
+
+// readAvroRecords calls fn with each record of the Avro file name.
+func readAvroRecords(name string, fn func(map[string]any)) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	ocf, err := goavro.NewOCFReader(bufio.NewReader(f))
+	if err != nil {
+		return err
+	}
+	for ocf.Scan() {
+		datum, err := ocf.Read()
+		if err != nil {
+			return err
+		}
+		if r, ok := datum.(map[string]any); ok {
+			fn(r)
+		}
+	}
+	return ocf.Err()
+}
+
+// avroInt returns an Avro int or long, 0 for other values such as null.
+func avroInt(v any) int64 {
+	switch v := v.(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	}
+	return 0
+}
@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/omarkamali/semango/internal/config"
 	"github.com/omarkamali/semango/internal/ingest"
@@ -24,7 +25,16 @@ func (l *JSONLoader) Extensions() []string { return []string{".json", ".jsonl"}
 
 func (l *JSONLoader) Load(ctx context.Context, relPath string, absPath string) ([]ingest.Representation, error) {
 	slog.Info("Loading JSON file", "relPath", relPath)
+	rows, err := l.readRows(relPath, absPath, readLimit(l.cfg))
+	if err != nil {
+		return nil, err
+	}
+	return BuildRepresentations(rows, relPath, l.cfg)
+}
 
+// readRows reads up to limit objects of the file, with their values
+// stringified.
+func (l *JSONLoader) readRows(relPath, absPath string, limit int) ([]map[string]string, error) {
 	f, err := os.Open(absPath)
 	if err != nil {
 		return nil, err
@@ -33,7 +43,7 @@ func (l *JSONLoader) Load(ctx context.Context, relPath string, absPath string) (
 
 	var rows []map[string]string
 
-	ext := filepath.Ext(absPath)
+	ext := strings.ToLower(filepath.Ext(absPath))
 	if ext == ".jsonl" {
 		// Each line is a JSON object
 		scanner := bufio.NewScanner(f)
@@ -45,7 +55,7 @@ func (l *JSONLoader) Load(ctx context.Context, relPath string, absPath string) (
 				continue
 			}
 			rows = append(rows, stringifyMap(obj))
-			if len(rows) >= l.cfg.MaxRowsEmbedded*2 {
+			if len(rows) >= limit {
 				break
 			}
 		}
@@ -61,14 +71,18 @@ func (l *JSONLoader) Load(ctx context.Context, relPath string, absPath string) (
 			return nil, err
 		}
 		if delim, ok := tok.(json.Delim); ok && delim == '[' {
-			// Array start
+			// Array start; items other than objects are skipped.
 			for dec.More() {
-				var obj map[string]interface{}
-				if err := dec.Decode(&obj); err != nil {
+				var item interface{}
+				if err := dec.Decode(&item); err != nil {
 					return nil, err
 				}
+				obj, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
 				rows = append(rows, stringifyMap(obj))
-				if len(rows) >= l.cfg.MaxRowsEmbedded*2 {
+				if len(rows) >= limit {
 					break
 				}
 			}
@@ -84,8 +98,7 @@ func (l *JSONLoader) Load(ctx context.Context, relPath string, absPath string) (
 			rows = append(rows, stringifyMap(obj))
 		}
 	}
-
-	return BuildRepresentations(rows, relPath, l.cfg)
+	return rows, nil
 }
 
 func stringifyMap(in map[string]interface{}) map[string]string {
@@ -0,0 +1,291 @@
+package tabular
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+)
+
+// Formats of lakehouse table directories.
+const (
+	FormatDelta   = "delta"
+	FormatIceberg = "iceberg"
+)
+
+// LakeTable is the Delta Lake or Iceberg table directory a file is part
+// of, see FindLakeTable.
+type LakeTable struct {
+	Format  string // FormatDelta or FormatIceberg
+	Path    string // of the directory, relative like the file's
+	AbsPath string
+	// Current is set for the file that stands for the table: the latest
+	// commit of a Delta log, or the current Iceberg metadata file. The
+	// table is loaded with it, and its other files, data files included,
+	// are indexed as part of the table only.
+	Current bool
+}
+
+// FindLakeTable returns the Delta Lake or Iceberg table the file at relPath,
+// absPath on disk, lies in: the nearest directory above it holding a
+// _delta_log directory, or a metadata directory with Iceberg metadata
+// files. The root of relPath is never a table.
+func FindLakeTable(relPath, absPath string) (LakeTable, bool) {
+	rel, abs := filepath.Clean(relPath), filepath.Clean(absPath)
+	root, ok := strings.CutSuffix(abs, rel)
+	if !ok || filepath.IsAbs(rel) || (root != "" && !os.IsPathSeparator(root[len(root)-1])) {
+		return LakeTable{}, false
+	}
+	for dir := filepath.Dir(rel); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		absDir := filepath.Join(root, dir)
+		format := lakeFormat(absDir)
+		if format == "" {
+			continue
+		}
+		t := LakeTable{Format: format, Path: dir, AbsPath: absDir}
+		if current, err := currentLakeFile(format, absDir); err == nil {
+			t.Current = filepath.Join(absDir, current) == abs
+		}
+		return t, true
+	}
+	return LakeTable{}, false
+}
+
+// lakeFormat returns the format of the table directory dir, or "" if it
+// is not one.
+func lakeFormat(dir string) string {
+	if isDir(filepath.Join(dir, "_delta_log")) {
+		return FormatDelta
+	}
+	if isDir(filepath.Join(dir, "metadata")) {
+		if _, err := currentIcebergMetadata(dir); err == nil {
+			return FormatIceberg
+		}
+	}
+	return ""
+}
+
+func isDir(p string) bool {
+	fi, err := os.Stat(p)
+	return err == nil && fi.IsDir()
+}
+
+// currentLakeFile returns the path, relative to the table directory dir,
+// of the file that stands for the table.
+func currentLakeFile(format, dir string) (string, error) {
+	if format == FormatIceberg {
+		name, err := currentIcebergMetadata(dir)
+		return filepath.Join("metadata", name), err
+	}
+	log, err := readDeltaLog(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(log.commits) > 0 {
+		return filepath.Join("_delta_log", log.commits[log.latest]), nil
+	}
+	return filepath.Join("_delta_log", "_last_checkpoint"), nil
+}
+
+// LakeTableLoader reads Delta Lake and Iceberg table directories, see
+// FindLakeTable, as one dataset: the rows of the data files of the current
+// snapshot, sampled across them, with the columns and their kinds of the
+// table schema. Files removed from the table by later commits, and the
+// Delta and Iceberg logs, are not read. Every chunk carries the table
+// format, the snapshot and the partition columns in its metadata, and rows
+// the data file they come from.
+type LakeTableLoader struct {
+	cfg config.TabularConfig
+}
+
+func NewLakeTableLoader(cfg config.TabularConfig) *LakeTableLoader {
+	return &LakeTableLoader{cfg: cfg}
+}
+
+// Extensions returns none: tables are directories, found by FindLakeTable.
+func (l *LakeTableLoader) Extensions() []string { return nil }
+
+// Load loads the table directory at absPath, relPath being its path.
+func (l *LakeTableLoader) Load(ctx context.Context, relPath string, absPath string) ([]ingest.Representation, error) {
+	slog.Info("Loading lakehouse table", "relPath", relPath)
+	snap, err := readLakeSnapshot(absPath)
+	if err != nil {
+		return nil, err
+	}
+	rows, from, err := snap.readRows(l.cfg, readLimit(l.cfg), true)
+	if err != nil {
+		return nil, err
+	}
+	reps, err := BuildRepresentationsWithSchema(rows, snap.schema, relPath, l.cfg)
+	if err != nil {
+		return nil, err
+	}
+	partitions := strings.Join(snap.partitions, ",")
+	for i, rep := range reps {
+		rep.Meta["table_format"] = snap.format
+		rep.Meta["snapshot"] = snap.version
+		if partitions != "" {
+			rep.Meta["partitions"] = partitions
+		}
+		switch {
+		case rep.Modality == "table_row":
+			idx, _ := strconv.Atoi(rep.Meta["row"])
+			rep.Meta["data_file"] = from[idx]
+		case rep.Meta["kind"] == "file_summary":
+			prefix := "Tabular file " + relPath + " with " + strconv.Itoa(len(rows)) + " rows."
+			reps[i].Text = strings.Replace(rep.Text, prefix, snap.describe(relPath, len(rows)), 1)
+		}
+	}
+	return reps, nil
+}
+
+// lakeSnapshot is the current snapshot of a table.
+type lakeSnapshot struct {
+	format     string
+	version    string // Delta version or Iceberg snapshot ID
+	schema     []Column
+	partitions []string // partition columns, or fields for Iceberg
+	files      []lakeFile
+	rows       int64 // of the table, -1 if unknown
+}
+
+// lakeFile is a data file of a snapshot.
+type lakeFile struct {
+	path   string // on disk
+	rel    string // as the table names it
+	format string // "parquet", "orc" or "avro"
+	// partition holds the values of partition columns, which Delta tables
+	// do not store in data files.
+	partition map[string]string
+}
+
+// readLakeSnapshot reads the current snapshot of the table directory dir.
+func readLakeSnapshot(dir string) (*lakeSnapshot, error) {
+	switch lakeFormat(dir) {
+	case FormatDelta:
+		return readDelta(dir)
+	case FormatIceberg:
+		return readIceberg(dir)
+	}
+	return nil, fmt.Errorf("%s is not a Delta Lake or Iceberg table", dir)
+}
+
+// describe writes the first sentence of the summary of the table, which
+// n rows were read of.
+func (s *lakeSnapshot) describe(relPath string, n int) string {
+	name, version := "Delta Lake", "version"
+	if s.format == FormatIceberg {
+		name, version = "Iceberg", "snapshot"
+	}
+	text := name + " table " + relPath + ", " + version + " " + s.version + ", with " + strconv.Itoa(len(s.files)) + " data files"
+	if s.rows >= 0 {
+		text += " and " + strconv.FormatInt(s.rows, 10) + " rows"
+	}
+	text += ", " + strconv.Itoa(n) + " of them read."
+	if len(s.partitions) > 0 {
+		text += " Partitioned by " + strings.Join(s.partitions, ", ") + "."
+	}
+	return text
+}
+
+// readRows reads up to limit rows of the data files, with the partition
+// values they do not store, and the data file each comes from. With spread,
+// the rows are shared between the files, which the first ones otherwise
+// fill. Columns of kind binary are left out, and missing data files are
+// skipped.
+func (s *lakeSnapshot) readRows(cfg config.TabularConfig, limit int, spread bool) ([]map[string]string, []string, error) {
+	var (
+		rows []map[string]string
+		from []string
+	)
+	for i, f := range s.files {
+		left := limit - len(rows)
+		if left <= 0 {
+			break
+		}
+		if spread {
+			left = max(left/(len(s.files)-i), 1)
+		}
+		fileRows, err := readDataFile(f, cfg, left)
+		if errors.Is(err, os.ErrNotExist) {
+			slog.Warn("Skipping missing data file of lakehouse table", "file", f.rel)
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("data file %s: %w", f.rel, err)
+		}
+		for _, row := range fileRows {
+			for k, v := range f.partition {
+				if _, ok := row[k]; !ok {
+					row[k] = v
+				}
+			}
+			for _, c := range s.schema {
+				if c.Kind == KindBinary {
+					delete(row, c.Name)
+				}
+			}
+			rows = append(rows, row)
+			from = append(from, f.rel)
+		}
+	}
+	return rows, from, nil
+}
+
+func readDataFile(f lakeFile, cfg config.TabularConfig, limit int) ([]map[string]string, error) {
+	if _, err := os.Stat(f.path); err != nil {
+		return nil, err
+	}
+	switch f.format {
+	case "parquet":
+		return NewParquetLoader(cfg).readRows(f.path, limit)
+	case "orc":
+		return NewORCLoader(cfg).readRows(f.path, limit)
+	case "avro":
+		return NewAvroLoader(cfg).readRows(f.path, limit)
+	}
+	return nil, fmt.Errorf("unsupported data file format %q", f.format)
+}
+
+// lakePath returns where the data file p of the table directory dir is on
+// disk. Paths under location, the URI the table was written at, such as
+// s3://bucket/db/events, are taken as relative to dir, so that copies of
+// tables can be read.
+func lakePath(dir, location, p string) string {
+	location = strings.TrimSuffix(location, "/")
+	if rest, ok := strings.CutPrefix(p, location+"/"); ok && location != "" {
+		return filepath.Join(dir, filepath.FromSlash(rest))
+	}
+	if u, err := url.Parse(p); err == nil && u.Scheme == "file" {
+		return filepath.FromSlash(u.Path)
+	}
+	if strings.Contains(p, "://") || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(dir, filepath.FromSlash(p))
+}
+
+// lakeKind returns the kind of a column of the primitive Delta or Iceberg
+// type t, e.g. "decimal(10,2)"; nested types are text.
+func lakeKind(t string) ColumnKind {
+	switch {
+	case t == "long" || t == "integer" || t == "int" || t == "short" || t == "byte" ||
+		t == "float" || t == "double" || strings.HasPrefix(t, "decimal"):
+		return KindNumeric
+	case t == "date" || t == "time" || strings.HasPrefix(t, "timestamp"):
+		return KindDateTime
+	case t == "boolean":
+		return KindCategorical
+	case t == "binary" || strings.HasPrefix(t, "fixed"):
+		return KindBinary
+	}
+	return KindText
+}
@@ -0,0 +1,173 @@
+package tabular
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// readODS reads the sheets of the OpenDocument spreadsheet at absPath, up
+// to limit rows each unless it is 0. Numbers, dates and booleans are read
+// as their values, e.g. "1200" for a cell showing "1,200.00", and the other
+// cells as their text.
+func readODS(absPath string, limit int) ([]*sheet, error) {
+	zr, err := zip.OpenReader(absPath)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	var content *zip.File
+	for _, f := range zr.File {
+		if f.Name == "content.xml" {
+			content = f
+		}
+	}
+	if content == nil {
+		return nil, fmt.Errorf("not an OpenDocument file: no content.xml")
+	}
+	rc, err := content.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var (
+		sheets     []*sheet
+		sh         *sheet
+		n          int      // rows of the sheet so far
+		cells      []string // of the current row
+		blanks     int      // empty cells not yet added to cells
+		rowRepeat  int
+		cellRepeat int
+		inCell     bool
+		inNote     bool // in a cell annotation, left out
+		paragraphs int
+		value      string // of a typed cell
+		text       strings.Builder
+	)
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "table":
+				sh = &sheet{name: odsAttr(t, "name")}
+				sheets = append(sheets, sh)
+				n = 0
+			case "table-row":
+				cells, blanks = nil, 0
+				rowRepeat = odsRepeat(t, "number-rows-repeated")
+			case "table-cell", "covered-table-cell":
+				inCell, paragraphs = true, 0
+				text.Reset()
+				cellRepeat = odsRepeat(t, "number-columns-repeated")
+				value = odsValue(t)
+			case "annotation":
+				inNote = true
+			case "p", "h":
+				if inCell && !inNote {
+					if paragraphs > 0 {
+						text.WriteByte('\n')
+					}
+					paragraphs++
+				}
+			case "s":
+				if inCell && !inNote {
+					text.WriteString(strings.Repeat(" ", odsRepeat(t, "c")))
+				}
+			case "tab":
+				if inCell && !inNote {
+					text.WriteByte('\t')
+				}
+			case "line-break":
+				if inCell && !inNote {
+					text.WriteByte('\n')
+				}
+			}
+		case xml.CharData:
+			if inCell && !inNote {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "annotation":
+				inNote = false
+			case "table-cell", "covered-table-cell":
+				inCell = false
+				v := value
+				if v == "" {
+					v = text.String()
+				}
+				if v == "" {
+					blanks += cellRepeat
+					break
+				}
+				// Blank cells only count when a value follows them.
+				for ; blanks > 0 && len(cells) < maxSheetColumns; blanks-- {
+					cells = append(cells, "")
+				}
+				for k := 0; k < cellRepeat && len(cells) < maxSheetColumns; k++ {
+					cells = append(cells, v)
+				}
+			case "table-row":
+				if sh == nil {
+					break
+				}
+				if len(cells) == 0 || sh.full(limit) {
+					n += rowRepeat
+					break
+				}
+				for k := 0; k < rowRepeat && !sh.full(limit); k++ {
+					n++
+					sh.add(n, cells)
+				}
+			}
+		}
+	}
+	return sheets, nil
+}
+
+// odsAttr returns the attribute of e with the local name, whatever its
+// namespace.
+func odsAttr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// odsRepeat returns the count of the repeat attribute of e, 1 without one.
+func odsRepeat(e xml.StartElement, name string) int {
+	n, err := strconv.Atoi(odsAttr(e, name))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// odsValue returns the value of a typed cell, or "" for text cells.
+func odsValue(e xml.StartElement) string {
+	switch odsAttr(e, "value-type") {
+	case "float", "percentage", "currency":
+		return odsAttr(e, "value")
+	case "date":
+		return odsAttr(e, "date-value")
+	case "time":
+		return odsAttr(e, "time-value")
+	case "boolean":
+		return strings.ToUpper(odsAttr(e, "boolean-value"))
+	}
+	return ""
+}
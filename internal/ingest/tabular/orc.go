@@ -0,0 +1,615 @@
+package tabular
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// A reader of Apache ORC files, see https://orc.apache.org/specification/ORCv1/.
+
+// Compression kinds of the postscript.
+const (
+	orcNone   = 0
+	orcZlib   = 1
+	orcSnappy = 2
+	orcZstd   = 5
+)
+
+// Type kinds of the footer.
+const (
+	orcBoolean = iota
+	orcByte
+	orcShort
+	orcInt
+	orcLong
+	orcFloat
+	orcDouble
+	orcString
+	orcBinary
+	orcTimestamp
+	orcList
+	orcMap
+	orcStruct
+	orcUnion
+	orcDecimal
+	orcDate
+	orcVarchar
+	orcChar
+	orcTimestampInstant
+)
+
+// Stream kinds of stripe footers.
+const (
+	orcPresent        = 0
+	orcData           = 1
+	orcLength         = 2
+	orcDictionaryData = 3
+	orcSecondary      = 5
+)
+
+// Column encodings of stripe footers; the V2 ones use integer RLE v2.
+const (
+	orcDirect       = 0
+	orcDictionary   = 1
+	orcDirectV2     = 2
+	orcDictionaryV2 = 3
+)
+
+// maxORCTail bounds the postscript and footer of a file.
+const maxORCTail = 16 << 20
+
+var errCorruptORC = errors.New("corrupt ORC file")
+
+// orcEpoch is what the seconds of timestamps count from.
+var orcEpoch = time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+type orcType struct {
+	kind     int
+	subtypes []int
+	names    []string
+	scale    int
+}
+
+type orcStripe struct {
+	offset, indexLength, dataLength, footerLength, rows uint64
+}
+
+// orcEncoding is the encoding of a column in a stripe.
+type orcEncoding struct {
+	kind           int
+	dictionarySize int
+}
+
+// orcFile is an open ORC file.
+type orcFile struct {
+	f           *os.File
+	size        uint64
+	compression int
+	blockSize   int
+	types       []orcType
+	stripes     []orcStripe
+	zstd        *zstd.Decoder
+}
+
+// orcColumn is a top-level column of an ORC file.
+type orcColumn struct {
+	Column
+	id int // of its type
+}
+
+// readORC reads up to limit rows of the ORC file at absPath, and the
+// columns its footer declares. Binary columns are declared but left out of
+// the rows, and nested ones (lists, maps, structs and unions) left out.
+func readORC(absPath string, limit int) ([]map[string]string, []Column, error) {
+	f, err := openORC(absPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.close()
+	cols, err := f.columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	schema := make([]Column, len(cols))
+	for i, c := range cols {
+		schema[i] = c.Column
+	}
+
+	var rows []map[string]string
+	for _, s := range f.stripes {
+		n := int(min(s.rows, uint64(limit-len(rows))))
+		if n <= 0 {
+			break
+		}
+		values, err := f.readStripe(s, cols, n)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := 0; i < n; i++ {
+			row := make(map[string]string, len(cols))
+			for j, c := range cols {
+				if c.Kind != KindBinary {
+					row[c.Name] = values[j][i]
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows, schema, nil
+}
+
+func openORC(absPath string) (*orcFile, error) {
+	file, err := os.Open(absPath)
+	if err != nil {
+		return nil, err
+	}
+	f := &orcFile{f: file, blockSize: 256 << 10}
+	if err := f.readTail(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *orcFile) close() {
+	if f.zstd != nil {
+		f.zstd.Close()
+	}
+	f.f.Close()
+}
+
+// readTail reads the postscript and footer at the end of the file.
+func (f *orcFile) readTail() error {
+	st, err := f.f.Stat()
+	if err != nil {
+		return err
+	}
+	size := st.Size()
+	f.size = uint64(size)
+	if size < 4 {
+		return errCorruptORC
+	}
+	last := make([]byte, min(size, 256))
+	if _, err := f.f.ReadAt(last, size-int64(len(last))); err != nil {
+		return err
+	}
+	psLen := int(last[len(last)-1])
+	if psLen+1 > len(last) {
+		return errCorruptORC
+	}
+	var footerLen uint64
+	err = protoFields(last[len(last)-1-psLen:len(last)-1], func(num protowire.Number, v uint64, data []byte) {
+		switch num {
+		case 1:
+			footerLen = v
+		case 2:
+			f.compression = int(v)
+		case 3:
+			f.blockSize = int(v)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("%w: postscript: %v", errCorruptORC, err)
+	}
+	switch f.compression {
+	case orcNone, orcZlib, orcSnappy:
+	case orcZstd:
+		if f.zstd, err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1)); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported ORC compression %d: only zlib, snappy and zstd are read", f.compression)
+	}
+	if footerLen > maxORCTail || int64(footerLen)+int64(psLen)+1 > size {
+		return errCorruptORC
+	}
+	footer, err := f.read(uint64(size)-uint64(psLen)-1-footerLen, footerLen)
+	if err != nil {
+		return err
+	}
+	perr := protoFields(footer, func(num protowire.Number, v uint64, data []byte) {
+		switch num {
+		case 3:
+			var s orcStripe
+			err = errors.Join(err, protoFields(data, func(num protowire.Number, v uint64, _ []byte) {
+				switch num {
+				case 1:
+					s.offset = v
+				case 2:
+					s.indexLength = v
+				case 3:
+					s.dataLength = v
+				case 4:
+					s.footerLength = v
+				case 5:
+					s.rows = v
+				}
+			}))
+			f.stripes = append(f.stripes, s)
+		case 4:
+			var t orcType
+			err = errors.Join(err, protoFields(data, func(num protowire.Number, v uint64, data []byte) {
+				switch num {
+				case 1:
+					t.kind = int(v)
+				case 2:
+					for _, id := range protoVarints(v, data) {
+						t.subtypes = append(t.subtypes, int(id))
+					}
+				case 3:
+					t.names = append(t.names, string(data))
+				case 6:
+					t.scale = int(v)
+				}
+			}))
+			f.types = append(f.types, t)
+		}
+	})
+	if err = errors.Join(perr, err); err != nil {
+		return fmt.Errorf("%w: footer: %v", errCorruptORC, err)
+	}
+	return nil
+}
+
+// read reads and decompresses length bytes at offset.
+func (f *orcFile) read(offset, length uint64) ([]byte, error) {
+	if offset > f.size || length > f.size-offset {
+		return nil, errCorruptORC
+	}
+	b := make([]byte, length)
+	if _, err := f.f.ReadAt(b, int64(offset)); err != nil {
+		return nil, err
+	}
+	return f.decompress(b)
+}
+
+// decompress decompresses a stream, a series of chunks each with a 3-byte
+// header giving its length and whether it is stored uncompressed.
+func (f *orcFile) decompress(b []byte) ([]byte, error) {
+	if f.compression == orcNone {
+		return b, nil
+	}
+	var out []byte
+	for len(b) > 0 {
+		if len(b) < 3 {
+			return nil, errCorruptORC
+		}
+		header := int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+		n := header >> 1
+		if n > len(b)-3 {
+			return nil, errCorruptORC
+		}
+		chunk := b[3 : 3+n]
+		b = b[3+n:]
+		if header&1 == 1 {
+			out = append(out, chunk...)
+			continue
+		}
+		var d []byte
+		var err error
+		switch f.compression {
+		case orcZlib:
+			d, err = io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(chunk)), int64(f.blockSize)+1))
+		case orcSnappy:
+			if size, _ := snappy.DecodedLen(chunk); size > f.blockSize {
+				return nil, errCorruptORC
+			}
+			d, err = snappy.Decode(nil, chunk)
+		case orcZstd:
+			d, err = f.zstd.DecodeAll(chunk, nil)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errCorruptORC, err)
+		}
+		if len(d) > f.blockSize {
+			return nil, fmt.Errorf("%w: chunk larger than the compression block size", errCorruptORC)
+		}
+		out = append(out, d...)
+	}
+	return out, nil
+}
+
+// columns returns the top-level columns of the file, but for nested ones.
+func (f *orcFile) columns() ([]orcColumn, error) {
+	if len(f.types) == 0 || f.types[0].kind != orcStruct || len(f.types[0].names) != len(f.types[0].subtypes) {
+		return nil, fmt.Errorf("%w: the root type is not a struct", errCorruptORC)
+	}
+	var cols []orcColumn
+	for i, id := range f.types[0].subtypes {
+		if id <= 0 || id >= len(f.types) {
+			return nil, errCorruptORC
+		}
+		var kind ColumnKind
+		switch f.types[id].kind {
+		case orcByte, orcShort, orcInt, orcLong, orcFloat, orcDouble, orcDecimal:
+			kind = KindNumeric
+		case orcDate, orcTimestamp, orcTimestampInstant:
+			kind = KindDateTime
+		case orcBoolean:
+			kind = KindCategorical
+		case orcString, orcVarchar, orcChar:
+			kind = KindText
+		case orcBinary:
+			kind = KindBinary
+		default:
+			continue
+		}
+		cols = append(cols, orcColumn{Column: Column{Name: f.types[0].names[i], Kind: kind}, id: id})
+	}
+	return cols, nil
+}
+
+// readStripe reads the values of the first n rows of the stripe for cols,
+// a slice of n values a column, "" for nulls.
+func (f *orcFile) readStripe(s orcStripe, cols []orcColumn, n int) ([][]string, error) {
+	footer, err := f.read(s.offset+s.indexLength+s.dataLength, s.footerLength)
+	if err != nil {
+		return nil, err
+	}
+	type stream struct{ kind, column int }
+	type extent struct{ offset, length uint64 }
+	streams := map[stream]extent{}
+	var encodings []orcEncoding
+	offset := s.offset
+	err = protoFields(footer, func(num protowire.Number, v uint64, data []byte) {
+		switch num {
+		case 1:
+			var st stream
+			var length uint64
+			err = errors.Join(err, protoFields(data, func(num protowire.Number, v uint64, _ []byte) {
+				switch num {
+				case 1:
+					st.kind = int(v)
+				case 2:
+					st.column = int(v)
+				case 3:
+					length = v
+				}
+			}))
+			streams[st] = extent{offset, length}
+			offset += length
+		case 2:
+			var enc orcEncoding
+			err = errors.Join(err, protoFields(data, func(num protowire.Number, v uint64, _ []byte) {
+				switch num {
+				case 1:
+					enc.kind = int(v)
+				case 2:
+					enc.dictionarySize = int(min(v, math.MaxInt32))
+				}
+			}))
+			encodings = append(encodings, enc)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: stripe footer: %v", errCorruptORC, err)
+	}
+	values := make([][]string, len(cols))
+	for i, c := range cols {
+		if c.Kind == KindBinary {
+			continue
+		}
+		read := func(kind int) ([]byte, error) {
+			e, ok := streams[stream{kind, c.id}]
+			if !ok {
+				return nil, nil
+			}
+			return f.read(e.offset, e.length)
+		}
+		var enc orcEncoding
+		if c.id < len(encodings) {
+			enc = encodings[c.id]
+		}
+		if values[i], err = f.columnValues(f.types[c.id], enc, read, n); err != nil {
+			return nil, fmt.Errorf("column %s: %w", c.Name, err)
+		}
+	}
+	return values, nil
+}
+
+// columnValues decodes the first n values of a column of type t, reading
+// its streams with read.
+func (f *orcFile) columnValues(t orcType, enc orcEncoding, read func(kind int) ([]byte, error), n int) ([]string, error) {
+	present, err := read(orcPresent)
+	if err != nil {
+		return nil, err
+	}
+	var isSet []bool
+	m := n // values present
+	if present != nil {
+		if isSet, err = decodeBools(present, n); err != nil {
+			return nil, err
+		}
+		m = 0
+		for _, ok := range isSet {
+			if ok {
+				m++
+			}
+		}
+	}
+	data, err := read(orcData)
+	if err != nil {
+		return nil, err
+	}
+	v2 := enc.kind == orcDirectV2 || enc.kind == orcDictionaryV2
+
+	var vals []string
+	switch t.kind {
+	case orcBoolean:
+		bools, err := decodeBools(data, m)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range bools {
+			vals = append(vals, strconv.FormatBool(b))
+		}
+	case orcByte:
+		bs, err := decodeByteRLE(data, m)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range bs {
+			vals = append(vals, strconv.Itoa(int(int8(b))))
+		}
+	case orcShort, orcInt, orcLong, orcDate:
+		ints, err := decodeInts(data, true, v2, m)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range ints {
+			if t.kind == orcDate {
+				vals = append(vals, time.Unix(v*86400, 0).UTC().Format("2006-01-02"))
+			} else {
+				vals = append(vals, strconv.FormatInt(v, 10))
+			}
+		}
+	case orcFloat, orcDouble:
+		size := 8
+		if t.kind == orcFloat {
+			size = 4
+		}
+		if len(data) < m*size {
+			return nil, errCorruptORC
+		}
+		for i := 0; i < m; i++ {
+			if size == 4 {
+				bits := uint32(data[i*4]) | uint32(data[i*4+1])<<8 | uint32(data[i*4+2])<<16 | uint32(data[i*4+3])<<24
+				vals = append(vals, strconv.FormatFloat(float64(math.Float32frombits(bits)), 'f', -1, 32))
+				continue
+			}
+			var bits uint64
+			for k := 7; k >= 0; k-- {
+				bits = bits<<8 | uint64(data[i*8+k])
+			}
+			vals = append(vals, strconv.FormatFloat(math.Float64frombits(bits), 'f', -1, 64))
+		}
+	case orcString, orcVarchar, orcChar:
+		lengthData, err := read(orcLength)
+		if err != nil {
+			return nil, err
+		}
+		if enc.kind == orcDictionary || enc.kind == orcDictionaryV2 {
+			dict, err := read(orcDictionaryData)
+			if err != nil {
+				return nil, err
+			}
+			idx, err := decodeInts(data, false, v2, m)
+			if err != nil {
+				return nil, err
+			}
+			lengths, err := decodeInts(lengthData, false, v2, enc.dictionarySize)
+			if err != nil {
+				return nil, err
+			}
+			entries, err := splitStrings(dict, lengths)
+			if err != nil {
+				return nil, err
+			}
+			for _, i := range idx {
+				if i < 0 || int(i) >= len(entries) {
+					return nil, errCorruptORC
+				}
+				vals = append(vals, entries[i])
+			}
+			break
+		}
+		lengths, err := decodeInts(lengthData, false, v2, m)
+		if err != nil {
+			return nil, err
+		}
+		if vals, err = splitStrings(data, lengths); err != nil {
+			return nil, err
+		}
+	case orcTimestamp, orcTimestampInstant:
+		nanoData, err := read(orcSecondary)
+		if err != nil {
+			return nil, err
+		}
+		secs, err := decodeInts(data, true, v2, m)
+		if err != nil {
+			return nil, err
+		}
+		nanos, err := decodeInts(nanoData, false, v2, m)
+		if err != nil {
+			return nil, err
+		}
+		for i, s := range secs {
+			ns := nanos[i] >> 3
+			if z := nanos[i] & 7; z != 0 {
+				ns *= int64(math.Pow10(int(z) + 1))
+			}
+			vals = append(vals, time.Unix(orcEpoch+s, ns).UTC().Format(time.RFC3339Nano))
+		}
+	case orcDecimal:
+		scaleData, err := read(orcSecondary)
+		if err != nil {
+			return nil, err
+		}
+		scales, err := decodeInts(scaleData, true, v2, m)
+		if err != nil {
+			return nil, err
+		}
+		r := &byteReader{b: data}
+		for i := 0; i < m; i++ {
+			v, err := r.bigVarint()
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, formatDecimal(v, int(scales[i])))
+		}
+	}
+
+	if isSet == nil {
+		return vals, nil
+	}
+	out := make([]string, n)
+	j := 0
+	for i, ok := range isSet {
+		if ok && j < len(vals) {
+			out[i] = vals[j]
+			j++
+		}
+	}
+	return out, nil
+}
+
+// splitStrings cuts strings of the given lengths out of data.
+func splitStrings(data []byte, lengths []int64) ([]string, error) {
+	out := make([]string, 0, len(lengths))
+	for _, l := range lengths {
+		if l < 0 || l > int64(len(data)) {
+			return nil, errCorruptORC
+		}
+		out = append(out, string(data[:l]))
+		data = data[l:]
+	}
+	return out, nil
+}
+
+// formatDecimal writes the unscaled value v with scale decimals.
+func formatDecimal(v *big.Int, scale int) string {
+	s := new(big.Int).Abs(v).String()
+	if scale > 0 {
+		if len(s) <= scale {
+			s = strings.Repeat("0", scale-len(s)+1) + s
+		}
+		s = s[:len(s)-scale] + "." + s[len(s)-scale:]
+	}
+	if v.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
@@ -0,0 +1,37 @@
+package tabular
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+)
+
+// ORCLoader reads Apache ORC files (.orc), uncompressed or compressed with
+// zlib, snappy or zstd. The columns and their kinds come from the types in
+// the file footer rather than from the values; binary columns are listed
+// in the schema but left out of rows, and nested ones (lists, maps, structs
+// and unions) left out.
+type ORCLoader struct {
+	cfg config.TabularConfig
+}
+
+func NewORCLoader(cfg config.TabularConfig) *ORCLoader { return &ORCLoader{cfg: cfg} }
+
+func (l *ORCLoader) Extensions() []string { return []string{".orc"} }
+
+func (l *ORCLoader) Load(ctx context.Context, relPath string, absPath string) ([]ingest.Representation, error) {
+	slog.Info("Loading ORC file", "relPath", relPath)
+	rows, schema, err := readORC(absPath, readLimit(l.cfg))
+	if err != nil {
+		return nil, err
+	}
+	return BuildRepresentationsWithSchema(rows, schema, relPath, l.cfg)
+}
+
+// readRows reads up to limit rows of the file.
+func (l *ORCLoader) readRows(absPath string, limit int) ([]map[string]string, error) {
+	rows, _, err := readORC(absPath, limit)
+	return rows, err
+}
@@ -0,0 +1,427 @@
+package tabular
+
+import (
+	"fmt"
+	"math/big"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The run-length encodings of ORC streams.
+
+// byteReader reads the bytes of a stream.
+type byteReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *byteReader) byte() (byte, error) {
+	if r.pos >= len(r.b) {
+		return 0, fmt.Errorf("%w: stream ends early", errCorruptORC)
+	}
+	r.pos++
+	return r.b[r.pos-1], nil
+}
+
+// uvarint reads a base 128 varint.
+func (r *byteReader) uvarint() (uint64, error) {
+	var v uint64
+	for shift := 0; shift < 64; shift += 7 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint64(b&0x7F) << shift
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: varint too long", errCorruptORC)
+}
+
+// varint reads a zigzag-encoded signed varint.
+func (r *byteReader) varint() (int64, error) {
+	v, err := r.uvarint()
+	return unzigzag(v), err
+}
+
+// bigVarint reads a zigzag-encoded signed varint of any size, the unscaled
+// value of a decimal.
+func (r *byteReader) bigVarint() (*big.Int, error) {
+	v := new(big.Int)
+	for shift := uint(0); ; shift += 7 {
+		if shift > 1024 {
+			return nil, fmt.Errorf("%w: decimal too long", errCorruptORC)
+		}
+		b, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		v.Or(v, new(big.Int).Lsh(big.NewInt(int64(b&0x7F)), shift))
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	negative := v.Bit(0) == 1
+	v.Rsh(v, 1)
+	if negative {
+		v.Neg(v).Sub(v, big.NewInt(1))
+	}
+	return v, nil
+}
+
+// bigEndian reads an n-byte big-endian integer.
+func (r *byteReader) bigEndian(n int) (uint64, error) {
+	var v uint64
+	for i := 0; i < n; i++ {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+// bits reads n integers of width bits each, packed from the most
+// significant bit and starting on a byte.
+func (r *byteReader) bits(n, width int) ([]uint64, error) {
+	out := make([]uint64, n)
+	if width == 0 {
+		return out, nil
+	}
+	var cur byte
+	left := 0 // bits of cur not read yet
+	for i := range out {
+		var v uint64
+		for need := width; need > 0; {
+			if left == 0 {
+				b, err := r.byte()
+				if err != nil {
+					return nil, err
+				}
+				cur, left = b, 8
+			}
+			take := min(need, left)
+			v = v<<take | uint64(cur>>(left-take))&(1<<take-1)
+			left -= take
+			need -= take
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// decodeByteRLE decodes n bytes: runs of 3 to 130 copies of a byte, and
+// series of up to 128 bytes.
+func decodeByteRLE(data []byte, n int) ([]byte, error) {
+	r := &byteReader{b: data}
+	out := make([]byte, 0, n)
+	for len(out) < n {
+		h, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if int8(h) >= 0 {
+			b, err := r.byte()
+			if err != nil {
+				return nil, err
+			}
+			for i := 0; i < int(h)+3; i++ {
+				out = append(out, b)
+			}
+			continue
+		}
+		for i := 0; i < -int(int8(h)); i++ {
+			b, err := r.byte()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, b)
+		}
+	}
+	return out[:n], nil
+}
+
+// decodeBools decodes n booleans, bits of byte run-length encoded bytes.
+func decodeBools(data []byte, n int) ([]bool, error) {
+	bs, err := decodeByteRLE(data, (n+7)/8)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]bool, n)
+	for i := range out {
+		out[i] = bs[i/8]&(0x80>>(i%8)) != 0
+	}
+	return out, nil
+}
+
+// decodeInts decodes n integers with integer RLE v2, or v1 unless v2.
+func decodeInts(data []byte, signed, v2 bool, n int) ([]int64, error) {
+	r := &byteReader{b: data}
+	out := make([]int64, 0, n)
+	for len(out) < n {
+		var err error
+		if v2 {
+			out, err = decodeRunV2(r, signed, out)
+		} else {
+			out, err = decodeRunV1(r, signed, out)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out[:n], nil
+}
+
+// decodeRunV1 appends a run of integer RLE v1 to out: 3 to 130 values
+// growing by a fixed delta, or up to 128 varints.
+func decodeRunV1(r *byteReader, signed bool, out []int64) ([]int64, error) {
+	read := func() (int64, error) {
+		if signed {
+			return r.varint()
+		}
+		v, err := r.uvarint()
+		return int64(v), err
+	}
+	h, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	if int8(h) < 0 {
+		for i := 0; i < -int(int8(h)); i++ {
+			v, err := read()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	}
+	delta, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	base, err := read()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < int(h)+3; i++ {
+		out = append(out, base+int64(i)*int64(int8(delta)))
+	}
+	return out, nil
+}
+
+// Sub-encodings of integer RLE v2, the top two bits of a run's header.
+const (
+	rleShortRepeat = iota
+	rleDirect
+	rlePatchedBase
+	rleDelta
+)
+
+// rleWidths maps the 5-bit width codes of integer RLE v2 to bit widths.
+var rleWidths = [32]int{
+	1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16,
+	17, 18, 19, 20, 21, 22, 23, 24, 26, 28, 30, 32, 40, 48, 56, 64,
+}
+
+// closestWidth rounds a bit width up to one rleWidths has.
+func closestWidth(n int) int {
+	for _, w := range rleWidths {
+		if w >= n {
+			return w
+		}
+	}
+	return 64
+}
+
+// decodeRunV2 appends a run of integer RLE v2 to out.
+func decodeRunV2(r *byteReader, signed bool, out []int64) ([]int64, error) {
+	h, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	fix := func(v uint64) int64 {
+		if signed {
+			return unzigzag(v)
+		}
+		return int64(v)
+	}
+
+	if h>>6 == rleShortRepeat {
+		v, err := r.bigEndian(int(h>>3&7) + 1)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < int(h&7)+3; i++ {
+			out = append(out, fix(v))
+		}
+		return out, nil
+	}
+
+	h2, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	width := rleWidths[h>>1&0x1F]
+	length := (int(h&1)<<8 | int(h2)) + 1
+
+	switch h >> 6 {
+	case rleDirect:
+		vals, err := r.bits(length, width)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vals {
+			out = append(out, fix(v))
+		}
+
+	case rlePatchedBase:
+		h3, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		h4, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		baseBytes := int(h3>>5) + 1
+		patchWidth := rleWidths[h3&0x1F]
+		gapWidth := int(h4>>5) + 1
+		patches := int(h4 & 0x1F)
+		b, err := r.bigEndian(baseBytes)
+		if err != nil {
+			return nil, err
+		}
+		// The top bit of the base is its sign.
+		signBit := uint64(1) << (8*baseBytes - 1)
+		base := int64(b &^ signBit)
+		if b&signBit != 0 {
+			base = -base
+		}
+		vals, err := r.bits(length, width)
+		if err != nil {
+			return nil, err
+		}
+		list, err := r.bits(patches, closestWidth(patchWidth+gapWidth))
+		if err != nil {
+			return nil, err
+		}
+		pos := 0
+		for _, p := range list {
+			pos += int(p >> patchWidth)
+			patch := p & (1<<patchWidth - 1)
+			if patch == 0 {
+				continue // a gap of 255 or more, carried over to the next patch
+			}
+			if pos >= length {
+				return nil, fmt.Errorf("%w: patch out of range", errCorruptORC)
+			}
+			vals[pos] |= patch << width
+		}
+		for _, v := range vals {
+			out = append(out, base+int64(v))
+		}
+
+	case rleDelta:
+		if h>>1&0x1F == 0 {
+			width = 0 // a fixed delta
+		}
+		var first int64
+		if signed {
+			first, err = r.varint()
+		} else {
+			var v uint64
+			v, err = r.uvarint()
+			first = int64(v)
+		}
+		if err != nil {
+			return nil, err
+		}
+		delta, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, first)
+		if width == 0 {
+			for i := 1; i < length; i++ {
+				out = append(out, first+int64(i)*delta)
+			}
+			return out, nil
+		}
+		if length < 2 {
+			return out, nil
+		}
+		prev := first + delta
+		out = append(out, prev)
+		deltas, err := r.bits(length-2, width)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range deltas {
+			if delta < 0 {
+				prev -= int64(d)
+			} else {
+				prev += int64(d)
+			}
+			out = append(out, prev)
+		}
+	}
+	return out, nil
+}
+
+// protoFields calls fn with each field of the protobuf message b: varints
+// as v, and length-delimited fields as data.
+func protoFields(b []byte, fn func(num protowire.Number, v uint64, data []byte)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch typ {
+		case protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			if n >= 0 {
+				fn(num, v, nil)
+			}
+		case protowire.BytesType:
+			var data []byte
+			data, n = protowire.ConsumeBytes(b)
+			if n >= 0 {
+				fn(num, 0, data)
+			}
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// protoVarints returns the values of a repeated varint field, v unless
+// they are packed in data.
+func protoVarints(v uint64, data []byte) []uint64 {
+	if data == nil {
+		return []uint64{v}
+	}
+	var out []uint64
+	for len(data) > 0 {
+		x, n := protowire.ConsumeVarint(data)
+		if n < 0 {
+			break
+		}
+		out = append(out, x)
+		data = data[n:]
+	}
+	return out
+}
@@ -23,7 +23,20 @@ func (l *ParquetLoader) Extensions() []string { return []string{".parquet"} }
 
 func (l *ParquetLoader) Load(ctx context.Context, relPath string, absPath string) ([]ingest.Representation, error) {
 	slog.Info("Loading Parquet file", "relPath", relPath)
+	rowsCap := readLimit(l.cfg)
+	if rowsCap <= 0 {
+		rowsCap = 50000
+	}
+	rows, err := l.readRows(absPath, rowsCap)
+	if err != nil {
+		return nil, err
+	}
+	return BuildRepresentations(rows, relPath, l.cfg)
+}
 
+// readRows reads up to limit rows of the file, with their values
+// stringified.
+func (l *ParquetLoader) readRows(absPath string, limit int) ([]map[string]string, error) {
 	fr, err := local.NewLocalFileReader(absPath)
 	if err != nil {
 		return nil, err
@@ -38,14 +51,9 @@ func (l *ParquetLoader) Load(ctx context.Context, relPath string, absPath string
 	defer pr.ReadStop()
 
 	num := int(pr.GetNumRows())
-	rowsCap := l.cfg.MaxRowsEmbedded * 2
-	if rowsCap <= 0 {
-		rowsCap = 50000
-	}
-
 	rowsToRead := num
-	if rowsToRead == 0 || rowsToRead > rowsCap {
-		rowsToRead = rowsCap
+	if rowsToRead > limit {
+		rowsToRead = limit
 	}
 
 	// Read into generic interface map[string]interface{}
@@ -70,6 +78,5 @@ func (l *ParquetLoader) Load(ctx context.Context, relPath string, absPath string
 		}
 		read += n
 	}
-
-	return BuildRepresentations(rows, relPath, l.cfg)
+	return rows, nil
 }
@@ -0,0 +1,420 @@
+package tabular
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+// MaxQueryRows is how many rows of each dataset Query loads; datasets with
+// more are queried over their first MaxQueryRows rows.
+const MaxQueryRows = 1000000
+
+// ErrInvalidQuery is wrapped by the errors Query returns for statements it
+// refuses to run or that SQLite rejects.
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Dataset is an indexed tabular file, table of a SQLite database or sheet
+// of a spreadsheet that Query can read.
+type Dataset struct {
+	// Path is the path the dataset is indexed under, e.g. "data/orders.csv",
+	// or "shop.db#orders" for the orders table of a SQLite database, and
+	// "sales.xlsx#Q1" for the sheet Q1 of a workbook with several.
+	Path string `json:"path"`
+	// Table is what queries call it, e.g. "orders"; the quoted path, e.g.
+	// "data/orders.csv", works too.
+	Table string `json:"table"`
+
+	file        string // on disk
+	sqliteTable string
+	sheet       string
+}
+
+// NewDatasets returns the datasets indexed under paths, with their files
+// under root, the directory local sources are relative to. Each is named
+// after its file, or its SQLite table, without the extension and in lower
+// case, with characters other than letters, digits and underscores turned
+// into underscores, e.g. "orders" for "data/Orders.csv"; sheets are named
+// after their file and sheet, e.g. "sales_q1" for "sales.xlsx#Q1".
+// Datasets that would share a name are named after their whole path
+// instead, e.g. "data_2024_orders".
+func NewDatasets(root string, paths []string) []Dataset {
+	datasets := make([]Dataset, 0, len(paths))
+	for _, p := range paths {
+		d := Dataset{Path: p, file: p}
+		if i := strings.LastIndex(p, "#"); i > 0 && isSQLiteFile(p[:i]) {
+			d.file, d.sqliteTable = p[:i], p[i+1:]
+		} else if i := strings.Index(p, "#"); i > 0 && isSpreadsheet(p[:i]) {
+			d.file, d.sheet = p[:i], p[i+1:]
+		}
+		d.file = filepath.Join(root, filepath.FromSlash(d.file))
+		datasets = append(datasets, d)
+	}
+	// Each way of naming applies to the datasets whose name is still taken.
+	for _, name := range []func(Dataset) string{
+		func(d Dataset) string {
+			if d.sqliteTable != "" {
+				return tableName(d.sqliteTable)
+			}
+			name := strings.TrimSuffix(filepath.Base(d.file), filepath.Ext(d.file))
+			if d.sheet != "" {
+				name += "_" + d.sheet
+			}
+			return tableName(name)
+		},
+		func(d Dataset) string {
+			if d.sheet != "" {
+				return tableName(strings.TrimSuffix(d.Path, filepath.Ext(d.file)+"#"+d.sheet) + "_" + d.sheet)
+			}
+			return tableName(strings.TrimSuffix(d.Path, filepath.Ext(d.Path)))
+		},
+		func(d Dataset) string { return tableName(d.Path) },
+	} {
+		count := map[string]int{}
+		for _, d := range datasets {
+			count[d.Table]++
+		}
+		for i, d := range datasets {
+			if d.Table == "" || count[d.Table] > 1 {
+				datasets[i].Table = name(d)
+			}
+		}
+	}
+	return datasets
+}
+
+func isSQLiteFile(p string) bool {
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".sqlite", ".db", ".sqlite3":
+		return true
+	}
+	return false
+}
+
+func isSpreadsheet(p string) bool {
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".xlsx", ".xlsm", ".xlsb", ".ods":
+		return true
+	}
+	return false
+}
+
+// tableName turns s into an SQL identifier that needs no quotes.
+func tableName(s string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToLower(r)
+		}
+		return '_'
+	}, s)
+	name = strings.Trim(name, "_")
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "t_" + name
+	}
+	return name
+}
+
+// read reads up to limit rows of the dataset.
+func (d Dataset) read(ctx context.Context, cfg config.TabularConfig, limit int) ([]map[string]string, error) {
+	if d.sqliteTable != "" {
+		db, err := openSQLite(d.file)
+		if err != nil {
+			return nil, err
+		}
+		defer db.Close()
+		return readSQLiteTable(ctx, db, d.sqliteTable, limit)
+	}
+	if lakeFormat(d.file) != "" {
+		snap, err := readLakeSnapshot(d.file)
+		if err != nil {
+			return nil, err
+		}
+		rows, _, err := snap.readRows(cfg, limit, false)
+		return rows, err
+	}
+	switch strings.ToLower(filepath.Ext(d.file)) {
+	case ".csv", ".tsv":
+		return NewCSVLoader(cfg).readRows(d.Path, d.file, limit)
+	case ".json", ".jsonl":
+		return NewJSONLoader(cfg).readRows(d.Path, d.file, limit)
+	case ".parquet":
+		return NewParquetLoader(cfg).readRows(d.file, limit)
+	case ".avro":
+		return NewAvroLoader(cfg).readRows(d.file, limit)
+	case ".orc":
+		return NewORCLoader(cfg).readRows(d.file, limit)
+	case ".xlsx", ".xlsm", ".xlsb", ".ods":
+		return NewExcelLoader(cfg).readRows(d.file, d.sheet, limit)
+	}
+	return nil, fmt.Errorf("%s cannot be queried", d.Path)
+}
+
+// QueryResult is what a query returned.
+type QueryResult struct {
+	Columns []string `json:"columns"`
+	Rows    [][]any  `json:"rows"`
+	// Truncated is set when the query returned more rows than asked for.
+	Truncated bool `json:"truncated,omitempty"`
+	// Tables are the datasets the query read.
+	Tables []Dataset `json:"tables"`
+	// Partial lists the paths of those with more than MaxQueryRows rows,
+	// of which only the first were queried.
+	Partial []string `json:"partial,omitempty"`
+}
+
+// Query runs the SQL statement query, which must be a single SELECT (or
+// WITH ... SELECT), over the datasets it names, and returns up to maxRows
+// rows of its result. Each dataset it names is loaded into an in-memory
+// SQLite database, redacted as tabular.redact and tabular.detect_pii ask as
+// when indexing, as a table with NUMERIC columns for the columns detected
+// as numeric and TEXT ones for the others, empty values being NULL. The
+// database is read-only while the query runs.
+func Query(ctx context.Context, query string, datasets []Dataset, cfg config.TabularConfig, maxRows int) (*QueryResult, error) {
+	stmt, words, err := parseReadOnly(query)
+	if err != nil {
+		return nil, err
+	}
+	res := &QueryResult{Columns: []string{}, Rows: [][]any{}, Tables: []Dataset{}}
+	for _, d := range datasets {
+		if words[strings.ToLower(d.Table)] || words[strings.ToLower(d.Path)] {
+			res.Tables = append(res.Tables, d)
+		}
+	}
+	if len(res.Tables) == 0 {
+		names := make([]string, len(datasets))
+		for i, d := range datasets {
+			names[i] = d.Table
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("%w: no tabular files are indexed", ErrInvalidQuery)
+		}
+		return nil, fmt.Errorf("%w: it names no indexed dataset (tables: %s)", ErrInvalidQuery, strings.Join(names, ", "))
+	}
+
+	db, err := sql.Open("sqlite", "file::memory:")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	// Every connection has its own in-memory database.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	for _, d := range res.Tables {
+		rows, err := d.read(ctx, cfg, MaxQueryRows+1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", d.Path, err)
+		}
+		if len(rows) > MaxQueryRows {
+			rows = rows[:MaxQueryRows]
+			res.Partial = append(res.Partial, d.Path)
+		}
+		redactColumns(rows, cfg)
+		if err := createTable(ctx, conn, d, rows); err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", d.Path, err)
+		}
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	defer rows.Close()
+	if res.Columns, err = rows.Columns(); err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		if len(res.Rows) == maxRows {
+			res.Truncated = true
+			break
+		}
+		vals := make([]any, len(res.Columns))
+		ptrs := make([]any, len(vals))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range vals {
+			if b, ok := v.([]byte); ok {
+				vals[i] = string(b)
+			}
+		}
+		res.Rows = append(res.Rows, vals)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	return res, nil
+}
+
+// createTable loads rows into a table named after d, and a view named after
+// its path.
+func createTable(ctx context.Context, conn *sql.Conn, d Dataset, rows []map[string]string) error {
+	kinds := map[string]ColumnKind{}
+	for _, c := range DetectSchema(rows) {
+		kinds[c.Name] = c.Kind
+	}
+	cols := columnNames(rows)
+	if len(cols) == 0 {
+		return fmt.Errorf("no rows")
+	}
+	defs := make([]string, len(cols))
+	for i, c := range cols {
+		typ := "TEXT"
+		if kinds[c] == KindNumeric {
+			typ = "NUMERIC"
+		}
+		defs[i] = quoteIdent(c) + " " + typ
+	}
+	table := quoteIdent(d.Table)
+	if _, err := conn.ExecContext(ctx, "CREATE TABLE "+table+" ("+strings.Join(defs, ", ")+")"); err != nil {
+		return err
+	}
+	if d.Path != d.Table {
+		if _, err := conn.ExecContext(ctx, "CREATE VIEW "+quoteIdent(d.Path)+" AS SELECT * FROM "+table); err != nil {
+			return err
+		}
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	insert, err := tx.PrepareContext(ctx, "INSERT INTO "+table+" VALUES ("+strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ")+")")
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+	args := make([]any, len(cols))
+	for _, row := range rows {
+		for i, c := range cols {
+			args[i] = nil
+			if v := strings.TrimSpace(row[c]); v != "" {
+				args[i] = row[c]
+			}
+		}
+		if _, err := insert.ExecContext(ctx, args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// parseReadOnly checks that query is a single SELECT statement and returns
+// it without a trailing semicolon, and the identifiers it uses, in lower
+// case and unquoted.
+func parseReadOnly(query string) (stmt string, words map[string]bool, err error) {
+	words = map[string]bool{}
+	first := ""
+	end := len(query)
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			j := strings.IndexByte(query[i:], '\n')
+			if j < 0 {
+				j = len(query) - i
+			}
+			i += j
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			j := strings.Index(query[i+2:], "*/")
+			if j < 0 {
+				return "", nil, fmt.Errorf("%w: unterminated comment", ErrInvalidQuery)
+			}
+			i += j + 4
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			j := i + 1
+			var quoted strings.Builder
+			for ; j < len(query); j++ {
+				if query[j] == closing {
+					if closing != ']' && j+1 < len(query) && query[j+1] == closing {
+						quoted.WriteByte(closing) // doubled to escape it
+						j++
+						continue
+					}
+					break
+				}
+				quoted.WriteByte(query[j])
+			}
+			if j == len(query) {
+				return "", nil, fmt.Errorf("%w: unterminated quote", ErrInvalidQuery)
+			}
+			if c != '\'' {
+				words[strings.ToLower(quoted.String())] = true
+			}
+			i = j + 1
+		case c == ';':
+			if strings.TrimSpace(stripComments(query[i+1:])) != "" {
+				return "", nil, fmt.Errorf("%w: only one statement can be run", ErrInvalidQuery)
+			}
+			end = i
+			i = len(query)
+		case c == '_' || c < unicode.MaxASCII && (unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))):
+			j := i
+			for j < len(query) && (query[j] == '_' || query[j] >= unicode.MaxASCII ||
+				unicode.IsLetter(rune(query[j])) || unicode.IsDigit(rune(query[j]))) {
+				j++
+			}
+			word := strings.ToLower(query[i:j])
+			if first == "" {
+				first = word
+			}
+			words[word] = true
+			i = j
+		default:
+			i++
+		}
+	}
+	if first != "select" && first != "with" {
+		return "", nil, fmt.Errorf("%w: only SELECT statements can be run", ErrInvalidQuery)
+	}
+	return strings.TrimSpace(query[:end]), words, nil
+}
+
+// stripComments removes the SQL comments in s, which holds no quotes.
+func stripComments(s string) string {
+	for {
+		i := strings.Index(s, "--")
+		j := strings.Index(s, "/*")
+		switch {
+		case i >= 0 && (j < 0 || i < j):
+			k := strings.IndexByte(s[i:], '\n')
+			if k < 0 {
+				return s[:i]
+			}
+			s = s[:i] + s[i+k:]
+		case j >= 0:
+			k := strings.Index(s[j:], "*/")
+			if k < 0 {
+				return s[:j]
+			}
+			s = s[:j] + s[j+k+2:]
+		default:
+			return s
+		}
+	}
+}
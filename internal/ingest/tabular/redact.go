@@ -0,0 +1,155 @@
+package tabular
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+// Redaction actions of tabular.redact and tabular.detect_pii.
+const (
+	RedactMask = "mask"
+	RedactHash = "hash"
+	RedactDrop = "drop"
+)
+
+// maskedValue replaces the values of masked columns.
+const maskedValue = "[REDACTED]"
+
+var (
+	reEmail = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[A-Za-z]{2,}$`)
+	reSSN   = regexp.MustCompile(`^\d{3}-\d{2}-\d{4}$`)
+	rePhone = regexp.MustCompile(`^\+?[\d\s().-]+$`)
+	reIPv4  = regexp.MustCompile(`^\d{1,3}(\.\d{1,3}){3}$`)
+)
+
+// piiKind returns the kind of personal data v is, "email", "ssn" or
+// "phone", or "" if it is none of them. Phone numbers need 7 to 15 digits
+// and a leading + or a separator, so that plain numbers, dates and IP
+// addresses do not count.
+func piiKind(v string) string {
+	switch {
+	case reEmail.MatchString(v):
+		return "email"
+	case reSSN.MatchString(v):
+		return "ssn"
+	case rePhone.MatchString(v) && !isNumeric(v) && !isDateTime(v) && !reIPv4.MatchString(v):
+		digits := 0
+		for _, c := range v {
+			if c >= '0' && c <= '9' {
+				digits++
+			}
+		}
+		if digits >= 7 && digits <= 15 {
+			return "phone"
+		}
+	}
+	return ""
+}
+
+// redactColumns applies tabular.redact and tabular.detect_pii to rows in
+// place and returns the action taken on each redacted column. A column is
+// detected as personal data when most of its non-empty values are of the
+// same kind (see piiKind).
+func redactColumns(rows []map[string]string, cfg config.TabularConfig) map[string]string {
+	if len(cfg.Redact) == 0 && cfg.DetectPII == "" {
+		return nil
+	}
+	actions := map[string]string{}
+	for _, name := range columnNames(rows) {
+		if action, ok := configuredRedaction(name, cfg.Redact); ok {
+			actions[name] = action
+		} else if cfg.DetectPII != "" && detectPII(rows, name) != "" {
+			actions[name] = cfg.DetectPII
+		}
+	}
+	for _, row := range rows {
+		for name, action := range actions {
+			v, ok := row[name]
+			if !ok {
+				continue
+			}
+			switch action {
+			case RedactDrop:
+				delete(row, name)
+			case RedactHash:
+				if v = strings.TrimSpace(v); v != "" {
+					row[name] = hashValue(v, cfg.HashSalt)
+				}
+			default:
+				if strings.TrimSpace(v) != "" {
+					row[name] = maskedValue
+				}
+			}
+		}
+	}
+	return actions
+}
+
+// configuredRedaction returns the action of the first entry of redact
+// naming column.
+func configuredRedaction(column string, redact []config.ColumnRedaction) (action string, ok bool) {
+	name := strings.ToLower(column)
+	for _, r := range redact {
+		for _, pattern := range r.Columns {
+			if match, _ := path.Match(strings.ToLower(pattern), name); match {
+				if r.Action == "" {
+					return RedactMask, true
+				}
+				return r.Action, true
+			}
+		}
+	}
+	return "", false
+}
+
+// detectPII returns the kind of personal data most non-empty values of
+// column are, or "".
+func detectPII(rows []map[string]string, column string) string {
+	counts := map[string]int{}
+	values := 0
+	for _, row := range rows {
+		v := strings.TrimSpace(row[column])
+		if v == "" {
+			continue
+		}
+		values++
+		if kind := piiKind(v); kind != "" {
+			counts[kind]++
+		}
+	}
+	for kind, n := range counts {
+		if n*2 > values {
+			return kind
+		}
+	}
+	return ""
+}
+
+// hashValue returns the first 16 hex digits of the SHA-256 of salt and v,
+// enough to tell values apart and to filter on one.
+func hashValue(v, salt string) string {
+	sum := sha256.Sum256([]byte(salt + v))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// columnNames returns the names of the columns of rows, sorted.
+func columnNames(rows []map[string]string) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, row := range rows {
+		for name := range row {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
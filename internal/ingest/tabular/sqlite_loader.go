@@ -25,59 +25,25 @@ func (l *SQLiteLoader) Extensions() []string { return []string{".sqlite", ".db",
 func (l *SQLiteLoader) Load(ctx context.Context, relPath string, absPath string) ([]ingest.Representation, error) {
 	slog.Info("Loading SQLite DB", "relPath", relPath)
 
-	dsn := fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&_pragma=busy_timeout=5000", absPath)
-	db, err := sql.Open("sqlite", dsn)
+	db, err := openSQLite(absPath)
 	if err != nil {
 		return nil, err
 	}
 	defer db.Close()
 
-	rowsTbl, err := db.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%'`)
+	tables, err := sqliteTables(ctx, db)
 	if err != nil {
 		return nil, err
 	}
-	defer rowsTbl.Close()
 
 	var allReps []ingest.Representation
 
-	for rowsTbl.Next() {
-		var table string
-		if err := rowsTbl.Scan(&table); err != nil {
-			continue
-		}
-
-		query := fmt.Sprintf("SELECT * FROM %s", table)
-		r, err := db.QueryContext(ctx, query)
+	for _, table := range tables {
+		maps, err := readSQLiteTable(ctx, db, table, readLimit(l.cfg))
 		if err != nil {
 			continue
 		}
 
-		cols, _ := r.Columns()
-		// iterate rows up to cap
-		var maps []map[string]string
-		capRows := l.cfg.MaxRowsEmbedded * 2
-		count := 0
-		for r.Next() {
-			vals := make([]interface{}, len(cols))
-			ptrs := make([]interface{}, len(cols))
-			for i := range vals {
-				ptrs[i] = &vals[i]
-			}
-			if err := r.Scan(ptrs...); err != nil {
-				continue
-			}
-			m := map[string]string{}
-			for i, c := range cols {
-				m[c] = fmt.Sprintf("%v", vals[i])
-			}
-			maps = append(maps, m)
-			count++
-			if count >= capRows {
-				break
-			}
-		}
-		r.Close()
-
 		reps, _ := BuildRepresentations(maps, relPath+"#"+table, l.cfg)
 		for i := range reps {
 			if reps[i].Meta == nil {
@@ -90,3 +56,63 @@ func (l *SQLiteLoader) Load(ctx context.Context, relPath string, absPath string)
 
 	return allReps, nil
 }
+
+// openSQLite opens the database at absPath.
+func openSQLite(absPath string) (*sql.DB, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&_pragma=busy_timeout=5000", absPath)
+	return sql.Open("sqlite", dsn)
+}
+
+// sqliteTables returns the names of the tables of db.
+func sqliteTables(ctx context.Context, db *sql.DB) ([]string, error) {
+	rowsTbl, err := db.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rowsTbl.Close()
+	var tables []string
+	for rowsTbl.Next() {
+		var table string
+		if err := rowsTbl.Scan(&table); err != nil {
+			continue
+		}
+		tables = append(tables, table)
+	}
+	return tables, rowsTbl.Err()
+}
+
+// readSQLiteTable reads up to limit rows of table, with their values
+// formatted as strings.
+func readSQLiteTable(ctx context.Context, db *sql.DB, table string, limit int) ([]map[string]string, error) {
+	query := fmt.Sprintf("SELECT * FROM %s", table)
+	r, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	cols, _ := r.Columns()
+	// iterate rows up to cap
+	var maps []map[string]string
+	for r.Next() {
+		vals := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := r.Scan(ptrs...); err != nil {
+			continue
+		}
+		m := map[string]string{}
+		for i, c := range cols {
+			if vals[i] != nil { // NULL stays empty
+				m[c] = fmt.Sprintf("%v", vals[i])
+			}
+		}
+		maps = append(maps, m)
+		if len(maps) >= limit {
+			break
+		}
+	}
+	return maps, nil
+}
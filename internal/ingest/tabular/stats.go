@@ -0,0 +1,72 @@
+package tabular
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// columnStats summarises the values of a numeric or datetime column.
+type columnStats struct {
+	Column
+	count         int
+	min, max, sum float64 // numeric columns
+	first, last   string  // datetime columns: the earliest and latest date
+}
+
+// summarizeColumns works out the range of the numeric and datetime columns
+// of schema over rows, in schema order, skipping the columns in skip and
+// values that do not parse.
+func summarizeColumns(rows []map[string]string, schema []Column, skip map[string]string) []columnStats {
+	var stats []columnStats
+	for _, col := range schema {
+		if skip[col.Name] != "" || (col.Kind != KindNumeric && col.Kind != KindDateTime) {
+			continue
+		}
+		st := columnStats{Column: col, min: math.Inf(1), max: math.Inf(-1)}
+		for _, row := range rows {
+			v := strings.TrimSpace(row[col.Name])
+			if col.Kind == KindDateTime {
+				if !isDateTime(v) {
+					continue
+				}
+				v = v[:len("2006-01-02")]
+				if st.count == 0 || v < st.first {
+					st.first = v
+				}
+				if v > st.last {
+					st.last = v
+				}
+				st.count++
+				continue
+			}
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || !isNumeric(v) {
+				continue
+			}
+			st.min, st.max = math.Min(st.min, f), math.Max(st.max, f)
+			st.sum += f
+			st.count++
+		}
+		if st.count > 0 {
+			stats = append(stats, st)
+		}
+	}
+	return stats
+}
+
+// String describes the column for the file summary, e.g. "amount: min 3,
+// max 1200, mean 245.75" or "ordered_at: from 2024-01-02 to 2025-03-01".
+func (st columnStats) String() string {
+	if st.Kind == KindDateTime {
+		return st.Name + ": from " + st.first + " to " + st.last
+	}
+	return st.Name + ": min " + formatNumber(st.min) + ", max " + formatNumber(st.max) +
+		", mean " + formatNumber(st.sum/float64(st.count))
+}
+
+// formatNumber writes f with at most two decimals, and none for whole
+// numbers.
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
@@ -1,16 +1,30 @@
 package tabular
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
 	"context"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/linkedin/goavro/v2"
 	"github.com/omarkamali/semango/internal/config"
 	"github.com/xitongsys/parquet-go-source/local"
 	"github.com/xitongsys/parquet-go/parquet"
 	"github.com/xitongsys/parquet-go/writer"
+	"github.com/xuri/excelize/v2"
+	"google.golang.org/protobuf/encoding/protowire"
 )
 
 func cfg() config.TabularConfig {
@@ -66,6 +80,21 @@ func TestJSONLoader(t *testing.T) {
 	if len(reps) == 0 {
 		t.Fatalf("expected representations >0 for json, got %d", len(reps))
 	}
+
+	// Items other than objects are skipped, and .JSONL is JSON Lines.
+	if err := os.WriteFile(file, []byte(`[{"name": "Alice"}, 3, null, ["x"], {"name": "Bob"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if rows, err := l.readRows("sample.json", file, 10); err != nil || len(rows) != 2 || rows[1]["name"] != "Bob" {
+		t.Errorf("rows = %v, %v", rows, err)
+	}
+	lines := filepath.Join(dir, "SAMPLE.JSONL")
+	if err := os.WriteFile(lines, []byte("{\"name\": \"Alice\"}\n{\"name\": \"Bob\"}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if rows, err := l.readRows("SAMPLE.JSONL", lines, 10); err != nil || len(rows) != 2 {
+		t.Errorf("JSONL rows = %v, %v", rows, err)
+	}
 }
 
 func TestParquetLoader(t *testing.T) {
@@ -136,3 +165,807 @@ func TestTSVLoader(t *testing.T) {
 		t.Fatalf("expected representations >0 for tsv, got %d", len(reps))
 	}
 }
+
+func TestRedactColumns(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "customers.csv")
+	content := "name,contact,phone,ssn,note,ip\n" +
+		"Alice,alice@example.com,+1 555 010 2000,123-45-6789,Asked about shipping to Lyon,10.0.0.1\n" +
+		"Bob,bob@example.org,(555) 010-3000,987-65-4321,Wants a refund for the lamp,10.0.0.2\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := cfg()
+	c.Redact = []config.ColumnRedaction{{Columns: []string{"NAME"}, Action: "hash"}, {Columns: []string{"s*n"}, Action: "drop"}}
+	c.DetectPII = "mask"
+	reps, err := NewCSVLoader(c).Load(context.Background(), "customers.csv", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := reps[0]
+	for _, leak := range []string{"Alice", "alice@example.com", "555", "123-45-6789"} {
+		if strings.Contains(row.Text, leak) {
+			t.Errorf("text %q leaks %q", row.Text, leak)
+		}
+		for k, v := range row.Meta {
+			if strings.Contains(v, leak) {
+				t.Errorf("meta %s = %q leaks %q", k, v, leak)
+			}
+		}
+	}
+	if !strings.Contains(row.Text, "note: Asked about shipping") {
+		t.Errorf("text = %q", row.Text)
+	}
+	if row.Meta["col.contact"] != "[REDACTED]" || row.Meta["col.ip"] != "10.0.0.1" {
+		t.Errorf("meta = %v", row.Meta)
+	}
+	if _, ok := row.Meta["col.ssn"]; ok {
+		t.Error("dropped column kept")
+	}
+	// Equal values hash alike, so they can still be filtered on.
+	if h := row.Meta["col.name"]; !strings.HasPrefix(h, "sha256:") || h != hashValue("Alice", "") {
+		t.Errorf("hashed name = %q", h)
+	}
+	if got := row.Meta["redacted"]; got != "contact,name,phone,ssn" {
+		t.Errorf("redacted = %q", got)
+	}
+}
+
+func TestPIIKind(t *testing.T) {
+	for v, want := range map[string]string{
+		"jane.doe@example.co.uk": "email",
+		"078-05-1120":            "ssn",
+		"+44 20 7946 0958":       "phone",
+		"555.010.2000":           "phone",
+		"1234567":                "",
+		"2024-01-02":             "",
+		"192.168.1.10":           "",
+		"v1.2.3":                 "",
+	} {
+		if got := piiKind(v); got != want {
+			t.Errorf("piiKind(%q) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestNumericSummaries(t *testing.T) {
+	rows := []map[string]string{
+		{"item": "Oak desk with drawers", "amount": "1200", "ordered_at": "2025-03-01T10:00:00Z"},
+		{"item": "Reading lamp", "amount": "3", "ordered_at": "2024-01-02"},
+		{"item": "Bookshelf", "amount": "34.5", "ordered_at": "2024-07-15"},
+	}
+	c := cfg()
+	reps, err := BuildRepresentations(rows, "orders.csv", c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary string
+	for _, r := range reps {
+		if r.Meta["kind"] == "file_summary" {
+			summary = r.Text
+		}
+	}
+	for _, want := range []string{"amount: min 3, max 1200, mean 412.5", "ordered_at: from 2024-01-02 to 2025-03-01"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary %q lacks %q", summary, want)
+		}
+	}
+	if strings.Contains(reps[0].Text, "1200") {
+		t.Errorf("row text %q has numbers without numbers_in_text", reps[0].Text)
+	}
+
+	c.NumbersInText = true
+	reps, err = BuildRepresentations(rows, "orders.csv", c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "amount: 1200\nitem: Oak desk with drawers\nordered_at: 2025-03-01T10:00:00Z"; reps[0].Text != want {
+		t.Errorf("row text = %q, want %q", reps[0].Text, want)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	var rows []map[string]string
+	for i := 0; i < 100; i++ {
+		rows = append(rows, map[string]string{"id": strconv.Itoa(i), "note": "row number " + strconv.Itoa(i)})
+	}
+	sampled := func(c config.TabularConfig) []string {
+		reps, err := BuildRepresentations(rows, "rows.csv", c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, r := range reps {
+			if r.Modality == "table_row" {
+				ids = append(ids, r.Meta["row"])
+			}
+		}
+		return ids
+	}
+	c := cfg()
+	c.MaxRowsEmbedded = 10
+	first := sampled(c)
+	if len(first) != 10 {
+		t.Fatalf("sampled %d rows, want 10", len(first))
+	}
+	if again := sampled(c); !reflect.DeepEqual(again, first) {
+		t.Errorf("re-sampling picked %v, then %v", first, again)
+	}
+
+	c.SamplingSeed = 42
+	seeded := sampled(c)
+	if reflect.DeepEqual(seeded, first) || !reflect.DeepEqual(sampled(c), seeded) {
+		t.Errorf("sampling_seed 42 picked %v, then %v, the rows' seed %v", seeded, sampled(c), first)
+	}
+
+	c.Sampling = SamplingAll
+	if all := sampled(c); len(all) != 100 {
+		t.Errorf("sampling all embedded %d rows, want 100", len(all))
+	}
+	if readLimit(c) != math.MaxInt {
+		t.Errorf("sampling all reads %d rows", readLimit(c))
+	}
+}
+
+func TestQuery(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"data/orders.csv":  "id,customer,amount,email\n1,Acme,1200,ops@acme.test\n2,Globex,30.5,it@globex.test\n3,Acme,999,ops@acme.test\n",
+		"data/clients.csv": "name,country\nAcme,FR\nGlobex,US\n",
+		"old/orders.csv":   "id,amount\n1,5\n",
+	}
+	for name, body := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	datasets := NewDatasets(dir, []string{"data/orders.csv", "data/clients.csv", "old/orders.csv", "shop.db#Line Items"})
+	var names []string
+	for _, d := range datasets {
+		names = append(names, d.Table)
+	}
+	if want := []string{"data_orders", "clients", "old_orders", "line_items"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("tables %v, want %v", names, want)
+	}
+
+	c := cfg()
+	c.DetectPII = "mask"
+	res, err := Query(context.Background(), `-- big orders
+		SELECT o.id, c.country, o.amount, o.email FROM data_orders o JOIN "data/clients.csv" c ON c.name = o.customer
+		WHERE o.amount > 100 ORDER BY o.amount DESC;`, datasets, c, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.Columns, []string{"id", "country", "amount", "email"}) {
+		t.Errorf("columns = %v", res.Columns)
+	}
+	// Numeric columns compare as numbers, and redacted ones stay redacted.
+	if want := [][]any{{int64(1), "FR", int64(1200), "[REDACTED]"}}; !reflect.DeepEqual(res.Rows, want) || !res.Truncated {
+		t.Errorf("rows = %#v, truncated %v", res.Rows, res.Truncated)
+	}
+	if len(res.Tables) != 2 {
+		t.Errorf("tables = %+v", res.Tables)
+	}
+
+	for _, q := range []string{
+		"DELETE FROM data_orders",
+		"SELECT 1 FROM clients; DROP TABLE clients",
+		"ATTACH 'x.db' AS x",
+		"SELECT 1",
+		"SELECT nope FROM clients",
+		"WITH x AS (SELECT 1) DELETE FROM clients",
+	} {
+		if _, err := Query(context.Background(), q, datasets, c, 10); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("%q: err = %v", q, err)
+		}
+	}
+}
+
+func TestExcelSheets(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "book.xlsx")
+	f := excelize.NewFile()
+	f.SetSheetName("Sheet1", "Big")
+	f.SetSheetRow("Big", "A1", &[]any{"item", "note"})
+	for i := 2; i <= 31; i++ {
+		f.SetSheetRow("Big", "A"+strconv.Itoa(i), &[]any{"item " + strconv.Itoa(i), "some note"})
+	}
+	f.NewSheet("Small")
+	f.SetSheetRow("Small", "A1", &[]any{"city", "remark"})
+	// Row 2 is empty and left out.
+	f.SetSheetRow("Small", "A3", &[]any{"Paris", "lovely place"})
+	f.SetSheetRow("Small", "A4", &[]any{"Oslo", "cold place"})
+	if err := f.SaveAs(file); err != nil {
+		t.Fatal(err)
+	}
+
+	c := cfg()
+	c.MaxRowsEmbedded = 10
+	reps, err := NewExcelLoader(c).Load(context.Background(), "book.xlsx", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := map[string][]string{}
+	ids := map[string]bool{}
+	for _, r := range reps {
+		ids[r.ID] = true
+		if r.Modality == "table_row" {
+			rows[r.Meta["sheet"]] = append(rows[r.Meta["sheet"]], r.Meta["row"])
+		} else if r.Meta["kind"] == "file_summary" && r.Meta["sheet"] == "Small" &&
+			!strings.HasPrefix(r.Text, "Sheet Small of spreadsheet book.xlsx with 2 rows") {
+			t.Errorf("summary = %q", r.Text)
+		}
+	}
+	if len(ids) != len(reps) {
+		t.Errorf("%d ids for %d chunks", len(ids), len(reps))
+	}
+	// The small sheet leaves 8 of the 10 rows to the big one.
+	if !reflect.DeepEqual(rows["Small"], []string{"3", "4"}) || len(rows["Big"]) != 8 {
+		t.Errorf("rows = %v", rows)
+	}
+
+	c.MaxRowsPerSheet = 3
+	reps, err = NewExcelLoader(c).Load(context.Background(), "book.xlsx", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows = map[string][]string{}
+	for _, r := range reps {
+		if r.Modality == "table_row" {
+			rows[r.Meta["sheet"]] = append(rows[r.Meta["sheet"]], r.Meta["row"])
+		}
+	}
+	if len(rows["Small"]) != 2 || len(rows["Big"]) != 3 {
+		t.Errorf("rows with max_rows_per_sheet = %v", rows)
+	}
+
+	datasets := NewDatasets(dir, []string{"book.xlsx#Small", "book.xlsx#Big"})
+	if datasets[0].Table != "book_small" {
+		t.Errorf("table = %q", datasets[0].Table)
+	}
+	res, err := Query(context.Background(), "SELECT city FROM book_small ORDER BY city", datasets, c, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]any{{"Oslo"}, {"Paris"}}; !reflect.DeepEqual(res.Rows, want) {
+		t.Errorf("rows = %v", res.Rows)
+	}
+}
+
+func TestReadODS(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+ xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:spreadsheet>
+<table:table table:name="Orders">
+<table:table-row><table:table-cell office:value-type="string"><text:p>customer</text:p></table:table-cell>
+<table:table-cell office:value-type="string"><text:p>amount</text:p></table:table-cell>
+<table:table-cell table:number-columns-repeated="1020"/></table:table-row>
+<table:table-row table:number-rows-repeated="2"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+<table:table-row><table:table-cell office:value-type="string"><office:annotation><text:p>check</text:p></office:annotation><text:p>Acme</text:p><text:p>Corp</text:p></table:table-cell>
+<table:table-cell office:value-type="float" office:value="1200"><text:p>1,200.00</text:p></table:table-cell></table:table-row>
+<table:table-row table:number-rows-repeated="2"><table:table-cell table:number-columns-repeated="2"/>
+<table:table-cell office:value-type="boolean" office:boolean-value="true"><text:p>TRUE</text:p></table:table-cell></table:table-row>
+<table:table-row table:number-rows-repeated="1048570"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+</table:table>
+<table:table table:name="Empty"/>
+</office:spreadsheet></office:body></office:document-content>`
+	file := filepath.Join(t.TempDir(), "book.ods")
+	writeZip(t, file, map[string][]byte{"content.xml": []byte(content)})
+
+	sheets, err := readODS(file, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheets) != 2 || sheets[0].name != "Orders" || len(sheets[1].rows) != 0 {
+		t.Fatalf("sheets = %+v", sheets)
+	}
+	want := []map[string]string{
+		{"customer": "Acme\nCorp", "amount": "1200"},
+		{"customer": "", "amount": "", "C": "TRUE"},
+		{"customer": "", "amount": "", "C": "TRUE"},
+	}
+	if !reflect.DeepEqual(sheets[0].rows, want) || !reflect.DeepEqual(sheets[0].numbers, []int{4, 5, 6}) {
+		t.Errorf("rows = %v, numbers %v", sheets[0].rows, sheets[0].numbers)
+	}
+	if sheets, _ := readODS(file, 1); len(sheets[0].rows) != 1 {
+		t.Errorf("limit 1 read %d rows", len(sheets[0].rows))
+	}
+}
+
+func TestReadXLSB(t *testing.T) {
+	record := func(typ int, data ...[]byte) []byte {
+		var b []byte
+		for typ >= 0x80 {
+			b = append(b, byte(typ)|0x80)
+			typ >>= 7
+		}
+		b = append(b, byte(typ))
+		body := bytes.Join(data, nil)
+		b = append(b, byte(len(body))) // records here are under 128 bytes
+		return append(b, body...)
+	}
+	u32 := func(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+	wide := func(s string) []byte {
+		b := u32(uint32(len(s)))
+		for _, r := range s {
+			b = binary.LittleEndian.AppendUint16(b, uint16(r))
+		}
+		return b
+	}
+	cell := func(col uint32) []byte { return append(u32(col), 0, 0, 0, 0) }
+	noStyle := make([]byte, 8)
+
+	workbook := bytes.Join([][]byte{
+		record(brtBundleSh, u32(0), u32(1), wide("rId1"), wide("Orders")),
+		record(brtBundleSh, u32(0), u32(2), wide("rId2"), wide("Chart")),
+	}, nil)
+	rels := `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.bin"/>
+</Relationships>`
+	strs := bytes.Join([][]byte{
+		record(brtSSTItem, []byte{0}, wide("name")),
+		record(brtSSTItem, []byte{0}, wide("amount")),
+		record(brtSSTItem, []byte{0}, wide("Gadget")),
+	}, nil)
+	sheet := bytes.Join([][]byte{
+		record(brtRowHdr, u32(0), noStyle),
+		record(brtCellIsst, cell(0), u32(0)),
+		record(brtCellIsst, cell(1), u32(1)),
+		record(brtRowHdr, u32(2), noStyle),
+		record(brtCellSt, cell(0), wide("Widget")),
+		record(brtCellRk, cell(1), u32(42<<2|2)),
+		record(brtRowHdr, u32(3), noStyle),
+		record(brtCellIsst, cell(0), u32(2)),
+		record(brtCellReal, cell(1), binary.LittleEndian.AppendUint64(nil, math.Float64bits(12.5))),
+		record(brtCellBool, cell(3), []byte{1}),
+	}, nil)
+	file := filepath.Join(t.TempDir(), "book.xlsb")
+	writeZip(t, file, map[string][]byte{
+		"xl/workbook.bin":            workbook,
+		"xl/_rels/workbook.bin.rels": []byte(rels),
+		"xl/sharedStrings.bin":       strs,
+		"xl/worksheets/sheet1.bin":   sheet,
+	})
+
+	sheets, err := readXLSB(file, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheets) != 1 || sheets[0].name != "Orders" {
+		t.Fatalf("sheets = %+v", sheets)
+	}
+	want := []map[string]string{
+		{"name": "Widget", "amount": "42"},
+		{"name": "Gadget", "amount": "12.5", "C": "", "D": "TRUE"},
+	}
+	if !reflect.DeepEqual(sheets[0].rows, want) || !reflect.DeepEqual(sheets[0].numbers, []int{3, 4}) {
+		t.Errorf("rows = %v, numbers %v", sheets[0].rows, sheets[0].numbers)
+	}
+	if rkNumber(0x3FF00000|1) != 0.01 || rkNumber(uint32(0xFFFFFFFE)) != -1 {
+		t.Errorf("rk numbers = %v, %v", rkNumber(0x3FF00000|1), rkNumber(uint32(0xFFFFFFFE)))
+	}
+}
+
+// writeZip writes a zip file holding files.
+func writeZip(t *testing.T, name string, files map[string][]byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for n, data := range files {
+		w, err := zw.Create(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAvroLoader(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "orders.avro")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := goavro.NewOCFWriter(goavro.OCFConfig{W: f, CompressionName: goavro.CompressionDeflateLabel, Schema: `{
+		"type": "record", "name": "Order", "fields": [
+			{"name": "id", "type": "long"},
+			{"name": "note", "type": ["null", "string"]},
+			{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["OPEN", "PAID"]}},
+			{"name": "previous", "type": ["null", "Status"]},
+			{"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 8, "scale": 2}},
+			{"name": "ordered", "type": {"type": "int", "logicalType": "date"}},
+			{"name": "raw", "type": "bytes"},
+			{"name": "tags", "type": {"type": "array", "items": "string"}}
+		]}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Append([]map[string]any{
+		{"id": int64(1), "note": goavro.Union("string", "left at the door"), "status": "PAID", "previous": goavro.Union("Status", "OPEN"),
+			"amount": big.NewRat(120050, 100), "ordered": time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), "raw": []byte{1}, "tags": []any{"gift"}},
+		{"id": int64(2), "note": nil, "status": "OPEN", "previous": nil,
+			"amount": big.NewRat(3, 1), "ordered": time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), "raw": []byte{}, "tags": []any{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	rows, schema, err := readAvro(file, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Column{
+		{"id", KindNumeric}, {"note", KindText}, {"status", KindCategorical}, {"previous", KindCategorical},
+		{"amount", KindNumeric}, {"ordered", KindDateTime}, {"raw", KindBinary}, {"tags", KindText},
+	}
+	if !reflect.DeepEqual(schema, want) {
+		t.Errorf("schema = %v", schema)
+	}
+	wantRow := map[string]string{"id": "1", "note": "left at the door", "status": "PAID", "previous": "OPEN",
+		"amount": "1200.50", "ordered": "2024-03-01", "tags": `["gift"]`}
+	if len(rows) != 2 || !reflect.DeepEqual(rows[0], wantRow) || rows[1]["note"] != "" {
+		t.Errorf("rows = %v", rows)
+	}
+
+	reps, err := NewAvroLoader(cfg()).Load(context.Background(), "orders.avro", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := reps[len(reps)-1]
+	if last.Text != "Schema: id(numeric), note(text), status(categorical), previous(categorical), amount(numeric), ordered(datetime), raw(binary), tags(text)" {
+		t.Errorf("schema text = %q", last.Text)
+	}
+}
+
+func TestORCIntegerRLE(t *testing.T) {
+	// The examples of the ORC specification.
+	for _, tc := range []struct {
+		data   []byte
+		signed bool
+		v2     bool
+		want   []int64
+	}{
+		{[]byte{0x0a, 0x27, 0x10}, false, true, []int64{10000, 10000, 10000, 10000, 10000}},
+		{[]byte{0x5e, 0x03, 0x5c, 0xa1, 0xab, 0x1e, 0xde, 0xad, 0xbe, 0xef}, false, true, []int64{23713, 43806, 57005, 48879}},
+		{[]byte{0x8e, 0x13, 0x2b, 0x21, 0x07, 0xd0, 0x1e, 0x00, 0x14, 0x70, 0x28, 0x32, 0x3c, 0x46, 0x50, 0x5a, 0x64, 0x6e,
+			0x78, 0x82, 0x8c, 0x96, 0xa0, 0xaa, 0xb4, 0xbe, 0xfc, 0xe8}, false, true,
+			[]int64{2030, 2000, 2020, 1000000, 2040, 2050, 2060, 2070, 2080, 2090, 2100, 2110, 2120, 2130, 2140, 2150, 2160, 2170, 2180, 2190}},
+		{[]byte{0xc6, 0x09, 0x02, 0x02, 0x22, 0x42, 0x42, 0x46}, false, true, []int64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29}},
+		{[]byte{0xfb, 0x02, 0x03, 0x04, 0x07, 0x0b}, false, false, []int64{2, 3, 4, 7, 11}},
+		{[]byte{0x00, 0xff, 0x0a}, true, false, []int64{5, 4, 3}},
+	} {
+		got, err := decodeInts(tc.data, tc.signed, tc.v2, len(tc.want))
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("decodeInts(% x) = %v, %v; want %v", tc.data, got, err, tc.want)
+		}
+	}
+	if got, err := decodeBools([]byte{0xff, 0x80}, 3); err != nil || !reflect.DeepEqual(got, []bool{true, false, false}) {
+		t.Errorf("decodeBools = %v, %v", got, err)
+	}
+	if got, err := decodeByteRLE([]byte{0x61, 0x00}, 100); err != nil || len(got) != 100 || got[99] != 0 {
+		t.Errorf("decodeByteRLE = %v, %v", got, err)
+	}
+}
+
+func TestORCLoader(t *testing.T) {
+	for _, compression := range []int{orcNone, orcZlib} {
+		file := filepath.Join(t.TempDir(), "orders.orc")
+		writeORC(t, file, compression)
+		rows, schema, err := readORC(file, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []Column{{"id", KindNumeric}, {"note", KindText}, {"city", KindText}, {"amount", KindNumeric},
+			{"day", KindDateTime}, {"price", KindNumeric}}
+		if !reflect.DeepEqual(schema, want) {
+			t.Errorf("schema = %v", schema)
+		}
+		wantRows := []map[string]string{
+			{"id": "1", "note": "first order", "city": "Paris", "amount": "12.5", "day": "2024-01-02", "price": "-0.05"},
+			{"id": "2", "note": "", "city": "Oslo", "amount": "-3", "day": "2024-01-03", "price": "120.00"},
+			{"id": "3", "note": "third", "city": "Paris", "amount": "0.25", "day": "1969-12-31", "price": "7.10"},
+		}
+		if !reflect.DeepEqual(rows, wantRows) {
+			t.Errorf("compression %d: rows = %v", compression, rows)
+		}
+		if rows, _, _ := readORC(file, 2); len(rows) != 2 {
+			t.Errorf("limit 2 read %d rows", len(rows))
+		}
+	}
+}
+
+// writeORC writes a one-stripe ORC file of three rows: a long, a string
+// with a null, a dictionary-encoded string, a double, a date and a
+// decimal(5,2) column.
+func writeORC(t *testing.T, name string, compression int) {
+	t.Helper()
+	compress := func(b []byte) []byte {
+		if compression == orcNone {
+			return b
+		}
+		var buf bytes.Buffer
+		fw, _ := flate.NewWriter(&buf, flate.BestCompression)
+		fw.Write(b)
+		fw.Close()
+		n := buf.Len()
+		return append([]byte{byte(n << 1), byte(n >> 7), byte(n >> 15)}, buf.Bytes()...)
+	}
+	msg := func(fields ...[]byte) []byte { return bytes.Join(fields, nil) }
+	varint := func(num protowire.Number, v uint64) []byte {
+		return protowire.AppendVarint(protowire.AppendTag(nil, num, protowire.VarintType), v)
+	}
+	bytesField := func(num protowire.Number, b []byte) []byte {
+		return protowire.AppendBytes(protowire.AppendTag(nil, num, protowire.BytesType), b)
+	}
+	varints := func(signed bool, vs ...int64) []byte {
+		var b []byte
+		for _, v := range vs {
+			u := uint64(v)
+			if signed {
+				u = protowire.EncodeZigZag(v)
+			}
+			b = protowire.AppendVarint(b, u)
+		}
+		return b
+	}
+	// ints writes a literal run of integer RLE v1.
+	ints := func(signed bool, vs ...int64) []byte {
+		return append([]byte{byte(-int8(len(vs)))}, varints(signed, vs...)...)
+	}
+	doubles := func(fs ...float64) []byte {
+		var b []byte
+		for _, f := range fs {
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(f))
+		}
+		return b
+	}
+
+	streams := []struct {
+		kind, column int
+		data         []byte
+	}{
+		{orcData, 1, []byte{0x00, 0x01, 0x02}}, // a run of 3 from 1 growing by 1
+		{orcPresent, 2, []byte{0xff, 0xa0}},    // 1 0 1
+		{orcData, 2, []byte("first orderthird")},
+		{orcLength, 2, ints(false, 11, 5)},
+		{orcData, 3, ints(false, 0, 1, 0)},
+		{orcDictionaryData, 3, []byte("ParisOslo")},
+		{orcLength, 3, ints(false, 5, 4)},
+		{orcData, 4, doubles(12.5, -3, 0.25)},
+		{orcData, 5, ints(true, 19724, 19725, -1)},
+		{orcData, 6, varints(true, -5, 12000, 710)}, // unscaled
+		{orcSecondary, 6, ints(true, 2, 2, 2)},
+	}
+
+	var stripe, streamInfo []byte
+	for _, s := range streams {
+		data := compress(s.data)
+		stripe = append(stripe, data...)
+		streamInfo = append(streamInfo, bytesField(1, msg(varint(1, uint64(s.kind)), varint(2, uint64(s.column)), varint(3, uint64(len(data)))))...)
+	}
+	encodings := []int{orcDirect, orcDirect, orcDirect, orcDictionary, orcDirect, orcDirect, orcDirect}
+	for _, e := range encodings {
+		enc := varint(1, uint64(e))
+		if e == orcDictionary {
+			enc = msg(enc, varint(2, 2))
+		}
+		streamInfo = append(streamInfo, bytesField(2, enc)...)
+	}
+	stripeFooter := compress(streamInfo)
+
+	root := msg(varint(1, orcStruct), bytesField(2, []byte{1, 2, 3, 4, 5, 6}))
+	for _, n := range []string{"id", "note", "city", "amount", "day", "price"} {
+		root = append(root, bytesField(3, []byte(n))...)
+	}
+	types := [][]byte{root, varint(1, orcLong), varint(1, orcString), varint(1, orcString), varint(1, orcDouble),
+		varint(1, orcDate), msg(varint(1, orcDecimal), varint(5, 5), varint(6, 2))}
+	footer := msg(varint(1, 3), varint(2, uint64(3+len(stripe)+len(stripeFooter))),
+		bytesField(3, msg(varint(1, 3), varint(2, 0), varint(3, uint64(len(stripe))), varint(4, uint64(len(stripeFooter))), varint(5, 3))))
+	for _, ty := range types {
+		footer = append(footer, bytesField(4, ty)...)
+	}
+	footer = compress(append(footer, varint(6, 3)...))
+	ps := msg(varint(1, uint64(len(footer))), varint(2, uint64(compression)), varint(3, 256<<10), bytesField(8000, []byte("ORC")))
+
+	file := append([]byte("ORC"), stripe...)
+	file = append(file, stripeFooter...)
+	file = append(file, footer...)
+	file = append(file, ps...)
+	file = append(file, byte(len(ps)))
+	if err := os.WriteFile(name, file, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeltaTable(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "lake", "events")
+	write := func(name, body string) {
+		t.Helper()
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	schema := `{"type":"struct","fields":[{"name":"id","type":"long"},{"name":"note","type":"string"},` +
+		`{"name":"payload","type":"binary"},{"name":"tags","type":{"type":"array","elementType":"string"}},` +
+		`{"name":"day","type":"date"},{"name":"country","type":"string"}]}`
+	write("_delta_log/00000000000000000000.json", `{"protocol":{"minReaderVersion":1}}
+{"metaData":{"schemaString":`+strconv.Quote(schema)+`,"partitionColumns":["country"]}}
+{"add":{"path":"country=FR/part-0.parquet","partitionValues":{"country":"FR"},"stats":"{\"numRecords\":3}"}}
+{"add":{"path":"country=NL%20BE/part-1.parquet","partitionValues":{"country":"NL BE"},"stats":"{\"numRecords\":2}"}}
+`)
+	write("_delta_log/00000000000000000001.json", `{"remove":{"path":"country=FR/part-0.parquet"}}
+{"add":{"path":"country=FR/part-2.parquet","partitionValues":{"country":"FR"},"stats":"{\"numRecords\":4}"}}
+{"commitInfo":{"operation":"OPTIMIZE"}}
+`)
+	write("country=FR/part-0.parquet", "stale")
+
+	snap, err := readDelta(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSchema := []Column{{"id", KindNumeric}, {"note", KindText}, {"payload", KindBinary}, {"tags", KindText},
+		{"day", KindDateTime}, {"country", KindText}}
+	if !reflect.DeepEqual(snap.schema, wantSchema) {
+		t.Errorf("schema = %v", snap.schema)
+	}
+	if snap.version != "1" || snap.rows != 6 || !reflect.DeepEqual(snap.partitions, []string{"country"}) {
+		t.Errorf("version %s, %d rows, partitions %v", snap.version, snap.rows, snap.partitions)
+	}
+	wantFiles := []lakeFile{
+		{path: filepath.Join(dir, "country=FR", "part-2.parquet"), rel: "country=FR/part-2.parquet", format: "parquet", partition: map[string]string{"country": "FR"}},
+		{path: filepath.Join(dir, "country=NL BE", "part-1.parquet"), rel: "country=NL BE/part-1.parquet", format: "parquet", partition: map[string]string{"country": "NL BE"}},
+	}
+	if !reflect.DeepEqual(snap.files, wantFiles) {
+		t.Errorf("files = %+v", snap.files)
+	}
+
+	for rel, current := range map[string]bool{
+		"lake/events/_delta_log/00000000000000000001.json": true,
+		"lake/events/_delta_log/00000000000000000000.json": false,
+		"lake/events/country=FR/part-0.parquet":            false,
+	} {
+		lt, ok := FindLakeTable(filepath.FromSlash(rel), filepath.Join(root, filepath.FromSlash(rel)))
+		if !ok || lt.Format != FormatDelta || lt.Path != filepath.Join("lake", "events") || lt.AbsPath != dir || lt.Current != current {
+			t.Errorf("FindLakeTable(%s) = %+v, %v", rel, lt, ok)
+		}
+	}
+	if _, ok := FindLakeTable("notes.csv", filepath.Join(root, "notes.csv")); ok {
+		t.Error("notes.csv is in a table")
+	}
+
+	// Older commits cleaned up after a checkpoint cannot be replayed.
+	if err := os.Remove(filepath.Join(dir, "_delta_log", "00000000000000000000.json")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readDelta(dir); err == nil {
+		t.Error("read a Delta log missing its first commit without a checkpoint")
+	}
+}
+
+func TestIcebergTable(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "warehouse", "orders")
+	location := "s3://bucket/warehouse/orders"
+	writeAvro := func(name, schema string, records ...map[string]any) {
+		t.Helper()
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Create(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		w, err := goavro.NewOCFWriter(goavro.OCFConfig{W: f, Schema: schema})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Append(records); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rowSchema := `{"type":"record","name":"row","fields":[{"name":"id","type":"long"},{"name":"customer","type":"string"},{"name":"raw","type":"bytes"}]}`
+	writeAvro("data/a.avro", rowSchema,
+		map[string]any{"id": int64(1), "customer": "Acme corporation", "raw": []byte{1}},
+		map[string]any{"id": int64(2), "customer": "Globex", "raw": []byte{2}})
+	writeAvro("data/b.avro", rowSchema, map[string]any{"id": int64(3), "customer": "Initech", "raw": []byte{3}})
+	writeAvro("data/old.avro", rowSchema, map[string]any{"id": int64(9), "customer": "Deleted", "raw": []byte{9}})
+
+	manifestSchema := `{"type":"record","name":"manifest_entry","fields":[{"name":"status","type":"int"},
+		{"name":"data_file","type":{"type":"record","name":"r2","fields":[{"name":"content","type":"int"},
+		{"name":"file_path","type":"string"},{"name":"file_format","type":"string"},{"name":"record_count","type":"long"}]}}]}`
+	entry := func(status int32, path string) map[string]any {
+		return map[string]any{"status": status, "data_file": map[string]any{
+			"content": int32(0), "file_path": location + "/" + path, "file_format": "AVRO", "record_count": int64(1)}}
+	}
+	writeAvro("metadata/m0.avro", manifestSchema, entry(1, "data/a.avro"), entry(2, "data/old.avro"), entry(0, "data/b.avro"))
+	writeAvro("metadata/snap-7.avro", `{"type":"record","name":"manifest_file","fields":[{"name":"manifest_path","type":"string"},{"name":"content","type":"int"}]}`,
+		map[string]any{"manifest_path": location + "/metadata/m0.avro", "content": int32(0)},
+		map[string]any{"manifest_path": location + "/metadata/deletes.avro", "content": int32(1)})
+
+	metadata := func(snapshot int) string {
+		return `{"format-version":2,"location":"` + location + `","current-snapshot-id":` + strconv.Itoa(snapshot) + `,
+			"current-schema-id":1,"schemas":[{"schema-id":0,"fields":[]},{"schema-id":1,"fields":[
+				{"id":1,"name":"id","type":"long"},{"id":2,"name":"customer","type":"string"},
+				{"id":3,"name":"raw","type":"binary"},{"id":4,"name":"ordered","type":"timestamptz"}]}],
+			"default-spec-id":0,"partition-specs":[{"spec-id":0,"fields":[{"name":"ordered_day","transform":"day","source-id":4}]}],
+			"snapshots":[{"snapshot-id":6,"manifest-list":"` + location + `/metadata/snap-6.avro"},
+				{"snapshot-id":7,"manifest-list":"` + location + `/metadata/snap-7.avro","summary":{"total-records":"3"}}]}`
+	}
+	for name, body := range map[string]string{
+		"00001-aaa.metadata.json": metadata(6),
+		"00002-bbb.metadata.json": metadata(7),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, "metadata", name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lt, ok := FindLakeTable(filepath.Join("warehouse", "orders", "metadata", "00002-bbb.metadata.json"), filepath.Join(dir, "metadata", "00002-bbb.metadata.json"))
+	if !ok || lt.Format != FormatIceberg || !lt.Current {
+		t.Fatalf("FindLakeTable = %+v, %v", lt, ok)
+	}
+	if lt, _ := FindLakeTable(filepath.Join("warehouse", "orders", "data", "a.avro"), filepath.Join(dir, "data", "a.avro")); lt.Current {
+		t.Error("a data file stands for the table")
+	}
+
+	reps, err := NewLakeTableLoader(cfg()).Load(context.Background(), "warehouse/orders", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows, files []string
+	for _, r := range reps {
+		if r.Meta["table_format"] != FormatIceberg || r.Meta["snapshot"] != "7" || r.Meta["partitions"] != "day(ordered)" {
+			t.Errorf("meta = %v", r.Meta)
+		}
+		switch {
+		case r.Modality == "table_row":
+			rows = append(rows, r.Meta["col.customer"])
+			files = append(files, r.Meta["data_file"])
+			if _, ok := r.Meta["col.raw"]; ok {
+				t.Errorf("binary column in row %v", r.Meta)
+			}
+		case r.Meta["kind"] == "file_summary":
+			if want := "Iceberg table warehouse/orders, snapshot 7, with 2 data files and 3 rows, 3 of them read. Partitioned by day(ordered)."; !strings.HasPrefix(r.Text, want) {
+				t.Errorf("summary = %q", r.Text)
+			}
+		case r.Meta["kind"] == "schema":
+			if r.Text != "Schema: id(numeric), customer(text), raw(binary), ordered(datetime)" {
+				t.Errorf("schema = %q", r.Text)
+			}
+		}
+	}
+	if !reflect.DeepEqual(rows, []string{"Acme corporation", "Globex", "Initech"}) ||
+		!reflect.DeepEqual(files, []string{"data/a.avro", "data/a.avro", "data/b.avro"}) {
+		t.Errorf("rows %v from %v", rows, files)
+	}
+
+	// Queries read the table like the loader.
+	snap, err := readLakeSnapshot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _, err := snap.readRows(cfg(), 2, false); err != nil || len(got) != 2 || got[1]["customer"] != "Globex" {
+		t.Errorf("readRows = %v, %v", got, err)
+	}
+}
@@ -0,0 +1,308 @@
+package tabular
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// BIFF12 records read from .xlsb files, see [MS-XLSB] 2.3.
+const (
+	brtRowHdr     = 0
+	brtCellBlank  = 1
+	brtCellRk     = 2
+	brtCellError  = 3
+	brtCellBool   = 4
+	brtCellReal   = 5
+	brtCellSt     = 6
+	brtCellIsst   = 7
+	brtFmlaString = 8
+	brtFmlaNum    = 9
+	brtFmlaBool   = 10
+	brtFmlaError  = 11
+	brtSSTItem    = 19
+	brtBundleSh   = 156
+)
+
+// xlsbErrors are the texts of error values.
+var xlsbErrors = map[byte]string{
+	0x00: "#NULL!", 0x07: "#DIV/0!", 0x0F: "#VALUE!", 0x17: "#REF!",
+	0x1D: "#NAME?", 0x24: "#NUM!", 0x2A: "#N/A", 0x2B: "#GETTING_DATA",
+}
+
+var (
+	errShortRecord = errors.New("truncated record")
+	errSheetFull   = errors.New("sheet full")
+)
+
+// readXLSB reads the sheets of the Excel binary workbook at absPath, up to
+// limit rows each unless it is 0. Cells are read as their values: dates
+// are the serial numbers Excel stores, e.g. "45292" for 2024-01-01.
+func readXLSB(absPath string, limit int) ([]*sheet, error) {
+	zr, err := zip.OpenReader(absPath)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	workbook := files["xl/workbook.bin"]
+	if workbook == nil {
+		return nil, fmt.Errorf("not an Excel binary workbook: no xl/workbook.bin")
+	}
+	targets, err := xlsbRelationships(files["xl/_rels/workbook.bin.rels"])
+	if err != nil {
+		return nil, err
+	}
+	var strs []string
+	if f := files["xl/sharedStrings.bin"]; f != nil {
+		err := readRecords(f, func(typ int, r *biffReader) error {
+			if typ == brtSSTItem {
+				r.next(1) // rich text and phonetic flags
+				strs = append(strs, r.wideString())
+			}
+			return r.err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("shared strings: %w", err)
+		}
+	}
+
+	type part struct{ name, file string }
+	var parts []part
+	err = readRecords(workbook, func(typ int, r *biffReader) error {
+		if typ == brtBundleSh {
+			r.next(8) // visibility and tab id
+			relID := r.wideString()
+			parts = append(parts, part{name: r.wideString(), file: targets[relID]})
+		}
+		return r.err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("workbook: %w", err)
+	}
+
+	var sheets []*sheet
+	for _, p := range parts {
+		f := files[p.file]
+		if f == nil {
+			continue // a chart sheet or a missing part
+		}
+		sh := &sheet{name: p.name}
+		n := 0
+		var cells []string
+		err := readRecords(f, func(typ int, r *biffReader) error {
+			switch {
+			case typ == brtRowHdr:
+				if n > 0 {
+					sh.add(n, cells)
+				}
+				if sh.full(limit) {
+					return errSheetFull
+				}
+				n, cells = int(r.uint32())+1, nil
+			case typ > brtCellBlank && typ <= brtFmlaError && n > 0:
+				col := int(r.uint32())
+				r.next(4) // style
+				v := xlsbValue(typ, r, strs)
+				if v == "" || col >= maxSheetColumns {
+					break
+				}
+				for len(cells) <= col {
+					cells = append(cells, "")
+				}
+				cells[col] = v
+			}
+			return r.err
+		})
+		if err != nil && err != errSheetFull {
+			return nil, fmt.Errorf("sheet %s: %w", p.name, err)
+		}
+		if n > 0 && !sh.full(limit) {
+			sh.add(n, cells)
+		}
+		sheets = append(sheets, sh)
+	}
+	return sheets, nil
+}
+
+// xlsbRelationships maps the relationship ids of the workbook to the parts
+// they name.
+func xlsbRelationships(f *zip.File) (map[string]string, error) {
+	if f == nil {
+		return nil, fmt.Errorf("not an Excel binary workbook: no xl/_rels/workbook.bin.rels")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := xml.NewDecoder(rc).Decode(&rels); err != nil {
+		return nil, fmt.Errorf("workbook relationships: %w", err)
+	}
+	targets := map[string]string{}
+	for _, rel := range rels.Relationships {
+		if strings.HasPrefix(rel.Target, "/") {
+			targets[rel.ID] = strings.TrimPrefix(rel.Target, "/")
+		} else {
+			targets[rel.ID] = path.Join("xl", rel.Target)
+		}
+	}
+	return targets, nil
+}
+
+// xlsbValue reads the value of a cell record of type typ.
+func xlsbValue(typ int, r *biffReader, strs []string) string {
+	switch typ {
+	case brtCellRk:
+		return formatXLSBNumber(rkNumber(r.uint32()))
+	case brtCellReal, brtFmlaNum:
+		return formatXLSBNumber(math.Float64frombits(binary.LittleEndian.Uint64(r.next(8))))
+	case brtCellSt, brtFmlaString:
+		return r.wideString()
+	case brtCellIsst:
+		if i := int(r.uint32()); i < len(strs) {
+			return strs[i]
+		}
+	case brtCellBool, brtFmlaBool:
+		if r.next(1)[0] != 0 {
+			return "TRUE"
+		}
+		return "FALSE"
+	case brtCellError, brtFmlaError:
+		return xlsbErrors[r.next(1)[0]]
+	}
+	return ""
+}
+
+// rkNumber decodes an RkNumber, a compressed integer or float.
+func rkNumber(rk uint32) float64 {
+	var f float64
+	if rk&2 != 0 {
+		f = float64(int32(rk) >> 2)
+	} else {
+		f = math.Float64frombits(uint64(rk&^3) << 32)
+	}
+	if rk&1 != 0 {
+		f /= 100
+	}
+	return f
+}
+
+func formatXLSBNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// readRecords calls fn with the type and a reader of each record of the
+// BIFF12 part f, stopping at the first error fn returns.
+func readRecords(f *zip.File, fn func(typ int, r *biffReader) error) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	br := bufio.NewReader(rc)
+	var buf []byte
+	for {
+		typ, err := readVarint(br, 2)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		size, err := readVarint(br, 4)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+		if cap(buf) < size {
+			buf = make([]byte, size)
+		}
+		buf = buf[:size]
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return err
+		}
+		if err := fn(typ, &biffReader{b: buf}); err != nil {
+			return err
+		}
+	}
+}
+
+// readVarint reads a record type or size, 7 bits a byte in up to maxBytes
+// bytes, the high bit of each telling whether another follows.
+func readVarint(br *bufio.Reader, maxBytes int) (int, error) {
+	v := 0
+	for i := 0; i < maxBytes; i++ {
+		b, err := br.ReadByte()
+		if err == io.EOF && i > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		v |= int(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	return v, nil
+}
+
+// biffReader reads the fields of a record, setting err, and returning zero
+// values, once the record is too short.
+type biffReader struct {
+	b   []byte
+	err error
+}
+
+func (r *biffReader) next(n int) []byte {
+	if r.err != nil || len(r.b) < n {
+		r.err = errShortRecord
+		return make([]byte, n)
+	}
+	p := r.b[:n]
+	r.b = r.b[n:]
+	return p
+}
+
+func (r *biffReader) uint32() uint32 {
+	return binary.LittleEndian.Uint32(r.next(4))
+}
+
+// wideString reads an XLWideString, UTF-16 characters after their count,
+// or "" for a null XLNullableWideString.
+func (r *biffReader) wideString() string {
+	n := r.uint32()
+	if n == math.MaxUint32 {
+		return ""
+	}
+	if r.err != nil || uint64(n)*2 > uint64(len(r.b)) {
+		r.err = errShortRecord
+		return ""
+	}
+	u := make([]uint16, n)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(r.next(2))
+	}
+	return string(utf16.Decode(u))
+}
@@ -0,0 +1,272 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+// VideoLoader indexes recordings by what is said in them. ffmpeg extracts the
+// audio track, whisper.cpp transcribes it, and the transcript is grouped into
+// fixed time windows, each becoming a text representation with start/end
+// timestamps. With keyframes enabled, frames sampled at a fixed interval are
+// added as image representations carrying a JPEG preview.
+type VideoLoader struct {
+	ffmpeg           string
+	whisper          string
+	whisperModel     string
+	whisperLanguage  string
+	segment          time.Duration
+	keyframes        bool
+	keyframeInterval time.Duration
+}
+
+// NewVideoLoader returns a VideoLoader configured from the media section.
+func NewVideoLoader(cfg config.MediaConfig) *VideoLoader {
+	vl := &VideoLoader{
+		ffmpeg:           cfg.FFmpegPath,
+		whisper:          cfg.WhisperPath,
+		whisperModel:     cfg.WhisperModel,
+		whisperLanguage:  cfg.WhisperLanguage,
+		segment:          time.Duration(cfg.SegmentSeconds) * time.Second,
+		keyframes:        cfg.Keyframes,
+		keyframeInterval: time.Duration(cfg.KeyframeInterval) * time.Second,
+	}
+	if vl.ffmpeg == "" {
+		vl.ffmpeg = "ffmpeg"
+	}
+	if vl.whisper == "" {
+		vl.whisper = "whisper-cli"
+	}
+	if vl.whisperLanguage == "" {
+		vl.whisperLanguage = "auto"
+	}
+	if vl.segment <= 0 {
+		vl.segment = 30 * time.Second
+	}
+	if vl.keyframeInterval <= 0 {
+		vl.keyframeInterval = 60 * time.Second
+	}
+	return vl
+}
+
+func (vl *VideoLoader) Extensions() []string {
+	return []string{".mp4", ".mkv", ".webm"}
+}
+
+// transcriptSegment is one timed piece of speech.
+type transcriptSegment struct {
+	start, end time.Duration
+	text       string
+}
+
+func (vl *VideoLoader) Load(ctx context.Context, relPath string, absPath string) ([]Representation, error) {
+	slog.Info("Loading video file", "relative_path", relPath, "absolute_path", absPath)
+
+	if vl.whisperModel == "" && !vl.keyframes {
+		slog.Warn("Skipping video: set media.whisper_model or media.keyframes to index videos", "path", relPath)
+		return nil, nil
+	}
+	if _, err := exec.LookPath(vl.ffmpeg); err != nil {
+		return nil, fmt.Errorf("video %s: ffmpeg not found (media.ffmpeg_path): %w", relPath, err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "semango-video-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var reps []Representation
+	if vl.whisperModel != "" {
+		segments, err := vl.transcribe(ctx, absPath, tmpDir)
+		if err != nil {
+			return nil, fmt.Errorf("video %s: %w", relPath, err)
+		}
+		for _, w := range groupTranscript(segments, vl.segment) {
+			reps = append(reps, Representation{
+				ID:       ChunkID(relPath, "text", w.start.Milliseconds()),
+				Path:     relPath,
+				Modality: "text",
+				Text:     w.text,
+				Meta:     videoMeta(relPath, "transcript", w.start, w.end),
+			})
+		}
+	}
+	if vl.keyframes {
+		frames, err := vl.extractKeyframes(ctx, relPath, absPath, tmpDir)
+		if err != nil {
+			return nil, fmt.Errorf("video %s: %w", relPath, err)
+		}
+		reps = append(reps, frames...)
+	}
+	slog.Debug("Created", "chunks", len(reps), "relPath", relPath)
+	return reps, nil
+}
+
+// transcribe extracts 16 kHz mono audio and runs whisper.cpp on it. A video
+// without an audio track yields no segments.
+func (vl *VideoLoader) transcribe(ctx context.Context, absPath, tmpDir string) ([]transcriptSegment, error) {
+	wav := filepath.Join(tmpDir, "audio.wav")
+	out, err := exec.CommandContext(ctx, vl.ffmpeg, "-nostdin", "-y", "-i", absPath,
+		"-vn", "-ac", "1", "-ar", "16000", "-c:a", "pcm_s16le", wav).CombinedOutput()
+	if err != nil {
+		if bytes.Contains(out, []byte("does not contain any stream")) || bytes.Contains(out, []byte("matches no streams")) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("extract audio: %w: %s", err, lastLine(out))
+	}
+
+	if _, err := exec.LookPath(vl.whisper); err != nil {
+		return nil, fmt.Errorf("whisper.cpp not found (media.whisper_path): %w", err)
+	}
+	prefix := filepath.Join(tmpDir, "transcript")
+	out, err = exec.CommandContext(ctx, vl.whisper, "-m", vl.whisperModel, "-f", wav,
+		"-l", vl.whisperLanguage, "-oj", "-of", prefix, "-np").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("transcribe: %w: %s", err, lastLine(out))
+	}
+	data, err := os.ReadFile(prefix + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("read transcript: %w", err)
+	}
+	return parseWhisperJSON(data)
+}
+
+// parseWhisperJSON reads the segments of a whisper.cpp "-oj" output file.
+func parseWhisperJSON(data []byte) ([]transcriptSegment, error) {
+	var doc struct {
+		Transcription []struct {
+			Offsets struct {
+				From int64 `json:"from"`
+				To   int64 `json:"to"`
+			} `json:"offsets"`
+			Text string `json:"text"`
+		} `json:"transcription"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse transcript: %w", err)
+	}
+	segments := make([]transcriptSegment, 0, len(doc.Transcription))
+	for _, t := range doc.Transcription {
+		text := strings.TrimSpace(t.Text)
+		if text == "" || isWhisperNonSpeech(text) {
+			continue
+		}
+		segments = append(segments, transcriptSegment{
+			start: time.Duration(t.Offsets.From) * time.Millisecond,
+			end:   time.Duration(t.Offsets.To) * time.Millisecond,
+			text:  text,
+		})
+	}
+	return segments, nil
+}
+
+// isWhisperNonSpeech matches annotations such as "[MUSIC]" or "(silence)"
+// that whisper emits for segments without speech.
+func isWhisperNonSpeech(text string) bool {
+	return (strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]")) ||
+		(strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")"))
+}
+
+// groupTranscript merges consecutive segments into windows of about window
+// length. A segment is never split, so windows end on segment boundaries.
+func groupTranscript(segments []transcriptSegment, window time.Duration) []transcriptSegment {
+	var (
+		out   []transcriptSegment
+		cur   transcriptSegment
+		parts []string
+	)
+	flush := func() {
+		if len(parts) > 0 {
+			cur.text = strings.Join(parts, " ")
+			out = append(out, cur)
+		}
+		parts = nil
+	}
+	for _, s := range segments {
+		if len(parts) > 0 && s.end-cur.start > window {
+			flush()
+		}
+		if len(parts) == 0 {
+			cur = transcriptSegment{start: s.start}
+		}
+		cur.end = s.end
+		parts = append(parts, s.text)
+	}
+	flush()
+	return out
+}
+
+// extractKeyframes samples one frame per keyframe interval as a 320px wide
+// JPEG and returns them as image representations.
+func (vl *VideoLoader) extractKeyframes(ctx context.Context, relPath, absPath, tmpDir string) ([]Representation, error) {
+	interval := vl.keyframeInterval.Seconds()
+	pattern := filepath.Join(tmpDir, "frame_%05d.jpg")
+	out, err := exec.CommandContext(ctx, vl.ffmpeg, "-nostdin", "-y", "-i", absPath,
+		"-vf", fmt.Sprintf("fps=1/%g,scale=320:-2", interval), "-q:v", "5", pattern).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("extract keyframes: %w: %s", err, lastLine(out))
+	}
+	files, err := filepath.Glob(filepath.Join(tmpDir, "frame_*.jpg"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	reps := make([]Representation, 0, len(files))
+	for i, f := range files {
+		img, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		// The fps filter emits frame i at the middle of its interval.
+		start := time.Duration((float64(i) + 0.5) * interval * float64(time.Second))
+		reps = append(reps, Representation{
+			ID:       ChunkID(relPath, "image", start.Milliseconds()),
+			Path:     relPath,
+			Modality: "image",
+			Preview:  img,
+			Meta:     videoMeta(relPath, "keyframe", start, start),
+		})
+	}
+	return reps, nil
+}
+
+func videoMeta(relPath, kind string, start, end time.Duration) map[string]string {
+	return map[string]string{
+		"source":    "VideoLoader",
+		"path":      relPath,
+		"kind":      kind,
+		"start":     strconv.FormatFloat(start.Seconds(), 'f', 3, 64),
+		"end":       strconv.FormatFloat(end.Seconds(), 'f', 3, 64),
+		"timestamp": formatTimestamp(start),
+	}
+}
+
+// formatTimestamp renders d as H:MM:SS for display and deep links.
+func formatTimestamp(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+	return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+}
+
+// lastLine returns the last non-empty line of tool output, which for ffmpeg
+// and whisper.cpp is usually the error message.
+func lastLine(out []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,54 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+func TestVideoLoader_TranscriptWindows(t *testing.T) {
+	data := []byte(`{"transcription": [
+		{"offsets": {"from": 0, "to": 4000}, "text": " Welcome to the weekly sync."},
+		{"offsets": {"from": 4000, "to": 9000}, "text": " [MUSIC]"},
+		{"offsets": {"from": 9000, "to": 28000}, "text": " First, the release is on track."},
+		{"offsets": {"from": 28000, "to": 41000}, "text": " Second, the budget review moved."},
+		{"offsets": {"from": 95000, "to": 99000}, "text": " Any questions?"}
+	]}`)
+	segments, err := parseWhisperJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 4 {
+		t.Fatalf("expected non-speech segment to be dropped, got %d segments", len(segments))
+	}
+
+	windows := groupTranscript(segments, 30*time.Second)
+	if len(windows) != 3 {
+		t.Fatalf("expected 3 windows, got %+v", windows)
+	}
+	if windows[0].text != "Welcome to the weekly sync. First, the release is on track." || windows[0].end != 28*time.Second {
+		t.Errorf("unexpected first window: %+v", windows[0])
+	}
+	if windows[1].start != 28*time.Second || windows[2].start != 95*time.Second {
+		t.Errorf("unexpected window starts: %v, %v", windows[1].start, windows[2].start)
+	}
+
+	meta := videoMeta("standup.mp4", "transcript", windows[2].start, windows[2].end)
+	if meta["timestamp"] != "0:01:35" || meta["start"] != "95.000" || meta["end"] != "99.000" {
+		t.Errorf("unexpected meta: %v", meta)
+	}
+}
+
+func TestNewVideoLoader_Defaults(t *testing.T) {
+	vl := NewVideoLoader(config.MediaConfig{})
+	if vl.ffmpeg != "ffmpeg" || vl.whisper != "whisper-cli" || vl.segment != 30*time.Second || vl.keyframeInterval != time.Minute {
+		t.Errorf("unexpected defaults: %+v", vl)
+	}
+	// Nothing to extract: the file is skipped without invoking any tool.
+	reps, err := vl.Load(context.Background(), "talk.mp4", "/nonexistent/talk.mp4")
+	if err != nil || reps != nil {
+		t.Errorf("expected skip, got %v, %v", reps, err)
+	}
+}
@@ -0,0 +1,243 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest/source"
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+// IndexTokenEnv holds an optional bearer token sent with http(s) bundle
+// uploads and downloads.
+const IndexTokenEnv = "SEMANGO_INDEX_TOKEN"
+
+// BundlePaths returns where the indexes of cfg live on disk.
+func BundlePaths(cfg *config.Config) storage.BundlePaths {
+	return storage.BundlePaths{
+		Lexical: cfg.Lexical.IndexPath,
+		Vector:  storage.SpaceIndexPath(cfg.VectorIndexPath(), ""),
+	}
+}
+
+// EmbeddingIdentity names the model that produced the vectors of cfg; see
+// config.EmbeddingConfig.Identity.
+func EmbeddingIdentity(cfg *config.Config) (provider, model string) {
+	return cfg.Embedding.Identity()
+}
+
+// PushIndex packs the local indexes into a bundle and uploads it to dest, an
+// s3://, gs:// or http(s):// URL. endpoint is only used for s3:// URLs on
+// S3-compatible servers.
+func PushIndex(ctx context.Context, cfg *config.Config, dest, endpoint string, m storage.BundleManifest) error {
+	f, err := os.CreateTemp("", "semango-bundle-*.tar.gz")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	m.CreatedAt = time.Now().UTC()
+	m.EmbeddingProvider, m.EmbeddingModel = EmbeddingIdentity(cfg)
+	if err := storage.WriteBundle(f, BundlePaths(cfg), m); err != nil {
+		return fmt.Errorf("write bundle: %w", err)
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	slog.Info("Uploading index bundle", "dest", dest, "bytes", size)
+	if isHTTPURL(dest) {
+		err = httpPut(ctx, dest, f, size)
+	} else {
+		var b *source.Bucket
+		var key string
+		b, key, err = source.ParseObjectURL(dest, endpoint)
+		if err == nil {
+			err = b.Put(ctx, key, f, size)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("upload %s: %w", dest, err)
+	}
+	return nil
+}
+
+// PullIndex downloads the bundle at src and replaces the local indexes with
+// it. A bundle embedded with a different provider or model is refused unless
+// force is set, since its vectors would not match the query embeddings.
+func PullIndex(ctx context.Context, cfg *config.Config, src, endpoint string, force bool) (storage.BundleManifest, error) {
+	slog.Info("Downloading index bundle", "src", src)
+	var m storage.BundleManifest
+	err := fetchBundle(ctx, src, endpoint, func(body io.Reader) error {
+		var err error
+		m, err = storage.ReadBundle(body, BundlePaths(cfg), acceptEmbedding(cfg, force))
+		return err
+	})
+	return m, err
+}
+
+// acceptEmbedding returns a storage.ReadBundle check that refuses bundles
+// embedded with another model than cfg's, unless force is set.
+func acceptEmbedding(cfg *config.Config, force bool) func(storage.BundleManifest) error {
+	return func(m storage.BundleManifest) error {
+		err := CheckEmbedding(cfg, m)
+		if err != nil && force {
+			slog.Warn("Installing index bundle built with a different embedding model", "error", err)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w (use --force to install anyway)", err)
+		}
+		return nil
+	}
+}
+
+// CheckEmbedding returns an error if the bundle described by m was embedded
+// with another provider or model than cfg uses, in which case its vectors
+// do not match cfg's query embeddings.
+func CheckEmbedding(cfg *config.Config, m storage.BundleManifest) error {
+	provider, model := EmbeddingIdentity(cfg)
+	if m.EmbeddingProvider == provider && m.EmbeddingModel == model {
+		return nil
+	}
+	return fmt.Errorf("bundle was embedded with %s/%s but this configuration uses %s/%s",
+		m.EmbeddingProvider, m.EmbeddingModel, provider, model)
+}
+
+// SnapshotDir is where named index snapshots are kept, as <name>.tar.gz
+// bundles next to the lexical index.
+func SnapshotDir(cfg *config.Config) string {
+	return filepath.Join(filepath.Dir(filepath.Clean(cfg.Lexical.IndexPath)), "snapshots")
+}
+
+var snapshotNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidSnapshotName reports whether name may name a snapshot: letters,
+// digits, '.', '_' and '-', so it cannot escape SnapshotDir.
+func ValidSnapshotName(name string) bool {
+	return snapshotNameRe.MatchString(name)
+}
+
+// OpenSnapshot unpacks the index bundle ref into dir for read-only
+// searching, leaving the local indexes alone. ref is the name of a snapshot
+// in SnapshotDir, a bundle file, or an s3://, gs:// or http(s):// URL as
+// accepted by PullIndex.
+func OpenSnapshot(ctx context.Context, cfg *config.Config, ref, endpoint, dir string) (storage.BundleManifest, storage.BundlePaths, error) {
+	var m storage.BundleManifest
+	var paths storage.BundlePaths
+	extract := func(body io.Reader) error {
+		var err error
+		m, paths, err = storage.ExtractBundle(body, dir)
+		return err
+	}
+
+	if strings.Contains(ref, "://") {
+		slog.Info("Downloading index snapshot", "src", ref)
+		err := fetchBundle(ctx, ref, endpoint, extract)
+		return m, paths, err
+	}
+	f, err := openSnapshotFile(cfg, ref)
+	if err != nil {
+		return m, paths, err
+	}
+	defer f.Close()
+	err = extract(f)
+	return m, paths, err
+}
+
+// openSnapshotFile opens the snapshot named ref in SnapshotDir, or else the
+// bundle file ref.
+func openSnapshotFile(cfg *config.Config, ref string) (*os.File, error) {
+	file := ref
+	if ValidSnapshotName(ref) {
+		if named := filepath.Join(SnapshotDir(cfg), ref+".tar.gz"); fileExists(named) {
+			file = named
+		}
+	}
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no snapshot named %q in %s and no such bundle file", ref, SnapshotDir(cfg))
+	}
+	return f, err
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// fetchBundle downloads the bundle at src and passes its body to read.
+func fetchBundle(ctx context.Context, src, endpoint string, read func(io.Reader) error) error {
+	if isHTTPURL(src) {
+		return httpGet(ctx, src, read)
+	}
+	b, key, err := source.ParseObjectURL(src, endpoint)
+	if err != nil {
+		return err
+	}
+	return b.Get(ctx, key, read)
+}
+
+func isHTTPURL(u string) bool {
+	return strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://")
+}
+
+func httpPut(ctx context.Context, url string, body io.Reader, size int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+	resp, err := doBundleRequest(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func httpGet(ctx context.Context, url string, read func(io.Reader) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := doBundleRequest(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return read(resp.Body)
+}
+
+// doBundleRequest sends req with the token from IndexTokenEnv, if any, and
+// turns non-2xx responses into errors.
+func doBundleRequest(req *http.Request) (*http.Response, error) {
+	if tok := os.Getenv(IndexTokenEnv); tok != "" {
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Redacted(), resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
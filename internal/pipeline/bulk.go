@@ -0,0 +1,171 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// BulkStats counts what a bulk session wrote.
+type BulkStats struct {
+	Files  int
+	Chunks int
+}
+
+// bulkSession holds the indexes a Manager keeps open in bulk mode and the
+// chunks waiting to be embedded.
+type bulkSession struct {
+	m       *Manager
+	size    int
+	bleve   *storage.BleveIndex
+	vec     storage.VectorIndex
+	spaces  map[string]storage.VectorIndex
+	pending []ingest.Representation
+	files   []string // files of pending
+	texts   int      // texts in pending
+	stats   BulkStats
+}
+
+// StartBulk switches m to bulk mode, for building an index from scratch as
+// fast as possible: the indexes are opened once and kept open, the chunks
+// of many files are embedded together, size texts at a time, and written to
+// the lexical index in batches of as many documents, and the vector indexes
+// are saved once, by FinishBulk, which also bumps the generation once.
+// Until FinishBulk returns nothing is guaranteed to be on disk, and an
+// interrupted run has to be started over.
+func (m *Manager) StartBulk(ctx context.Context, size int) error {
+	if m.bulk != nil {
+		return fmt.Errorf("bulk indexing already started")
+	}
+	b := &bulkSession{m: m, size: size, spaces: map[string]storage.VectorIndex{}}
+	if err := m.prepareLexical(ctx); err != nil {
+		return err
+	}
+	var err error
+	if b.bleve, err = storage.OpenBulkBleveIndex(m.cfg.Lexical.IndexPath, size); err != nil {
+		return err
+	}
+	b.bleve.SetCodeTokenFilter(m.cfg.Lexical.CodeTokenFilter)
+	if b.vec, err = m.openVectors(ctx, "", m.embedder.Dimension()); err != nil {
+		b.close()
+		return err
+	}
+	deferSave(b.vec)
+	for name, e := range m.spaces {
+		idx, err := m.openVectors(ctx, name, e.Dimension())
+		if err != nil {
+			b.close()
+			return err
+		}
+		deferSave(idx)
+		b.spaces[name] = idx
+	}
+	m.bulk = b
+	return nil
+}
+
+// deferSave makes a FAISS index keep its changes in memory until it is
+// closed. Remote vector stores batch their upserts anyway.
+func deferSave(idx storage.VectorIndex) {
+	if d, ok := idx.(interface{ DeferSave() }); ok {
+		d.DeferSave()
+	}
+}
+
+// FinishBulk indexes the chunks still pending, saves and closes the
+// indexes and leaves bulk mode. It must be called after StartBulk, also
+// when indexing failed, so that the files indexed so far are kept.
+func (m *Manager) FinishBulk(ctx context.Context) (BulkStats, error) {
+	b := m.bulk
+	if b == nil {
+		return BulkStats{}, fmt.Errorf("bulk indexing not started")
+	}
+	err := b.flush(ctx)
+	m.bulk = nil
+	if cerr := b.close(); err == nil {
+		err = cerr
+	}
+	if _, gerr := storage.BumpGeneration(storage.GenerationPath(m.cfg.Lexical.IndexPath)); gerr != nil {
+		util.FromContext(ctx).Warn("Failed to update index generation", "err", gerr)
+	}
+	return b.stats, err
+}
+
+// add queues the chunks of relPath and indexes the queue once it holds
+// size texts.
+func (b *bulkSession) add(ctx context.Context, relPath string, reps []ingest.Representation) error {
+	b.pending = append(b.pending, reps...)
+	b.files = append(b.files, relPath)
+	for _, r := range reps {
+		if r.Text != "" {
+			b.texts++
+		}
+	}
+	if b.texts < b.size {
+		return nil
+	}
+	return b.flush(ctx)
+}
+
+// flush embeds the pending chunks in one call per embedder and writes them
+// to the open indexes. If embedding fails, the pending files are dropped
+// and the error names how many there were.
+func (b *bulkSession) flush(ctx context.Context) error {
+	reps, files := b.pending, b.files
+	b.pending, b.files, b.texts = nil, nil, 0
+	if len(reps) == 0 {
+		return nil
+	}
+	logger := util.FromContext(ctx)
+	texts, idxMap := textsOf(reps)
+	if len(texts) > 0 {
+		vecs, err := b.m.embedder.Embed(ctx, texts)
+		if err != nil {
+			return fileError(StageEmbed, util.WrapError(err, "Failed to embed a bulk batch", slog.Int("files", len(files)), slog.String("first_file", files[0])), files...)
+		}
+		for j, v := range vecs {
+			reps[idxMap[j]].Vector = v
+		}
+	}
+	writeChunks(ctx, b.bleve, b.vec, reps)
+	for name, e := range b.m.spaces {
+		if len(texts) == 0 {
+			break
+		}
+		vecs, err := e.Embed(ctx, texts)
+		if err == nil {
+			err = upsertAll(ctx, b.spaces[name], reps, idxMap, vecs)
+		}
+		if err != nil {
+			logger.Error("vector space write error", "space", name, "files", len(files), "err", err)
+		}
+	}
+	b.stats.Files += len(files)
+	b.stats.Chunks += len(reps)
+	b.m.chunks.Add(int64(len(reps)))
+	logger.Debug("Indexed bulk batch", "files", len(files), "chunks", len(reps))
+	return nil
+}
+
+// close saves and closes every open index.
+func (b *bulkSession) close() error {
+	var err error
+	if b.bleve != nil {
+		err = b.bleve.Close()
+	}
+	if b.vec != nil {
+		if cerr := b.vec.Close(); err == nil {
+			err = cerr
+		}
+	}
+	for _, idx := range b.spaces {
+		if cerr := idx.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,179 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// dedupDistance returns the SimHash distance at which chunks are near
+// duplicates under files.dedup: 0 for exact duplicates only.
+func (m *Manager) dedupDistance() int {
+	if m.cfg.Files.Dedup != "near" {
+		return 0
+	}
+	if d := m.cfg.Files.DedupDistance; d > 0 {
+		return d
+	}
+	return storage.MaxDedupDistance
+}
+
+// dedup drops the text chunks of relPath that duplicate a chunk already
+// indexed, with files.dedup set, and returns the rest and the IDs dropped.
+// Paths whose duplicates lost their canonical chunk are noted for
+// TakeDedupOrphans.
+func (m *Manager) dedup(ctx context.Context, relPath string, reps []ingest.Representation) ([]ingest.Representation, []string, error) {
+	if m.cfg.Files.Dedup == "" {
+		return reps, nil, nil
+	}
+	var chunks []storage.DedupChunk
+	for _, r := range reps {
+		if r.Modality == "text" && r.Text != "" {
+			chunks = append(chunks, storage.DedupChunk{ID: r.ID, Fingerprint: ingest.FingerprintText(r.Text)})
+		}
+	}
+	store, err := m.openDedupStore()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer store.Close()
+	dups, orphaned, err := store.Add(relPath, chunks, m.dedupDistance())
+	if err != nil {
+		return nil, nil, util.WrapError(err, "Deduplication failed")
+	}
+	m.noteDedupOrphans(orphaned)
+	if len(dups) == 0 {
+		return reps, nil, nil
+	}
+	kept := reps[:0]
+	var dropped []string
+	for _, r := range reps {
+		if _, dup := dups[r.ID]; dup {
+			dropped = append(dropped, r.ID)
+		} else {
+			kept = append(kept, r)
+		}
+	}
+	util.FromContext(ctx).Info("Skipped duplicate chunks", "file", relPath, "duplicates", len(dropped))
+	return kept, dropped, nil
+}
+
+// forgetDuplicates removes relPath, whose indexed chunks were ids, from the
+// deduplication store.
+func (m *Manager) forgetDuplicates(relPath string, ids []string) error {
+	return m.updateDedup(func(store *storage.DedupStore) ([]string, error) {
+		return store.RemovePath(relPath, ids)
+	})
+}
+
+// forgetStaleChunks removes ids, chunks the new version of relPath no
+// longer has, from the deduplication store.
+func (m *Manager) forgetStaleChunks(relPath string, ids []string) error {
+	return m.updateDedup(func(store *storage.DedupStore) ([]string, error) {
+		return store.RemoveChunks(relPath, ids)
+	})
+}
+
+// updateDedup applies update, which returns orphaned paths, to the
+// deduplication store, if there is one.
+func (m *Manager) updateDedup(update func(*storage.DedupStore) ([]string, error)) error {
+	path := storage.DedupPath(m.cfg.Lexical.IndexPath)
+	if m.cfg.Files.Dedup == "" {
+		if _, err := os.Stat(path); err != nil {
+			return nil // never deduplicated
+		}
+	}
+	store, err := m.openDedupStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	orphaned, err := update(store)
+	if err != nil {
+		return util.WrapError(err, "Failed to update the deduplication store")
+	}
+	m.noteDedupOrphans(orphaned)
+	return nil
+}
+
+func (m *Manager) openDedupStore() (*storage.DedupStore, error) {
+	path := storage.DedupPath(m.cfg.Lexical.IndexPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return storage.OpenDedupStore(path, false)
+}
+
+func (m *Manager) noteDedupOrphans(paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	m.orphansMu.Lock()
+	defer m.orphansMu.Unlock()
+	if m.orphans == nil {
+		m.orphans = map[string]bool{}
+	}
+	for _, p := range paths {
+		m.orphans[p] = true
+	}
+}
+
+// TakeDedupOrphans returns and forgets the paths, sorted, whose duplicate
+// chunks lost the canonical chunk they were deduplicated against since the
+// last call, because it changed or its file was removed. Those chunks are
+// in no index until the paths are indexed again.
+func (m *Manager) TakeDedupOrphans() []string {
+	m.orphansMu.Lock()
+	defer m.orphansMu.Unlock()
+	paths := make([]string, 0, len(m.orphans))
+	for p := range m.orphans {
+		paths = append(paths, p)
+	}
+	m.orphans = nil
+	sort.Strings(paths)
+	return paths
+}
+
+// deleteChunks removes ids from the lexical and vector indexes, e.g.
+// chunks indexed before they became duplicates.
+func (m *Manager) deleteChunks(ctx context.Context, ids []string) error {
+	bleveIdx, err := m.openLexical(ctx)
+	if err != nil {
+		return err
+	}
+	err = bleveIdx.DeleteIDs(ids)
+	bleveIdx.Close()
+	if err != nil {
+		return err
+	}
+	return m.deleteVectors(ctx, ids)
+}
+
+// deleteVectors removes ids from the default and space vector indexes.
+func (m *Manager) deleteVectors(ctx context.Context, ids []string) error {
+	vecIdx, err := m.openVectors(ctx, "", m.embedder.Dimension())
+	if err != nil {
+		return err
+	}
+	defer vecIdx.Close()
+	if err := vecIdx.Delete(ctx, ids); err != nil {
+		return err
+	}
+	for name, e := range m.spaces {
+		spaceIdx, err := m.openVectors(ctx, name, e.Dimension())
+		if err != nil {
+			return err
+		}
+		err = spaceIdx.Delete(ctx, ids)
+		spaceIdx.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
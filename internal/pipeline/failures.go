@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"errors"
+	"os"
+
+	"github.com/omarkamali/semango/internal/ingest"
+)
+
+// Stages at which indexing a file can fail; see FileError.
+const (
+	StageLoad    = "load"    // the loader could not read or parse the file
+	StageProcess = "process" // a files.post_processors step failed
+	StageEmbed   = "embed"   // the embedder failed
+	StageIndex   = "index"   // the indexes could not be opened or written
+)
+
+// FileError is the failure of ProcessFile or IndexRepresentations to index
+// files. Its message is that of Err.
+type FileError struct {
+	Stage string
+	// Paths are the files that failed: the one being indexed or, in bulk
+	// mode, every file whose chunks were embedded together.
+	Paths []string
+	Err   error
+}
+
+func (e *FileError) Error() string { return e.Err.Error() }
+
+func (e *FileError) Unwrap() error { return e.Err }
+
+// fileError returns err as a FileError of paths failing at stage, unless
+// it is nil or already one.
+func fileError(stage string, err error, paths ...string) error {
+	var fe *FileError
+	if err == nil || errors.As(err, &fe) {
+		return err
+	}
+	return &FileError{Stage: stage, Paths: paths, Err: err}
+}
+
+// OnSkip makes m call fn with each file ProcessFile leaves out and why,
+// one of SkipNoLoader, SkipBinary, SkipTooLarge, SkipEmpty or
+// SkipLakeTablePart, and returns m.
+func (m *Manager) OnSkip(fn func(relPath, reason string)) *Manager {
+	m.onSkip = fn
+	return m
+}
+
+func (m *Manager) skip(relPath, reason string) {
+	if m.onSkip != nil {
+		m.onSkip(relPath, reason)
+	}
+}
+
+// tooLarge reports whether the file at absPath is larger than
+// files.max_file_size or, for loader l, the size the loader reads.
+func (m *Manager) tooLarge(l ingest.Loader, absPath string) bool {
+	limit := m.cfg.Files.MaxFileSize
+	if _, ok := l.(*ingest.CodeLoader); ok && (limit <= 0 || limit > maxCodeFileSize) {
+		limit = maxCodeFileSize
+	}
+	if limit <= 0 {
+		return false
+	}
+	fi, err := os.Stat(absPath)
+	return err == nil && fi.Size() > limit
+}
@@ -0,0 +1,34 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/omarkamali/semango/internal/ingest/tabular"
+)
+
+// processLakeTable indexes the Delta Lake or Iceberg table t the file at
+// relPath is part of, under the path of the table directory. Only the file
+// standing for the current version of the table loads it, so that its data
+// files, stale ones included, and its logs are not indexed on their own;
+// the others are skipped.
+func (m *Manager) processLakeTable(ctx context.Context, relPath string, t tabular.LakeTable) error {
+	if !t.Current {
+		m.skip(relPath, SkipLakeTablePart)
+		return nil
+	}
+	reps, err := tabular.NewLakeTableLoader(m.cfg.Tabular).Load(ctx, t.Path, t.AbsPath)
+	if err != nil {
+		return fileError(StageLoad, err, t.Path)
+	}
+	// The previous version may have had other rows sampled. Bulk indexes
+	// start empty.
+	if m.bulk == nil {
+		if err := m.RemovePath(ctx, t.Path); err != nil {
+			return fileError(StageIndex, err, t.Path)
+		}
+	}
+	if len(reps) == 0 {
+		m.skip(t.Path, SkipEmpty)
+	}
+	return m.IndexRepresentations(ctx, t.Path, reps)
+}
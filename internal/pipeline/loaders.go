@@ -0,0 +1,180 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/ingest/tabular"
+)
+
+// loaderNames are the names of the loaders, as used in files.rules, in the
+// order they are tried for an extension.
+var loaderNames = []string{
+	"text", "markdown", "latex", "rst", "code", "pdf", "image", "office", "epub", "email", "notebook",
+	"config", "schema", "hcl", "video", "csv", "json", "parquet", "avro", "orc", "sqlite", "excel", "archive",
+}
+
+// maxCodeFileSize is the size above which the code loader skips a file.
+const maxCodeFileSize = 5 * 1024 * 1024
+
+// loaderParams are the settings loaders are built with: those of the files
+// section, overridden by the rules matching a file.
+type loaderParams struct {
+	chunkSize    int
+	overlap      int
+	chunking     string
+	stripImports bool
+}
+
+// namedLoader is a loader with its name in loaderNames.
+type namedLoader struct {
+	name string
+	ingest.Loader
+}
+
+// newLoader builds the loader called name with p, or returns nil for an
+// unknown name. Loaders that do not chunk text ignore the chunk settings.
+func (m *Manager) newLoader(name string, p loaderParams) ingest.Loader {
+	l := m.buildLoader(name, p)
+	if cs, ok := l.(ingest.ChunkingSetter); ok && p.chunking != "" && p.chunking != ingest.ChunkFixed {
+		c := m.Chunking()
+		c.Strategy = p.chunking
+		cs.SetChunking(c)
+	}
+	return l
+}
+
+// Chunking returns the chunking of files.chunking. Semantic chunking
+// embeds sentences with the default embedder; a Manager without one, such
+// as a planner (see NewPlanner), cuts whole sentences instead.
+func (m *Manager) Chunking() ingest.Chunking {
+	c := ingest.Chunking{
+		Strategy:   m.cfg.Files.Chunking,
+		Percentile: m.cfg.Files.SemanticPercentile,
+	}
+	if m.embedder != nil {
+		c.Embed = func(texts []string) ([][]float32, error) {
+			return m.embedder.Embed(context.Background(), texts)
+		}
+	}
+	return c
+}
+
+func (m *Manager) buildLoader(name string, p loaderParams) ingest.Loader {
+	cfg := m.cfg
+	switch name {
+	case "text":
+		return ingest.NewTextLoader(p.chunkSize, p.overlap)
+	case "markdown":
+		return ingest.NewMarkdownLoader(p.chunkSize, p.overlap)
+	case "latex":
+		return ingest.NewLaTeXLoader(p.chunkSize, p.overlap)
+	case "rst":
+		return ingest.NewRSTLoader(p.chunkSize, p.overlap)
+	case "code":
+		return ingest.NewCodeLoader(p.stripImports, maxCodeFileSize)
+	case "pdf":
+		return &ingest.PDFLoader{}
+	case "image":
+		return &ingest.ImageLoader{}
+	case "office":
+		return ingest.NewOfficeLoader(p.chunkSize, p.overlap)
+	case "epub":
+		return ingest.NewEPUBLoader(p.chunkSize, p.overlap)
+	case "email":
+		return ingest.NewEmailLoader()
+	case "notebook":
+		return ingest.NewNotebookLoader(p.chunkSize, p.overlap)
+	case "config":
+		return ingest.NewConfigFileLoader(p.chunkSize)
+	case "schema":
+		return ingest.NewSchemaFileLoader(p.chunkSize)
+	case "hcl":
+		return ingest.NewHCLLoader(p.chunkSize)
+	case "video":
+		return ingest.NewVideoLoader(cfg.Media)
+	case "csv":
+		return tabular.NewCSVLoader(cfg.Tabular)
+	case "json":
+		return tabular.NewJSONLoader(cfg.Tabular)
+	case "parquet":
+		return tabular.NewParquetLoader(cfg.Tabular)
+	case "avro":
+		return tabular.NewAvroLoader(cfg.Tabular)
+	case "orc":
+		return tabular.NewORCLoader(cfg.Tabular)
+	case "sqlite":
+		return tabular.NewSQLiteLoader(cfg.Tabular)
+	case "excel":
+		return tabular.NewExcelLoader(cfg.Tabular)
+	case "archive":
+		// Archives route their contents back through the default loaders.
+		return ingest.NewArchiveLoader(m.loaderForExt, ingest.ArchiveLimits{
+			MaxTotalBytes: cfg.Files.ArchiveMaxBytes,
+			MaxDepth:      cfg.Files.ArchiveMaxDepth,
+		})
+	}
+	return nil
+}
+
+// validateRules checks the patterns and loader names of files.rules.
+func validateRules(rules []config.FileRule) error {
+	for i, r := range rules {
+		if !doublestar.ValidatePattern(r.Path) {
+			return fmt.Errorf("rule %d: invalid path pattern %q", i+1, r.Path)
+		}
+		if r.Loader != "" && !contains(loaderNames, r.Loader) {
+			return fmt.Errorf("rule %d (%s): unknown loader %q", i+1, r.Path, r.Loader)
+		}
+	}
+	return nil
+}
+
+// loaderFor returns the loader for relPath, whose extension is ext, and its
+// name: the one chosen by extension, unless the rules matching relPath name
+// another or change its settings. It returns nil when no loader reads the
+// file.
+func (m *Manager) loaderFor(relPath, ext string) (ingest.Loader, string) {
+	var name string
+	var l ingest.Loader
+	for _, nl := range m.loaders {
+		if contains(nl.Extensions(), ext) {
+			name, l = nl.name, nl.Loader
+			break
+		}
+	}
+	rule, ok := m.cfg.Files.RuleFor(relPath)
+	if !ok {
+		return l, name
+	}
+	if rule.Loader != "" {
+		name = rule.Loader
+	}
+	if name == "" {
+		return nil, ""
+	}
+	p := m.defaults
+	if rule.ChunkSize > 0 {
+		p.chunkSize = rule.ChunkSize
+	}
+	if rule.ChunkOverlap > 0 {
+		p.overlap = rule.ChunkOverlap
+	}
+	if rule.Chunking != "" {
+		p.chunking = rule.Chunking
+	}
+	p.stripImports = rule.StripImports
+	return m.newLoader(name, p), name
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
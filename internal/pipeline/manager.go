@@ -2,78 +2,418 @@ package pipeline
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 
-	"github.com/blevesearch/go-faiss"
 	"github.com/omarkamali/semango/internal/config"
 	"github.com/omarkamali/semango/internal/ingest"
 	"github.com/omarkamali/semango/internal/ingest/tabular"
 	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
 )
 
 // Manager glues: filesystem crawler -> loaders -> embedder -> indexes.
 type Manager struct {
-	cfg      *config.Config
-	embedder ingest.Embedder
-	loaders  []ingest.Loader
+	cfg       *config.Config
+	embedding config.EmbeddingConfig // of embedder and spaces; see SetEmbedders
+	embedder  ingest.Embedder
+	spaces    map[string]ingest.Embedder // extra vector spaces, by name
+	loaders   []namedLoader              // built with defaults, in loaderNames order
+	defaults  loaderParams
+	post      ingest.Chain
+	cfgErr    error // invalid files.post_processors or files.rules, reported on indexing
+	bulk      *bulkSession
+	onSkip    func(relPath, reason string) // see OnSkip
+	chunks    atomic.Int64                 // written to the indexes; see ChunksIndexed
+
+	prepareOnce sync.Once
+	prepareErr  error // see prepareLexical
+
+	orphansMu sync.Mutex
+	orphans   map[string]bool // see TakeDedupOrphans
+
+	// modelsMu guards models, the vector indexes whose fingerprint was
+	// checked; see checkIndexModel.
+	modelsMu           sync.Mutex
+	models             map[string]bool
+	allowModelMismatch bool
+
+	collection string             // whose indexes m writes; see WithQuota
+	quota      config.QuotaConfig // of collection
+	quotaMu    sync.Mutex
+	quotaPaths map[string]bool // indexed paths, for max_documents; see reserveQuota
 }
 
 func NewManager(cfg *config.Config, embedder ingest.Embedder) *Manager {
+	m := &Manager{cfg: cfg, embedding: cfg.Embedding, embedder: embedder,
+		defaults: loaderParams{chunkSize: cfg.Files.ChunkSize, overlap: cfg.Files.ChunkOverlap, chunking: cfg.Files.Chunking}}
 	// register loaders once
-	ls := []ingest.Loader{
-		ingest.NewTextLoader(cfg.Files.ChunkSize, cfg.Files.ChunkOverlap),
-		ingest.NewCodeLoader(false, 5*1024*1024),
-		&ingest.PDFLoader{}, &ingest.ImageLoader{},
-		tabular.NewCSVLoader(cfg.Tabular),
-		tabular.NewJSONLoader(cfg.Tabular),
-		tabular.NewParquetLoader(cfg.Tabular),
-		tabular.NewSQLiteLoader(cfg.Tabular),
-		tabular.NewExcelLoader(cfg.Tabular),
+	for _, name := range loaderNames {
+		m.loaders = append(m.loaders, namedLoader{name, m.newLoader(name, m.defaults)})
+	}
+	var err error
+	if m.post, err = ingest.NewChain(cfg.Files.PostProcessors); err != nil {
+		m.cfgErr = util.WrapError(err, "Invalid files.post_processors")
+	} else if err := validateRules(cfg.Files.Rules); err != nil {
+		m.cfgErr = util.WrapError(err, "Invalid files.rules")
+	} else if err := storage.ValidateAnalyzers(cfg.Lexical.Analyzers); err != nil {
+		m.cfgErr = util.WrapError(err, "Invalid lexical.analyzers")
 	}
-	return &Manager{cfg: cfg, embedder: embedder, loaders: ls}
+	return m
+}
+
+// WithSpaces makes m also write vectors into the given named vector spaces
+// (see embedding.spaces) and returns m.
+func (m *Manager) WithSpaces(spaces map[string]ingest.Embedder) *Manager {
+	m.spaces = spaces
+	return m
+}
+
+// SetEmbedders replaces the default and space embedders, built from ec,
+// e.g. after the embedding model was switched. It must not be called while
+// indexing.
+func (m *Manager) SetEmbedders(ec config.EmbeddingConfig, embedder ingest.Embedder, spaces map[string]ingest.Embedder) {
+	m.embedding = ec
+	m.embedder = embedder
+	m.spaces = spaces
+	m.modelsMu.Lock()
+	m.models = nil
+	m.modelsMu.Unlock()
+}
+
+// AllowModelMismatch makes m write to vector indexes built with another
+// embedding model, with a warning, instead of failing with
+// storage.ErrModelMismatch, and returns m.
+func (m *Manager) AllowModelMismatch() *Manager {
+	m.allowModelMismatch = true
+	return m
 }
 
+// ChunksIndexed returns the number of chunks m wrote to the indexes so far,
+// for progress reports. In bulk mode chunks count once their batch is
+// embedded.
+func (m *Manager) ChunksIndexed() int64 {
+	return m.chunks.Load()
+}
+
+// Err reports configuration errors, such as an unknown post-processor or
+// loader, that make every file fail to index.
+func (m *Manager) Err() error {
+	return m.cfgErr
+}
+
+// loaderForExt returns the default loader for ext, ignoring files.rules.
 func (m *Manager) loaderForExt(ext string) ingest.Loader {
 	for _, l := range m.loaders {
-		for _, e := range l.Extensions() {
-			if e == ext {
-				return l
-			}
+		if contains(l.Extensions(), ext) {
+			return l.Loader
 		}
 	}
 	return nil
 }
 
 // ProcessFile ingests one path (relative & absolute) into vector + lexical indexes.
+// The loader is chosen by extension and files.rules. Remote sources use URLs
+// as relPath, so when its extension has no loader the one of the downloaded
+// file is tried. Files of Delta Lake and Iceberg tables index the whole
+// table, see processLakeTable. Files that fail return a *FileError; those
+// left out are reported to the OnSkip function.
 func (m *Manager) ProcessFile(ctx context.Context, relPath, absPath string) error {
-	ext := filepath.Ext(relPath)
-	l := m.loaderForExt(ext)
-	if l == nil {
-		slog.Warn("No suitable loader found for file", "path", relPath, "extension", ext)
+	if m.cfgErr != nil {
+		return m.cfgErr
+	}
+	if t, ok := tabular.FindLakeTable(relPath, absPath); ok {
+		return m.processLakeTable(ctx, relPath, t)
+	}
+	l, _, ext, mimeType, skip := m.chooseLoader(relPath, absPath)
+	switch skip {
+	case SkipNoLoader:
+		util.FromContext(ctx).Warn("No suitable loader found for file", "path", relPath, "extension", ext)
+		m.skip(relPath, skip)
+		return nil
+	case SkipBinary:
+		util.FromContext(ctx).Warn("Binary file, skipping", "path", relPath, "mime", mimeType)
+		m.skip(relPath, skip)
+		return nil
+	}
+	if m.tooLarge(l, absPath) {
+		util.FromContext(ctx).Warn("File too large, skipping", "path", relPath)
+		m.skip(relPath, SkipTooLarge)
 		return nil
 	}
 	reps, err := l.Load(ctx, relPath, absPath)
 	if err != nil {
-		return err
+		return fileError(StageLoad, err, relPath)
 	}
 	if len(reps) == 0 {
+		m.skip(relPath, SkipEmpty)
+	}
+	setMIME(reps, relPath, mimeType)
+	return m.IndexRepresentations(ctx, relPath, reps)
+}
+
+// fileLoader returns the loader of a file, its name and the extension it
+// was chosen by; see ProcessFile.
+func (m *Manager) fileLoader(relPath, absPath string) (ingest.Loader, string, string) {
+	ext := filepath.Ext(relPath)
+	l, name := m.loaderFor(relPath, ext)
+	if l == nil && filepath.Ext(absPath) != ext {
+		ext = filepath.Ext(absPath)
+		l, name = m.loaderFor(relPath, ext)
+	}
+	return l, name, ext
+}
+
+// lexicalSettings returns the lexical index settings of the configuration.
+func (m *Manager) lexicalSettings() storage.LexicalSettings {
+	return storage.LexicalSettings{Analyzers: m.cfg.Lexical.Analyzers, CodeTokenFilter: m.cfg.Lexical.CodeTokenFilter}
+}
+
+// prepareLexical rebuilds the lexical index, once per Manager, if it was
+// built with other lexical.analyzers or lexical.code_token_filter; see
+// storage.PrepareLexicalIndex.
+func (m *Manager) prepareLexical(ctx context.Context) error {
+	m.prepareOnce.Do(func() {
+		rebuilt, err := storage.PrepareLexicalIndex(ctx, m.cfg.Lexical.IndexPath, m.lexicalSettings())
+		if err != nil {
+			m.prepareErr = err
+			return
+		}
+		if rebuilt {
+			if _, err := storage.BumpGeneration(storage.GenerationPath(m.cfg.Lexical.IndexPath)); err != nil {
+				util.FromContext(ctx).Warn("Failed to update index generation", "err", err)
+			}
+		}
+	})
+	return m.prepareErr
+}
+
+// openLexical opens the lexical index for writing.
+func (m *Manager) openLexical(ctx context.Context) (*storage.BleveIndex, error) {
+	if err := m.prepareLexical(ctx); err != nil {
+		return nil, err
+	}
+	bleveIdx, err := storage.OpenOrCreateBleveIndex(m.cfg.Lexical.IndexPath)
+	if err != nil {
+		return nil, err
+	}
+	bleveIdx.SetCodeTokenFilter(m.cfg.Lexical.CodeTokenFilter)
+	return bleveIdx, nil
+}
+
+// openVectors opens the vector index of space ("" for the default one),
+// whose embedder produces vectors of dimension dim, in the configured
+// vector store.
+func (m *Manager) openVectors(ctx context.Context, space string, dim int) (storage.VectorIndex, error) {
+	path := storage.SpaceIndexPath(m.cfg.VectorIndexPath(), space)
+	if err := m.checkIndexModel(ctx, path, space, dim); err != nil {
+		return nil, err
+	}
+	return storage.OpenVectorIndex(ctx, m.cfg.VectorStore, m.cfg.Vector, path, space, dim)
+}
+
+// checkIndexModel makes sure the vector index of space at path is written
+// with the model it was built with, once per index: an index without a
+// fingerprint gets that of the model of space, whose vectors have
+// dimension dim, and one with another model's is refused with
+// storage.ErrModelMismatch unless AllowModelMismatch was called. Forced
+// writes keep the old fingerprint, since the index then mixes models.
+func (m *Manager) checkIndexModel(ctx context.Context, path, space string, dim int) error {
+	m.modelsMu.Lock()
+	defer m.modelsMu.Unlock()
+	if m.models[path] {
 		return nil
 	}
+	ec := m.embedding
+	if space != "" {
+		ec, _ = ec.ForSpace(space)
+	}
+	provider, model := ec.Identity()
+	want := storage.IndexModel{Provider: provider, Model: model, Dimension: dim}
+	_, ok, err := storage.ReadIndexModel(path)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if err := storage.WriteIndexModel(path, want); err != nil {
+			return err
+		}
+	} else if err := storage.CheckIndexModel(path, want); err != nil {
+		if !m.allowModelMismatch || !errors.Is(err, storage.ErrModelMismatch) {
+			return err
+		}
+		util.FromContext(ctx).Warn("Writing to a vector index built with another embedding model", "err", err)
+	}
+	if m.models == nil {
+		m.models = map[string]bool{}
+	}
+	m.models[path] = true
+	return nil
+}
 
-	// Embed textual reps (only those with Text)
-	var texts []string
-	var idxMap []int
-	for i, r := range reps {
-		if r.Text != "" {
-			texts = append(texts, r.Text)
-			idxMap = append(idxMap, i)
+// RemovePath deletes every chunk of relPath from the lexical and vector
+// indexes, e.g. for a file deleted since the last run or before re-indexing
+// a file whose chunk count may have shrunk.
+func (m *Manager) RemovePath(ctx context.Context, relPath string) error {
+	bleveIdx, err := m.openLexical(ctx)
+	if err != nil {
+		return err
+	}
+	defer bleveIdx.Close()
+	ids, err := bleveIdx.DeletePath(relPath)
+	if err != nil {
+		return err
+	}
+	m.releaseQuota(relPath)
+	// A file whose chunks were all duplicates has none in the indexes but
+	// is still known to the deduplication store.
+	if err := m.forgetDuplicates(relPath, ids); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := m.deleteVectors(ctx, ids); err != nil {
+		return err
+	}
+	logger := util.FromContext(ctx)
+	if _, err := storage.BumpGeneration(storage.GenerationPath(m.cfg.Lexical.IndexPath)); err != nil {
+		logger.Warn("Failed to update index generation", "err", err)
+	}
+	logger.Info("Removed", "file", relPath, "chunks", len(ids))
+	return nil
+}
+
+// ReplacePath indexes reps as the new version of relPath, then deletes
+// the chunks of its previous version that reps no longer have. Until reps
+// are written the previous version stays searchable, and a failure leaves
+// it in place.
+func (m *Manager) ReplacePath(ctx context.Context, relPath string, reps []ingest.Representation) error {
+	if m.cfgErr != nil {
+		return m.cfgErr
+	}
+	if m.bulk != nil {
+		return m.IndexRepresentations(ctx, relPath, reps) // bulk indexes start empty
+	}
+	bleveIdx, err := m.openLexical(ctx)
+	if err != nil {
+		return err
+	}
+	old, err := bleveIdx.PathIDs(relPath)
+	bleveIdx.Close()
+	if err != nil {
+		return err
+	}
+	written, err := m.indexRepresentations(ctx, relPath, reps)
+	if err != nil {
+		return err
+	}
+	if len(written) == 0 {
+		m.releaseQuota(relPath) // all of it goes
+	}
+	keep := make(map[string]bool, len(written))
+	for _, id := range written {
+		keep[id] = true
+	}
+	var stale []string
+	for _, id := range old {
+		if !keep[id] {
+			stale = append(stale, id)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	if err := m.deleteChunks(ctx, stale); err != nil {
+		return err
+	}
+	if err := m.forgetStaleChunks(relPath, stale); err != nil {
+		return err
+	}
+	logger := util.FromContext(ctx)
+	if _, err := storage.BumpGeneration(storage.GenerationPath(m.cfg.Lexical.IndexPath)); err != nil {
+		logger.Warn("Failed to update index generation", "err", err)
+	}
+	logger.Info("Removed stale chunks", "file", relPath, "chunks", len(stale))
+	return nil
+}
+
+// IndexGitHistory indexes the commit history of the repository containing
+// dir, as configured in the git section.
+func (m *Manager) IndexGitHistory(ctx context.Context, dir string) error {
+	reps, err := ingest.NewGitLoader(m.cfg.Git, m.cfg.Files.ChunkSize).LoadHistory(ctx, dir)
+	if err != nil {
+		return err
+	}
+	return m.IndexRepresentations(ctx, "git history", reps)
+}
+
+// IndexRepresentations embeds the textual representations and writes them to
+// the lexical and vector indexes. Chunk IDs are upserted, so indexing the same
+// representations twice leaves a single copy of each chunk.
+func (m *Manager) IndexRepresentations(ctx context.Context, relPath string, reps []ingest.Representation) error {
+	_, err := m.indexRepresentations(ctx, relPath, reps)
+	return err
+}
+
+// indexRepresentations is IndexRepresentations, which also returns the IDs
+// of the chunks written outside bulk mode.
+func (m *Manager) indexRepresentations(ctx context.Context, relPath string, reps []ingest.Representation) (ids []string, err error) {
+	if m.cfgErr != nil {
+		return nil, m.cfgErr
+	}
+	reserved, err := m.reserveQuota(ctx, relPath)
+	if err != nil {
+		return nil, fileError(StageIndex, err, relPath)
+	}
+	if reserved {
+		// A new path that ends up without chunks does not count.
+		defer func() {
+			if err != nil || (m.bulk == nil && len(ids) == 0) {
+				m.releaseQuota(relPath)
+			}
+		}()
+	}
+	reps, err = m.post.Process(ctx, reps)
+	if err != nil {
+		return nil, fileError(StageProcess, util.WrapError(err, "Chunk post-processing failed", slog.String("path", relPath)), relPath)
+	}
+	if len(reps) == 0 {
+		return nil, nil
+	}
+	if m.cfg.Files.DetectLanguage {
+		ingest.DetectLanguages(reps)
+	}
+	ingest.ScoreQuality(reps)
+	ingest.AssignParents(reps, m.cfg.Files.ParentChunkSize)
+	reps, dropped, err := m.dedup(ctx, relPath, reps)
+	if err != nil {
+		return nil, fileError(StageIndex, err, relPath)
+	}
+	// Bulk indexes start empty, so only incremental runs can hold earlier
+	// copies of the duplicates.
+	if len(dropped) > 0 && m.bulk == nil {
+		if err := m.deleteChunks(ctx, dropped); err != nil {
+			return nil, fileError(StageIndex, err, relPath)
 		}
 	}
+	if len(reps) == 0 {
+		return nil, nil
+	}
+	ingest.LinkNeighbours(reps)
+	if m.bulk != nil {
+		return nil, fileError(StageIndex, m.bulk.add(ctx, relPath, reps), relPath)
+	}
+
+	// Embed textual reps (only those with Text)
+	texts, idxMap := textsOf(reps)
 	if len(texts) > 0 {
 		vecs, err := m.embedder.Embed(ctx, texts)
 		if err != nil {
-			return err
+			return nil, fileError(StageEmbed, err, relPath)
 		}
 		for j, v := range vecs {
 			reps[idxMap[j]].Vector = v
@@ -81,30 +421,91 @@ func (m *Manager) ProcessFile(ctx context.Context, relPath, absPath string) erro
 	}
 
 	// Open indexes once
-	bleveIdx, err := storage.OpenOrCreateBleveIndex(m.cfg.Lexical.IndexPath)
+	bleveIdx, err := m.openLexical(ctx)
 	if err != nil {
-		return err
+		return nil, fileError(StageIndex, err, relPath)
 	}
 	defer bleveIdx.Close()
 
-	faissPath := filepath.Join("semango", "index", "faiss.index")
-	vecIdx, err := storage.NewFaissVectorIndex(ctx, faissPath, m.embedder.Dimension(), faiss.MetricInnerProduct)
+	vecIdx, err := m.openVectors(ctx, "", m.embedder.Dimension())
 	if err != nil {
-		return err
+		return nil, fileError(StageIndex, err, relPath)
 	}
 	defer vecIdx.Close()
 
-	// Index loop
+	logger := util.FromContext(ctx)
+	writeChunks(ctx, bleveIdx, vecIdx, reps)
+	m.chunks.Add(int64(len(reps)))
+	for name, e := range m.spaces {
+		// A candidate model must not hold up the default index, so its
+		// failures are logged like other per-chunk index errors.
+		if err := m.writeSpace(ctx, name, e, reps, idxMap, texts); err != nil {
+			logger.Error("vector space write error", "space", name, "file", relPath, "err", err)
+		}
+	}
+	if _, err := storage.BumpGeneration(storage.GenerationPath(m.cfg.Lexical.IndexPath)); err != nil {
+		logger.Warn("Failed to update index generation", "err", err)
+	}
+	logger.Info("Indexed", "file", relPath, "chunks", len(reps))
+	ids = make([]string, len(reps))
+	for i, r := range reps {
+		ids[i] = r.ID
+	}
+	return ids, nil
+}
+
+// textsOf returns the non-empty texts of reps, which are embedded, and the
+// position of each in reps.
+func textsOf(reps []ingest.Representation) (texts []string, idxMap []int) {
+	for i, r := range reps {
+		if r.Text != "" {
+			texts = append(texts, r.Text)
+			idxMap = append(idxMap, i)
+		}
+	}
+	return texts, idxMap
+}
+
+// writeChunks writes reps to the lexical index and their vectors to the
+// default vector index. Failures are logged per chunk.
+func writeChunks(ctx context.Context, bleveIdx *storage.BleveIndex, vecIdx storage.VectorIndex, reps []ingest.Representation) {
+	logger := util.FromContext(ctx)
 	for _, r := range reps {
 		if err := bleveIdx.IndexDocument(r.ID, r.Text, r.Meta); err != nil {
-			slog.Error("bleve index error", "id", r.ID, "err", err)
+			logger.Error("bleve index error", "id", r.ID, "err", err)
 		}
 		if r.Vector != nil {
 			if err := vecIdx.Upsert(ctx, r.ID, r.Vector); err != nil {
-				slog.Error("faiss upsert error", "id", r.ID, "err", err)
+				logger.Error("vector upsert error", "id", r.ID, "err", err)
 			}
 		}
 	}
-	slog.Info("Indexed", "file", relPath, "chunks", len(reps))
+}
+
+// writeSpace embeds texts, the text of reps[idxMap[i]], with the embedder of
+// a vector space and upserts the vectors into that space's index.
+func (m *Manager) writeSpace(ctx context.Context, space string, e ingest.Embedder, reps []ingest.Representation, idxMap []int, texts []string) error {
+	if len(texts) == 0 {
+		return nil
+	}
+	vecs, err := e.Embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+	vecIdx, err := m.openVectors(ctx, space, e.Dimension())
+	if err != nil {
+		return err
+	}
+	defer vecIdx.Close()
+	return upsertAll(ctx, vecIdx, reps, idxMap, vecs)
+}
+
+// upsertAll upserts vecs[j] under the ID of reps[idxMap[j]].
+func upsertAll(ctx context.Context, vecIdx storage.VectorIndex, reps []ingest.Representation, idxMap []int, vecs [][]float32) error {
+	for j, v := range vecs {
+		if err := vecIdx.Upsert(ctx, reps[idxMap[j]].ID, v); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,82 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+// flakyEmbedder returns constant vectors, or fail once it is set.
+type flakyEmbedder struct{ fail error }
+
+func (e *flakyEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.fail != nil {
+		return nil, e.fail
+	}
+	vecs := make([][]float32, len(texts))
+	for i := range vecs {
+		vecs[i] = []float32{1, 0}
+	}
+	return vecs, nil
+}
+
+func (e *flakyEmbedder) Dimension() int { return 2 }
+
+func pathIDs(t *testing.T, cfg *config.Config, path string) []string {
+	t.Helper()
+	idx, err := storage.OpenOrCreateBleveIndex(cfg.Lexical.IndexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	ids, err := idx.PathIDs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestReplacePath_FailureKeepsPreviousVersion(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{}
+	cfg.Lexical.IndexPath = filepath.Join(dir, "bleve")
+	cfg.Vector.IndexPath = filepath.Join(dir, "vectors.faiss")
+	embedder := &flakyEmbedder{}
+	m := NewManager(cfg, embedder)
+	splitter := ingest.NewTextLoader(10, 0)
+	ctx := context.Background()
+
+	if err := m.ReplacePath(ctx, "notes/a.txt", splitter.Split("notes/a.txt", "first version of a longer note")); err != nil {
+		t.Fatal(err)
+	}
+	before := pathIDs(t, cfg, "notes/a.txt")
+	if len(before) < 2 {
+		t.Fatalf("expected several chunks, got %v", before)
+	}
+
+	// A re-POST whose embedding fails must leave the previous version alone.
+	embedder.fail = errors.New("embedder timeout")
+	if err := m.ReplacePath(ctx, "notes/a.txt", splitter.Split("notes/a.txt", "short")); err == nil {
+		t.Fatal("expected the failing replacement to be reported")
+	}
+	if got := pathIDs(t, cfg, "notes/a.txt"); len(got) != len(before) {
+		t.Fatalf("failed replacement changed the chunks: %v, want %v", got, before)
+	}
+
+	// A successful one drops the chunks past the end of the shorter text.
+	embedder.fail = nil
+	reps := splitter.Split("notes/a.txt", "short")
+	if err := m.ReplacePath(ctx, "notes/a.txt", reps); err != nil {
+		t.Fatal(err)
+	}
+	if got := pathIDs(t, cfg, "notes/a.txt"); len(got) != 1 || got[0] != reps[0].ID {
+		t.Errorf("after replacement got chunks %v, want [%s]", got, reps[0].ID)
+	}
+}
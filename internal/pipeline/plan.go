@@ -0,0 +1,89 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/ingest/tabular"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// Reasons a planned file would add nothing to the indexes.
+const (
+	SkipNoLoader = "no loader"
+	SkipBinary   = "binary"
+	SkipTooLarge = "too large"
+	SkipEmpty    = "no text"
+	// SkipLakeTablePart is for the files of Delta Lake and Iceberg tables
+	// but the one the table is indexed with.
+	SkipLakeTablePart = "part of a lakehouse table"
+)
+
+// FilePlan is what indexing a file would add, as worked out by PlanFile.
+type FilePlan struct {
+	Loader string // name of the loader, as in files.rules; "" without one
+	Chunks int
+	// Tokens estimates the tokens of the chunk texts sent to the embedder,
+	// see ingest.EstimateTokens.
+	Tokens int
+	// Skipped says why the file would add nothing, e.g. SkipNoLoader; ""
+	// for files with chunks.
+	Skipped string
+}
+
+// NewPlanner returns a Manager for PlanFile only. It has no embedder, so
+// semantic chunking cuts whole sentences instead (see Manager.Chunking) and
+// the chunk counts it reports for it are estimates.
+func NewPlanner(cfg *config.Config) *Manager {
+	return NewManager(cfg, nil)
+}
+
+// PlanFile loads and chunks a file like ProcessFile, post-processors
+// included, and reports what indexing it would add. It embeds nothing and
+// writes no index. Chunks that files.dedup would drop as duplicates are
+// counted.
+func (m *Manager) PlanFile(ctx context.Context, relPath, absPath string) (FilePlan, error) {
+	if m.cfgErr != nil {
+		return FilePlan{}, m.cfgErr
+	}
+	var (
+		l    ingest.Loader
+		name string
+		skip string
+	)
+	if t, ok := tabular.FindLakeTable(relPath, absPath); ok {
+		// Like processLakeTable.
+		if !t.Current {
+			return FilePlan{Skipped: SkipLakeTablePart}, nil
+		}
+		l, name = tabular.NewLakeTableLoader(m.cfg.Tabular), t.Format
+		relPath, absPath = t.Path, t.AbsPath
+	} else {
+		l, name, _, _, skip = m.chooseLoader(relPath, absPath)
+	}
+	if skip != "" {
+		return FilePlan{Skipped: skip}, nil
+	}
+	plan := FilePlan{Loader: name}
+	if m.tooLarge(l, absPath) {
+		plan.Skipped = SkipTooLarge
+		return plan, nil
+	}
+	reps, err := l.Load(ctx, relPath, absPath)
+	if err != nil {
+		return plan, err
+	}
+	if reps, err = m.post.Process(ctx, reps); err != nil {
+		return plan, util.WrapError(err, "Chunk post-processing failed")
+	}
+	texts, _ := textsOf(reps)
+	for _, t := range texts {
+		plan.Tokens += ingest.EstimateTokens(t)
+	}
+	plan.Chunks = len(reps)
+	if plan.Chunks == 0 {
+		plan.Skipped = SkipEmpty
+	}
+	return plan, nil
+}
@@ -0,0 +1,184 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+// States of an index run.
+const (
+	ProgressRunning = "running"
+	ProgressDone    = "done"
+	ProgressFailed  = "failed"
+)
+
+// Progress is how far an index run has come. The index command keeps it in
+// a file next to the indexes (see ProgressPath), where a server on the same
+// indexes reads it for GET /api/v1/index/progress.
+type Progress struct {
+	State     string    `json:"state"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// FilesTotal and BytesTotal are those of the files found to index; 0
+	// when not known, as for remote sources, which are not listed ahead.
+	FilesTotal int   `json:"files_total,omitempty"`
+	BytesTotal int64 `json:"bytes_total,omitempty"`
+	Files      int   `json:"files"`
+	Bytes      int64 `json:"bytes"`
+	Chunks     int64 `json:"chunks"` // written to the indexes
+	// FilesPerSecond and ChunksPerSecond are averages over the run.
+	FilesPerSecond  float64 `json:"files_per_second"`
+	ChunksPerSecond float64 `json:"chunks_per_second"`
+	// ETASeconds estimates the time left from the bytes done so far; nil
+	// when the total is not known.
+	ETASeconds *int64 `json:"eta_seconds,omitempty"`
+	Error      string `json:"error,omitempty"` // why a failed run failed
+}
+
+const progressFile = "progress.json"
+
+// ProgressPath returns the progress file of index runs into the indexes
+// whose lexical index lives at lexicalIndexPath.
+func ProgressPath(lexicalIndexPath string) string {
+	return filepath.Join(storage.IndexDir(lexicalIndexPath), progressFile)
+}
+
+// ReadProgress loads the progress stored at path. ok is false if no index
+// run wrote one.
+func ReadProgress(path string) (p Progress, ok bool, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, false, nil
+	}
+	if err != nil {
+		return p, false, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, false, err
+	}
+	return p, true, nil
+}
+
+// ProgressTracker follows an index run. It is safe for concurrent use.
+type ProgressTracker struct {
+	mu       sync.Mutex
+	p        Progress
+	path     string // "" to keep no file
+	interval time.Duration
+	last     time.Time
+	chunks   func() int64
+	report   func(Progress)
+}
+
+// NewProgressTracker starts following an index run. chunks returns the
+// chunks written so far, e.g. Manager.ChunksIndexed. report, if not nil, is
+// called with the progress at most every interval and once more when the
+// run finishes, and the progress is written to path, unless it is "", at
+// the same times.
+func NewProgressTracker(path string, interval time.Duration, chunks func() int64, report func(Progress)) *ProgressTracker {
+	now := time.Now()
+	t := &ProgressTracker{
+		p:        Progress{State: ProgressRunning, StartedAt: now.UTC(), UpdatedAt: now.UTC()},
+		path:     path,
+		interval: interval,
+		last:     now,
+		chunks:   chunks,
+		report:   report,
+	}
+	t.publish(now)
+	return t
+}
+
+// Found records the files found to index and their total size.
+func (t *ProgressTracker) Found(files int, bytes int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.p.FilesTotal, t.p.BytesTotal = files, bytes
+}
+
+// FileDone records a file of size bytes as indexed.
+func (t *ProgressTracker) FileDone(bytes int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.p.Files++
+	t.p.Bytes += bytes
+	if now := time.Now(); now.Sub(t.last) >= t.interval {
+		t.last = now
+		t.publish(now)
+	}
+}
+
+// Finish records the end of the run, failed if err is not nil, and reports
+// the final progress.
+func (t *ProgressTracker) Finish(err error) Progress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.p.State = ProgressDone
+	if err != nil {
+		t.p.State, t.p.Error = ProgressFailed, err.Error()
+	}
+	t.publish(time.Now())
+	return t.p
+}
+
+// Progress returns the progress so far.
+func (t *ProgressTracker) Progress() Progress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.update(time.Now())
+	return t.p
+}
+
+// update works out the rates and the time left at now.
+func (t *ProgressTracker) update(now time.Time) {
+	t.p.UpdatedAt = now.UTC()
+	if t.chunks != nil {
+		t.p.Chunks = t.chunks()
+	}
+	elapsed := now.Sub(t.p.StartedAt).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	t.p.FilesPerSecond = math.Round(float64(t.p.Files)/elapsed*10) / 10
+	t.p.ChunksPerSecond = math.Round(float64(t.p.Chunks)/elapsed*10) / 10
+	t.p.ETASeconds = nil
+	if t.p.State == ProgressRunning && t.p.Bytes > 0 && t.p.BytesTotal >= t.p.Bytes {
+		left := int64(elapsed * float64(t.p.BytesTotal-t.p.Bytes) / float64(t.p.Bytes))
+		t.p.ETASeconds = &left
+	}
+}
+
+// publish reports the progress and writes it to the progress file. A file
+// that cannot be written only costs the server its view of the run.
+func (t *ProgressTracker) publish(now time.Time) {
+	t.update(now)
+	if t.report != nil {
+		t.report(t.p)
+	}
+	if t.path == "" {
+		return
+	}
+	data, err := json.Marshal(t.p)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(t.path), progressFile+".*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil && cerr == nil {
+		os.Rename(tmp.Name(), t.path)
+	}
+}
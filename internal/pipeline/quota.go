@@ -0,0 +1,92 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/search"
+)
+
+// QuotaError refuses a write that would take a collection past a limit of
+// its quota (collections.<name>.quota).
+type QuotaError struct {
+	Collection string
+	Limit      string // "max_documents" or "max_bytes"
+	Max        int64
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("collection %q is at its %s quota of %d", e.Collection, e.Limit, e.Max)
+}
+
+// WithQuota makes m, which writes the indexes of the named collection,
+// refuse writes beyond the max_documents and max_bytes of quota, and
+// returns m.
+func (m *Manager) WithQuota(collection string, quota config.QuotaConfig) *Manager {
+	m.collection = collection
+	m.quota = quota
+	return m
+}
+
+// reserveQuota checks that the collection may take relPath and counts
+// relPath as indexed. It reports whether relPath was new, for
+// releaseQuota after a failed write.
+//
+// The indexed paths are read from the lexical index once and then kept up
+// to date by m's own writes, so paths another process indexes meanwhile
+// count from the next Manager on.
+func (m *Manager) reserveQuota(ctx context.Context, relPath string) (bool, error) {
+	q := m.quota
+	if q.MaxBytes > 0 && search.IndexBytes(m.cfg) >= q.MaxBytes {
+		return false, &QuotaError{Collection: m.collection, Limit: "max_bytes", Max: q.MaxBytes}
+	}
+	if q.MaxDocuments <= 0 {
+		return false, nil
+	}
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+	if m.quotaPaths == nil {
+		paths, err := m.indexedPaths(ctx)
+		if err != nil {
+			return false, err
+		}
+		m.quotaPaths = paths
+	}
+	if m.quotaPaths[relPath] {
+		return false, nil
+	}
+	if len(m.quotaPaths) >= q.MaxDocuments {
+		return false, &QuotaError{Collection: m.collection, Limit: "max_documents", Max: int64(q.MaxDocuments)}
+	}
+	m.quotaPaths[relPath] = true
+	return true, nil
+}
+
+// releaseQuota stops counting relPath, which has no chunks in the indexes
+// (any more).
+func (m *Manager) releaseQuota(relPath string) {
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+	delete(m.quotaPaths, relPath)
+}
+
+// indexedPaths returns the paths with chunks in the lexical index. Bulk
+// indexes start empty.
+func (m *Manager) indexedPaths(ctx context.Context) (map[string]bool, error) {
+	paths := map[string]bool{}
+	if m.bulk != nil {
+		return paths, nil
+	}
+	bleveIdx, err := m.openLexical(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer bleveIdx.Close()
+	err = bleveIdx.EachDocument(ctx, []string{"path"}, func(_ string, values map[string]interface{}) {
+		if path, _ := values["path"].(string); path != "" {
+			paths[path] = true
+		}
+	})
+	return paths, err
+}
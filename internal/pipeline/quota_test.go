@@ -0,0 +1,61 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+)
+
+func TestQuota(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{}
+	cfg.Lexical.IndexPath = filepath.Join(dir, "bleve")
+	cfg.Vector.IndexPath = filepath.Join(dir, "vectors.faiss")
+	embedder := &flakyEmbedder{fail: errors.New("embedder timeout")}
+	m := NewManager(cfg, embedder).WithQuota("team", config.QuotaConfig{MaxDocuments: 2})
+	splitter := ingest.NewTextLoader(1000, 0)
+	ctx := context.Background()
+	index := func(path string) error {
+		return m.ReplacePath(ctx, path, splitter.Split(path, "text of "+path))
+	}
+
+	// A path whose write failed does not count.
+	if err := index("x.md"); err == nil {
+		t.Fatal("expected the failing write to be reported")
+	}
+	embedder.fail = nil
+	for _, path := range []string{"a.md", "b.md"} {
+		if err := index(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var quota *QuotaError
+	if err := index("c.md"); !errors.As(err, &quota) || quota.Collection != "team" || quota.Limit != "max_documents" || quota.Max != 2 {
+		t.Fatalf("third document: got %v", err)
+	}
+	// Paths already indexed can be replaced, and removing one makes room.
+	if err := index("a.md"); err != nil {
+		t.Errorf("re-indexing a counted path: %v", err)
+	}
+	if err := m.RemovePath(ctx, "b.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := index("c.md"); err != nil {
+		t.Errorf("after a removal: %v", err)
+	}
+
+	// A fresh Manager counts the paths already in the index.
+	m = NewManager(cfg, &flakyEmbedder{}).WithQuota("team", config.QuotaConfig{MaxDocuments: 2})
+	if err := index("d.md"); !errors.As(err, &quota) {
+		t.Errorf("new Manager: got %v, want a quota error", err)
+	}
+
+	m = NewManager(cfg, &flakyEmbedder{}).WithQuota("team", config.QuotaConfig{MaxBytes: 1})
+	if err := index("a.md"); !errors.As(err, &quota) || quota.Limit != "max_bytes" || quota.Max != 1 {
+		t.Errorf("over max_bytes: got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+// SnapshotInfo describes a snapshot in SnapshotDir.
+type SnapshotInfo struct {
+	Name     string                 `json:"name"`
+	Path     string                 `json:"path"`
+	Size     int64                  `json:"size"`
+	Manifest storage.BundleManifest `json:"manifest"`
+	// Err is set for a file that is not a readable bundle, or, when
+	// verified, one whose checksums do not match.
+	Err error `json:"-"`
+}
+
+// CreateSnapshot packs the indexes of cfg into the snapshot name in
+// SnapshotDir. An empty name defaults to the generation and the current
+// time, e.g. "g42-20240501T120000Z". The bundle is written to a temporary
+// file and renamed into place, so a snapshot is either complete or absent;
+// an existing snapshot is never overwritten.
+func CreateSnapshot(cfg *config.Config, name string, m storage.BundleManifest) (string, storage.BundleManifest, error) {
+	m.CreatedAt = time.Now().UTC()
+	if name == "" {
+		g, err := storage.ReadGeneration(storage.GenerationPath(cfg.Lexical.IndexPath))
+		if err != nil {
+			return "", m, err
+		}
+		name = fmt.Sprintf("g%d-%s", g.Number, m.CreatedAt.Format("20060102T150405Z"))
+	}
+	if !ValidSnapshotName(name) {
+		return "", m, fmt.Errorf("invalid snapshot name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	dir := SnapshotDir(cfg)
+	dst := filepath.Join(dir, name+".tar.gz")
+	if _, err := os.Stat(dst); err == nil {
+		return "", m, fmt.Errorf("snapshot %q already exists", name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", m, err
+	}
+
+	f, err := os.CreateTemp(dir, "."+name+"-*.tmp")
+	if err != nil {
+		return "", m, err
+	}
+	defer os.Remove(f.Name())
+	m.EmbeddingProvider, m.EmbeddingModel = EmbeddingIdentity(cfg)
+	err = storage.WriteBundle(f, BundlePaths(cfg), m)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", m, err
+	}
+	// Report the manifest as written, with its generation and checksums.
+	if m, err = readManifest(f.Name()); err != nil {
+		return "", m, err
+	}
+	if err := os.Link(f.Name(), dst); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return "", m, fmt.Errorf("snapshot %q already exists", name)
+		}
+		return "", m, err
+	}
+	return name, m, nil
+}
+
+// ListSnapshots returns the snapshots in SnapshotDir, newest first. With
+// verify, every snapshot is read in full and its checksums checked;
+// otherwise only manifests are read.
+func ListSnapshots(cfg *config.Config, verify bool) ([]SnapshotInfo, error) {
+	dir := SnapshotDir(cfg)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []SnapshotInfo
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".tar.gz")
+		if !ok || !e.Type().IsRegular() || !ValidSnapshotName(name) {
+			continue
+		}
+		info := SnapshotInfo{Name: name, Path: filepath.Join(dir, e.Name())}
+		if fi, err := e.Info(); err == nil {
+			info.Size = fi.Size()
+		}
+		if verify {
+			info.Manifest, info.Err = verifyFile(info.Path)
+		} else {
+			info.Manifest, info.Err = readManifest(info.Path)
+		}
+		out = append(out, info)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Manifest.CreatedAt.After(out[j].Manifest.CreatedAt)
+	})
+	return out, nil
+}
+
+// RestoreSnapshot installs the snapshot named ref in SnapshotDir, or the
+// bundle file ref, after checking its checksums. With an empty dir it
+// replaces the indexes of cfg, like PullIndex; otherwise it restores into
+// dir, which must not exist or be empty, as dir/bleve and dir/faiss.index
+// for a configuration whose lexical.index_path is dir/bleve. Snapshots
+// embedded with another model than cfg's are refused unless force is set.
+func RestoreSnapshot(cfg *config.Config, ref, dir string, force bool) (storage.BundleManifest, error) {
+	paths := BundlePaths(cfg)
+	if dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return storage.BundleManifest{}, err
+		}
+		if len(entries) > 0 {
+			return storage.BundleManifest{}, fmt.Errorf("%s is not empty", dir)
+		}
+		paths = storage.BundlePaths{Lexical: filepath.Join(dir, "bleve"), Vector: storage.VectorIndexPath(dir, "")}
+	}
+	f, err := openSnapshotFile(cfg, ref)
+	if err != nil {
+		return storage.BundleManifest{}, err
+	}
+	defer f.Close()
+	return storage.ReadBundle(f, paths, acceptEmbedding(cfg, force))
+}
+
+func readManifest(path string) (storage.BundleManifest, error) {
+	return withFile(path, storage.ReadBundleManifest)
+}
+
+func verifyFile(path string) (storage.BundleManifest, error) {
+	return withFile(path, storage.VerifyBundle)
+}
+
+func withFile(path string, read func(io.Reader) (storage.BundleManifest, error)) (storage.BundleManifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return storage.BundleManifest{}, err
+	}
+	defer f.Close()
+	return read(f)
+}
@@ -0,0 +1,61 @@
+package pipeline
+
+import (
+	"github.com/omarkamali/semango/internal/ingest"
+)
+
+// textLoaders are the loaders that read text, which binary files given to
+// them by their extension are skipped for.
+var textLoaders = map[string]bool{
+	"text": true, "markdown": true, "latex": true, "rst": true, "code": true, "email": true, "notebook": true,
+	"config": true, "schema": true, "hcl": true, "csv": true, "json": true,
+}
+
+// chooseLoader returns the loader of a file, its name and the extension it
+// was chosen by, like fileLoader, checked against the content of the file
+// (see ingest.Sniff): text files without a loader for their extension, such
+// as extensionless ones, go to the text loader, and binary files are only
+// given to loaders of binary formats. skip is SkipNoLoader or SkipBinary
+// when the file has no loader. mimeType is the detected MIME type, "" if
+// the file could not be read, in which case the loader reports why.
+func (m *Manager) chooseLoader(relPath, absPath string) (l ingest.Loader, name, ext, mimeType, skip string) {
+	l, name, ext = m.fileLoader(relPath, absPath)
+	mimeType, binary, err := ingest.Sniff(absPath)
+	switch {
+	case err != nil:
+		if l == nil {
+			skip = SkipNoLoader
+		}
+		return l, name, ext, "", skip
+	case l == nil && binary:
+		skip = SkipBinary
+	case l == nil:
+		// The text loader by the rules matching relPath.
+		if l, name = m.loaderFor(relPath, ".txt"); l == nil {
+			skip = SkipNoLoader
+		}
+	case binary && textLoaders[name]:
+		l, name, skip = nil, "", SkipBinary
+	}
+	return l, name, ext, mimeType, skip
+}
+
+// setMIME records mimeType as the "mime" metadata of the chunks of the file
+// at relPath that have none. Chunks of files inside it, as of archives,
+// keep theirs.
+func setMIME(reps []ingest.Representation, relPath, mimeType string) {
+	if mimeType == "" {
+		return
+	}
+	for i := range reps {
+		if reps[i].Path != relPath {
+			continue
+		}
+		if reps[i].Meta == nil {
+			reps[i].Meta = map[string]string{}
+		}
+		if _, ok := reps[i].Meta["mime"]; !ok {
+			reps[i].Meta["mime"] = mimeType
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Package quickstart holds the demo corpus and configuration used by
+// `semango quickstart`.
+package quickstart
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+//go:embed corpus
+var corpus embed.FS
+
+// CorpusDir is where the demo corpus is written, relative to the quickstart
+// directory.
+const CorpusDir = "docs"
+
+// LocalModel is the embedding model used by the quickstart: small enough to
+// download in a few seconds, and run on a CPU.
+const LocalModel = "onnx-models/all-MiniLM-L6-v2-onnx"
+
+// SampleQueries are suggested first searches over the demo corpus.
+var SampleQueries = []string{
+	"how do I roll back a bad release",
+	"what to do when the tracking API is down",
+	"rotate leaked api keys",
+	"when is the coffee machine unavailable",
+}
+
+// WriteCorpus writes the demo corpus into dir/CorpusDir. Files that already
+// exist are left alone, so local edits survive a second run. It returns the
+// number of files written.
+func WriteCorpus(dir string) (int, error) {
+	dst := filepath.Join(dir, CorpusDir)
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return 0, err
+	}
+	entries, err := fs.ReadDir(corpus, "corpus")
+	if err != nil {
+		return 0, err
+	}
+	written := 0
+	for _, e := range entries {
+		data, err := corpus.ReadFile("corpus/" + e.Name())
+		if err != nil {
+			return written, err
+		}
+		f, err := os.OpenFile(filepath.Join(dst, e.Name()), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return written, err
+		}
+		_, err = f.Write(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, nil
+}
+
+// Config returns the quickstart configuration: the defaults, restricted to
+// the demo corpus, embedded with LocalModel and served on localhost only.
+func Config() *config.Config {
+	cfg := config.GetDefaultConfig()
+	cfg.Embedding.Provider = "local"
+	cfg.Embedding.LocalModelPath = LocalModel
+	cfg.Files.Include = []string{CorpusDir + "/**/*.md"}
+	cfg.Server.Host = "127.0.0.1"
+	cfg.Plugins = nil
+	return cfg
+}
@@ -0,0 +1,49 @@
+package quickstart
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+func TestWriteCorpus(t *testing.T) {
+	dir := t.TempDir()
+	n, err := WriteCorpus(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Fatal("no corpus files written")
+	}
+
+	// Local edits survive a second run.
+	edited := filepath.Join(dir, CorpusDir, "office.md")
+	if err := os.WriteFile(edited, []byte("# Mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := WriteCorpus(dir); err != nil || n != 0 {
+		t.Fatalf("second run wrote %d files, err %v", n, err)
+	}
+	if data, _ := os.ReadFile(edited); string(data) != "# Mine\n" {
+		t.Errorf("edited file overwritten: %q", data)
+	}
+}
+
+func TestConfigValidates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "semango.yml")
+	if err := config.WriteConfig(path, Config()); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path, "")
+	if err != nil {
+		t.Fatalf("quickstart config does not validate: %v", err)
+	}
+	if cfg.Embedding.Provider != "local" || cfg.Embedding.LocalModelPath != LocalModel {
+		t.Errorf("embedding = %+v", cfg.Embedding)
+	}
+	if len(cfg.Files.Include) != 1 || cfg.Files.Include[0] != "docs/**/*.md" {
+		t.Errorf("include = %v", cfg.Files.Include)
+	}
+}
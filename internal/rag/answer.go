@@ -0,0 +1,178 @@
+// Package rag answers questions from search results: it retrieves chunks
+// with the Searcher, packs them into a prompt as numbered sources, and asks
+// a chat model for an answer that cites them.
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/search"
+)
+
+// Defaults for zero values in config.LLMConfig.
+const (
+	DefaultTopK       = 8
+	DefaultMaxContext = 6000 // tokens
+)
+
+// noSourcesAnswer is returned without calling the model when retrieval
+// finds nothing.
+const noSourcesAnswer = "I could not find anything in the index that answers this question."
+
+const systemPrompt = `You answer questions using only the numbered sources provided.
+Cite the sources that support each statement with their numbers in square brackets, e.g. [1] or [2][3].
+If the sources do not contain the answer, say so instead of guessing.
+Answer in the language of the question.`
+
+// ErrModel wraps errors from the chat model, as opposed to retrieval.
+var ErrModel = errors.New("LLM call failed")
+
+// Retriever finds the chunks a question is answered from. *search.Searcher
+// implements it.
+type Retriever interface {
+	Search(ctx context.Context, query string, topK int, opts search.Options) ([]search.Result, error)
+}
+
+// ChatModel completes a conversation of a system and a user message.
+type ChatModel interface {
+	Complete(ctx context.Context, system, user string) (string, error)
+	Name() string
+}
+
+// Source is a chunk given to the model, numbered as it may be cited.
+type Source struct {
+	N     int               `json:"n"`
+	ID    string            `json:"id"` // Chunk ID
+	Path  string            `json:"path"`
+	Score float64           `json:"score"`
+	Text  string            `json:"text"` // As included in the prompt, possibly truncated
+	Meta  map[string]string `json:"meta,omitempty"`
+	Link  string            `json:"link,omitempty"`
+}
+
+// Answer is a grounded answer and the sources it was written from.
+type Answer struct {
+	Answer  string   `json:"answer"`
+	Sources []Source `json:"sources"`
+	// Citations lists the source numbers the answer cites, in order.
+	Citations []int  `json:"citations"`
+	Model     string `json:"model,omitempty"`
+}
+
+// Answerer answers questions from the index.
+type Answerer struct {
+	retriever  Retriever
+	model      ChatModel
+	topK       int
+	maxContext int
+}
+
+// NewAnswerer returns an Answerer that retrieves with r and answers with m,
+// with limits from cfg.
+func NewAnswerer(cfg config.LLMConfig, r Retriever, m ChatModel) *Answerer {
+	a := &Answerer{retriever: r, model: m, topK: cfg.TopK, maxContext: cfg.MaxContext}
+	if a.topK <= 0 {
+		a.topK = DefaultTopK
+	}
+	if a.maxContext <= 0 {
+		a.maxContext = DefaultMaxContext
+	}
+	return a
+}
+
+// Answer retrieves up to topK chunks for question (the configured number
+// when topK is 0) with opts and asks the model to answer from them.
+func (a *Answerer) Answer(ctx context.Context, question string, topK int, opts search.Options) (*Answer, error) {
+	if topK <= 0 {
+		topK = a.topK
+	}
+	results, err := a.retriever.Search(ctx, question, topK, opts)
+	if err != nil {
+		return nil, fmt.Errorf("retrieval failed: %w", err)
+	}
+	prompt, sources := BuildPrompt(question, results, a.maxContext)
+	if len(sources) == 0 {
+		return &Answer{Answer: noSourcesAnswer, Sources: []Source{}, Citations: []int{}}, nil
+	}
+
+	text, err := a.model.Complete(ctx, systemPrompt, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrModel, err)
+	}
+	text = strings.TrimSpace(text)
+	return &Answer{
+		Answer:    text,
+		Sources:   sources,
+		Citations: Citations(text, len(sources)),
+		Model:     a.model.Name(),
+	}, nil
+}
+
+// BuildPrompt packs results, best first, into the user message as numbered
+// sources until maxTokens of source text are used. Tokens are estimated as
+// four bytes each; a source that does not fit whole is truncated if it is
+// the first, and dropped otherwise.
+func BuildPrompt(question string, results []search.Result, maxTokens int) (string, []Source) {
+	budget := maxTokens * 4
+	var b strings.Builder
+	b.WriteString("Sources:\n\n")
+	sources := make([]Source, 0, len(results))
+	for _, r := range results {
+		text := strings.TrimSpace(r.Text)
+		if text == "" {
+			continue
+		}
+		if len(text) > budget {
+			if len(sources) > 0 {
+				break
+			}
+			text = truncateUTF8(text, budget)
+		}
+		budget -= len(text)
+		n := len(sources) + 1
+		fmt.Fprintf(&b, "[%d] %s\n%s\n\n", n, r.Path, text)
+		sources = append(sources, Source{
+			N: n, ID: r.ID, Path: r.Path, Score: r.Score, Text: text, Meta: r.Meta, Link: r.Link,
+		})
+	}
+	b.WriteString("Question: ")
+	b.WriteString(question)
+	return b.String(), sources
+}
+
+var citationRe = regexp.MustCompile(`\[(\d+)\]`)
+
+// Citations returns the distinct source numbers between 1 and n cited in
+// text as [n], in ascending order.
+func Citations(text string, n int) []int {
+	seen := make(map[int]bool)
+	cited := []int{}
+	for _, m := range citationRe.FindAllStringSubmatch(text, -1) {
+		i, err := strconv.Atoi(m[1])
+		if err != nil || i < 1 || i > n || seen[i] {
+			continue
+		}
+		seen[i] = true
+		cited = append(cited, i)
+	}
+	sort.Ints(cited)
+	return cited
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,115 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/search"
+)
+
+type fakeRetriever struct {
+	results []search.Result
+	topK    int
+}
+
+func (f *fakeRetriever) Search(_ context.Context, _ string, topK int, _ search.Options) ([]search.Result, error) {
+	f.topK = topK
+	return f.results, nil
+}
+
+type fakeModel struct {
+	answer string
+	prompt string
+	calls  int
+}
+
+func (f *fakeModel) Complete(_ context.Context, _, user string) (string, error) {
+	f.calls++
+	f.prompt = user
+	if f.answer == "" {
+		return "", errors.New("boom")
+	}
+	return f.answer, nil
+}
+
+func (f *fakeModel) Name() string { return "fake" }
+
+func TestAnswer(t *testing.T) {
+	r := &fakeRetriever{results: []search.Result{
+		{ID: "deploy#0", Path: "docs/deployments.md", Text: "Run harbor deploy --rollback to roll back.", Score: 0.9},
+		{ID: "empty#0", Path: "docs/empty.md", Text: "   "},
+		{ID: "office#0", Path: "docs/office.md", Text: "The coffee machine descales on Mondays.", Score: 0.2},
+	}}
+	m := &fakeModel{answer: " Use the rollback flag [1]. See also [9] and [1]. "}
+	a := NewAnswerer(config.LLMConfig{}, r, m)
+
+	ans, err := a.Answer(context.Background(), "how do I roll back?", 0, search.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.topK != DefaultTopK {
+		t.Errorf("retrieved top %d, want %d", r.topK, DefaultTopK)
+	}
+	if !strings.Contains(m.prompt, "[1] docs/deployments.md\nRun harbor deploy") ||
+		!strings.Contains(m.prompt, "[2] docs/office.md") ||
+		!strings.HasSuffix(m.prompt, "Question: how do I roll back?") {
+		t.Errorf("unexpected prompt:\n%s", m.prompt)
+	}
+	if ans.Answer != "Use the rollback flag [1]. See also [9] and [1]." || ans.Model != "fake" {
+		t.Errorf("answer = %+v", ans)
+	}
+	if len(ans.Sources) != 2 || ans.Sources[1].N != 2 || ans.Sources[1].ID != "office#0" {
+		t.Errorf("sources = %+v", ans.Sources)
+	}
+	if !reflect.DeepEqual(ans.Citations, []int{1}) {
+		t.Errorf("citations = %v, want [1]", ans.Citations)
+	}
+
+	// Nothing retrieved: no model call.
+	r.results = nil
+	m.calls = 0
+	ans, err = a.Answer(context.Background(), "unknown", 3, search.Options{})
+	if err != nil || m.calls != 0 || ans.Answer != noSourcesAnswer || r.topK != 3 {
+		t.Errorf("answer without sources = %+v, %v (model calls %d)", ans, err, m.calls)
+	}
+}
+
+func TestBuildPromptBudget(t *testing.T) {
+	results := []search.Result{
+		{Path: "a.md", Text: strings.Repeat("é", 30)}, // 60 bytes
+		{Path: "b.md", Text: "short"},
+	}
+	// 10 tokens = 40 bytes: the first source is cut, the second dropped.
+	prompt, sources := BuildPrompt("q", results, 10)
+	if len(sources) != 1 || len(sources[0].Text) != 40 || sources[0].Text != strings.Repeat("é", 20) {
+		t.Fatalf("sources = %+v", sources)
+	}
+	if strings.Contains(prompt, "b.md") {
+		t.Error("second source should not fit")
+	}
+
+	_, sources = BuildPrompt("q", results, 100)
+	if len(sources) != 2 {
+		t.Errorf("got %d sources, want 2", len(sources))
+	}
+}
+
+func TestRewriter(t *testing.T) {
+	m := &fakeModel{answer: "  Releases are rolled back with harbor deploy --rollback.\n"}
+	var w search.QueryRewriter = NewRewriter(m)
+	doc, err := w.WriteHypothetical(context.Background(), "how do I roll back?")
+	if err != nil || doc != "Releases are rolled back with harbor deploy --rollback." || m.prompt != "how do I roll back?" {
+		t.Errorf("WriteHypothetical = %q, %v (prompt %q)", doc, err, m.prompt)
+	}
+
+	m.answer = "1. undo a release\n\n- \"revert deployment\"\nHow do I roll back?\n3) rollback command\nextra line"
+	variants, err := w.WriteVariants(context.Background(), "how do I roll back?", 3)
+	want := []string{"undo a release", "revert deployment", "rollback command"}
+	if err != nil || !reflect.DeepEqual(variants, want) {
+		t.Errorf("WriteVariants = %q, %v, want %q", variants, err, want)
+	}
+}
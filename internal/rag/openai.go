@@ -0,0 +1,67 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/sashabaranov/go-openai"
+)
+
+// openAIChat is a ChatModel for OpenAI and OpenAI-compatible APIs such as
+// Ollama, vLLM or llama.cpp's server.
+type openAIChat struct {
+	client    *openai.Client
+	model     string
+	maxTokens int
+}
+
+// NewChatModel returns the chat model configured in cfg. The API key is read
+// from cfg.APIKeyEnv (OPENAI_API_KEY by default); it may only be missing
+// when base_url points at a server that needs none.
+func NewChatModel(cfg config.LLMConfig) (ChatModel, error) {
+	if cfg.Model == "" {
+		return nil, errors.New("llm.model is not set")
+	}
+	if cfg.Provider != "" && cfg.Provider != "openai" {
+		return nil, fmt.Errorf("unsupported llm.provider %q (expected openai)", cfg.Provider)
+	}
+	keyEnv := cfg.APIKeyEnv
+	if keyEnv == "" {
+		keyEnv = "OPENAI_API_KEY"
+	}
+	key := os.Getenv(keyEnv)
+	if key == "" && cfg.BaseURL == "" {
+		return nil, fmt.Errorf("LLM API key is required but not found in the %s environment variable", keyEnv)
+	}
+
+	ocfg := openai.DefaultConfig(key)
+	if cfg.BaseURL != "" {
+		ocfg.BaseURL = cfg.BaseURL
+	}
+	return &openAIChat{client: openai.NewClientWithConfig(ocfg), model: cfg.Model, maxTokens: cfg.MaxTokens}, nil
+}
+
+func (c *openAIChat) Complete(ctx context.Context, system, user string) (string, error) {
+	resp, err := c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: c.model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: system},
+			{Role: openai.ChatMessageRoleUser, Content: user},
+		},
+		MaxTokens: c.maxTokens,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", errors.New("the model returned no answer")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+func (c *openAIChat) Name() string {
+	return c.model
+}
@@ -0,0 +1,57 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const hydePrompt = `Write a short passage, of one or two paragraphs, that answers the question below as a page of the documentation or knowledge base being searched would.
+Write it as the document itself: no preamble, and no remarks about being uncertain or hypothetical. Use the language of the question.`
+
+const variantsPrompt = `Rephrase the search query below in %d different ways, to find documents that use other words for the same thing.
+Vary the vocabulary, and split a compound question into its parts. Write one query per line, without numbering or commentary, in the language of the query.`
+
+// Rewriter rewrites search queries with a chat model; it implements
+// search.QueryRewriter.
+type Rewriter struct {
+	model ChatModel
+}
+
+// NewRewriter returns a Rewriter using m.
+func NewRewriter(m ChatModel) *Rewriter {
+	return &Rewriter{model: m}
+}
+
+// WriteHypothetical writes the passage the model expects to answer query,
+// for HyDE: embedding it matches documents better than a short question.
+func (r *Rewriter) WriteHypothetical(ctx context.Context, query string) (string, error) {
+	text, err := r.model.Complete(ctx, hydePrompt, query)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(text), nil
+}
+
+// WriteVariants asks the model for n rephrasings of query. List markers
+// the model adds anyway are stripped, and at most n variants different
+// from query are returned.
+func (r *Rewriter) WriteVariants(ctx context.Context, query string, n int) ([]string, error) {
+	text, err := r.model.Complete(ctx, fmt.Sprintf(variantsPrompt, n), query)
+	if err != nil {
+		return nil, err
+	}
+	var variants []string
+	for _, line := range strings.Split(text, "\n") {
+		v := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*•0123456789.)"))
+		v = strings.Trim(v, `"`)
+		if v == "" || strings.EqualFold(v, query) {
+			continue
+		}
+		variants = append(variants, v)
+		if len(variants) == n {
+			break
+		}
+	}
+	return variants, nil
+}
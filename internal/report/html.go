@@ -0,0 +1,99 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(f float64) string { return fmt.Sprintf("%.1f%%", 100*f) },
+	"bytes":   formatBytes,
+	"signed":  func(n any) string { return fmt.Sprintf("%+d", n) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Semango health report {{.GeneratedAt.Format "2006-01-02 15:04 MST"}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em auto; max-width: 60em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { text-align: left; padding: .25em 1em .25em 0; border-bottom: 1px solid #ddd; }
+td.n { text-align: right; }
+.warn { color: #b00; }
+</style>
+</head>
+<body>
+<h1>Semango health report</h1>
+<p>Generated {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}{{with .Activity}}, covering activity since {{.Since.Format "2006-01-02 15:04:05 MST"}}{{end}}.</p>
+
+{{with .Index}}
+<h2>Index</h2>
+<table>
+<tr><th>Files</th><td class="n">{{.TotalDocuments}}</td></tr>
+<tr><th>Chunks</th><td class="n">{{.TotalChunks}}</td></tr>
+<tr><th>Vectors</th><td class="n">{{.Vectors}}</td></tr>
+<tr><th>Orphaned vectors</th><td class="n{{if .OrphanedVectors}} warn{{end}}">{{.OrphanedVectors}}</td></tr>
+<tr><th>Index size</th><td class="n">{{bytes .IndexSize}}</td></tr>
+<tr><th>Embedding model</th><td>{{.EmbeddingProvider}}/{{.EmbeddingModel}}, dimension {{.Dimension}}</td></tr>
+<tr><th>Generation</th><td class="n">{{.Generation}}{{with .IndexedAt}}, written {{.Format "2006-01-02 15:04:05 MST"}}{{end}}</td></tr>
+</table>
+{{end}}
+
+{{with .Growth}}
+<h2>Growth since {{.Since.Format "2006-01-02 15:04 MST"}}</h2>
+<table>
+<tr><th>Files</th><td class="n">{{signed .Documents}}</td></tr>
+<tr><th>Chunks</th><td class="n">{{signed .Chunks}}</td></tr>
+<tr><th>Vectors</th><td class="n">{{signed .Vectors}}</td></tr>
+<tr><th>Index bytes</th><td class="n">{{signed .IndexBytes}}</td></tr>
+</table>
+{{end}}
+
+{{with .Activity}}
+<h2>Requests</h2>
+<table>
+<tr><th>Requests</th><td class="n">{{.Requests}}</td></tr>
+<tr><th>Server errors</th><td class="n{{if .ServerErrors}} warn{{end}}">{{.ServerErrors}} ({{percent .ErrorRate}})</td></tr>
+<tr><th>Client errors</th><td class="n">{{.ClientErrors}}</td></tr>
+<tr><th>Searches</th><td class="n">{{.Searches}}</td></tr>
+<tr><th>Zero-result searches</th><td class="n">{{.ZeroResultSearches}} ({{percent .ZeroResultRate}})</td></tr>
+<tr><th>Ranking cache</th><td class="n">{{.Cache.Hits}} hits, {{.Cache.Misses}} misses ({{percent .Cache.HitRate}})</td></tr>
+<tr><th>Not modified (304)</th><td class="n">{{.Cache.NotModified}}</td></tr>
+</table>
+
+<h2>Slowest queries</h2>
+{{template "queries" .SlowestQueries}}
+
+<h2>Zero-result queries</h2>
+{{template "queries" .ZeroResultQueries}}
+{{else}}
+<p>No server activity: this report was built from the indexes alone.</p>
+{{end}}
+</body>
+</html>
+{{define "queries"}}{{if .}}
+<table>
+<tr><th>Query</th><th>Searches</th><th>Slowest</th></tr>
+{{range .}}<tr><td>{{if .Query}}{{.Query}}{{else}}<code>{{.Hash}}</code>{{end}}</td><td class="n">{{.Count}}</td><td class="n">{{printf "%.0f" .LatencyMS}} ms</td></tr>
+{{end}}</table>
+{{else}}<p>None.</p>{{end}}{{end}}`))
+
+// WriteHTML renders r as a standalone HTML page.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, r)
+}
+
+// formatBytes returns n in B, KB, MB or GB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 2; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMG"[exp])
+}
@@ -0,0 +1,216 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxTrackedQueries bounds the distinct queries a Recorder keeps per list,
+// so that a flood of unique queries cannot grow its memory.
+const maxTrackedQueries = 1000
+
+// topQueries is how many slow and zero-result queries an Activity lists.
+const topQueries = 10
+
+// Activity is what a server saw of its requests over a period.
+type Activity struct {
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+
+	Requests     int `json:"requests"`
+	ServerErrors int `json:"server_errors"` // 5xx responses
+	ClientErrors int `json:"client_errors"` // 4xx responses, including rate limited ones
+	// ErrorRate is the fraction of requests answered with a server error.
+	ErrorRate float64 `json:"error_rate"`
+
+	Searches           int     `json:"searches"`
+	ZeroResultSearches int     `json:"zero_result_searches"`
+	ZeroResultRate     float64 `json:"zero_result_rate"`
+	// SlowestQueries are the queries with the slowest searches, slowest
+	// first; ZeroResultQueries those that most often found nothing.
+	SlowestQueries    []QueryStat `json:"slowest_queries"`
+	ZeroResultQueries []QueryStat `json:"zero_result_queries"`
+
+	Cache CacheStats `json:"cache"`
+}
+
+// QueryStat is one query in an Activity. Query is left out when queries
+// are redacted; Hash still tells queries apart.
+type QueryStat struct {
+	Query     string  `json:"query,omitempty"`
+	Hash      string  `json:"query_hash"`
+	Count     int     `json:"count"`
+	LatencyMS float64 `json:"latency_ms"` // of the slowest search
+}
+
+// CacheStats counts how searches were served from the ranking cache behind
+// paged searches, and the conditional requests answered with 304.
+type CacheStats struct {
+	Hits        int     `json:"hits"`
+	Misses      int     `json:"misses"`
+	HitRate     float64 `json:"hit_rate"`
+	NotModified int     `json:"not_modified"`
+}
+
+// Recorder collects the Activity of a server. It is safe for concurrent
+// use, and a nil Recorder records nothing.
+type Recorder struct {
+	mu      sync.Mutex
+	redact  bool // keep only query hashes
+	now     func() time.Time
+	a       Activity
+	slowest map[string]*QueryStat // by hash
+	zero    map[string]*QueryStat // by hash
+}
+
+// NewRecorder returns a Recorder starting now. With redact set it keeps
+// the hashes of queries but not their text.
+func NewRecorder(redact bool) *Recorder {
+	r := &Recorder{redact: redact, now: time.Now}
+	r.reset()
+	return r
+}
+
+func (r *Recorder) reset() {
+	r.a = Activity{Since: r.now()}
+	r.slowest = map[string]*QueryStat{}
+	r.zero = map[string]*QueryStat{}
+}
+
+// Request counts a response with the given HTTP status.
+func (r *Recorder) Request(status int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.a.Requests++
+	switch {
+	case status >= http.StatusInternalServerError:
+		r.a.ServerErrors++
+	case status >= http.StatusBadRequest:
+		r.a.ClientErrors++
+	case status == http.StatusNotModified:
+		r.a.Cache.NotModified++
+	}
+}
+
+// Search records a search for query that returned results after latency.
+func (r *Recorder) Search(query string, latency time.Duration, results int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.a.Searches++
+	sum := sha256.Sum256([]byte(query))
+	hash := hex.EncodeToString(sum[:8])
+	if r.redact {
+		query = ""
+	}
+	ms := float64(latency.Microseconds()) / 1000
+
+	if q := r.slowest[hash]; q != nil {
+		q.Count++
+		q.LatencyMS = max(q.LatencyMS, ms)
+	} else if len(r.slowest) < maxTrackedQueries {
+		r.slowest[hash] = &QueryStat{Query: query, Hash: hash, Count: 1, LatencyMS: ms}
+	} else if fastest := fastestQuery(r.slowest); r.slowest[fastest].LatencyMS < ms {
+		// Full: make room by forgetting the fastest query.
+		delete(r.slowest, fastest)
+		r.slowest[hash] = &QueryStat{Query: query, Hash: hash, Count: 1, LatencyMS: ms}
+	}
+
+	if results > 0 {
+		return
+	}
+	r.a.ZeroResultSearches++
+	if q := r.zero[hash]; q != nil {
+		q.Count++
+		q.LatencyMS = max(q.LatencyMS, ms)
+	} else if len(r.zero) < maxTrackedQueries {
+		r.zero[hash] = &QueryStat{Query: query, Hash: hash, Count: 1, LatencyMS: ms}
+	}
+}
+
+func fastestQuery(qs map[string]*QueryStat) string {
+	fastest := ""
+	for h, q := range qs {
+		if fastest == "" || q.LatencyMS < qs[fastest].LatencyMS {
+			fastest = h
+		}
+	}
+	return fastest
+}
+
+// Cache counts a search that was served from the ranking cache (hit) or
+// had to run (miss).
+func (r *Recorder) Cache(hit bool) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if hit {
+		r.a.Cache.Hits++
+	} else {
+		r.a.Cache.Misses++
+	}
+}
+
+// Snapshot returns the activity recorded so far.
+func (r *Recorder) Snapshot() *Activity {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.snapshot()
+}
+
+// Take returns the activity recorded so far and starts a new period.
+func (r *Recorder) Take() *Activity {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a := r.snapshot()
+	r.reset()
+	return a
+}
+
+func (r *Recorder) snapshot() *Activity {
+	a := r.a
+	a.Until = r.now()
+	a.ErrorRate = ratio(a.ServerErrors, a.Requests)
+	a.ZeroResultRate = ratio(a.ZeroResultSearches, a.Searches)
+	a.Cache.HitRate = ratio(a.Cache.Hits, a.Cache.Hits+a.Cache.Misses)
+	a.SlowestQueries = top(r.slowest, func(x, y QueryStat) bool { return x.LatencyMS > y.LatencyMS })
+	a.ZeroResultQueries = top(r.zero, func(x, y QueryStat) bool { return x.Count > y.Count })
+	return &a
+}
+
+// top returns the first topQueries of qs ordered by less, ties broken by
+// hash so that reports are stable.
+func top(qs map[string]*QueryStat, less func(x, y QueryStat) bool) []QueryStat {
+	out := make([]QueryStat, 0, len(qs))
+	for _, q := range qs {
+		out = append(out, *q)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if less(out[i], out[j]) != less(out[j], out[i]) {
+			return less(out[i], out[j])
+		}
+		return out[i].Hash < out[j].Hash
+	})
+	if len(out) > topQueries {
+		out = out[:topQueries]
+	}
+	return out
+}
+
+func ratio(n, of int) float64 {
+	if of == 0 {
+		return 0
+	}
+	return float64(n) / float64(of)
+}
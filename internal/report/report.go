@@ -0,0 +1,146 @@
+// Package report builds index health reports: what the indexes hold, how
+// they grew since the previous report, and what a server saw of its
+// searches. Reports are kept as JSON, with an HTML rendering next to each.
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/search"
+)
+
+// keepReports is how many reports Save keeps in the report directory.
+const keepReports = 30
+
+// fileTime is the time layout in report file names; it sorts by time.
+const fileTime = "20060102T150405Z"
+
+// Report is an index health report.
+type Report struct {
+	GeneratedAt time.Time     `json:"generated_at"`
+	Index       *search.Stats `json:"index"`
+	// Growth compares Index with the previous report; nil for the first.
+	Growth *Growth `json:"growth,omitempty"`
+	// Activity is nil for reports built without a running server.
+	Activity *Activity `json:"activity,omitempty"`
+}
+
+// Growth is the change in the indexes since the previous report. Negative
+// values mean the indexes shrank.
+type Growth struct {
+	Since      time.Time `json:"since"`
+	Documents  int       `json:"documents"`
+	Chunks     int       `json:"chunks"`
+	Vectors    int64     `json:"vectors"`
+	IndexBytes int64     `json:"index_bytes"`
+}
+
+// New returns the report for the index statistics stats at now, with the
+// growth since prev when there is one and the server activity, if any.
+func New(now time.Time, stats *search.Stats, prev *Report, activity *Activity) *Report {
+	r := &Report{GeneratedAt: now.UTC(), Index: stats, Activity: activity}
+	if prev != nil && prev.Index != nil {
+		r.Growth = &Growth{
+			Since:      prev.GeneratedAt,
+			Documents:  stats.TotalDocuments - prev.Index.TotalDocuments,
+			Chunks:     stats.TotalChunks - prev.Index.TotalChunks,
+			Vectors:    stats.Vectors - prev.Index.Vectors,
+			IndexBytes: stats.IndexSize - prev.Index.IndexSize,
+		}
+	}
+	return r
+}
+
+// Dir returns the directory reports of cfg are kept in: report.dir, or
+// reports/ next to the lexical index.
+func Dir(cfg *config.Config) string {
+	if cfg.Report.Dir != "" {
+		return cfg.Report.Dir
+	}
+	return filepath.Join(filepath.Dir(filepath.Clean(cfg.Lexical.IndexPath)), "reports")
+}
+
+// Save writes r to dir as report-<time>.json and report-<time>.html and
+// deletes all but the newest keepReports reports. It returns the path of
+// the JSON file.
+func Save(dir string, r *Report) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	base := filepath.Join(dir, "report-"+r.GeneratedAt.UTC().Format(fileTime))
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".json", data, 0o644); err != nil {
+		return "", err
+	}
+	html, err := os.Create(base + ".html")
+	if err != nil {
+		return "", err
+	}
+	if err := r.WriteHTML(html); err != nil {
+		html.Close()
+		return "", err
+	}
+	if err := html.Close(); err != nil {
+		return "", err
+	}
+
+	names, err := list(dir)
+	if err != nil {
+		return "", err
+	}
+	for len(names) > keepReports {
+		stem := strings.TrimSuffix(names[0], ".json")
+		os.Remove(filepath.Join(dir, stem+".json"))
+		os.Remove(filepath.Join(dir, stem+".html"))
+		names = names[1:]
+	}
+	return base + ".json", nil
+}
+
+// Latest returns the newest report in dir, or nil if there is none.
+func Latest(dir string) (*Report, error) {
+	names, err := list(dir)
+	if err != nil || len(names) == 0 {
+		return nil, err
+	}
+	path := filepath.Join(dir, names[len(names)-1])
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid report %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// list returns the names of the JSON reports in dir, oldest first.
+func list(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if n := e.Name(); strings.HasPrefix(n, "report-") && strings.HasSuffix(n, ".json") {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
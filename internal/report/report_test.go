@@ -0,0 +1,119 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/omarkamali/semango/internal/search"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder(false)
+	r.Request(200)
+	r.Request(404)
+	r.Request(500)
+	r.Request(304)
+	r.Search("fast", time.Millisecond, 3)
+	r.Search("slow", 50*time.Millisecond, 1)
+	r.Search("nothing", 2*time.Millisecond, 0)
+	r.Search("nothing", 3*time.Millisecond, 0)
+	r.Cache(true)
+	r.Cache(false)
+	r.Cache(false)
+
+	a := r.Take()
+	if a.Requests != 4 || a.ServerErrors != 1 || a.ClientErrors != 1 || a.Cache.NotModified != 1 {
+		t.Errorf("request counts: %+v", a)
+	}
+	if a.ErrorRate != 0.25 {
+		t.Errorf("error rate %v, want 0.25", a.ErrorRate)
+	}
+	if a.Searches != 4 || a.ZeroResultSearches != 2 || a.ZeroResultRate != 0.5 {
+		t.Errorf("search counts: %+v", a)
+	}
+	if len(a.SlowestQueries) != 3 || a.SlowestQueries[0].Query != "slow" {
+		t.Errorf("slowest queries: %+v", a.SlowestQueries)
+	}
+	if len(a.ZeroResultQueries) != 1 || a.ZeroResultQueries[0].Query != "nothing" || a.ZeroResultQueries[0].Count != 2 || a.ZeroResultQueries[0].LatencyMS != 3 {
+		t.Errorf("zero-result queries: %+v", a.ZeroResultQueries)
+	}
+	if a.Cache.Hits != 1 || a.Cache.Misses != 2 {
+		t.Errorf("cache: %+v", a.Cache)
+	}
+
+	if b := r.Snapshot(); b.Requests != 0 || b.Searches != 0 || len(b.SlowestQueries) != 0 {
+		t.Errorf("Take did not start a new period: %+v", b)
+	}
+}
+
+func TestRecorderRedacts(t *testing.T) {
+	r := NewRecorder(true)
+	r.Search("secret", time.Millisecond, 0)
+	a := r.Snapshot()
+	if q := a.ZeroResultQueries[0]; q.Query != "" || q.Hash == "" {
+		t.Errorf("redacted query: %+v", q)
+	}
+	var nilRecorder *Recorder
+	nilRecorder.Request(200) // must not panic
+}
+
+func TestSaveAndLatest(t *testing.T) {
+	dir := t.TempDir()
+	if r, err := Latest(dir); r != nil || err != nil {
+		t.Fatalf("Latest of an empty directory: %v, %v", r, err)
+	}
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var prev *Report
+	for i := 0; i < keepReports+2; i++ {
+		stats := &search.Stats{TotalDocuments: 10 + i, TotalChunks: 100 + 3*i, Vectors: int64(100 + 3*i)}
+		r := New(start.Add(time.Duration(i)*time.Hour), stats, prev, nil)
+		if _, err := Save(dir, r); err != nil {
+			t.Fatal(err)
+		}
+		prev = r
+	}
+
+	latest, err := Latest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Index.TotalDocuments != 10+keepReports+1 {
+		t.Errorf("latest report has %d files", latest.Index.TotalDocuments)
+	}
+	if g := latest.Growth; g == nil || g.Documents != 1 || g.Chunks != 3 || !g.Since.Equal(start.Add(time.Duration(keepReports)*time.Hour)) {
+		t.Errorf("growth: %+v", g)
+	}
+
+	json, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	html, _ := filepath.Glob(filepath.Join(dir, "*.html"))
+	if len(json) != keepReports || len(html) != keepReports {
+		t.Errorf("kept %d JSON and %d HTML reports, want %d", len(json), len(html), keepReports)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "report-20260101T000000Z.json")); !os.IsNotExist(err) {
+		t.Error("the oldest report was not deleted")
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	a := NewRecorder(false)
+	a.Search("<script>", time.Millisecond, 0)
+	r := New(time.Now(), &search.Stats{TotalDocuments: 2, OrphanedVectors: 1, IndexSize: 2048}, nil, a.Snapshot())
+	var buf bytes.Buffer
+	if err := r.WriteHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Orphaned vectors", "2.0 KB", "&lt;script&gt;", "Zero-result queries"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML report lacks %q", want)
+		}
+	}
+	if strings.Contains(out, "<script>") {
+		t.Error("query text was not escaped")
+	}
+}
@@ -0,0 +1,55 @@
+package search
+
+import "github.com/omarkamali/semango/internal/config"
+
+// DefaultAdaptiveDrop is the relative score drop that ends an adaptive
+// ranking when search.adaptive_drop is not set.
+const DefaultAdaptiveDrop = 0.2
+
+// AdaptiveDrop returns the value for Options.Adaptive configured by
+// search.adaptive_drop, or DefaultAdaptiveDrop.
+func AdaptiveDrop(cfg config.SearchConfig) float64 {
+	if cfg.AdaptiveDrop > 0 {
+		return cfg.AdaptiveDrop
+	}
+	return DefaultAdaptiveDrop
+}
+
+// AdaptiveCutoff finds the elbow of a descending list of scores: the
+// largest drop between neighbours, measured as a fraction of the top
+// score. If that drop is at least minDrop, it returns the number of scores
+// above it; otherwise the scores fall off gradually and all of them are
+// kept. At least one score is always kept.
+func AdaptiveCutoff(scores []float64, minDrop float64) int {
+	if len(scores) < 2 || scores[0] <= 0 {
+		return len(scores)
+	}
+	cut, largest := len(scores), 0.0
+	for i := 1; i < len(scores); i++ {
+		if drop := (scores[i-1] - scores[i]) / scores[0]; drop > largest {
+			cut, largest = i, drop
+		}
+	}
+	if largest < minDrop {
+		return len(scores)
+	}
+	return cut
+}
+
+// cutAdaptive applies opts.Adaptive to a complete ranking of total
+// candidates. It returns the ranking, cut at the elbow unless
+// opts.AdaptiveFull is set, the total to report, and the cutoff.
+func cutAdaptive(results []Result, total int, opts Options) ([]Result, int, int) {
+	if opts.Adaptive <= 0 {
+		return results, total, 0
+	}
+	scores := make([]float64, len(results))
+	for i, r := range results {
+		scores[i] = r.Score
+	}
+	cutoff := AdaptiveCutoff(scores, opts.Adaptive)
+	if opts.AdaptiveFull {
+		return results, total, cutoff
+	}
+	return results[:cutoff], min(total, cutoff), cutoff
+}
@@ -0,0 +1,44 @@
+package search
+
+import "testing"
+
+func TestAdaptiveCutoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores []float64
+		want   int
+	}{
+		{"steep drop after two", []float64{1, 0.95, 0.4, 0.35, 0.3}, 2},
+		{"largest drop wins", []float64{1, 0.7, 0.65, 0.2}, 3},
+		{"gradual fall keeps all", []float64{1, 0.9, 0.8, 0.7, 0.6}, 5},
+		{"drop after the first", []float64{0.9, 0.1}, 1},
+		{"single score", []float64{0.5}, 1},
+		{"no scores", nil, 0},
+		{"zero top score", []float64{0, 0, 0}, 3},
+	}
+	for _, tt := range tests {
+		if got := AdaptiveCutoff(tt.scores, 0.2); got != tt.want {
+			t.Errorf("%s: AdaptiveCutoff(%v) = %d, want %d", tt.name, tt.scores, got, tt.want)
+		}
+	}
+}
+
+func TestCutAdaptive(t *testing.T) {
+	results := []Result{{ID: "a", Score: 1}, {ID: "b", Score: 0.9}, {ID: "c", Score: 0.3}, {ID: "d", Score: 0.25}}
+
+	got, total, cutoff := cutAdaptive(results, 10, Options{})
+	if len(got) != 4 || total != 10 || cutoff != 0 {
+		t.Errorf("without Adaptive: %d results, total %d, cutoff %d; want 4, 10, 0", len(got), total, cutoff)
+	}
+	got, total, cutoff = cutAdaptive(results, 10, Options{Adaptive: 0.2})
+	if len(got) != 2 || total != 2 || cutoff != 2 {
+		t.Errorf("Adaptive: %d results, total %d, cutoff %d; want 2, 2, 2", len(got), total, cutoff)
+	}
+	got, total, cutoff = cutAdaptive(results, 10, Options{Adaptive: 0.2, AdaptiveFull: true})
+	if len(got) != 4 || total != 10 || cutoff != 2 {
+		t.Errorf("AdaptiveFull: %d results, total %d, cutoff %d; want 4, 10, 2", len(got), total, cutoff)
+	}
+	if _, _, cutoff := cutAdaptive(results, 10, Options{Adaptive: 0.7}); cutoff != 4 {
+		t.Errorf("drop below the threshold: cutoff %d, want 4", cutoff)
+	}
+}
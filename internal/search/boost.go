@@ -0,0 +1,77 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/omarkamali/semango/internal/ingest"
+)
+
+// ValidateBoosts checks that every key of boosts is a valid path pattern and
+// every factor a positive number. Boosts only re-rank results, so a factor
+// of zero, which would drop matches, is rejected; use a filter instead.
+func ValidateBoosts(boosts map[string]float64) error {
+	for pattern, factor := range boosts {
+		if !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("invalid boost pattern %q", pattern)
+		}
+		if !(factor > 0) || math.IsInf(factor, 0) {
+			return fmt.Errorf("boost factor for %q must be a positive number, got %v", pattern, factor)
+		}
+	}
+	return nil
+}
+
+// ParseBoosts parses boosts given as pattern=factor terms, e.g.
+// `docs/**=1.5`, as used on the command line and in query strings.
+func ParseBoosts(terms []string) (map[string]float64, error) {
+	boosts := make(map[string]float64, len(terms))
+	for _, term := range terms {
+		eq := strings.LastIndexByte(term, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("invalid boost %q: expected pattern=factor", term)
+		}
+		pattern := term[:eq]
+		factor, err := strconv.ParseFloat(term[eq+1:], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boost %q: expected pattern=factor", term)
+		}
+		if _, dup := boosts[pattern]; dup {
+			return nil, fmt.Errorf("boost pattern %q given more than once", pattern)
+		}
+		boosts[pattern] = factor
+	}
+	if err := ValidateBoosts(boosts); err != nil {
+		return nil, err
+	}
+	return boosts, nil
+}
+
+// boostFactor returns the product of the factors of all patterns in boosts
+// that match path, or 1 when none does.
+func boostFactor(boosts map[string]float64, path string) float64 {
+	factor := 1.0
+	for pattern, f := range boosts {
+		if ok, _ := doublestar.Match(pattern, path); ok {
+			factor *= f
+		}
+	}
+	return factor
+}
+
+// qualityFactor scales the score of a chunk by its ingest-time quality
+// score with weight prior (search.quality_prior): 1 for a clean chunk, down
+// to 1-prior for noise. Chunks indexed without a score are left alone.
+func qualityFactor(prior float64, meta map[string]string) float64 {
+	if prior <= 0 {
+		return 1
+	}
+	q, err := strconv.ParseFloat(meta[ingest.MetaQuality], 64)
+	if err != nil {
+		return 1
+	}
+	return 1 - prior*(1-math.Max(0, math.Min(1, q)))
+}
@@ -0,0 +1,70 @@
+package search
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseBoosts(t *testing.T) {
+	got, err := ParseBoosts([]string{"docs/**=1.5", "tests/**=0.5"})
+	if err != nil {
+		t.Fatalf("ParseBoosts: %v", err)
+	}
+	if len(got) != 2 || got["docs/**"] != 1.5 || got["tests/**"] != 0.5 {
+		t.Errorf("ParseBoosts = %v", got)
+	}
+	for _, bad := range [][]string{
+		{"docs/**"},
+		{"=2"},
+		{"docs/**=fast"},
+		{"docs/**=0"},
+		{"docs/**=-1"},
+		{"docs/**=Inf"},
+		{"docs/[=2"},
+		{"a=1", "a=2"},
+	} {
+		if _, err := ParseBoosts(bad); err == nil {
+			t.Errorf("ParseBoosts(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestBoostFactor(t *testing.T) {
+	boosts := map[string]float64{"docs/**": 1.5, "docs/api/**": 2, "**/*_test.go": 0.5}
+	tests := []struct {
+		path string
+		want float64
+	}{
+		{"docs/intro.md", 1.5},
+		{"docs/api/search.md", 3},
+		{"internal/search/boost_test.go", 0.5},
+		{"README.md", 1},
+	}
+	for _, tt := range tests {
+		if got := boostFactor(boosts, tt.path); got != tt.want {
+			t.Errorf("boostFactor(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	if got := boostFactor(nil, "docs/intro.md"); got != 1 {
+		t.Errorf("boostFactor(nil) = %v, want 1", got)
+	}
+}
+
+func TestQualityFactor(t *testing.T) {
+	tests := []struct {
+		prior float64
+		meta  map[string]string
+		want  float64
+	}{
+		{0, map[string]string{"quality": "0.2"}, 1},
+		{0.5, map[string]string{"quality": "1.00"}, 1},
+		{0.5, map[string]string{"quality": "0.20"}, 0.6},
+		{0.5, map[string]string{}, 1},
+		{0.5, map[string]string{"quality": "bad"}, 1},
+	}
+	for _, tt := range tests {
+		if got := qualityFactor(tt.prior, tt.meta); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("qualityFactor(%v, %v) = %v, want %v", tt.prior, tt.meta, got, tt.want)
+		}
+	}
+}
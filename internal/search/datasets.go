@@ -0,0 +1,66 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+// maxDatasets bounds the tabular datasets ListDatasets reads.
+const maxDatasets = 100000
+
+// DatasetSummary is an indexed tabular dataset as listed by ListDatasets.
+type DatasetSummary struct {
+	// Path is the path the dataset is indexed under, e.g. "data/orders.csv",
+	// or "shop.db#orders" for the orders table of a SQLite database; the
+	// sheets of a workbook with several are listed as "sales.xlsx#Q1".
+	Path string `json:"path"`
+	// Schema lists its columns and their kinds, e.g. "amount(numeric),
+	// name(text)".
+	Schema string `json:"schema"`
+}
+
+// ListDatasets returns the tabular files, SQLite tables and spreadsheet
+// sheets indexed in the lexical index of cfg, sorted by path, from the
+// schema chunk the tabular loaders write for each.
+func ListDatasets(ctx context.Context, cfg *config.Config) ([]DatasetSummary, error) {
+	bleveIdx, err := storage.OpenOrCreateBleveIndex(cfg.Lexical.IndexPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Bleve index: %w", err)
+	}
+	defer bleveIdx.Close()
+
+	ids, _, err := bleveIdx.FilterIDs(ctx, map[string]string{"kind": "schema", "source": "TabularLoader"}, maxDatasets)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		datasets []DatasetSummary
+		sheets   []string
+	)
+	for _, id := range ids {
+		// The filter is analysed, so check the stored values.
+		r, ok := readChunk(bleveIdx, id)
+		if !ok || r.Meta["kind"] != "schema" || r.Meta["source"] != "TabularLoader" {
+			continue
+		}
+		datasets = append(datasets, DatasetSummary{Path: r.Path, Schema: strings.TrimPrefix(r.Text, "Schema: ")})
+		sheets = append(sheets, r.Meta["sheet"])
+	}
+	// Spreadsheets have a schema chunk a sheet.
+	count := map[string]int{}
+	for _, d := range datasets {
+		count[d.Path]++
+	}
+	for i, sheet := range sheets {
+		if sheet != "" && count[datasets[i].Path] > 1 {
+			datasets[i].Path += "#" + sheet
+		}
+	}
+	sort.Slice(datasets, func(i, j int) bool { return datasets[i].Path < datasets[j].Path })
+	return datasets, nil
+}
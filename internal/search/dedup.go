@@ -0,0 +1,75 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"slices"
+	"sort"
+
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// collapseDuplicates keeps only the best of the results whose text is the
+// same up to case and white space, such as chunks indexed before
+// files.dedup was set, and lists the paths of the others among its aliases.
+// results must be sorted by score.
+func collapseDuplicates(results []Result) []Result {
+	kept := make(map[string]int) // text hash -> index in out
+	out := results[:0]
+	for _, r := range results {
+		if r.Text == "" {
+			out = append(out, r)
+			continue
+		}
+		hash := ingest.FingerprintText(r.Text).Hash
+		i, ok := kept[hash]
+		if !ok {
+			kept[hash] = len(out)
+			out = append(out, r)
+			continue
+		}
+		out[i].Aliases = addAliases(out[i].Aliases, out[i].Path, append([]string{r.Path}, r.Aliases...))
+	}
+	return out
+}
+
+// addAliases adds the paths of add missing from aliases, other than path,
+// and sorts them.
+func addAliases(aliases []string, path string, add []string) []string {
+	for _, p := range add {
+		if p != path && !slices.Contains(aliases, p) {
+			aliases = append(aliases, p)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// expandAliases lists, for each result, the paths of the duplicates of its
+// chunk that files.dedup left out of the indexes.
+func (s *Searcher) expandAliases(ctx context.Context, results []Result) {
+	if len(results) == 0 {
+		return
+	}
+	store, err := storage.OpenDedupStore(storage.DedupPath(s.lexicalPath()), true)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			util.FromContext(ctx).Warn("Deduplication store unreadable; aliases left out", "error", err)
+		}
+		return
+	}
+	defer store.Close()
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.ID
+	}
+	aliases := store.Aliases(ids)
+	for i := range results {
+		if a := aliases[results[i].ID]; len(a) > 0 {
+			results[i].Aliases = addAliases(results[i].Aliases, results[i].Path, a)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollapseDuplicates(t *testing.T) {
+	results := []Result{
+		{ID: "a", Path: "README.md", Text: "Install with make."},
+		{ID: "b", Path: "docs/other.md", Text: "Something else."},
+		{ID: "c", Path: "vendor/x/README.md", Text: "install  with\nmake.", Aliases: []string{"vendor/y/README.md"}},
+		{ID: "d", Path: "README.md", Text: "Install with make."},
+		{ID: "img", Path: "logo.png"},
+		{ID: "img2", Path: "logo2.png"},
+	}
+	got := collapseDuplicates(results)
+	var ids []string
+	for _, r := range got {
+		ids = append(ids, r.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"a", "b", "img", "img2"}) {
+		t.Fatalf("kept %v", ids)
+	}
+	if want := []string{"vendor/x/README.md", "vendor/y/README.md"}; !reflect.DeepEqual(got[0].Aliases, want) {
+		t.Errorf("aliases = %v, want %v", got[0].Aliases, want)
+	}
+}
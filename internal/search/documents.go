@@ -0,0 +1,132 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+// ErrDocumentNotFound is returned by ReadDocument for a path with no
+// indexed chunks.
+var ErrDocumentNotFound = errors.New("document not found")
+
+// maxDocumentChunks bounds the chunks read for one document.
+const maxDocumentChunks = 100000
+
+// DocumentSummary is an indexed document as listed by ListDocuments.
+type DocumentSummary struct {
+	Path     string `json:"path"`
+	Modality string `json:"modality"`
+	Chunks   int    `json:"chunks"`
+}
+
+// Document is the indexed content of one document: its chunks in document
+// order and the text they were cut from.
+type Document struct {
+	Path   string
+	Text   string
+	Chunks []Result
+}
+
+// ListDocuments returns up to limit indexed documents sorted by path,
+// starting after the path after; pass "" for the first page. The second
+// result is the path to pass for the next page, or "" after the last one.
+func (s *Searcher) ListDocuments(ctx context.Context, after string, limit int) ([]DocumentSummary, string, error) {
+	bleveIdx, err := storage.OpenOrCreateBleveIndex(s.lexicalPath())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open Bleve index: %w", err)
+	}
+	defer bleveIdx.Close()
+
+	docs := map[string]*DocumentSummary{}
+	err = bleveIdx.EachDocument(ctx, []string{"path", "meta.modality"}, func(_ string, values map[string]interface{}) {
+		path, _ := values["path"].(string)
+		if path == "" || path <= after {
+			return
+		}
+		d := docs[path]
+		if d == nil {
+			modality, _ := values["meta.modality"].(string)
+			d = &DocumentSummary{Path: path, Modality: getModality(modality, path)}
+			docs[path] = d
+		}
+		d.Chunks++
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read chunks: %w", err)
+	}
+
+	paths := make([]string, 0, len(docs))
+	for p := range docs {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	next := ""
+	if limit > 0 && len(paths) > limit {
+		paths = paths[:limit]
+		next = paths[limit-1]
+	}
+	out := make([]DocumentSummary, len(paths))
+	for i, p := range paths {
+		out[i] = *docs[p]
+	}
+	return out, next, nil
+}
+
+// ReadDocument returns the indexed chunks of the document at path, matched
+// exactly, and the document text rebuilt from them. Overlapping chunks are
+// merged; chunks of documents split into sections, such as notebook cells,
+// are joined with blank lines.
+func (s *Searcher) ReadDocument(ctx context.Context, path string) (*Document, error) {
+	bleveIdx, err := storage.OpenOrCreateBleveIndex(s.lexicalPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Bleve index: %w", err)
+	}
+	defer bleveIdx.Close()
+
+	// The filter is analysed, so compare the stored path of each candidate.
+	ids, _, err := bleveIdx.FilterIDs(ctx, map[string]string{"path": path}, maxDocumentChunks)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{Path: path}
+	for _, id := range ids {
+		r, ok := readChunk(bleveIdx, id)
+		if !ok || r.Path != path {
+			continue
+		}
+		doc.Chunks = append(doc.Chunks, r)
+	}
+	if len(doc.Chunks) == 0 {
+		return nil, ErrDocumentNotFound
+	}
+	sort.SliceStable(doc.Chunks, func(i, j int) bool { return doc.Chunks[i].ID < doc.Chunks[j].ID })
+	sortByPosition(doc.Chunks)
+	doc.Text = documentText(doc.Chunks)
+	return doc, nil
+}
+
+// documentText joins chunks sorted by position. Offsets locate chunks in
+// the whole source only when no section key resets them, and only then can
+// the overlap between neighbours be dropped.
+func documentText(chunks []Result) string {
+	spans := make([]childSpan, 0, len(chunks))
+	for _, c := range chunks {
+		_, cell := c.Meta["cell_index"]
+		_, chapter := c.Meta["chapter_index"]
+		off, ok := metaNumber(c.Meta, "offset")
+		if cell || chapter || !ok {
+			texts := make([]string, len(chunks))
+			for i, c := range chunks {
+				texts[i] = c.Text
+			}
+			return strings.Join(texts, "\n\n")
+		}
+		spans = append(spans, childSpan{offset: int(off), text: c.Text})
+	}
+	return mergeSpans(spans)
+}
@@ -0,0 +1,29 @@
+package search
+
+import "testing"
+
+func TestDocumentText(t *testing.T) {
+	chunk := func(text string, meta map[string]string) Result { return Result{Text: text, Meta: meta} }
+
+	overlapping := []Result{
+		chunk("Hello wor", map[string]string{"offset": "0"}),
+		chunk("world, again", map[string]string{"offset": "6"}),
+		chunk("Apart", map[string]string{"offset": "30"}),
+	}
+	if got, want := documentText(overlapping), "Hello world, again\n\nApart"; got != want {
+		t.Errorf("overlapping chunks: got %q, want %q", got, want)
+	}
+
+	cells := []Result{
+		chunk("import os", map[string]string{"cell_index": "0", "offset": "0"}),
+		chunk("print(1)", map[string]string{"cell_index": "1", "offset": "0"}),
+	}
+	if got, want := documentText(cells), "import os\n\nprint(1)"; got != want {
+		t.Errorf("notebook cells: got %q, want %q", got, want)
+	}
+
+	unpositioned := []Result{chunk("a = 1", map[string]string{"line": "1"}), chunk("b = 2", nil)}
+	if got, want := documentText(unpositioned), "a = 1\n\nb = 2"; got != want {
+		t.Errorf("chunks without offsets: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,186 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// ErrDimensionMismatch is returned by SetEmbedding when a new model's
+// vectors do not have the dimension of an existing vector index, and by
+// searches whose query embedder does not match the index searched.
+var ErrDimensionMismatch = storage.ErrDimensionMismatch
+
+// ErrModelMismatch is returned by searches and SetEmbedding when a vector
+// index was built with another embedding model than the one searched or
+// switched to, unless the Searcher allows it (see AllowModelMismatch).
+var ErrModelMismatch = storage.ErrModelMismatch
+
+// embedders are the models built from one embedding configuration. A
+// Searcher and its copies share a pointer to them, so SetEmbedding switches
+// them all at once.
+type embedders struct {
+	cfg    config.EmbeddingConfig
+	def    ingest.Embedder
+	langs  map[string]ingest.Embedder // keyed by normalized language code
+	spaces map[string]ingest.Embedder // extra vector spaces, by name
+}
+
+// newEmbedders builds the default, language and space embedders of ec.
+func newEmbedders(ec config.EmbeddingConfig) (*embedders, error) {
+	def, err := ingest.NewEmbedderFromConfig(ec)
+	if err != nil {
+		return nil, err
+	}
+
+	// Language-specific query embedders must share the default vector space.
+	langs := make(map[string]ingest.Embedder)
+	for lang := range ec.Languages {
+		langCfg, _ := ec.ForLanguage(lang)
+		e, err := ingest.NewEmbedderFromConfig(langCfg)
+		if err != nil {
+			return nil, util.WrapError(err, "Failed to create embedder for language", slog.String("lang", lang))
+		}
+		if e.Dimension() != def.Dimension() {
+			return nil, util.NewError(fmt.Sprintf("Embedder for language %q has dimension %d, expected %d", lang, e.Dimension(), def.Dimension()))
+		}
+		langs[ingest.NormalizeLang(lang)] = e
+	}
+
+	spaces, err := ingest.NewSpaceEmbedders(ec)
+	if err != nil {
+		return nil, err
+	}
+	return &embedders{cfg: ec, def: def, langs: langs, spaces: spaces}, nil
+}
+
+// indexModel returns the fingerprint of the model of space ("" for the
+// default one), which its vector index must have been built with.
+func (m *embedders) indexModel(space string) storage.IndexModel {
+	ec, e := m.cfg, m.def
+	if space != "" {
+		ec, _ = ec.ForSpace(space)
+		e = m.spaces[space]
+	}
+	provider, model := ec.Identity()
+	return storage.IndexModel{Provider: provider, Model: model, Dimension: e.Dimension()}
+}
+
+// AllowModelMismatch makes s, and the Searchers derived from it afterwards,
+// search vector indexes built with another embedding model, reporting
+// WarningModelMismatch, and lets SetEmbedding switch to such a model,
+// instead of failing with ErrModelMismatch. It returns s.
+func (s *Searcher) AllowModelMismatch() *Searcher {
+	s.allowModelMismatch = true
+	return s
+}
+
+// ModelMismatchAllowed reports whether AllowModelMismatch was called.
+func (s *Searcher) ModelMismatchAllowed() bool {
+	return s.allowModelMismatch
+}
+
+// checkIndexModel returns an error wrapping ErrModelMismatch if the vector
+// index of space was built with another model than s searches it with.
+func (s *Searcher) checkIndexModel(space string) error {
+	return storage.CheckIndexModel(s.vectorIndexPath(space), s.models.Load().indexModel(space))
+}
+
+// EmbeddingConfig returns the embedding settings s currently searches with.
+func (s *Searcher) EmbeddingConfig() config.EmbeddingConfig {
+	return s.models.Load().cfg
+}
+
+// SetEmbedding switches s, and every Searcher derived from it with
+// WithConfig or AsOf, to the models of ec, e.g. a new provider or model.
+// The models are built and the default one is tried on a short text
+// before anything changes; the switch itself is atomic, and searches in
+// flight finish with the old models.
+//
+// The vectors of each model must fit the vector index of its space in s's
+// indexes and in those of others, typically the collections sharing s's
+// embedders. If a model's dimension differs from that of an existing
+// index, SetEmbedding fails with ErrDimensionMismatch and s is unchanged.
+// An index whose fingerprint names another model fails it with
+// ErrModelMismatch unless s allows mismatches (see AllowModelMismatch).
+// Indexes without a fingerprint accept a model of the same dimension,
+// whose vectors only compare with the indexed ones once the corpus is
+// re-indexed.
+func (s *Searcher) SetEmbedding(ctx context.Context, ec config.EmbeddingConfig, others ...*config.Config) error {
+	next, err := newEmbedders(ec)
+	if err != nil {
+		return err
+	}
+	vecs, err := next.def.Embed(ctx, []string{"semango embedder check"})
+	if err != nil {
+		return util.WrapError(err, "The new embedder failed to embed a test text")
+	}
+	if len(vecs) != 1 || len(vecs[0]) != next.def.Dimension() {
+		return util.NewError(fmt.Sprintf("The new embedder returned %d vectors, want 1 of dimension %d", len(vecs), next.def.Dimension()))
+	}
+
+	paths := []string{s.config.VectorIndexPath()}
+	for _, cfg := range others {
+		paths = append(paths, cfg.VectorIndexPath())
+	}
+	spaces := make([]string, 0, len(next.spaces))
+	for name := range next.spaces {
+		spaces = append(spaces, name)
+	}
+	sort.Strings(spaces)
+	for _, path := range paths {
+		for _, space := range append([]string{""}, spaces...) {
+			e := next.def
+			if space != "" {
+				e = next.spaces[space]
+			}
+			p := storage.SpaceIndexPath(path, space)
+			if err := checkDimension(p, e.Dimension()); err != nil {
+				return err
+			}
+			if s.allowModelMismatch {
+				continue
+			}
+			if err := storage.CheckIndexModel(p, next.indexModel(space)); err != nil {
+				return err
+			}
+		}
+	}
+
+	prev := s.models.Swap(next)
+	oldProvider, oldModel := prev.cfg.Identity()
+	provider, model := ec.Identity()
+	util.FromContext(ctx).Info("Switched embedder",
+		"from", oldProvider+"/"+oldModel, "to", provider+"/"+model, "dimension", next.def.Dimension())
+	if oldProvider != provider || oldModel != model {
+		util.FromContext(ctx).Warn("The indexed vectors were embedded with another model; re-index for meaningful vector results",
+			"indexed_with", oldProvider+"/"+oldModel)
+	}
+	return nil
+}
+
+// checkDimension returns ErrDimensionMismatch with a migration hint if the
+// vector index at path exists and does not hold vectors of dimension dim.
+func checkDimension(path string, dim int) error {
+	_, indexDim, err := storage.ReadFaissIndexInfo(path)
+	if errors.Is(err, storage.ErrNoVectorIndex) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if indexDim != dim {
+		return fmt.Errorf("%w: %s holds vectors of dimension %d, the new model produces %d; "+
+			"build an index for the model first, e.g. add it under embedding.spaces to dual-write and re-index, "+
+			"or change the model in the configuration and run semango index --recreate",
+			ErrDimensionMismatch, path, indexDim, dim)
+	}
+	return nil
+}
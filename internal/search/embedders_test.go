@@ -0,0 +1,44 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+func TestSearchRefusesIndexOfAnotherModel(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.GetDefaultConfig()
+	cfg.Embedding.Provider = "local"
+	cfg.Embedding.LocalModelPath = "/models/all-MiniLM-L6-v2"
+	cfg.Lexical.IndexPath = filepath.Join(dir, "bleve")
+	cfg.Vector.IndexPath = filepath.Join(dir, "faiss.index")
+	s := NewSearcherWithEmbedder(cfg, &ingest.NoopEmbedder{})
+
+	if err := s.checkIndexModel(""); err != nil {
+		t.Errorf("index without a fingerprint: %v", err)
+	}
+	if err := storage.WriteIndexModel(cfg.Vector.IndexPath, storage.IndexModel{Provider: "local", Model: "all-MiniLM-L6-v2", Dimension: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.checkIndexModel(""); err != nil {
+		t.Errorf("index of the same model: %v", err)
+	}
+
+	storage.WriteIndexModel(cfg.Vector.IndexPath, storage.IndexModel{Provider: "openai", Model: "text-embedding-3-small", Dimension: 1})
+	if err := os.WriteFile(cfg.Vector.IndexPath, []byte("index"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SearchPage(context.Background(), "query", 0, 10, Options{Mode: ModeVector}); !errors.Is(err, ErrModelMismatch) {
+		t.Errorf("vector search of another model's index: %v", err)
+	}
+	if !s.AllowModelMismatch().ModelMismatchAllowed() || !s.WithConfig(cfg).ModelMismatchAllowed() {
+		t.Error("AllowModelMismatch does not carry over to derived searchers")
+	}
+}
@@ -0,0 +1,191 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// Query expansion modes for Options.Expand. Expansion rewrites the query
+// before retrieval to improve recall for short or ambiguous queries.
+const (
+	// ExpandTerms adds terms that are frequent in the top lexical matches
+	// of the query (pseudo-relevance feedback) to both retrievers' query.
+	ExpandTerms = "terms"
+	// ExpandHyDE embeds a hypothetical answer written by an LLM instead of
+	// the query (Hypothetical Document Embeddings). Lexical matching still
+	// uses the query as given.
+	ExpandHyDE = "hyde"
+	// ExpandMulti has an LLM write variants of the query, searches each
+	// and merges the rankings (see Options.Queries).
+	ExpandMulti = "multi"
+)
+
+// ValidExpand reports whether expand is empty, "none" or an expansion mode.
+func ValidExpand(expand string) bool {
+	switch expand {
+	case "", "none", ExpandTerms, ExpandHyDE, ExpandMulti:
+		return true
+	}
+	return false
+}
+
+// QueryRewriter rewrites queries with an LLM for ExpandHyDE and
+// ExpandMulti.
+type QueryRewriter interface {
+	// WriteHypothetical writes a passage answering query, as a document in
+	// the index might.
+	WriteHypothetical(ctx context.Context, query string) (string, error)
+	// WriteVariants writes up to n rephrasings of query.
+	WriteVariants(ctx context.Context, query string, n int) ([]string, error)
+}
+
+// WithRewriter enables ExpandHyDE and ExpandMulti, rewriting queries with w.
+func (s *Searcher) WithRewriter(w QueryRewriter) *Searcher {
+	s.rewriter = w
+	return s
+}
+
+// CanRewrite reports whether ExpandHyDE and ExpandMulti are available.
+func (s *Searcher) CanRewrite() bool {
+	return s.rewriter != nil
+}
+
+func errNoRewriter(expand string) error {
+	return fmt.Errorf("query expansion %s needs an LLM (see llm.model)", expand)
+}
+
+const (
+	feedbackDocs  = 5 // top lexical matches terms are taken from
+	feedbackTerms = 5 // terms added to the query
+)
+
+// expandQuery returns the query for the lexical and for the vector
+// retriever under opts.Expand. Expansion failures are logged and leave the
+// query unchanged.
+func (s *Searcher) expandQuery(ctx context.Context, bleveIdx *storage.BleveIndex, query, lang string, filter map[string]string, opts Options) (lexical, vector string) {
+	logger := util.FromContext(ctx)
+	switch opts.Expand {
+	case ExpandTerms:
+		hits, err := bleveIdx.SearchTextFiltered(ctx, query, lang, filter, feedbackDocs)
+		if err != nil {
+			logger.Warn("Query expansion failed, searching the query as given", "error", err)
+			return query, query
+		}
+		texts := make([]string, 0, len(hits))
+		for _, hit := range hits {
+			doc, err := bleveIdx.GetDocument(hit.ID)
+			if err != nil || doc == nil {
+				continue
+			}
+			for _, field := range doc.Fields {
+				if field.Name() == "text" {
+					texts = append(texts, string(field.Value()))
+				}
+			}
+		}
+		terms := expansionTerms(query, texts, feedbackTerms)
+		if len(terms) == 0 {
+			return query, query
+		}
+		expanded := query + " " + strings.Join(terms, " ")
+		logger.Info("Expanded query", "expand", opts.Expand, "terms", terms)
+		return expanded, expanded
+	case ExpandHyDE:
+		if s.rewriter == nil || opts.Mode == ModeLexical {
+			return query, query
+		}
+		doc, err := s.rewriter.WriteHypothetical(ctx, query)
+		if err != nil || strings.TrimSpace(doc) == "" {
+			logger.Warn("Hypothetical document generation failed, embedding the query as given", "error", err)
+			return query, query
+		}
+		logger.Debug("Embedding hypothetical document", "expand", opts.Expand, "length", len(doc))
+		return query, doc
+	}
+	return query, query
+}
+
+// expansionTerms picks up to n terms from texts, the top matches of query,
+// to add to it. Terms must occur in at least two of the texts; they are
+// ranked by the number of texts containing them, then by total occurrences.
+// Query terms, stop words and terms shorter than three letters are skipped.
+func expansionTerms(query string, texts []string, n int) []string {
+	if len(texts) < 2 {
+		return nil
+	}
+	skip := make(map[string]bool)
+	for _, t := range tokenize(query) {
+		skip[t] = true
+	}
+	docs := make(map[string]int)
+	count := make(map[string]int)
+	for _, text := range texts {
+		seen := make(map[string]bool)
+		for _, t := range tokenize(text) {
+			if skip[t] || stopWords[t] || len([]rune(t)) < 3 || isNumber(t) {
+				continue
+			}
+			count[t]++
+			if !seen[t] {
+				seen[t] = true
+				docs[t]++
+			}
+		}
+	}
+	var terms []string
+	for t, d := range docs {
+		if d >= 2 {
+			terms = append(terms, t)
+		}
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		a, b := terms[i], terms[j]
+		if docs[a] != docs[b] {
+			return docs[a] > docs[b]
+		}
+		if count[a] != count[b] {
+			return count[a] > count[b]
+		}
+		return a < b
+	})
+	if len(terms) > n {
+		terms = terms[:n]
+	}
+	return terms
+}
+
+// tokenize lowercases text and splits it into runs of letters and digits.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func isNumber(t string) bool {
+	for _, r := range t {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// stopWords are common English words that say nothing about a topic.
+var stopWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`about above after again against all also and any are because been before
+		being below between both but can could did does doing down during each few for from further had has
+		have having her here hers herself him himself his how into its itself just more most not now off
+		once only other our ours out over own same she should some such than that the their theirs them
+		then there these they this those through too under until very was were what when where which while
+		who whom why will with would you your yours yourself use used using may might must shall get gets`) {
+		stopWords[w] = true
+	}
+}
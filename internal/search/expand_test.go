@@ -0,0 +1,39 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpansionTerms(t *testing.T) {
+	texts := []string{
+		"Roll back a release with harbor deploy --rollback. The rollback restores the previous release.",
+		"A rollback is safe while the database migration is reversible.",
+		"Every release has a rollback plan; the migration must be reversible.",
+	}
+	got := expansionTerms("Release", texts, 3)
+	// "release" is the query; "the" is a stop word; "rollback" is in all
+	// three texts, "migration" and "reversible" in two.
+	want := []string{"rollback", "migration", "reversible"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expansionTerms = %v, want %v", got, want)
+	}
+
+	if got := expansionTerms("release", texts[:1], 3); got != nil {
+		t.Errorf("a single text should not expand the query, got %v", got)
+	}
+	if got := expansionTerms("x", []string{"port 8080 ok", "port 8080 ok"}, 3); !reflect.DeepEqual(got, []string{"port"}) {
+		t.Errorf("numbers and short terms should be skipped, got %v", got)
+	}
+}
+
+func TestValidExpand(t *testing.T) {
+	for _, e := range []string{"", "none", ExpandTerms, ExpandHyDE, ExpandMulti} {
+		if !ValidExpand(e) {
+			t.Errorf("ValidExpand(%q) = false", e)
+		}
+	}
+	if ValidExpand("synonyms") {
+		t.Error(`ValidExpand("synonyms") = true`)
+	}
+}
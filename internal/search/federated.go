@@ -0,0 +1,86 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Pager ranks results for a query page by page. Searcher and Federation
+// implement it.
+type Pager interface {
+	SearchPage(ctx context.Context, query string, offset, limit int, opts Options) (Page, error)
+}
+
+// Member is one collection of a Federation.
+type Member struct {
+	Collection string
+	Searcher   Pager
+	// Weight scales the collection's share of the fused score; 0 counts
+	// as 1.
+	Weight float64
+}
+
+// Federation searches several collections as one: each member is searched
+// concurrently and the rankings are merged with weighted Reciprocal Rank
+// Fusion, since the scores of different indexes are not comparable. Results
+// carry the name of their collection.
+type Federation []Member
+
+// SearchPage searches every member for offset+limit results and returns
+// limit fused results from rank offset. Total is the sum of the members'
+// totals. It fails if any member does.
+func (f Federation) SearchPage(ctx context.Context, query string, offset, limit int, opts Options) (Page, error) {
+	pages := make([]Page, len(f))
+	errs := make([]error, len(f))
+	sub := opts
+	sub.Adaptive = 0 // the fused ranking is cut below
+	var wg sync.WaitGroup
+	for i, m := range f {
+		wg.Add(1)
+		go func(i int, m Member) {
+			defer wg.Done()
+			pages[i], errs[i] = m.Searcher.SearchPage(ctx, query, 0, offset+limit, sub)
+		}(i, m)
+	}
+	wg.Wait()
+
+	lists := make([][]Result, len(f))
+	weights := make([]float64, len(f))
+	total := 0
+	var warnings []string
+	for i, m := range f {
+		if errs[i] != nil {
+			return Page{}, fmt.Errorf("collection %s: %w", m.Collection, errs[i])
+		}
+		for j := range pages[i].Results {
+			pages[i].Results[j].Collection = m.Collection
+		}
+		lists[i] = pages[i].Results
+		weights[i] = m.Weight
+		if weights[i] == 0 {
+			weights[i] = 1
+		}
+		total += pages[i].Total
+		for _, w := range pages[i].Warnings {
+			warnings = appendWarnings(warnings, "collection "+m.Collection+": "+w)
+		}
+	}
+
+	results := fuseRankings(lists, weights, opts.Parents)
+	results, total, cutoff := cutAdaptive(results, total, opts)
+	if offset > len(results) {
+		offset = len(results)
+	}
+	results = results[offset:]
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	if opts.Window > 0 {
+		results = collapseWindows(results)
+	}
+	if opts.Path != "" {
+		sortByPosition(results)
+	}
+	return Page{Results: results, Total: total, Warnings: warnings, Cutoff: cutoff}, nil
+}
@@ -0,0 +1,78 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type fixedPager []Result
+
+func (p fixedPager) SearchPage(ctx context.Context, query string, offset, limit int, opts Options) (Page, error) {
+	results := append([]Result(nil), p...)
+	if len(results) > offset+limit {
+		results = results[:offset+limit]
+	}
+	return Page{Results: results[offset:], Total: len(p)}, nil
+}
+
+type failingPager struct{}
+
+func (failingPager) SearchPage(context.Context, string, int, int, Options) (Page, error) {
+	return Page{}, errors.New("index missing")
+}
+
+func TestFederationSearchPage(t *testing.T) {
+	f := Federation{
+		{Collection: "docs", Searcher: fixedPager{{ID: "a"}, {ID: "b"}}},
+		{Collection: "code", Searcher: fixedPager{{ID: "a"}, {ID: "x"}}, Weight: 2},
+	}
+	page, err := f.SearchPage(context.Background(), "q", 0, 10, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same chunk ID in two collections is two results; code's weight
+	// puts its results first at equal ranks.
+	var got []string
+	for _, r := range page.Results {
+		got = append(got, r.Collection+":"+r.ID)
+	}
+	if want := []string{"code:a", "code:x", "docs:a", "docs:b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+	if page.Total != 4 {
+		t.Errorf("Total = %d, want 4", page.Total)
+	}
+
+	page, _ = f.SearchPage(context.Background(), "q", 1, 2, Options{})
+	if len(page.Results) != 2 || page.Results[0].ID != "x" {
+		t.Errorf("second page = %+v", page.Results)
+	}
+
+	f = append(f, Member{Collection: "tickets", Searcher: failingPager{}})
+	if _, err := f.SearchPage(context.Background(), "q", 0, 10, Options{}); err == nil {
+		t.Error("a failing collection should fail the search")
+	}
+}
+
+type warningPager struct{}
+
+func (warningPager) SearchPage(context.Context, string, int, int, Options) (Page, error) {
+	return Page{Results: []Result{{ID: "a"}}, Total: 1, Warnings: []string{WarningLexicalOnly}}, nil
+}
+
+func TestFederationWarnings(t *testing.T) {
+	f := Federation{
+		{Collection: "docs", Searcher: fixedPager{{ID: "a"}}},
+		{Collection: "code", Searcher: warningPager{}},
+	}
+	page, err := f.SearchPage(context.Background(), "q", 0, 10, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"collection code: " + WarningLexicalOnly}
+	if !reflect.DeepEqual(page.Warnings, want) {
+		t.Errorf("warnings = %q, want %q", page.Warnings, want)
+	}
+}
@@ -0,0 +1,65 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ParseFilter parses a metadata filter of space-separated key:value terms,
+// e.g. `source:EmailLoader thread_id:"abc@example.com"`. Values containing
+// spaces must be double-quoted. All terms must match; repeating a key is an
+// error.
+func ParseFilter(s string) (map[string]string, error) {
+	filter := make(map[string]string)
+	rest := strings.TrimSpace(s)
+	for rest != "" {
+		colon := strings.IndexByte(rest, ':')
+		if colon <= 0 {
+			return nil, fmt.Errorf("invalid filter term %q: expected key:value", firstField(rest))
+		}
+		key := rest[:colon]
+		if strings.IndexFunc(key, unicode.IsSpace) >= 0 {
+			return nil, fmt.Errorf("invalid filter term %q: expected key:value", firstField(rest))
+		}
+		rest = rest[colon+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in filter value for %q", key)
+			}
+			value, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			value = firstField(rest)
+			rest = rest[len(value):]
+		}
+		if value == "" {
+			return nil, fmt.Errorf("empty filter value for %q", key)
+		}
+		if _, dup := filter[key]; dup {
+			return nil, fmt.Errorf("filter key %q given more than once", key)
+		}
+		filter[key] = value
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+	}
+	return filter, nil
+}
+
+func firstField(s string) string {
+	if i := strings.IndexFunc(s, unicode.IsSpace); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// matchesFilter reports whether meta has every key/value pair in filter.
+func matchesFilter(meta, filter map[string]string) bool {
+	for k, v := range filter {
+		if meta[k] != v {
+			return false
+		}
+	}
+	return true
+}
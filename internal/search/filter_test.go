@@ -0,0 +1,36 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    map[string]string
+		wantErr bool
+	}{
+		{in: "", want: map[string]string{}},
+		{in: "source:EmailLoader", want: map[string]string{"source": "EmailLoader"}},
+		{
+			in:   `  lang:de  chapter:"The Sea"  thread_id:a@b:c `,
+			want: map[string]string{"lang": "de", "chapter": "The Sea", "thread_id": "a@b:c"},
+		},
+		{in: "nocolon", wantErr: true},
+		{in: ":value", wantErr: true},
+		{in: "key:", wantErr: true},
+		{in: `title:"open`, wantErr: true},
+		{in: "a:1 a:2", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFilter(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFilter(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseFilter(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package search
+
+// GroupDocument groups results by document, see Options.GroupBy.
+const GroupDocument = "document"
+
+// groupFetchFactor multiplies the candidates ranked for a page when results
+// are grouped or capped per document, since that leaves fewer of them.
+const groupFetchFactor = 3
+
+// ValidGroupBy reports whether groupBy is empty or GroupDocument.
+func ValidGroupBy(groupBy string) bool {
+	return groupBy == "" || groupBy == GroupDocument
+}
+
+// grouped reports whether opts group or cap results per document.
+func (o Options) grouped() bool {
+	return o.GroupBy != "" || o.MaxChunksPerDoc > 0
+}
+
+// groupResults applies Options.GroupBy and Options.MaxChunksPerDoc to
+// results, which must be sorted by score: grouped by document, the best
+// chunk of each document is the result and the next ones are nested in its
+// Chunks; otherwise the chunks of a document after the first
+// MaxChunksPerDoc are left out. Documents of different collections are
+// distinct.
+func groupResults(results []Result, opts Options) []Result {
+	if !opts.grouped() {
+		return results
+	}
+	limit := opts.MaxChunksPerDoc
+	first := make(map[string]int) // document -> index in out
+	count := make(map[string]int)
+	out := results[:0]
+	for _, r := range results {
+		key := r.Collection + "\x00" + r.Path
+		count[key]++
+		if limit > 0 && count[key] > limit {
+			continue
+		}
+		i, ok := first[key]
+		if opts.GroupBy != GroupDocument || !ok {
+			first[key] = len(out)
+			out = append(out, r)
+			continue
+		}
+		r.Chunks = nil
+		out[i].Chunks = append(out[i].Chunks, r)
+	}
+	return out
+}
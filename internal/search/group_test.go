@@ -0,0 +1,43 @@
+package search
+
+import "testing"
+
+func TestGroupResults(t *testing.T) {
+	results := func() []Result {
+		return []Result{
+			{ID: "a1", Path: "a.md"},
+			{ID: "a2", Path: "a.md"},
+			{ID: "b1", Path: "b.md"},
+			{ID: "a3", Path: "a.md"},
+			{ID: "a1", Path: "a.md", Collection: "other"},
+		}
+	}
+	ids := func(rs []Result) []string {
+		var out []string
+		for _, r := range rs {
+			out = append(out, r.ID)
+		}
+		return out
+	}
+
+	got := groupResults(results(), Options{GroupBy: GroupDocument})
+	if len(got) != 3 || got[0].ID != "a1" || got[1].ID != "b1" || got[2].Collection != "other" {
+		t.Fatalf("grouped = %+v", got)
+	}
+	if c := ids(got[0].Chunks); len(c) != 2 || c[0] != "a2" || c[1] != "a3" {
+		t.Errorf("nested chunks = %v", c)
+	}
+
+	got = groupResults(results(), Options{GroupBy: GroupDocument, MaxChunksPerDoc: 2})
+	if c := ids(got[0].Chunks); len(c) != 1 || c[0] != "a2" {
+		t.Errorf("nested chunks capped at 2 = %v", c)
+	}
+
+	got = groupResults(results(), Options{MaxChunksPerDoc: 1})
+	if g := ids(got); len(g) != 3 || g[0] != "a1" || g[1] != "b1" || g[2] != "a1" {
+		t.Errorf("capped = %v", g)
+	}
+	if got := groupResults(results(), Options{}); len(got) != 5 {
+		t.Errorf("ungrouped = %d results", len(got))
+	}
+}
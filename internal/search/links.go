@@ -0,0 +1,106 @@
+package search
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+// linkRenderer expands the configured link template for a search result.
+// Supported placeholders: {path}, {abs_path}, {line}, {branch} and
+// {meta.<key>} for any metadata field.
+type linkRenderer struct {
+	template string
+	root     string
+	branch   string
+	escape   bool // URL-escape path segments for http(s) templates
+}
+
+var linkPlaceholder = regexp.MustCompile(`\{([a-z_]+(?:\.[A-Za-z0-9_.-]+)?)\}`)
+
+func newLinkRenderer(cfg config.LinksConfig) *linkRenderer {
+	if cfg.Template == "" {
+		return nil
+	}
+	root := cfg.Root
+	if root == "" {
+		root, _ = os.Getwd()
+	}
+	branch := cfg.Branch
+	if branch == "" {
+		branch = "main"
+	}
+	lower := strings.ToLower(cfg.Template)
+	return &linkRenderer{
+		template: cfg.Template,
+		root:     root,
+		branch:   branch,
+		escape:   strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://"),
+	}
+}
+
+// Render returns the link for a result at path (relative to the indexed
+// root). A nil renderer yields an empty link.
+func (lr *linkRenderer) Render(path string, meta map[string]string) string {
+	if lr == nil || path == "" {
+		return ""
+	}
+	// Table-level paths such as "data.sqlite#users" point into a file.
+	if i := strings.IndexByte(path, '#'); i >= 0 {
+		path = path[:i]
+	}
+	line := meta["line"]
+	if line == "" {
+		line = "1"
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, loc := range linkPlaceholder.FindAllStringSubmatchIndex(lr.template, -1) {
+		sb.WriteString(lr.template[last:loc[0]])
+		last = loc[1]
+		name := lr.template[loc[2]:loc[3]]
+		switch name {
+		case "path":
+			sb.WriteString(lr.escapePath(filepath.ToSlash(path)))
+		case "abs_path":
+			abs := filepath.ToSlash(filepath.Join(lr.root, path))
+			// "vscode://file/{abs_path}" should not produce a double slash.
+			if strings.HasSuffix(sb.String(), "/") {
+				abs = strings.TrimPrefix(abs, "/")
+			}
+			sb.WriteString(lr.escapePath(abs))
+		case "line":
+			sb.WriteString(line)
+		case "branch":
+			sb.WriteString(lr.branch)
+		default:
+			if key, ok := strings.CutPrefix(name, "meta."); ok {
+				if lr.escape {
+					sb.WriteString(url.QueryEscape(meta[key]))
+				} else {
+					sb.WriteString(meta[key])
+				}
+			} else {
+				sb.WriteString(lr.template[loc[0]:loc[1]])
+			}
+		}
+	}
+	sb.WriteString(lr.template[last:])
+	return sb.String()
+}
+
+func (lr *linkRenderer) escapePath(p string) string {
+	if !lr.escape {
+		return p
+	}
+	segs := strings.Split(p, "/")
+	for i, s := range segs {
+		segs[i] = url.PathEscape(s)
+	}
+	return strings.Join(segs, "/")
+}
@@ -0,0 +1,51 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+func TestLinkRenderer(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.LinksConfig
+		path     string
+		meta     map[string]string
+		expected string
+	}{
+		{
+			name:     "vscode with line",
+			cfg:      config.LinksConfig{Template: "vscode://file/{abs_path}:{line}", Root: "/repo"},
+			path:     "docs/readme.md",
+			meta:     map[string]string{"line": "42"},
+			expected: "vscode://file/repo/docs/readme.md:42",
+		},
+		{
+			name:     "github blob defaults",
+			cfg:      config.LinksConfig{Template: "https://github.com/acme/app/blob/{branch}/{path}#L{line}"},
+			path:     "docs/my file.md",
+			expected: "https://github.com/acme/app/blob/main/docs/my%20file.md#L1",
+		},
+		{
+			name:     "table path and meta placeholder",
+			cfg:      config.LinksConfig{Template: "{path}?table={meta.table}", Branch: "dev"},
+			path:     "data/app.sqlite#users",
+			meta:     map[string]string{"table": "users"},
+			expected: "data/app.sqlite?table=users",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newLinkRenderer(tt.cfg).Render(tt.path, tt.meta)
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	var disabled *linkRenderer = newLinkRenderer(config.LinksConfig{})
+	if got := disabled.Render("a.md", nil); got != "" {
+		t.Errorf("expected empty link without template, got %q", got)
+	}
+}
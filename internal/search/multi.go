@@ -0,0 +1,164 @@
+package search
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/util"
+)
+
+const (
+	// MaxQueryVariants bounds Options.Queries.
+	MaxQueryVariants = 9
+	// generatedVariants is the number of variants ExpandMulti asks for.
+	generatedVariants = 3
+	// rrfK damps the weight of top ranks in Reciprocal Rank Fusion.
+	rrfK = 60.0
+)
+
+// searchMulti searches query and each of its variants for offset+limit
+// results and merges the rankings with Reciprocal Rank Fusion, so chunks
+// found by several variants rise to the top.
+func (s *Searcher) searchMulti(ctx context.Context, query string, offset, limit int, opts Options) (Page, error) {
+	logger := util.FromContext(ctx)
+	queries := append([]string{query}, opts.Queries...)
+	sub := opts
+	sub.Queries = nil
+	// The fused ranking is cut and grouped below.
+	sub.Adaptive = 0
+	sub.GroupBy, sub.MaxChunksPerDoc = "", 0
+	fetch := offset + limit
+	if opts.grouped() {
+		fetch *= groupFetchFactor
+	}
+	if opts.Expand == ExpandMulti {
+		sub.Expand = ""
+		if s.rewriter == nil {
+			return Page{}, errNoRewriter(opts.Expand)
+		}
+		variants, err := s.rewriter.WriteVariants(ctx, query, generatedVariants)
+		if err != nil {
+			logger.Warn("Query variant generation failed, searching the given queries only", "error", err)
+		}
+		queries = append(queries, variants...)
+	}
+	queries = distinctQueries(queries)
+	logger.Info("Searching query variants", "queries", queries)
+
+	lists := make([][]Result, 0, len(queries))
+	total := 0
+	var warnings []string
+	for _, q := range queries {
+		page, err := s.searchPage(ctx, q, 0, fetch, sub)
+		if err != nil {
+			return Page{}, err
+		}
+		lists = append(lists, page.Results)
+		total = max(total, page.Total)
+		warnings = appendWarnings(warnings, page.Warnings...)
+	}
+
+	results := fuseRankings(lists, nil, opts.Parents)
+	if s.config.Files.Dedup != "" {
+		results = collapseDuplicates(results)
+	}
+	results = groupResults(results, opts)
+	total = max(total, len(results))
+	results, total, cutoff := cutAdaptive(results, total, opts)
+	if offset > len(results) {
+		offset = len(results)
+	}
+	results = results[offset:]
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	if opts.Window > 0 {
+		results = collapseWindows(results)
+	}
+	if opts.Path != "" {
+		sortByPosition(results)
+	}
+	return Page{Results: results, Total: total, Warnings: warnings, Cutoff: cutoff}, nil
+}
+
+// appendWarnings appends the warnings of add that warnings lacks.
+func appendWarnings(warnings []string, add ...string) []string {
+	for _, w := range add {
+		if !slices.Contains(warnings, w) {
+			warnings = append(warnings, w)
+		}
+	}
+	return warnings
+}
+
+// fuseRankings merges ranked lists with Reciprocal Rank Fusion: a result
+// scores the sum of weight/(rrfK+rank) over the lists it appears in, and
+// keeps the fields of its best-ranked appearance. weights has one entry per
+// list, or is nil for equal weights of 1. Results of different collections
+// never merge; with byParent, results of the same parent section do.
+func fuseRankings(lists [][]Result, weights []float64, byParent bool) []Result {
+	type fused struct {
+		result Result
+		score  float64
+		best   int
+	}
+	byKey := make(map[string]*fused)
+	var order []string
+	for i, list := range lists {
+		weight := 1.0
+		if weights != nil {
+			weight = weights[i]
+		}
+		for rank, r := range list {
+			key := r.ID
+			if p := r.Meta["parent_id"]; byParent && p != "" {
+				key = "parent:" + p
+			}
+			key = r.Collection + "\x00" + key
+			f, ok := byKey[key]
+			if !ok {
+				f = &fused{result: r, best: rank}
+				byKey[key] = f
+				order = append(order, key)
+			} else if rank < f.best {
+				f.result, f.best = r, rank
+			}
+			f.score += weight / (rrfK + float64(rank+1))
+		}
+	}
+	out := make([]Result, len(order))
+	for i, key := range order {
+		f := byKey[key]
+		out[i] = f.result
+		out[i].Score = f.score
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		if out[i].ID != out[j].ID {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].Collection < out[j].Collection
+	})
+	return out
+}
+
+// distinctQueries drops blank and repeated queries, ignoring case and
+// surrounding space, keeping the first occurrence.
+func distinctQueries(queries []string) []string {
+	seen := make(map[string]bool, len(queries))
+	out := queries[:0:0]
+	for _, q := range queries {
+		q = strings.TrimSpace(q)
+		key := strings.ToLower(q)
+		if q == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, q)
+	}
+	return out
+}
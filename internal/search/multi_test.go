@@ -0,0 +1,48 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func ids(results []Result) []string {
+	out := make([]string, len(results))
+	for i, r := range results {
+		out[i] = r.ID
+	}
+	return out
+}
+
+func TestFuseRankings(t *testing.T) {
+	lists := [][]Result{
+		{{ID: "a"}, {ID: "b"}, {ID: "c"}},
+		{{ID: "c"}, {ID: "b"}, {ID: "d"}},
+	}
+	got := fuseRankings(lists, nil, false)
+	// b and c are found by both variants and beat a, the first hit of one;
+	// c ranks 1st and 3rd, slightly ahead of b's 2nd and 2nd.
+	if want := []string{"c", "b", "a", "d"}; !reflect.DeepEqual(ids(got), want) {
+		t.Fatalf("fused order = %v, want %v", ids(got), want)
+	}
+	if want := 2 / (rrfK + 2); got[1].Score != want {
+		t.Errorf("score of b = %v, want %v", got[1].Score, want)
+	}
+
+	parents := [][]Result{
+		{{ID: "s1", Text: "section one", Meta: map[string]string{"parent_id": "p1"}}},
+		{{ID: "s2", Text: "section one", Meta: map[string]string{"parent_id": "p1"}}, {ID: "x"}},
+	}
+	if got := ids(fuseRankings(parents, nil, true)); !reflect.DeepEqual(got, []string{"s1", "x"}) {
+		t.Errorf("chunks of one parent should merge, got %v", got)
+	}
+	if got := ids(fuseRankings(parents, nil, false)); len(got) != 3 {
+		t.Errorf("without parents every chunk counts, got %v", got)
+	}
+}
+
+func TestDistinctQueries(t *testing.T) {
+	got := distinctQueries([]string{"Rollback", " rollback ", "", "undo a release"})
+	if want := []string{"Rollback", "undo a release"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("distinctQueries = %v, want %v", got, want)
+	}
+}
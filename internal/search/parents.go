@@ -0,0 +1,114 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// maxParentChunks bounds the child chunks read to rebuild one parent section.
+const maxParentChunks = 1000
+
+// collapseParents keeps only the best result of each parent section, so a
+// parent is returned once however many of its chunks matched. results must
+// be sorted by score; results without a parent are kept as they are.
+func collapseParents(results []Result) []Result {
+	seen := make(map[string]bool)
+	out := results[:0]
+	for _, r := range results {
+		if id := r.Meta["parent_id"]; id != "" {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// expandParents replaces the text of each result that has a parent with the
+// parent section, rebuilt from its chunks in the lexical index. The matched
+// chunk's own metadata, including its offset, is kept.
+func (s *Searcher) expandParents(ctx context.Context, bleveIdx *storage.BleveIndex, results []Result, query string) {
+	for i := range results {
+		id := results[i].Meta["parent_id"]
+		if id == "" {
+			continue
+		}
+		text, err := parentText(ctx, bleveIdx, id)
+		if err != nil || text == "" {
+			util.FromContext(ctx).Warn("Could not rebuild parent section, returning the chunk", "parent_id", id, "error", err)
+			continue
+		}
+		results[i].Text = text
+		results[i].Highlights = s.createHighlights(text, query, results[i].Meta)
+	}
+}
+
+// childSpan is a chunk of a parent section and its byte offset in the source.
+type childSpan struct {
+	offset int
+	text   string
+}
+
+// parentText reads the chunks of parent parentID and joins them.
+func parentText(ctx context.Context, bleveIdx *storage.BleveIndex, parentID string) (string, error) {
+	ids, _, err := bleveIdx.FilterIDs(ctx, map[string]string{"parent_id": parentID}, maxParentChunks)
+	if err != nil {
+		return "", err
+	}
+	var spans []childSpan
+	for _, id := range ids {
+		doc, err := bleveIdx.GetDocument(id)
+		if err != nil || doc == nil {
+			continue
+		}
+		var text, parent, offset string
+		for _, field := range doc.Fields {
+			switch field.Name() {
+			case "text":
+				text = string(field.Value())
+			case "meta.parent_id":
+				parent = string(field.Value())
+			case "meta.offset":
+				offset = string(field.Value())
+			}
+		}
+		off, err := strconv.Atoi(offset)
+		if parent != parentID || err != nil {
+			continue
+		}
+		spans = append(spans, childSpan{offset: off, text: text})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].offset < spans[j].offset })
+	return mergeSpans(spans), nil
+}
+
+// mergeSpans joins chunks sorted by offset into the text they were cut from,
+// dropping the overlap between neighbours. Gaps, such as a code block chunked
+// separately, are bridged with a blank line.
+func mergeSpans(spans []childSpan) string {
+	var b strings.Builder
+	end := -1
+	for _, sp := range spans {
+		spEnd := sp.offset + len(sp.text)
+		switch {
+		case end < 0:
+			b.WriteString(sp.text)
+		case sp.offset > end:
+			b.WriteString("\n\n")
+			b.WriteString(sp.text)
+		case spEnd > end:
+			b.WriteString(sp.text[end-sp.offset:])
+		}
+		if spEnd > end {
+			end = spEnd
+		}
+	}
+	return b.String()
+}
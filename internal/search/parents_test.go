@@ -0,0 +1,36 @@
+package search
+
+import "testing"
+
+func TestMergeSpans(t *testing.T) {
+	src := "The quick brown fox jumps over the lazy dog."
+	spans := []childSpan{
+		{offset: 0, text: src[0:20]},
+		{offset: 16, text: src[16:35]}, // overlaps the first chunk
+		{offset: 30, text: src[30:]},
+	}
+	if got := mergeSpans(spans); got != src {
+		t.Errorf("mergeSpans = %q, want %q", got, src)
+	}
+
+	gap := []childSpan{{offset: 0, text: "intro"}, {offset: 20, text: "after the code"}}
+	if got := mergeSpans(gap); got != "intro\n\nafter the code" {
+		t.Errorf("gap: got %q", got)
+	}
+	if got := mergeSpans(nil); got != "" {
+		t.Errorf("empty: got %q", got)
+	}
+}
+
+func TestCollapseParents(t *testing.T) {
+	results := []Result{
+		{Score: 0.9, Meta: map[string]string{"parent_id": "p1"}},
+		{Score: 0.8, Meta: map[string]string{}},
+		{Score: 0.7, Meta: map[string]string{"parent_id": "p1"}},
+		{Score: 0.6, Meta: map[string]string{"parent_id": "p2"}},
+	}
+	got := collapseParents(results)
+	if len(got) != 3 || got[0].Score != 0.9 || got[1].Score != 0.8 || got[2].Score != 0.6 {
+		t.Errorf("collapseParents = %+v", got)
+	}
+}
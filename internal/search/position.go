@@ -0,0 +1,45 @@
+package search
+
+import (
+	"sort"
+	"strconv"
+)
+
+// positionKeys are the metadata keys locating a chunk within its document,
+// most significant first. Sections (notebook cells, EPUB chapters) come
+// before the offset, which loaders reset at the start of each section; line
+// and start cover loaders that record no offset, such as config files and
+// video transcripts.
+var positionKeys = []string{"cell_index", "chapter_index", "offset", "line", "start"}
+
+// sortByPosition orders results of a single document by where their chunks
+// occur in it. Chunks without position metadata keep their relative order
+// after the positioned ones.
+func sortByPosition(results []Result) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return positionLess(results[i].Meta, results[j].Meta)
+	})
+}
+
+func positionLess(a, b map[string]string) bool {
+	for _, key := range positionKeys {
+		av, aok := metaNumber(a, key)
+		bv, bok := metaNumber(b, key)
+		switch {
+		case aok && bok && av != bv:
+			return av < bv
+		case aok != bok:
+			return aok
+		}
+	}
+	return false
+}
+
+func metaNumber(meta map[string]string, key string) (float64, bool) {
+	v, ok := meta[key]
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	return f, err == nil
+}
@@ -0,0 +1,29 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortByPosition(t *testing.T) {
+	results := []Result{
+		{Text: "no position", Meta: map[string]string{}},
+		{Text: "offset 900", Meta: map[string]string{"offset": "900"}},
+		{Text: "cell 2", Meta: map[string]string{"cell_index": "2", "offset": "0"}},
+		{Text: "offset 40", Meta: map[string]string{"offset": "40"}},
+		{Text: "cell 1 offset 500", Meta: map[string]string{"cell_index": "1", "offset": "500"}},
+		{Text: "cell 1 offset 0", Meta: map[string]string{"cell_index": "1", "offset": "0"}},
+		{Text: "line 3", Meta: map[string]string{"line": "3"}},
+		{Text: "offset 40 (2)", Meta: map[string]string{"offset": "40"}},
+	}
+	sortByPosition(results)
+
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Text)
+	}
+	want := []string{"cell 1 offset 0", "cell 1 offset 500", "cell 2", "offset 40", "offset 40 (2)", "offset 900", "line 3", "no position"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %q, want %q", got, want)
+	}
+}
@@ -2,13 +2,14 @@ package search
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log/slog"
-	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync/atomic"
 
-	"github.com/blevesearch/go-faiss"
+	blevesearch "github.com/blevesearch/bleve/v2/search"
 	"github.com/omarkamali/semango/internal/config"
 	"github.com/omarkamali/semango/internal/ingest"
 	"github.com/omarkamali/semango/internal/storage"
@@ -18,11 +19,128 @@ import (
 // Searcher handles search operations using the real search implementation
 type Searcher struct {
 	config   *config.Config
-	embedder ingest.Embedder
+	models   *atomic.Pointer[embedders] // shared with copies; see SetEmbedding
+	tuning   *atomic.Pointer[tuning]    // shared with copies; see SetTuning
+	links    *linkRenderer
+	snapshot *storage.BundlePaths    // set by AsOf; nil for the live indexes
+	indexes  *atomic.Pointer[string] // the generation directory searched; see Reload
+	rewriter QueryRewriter           // set by WithRewriter; nil disables LLM query expansion
+	// allowModelMismatch is set by AllowModelMismatch.
+	allowModelMismatch bool
 }
 
+// Options carries per-query settings.
+type Options struct {
+	// Lang is a language hint such as "de" or "pt-BR", or LangAuto. It
+	// selects the language analyzer for lexical matching and, if one is
+	// configured under embedding.languages, the embedder used for the query.
+	Lang string
+	// Filter restricts results to chunks whose metadata has exactly these
+	// key/value pairs. Both lexical and vector search are restricted up
+	// front rather than filtering an over-fetched candidate list.
+	Filter map[string]string
+	// Path restricts the search to the chunks of one document, matched
+	// exactly. Results are then returned in document order rather than by
+	// score, e.g. for jumping to the relevant sections of an open file.
+	Path string
+	// Mode selects the retrievers: ModeHybrid (the default when empty),
+	// ModeLexical or ModeVector.
+	Mode string
+	// Parents returns the parent section of each matched chunk instead of
+	// the chunk itself, once per parent. It needs files.parent_chunk_size to
+	// have been set at indexing time; chunks without a parent are returned
+	// unchanged.
+	Parents bool
+	// Window widens each result to the chunks around it: up to this many
+	// before and after it in its document, at most MaxWindow. Results inside
+	// the window of a better one on the same page are left out. It needs
+	// the chunk links recorded since they were added, and leaves results
+	// expanded to their parent section alone.
+	Window int
+	// Boosts multiply the score of results whose path matches a pattern,
+	// such as {"docs/**": 1.5, "tests/**": 0.5}, after fusion. Factors of
+	// all matching patterns are multiplied; nothing is excluded.
+	Boosts map[string]float64
+	// Space selects the vector space to search: one of embedding.spaces, or
+	// DefaultSpace (also when empty) for the default model. Lexical matching
+	// is the same in every space.
+	Space string
+	// Expand rewrites the query before retrieval: ExpandTerms, ExpandHyDE
+	// or ExpandMulti. Empty or "none" searches the query as given.
+	Expand string
+	// Queries are further variants of the query, e.g. sub-questions an
+	// agent decomposed it into. Each is searched like the query and the
+	// rankings are merged with Reciprocal Rank Fusion.
+	Queries []string
+	// Adaptive, when above 0, returns results only down to the elbow of
+	// the score distribution: the largest drop between neighbouring scores,
+	// if it is at least this fraction of the top score (see
+	// AdaptiveCutoff). The limit still caps the count. It keeps weakly
+	// related chunks out of RAG contexts without guessing a top_k.
+	Adaptive float64
+	// AdaptiveFull keeps the results below the elbow; Page.Cutoff still
+	// says where it is.
+	AdaptiveFull bool
+	// GroupBy set to GroupDocument returns one result per document: its
+	// best chunk, with the document's other matching chunks, best first,
+	// in Result.Chunks. Empty returns every chunk as a result of its own.
+	GroupBy string
+	// MaxChunksPerDoc, when above 0, keeps at most this many chunks of a
+	// document, counting the nested ones when grouped, so one long
+	// document cannot fill the page.
+	MaxChunksPerDoc int
+	// LexicalSyntax SyntaxAdvanced parses the query for the lexical
+	// retriever as a storage.LexicalQuery: phrases, required and excluded
+	// terms, metadata fields and fuzzy terms. The vector retriever and
+	// highlighting then see only the words looked for in the text. Empty or
+	// SyntaxSimple matches the query's words as written.
+	LexicalSyntax string
+}
+
+// DefaultSpace names the vector space of the default embedding model.
+const DefaultSpace = "default"
+
+// LangAuto as Options.Lang detects the language of the query, see
+// ingest.DetectLanguage; queries too short to tell get no language.
+const LangAuto = "auto"
+
+// Search modes. A single-retriever mode skips the other index entirely and
+// scores results by that retriever's normalized score alone.
+const (
+	ModeHybrid  = "hybrid"
+	ModeLexical = "lexical"
+	ModeVector  = "vector"
+)
+
+// ValidMode reports whether mode is empty or one of the search modes.
+func ValidMode(mode string) bool {
+	switch mode {
+	case "", ModeHybrid, ModeLexical, ModeVector:
+		return true
+	}
+	return false
+}
+
+// Lexical query syntaxes for Options.LexicalSyntax.
+const (
+	SyntaxSimple   = "simple"
+	SyntaxAdvanced = "advanced"
+)
+
+// ValidLexicalSyntax reports whether syntax is empty or a lexical query
+// syntax.
+func ValidLexicalSyntax(syntax string) bool {
+	return syntax == "" || syntax == SyntaxSimple || syntax == SyntaxAdvanced
+}
+
+// maxFilterIDs bounds the allowlist passed to the vector index. Filters that
+// match more chunks fall back to an unrestricted vector search whose hits are
+// filtered afterwards.
+const maxFilterIDs = 100000
+
 // Result represents a search result
 type Result struct {
+	ID            string                 `json:"id"`             // Chunk ID
 	Score         float64                `json:"score"`          // Combined score
 	LexicalScore  float64                `json:"lexical_score"`  // BM25 relevance score
 	SemanticScore float64                `json:"semantic_score"` // Cosine similarity score
@@ -31,115 +149,302 @@ type Result struct {
 	Text          string                 `json:"text"`
 	Meta          map[string]string      `json:"meta,omitempty"`
 	Highlights    map[string]interface{} `json:"highlights,omitempty"`
-}
-
-// Stats represents search statistics
-type Stats struct {
-	TotalDocuments int `json:"total_documents"`
-	TotalChunks    int `json:"total_chunks"`
-	IndexSize      int `json:"index_size_bytes"`
+	Link          string                 `json:"link,omitempty"`       // Rendered from links.template
+	Truncated     bool                   `json:"truncated,omitempty"`  // Text was cut to a maximum length
+	Collection    string                 `json:"collection,omitempty"` // Set by federated searches
+	Window        []string               `json:"window,omitempty"`     // Chunk IDs of Text with Options.Window, in document order
+	Aliases       []string               `json:"aliases,omitempty"`    // Paths of duplicates of the chunk, see files.dedup
+	Chunks        []Result               `json:"chunks,omitempty"`     // Further chunks of the document with Options.GroupBy
 }
 
 // NewSearcher creates a new searcher instance with real search capabilities
 func NewSearcher(cfg *config.Config) (*Searcher, error) {
-	// Initialize embedder (same logic as search command)
-	var embedder ingest.Embedder
-	prov := cfg.Embedding.Provider
-	switch prov {
-	case "openai", "": // default to openai
-		apiKey := os.Getenv("OPENAI_API_KEY")
-		if apiKey == "" {
-			return nil, util.NewError("OpenAI API key is required but not found in OPENAI_API_KEY environment variable")
-		}
-		openCfg := ingest.OpenAIConfig{
-			APIKey:     apiKey,
-			Model:      cfg.Embedding.Model,
-			BatchSize:  cfg.Embedding.BatchSize,
-			Concurrent: cfg.Embedding.Concurrent,
-		}
-		e, err := ingest.NewOpenAIEmbedder(openCfg)
-		if err != nil {
-			return nil, util.WrapError(err, "Failed to create OpenAI embedder")
-		}
-		embedder = e
-	case "local":
-		if cfg.Embedding.LocalModelPath == "" {
-			return nil, util.NewError("Local model path is required for local embedder provider")
+	models, err := newEmbedders(cfg.Embedding)
+	if err != nil {
+		return nil, err
+	}
+	s := &Searcher{
+		config: cfg,
+		models: &atomic.Pointer[embedders]{},
+		tuning: newTuning(cfg),
+		links:  newLinkRenderer(cfg.Links),
+	}
+	s.models.Store(models)
+	s.Reload()
+	return s, nil
+}
+
+// NewSearcherWithEmbedder creates a searcher that embeds queries with e
+// instead of the models configured under cfg.Embedding, e.g. a fake
+// embedder in tests. Language-specific embedders and vector spaces are not
+// used.
+func NewSearcherWithEmbedder(cfg *config.Config, e ingest.Embedder) *Searcher {
+	s := &Searcher{
+		config: cfg,
+		models: &atomic.Pointer[embedders]{},
+		tuning: newTuning(cfg),
+		links:  newLinkRenderer(cfg.Links),
+	}
+	s.models.Store(&embedders{cfg: cfg.Embedding, def: e})
+	s.Reload()
+	return s
+}
+
+// Embedder returns the default embedder, for callers that index new content
+// into the same vector space.
+func (s *Searcher) Embedder() ingest.Embedder {
+	return s.models.Load().def
+}
+
+// SpaceEmbedders returns the embedders of the configured vector spaces, for
+// callers that index new content into every space.
+func (s *Searcher) SpaceEmbedders() map[string]ingest.Embedder {
+	return s.models.Load().spaces
+}
+
+// WithConfig returns a Searcher over the indexes of cfg, typically a
+// collection's configuration (see config.Config.ForCollection). It shares
+// s's embedders, so cfg must use the same embedding settings.
+func (s *Searcher) WithConfig(cfg *config.Config) *Searcher {
+	c := *s
+	c.config = cfg
+	c.links = newLinkRenderer(cfg.Links)
+	c.indexes = nil
+	c.Reload()
+	return &c
+}
+
+// AsOf returns a Searcher over the indexes at paths, typically an index
+// bundle unpacked with storage.ExtractBundle, instead of the live ones. It
+// shares s's embedders. Bundles only carry the default vector space, so
+// other spaces cannot be searched.
+func (s *Searcher) AsOf(paths storage.BundlePaths) *Searcher {
+	c := *s
+	c.snapshot = &paths
+	return &c
+}
+
+// lexicalPath returns the Bleve index to search.
+func (s *Searcher) lexicalPath() string {
+	if s.snapshot != nil {
+		return s.snapshot.Lexical
+	}
+	return s.inIndexDir(s.config.Lexical.IndexPath)
+}
+
+// Reload resolves the index directory again and reports whether it now
+// points to another generation, e.g. after semango index --rebuild swapped
+// in new indexes. Searches resolve it once, when the Searcher is created
+// and on Reload, so that a swap never makes one search read the lexical
+// index of one generation and the vector index of another; searches in
+// flight finish with the generation they started with.
+func (s *Searcher) Reload() bool {
+	if s.indexes == nil {
+		s.indexes = &atomic.Pointer[string]{}
+	}
+	dir := storage.ResolveIndexDir(storage.IndexDir(s.config.Lexical.IndexPath))
+	if prev := s.indexes.Swap(&dir); prev != nil && *prev != dir {
+		return true
+	}
+	return false
+}
+
+// inIndexDir returns the path p of the configured index directory in the
+// generation directory it was resolved to. Paths elsewhere are returned
+// unchanged.
+func (s *Searcher) inIndexDir(p string) string {
+	if s.indexes == nil {
+		return p
+	}
+	dir := storage.IndexDir(s.config.Lexical.IndexPath)
+	resolved := *s.indexes.Load()
+	if resolved == dir || filepath.Dir(filepath.Clean(p)) != dir {
+		return p
+	}
+	return filepath.Join(resolved, filepath.Base(p))
+}
+
+// Page is one page of ranked results.
+type Page struct {
+	Results []Result
+	// Total is the number of candidates ranked for the query: the fused
+	// lexical and vector hits that passed the filter, before paging.
+	Total int
+	// Warnings say how the results fall short of what was asked, e.g.
+	// WarningLexicalOnly.
+	Warnings []string
+	// Cutoff is set by adaptive searches: the number of results, counted
+	// from rank 1, above the score drop-off.
+	Cutoff int
+}
+
+// WarningLexicalOnly is reported when a hybrid or vector search is answered
+// from the lexical index alone because the vector index is missing or
+// FAISS is not supported on this platform.
+const WarningLexicalOnly = "vector index unavailable; results are from the lexical index only"
+
+// WarningModelMismatch is reported when a hybrid or vector search is
+// answered from a vector index built with another embedding model, which
+// only a Searcher with AllowModelMismatch does.
+const WarningModelMismatch = "vector index was built with another embedding model; vector results are not meaningful until it is rebuilt"
+
+// Search performs a real search query using the existing search implementation
+func (s *Searcher) Search(ctx context.Context, query string, topK int, opts Options) ([]Result, error) {
+	page, err := s.SearchPage(ctx, query, 0, topK, opts)
+	return page.Results, err
+}
+
+// SearchPage returns limit results starting at rank offset. Both retrievers
+// are asked for enough candidates to rank offset+limit results, and ties are
+// broken by chunk ID, so the same query against the same index always ranks
+// the same way. With query variants (opts.Queries or ExpandMulti), each
+// variant is searched and the rankings are merged.
+func (s *Searcher) SearchPage(ctx context.Context, query string, offset, limit int, opts Options) (Page, error) {
+	if len(opts.Queries) > 0 || opts.Expand == ExpandMulti {
+		return s.searchMulti(ctx, query, offset, limit, opts)
+	}
+	return s.searchPage(ctx, query, offset, limit, opts)
+}
+
+// searchPage is SearchPage for a single query.
+func (s *Searcher) searchPage(ctx context.Context, query string, offset, limit int, opts Options) (Page, error) {
+	topK := offset + limit
+	if opts.grouped() {
+		topK *= groupFetchFactor
+	}
+	lang := ingest.NormalizeLang(opts.Lang)
+	if opts.Lang == LangAuto {
+		lang = ingest.DetectLanguage(query)
+	}
+	mode := opts.Mode
+	if mode == "" {
+		mode = ModeHybrid
+	}
+	if !ValidMode(mode) {
+		return Page{}, fmt.Errorf("unknown search mode %q (expected hybrid, lexical or vector)", mode)
+	}
+	space := opts.Space
+	if space == DefaultSpace {
+		space = ""
+	}
+	if _, ok := s.models.Load().spaces[space]; space != "" && !ok {
+		return Page{}, fmt.Errorf("unknown vector space %q", opts.Space)
+	}
+	if space != "" && s.snapshot != nil {
+		return Page{}, fmt.Errorf("vector space %q is not available in index snapshots", opts.Space)
+	}
+	if !ValidExpand(opts.Expand) {
+		return Page{}, fmt.Errorf("unknown query expansion %q (expected terms, hyde or multi)", opts.Expand)
+	}
+	if !ValidGroupBy(opts.GroupBy) {
+		return Page{}, fmt.Errorf("unknown grouping %q (expected document)", opts.GroupBy)
+	}
+	if (opts.Expand == ExpandHyDE || opts.Expand == ExpandMulti) && s.rewriter == nil {
+		return Page{}, errNoRewriter(opts.Expand)
+	}
+	if !ValidLexicalSyntax(opts.LexicalSyntax) {
+		return Page{}, fmt.Errorf("unknown lexical syntax %q (expected simple or advanced)", opts.LexicalSyntax)
+	}
+	logger := util.FromContext(ctx)
+	var advanced storage.LexicalQuery
+	if opts.LexicalSyntax == SyntaxAdvanced {
+		var err error
+		if advanced, err = storage.ParseLexicalQuery(query); err != nil {
+			return Page{}, fmt.Errorf("invalid lexical query: %w", err)
 		}
-		localCfg := ingest.LocalEmbedderConfig{
-			ModelPath: cfg.Embedding.LocalModelPath,
-			CacheDir:  cfg.Embedding.ModelCacheDir,
-			BatchSize: cfg.Embedding.BatchSize,
-			MaxLength: 512, // Default max length
+		// Everything else sees the words looked for in the text.
+		query = advanced.Text()
+		if query == "" && mode != ModeLexical {
+			logger.Debug("Query has no words to embed, searching the lexical index only")
+			mode = ModeLexical
 		}
-		// Validate configuration
-		if err := ingest.ValidateModelConfig(localCfg); err != nil {
-			return nil, util.WrapError(err, "Invalid local embedder configuration")
+	}
+	logger.Info("Performing search", "query", query, "offset", offset, "limit", limit, "mode", mode, "lang", lang, "path", opts.Path, "space", space, "expand", opts.Expand)
+
+	var warnings []string
+	if mode != ModeLexical {
+		if err := storage.CheckVectorStore(s.vectorStore(), s.vectorIndexPath(space)); err != nil {
+			if !errors.Is(err, storage.ErrNoVectorIndex) {
+				return Page{}, fmt.Errorf("failed to open vector index: %w", err)
+			}
+			logger.Warn("Vector index unavailable, searching the lexical index only", "mode", mode, "err", err)
+			warnings = append(warnings, WarningLexicalOnly)
+			mode = ModeLexical
 		}
-		e, err := ingest.NewLocalEmbedder(localCfg)
-		if err != nil {
-			return nil, util.WrapError(err, "Failed to create local embedder")
+	}
+	if mode != ModeLexical {
+		if err := s.checkIndexModel(space); err != nil {
+			if !s.allowModelMismatch || !errors.Is(err, ErrModelMismatch) {
+				return Page{}, err
+			}
+			logger.Warn("Searching a vector index built with another embedding model", "err", err)
+			warnings = append(warnings, WarningModelMismatch)
 		}
-		embedder = e
-	default:
-		return nil, util.NewError(fmt.Sprintf("Unsupported embedder provider: %s. Supported providers: openai, local", prov))
 	}
 
-	return &Searcher{
-		config:   cfg,
-		embedder: embedder,
-	}, nil
-}
-
-// Search performs a real search query using the existing search implementation
-func (s *Searcher) Search(ctx context.Context, query string, topK int) ([]Result, error) {
-	slog.Info("Performing hybrid search", "query", query, "top_k", topK)
+	filter := opts.Filter
+	if opts.Path != "" {
+		filter = make(map[string]string, len(opts.Filter)+1)
+		for k, v := range opts.Filter {
+			filter[k] = v
+		}
+		filter["path"] = opts.Path
+	}
 
 	// Perform lexical search
-	bleveIdx, err := storage.OpenOrCreateBleveIndex(s.config.Lexical.IndexPath)
+	bleveIdx, err := storage.OpenOrCreateBleveIndex(s.lexicalPath())
 	if err != nil {
-		return nil, fmt.Errorf("failed to open Bleve index: %w", err)
+		return Page{}, fmt.Errorf("failed to open Bleve index: %w", err)
 	}
 	defer bleveIdx.Close()
 
-	lexicalHits, err := bleveIdx.SearchText(query, topK*2) // Get more for better fusion
-	if err != nil {
-		return nil, fmt.Errorf("lexical search failed: %w", err)
+	opts.Mode = mode
+	lexicalQuery, vectorQuery := s.expandQuery(ctx, bleveIdx, query, lang, filter, opts)
+
+	var lexicalHits []*blevesearch.DocumentMatch
+	if mode != ModeVector {
+		if advanced != nil {
+			// Expansion appends its terms to the query.
+			terms := strings.Fields(strings.TrimPrefix(lexicalQuery, query))
+			lexicalHits, err = bleveIdx.SearchQueryFiltered(ctx, advanced.WithTerms(terms...), lang, filter, topK*2)
+		} else {
+			lexicalHits, err = bleveIdx.SearchTextFiltered(ctx, lexicalQuery, lang, filter, topK*2) // Get more for better fusion
+		}
+		if err != nil {
+			return Page{}, fmt.Errorf("lexical search failed: %w", err)
+		}
+		// Bleve ranks by TF-IDF; lexical.bm25_k1 0 keeps its scores.
+		if k1 := s.config.Lexical.BM25K1; k1 > 0 && strings.TrimSpace(lexicalQuery) != "" {
+			if !bleveIdx.RescoreBM25(ctx, lexicalHits, lexicalQuery, lang, k1, s.config.Lexical.BM25B) {
+				logger.Debug("Lexical index has no field lengths, kept TF-IDF scores")
+			}
+		}
 	}
 
-	slog.Debug("Lexical search results", "query", query, "hits", len(lexicalHits))
+	logger.Debug("Lexical search results", "query", query, "hits", len(lexicalHits))
 	for i, hit := range lexicalHits {
 		if i < 3 { // Log first 3 hits
-			slog.Debug("Lexical hit", "rank", i+1, "id", hit.ID, "score", hit.Score)
+			logger.Debug("Lexical hit", "rank", i+1, "id", hit.ID, "score", hit.Score)
 		}
 	}
 
-	// Perform vector search
-	queryEmbedding, err := s.embedder.Embed(ctx, []string{query})
-	if err != nil {
-		return nil, fmt.Errorf("failed to embed query: %w", err)
-	}
-
-	// Open vector index
-	faissPath := filepath.Join("semango", "index", "faiss.index")
-	vecIdx, err := storage.NewFaissVectorIndex(ctx, faissPath, s.embedder.Dimension(), faiss.MetricInnerProduct)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open vector index: %w", err)
-	}
-	defer vecIdx.Close()
-
-	vecResults, err := vecIdx.Search(ctx, queryEmbedding[0], topK*2)
-	if err != nil {
-		return nil, fmt.Errorf("vector search failed: %w", err)
+	var vecResults []storage.VectorResult
+	if mode != ModeLexical {
+		vecResults, err = s.vectorSearch(ctx, bleveIdx, vectorQuery, lang, space, filter, topK)
+		if err != nil {
+			return Page{}, err
+		}
 	}
 
-	slog.Debug("Vector search results", "query", query, "hits", len(vecResults))
+	logger.Debug("Vector search results", "query", query, "hits", len(vecResults))
 	for i, result := range vecResults {
 		if i < 3 { // Log first 3 hits
-			slog.Debug("Vector hit", "rank", i+1, "id", result.ID, "score", result.Score)
+			logger.Debug("Vector hit", "rank", i+1, "id", result.ID, "score", result.Score)
 		}
 	}
 
+	hybrid := s.Hybrid()
+
 	// Create rank maps for RRF
 	lexicalRanks := make(map[string]int)
 	semanticRanks := make(map[string]int)
@@ -153,7 +458,7 @@ func (s *Searcher) Search(ctx context.Context, query string, topK int) ([]Result
 		semanticRanks[result.ID] = i + 1 // Rank starts from 1
 	}
 
-	slog.Debug("Raw score ranges",
+	logger.Debug("Raw score ranges",
 		"lexical_hits", len(lexicalHits),
 		"semantic_hits", len(vecResults))
 
@@ -174,13 +479,13 @@ func (s *Searcher) Search(ctx context.Context, query string, topK int) ([]Result
 	// Build final results with proper relevance scoring
 	var finalResults []Result
 
-	slog.Debug("Processing chunks", "total_unique_chunks", len(allChunkIDs))
+	logger.Debug("Processing chunks", "total_unique_chunks", len(allChunkIDs))
 
 	for chunkID := range allChunkIDs {
 		// Get document from Bleve to extract text and metadata
 		doc, err := bleveIdx.GetDocument(chunkID)
 		if err != nil || doc == nil {
-			slog.Warn("Could not retrieve document", "chunk_id", chunkID, "error", err)
+			logger.Warn("Could not retrieve document", "chunk_id", chunkID, "error", err)
 			continue
 		}
 
@@ -209,6 +514,11 @@ func (s *Searcher) Search(ctx context.Context, query string, topK int) ([]Result
 			path = meta["path"]
 		}
 
+		// Filter matching in the index is analysed; enforce exact values here.
+		if !matchesFilter(meta, filter) {
+			continue
+		}
+
 		// Calculate combined score using proper relevance scoring
 		var finalScore float64
 
@@ -238,7 +548,7 @@ func (s *Searcher) Search(ctx context.Context, query string, topK int) ([]Result
 			}
 		}
 
-		slog.Debug("Chunk analysis",
+		logger.Debug("Chunk analysis",
 			"chunk_id", chunkID,
 			"found_lexical", foundInLexical,
 			"found_semantic", foundInSemantic,
@@ -254,50 +564,52 @@ func (s *Searcher) Search(ctx context.Context, query string, topK int) ([]Result
 		normalizedSemantic := semanticScore
 
 		// Apply hybrid fusion using consistently normalized scores
-		switch s.config.Hybrid.Fusion {
-		case "rrf":
+		switch {
+		case mode == ModeLexical:
+			finalScore = normalizedLexical
+		case mode == ModeVector:
+			finalScore = normalizedSemantic
+		case hybrid.Fusion == "rrf":
 			// Reciprocal Rank Fusion using actual ranks
 			k := 60.0
 			rrfScore := 0.0
 
 			if lexicalRank, hasLexical := lexicalRanks[chunkID]; hasLexical {
-				rrfScore += s.config.Hybrid.LexicalWeight / (k + float64(lexicalRank))
+				rrfScore += hybrid.LexicalWeight / (k + float64(lexicalRank))
 			}
 
 			if semanticRank, hasSemantic := semanticRanks[chunkID]; hasSemantic {
-				rrfScore += s.config.Hybrid.VectorWeight / (k + float64(semanticRank))
+				rrfScore += hybrid.VectorWeight / (k + float64(semanticRank))
 			}
 
 			finalScore = rrfScore
 
-		case "linear":
+		case hybrid.Fusion == "linear":
 			// Linear combination of consistently normalized scores
-			finalScore = (normalizedLexical * s.config.Hybrid.LexicalWeight) +
-				(normalizedSemantic * s.config.Hybrid.VectorWeight)
+			finalScore = (normalizedLexical * hybrid.LexicalWeight) +
+				(normalizedSemantic * hybrid.VectorWeight)
 
 		default:
 			// Default to linear combination
-			finalScore = (normalizedLexical * s.config.Hybrid.LexicalWeight) +
-				(normalizedSemantic * s.config.Hybrid.VectorWeight)
+			finalScore = (normalizedLexical * hybrid.LexicalWeight) +
+				(normalizedSemantic * hybrid.VectorWeight)
 		}
+		boost := boostFactor(opts.Boosts, path) * qualityFactor(s.config.Search.QualityPrior, meta)
+		finalScore *= boost
 
-		slog.Debug("Score calculation",
+		logger.Debug("Score calculation",
 			"chunk_id", chunkID,
 			"raw_lexical", lexicalScore,
 			"raw_semantic", semanticScore,
 			"norm_lexical", normalizedLexical,
 			"norm_semantic", normalizedSemantic,
-			"weights", fmt.Sprintf("lex=%.1f sem=%.1f", s.config.Hybrid.LexicalWeight, s.config.Hybrid.VectorWeight),
+			"weights", fmt.Sprintf("lex=%.1f sem=%.1f", hybrid.LexicalWeight, hybrid.VectorWeight),
+			"boost", boost,
 			"final_score", finalScore,
-			"fusion", s.config.Hybrid.Fusion)
-
-		// Create highlights only for lexical matches
-		var highlights map[string]interface{}
-		if _, hasLexical := lexicalRanks[chunkID]; hasLexical {
-			highlights = s.createHighlights(text, query)
-		}
+			"fusion", hybrid.Fusion)
 
 		result := Result{
+			ID:            chunkID,
 			Score:         finalScore,
 			LexicalScore:  lexicalScore,
 			SemanticScore: semanticScore,
@@ -305,28 +617,123 @@ func (s *Searcher) Search(ctx context.Context, query string, topK int) ([]Result
 			Path:          path,
 			Text:          text, // Complete chunk content
 			Meta:          meta,
-			Highlights:    highlights,
+			Highlights:    s.createHighlights(text, query, meta),
+			Link:          s.links.Render(path, meta),
 		}
 
 		finalResults = append(finalResults, result)
 	}
 
 	// Sort by final score (descending)
-	for i := 0; i < len(finalResults)-1; i++ {
-		for j := i + 1; j < len(finalResults); j++ {
-			if finalResults[i].Score < finalResults[j].Score {
-				finalResults[i], finalResults[j] = finalResults[j], finalResults[i]
-			}
+	sort.Slice(finalResults, func(i, j int) bool {
+		if finalResults[i].Score != finalResults[j].Score {
+			return finalResults[i].Score > finalResults[j].Score
+		}
+		return finalResults[i].ID < finalResults[j].ID
+	})
+
+	if s.config.Files.Dedup != "" {
+		finalResults = collapseDuplicates(finalResults)
+	}
+	if opts.Parents {
+		finalResults = collapseParents(finalResults)
+	}
+	finalResults = groupResults(finalResults, opts)
+	var cutoff int
+	finalResults, _, cutoff = cutAdaptive(finalResults, len(finalResults), opts)
+
+	// Cut out the requested page
+	total := len(finalResults)
+	if offset > len(finalResults) {
+		offset = len(finalResults)
+	}
+	finalResults = finalResults[offset:]
+	if len(finalResults) > limit {
+		finalResults = finalResults[:limit]
+	}
+	s.expandAliases(ctx, finalResults)
+	if opts.Parents {
+		s.expandParents(ctx, bleveIdx, finalResults, query)
+	}
+	if opts.Window > 0 {
+		s.expandWindows(ctx, bleveIdx, finalResults, opts.Window, query, opts.Parents)
+		finalResults = collapseWindows(finalResults)
+	}
+	if opts.Path != "" {
+		sortByPosition(finalResults)
+	}
+
+	logger.Info("Search completed", "total_results", len(finalResults), "total_candidates", total, "lexical_hits", len(lexicalHits), "vector_hits", len(vecResults))
+	return Page{Results: finalResults, Total: total, Warnings: warnings, Cutoff: cutoff}, nil
+}
+
+// vectorSearch embeds query and searches the vector index of space ("" for
+// the default one), restricted to the chunks matching filter when one is
+// given.
+func (s *Searcher) vectorSearch(ctx context.Context, bleveIdx *storage.BleveIndex, query, lang, space string, filter map[string]string, topK int) ([]storage.VectorResult, error) {
+	models := s.models.Load()
+	queryEmbedder := models.def
+	if space != "" {
+		// Language embedders share the default space only.
+		queryEmbedder = models.spaces[space]
+	} else if e, ok := models.langs[lang]; ok {
+		queryEmbedder = e
+	}
+	queryEmbedding, err := queryEmbedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	// A search canceled or timed out while the query was embedded would
+	// only waste the index load below.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Open vector index; without compression settings searches never
+	// convert it.
+	vecIdx, err := storage.OpenVectorIndex(ctx, s.vectorStore(), config.VectorConfig{}, s.vectorIndexPath(space), space, queryEmbedder.Dimension())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vector index: %w", err)
+	}
+	defer vecIdx.Close()
+
+	var vecResults []storage.VectorResult
+	if len(filter) > 0 {
+		allowed, truncated, ferr := bleveIdx.FilterIDs(ctx, filter, maxFilterIDs)
+		if ferr != nil {
+			return nil, fmt.Errorf("failed to resolve filter: %w", ferr)
 		}
+		if truncated {
+			util.FromContext(ctx).Debug("Filter matches too many chunks for an allowlist, filtering vector hits afterwards", "limit", maxFilterIDs)
+			vecResults, err = vecIdx.Search(ctx, queryEmbedding[0], topK*8)
+		} else {
+			vecResults, err = vecIdx.SearchAllowed(ctx, queryEmbedding[0], topK*2, allowed)
+		}
+	} else {
+		vecResults, err = vecIdx.Search(ctx, queryEmbedding[0], topK*2)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("vector search failed: %w", err)
 	}
+	return vecResults, nil
+}
 
-	// Limit to topK
-	if len(finalResults) > topK {
-		finalResults = finalResults[:topK]
+// vectorIndexPath returns where the vector index of space ("" for the
+// default one) is, in the snapshot if one is searched.
+func (s *Searcher) vectorIndexPath(space string) string {
+	if s.snapshot != nil {
+		return s.snapshot.Vector
 	}
+	return storage.SpaceIndexPath(s.inIndexDir(s.config.VectorIndexPath()), space)
+}
 
-	slog.Info("Search completed", "total_results", len(finalResults), "lexical_hits", len(lexicalHits), "vector_hits", len(vecResults))
-	return finalResults, nil
+// vectorStore returns the configured vector store, or FAISS when a
+// snapshot is searched, since bundles hold FAISS index files.
+func (s *Searcher) vectorStore() config.VectorStoreConfig {
+	if s.snapshot != nil {
+		return config.VectorStoreConfig{}
+	}
+	return s.config.VectorStore
 }
 
 // Helper method to get representation by ID (this would need to be implemented)
@@ -336,39 +743,16 @@ func (s *Searcher) getRepresentationByID(id string) (ingest.Representation, bool
 	return ingest.Representation{}, false
 }
 
-// createHighlights creates highlight information for lexical matches
-func (s *Searcher) createHighlights(text, query string) map[string]interface{} {
-	highlights := make(map[string]interface{})
-
-	// Simple case-insensitive highlighting
-	queryLower := strings.ToLower(query)
-	textLower := strings.ToLower(text)
-
-	var matches []map[string]int
-	start := 0
-
-	for {
-		index := strings.Index(textLower[start:], queryLower)
-		if index == -1 {
-			break
-		}
-
-		actualStart := start + index
-		actualEnd := actualStart + len(query)
-
-		matches = append(matches, map[string]int{
-			"start": actualStart,
-			"end":   actualEnd,
-		})
-
-		start = actualEnd
+// createHighlights returns the spans of text that match a term of query, as
+// the lexical index analyses them (see storage.MatchSpans), under "text",
+// or nil when none do. Results found by vector search alone get the query
+// terms they contain highlighted too.
+func (s *Searcher) createHighlights(text, query string, meta map[string]string) map[string]interface{} {
+	spans := storage.MatchSpans(text, query, meta)
+	if len(spans) == 0 {
+		return nil
 	}
-
-	if len(matches) > 0 {
-		highlights["text"] = matches
-	}
-
-	return highlights
+	return map[string]interface{}{"text": spans}
 }
 
 // combineScores combines lexical and semantic scores based on fusion strategy
@@ -384,30 +768,9 @@ func combineScores(lexicalScore, semanticScore float64, fusion string) float64 {
 	}
 }
 
-// GetStats returns real search index statistics
+// GetStats counts what the indexes searched by s hold; see IndexStats.
 func (s *Searcher) GetStats(ctx context.Context) (*Stats, error) {
-	stats := &Stats{}
-
-	// Get Bleve stats - estimate based on search results
-	bleveIdx, err := storage.OpenOrCreateBleveIndex(s.config.Lexical.IndexPath)
-	if err == nil {
-		defer bleveIdx.Close()
-		// Estimate document count by doing a broad search
-		if hits, err := bleveIdx.SearchText("*", 1000); err == nil {
-			stats.TotalDocuments = len(hits)
-		}
-	}
-
-	// Get FAISS stats
-	faissPath := filepath.Join("semango", "index", "faiss.index")
-	if info, err := os.Stat(faissPath); err == nil {
-		stats.IndexSize = int(info.Size())
-	}
-
-	// Estimate chunks (rough approximation)
-	stats.TotalChunks = stats.TotalDocuments * 3 // Rough estimate
-
-	return stats, nil
+	return indexStats(ctx, s.models.Load().cfg, s.lexicalPath(), s.vectorIndexPath)
 }
 
 // Helper functions
@@ -425,6 +788,8 @@ func getModality(modalityMeta, path string) string {
 		return "image"
 	case ".mp3", ".wav", ".flac", ".m4a":
 		return "audio"
+	case ".mp4", ".mkv", ".webm":
+		return "video"
 	case ".pdf":
 		return "pdf"
 	default:
@@ -0,0 +1,53 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+func TestSearcherReload(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "index")
+	cfg := config.GetDefaultConfig()
+	cfg.Lexical.IndexPath = filepath.Join(dir, "bleve")
+	cfg.Vector.IndexPath = filepath.Join(dir, "faiss.index")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	s := NewSearcherWithEmbedder(cfg, &ingest.NoopEmbedder{})
+
+	r, err := storage.StartRebuild(cfg.Lexical.IndexPath, cfg.Vector.IndexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, err := r.Swap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Until it reloads, the searcher keeps the generation it resolved.
+	if got := s.vectorIndexPath(""); got != cfg.Vector.IndexPath {
+		t.Errorf("vector index before Reload = %s", got)
+	}
+	if !s.Reload() || s.Reload() {
+		t.Error("Reload should report the swap once")
+	}
+	if got := s.lexicalPath(); got != filepath.Join(r.Staging, "bleve") {
+		t.Errorf("lexical index after Reload = %s", got)
+	}
+	if got := s.vectorIndexPath("images"); got != storage.SpaceIndexPath(filepath.Join(r.Staging, "faiss.index"), "images") {
+		t.Errorf("space index after Reload = %s", got)
+	}
+	if previous == r.Staging {
+		t.Errorf("previous generation = %s", previous)
+	}
+
+	other := config.GetDefaultConfig()
+	other.Lexical.IndexPath = filepath.Join(t.TempDir(), "bleve")
+	if c := s.WithConfig(other); c.lexicalPath() != other.Lexical.IndexPath || s.lexicalPath() == other.Lexical.IndexPath {
+		t.Error("WithConfig shares the resolved index directory")
+	}
+}
@@ -0,0 +1,120 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// DefaultSnippetLength is the length of snippets, in characters, when
+// search.snippet_length is 0.
+const DefaultSnippetLength = 240
+
+// Snippet returns the result's text cut to about length characters around
+// its best highlight; see Snippet.
+func (r Result) Snippet(length int) string {
+	spans, _ := r.Highlights["text"].([]storage.Span)
+	return Snippet(r.Text, spans, length)
+}
+
+// Snippet cuts text to at most length characters, 0 for
+// DefaultSnippetLength. The window starts a little before the span that has
+// the most spans after it within reach, or at the start of the text without
+// spans, and is cut at word boundaries where it can be; cut ends are marked
+// with util.Ellipsis. Text that fits is returned whole.
+func Snippet(text string, spans []storage.Span, length int) string {
+	if length <= 0 {
+		length = DefaultSnippetLength
+	}
+	if utf8.RuneCountInString(text) <= length {
+		return text
+	}
+	budget := length - 2 // room for an ellipsis at each end
+
+	// The match the window is built around, kept whole.
+	anchorStart, anchorEnd := 0, 0
+	if i := bestSpan(text, spans, budget); i >= 0 {
+		anchorStart, anchorEnd = spans[i].Start, spans[i].End
+	}
+	start := runesBack(text, anchorStart, budget/4)
+	end := runesForward(text, start, budget)
+	if end == len(text) {
+		start = runesBack(text, end, budget)
+	}
+
+	if start > 0 && !spaceBefore(text, start) {
+		if i := strings.IndexFunc(text[start:anchorStart], unicode.IsSpace); i >= 0 {
+			start += i
+		}
+	}
+	if from := max(anchorEnd, start); end < len(text) && from < end && !spaceAt(text, end) {
+		if i := strings.LastIndexFunc(text[from:end], unicode.IsSpace); i >= 0 {
+			end = from + i
+		}
+	}
+	snippet := strings.TrimSpace(text[start:end])
+	if start > 0 {
+		snippet = util.Ellipsis + snippet
+	}
+	if end < len(text) {
+		snippet += util.Ellipsis
+	}
+	return snippet
+}
+
+// bestSpan returns the index of the span followed by the most spans within
+// n characters, or -1 without spans that lie in text.
+func bestSpan(text string, spans []storage.Span, n int) int {
+	best, bestCount := -1, 0
+	for i, sp := range spans {
+		if sp.Start < 0 || sp.End > len(text) || sp.Start > sp.End {
+			continue
+		}
+		reach := runesForward(text, sp.Start, n*3/4)
+		count := 0
+		for _, other := range spans[i:] {
+			if other.Start >= sp.Start && other.End <= reach {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = i, count
+		}
+	}
+	return best
+}
+
+// runesBack returns the byte offset n characters before pos in text.
+func runesBack(text string, pos, n int) int {
+	for ; n > 0 && pos > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(text[:pos])
+		pos -= size
+	}
+	return pos
+}
+
+// runesForward returns the byte offset n characters after pos in text.
+func runesForward(text string, pos, n int) int {
+	for ; n > 0 && pos < len(text); n-- {
+		_, size := utf8.DecodeRuneInString(text[pos:])
+		pos += size
+	}
+	return pos
+}
+
+// spaceBefore reports whether the character before byte offset pos of text
+// is white space.
+func spaceBefore(text string, pos int) bool {
+	r, _ := utf8.DecodeLastRuneInString(text[:pos])
+	return unicode.IsSpace(r)
+}
+
+// spaceAt reports whether the character at byte offset pos of text is
+// white space.
+func spaceAt(text string, pos int) bool {
+	r, _ := utf8.DecodeRuneInString(text[pos:])
+	return unicode.IsSpace(r)
+}
@@ -0,0 +1,49 @@
+package search
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+func TestSnippet(t *testing.T) {
+	if got := Snippet("short text", nil, 20); got != "short text" {
+		t.Errorf("short text: %q", got)
+	}
+
+	filler := strings.Repeat("lorem ipsum dolor sit amet ", 10)
+	text := filler + "the bleve index scores bleve matches " + filler
+	if got := Snippet(text, nil, 40); got != "lorem ipsum dolor sit amet lorem ipsum…" {
+		t.Errorf("no spans: %q", got)
+	}
+
+	// The window goes where two matches are close, not to the lone one.
+	lone := strings.Index(text, "ipsum")
+	first := strings.Index(text, "bleve")
+	second := strings.LastIndex(text, "bleve")
+	spans := []storage.Span{
+		{Start: lone, End: lone + 5, Term: "ipsum"},
+		{Start: first, End: first + 5, Term: "bleve"},
+		{Start: second, End: second + 5, Term: "bleve"},
+	}
+	got := Snippet(text, spans, 60)
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") || !strings.Contains(got, "the bleve index scores bleve matches") {
+		t.Errorf("spans: %q", got)
+	}
+	if n := utf8.RuneCountInString(got); n > 60 {
+		t.Errorf("snippet has %d characters, want at most 60", n)
+	}
+	for _, w := range strings.Fields(strings.Trim(got, "…")) {
+		if !strings.Contains(" lorem ipsum dolor sit amet the bleve index scores matches ", " "+w+" ") {
+			t.Errorf("snippet cuts a word: %q in %q", w, got)
+		}
+	}
+
+	// A match near the end pulls the window back to fill it.
+	end := Snippet(text+"bleve", []storage.Span{{Start: len(text), End: len(text) + 5}}, 30)
+	if !strings.HasSuffix(end, "bleve") || utf8.RuneCountInString(end) > 30 {
+		t.Errorf("match at the end: %q", end)
+	}
+}
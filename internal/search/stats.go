@@ -0,0 +1,213 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/storage"
+)
+
+// Stats describes what the indexes hold.
+type Stats struct {
+	TotalDocuments int `json:"total_documents"` // Distinct paths
+	TotalChunks    int `json:"total_chunks"`    // Documents in the lexical index
+	// Vectors counts the vectors of the default vector index, SpaceVectors
+	// those of each vector space.
+	Vectors      int64            `json:"vectors"`
+	SpaceVectors map[string]int64 `json:"space_vectors,omitempty"`
+	// OrphanedVectors counts the vectors, over all vector indexes, whose
+	// chunk is no longer in the lexical index. Searches skip them;
+	// semango index --recreate drops them.
+	OrphanedVectors  int64          `json:"orphaned_vectors"`
+	ChunksByModality map[string]int `json:"chunks_by_modality"`
+	// FilesByExtension counts paths by lower-case extension, "" for paths
+	// without one.
+	FilesByExtension map[string]int `json:"files_by_extension"`
+	// IndexSize is the size on disk of the lexical index directory and the
+	// vector indexes with their ID maps.
+	IndexSize        int64 `json:"index_size_bytes"`
+	LexicalIndexSize int64 `json:"lexical_index_bytes"`
+	VectorIndexSize  int64 `json:"vector_index_bytes"`
+	// The configured embedding model, and the dimension of the vectors in
+	// the default vector index (0 when it is empty or missing).
+	EmbeddingProvider string `json:"embedding_provider"`
+	EmbeddingModel    string `json:"embedding_model"`
+	Dimension         int    `json:"dimension"`
+	// IndexedWith is the provider/model the default vector index was built
+	// with, from its fingerprint; "" for indexes without one.
+	IndexedWith string `json:"indexed_with,omitempty"`
+	// Compression is how the default vector index stores vectors (see
+	// vector.compression), "" when it is empty or of a type Semango does
+	// not write. BytesPerVector is the size of its file divided by its
+	// vectors, labels included.
+	Compression    string `json:"compression,omitempty"`
+	BytesPerVector int64  `json:"bytes_per_vector,omitempty"`
+	// Generation and IndexedAt are those of the last write to the indexes.
+	Generation uint64     `json:"generation"`
+	IndexedAt  *time.Time `json:"indexed_at,omitempty"`
+}
+
+// IndexStats counts what the indexes of cfg hold. Every chunk of the
+// lexical index is read once, so it takes time proportional to the size of
+// the index. No embedder is needed. A missing vector index counts as empty.
+func IndexStats(ctx context.Context, cfg *config.Config) (*Stats, error) {
+	return indexStats(ctx, cfg.Embedding, cfg.Lexical.IndexPath, func(space string) string {
+		return storage.SpaceIndexPath(cfg.VectorIndexPath(), space)
+	})
+}
+
+// indexStats is IndexStats for the embedding settings ec, the lexical index
+// at lexicalPath and the vector indexes at vectorPath(space), "" being the
+// default space.
+func indexStats(ctx context.Context, ec config.EmbeddingConfig, lexicalPath string, vectorPath func(space string) string) (*Stats, error) {
+	stats := &Stats{
+		ChunksByModality: map[string]int{},
+		FilesByExtension: map[string]int{},
+	}
+	stats.EmbeddingProvider, stats.EmbeddingModel = ec.Identity()
+
+	bleveIdx, err := storage.OpenOrCreateBleveIndex(lexicalPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Bleve index: %w", err)
+	}
+	defer bleveIdx.Close()
+	chunks, err := bleveIdx.DocCount()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count chunks: %w", err)
+	}
+	stats.TotalChunks = int(chunks)
+	paths := map[string]bool{}
+	ids := make(map[string]bool, int(chunks))
+	err = bleveIdx.EachDocument(ctx, []string{"path", "meta.modality"}, func(id string, values map[string]interface{}) {
+		ids[id] = true
+		path, _ := values["path"].(string)
+		modality, _ := values["meta.modality"].(string)
+		stats.ChunksByModality[getModality(modality, path)]++
+		if !paths[path] {
+			paths[path] = true
+			stats.FilesByExtension[strings.ToLower(filepath.Ext(path))]++
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunks: %w", err)
+	}
+	stats.TotalDocuments = len(paths)
+	stats.LexicalIndexSize = dirSize(lexicalPath)
+
+	spaces := make([]string, 0, len(ec.Spaces))
+	for name := range ec.Spaces {
+		spaces = append(spaces, name)
+	}
+	sort.Strings(spaces)
+	for _, space := range append([]string{""}, spaces...) {
+		path := vectorPath(space)
+		stats.VectorIndexSize += vectorFilesSize(path)
+		vectors, dim, err := storage.ReadFaissIndexInfo(path)
+		if err != nil && !errors.Is(err, storage.ErrNoVectorIndex) {
+			return nil, err
+		}
+		orphans, err := orphanedVectors(path, vectors, ids)
+		if err != nil {
+			return nil, err
+		}
+		stats.OrphanedVectors += orphans
+		if space == "" {
+			stats.Vectors, stats.Dimension = vectors, dim
+			if m, ok, _ := storage.ReadIndexModel(path); ok {
+				stats.IndexedWith = m.Provider + "/" + m.Model
+			}
+			if vectors > 0 {
+				stats.Compression, _ = storage.ReadVectorIndexCompression(path)
+				if fi, err := os.Stat(path); err == nil {
+					stats.BytesPerVector = fi.Size() / vectors
+				}
+			}
+			continue
+		}
+		if stats.SpaceVectors == nil {
+			stats.SpaceVectors = map[string]int64{}
+		}
+		stats.SpaceVectors[space] = vectors
+	}
+	stats.IndexSize = stats.LexicalIndexSize + stats.VectorIndexSize
+
+	if g, err := storage.ReadGeneration(storage.GenerationPath(lexicalPath)); err == nil {
+		stats.Generation = g.Number
+		if !g.UpdatedAt.IsZero() {
+			t := g.UpdatedAt.UTC()
+			stats.IndexedAt = &t
+		}
+	}
+	return stats, nil
+}
+
+// IndexBytes returns the size on disk of the indexes of cfg, as
+// Stats.IndexSize counts it, without reading them. Vectors kept in a
+// vector database do not count.
+func IndexBytes(cfg *config.Config) int64 {
+	size := dirSize(cfg.Lexical.IndexPath)
+	size += vectorFilesSize(cfg.VectorIndexPath())
+	for space := range cfg.Embedding.Spaces {
+		size += vectorFilesSize(storage.SpaceIndexPath(cfg.VectorIndexPath(), space))
+	}
+	return size
+}
+
+// vectorFilesSize returns the size of the vector index at path and of its
+// ID map, 0 for missing files.
+func vectorFilesSize(path string) int64 {
+	var size int64
+	for _, p := range []string{path, storage.JSONIDMapPath(path)} {
+		if fi, err := os.Stat(p); err == nil {
+			size += fi.Size()
+		}
+	}
+	return size
+}
+
+// orphanedVectors counts the vectors of the index at path whose chunk is
+// not among ids: those mapped to a deleted chunk, and those whose label has
+// no ID at all, of which there are as many as the index holds vectors
+// beyond the ID map.
+func orphanedVectors(path string, vectors int64, ids map[string]bool) (int64, error) {
+	if vectors == 0 {
+		return 0, nil
+	}
+	m, err := storage.OpenJSONIDMap(storage.JSONIDMapPath(path))
+	if err != nil {
+		return 0, err
+	}
+	var orphans int64
+	for _, id := range m.IDs() {
+		if !ids[id] {
+			orphans++
+		}
+	}
+	if unmapped := vectors - int64(m.Len()); unmapped > 0 {
+		orphans += unmapped
+	}
+	return orphans, nil
+}
+
+// dirSize returns the total size of the files under dir, 0 if it is missing.
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && d.Type().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
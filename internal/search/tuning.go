@@ -0,0 +1,38 @@
+package search
+
+import (
+	"sync/atomic"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+// tuning is the ranking configuration a Searcher reads per search, so that
+// it can change while the Searcher serves. Like embedders, a Searcher and
+// its copies share one pointer to it.
+type tuning struct {
+	hybrid   config.HybridConfig
+	reranker config.RerankerConfig
+}
+
+func newTuning(cfg *config.Config) *atomic.Pointer[tuning] {
+	p := &atomic.Pointer[tuning]{}
+	p.Store(&tuning{hybrid: cfg.Hybrid, reranker: cfg.Reranker})
+	return p
+}
+
+// Hybrid returns the fusion method and weights s ranks with.
+func (s *Searcher) Hybrid() config.HybridConfig {
+	return s.tuning.Load().hybrid
+}
+
+// Reranker returns the reranker settings s uses.
+func (s *Searcher) Reranker() config.RerankerConfig {
+	return s.tuning.Load().reranker
+}
+
+// SetTuning switches s, and every Searcher derived from it with WithConfig
+// or AsOf, to the fusion settings of hybrid and the reranker settings of
+// reranker. Searches in flight finish with the old ones.
+func (s *Searcher) SetTuning(hybrid config.HybridConfig, reranker config.RerankerConfig) {
+	s.tuning.Store(&tuning{hybrid: hybrid, reranker: reranker})
+}
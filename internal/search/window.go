@@ -0,0 +1,105 @@
+package search
+
+import (
+	"context"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/storage"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// MaxWindow bounds Options.Window.
+const MaxWindow = 10
+
+// expandWindows replaces the text of each result with that of the window
+// around it: up to n chunks before and n after the matched chunk in its
+// document, followed through the prev_chunk and next_chunk links recorded
+// at indexing time. Window lists the chunk IDs in document order. Results
+// expanded to their parent section are left alone, as are chunks indexed
+// without links, whose window is the chunk itself.
+func (s *Searcher) expandWindows(ctx context.Context, bleveIdx *storage.BleveIndex, results []Result, n int, query string, parents bool) {
+	for i := range results {
+		r := &results[i]
+		if parents && r.Meta["parent_id"] != "" {
+			continue
+		}
+		var before, after []Result
+		for id, k := r.Meta["prev_chunk"], 0; id != "" && k < n; k++ {
+			c, ok := readChunk(bleveIdx, id)
+			if !ok {
+				util.FromContext(ctx).Warn("Could not read neighbouring chunk", "chunk_id", id)
+				break
+			}
+			before = append(before, c)
+			id = c.Meta["prev_chunk"]
+		}
+		for id, k := r.Meta["next_chunk"], 0; id != "" && k < n; k++ {
+			c, ok := readChunk(bleveIdx, id)
+			if !ok {
+				util.FromContext(ctx).Warn("Could not read neighbouring chunk", "chunk_id", id)
+				break
+			}
+			after = append(after, c)
+			id = c.Meta["next_chunk"]
+		}
+		window := make([]Result, 0, len(before)+1+len(after))
+		for j := len(before) - 1; j >= 0; j-- {
+			window = append(window, before[j])
+		}
+		window = append(window, *r)
+		window = append(window, after...)
+
+		r.Window = make([]string, len(window))
+		for j, c := range window {
+			r.Window[j] = c.ID
+		}
+		if len(window) == 1 {
+			continue
+		}
+		r.Text = documentText(window)
+		r.Highlights = s.createHighlights(r.Text, query, r.Meta)
+	}
+}
+
+// collapseWindows drops the results whose chunk is in the window of a
+// better one, so no text is returned twice. results must be sorted by
+// score.
+func collapseWindows(results []Result) []Result {
+	seen := make(map[string]bool)
+	out := results[:0]
+	for _, r := range results {
+		if seen[r.Collection+"\x00"+r.ID] {
+			continue
+		}
+		for _, id := range r.Window {
+			seen[r.Collection+"\x00"+id] = true
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// readChunk reads the chunk id from the lexical index, with its text, path
+// and metadata.
+func readChunk(bleveIdx *storage.BleveIndex, id string) (Result, bool) {
+	d, err := bleveIdx.GetDocument(id)
+	if err != nil || d == nil {
+		return Result{}, false
+	}
+	r := Result{ID: id, Meta: map[string]string{}}
+	for _, field := range d.Fields {
+		switch name := field.Name(); {
+		case name == "text":
+			r.Text = string(field.Value())
+		case name == "path":
+			r.Path = string(field.Value())
+		case strings.HasPrefix(name, "meta."):
+			r.Meta[strings.TrimPrefix(name, "meta.")] = string(field.Value())
+		}
+	}
+	if r.Path == "" {
+		r.Path = r.Meta["path"]
+	}
+	r.Modality = getModality(r.Meta["modality"], r.Path)
+	return r, true
+}
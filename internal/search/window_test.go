@@ -0,0 +1,17 @@
+package search
+
+import "testing"
+
+func TestCollapseWindows(t *testing.T) {
+	results := []Result{
+		{ID: "c3", Window: []string{"c2", "c3", "c4"}},
+		{ID: "c4", Window: []string{"c3", "c4", "c5"}},
+		{ID: "c4", Collection: "other", Window: []string{"c4"}},
+		{ID: "c9", Window: []string{"c8", "c9"}},
+		{ID: "c8"},
+	}
+	got := collapseWindows(results)
+	if len(got) != 3 || got[0].ID != "c3" || got[1].Collection != "other" || got[2].ID != "c9" {
+		t.Errorf("collapseWindows = %+v", got)
+	}
+}
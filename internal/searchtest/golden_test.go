@@ -0,0 +1,184 @@
+package searchtest
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/search"
+)
+
+// goldenCases are the ranking invariants the golden corpus must keep. A
+// change that breaks one of them changes what users get back, so it needs
+// a deliberate update here rather than a silent regression.
+var goldenCases = []struct {
+	name  string
+	query string
+	opts  search.Options
+	// first is the path expected at rank 1; empty skips the check.
+	first string
+	// absent are paths that must not appear at all.
+	absent []string
+	// every result must satisfy check, when set.
+	check func(search.Result) bool
+}{
+	{
+		name:  "exact phrase ranks its document first",
+		query: "blue-green rollouts",
+		first: "docs/deploy.md",
+	},
+	{
+		name:  "identifier ranks its source file first",
+		query: "NewTokenBucket",
+		first: "src/ratelimit.go",
+	},
+	{
+		name:  "unique term ranks its document first",
+		query: "acknowledgement paged",
+		first: "docs/oncall.md",
+	},
+	{
+		name:   "excluded directories are not indexed",
+		query:  "refund",
+		absent: []string{"vendor/acme/refunds.md"},
+	},
+	{
+		name:   "excluded drafts are not indexed",
+		query:  "rollback blue-green",
+		first:  "docs/deploy.md",
+		absent: []string{"drafts/rollback.md"},
+	},
+	{
+		name:  "metadata filter is respected",
+		query: "escalation",
+		opts:  search.Options{Filter: map[string]string{"frontmatter.team": "payments"}},
+		first: "docs/billing.md",
+		check: func(r search.Result) bool { return r.Meta["frontmatter.team"] == "payments" },
+	},
+	{
+		name:  "path restriction is respected",
+		query: "rollback",
+		opts:  search.Options{Path: "docs/deploy.md"},
+		check: func(r search.Result) bool { return r.Path == "docs/deploy.md" },
+	},
+}
+
+func TestGoldenCorpus(t *testing.T) {
+	ctx := context.Background()
+	cfg := Config(t.TempDir())
+	root, err := filepath.Abs(GoldenCorpus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Index(ctx, cfg, root, &HashEmbedder{Dim: 64})
+	if err != nil {
+		t.Fatalf("indexing the golden corpus: %v", err)
+	}
+
+	for _, mode := range []string{search.ModeLexical, search.ModeHybrid} {
+		for _, tc := range goldenCases {
+			t.Run(mode+"/"+tc.name, func(t *testing.T) {
+				opts := tc.opts
+				opts.Mode = mode
+				results, err := s.Search(ctx, tc.query, 10, opts)
+				if err != nil {
+					t.Fatalf("Search(%q): %v", tc.query, err)
+				}
+				if len(results) == 0 {
+					t.Fatalf("Search(%q) returned no results", tc.query)
+				}
+				if tc.first != "" && results[0].Path != tc.first {
+					t.Errorf("rank 1 is %s, want %s; ranking: %s", results[0].Path, tc.first, paths(results))
+				}
+				for _, r := range results {
+					for _, p := range tc.absent {
+						if r.Path == p {
+							t.Errorf("excluded %s returned; ranking: %s", p, paths(results))
+						}
+					}
+					if tc.check != nil && !tc.check(r) {
+						t.Errorf("result %s (meta %v) violates the restriction", r.Path, r.Meta)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestGoldenDocuments(t *testing.T) {
+	ctx := context.Background()
+	cfg := Config(t.TempDir())
+	root, err := filepath.Abs(GoldenCorpus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Index(ctx, cfg, root, &HashEmbedder{Dim: 64})
+	if err != nil {
+		t.Fatalf("indexing the golden corpus: %v", err)
+	}
+
+	var listed []string
+	after := ""
+	for {
+		docs, next, err := s.ListDocuments(ctx, after, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range docs {
+			listed = append(listed, d.Path)
+		}
+		if next == "" {
+			break
+		}
+		after = next
+	}
+	want := "docs/billing.md, docs/deploy.md, docs/oncall.md, notes/refunds.txt, src/ratelimit.go"
+	if got := strings.Join(listed, ", "); got != want {
+		t.Errorf("listed documents: %s, want %s", got, want)
+	}
+
+	doc, err := s.ReadDocument(ctx, "docs/deploy.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{"blue-green rollouts", "A rollback needs no new build."} {
+		if !strings.Contains(doc.Text, part) {
+			t.Errorf("document text lacks %q:\n%s", part, doc.Text)
+		}
+	}
+	if _, err := s.ReadDocument(ctx, "vendor/acme/refunds.md"); err != search.ErrDocumentNotFound {
+		t.Errorf("reading an excluded document: %v, want ErrDocumentNotFound", err)
+	}
+}
+
+func TestHashEmbedder(t *testing.T) {
+	e := &HashEmbedder{Dim: 32}
+	vecs, err := e.Embed(context.Background(), []string{"Blue green", "blue GREEN!", "invoices", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dot := func(a, b []float32) (d float32) {
+		for i := range a {
+			d += a[i] * b[i]
+		}
+		return d
+	}
+	if d := dot(vecs[0], vecs[1]); d < 0.999 {
+		t.Errorf("case and punctuation should not matter: similarity %v", d)
+	}
+	if dot(vecs[0], vecs[2]) >= dot(vecs[0], vecs[1]) {
+		t.Error("unrelated text is as similar as the same words")
+	}
+	if d := dot(vecs[3], vecs[3]); d != 0 {
+		t.Errorf("empty text should embed to the zero vector, got norm² %v", d)
+	}
+}
+
+func paths(results []search.Result) string {
+	ps := make([]string, len(results))
+	for i, r := range results {
+		ps[i] = r.Path
+	}
+	return strings.Join(ps, ", ")
+}
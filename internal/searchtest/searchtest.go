@@ -0,0 +1,104 @@
+// Package searchtest indexes a small golden corpus with a deterministic fake
+// embedder so that search quality can be checked in ordinary Go tests,
+// without model downloads or API keys.
+package searchtest
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/pipeline"
+	"github.com/omarkamali/semango/internal/search"
+)
+
+// GoldenCorpus is the directory of the golden corpus, relative to this
+// package.
+const GoldenCorpus = "testdata/golden"
+
+// GoldenExclude are the exclude patterns the golden corpus is indexed with.
+// Files under them would outrank the expected results if they were
+// indexed.
+var GoldenExclude = []string{"vendor/**", "drafts/**"}
+
+// HashEmbedder is a fake embedder: each lowercase word of a text is hashed
+// into one of Dim buckets and the counts are normalized to unit length. Texts
+// sharing words get similar vectors, so vector search behaves like a crude
+// keyword match and rankings are reproducible.
+type HashEmbedder struct {
+	Dim int
+}
+
+func (h *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		v := make([]float32, h.Dim)
+		words := strings.FieldsFunc(strings.ToLower(t), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, w := range words {
+			f := fnv.New32a()
+			f.Write([]byte(w))
+			v[f.Sum32()%uint32(h.Dim)]++
+		}
+		var norm float64
+		for _, x := range v {
+			norm += float64(x * x)
+		}
+		if norm > 0 {
+			for j := range v {
+				v[j] /= float32(math.Sqrt(norm))
+			}
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func (h *HashEmbedder) Dimension() int { return h.Dim }
+
+// Config returns the default configuration with every index kept under
+// dir and the golden exclude patterns.
+func Config(dir string) *config.Config {
+	cfg := config.GetDefaultConfig()
+	cfg.Lexical.IndexPath = filepath.Join(dir, "bleve")
+	cfg.Vector.IndexPath = filepath.Join(dir, config.DefaultVectorIndexFile)
+	cfg.Files.Include = nil
+	cfg.Files.Exclude = GoldenExclude
+	cfg.Plugins = nil
+	return cfg
+}
+
+// Index indexes the files of root selected by cfg.Files with e and returns
+// a searcher over the result. Paths are relative to root.
+func Index(ctx context.Context, cfg *config.Config, root string, e ingest.Embedder) (*search.Searcher, error) {
+	mgr := pipeline.NewManager(cfg, e)
+	if err := mgr.Err(); err != nil {
+		return nil, err
+	}
+	paths := make(chan string, 100)
+	errs := make(chan error, 1)
+	go ingest.CrawlDir(root, cfg.Files, paths, errs)
+
+	var indexErr error
+	for relPath := range paths {
+		if indexErr != nil {
+			continue // drain so the crawler can finish
+		}
+		indexErr = mgr.ProcessFile(ctx, relPath, filepath.Join(root, relPath))
+	}
+	if indexErr != nil {
+		return nil, indexErr
+	}
+	select {
+	case err := <-errs:
+		return nil, err
+	default:
+	}
+	return search.NewSearcherWithEmbedder(cfg, e), nil
+}
@@ -0,0 +1,15 @@
+package ratelimit
+
+import "time"
+
+// NewTokenBucket returns a limiter that allows burst requests at once and
+// refills at rate tokens per second.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{rate: rate, tokens: float64(burst), max: float64(burst), last: time.Now()}
+}
+
+// TokenBucket is a token bucket rate limiter.
+type TokenBucket struct {
+	rate, tokens, max float64
+	last              time.Time
+}
@@ -0,0 +1,277 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// Values of lexical.analyzers.
+const (
+	AnalyzerStandard = "standard" // Unicode words, lower-cased
+	AnalyzerEnglish  = "en"       // standard plus English stop words and stemming
+	AnalyzerCode     = "code"     // CodeAnalyzer: identifiers split into words
+)
+
+// ValidateAnalyzers checks lexical.analyzers: keys are "text", "path" or
+// "meta.<key>", values one of the Analyzer constants.
+func ValidateAnalyzers(analyzers map[string]string) error {
+	for field, name := range analyzers {
+		if field != "text" && field != "path" && (!strings.HasPrefix(field, "meta.") || field == "meta.") {
+			return fmt.Errorf("field %q: analyzers apply to text, path or meta.<key>", field)
+		}
+		if bleveAnalyzer(name) == "" {
+			return fmt.Errorf("field %q: unknown analyzer %q, want %s, %s or %s", field, name, AnalyzerStandard, AnalyzerEnglish, AnalyzerCode)
+		}
+	}
+	return nil
+}
+
+// bleveAnalyzer returns the Bleve analyzer registered for a value of
+// lexical.analyzers, or "" for an unknown one.
+func bleveAnalyzer(name string) string {
+	switch name {
+	case AnalyzerStandard:
+		return standard.Name
+	case AnalyzerEnglish:
+		return "en"
+	case AnalyzerCode:
+		return CodeAnalyzer
+	}
+	return ""
+}
+
+// LexicalSettings are the options that decide how documents are written to
+// the lexical index. An index records the settings it was built with, and
+// PrepareLexicalIndex rebuilds it when they change.
+type LexicalSettings struct {
+	// Analyzers maps fields to a value of lexical.analyzers; fields not
+	// listed use the standard analyzer.
+	Analyzers map[string]string `json:"analyzers,omitempty"`
+	// CodeTokenFilter is a value of lexical.code_token_filter.
+	CodeTokenFilter string `json:"code_token_filter,omitempty"`
+}
+
+// normalized drops the entries that select a default, so that settings
+// differing only in spelling out defaults compare equal.
+func (s LexicalSettings) normalized() LexicalSettings {
+	var out LexicalSettings
+	for field, name := range s.Analyzers {
+		if name == AnalyzerStandard {
+			continue
+		}
+		if out.Analyzers == nil {
+			out.Analyzers = map[string]string{}
+		}
+		out.Analyzers[field] = name
+	}
+	if s.CodeTokenFilter != CodeTokenFilterSplit {
+		out.CodeTokenFilter = s.CodeTokenFilter
+	}
+	return out
+}
+
+// Equal reports whether indexes built with s and t are the same.
+func (s LexicalSettings) Equal(t LexicalSettings) bool {
+	return reflect.DeepEqual(s.normalized(), t.normalized())
+}
+
+// newIndexMapping returns the mapping of a lexical index with the given
+// lexical.analyzers. Fields without an analyzer are mapped dynamically.
+func newIndexMapping(analyzers map[string]string) *mapping.IndexMappingImpl {
+	im := bleve.NewIndexMapping()
+	seen := map[string]bool{}
+	for _, analyzer := range lexicalAnalyzers {
+		if seen[analyzer] {
+			continue
+		}
+		seen[analyzer] = true
+		fm := bleve.NewTextFieldMapping()
+		fm.Analyzer = analyzer
+		fm.Store = false
+		fm.IncludeInAll = false
+		im.DefaultMapping.AddFieldMappingsAt(langField(analyzer), fm)
+	}
+	fm := bleve.NewTextFieldMapping()
+	fm.Analyzer = CodeAnalyzer
+	fm.Store = false
+	fm.IncludeInAll = false
+	im.DefaultMapping.AddFieldMappingsAt(codeField, fm)
+
+	fields := make([]string, 0, len(analyzers))
+	for field := range analyzers {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	var meta *mapping.DocumentMapping
+	for _, field := range fields {
+		name := analyzers[field]
+		if name == AnalyzerStandard {
+			continue
+		}
+		fm := bleve.NewTextFieldMapping()
+		fm.Analyzer = bleveAnalyzer(name)
+		key, ok := strings.CutPrefix(field, "meta.")
+		if !ok {
+			im.DefaultMapping.AddFieldMappingsAt(field, fm)
+			continue
+		}
+		if meta == nil {
+			meta = bleve.NewDocumentMapping()
+			im.DefaultMapping.AddSubDocumentMapping("meta", meta)
+		}
+		meta.AddFieldMappingsAt(key, fm)
+	}
+	return im
+}
+
+// Internal keys of the lexical index.
+var (
+	settingsKey     = []byte("semango_settings")
+	fieldLengthsKey = []byte("semango_field_lengths")
+)
+
+// PrepareLexicalIndex makes the lexical index at path ready to be written
+// with settings: it creates it when missing and rebuilds it from its stored
+// fields when it was built with other settings, or before field lengths
+// were recorded for BM25. Vectors are not touched, since chunk IDs do not
+// change. It reports whether the index was rebuilt.
+func PrepareLexicalIndex(ctx context.Context, path string, settings LexicalSettings) (bool, error) {
+	recoverRebuild(path)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		b, err := createBleveIndex(path, settings, nil)
+		if err != nil {
+			return false, err
+		}
+		return false, b.Close()
+	}
+	old, err := OpenOrCreateBleveIndex(path)
+	if err != nil {
+		return false, err
+	}
+	if old.lengths != nil && old.settings.Equal(settings) {
+		return false, old.Close()
+	}
+	count, _ := old.DocCount()
+	util.FromContext(ctx).Info("Rebuilding lexical index", "path", path, "chunks", count)
+	err = rebuildInto(ctx, old, path+".rebuild", settings)
+	if cerr := old.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.RemoveAll(path + ".rebuild")
+		return false, util.WrapError(err, "Failed to rebuild lexical index")
+	}
+	if err := os.Rename(path, path+".old"); err != nil {
+		return false, err
+	}
+	if err := os.Rename(path+".rebuild", path); err != nil {
+		return false, err
+	}
+	return true, os.RemoveAll(path + ".old")
+}
+
+// recoverRebuild restores the index at path when a rebuild stopped between
+// moving the old index aside and moving the new one in.
+func recoverRebuild(path string) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := os.Stat(path + ".old"); err == nil {
+			os.Rename(path+".old", path)
+		}
+	}
+	os.RemoveAll(path + ".rebuild")
+}
+
+// rebuildInto writes every document of old into a new index at path built
+// with settings.
+func rebuildInto(ctx context.Context, old *BleveIndex, path string, settings LexicalSettings) error {
+	b, err := createBleveIndex(path, settings, map[string]interface{}{"unsafe_batch": true})
+	if err != nil {
+		return err
+	}
+	b.batch = b.idx.NewBatch()
+	b.batchSize = scanPageSize
+	b.SetCodeTokenFilter(settings.CodeTokenFilter)
+	var ierr error
+	err = old.EachDocument(ctx, []string{"*"}, func(id string, values map[string]interface{}) {
+		if ierr == nil {
+			text, meta := storedChunk(values)
+			ierr = b.IndexDocument(id, text, meta)
+		}
+	})
+	if err == nil {
+		err = ierr
+	}
+	if cerr := b.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// storedChunk returns the text and metadata of a document from its stored
+// fields.
+func storedChunk(values map[string]interface{}) (string, map[string]string) {
+	text, _ := values["text"].(string)
+	meta := map[string]string{}
+	for field, v := range values {
+		if key, ok := strings.CutPrefix(field, "meta."); ok {
+			if s, ok := v.(string); ok {
+				meta[key] = s
+			}
+		}
+	}
+	return text, meta
+}
+
+// loadState reads the settings and field lengths recorded in the index.
+// Field lengths stay nil for an index that holds documents written before
+// they were recorded.
+func (b *BleveIndex) loadState() error {
+	if v, err := b.idx.GetInternal(settingsKey); err != nil {
+		return err
+	} else if v != nil {
+		if err := json.Unmarshal(v, &b.settings); err != nil {
+			return fmt.Errorf("lexical index settings: %w", err)
+		}
+	}
+	v, err := b.idx.GetInternal(fieldLengthsKey)
+	if err != nil {
+		return err
+	}
+	if v != nil {
+		return json.Unmarshal(v, &b.lengths)
+	}
+	if n, err := b.idx.DocCount(); err == nil && n == 0 {
+		b.lengths = map[string]fieldLength{}
+	}
+	return nil
+}
+
+// tokens returns the number of tokens field holds for text, analysed the
+// way the index analyses it.
+func (b *BleveIndex) tokens(field, text string) int64 {
+	a := b.analyzer(field)
+	if a == nil {
+		return 0
+	}
+	return int64(len(a.Analyze([]byte(text))))
+}
+
+// analyzer returns the analyzer the index mapping applies to field.
+func (b *BleveIndex) analyzer(field string) analysis.Analyzer {
+	m := b.idx.Mapping()
+	if m == nil {
+		return nil
+	}
+	return m.AnalyzerNamed(m.AnalyzerNameForPath(field))
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateAnalyzers(t *testing.T) {
+	if err := ValidateAnalyzers(map[string]string{"text": "en", "path": "code", "meta.title": "standard"}); err != nil {
+		t.Errorf("valid analyzers rejected: %v", err)
+	}
+	for _, bad := range []map[string]string{
+		{"body": "en"},
+		{"meta.": "en"},
+		{"text": "french"},
+	} {
+		if err := ValidateAnalyzers(bad); err == nil {
+			t.Errorf("%v accepted", bad)
+		}
+	}
+}
+
+func TestLexicalSettingsEqual(t *testing.T) {
+	defaults := LexicalSettings{}
+	if !defaults.Equal(LexicalSettings{Analyzers: map[string]string{"text": "standard"}, CodeTokenFilter: CodeTokenFilterSplit}) {
+		t.Error("spelled out defaults differ from the defaults")
+	}
+	if defaults.Equal(LexicalSettings{Analyzers: map[string]string{"text": "en"}}) {
+		t.Error("a text analyzer does not change the settings")
+	}
+	if defaults.Equal(LexicalSettings{CodeTokenFilter: CodeTokenFilterNone}) {
+		t.Error("code_token_filter none does not change the settings")
+	}
+}
+
+func TestPrepareLexicalIndex(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "prepare.bleve")
+	if rebuilt, err := PrepareLexicalIndex(ctx, path, LexicalSettings{}); err != nil || rebuilt {
+		t.Fatalf("new index: rebuilt %v, err %v", rebuilt, err)
+	}
+	idx, err := OpenOrCreateBleveIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.IndexDocument("a", "pooled connections are reused", map[string]string{"path": "a.md"}); err != nil {
+		t.Fatal(err)
+	}
+	idx.Close()
+
+	english := LexicalSettings{Analyzers: map[string]string{"text": AnalyzerEnglish}}
+	search := func() int {
+		t.Helper()
+		idx, err := OpenOrCreateBleveIndex(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer idx.Close()
+		hits, err := idx.SearchTextFiltered(ctx, "connection", "", nil, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(hits)
+	}
+	if n := search(); n != 0 {
+		t.Fatalf("standard analyzer stemmed: %d hits", n)
+	}
+	if rebuilt, err := PrepareLexicalIndex(ctx, path, english); err != nil || !rebuilt {
+		t.Fatalf("changed analyzer: rebuilt %v, err %v", rebuilt, err)
+	}
+	if n := search(); n != 1 {
+		t.Errorf("after the rebuild with en: %d hits, want 1", n)
+	}
+	if rebuilt, err := PrepareLexicalIndex(ctx, path, english); err != nil || rebuilt {
+		t.Errorf("same settings: rebuilt %v, err %v", rebuilt, err)
+	}
+}
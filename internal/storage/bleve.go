@@ -1,33 +1,137 @@
 package storage
 
 import (
+	"context"
+	"encoding/json"
+
 	"github.com/blevesearch/bleve/v2"
 	"github.com/blevesearch/bleve/v2/document"
 	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/util"
 )
 
+// lexicalAnalyzers maps ISO 639-1 codes to Bleve's built-in language
+// analyzers. Chunks whose "lang" metadata matches are additionally indexed
+// into a "text_<analyzer>" field analysed with stemming and stop words for
+// that language.
+var lexicalAnalyzers = map[string]string{
+	"ar": "ar", "da": "da", "de": "de", "en": "en", "es": "es", "fa": "fa",
+	"fi": "fi", "fr": "fr", "hi": "hi", "hu": "hu", "it": "it", "nl": "nl",
+	"no": "no", "pt": "pt", "ro": "ro", "ru": "ru", "sv": "sv", "tr": "tr",
+	"ckb": "ckb", "zh": "cjk", "ja": "cjk", "ko": "cjk",
+}
+
+// LexicalAnalyzer returns the Bleve analyzer for a language hint, or "" if
+// the language has no dedicated analyzer.
+func LexicalAnalyzer(lang string) string {
+	return lexicalAnalyzers[ingest.NormalizeLang(lang)]
+}
+
+func langField(analyzer string) string { return "text_" + analyzer }
+
 // BleveIndex wraps a Bleve index instance.
 type BleveIndex struct {
-	idx bleve.Index
+	idx              bleve.Index
+	splitIdentifiers bool
+	batch            *bleve.Batch // pending documents in bulk mode
+	batchSize        int
+
+	settings LexicalSettings        // recorded when the index was created
+	lengths  map[string]fieldLength // by field; nil when not recorded
 }
 
 // OpenOrCreateBleveIndex opens or creates a Bleve index at the given path.
 func OpenOrCreateBleveIndex(path string) (*BleveIndex, error) {
-	idx, err := bleve.Open(path)
+	return openOrCreateBleveIndex(path, nil)
+}
+
+// OpenBulkBleveIndex opens or creates the Bleve index at path for bulk
+// indexing: IndexDocument collects documents into batches of batchSize,
+// which make few segments for Bleve to merge, and batches are not synced to
+// disk one by one. Close writes the last batch and waits for everything to
+// be persisted; documents may be lost if the process dies before then.
+func OpenBulkBleveIndex(path string, batchSize int) (*BleveIndex, error) {
+	b, err := openOrCreateBleveIndex(path, map[string]interface{}{"unsafe_batch": true})
+	if err != nil {
+		return nil, err
+	}
+	b.batch = b.idx.NewBatch()
+	b.batchSize = batchSize
+	return b, nil
+}
+
+func openOrCreateBleveIndex(path string, runtimeConfig map[string]interface{}) (*BleveIndex, error) {
+	idx, err := bleve.OpenUsing(path, runtimeConfig)
 	if err == bleve.ErrorIndexPathDoesNotExist {
-		mapping := bleve.NewIndexMapping()
-		idx, err = bleve.New(path, mapping)
-		if err != nil {
-			return nil, err
-		}
+		return createBleveIndex(path, LexicalSettings{}, runtimeConfig)
 	} else if err != nil {
 		return nil, err
 	}
-	return &BleveIndex{idx: idx}, nil
+	b := &BleveIndex{idx: idx, splitIdentifiers: true}
+	if err := b.loadState(); err != nil {
+		idx.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// createBleveIndex creates a Bleve index at path mapped for settings and
+// records them.
+func createBleveIndex(path string, settings LexicalSettings, runtimeConfig map[string]interface{}) (*BleveIndex, error) {
+	idx, err := bleve.NewUsing(path, newIndexMapping(settings.Analyzers), bleve.Config.DefaultIndexType, bleve.Config.DefaultKVStore, runtimeConfig)
+	if err != nil {
+		return nil, err
+	}
+	b := &BleveIndex{idx: idx, splitIdentifiers: true, settings: settings.normalized(), lengths: map[string]fieldLength{}}
+	v, err := json.Marshal(b.settings)
+	if err == nil {
+		err = idx.SetInternal(settingsKey, v)
+	}
+	if err == nil {
+		err = b.saveLengths(nil)
+	}
+	if err != nil {
+		idx.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// SetCodeTokenFilter selects how code chunks are tokenized for indexing:
+// CodeTokenFilterSplit (the default, also used for "") additionally indexes
+// identifiers split into words, CodeTokenFilterNone indexes them as written.
+func (b *BleveIndex) SetCodeTokenFilter(name string) {
+	b.splitIdentifiers = name != CodeTokenFilterNone
 }
 
 // IndexDocument indexes a document by ID and text.
 func (b *BleveIndex) IndexDocument(id, text string, meta map[string]string) error {
+	doc := b.document(text, meta)
+	b.forget(id)
+	b.count(doc, 1)
+	if b.batch == nil {
+		batch := b.idx.NewBatch()
+		if err := batch.Index(id, doc); err != nil {
+			return err
+		}
+		if err := b.saveLengths(batch); err != nil {
+			return err
+		}
+		return b.idx.Batch(batch)
+	}
+	if err := b.batch.Index(id, doc); err != nil {
+		return err
+	}
+	if b.batch.Size() >= b.batchSize {
+		return b.flush()
+	}
+	return nil
+}
+
+// document returns the Bleve document of a chunk.
+func (b *BleveIndex) document(text string, meta map[string]string) map[string]interface{} {
 	doc := map[string]interface{}{
 		"text": text,
 		"meta": meta,
@@ -35,23 +139,288 @@ func (b *BleveIndex) IndexDocument(id, text string, meta map[string]string) erro
 	if p, ok := meta["path"]; ok {
 		doc["path"] = p
 	}
-	return b.idx.Index(id, doc)
+	if analyzer := LexicalAnalyzer(meta["lang"]); analyzer != "" {
+		doc[langField(analyzer)] = text
+	}
+	if b.splitIdentifiers && isCodeChunk(meta) {
+		doc[codeField] = text
+	}
+	return doc
+}
+
+// count adds sign times the lengths of the text fields of doc to the field
+// lengths, when they are recorded.
+func (b *BleveIndex) count(doc map[string]interface{}, sign int64) {
+	if b.lengths == nil {
+		return
+	}
+	for field, v := range doc {
+		text, ok := v.(string)
+		if !ok || field == "path" {
+			continue
+		}
+		l := b.lengths[field]
+		l.Docs += sign
+		l.Tokens += sign * b.tokens(field, text)
+		b.lengths[field] = l
+	}
+}
+
+// forget subtracts the field lengths of the indexed document id, if any,
+// before it is replaced or deleted.
+func (b *BleveIndex) forget(id string) {
+	if b.lengths == nil {
+		return
+	}
+	doc, err := b.GetDocument(id)
+	if err != nil || doc == nil {
+		return
+	}
+	values := map[string]interface{}{}
+	for _, field := range doc.Fields {
+		values[field.Name()] = string(field.Value())
+	}
+	text, meta := storedChunk(values)
+	b.count(b.document(text, meta), -1)
+}
+
+// saveLengths records the field lengths with batch, or directly when batch
+// is nil.
+func (b *BleveIndex) saveLengths(batch *bleve.Batch) error {
+	if b.lengths == nil {
+		return nil
+	}
+	v, err := json.Marshal(b.lengths)
+	if err != nil {
+		return err
+	}
+	if batch != nil {
+		batch.SetInternal(fieldLengthsKey, v)
+		return nil
+	}
+	return b.idx.SetInternal(fieldLengthsKey, v)
+}
+
+// flush writes the pending batch of a bulk index.
+func (b *BleveIndex) flush() error {
+	if b.batch == nil || b.batch.Size() == 0 {
+		return nil
+	}
+	if err := b.saveLengths(b.batch); err != nil {
+		return err
+	}
+	err := b.idx.Batch(b.batch)
+	b.batch.Reset()
+	return err
+}
+
+// persist writes the pending batch of a bulk index and waits for Bleve to
+// persist it along with the batches before it: Bleve does not persist
+// unsafe batches when the index is closed.
+func (b *BleveIndex) persist() error {
+	persisted := make(chan error, 1)
+	b.batch.SetPersistedCallback(func(err error) { persisted <- err })
+	if err := b.saveLengths(b.batch); err != nil {
+		return err
+	}
+	if err := b.idx.Batch(b.batch); err != nil {
+		return err
+	}
+	b.batch.Reset()
+	return <-persisted
 }
 
 // SearchText performs a simple match search on the text field.
 func (b *BleveIndex) SearchText(query string, size int) ([]*search.DocumentMatch, error) {
-	q := bleve.NewMatchQuery(query)
+	return b.search(context.Background(), bleve.NewMatchQuery(query), size)
+}
+
+// SearchTextLang is like SearchText but also matches the language-specific
+// field for lang, so stemmed forms in that language score. Without an
+// analyzer for lang it behaves exactly like SearchText.
+func (b *BleveIndex) SearchTextLang(text, lang string, size int) ([]*search.DocumentMatch, error) {
+	return b.SearchTextFiltered(context.Background(), text, lang, nil, size)
+}
+
+// SearchTextFiltered is SearchTextLang restricted to documents whose
+// metadata matches every key/value pair in filter. The query is also matched
+// against identifiers split into words, so "user by id" finds getUserByID.
+// The search stops when ctx is cancelled, and logs with the logger of ctx.
+func (b *BleveIndex) SearchTextFiltered(ctx context.Context, text, lang string, filter map[string]string, size int) ([]*search.DocumentMatch, error) {
+	return b.searchFiltered(ctx, b.textQuery(text, lang), filter, size)
+}
+
+// SearchQueryFiltered is SearchTextFiltered for a query in the advanced
+// lexical syntax.
+func (b *BleveIndex) SearchQueryFiltered(ctx context.Context, q LexicalQuery, lang string, filter map[string]string, size int) ([]*search.DocumentMatch, error) {
+	return b.searchFiltered(ctx, q.bleveQuery(b.textQuery, lang), filter, size)
+}
+
+// textQuery matches text in the text field, in identifiers split into words
+// and, when lang has an analyzer, in the text analysed for it.
+func (b *BleveIndex) textQuery(text, lang string) query.Query {
+	code := bleve.NewMatchQuery(text)
+	code.SetField(codeField)
+	code.Analyzer = CodeAnalyzer
+	var q query.Query = bleve.NewDisjunctionQuery(bleve.NewMatchQuery(text), code)
+	if _, ok := b.settings.Analyzers["text"]; ok {
+		// The unfielded query is analysed with the standard analyzer, which
+		// misses the terms of another text analyzer.
+		own := bleve.NewMatchQuery(text)
+		own.SetField("text")
+		q = bleve.NewDisjunctionQuery(q, own)
+	}
+	if analyzer := LexicalAnalyzer(lang); analyzer != "" {
+		localized := bleve.NewMatchQuery(text)
+		localized.SetField(langField(analyzer))
+		localized.Analyzer = analyzer
+		q = bleve.NewDisjunctionQuery(q, localized)
+	}
+	return q
+}
+
+func (b *BleveIndex) searchFiltered(ctx context.Context, q query.Query, filter map[string]string, size int) ([]*search.DocumentMatch, error) {
+	if len(filter) > 0 {
+		q = bleve.NewConjunctionQuery(q, metaFilterQuery(filter))
+	}
+	return b.search(ctx, q, size)
+}
+
+// FilterIDs returns the IDs of up to limit documents whose metadata matches
+// every key/value pair in filter. The second result reports whether more
+// documents matched than were returned.
+func (b *BleveIndex) FilterIDs(ctx context.Context, filter map[string]string, limit int) ([]string, bool, error) {
+	sreq := bleve.NewSearchRequestOptions(metaFilterQuery(filter), limit, 0, false)
+	sres, err := b.idx.SearchInContext(ctx, sreq)
+	if err != nil {
+		util.FromContext(ctx).Error("Failed to resolve metadata filter in Bleve index", "error", err, "filter", filter)
+		return nil, false, err
+	}
+	util.FromContext(ctx).Debug("Resolved metadata filter in Bleve index", "matches", sres.Total, "took", sres.Took)
+	ids := make([]string, len(sres.Hits))
+	for i, hit := range sres.Hits {
+		ids[i] = hit.ID
+	}
+	return ids, sres.Total > uint64(len(ids)), nil
+}
+
+// metaFilterQuery matches each value as a phrase in its "meta.<key>" field.
+// Matching is analysed, so callers wanting exact equality should re-check
+// the stored values.
+func metaFilterQuery(filter map[string]string) query.Query {
+	clauses := make([]query.Query, 0, len(filter))
+	for k, v := range filter {
+		mq := bleve.NewMatchPhraseQuery(v)
+		mq.SetField("meta." + k)
+		clauses = append(clauses, mq)
+	}
+	return bleve.NewConjunctionQuery(clauses...)
+}
+
+func (b *BleveIndex) search(ctx context.Context, q query.Query, size int) ([]*search.DocumentMatch, error) {
 	sreq := bleve.NewSearchRequestOptions(q, size, 0, false)
-	sres, err := b.idx.Search(sreq)
+	sres, err := b.idx.SearchInContext(ctx, sreq)
 	if err != nil {
+		util.FromContext(ctx).Error("Failed to search Bleve index", "error", err, "size", size)
 		return nil, err
 	}
+	util.FromContext(ctx).Debug("Searched Bleve index", "size", size, "hits", len(sres.Hits), "total", sres.Total, "took", sres.Took)
 	return sres.Hits, nil
 }
 
-// Close closes the Bleve index.
+// DocCount returns the number of documents, i.e. chunks, in the index.
+func (b *BleveIndex) DocCount() (uint64, error) {
+	return b.idx.DocCount()
+}
+
+// scanPageSize is how many documents EachDocument reads per request.
+const scanPageSize = 1000
+
+// EachDocument calls fn with the ID and the stored values of fields of every
+// document in the index, in ID order. Pages are read with search_after, so
+// the cost does not grow with the depth of the scan.
+func (b *BleveIndex) EachDocument(ctx context.Context, fields []string, fn func(id string, values map[string]interface{})) error {
+	var after []string
+	for {
+		sreq := bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), scanPageSize, 0, false)
+		sreq.Fields = fields
+		sreq.SortBy([]string{"_id"})
+		sreq.SearchAfter = after
+		sres, err := b.idx.SearchInContext(ctx, sreq)
+		if err != nil {
+			return err
+		}
+		for _, hit := range sres.Hits {
+			fn(hit.ID, hit.Fields)
+		}
+		if len(sres.Hits) < scanPageSize {
+			return nil
+		}
+		after = []string{sres.Hits[len(sres.Hits)-1].ID}
+	}
+}
+
+// maxPathChunks bounds the chunks looked up for a single path.
+const maxPathChunks = 1000000
+
+// PathIDs returns the IDs of the chunks indexed under path.
+func (b *BleveIndex) PathIDs(path string) ([]string, error) {
+	candidates, _, err := b.FilterIDs(context.Background(), map[string]string{"path": path}, maxPathChunks)
+	if err != nil {
+		return nil, err
+	}
+	// The filter is analysed, so "a/b.md" also matches "x/a/b.md"; compare
+	// the stored path.
+	var ids []string
+	for _, id := range candidates {
+		doc, err := b.GetDocument(id)
+		if err != nil || doc == nil {
+			continue
+		}
+		for _, field := range doc.Fields {
+			if field.Name() == "meta.path" && string(field.Value()) == path {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	return ids, nil
+}
+
+// DeletePath removes every chunk indexed under path and returns their IDs,
+// so the caller can drop the matching vectors.
+func (b *BleveIndex) DeletePath(path string) ([]string, error) {
+	ids, err := b.PathIDs(path)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	return ids, b.DeleteIDs(ids)
+}
+
+// DeleteIDs removes the chunks ids; IDs not in the index are ignored.
+func (b *BleveIndex) DeleteIDs(ids []string) error {
+	batch := b.idx.NewBatch()
+	for _, id := range ids {
+		b.forget(id)
+		batch.Delete(id)
+	}
+	if err := b.saveLengths(batch); err != nil {
+		return err
+	}
+	return b.idx.Batch(batch)
+}
+
+// Close writes the pending batch of a bulk index and closes the Bleve
+// index.
 func (b *BleveIndex) Close() error {
-	return b.idx.Close()
+	var err error
+	if b.batch != nil {
+		err = b.persist()
+	}
+	if cerr := b.idx.Close(); err == nil {
+		err = cerr
+	}
+	return err
 }
 
 // GetDocument fetches a document by ID from the index.
@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+// TestLexicalAnalyzersRegistered maps a field with the analyzer of each
+// language, so that one whose package is not imported fails here rather
+// than when an index is created.
+func TestLexicalAnalyzersRegistered(t *testing.T) {
+	for lang, analyzer := range lexicalAnalyzers {
+		im := bleve.NewIndexMapping()
+		fm := bleve.NewTextFieldMapping()
+		fm.Analyzer = analyzer
+		im.DefaultMapping.AddFieldMappingsAt(langField(analyzer), fm)
+		if err := im.Validate(); err != nil {
+			t.Errorf("analyzer %q of %q: %v", analyzer, lang, err)
+		}
+	}
+}
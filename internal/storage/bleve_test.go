@@ -1,6 +1,9 @@
 package storage
 
 import (
+	"context"
+	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -32,4 +35,160 @@ func TestBleveIndex_Basic(t *testing.T) {
 	if err != nil || doc == nil {
 		t.Fatalf("failed to get document: %v", err)
 	}
-} 
\ No newline at end of file
+} 
+func TestBleveIndex_SearchTextLang(t *testing.T) {
+	idx, err := OpenOrCreateBleveIndex(t.TempDir() + "/lang.bleve")
+	if err != nil {
+		t.Fatalf("failed to open/create index: %v", err)
+	}
+	defer idx.Close()
+
+	if err := idx.IndexDocument("doc1", "the dogs were running home", map[string]string{"lang": "en"}); err != nil {
+		t.Fatalf("failed to index document: %v", err)
+	}
+
+	// The standard analyzer does not stem, so "run" misses "running".
+	hits, err := idx.SearchText("run", 5)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(hits) != 0 {
+		t.Fatalf("expected no unstemmed hits, got %+v", hits)
+	}
+
+	hits, err = idx.SearchTextLang("run", "en-US", 5)
+	if err != nil {
+		t.Fatalf("language search failed: %v", err)
+	}
+	if len(hits) == 0 || hits[0].ID != "doc1" {
+		t.Errorf("expected stemmed hit for doc1, got %+v", hits)
+	}
+}
+
+func TestBleveIndex_MetaFilter(t *testing.T) {
+	idx, err := OpenOrCreateBleveIndex(t.TempDir() + "/filter.bleve")
+	if err != nil {
+		t.Fatalf("failed to open/create index: %v", err)
+	}
+	defer idx.Close()
+
+	docs := map[string]map[string]string{
+		"a": {"source": "EmailLoader", "thread_id": "t1@example.com"},
+		"b": {"source": "EmailLoader", "thread_id": "t2@example.com"},
+		"c": {"source": "MarkdownLoader"},
+	}
+	for id, meta := range docs {
+		if err := idx.IndexDocument(id, "quarterly report numbers", meta); err != nil {
+			t.Fatalf("failed to index document: %v", err)
+		}
+	}
+
+	ids, truncated, err := idx.FilterIDs(context.Background(), map[string]string{"source": "EmailLoader"}, 10)
+	if err != nil {
+		t.Fatalf("filter failed: %v", err)
+	}
+	if len(ids) != 2 || truncated {
+		t.Errorf("expected 2 untruncated IDs, got %v (truncated=%v)", ids, truncated)
+	}
+	if _, truncated, _ := idx.FilterIDs(context.Background(), map[string]string{"source": "EmailLoader"}, 1); !truncated {
+		t.Error("expected truncation with limit 1")
+	}
+
+	hits, err := idx.SearchTextFiltered(context.Background(), "report", "", map[string]string{"source": "EmailLoader", "thread_id": "t2@example.com"}, 10)
+	if err != nil {
+		t.Fatalf("filtered search failed: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ID != "b" {
+		t.Errorf("expected only doc b, got %+v", hits)
+	}
+}
+
+func TestBleveIndex_SplitIdentifiers(t *testing.T) {
+	idx, err := OpenOrCreateBleveIndex(t.TempDir() + "/code.bleve")
+	if err != nil {
+		t.Fatalf("failed to open/create index: %v", err)
+	}
+	defer idx.Close()
+
+	code := map[string]string{"source": "CodeLoader", "language": "go"}
+	if err := idx.IndexDocument("camel", "func getUserByID(id int) *User", code); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.IndexDocument("snake", "def load_http_config(path):", code); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.IndexDocument("prose", "getUserByID appears in this note", map[string]string{"source": "TextLoader"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for query, want := range map[string]string{"user by id": "camel", "http config": "snake"} {
+		hits, err := idx.SearchTextFiltered(context.Background(), query, "", nil, 5)
+		if err != nil {
+			t.Fatalf("search %q failed: %v", query, err)
+		}
+		if len(hits) != 1 || hits[0].ID != want {
+			t.Errorf("search %q: expected only %s, got %+v", query, want, hits)
+		}
+	}
+
+	// Whole identifiers still match through the regular text field.
+	hits, err := idx.SearchTextFiltered(context.Background(), "getUserByID", "", nil, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 {
+		t.Errorf("expected code and prose hits for the full identifier, got %+v", hits)
+	}
+}
+
+func TestBleveIndex_DeletePath(t *testing.T) {
+	idx, err := OpenOrCreateBleveIndex(t.TempDir() + "/delete.bleve")
+	if err != nil {
+		t.Fatalf("failed to open/create index: %v", err)
+	}
+	defer idx.Close()
+
+	for id, path := range map[string]string{"a0": "docs/a.md", "a1": "docs/a.md", "nested": "old/docs/a.md", "b": "docs/b.md"} {
+		if err := idx.IndexDocument(id, "release notes", map[string]string{"path": path}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids, err := idx.DeletePath("docs/a.md")
+	if err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	sort.Strings(ids)
+	if !reflect.DeepEqual(ids, []string{"a0", "a1"}) {
+		t.Errorf("expected only the exact path's chunks deleted, got %v", ids)
+	}
+	hits, _ := idx.SearchText("release", 10)
+	if len(hits) != 2 {
+		t.Errorf("expected 2 remaining chunks, got %d", len(hits))
+	}
+}
+
+func TestOpenBulkBleveIndex_PersistsOnClose(t *testing.T) {
+	path := t.TempDir() + "/bulk.bleve"
+	idx, err := OpenBulkBleveIndex(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if err := idx.IndexDocument(id, "bulk written chunk "+id, map[string]string{"path": id + ".md"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := idx.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err = OpenOrCreateBleveIndex(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer idx.Close()
+	if n, err := idx.DocCount(); err != nil || n != 3 {
+		t.Errorf("reopened bulk index has %d documents, err %v; want 3", n, err)
+	}
+}
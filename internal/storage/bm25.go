@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/blevesearch/bleve/v2/search"
+	index "github.com/blevesearch/bleve_index_api"
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// fieldLength counts the documents holding a field and their tokens, for
+// the average document length of BM25.
+type fieldLength struct {
+	Docs   int64 `json:"docs"`
+	Tokens int64 `json:"tokens"`
+}
+
+// bm25 scores a term occurring tf times in a document of dl tokens, where
+// the field holds avgdl tokens on average and df of its docs documents
+// contain the term.
+func bm25(tf, dl, avgdl float64, df, docs int64, k1, b float64) float64 {
+	idf := math.Log(1 + (float64(docs-df)+0.5)/(float64(df)+0.5))
+	return idf * tf * (k1 + 1) / (tf + k1*(1-b+b*dl/avgdl))
+}
+
+// RescoreBM25 replaces the scores of hits, a search for text with lang, by
+// their BM25 scores with parameters k1 and b, and sorts them by the new
+// scores. A hit scores the best of the fields the text query matches: the
+// text, identifiers split into words and the text analysed for lang. It
+// returns false and leaves hits alone when the index has no field lengths,
+// because it was built before they were recorded.
+func (b *BleveIndex) RescoreBM25(ctx context.Context, hits []*search.DocumentMatch, text, lang string, k1, bParam float64) bool {
+	if b.lengths == nil || len(hits) == 0 {
+		return false
+	}
+	adv, err := b.idx.Advanced()
+	if err != nil {
+		return false
+	}
+	r, err := adv.Reader()
+	if err != nil {
+		return false
+	}
+	defer r.Close()
+
+	// Internal IDs are resolved in this reader's snapshot, in which they
+	// are walked in order.
+	type hit struct {
+		match *search.DocumentMatch
+		id    index.IndexInternalID
+		score float64
+	}
+	docs := make([]*hit, 0, len(hits))
+	for _, m := range hits {
+		id, err := r.InternalID(m.ID)
+		if err != nil || id == nil {
+			continue
+		}
+		docs = append(docs, &hit{match: m, id: id})
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].id.Compare(docs[j].id) < 0 })
+
+	fields := []string{"text", codeField}
+	if analyzer := LexicalAnalyzer(lang); analyzer != "" {
+		fields = append(fields, langField(analyzer))
+	}
+	logger := util.FromContext(ctx)
+	for _, field := range fields {
+		stats := b.lengths[field]
+		if stats.Docs == 0 || stats.Tokens == 0 {
+			continue
+		}
+		avgdl := float64(stats.Tokens) / float64(stats.Docs)
+		scores := make([]float64, len(docs))
+		for _, term := range b.terms(field, text) {
+			tfr, err := r.TermFieldReader(ctx, []byte(term), field, true, true, false)
+			if err != nil {
+				logger.Warn("BM25 rescoring failed", "field", field, "error", err)
+				return false
+			}
+			df := min(int64(tfr.Count()), stats.Docs)
+			var tfd index.TermFieldDoc
+			for i, d := range docs {
+				got, err := tfr.Advance(d.id, &tfd)
+				if err != nil || got == nil {
+					break
+				}
+				if !got.ID.Equals(d.id) || got.Norm <= 0 {
+					continue
+				}
+				dl := 1 / (got.Norm * got.Norm) // norms are 1/sqrt(length)
+				scores[i] += bm25(float64(got.Freq), dl, avgdl, df, stats.Docs, k1, bParam)
+			}
+			tfr.Close()
+		}
+		for i, s := range scores {
+			docs[i].score = max(docs[i].score, s)
+		}
+	}
+	for _, m := range hits {
+		m.Score = 0
+	}
+	for _, d := range docs {
+		d.match.Score = d.score
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	return true
+}
+
+// terms returns the distinct terms of text analysed for field.
+func (b *BleveIndex) terms(field, text string) []string {
+	a := b.analyzer(field)
+	if a == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var terms []string
+	for _, tok := range a.Analyze([]byte(text)) {
+		if t := string(tok.Term); !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
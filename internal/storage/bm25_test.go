@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestBM25(t *testing.T) {
+	base := bm25(1, 10, 10, 5, 100, 1.2, 0.75)
+	if more := bm25(2, 10, 10, 5, 100, 1.2, 0.75); more <= base {
+		t.Errorf("a second occurrence did not raise the score: %v <= %v", more, base)
+	}
+	if many, more := bm25(20, 10, 10, 5, 100, 1.2, 0.75), bm25(40, 10, 10, 5, 100, 1.2, 0.75); more-many > 0.05*many {
+		t.Errorf("term frequency does not saturate: %v then %v", many, more)
+	}
+	if short := bm25(1, 5, 10, 5, 100, 1.2, 0.75); short <= base {
+		t.Errorf("a shorter document did not score higher: %v <= %v", short, base)
+	}
+	if rare := bm25(1, 10, 10, 1, 100, 1.2, 0.75); rare <= base {
+		t.Errorf("a rarer term did not score higher: %v <= %v", rare, base)
+	}
+	if flat := bm25(1, 5, 10, 5, 100, 1.2, 0); flat != bm25(1, 20, 10, 5, 100, 1.2, 0) {
+		t.Error("b = 0 still normalizes by length")
+	}
+}
+
+func TestBleveIndex_RescoreBM25(t *testing.T) {
+	idx, err := OpenOrCreateBleveIndex(filepath.Join(t.TempDir(), "bm25.bleve"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	docs := map[string]string{
+		"short": "cache eviction",
+		"long":  "the cache is cleared when the server restarts and when the configuration changes on disk",
+		"other": "vector search with faiss",
+	}
+	for id, text := range docs {
+		if err := idx.IndexDocument(id, text, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if l := idx.lengths["text"]; l.Docs != 3 || l.Tokens == 0 {
+		t.Fatalf("text field lengths %+v", l)
+	}
+
+	ctx := context.Background()
+	hits, err := idx.SearchTextFiltered(ctx, "cache", "", nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !idx.RescoreBM25(ctx, hits, "cache", "", 1.2, 0.75) {
+		t.Fatal("no field lengths")
+	}
+	if len(hits) != 2 || hits[0].ID != "short" || hits[0].Score <= hits[1].Score {
+		t.Errorf("hits %v, want the short document first", hits)
+	}
+
+	if err := idx.DeleteIDs([]string{"long"}); err != nil {
+		t.Fatal(err)
+	}
+	if l := idx.lengths["text"]; l.Docs != 2 {
+		t.Errorf("after a delete: %d documents", l.Docs)
+	}
+}
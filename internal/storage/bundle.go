@@ -0,0 +1,406 @@
+package storage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BundleFormat is the version of the index bundle layout written by
+// WriteBundle.
+const BundleFormat = 1
+
+// BundleManifest describes a prebuilt index bundle. The embedding fields let
+// a consumer refuse vectors from a model other than its own.
+type BundleManifest struct {
+	Format            int        `json:"format"`
+	CreatedAt         time.Time  `json:"created_at"`
+	Generation        Generation `json:"generation"`
+	EmbeddingProvider string     `json:"embedding_provider"`
+	EmbeddingModel    string     `json:"embedding_model"`
+	Dimension         int        `json:"dimension,omitempty"`
+	SemangoVersion    string     `json:"semango_version,omitempty"`
+	// Files holds the hex SHA-256 of every other entry, by name. Bundles
+	// written before checksums were added have none and are not verified.
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// ErrBundleChecksum reports a bundle whose entries do not match the
+// checksums in its manifest, e.g. a truncated or tampered file.
+var ErrBundleChecksum = errors.New("bundle checksum mismatch")
+
+// ErrIndexChanged reports an index that was written to while a bundle of
+// it was being made.
+var ErrIndexChanged = errors.New("index changed while the bundle was written")
+
+// BundlePaths locates the on-disk indexes that make up a bundle.
+type BundlePaths struct {
+	Lexical string // Bleve index directory
+	Vector  string // FAISS index file; its ".ids.json" mapping and ".model.json" fingerprint travel with it
+}
+
+func (p BundlePaths) vectorFiles() map[string]string {
+	return map[string]string{
+		"vector/index":            p.Vector,
+		"vector/index.ids.json":   JSONIDMapPath(p.Vector),
+		"vector/index.model.json": IndexModelPath(p.Vector),
+	}
+}
+
+// bundleEntry is a file to be written into a bundle.
+type bundleEntry struct {
+	name, src string
+	size      int64
+}
+
+// bundleEntries lists the files of the indexes at paths. A missing vector
+// index or generation stamp is allowed, for lexical-only setups.
+func bundleEntries(paths BundlePaths) ([]bundleEntry, error) {
+	var entries []bundleEntry
+	add := func(name, src string, optional bool) error {
+		info, err := os.Stat(src)
+		if optional && os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		entries = append(entries, bundleEntry{name: name, src: src, size: info.Size()})
+		return nil
+	}
+	if err := add("generation.json", GenerationPath(paths.Lexical), true); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"vector/index", "vector/index.ids.json", "vector/index.model.json"} {
+		if err := add(name, paths.vectorFiles()[name], true); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := os.Stat(paths.Lexical); err != nil {
+		return nil, fmt.Errorf("lexical index: %w", err)
+	}
+	err := filepath.WalkDir(paths.Lexical, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(paths.Lexical, p)
+		if err != nil {
+			return err
+		}
+		return add("lexical/"+filepath.ToSlash(rel), p, false)
+	})
+	return entries, err
+}
+
+// WriteBundle writes the indexes at paths, their generation stamp and m as a
+// gzipped tarball. The manifest comes first and records a checksum of every
+// file; the files are read twice, to checksum and to copy them, and if one
+// changes in between ErrIndexChanged is returned, so a bundle never mixes
+// two states of an index.
+func WriteBundle(w io.Writer, paths BundlePaths, m BundleManifest) error {
+	m.Format = BundleFormat
+	g, err := ReadGeneration(GenerationPath(paths.Lexical))
+	if err != nil {
+		return err
+	}
+	m.Generation = g
+	entries, err := bundleEntries(paths)
+	if err != nil {
+		return err
+	}
+	m.Files = make(map[string]string, len(entries))
+	for _, e := range entries {
+		sum, err := fileSHA256(e.src)
+		if err != nil {
+			return err
+		}
+		m.Files[e.name] = sum
+	}
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := writeTarBytes(tw, "manifest.json", manifest); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := writeTarFile(tw, e, m.Files[e.name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeTarBytes(tw *tar.Writer, name string, data []byte) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// writeTarFile copies e into tw and checks that its content still has the
+// checksum sum.
+func writeTarFile(tw *tar.Writer, e bundleEntry, sum string) error {
+	f, err := os.Open(e.src)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrIndexChanged, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() != e.size {
+		return fmt.Errorf("%w: %s", ErrIndexChanged, e.name)
+	}
+	hdr := &tar.Header{Name: e.name, Mode: 0644, Size: e.size, ModTime: info.ModTime()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	h := sha256.New()
+	if _, err := io.CopyN(io.MultiWriter(tw, h), f, e.size); err != nil {
+		return err
+	}
+	if hex.EncodeToString(h.Sum(nil)) != sum {
+		return fmt.Errorf("%w: %s", ErrIndexChanged, e.name)
+	}
+	return nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ReadBundle unpacks a bundle into a staging directory next to the lexical
+// index, lets accept inspect the manifest, and only then replaces the local
+// indexes. If accept returns an error nothing is changed.
+func ReadBundle(r io.Reader, paths BundlePaths, accept func(BundleManifest) error) (BundleManifest, error) {
+	var m BundleManifest
+	parent := filepath.Dir(filepath.Clean(paths.Lexical))
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return m, err
+	}
+	stage, err := os.MkdirTemp(parent, ".bundle-*")
+	if err != nil {
+		return m, err
+	}
+	defer os.RemoveAll(stage)
+
+	if m, err = unpackBundle(r, stage); err != nil {
+		return m, err
+	}
+	if accept != nil {
+		if err := accept(m); err != nil {
+			return m, err
+		}
+	}
+
+	// Install: the lexical directory is swapped as a whole, the vector files
+	// and generation stamp are renamed over the existing ones.
+	old := paths.Lexical + ".old"
+	os.RemoveAll(old)
+	if err := os.Rename(paths.Lexical, old); err != nil && !os.IsNotExist(err) {
+		return m, err
+	}
+	if err := os.Rename(filepath.Join(stage, "lexical"), paths.Lexical); err != nil {
+		os.Rename(old, paths.Lexical)
+		return m, err
+	}
+	os.RemoveAll(old)
+	for name, dst := range paths.vectorFiles() {
+		src := filepath.Join(stage, filepath.FromSlash(name))
+		if _, err := os.Stat(src); err != nil {
+			os.Remove(dst) // a lexical-only bundle must not leave stale vectors behind
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return m, err
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return m, err
+		}
+	}
+	if err := os.Rename(filepath.Join(stage, "generation.json"), GenerationPath(paths.Lexical)); err != nil && !os.IsNotExist(err) {
+		return m, err
+	}
+	return m, nil
+}
+
+// ExtractBundle unpacks a bundle into dir, leaving the local indexes alone,
+// and returns where its indexes are, e.g. to search an old index read-only.
+// The generation stamp is placed where GenerationPath expects it.
+func ExtractBundle(r io.Reader, dir string) (BundleManifest, BundlePaths, error) {
+	paths := BundlePaths{
+		Lexical: filepath.Join(dir, "lexical"),
+		Vector:  filepath.Join(dir, "vector", "index"),
+	}
+	m, err := unpackBundle(r, dir)
+	return m, paths, err
+}
+
+// unpackBundle extracts the entries of a bundle into dir, which must exist,
+// and checks its manifest and checksums.
+func unpackBundle(r io.Reader, stage string) (BundleManifest, error) {
+	return readBundle(r, func(name string, body io.Reader) error {
+		dst := filepath.Join(stage, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		f, err := os.Create(dst)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, body)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	})
+}
+
+// VerifyBundle reads a whole bundle and checks its manifest and checksums
+// without extracting it.
+func VerifyBundle(r io.Reader) (BundleManifest, error) {
+	return readBundle(r, func(string, io.Reader) error { return nil })
+}
+
+// ReadBundleManifest returns the manifest of a bundle, reading no further
+// than needed. Bundles written by WriteBundle start with it.
+func ReadBundleManifest(r io.Reader) (BundleManifest, error) {
+	var m BundleManifest
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return m, fmt.Errorf("not an index bundle: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return m, errors.New("not an index bundle: manifest.json missing")
+		}
+		if err != nil {
+			return m, err
+		}
+		if path.Clean("/"+hdr.Name) == "/manifest.json" {
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return m, err
+			}
+			return parseManifest(data)
+		}
+	}
+}
+
+// readBundle passes every file entry but the manifest to fn, then checks
+// the manifest and, when it has them, the checksums of the entries.
+func readBundle(r io.Reader, fn func(name string, body io.Reader) error) (BundleManifest, error) {
+	var m BundleManifest
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return m, fmt.Errorf("not an index bundle: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	sums := map[string]string{}
+	var manifest []byte
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return m, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean("/" + hdr.Name)[1:]
+		if name == "" || strings.HasPrefix(name, "../") {
+			return m, fmt.Errorf("invalid bundle entry %q", hdr.Name)
+		}
+		if name == "manifest.json" {
+			if manifest, err = io.ReadAll(tr); err != nil {
+				return m, err
+			}
+			continue
+		}
+		h := sha256.New()
+		body := io.TeeReader(tr, h)
+		if err := fn(name, body); err != nil {
+			return m, err
+		}
+		if _, err := io.Copy(io.Discard, body); err != nil {
+			return m, err
+		}
+		sums[name] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	if manifest == nil {
+		return m, errors.New("not an index bundle: manifest.json missing")
+	}
+	if m, err = parseManifest(manifest); err != nil {
+		return m, err
+	}
+	if m.Files != nil {
+		for name, want := range m.Files {
+			if got, ok := sums[name]; !ok {
+				return m, fmt.Errorf("%w: %s missing", ErrBundleChecksum, name)
+			} else if got != want {
+				return m, fmt.Errorf("%w: %s", ErrBundleChecksum, name)
+			}
+		}
+		for name := range sums {
+			if _, ok := m.Files[name]; !ok {
+				return m, fmt.Errorf("%w: unexpected entry %s", ErrBundleChecksum, name)
+			}
+		}
+	}
+	lexical := false
+	for name := range sums {
+		lexical = lexical || strings.HasPrefix(name, "lexical/")
+	}
+	if !lexical {
+		return m, errors.New("invalid bundle: lexical index missing")
+	}
+	return m, nil
+}
+
+func parseManifest(data []byte) (BundleManifest, error) {
+	var m BundleManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	if m.Format != BundleFormat {
+		return m, fmt.Errorf("unsupported bundle format %d (expected %d)", m.Format, BundleFormat)
+	}
+	return m, nil
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBundle_RoundTrip(t *testing.T) {
+	src := t.TempDir()
+	srcPaths := BundlePaths{Lexical: filepath.Join(src, "bleve"), Vector: filepath.Join(src, "faiss.index")}
+	os.MkdirAll(filepath.Join(srcPaths.Lexical, "store"), 0755)
+	os.WriteFile(filepath.Join(srcPaths.Lexical, "index_meta.json"), []byte(`{"storage":"scorch"}`), 0644)
+	os.WriteFile(filepath.Join(srcPaths.Lexical, "store", "000001.zap"), []byte("segment"), 0644)
+	os.WriteFile(srcPaths.Vector, []byte("vectors"), 0644)
+	os.WriteFile(srcPaths.Vector+".ids.json", []byte(`{"a":1}`), 0644)
+	os.WriteFile(srcPaths.Vector+".model.json", []byte(`{"provider":"openai"}`), 0644)
+	if _, err := BumpGeneration(GenerationPath(srcPaths.Lexical)); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, srcPaths, BundleManifest{EmbeddingProvider: "openai", EmbeddingModel: "text-embedding-3-small"}); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := t.TempDir()
+	dstPaths := BundlePaths{Lexical: filepath.Join(dst, "bleve"), Vector: filepath.Join(dst, "vec", "faiss.index")}
+	os.MkdirAll(filepath.Join(dstPaths.Lexical, "store"), 0755)
+	os.WriteFile(filepath.Join(dstPaths.Lexical, "store", "stale.zap"), []byte("old"), 0644)
+
+	// A rejected bundle leaves the local index alone.
+	bundle := buf.Bytes()
+	_, err := ReadBundle(bytes.NewReader(bundle), dstPaths, func(BundleManifest) error { return errors.New("wrong model") })
+	if err == nil {
+		t.Fatal("expected the accept error")
+	}
+	if _, err := os.Stat(filepath.Join(dstPaths.Lexical, "store", "stale.zap")); err != nil {
+		t.Errorf("rejected bundle modified the index: %v", err)
+	}
+
+	m, err := ReadBundle(bytes.NewReader(bundle), dstPaths, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Format != BundleFormat || m.EmbeddingModel != "text-embedding-3-small" || m.Generation.Number != 1 {
+		t.Errorf("unexpected manifest %+v", m)
+	}
+	for p, want := range map[string]string{
+		filepath.Join(dstPaths.Lexical, "store", "000001.zap"): "segment",
+		dstPaths.Vector:                 "vectors",
+		dstPaths.Vector + ".ids.json":   `{"a":1}`,
+		dstPaths.Vector + ".model.json": `{"provider":"openai"}`,
+	} {
+		if got, err := os.ReadFile(p); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", p, got, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dstPaths.Lexical, "store", "stale.zap")); !os.IsNotExist(err) {
+		t.Error("stale lexical files survived the pull")
+	}
+	if g, _ := ReadGeneration(GenerationPath(dstPaths.Lexical)); g.Number != 1 {
+		t.Errorf("generation = %d, want 1", g.Number)
+	}
+
+	if _, err := ReadBundle(bytes.NewReader([]byte("not gzip")), dstPaths, nil); err == nil {
+		t.Error("expected an error for a non-bundle")
+	}
+}
+
+func TestExtractBundle(t *testing.T) {
+	src := t.TempDir()
+	srcPaths := BundlePaths{Lexical: filepath.Join(src, "bleve"), Vector: filepath.Join(src, "faiss.index")}
+	os.MkdirAll(srcPaths.Lexical, 0755)
+	os.WriteFile(filepath.Join(srcPaths.Lexical, "index_meta.json"), []byte(`{"storage":"scorch"}`), 0644)
+	os.WriteFile(srcPaths.Vector, []byte("vectors"), 0644)
+	BumpGeneration(GenerationPath(srcPaths.Lexical))
+	BumpGeneration(GenerationPath(srcPaths.Lexical))
+
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, srcPaths, BundleManifest{EmbeddingModel: "m"}); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	m, paths, err := ExtractBundle(&buf, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Generation.Number != 2 {
+		t.Errorf("manifest generation = %d, want 2", m.Generation.Number)
+	}
+	if got, err := os.ReadFile(paths.Vector); err != nil || string(got) != "vectors" {
+		t.Errorf("vector index = %q, %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(paths.Lexical, "index_meta.json")); err != nil {
+		t.Error(err)
+	}
+	if g, _ := ReadGeneration(GenerationPath(paths.Lexical)); g.Number != 2 {
+		t.Errorf("generation = %d, want 2", g.Number)
+	}
+}
+
+func TestVerifyBundle(t *testing.T) {
+	src := t.TempDir()
+	paths := BundlePaths{Lexical: filepath.Join(src, "bleve"), Vector: filepath.Join(src, "faiss.index")}
+	os.MkdirAll(paths.Lexical, 0755)
+	os.WriteFile(filepath.Join(paths.Lexical, "index_meta.json"), []byte(`{"storage":"scorch"}`), 0644)
+	os.WriteFile(paths.Vector, []byte("vectors"), 0644)
+	BumpGeneration(GenerationPath(paths.Lexical))
+
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, paths, BundleManifest{EmbeddingModel: "m"}); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadBundleManifest(bytes.NewReader(buf.Bytes()))
+	if err != nil || m.EmbeddingModel != "m" || len(m.Files) != 3 {
+		t.Fatalf("manifest = %+v, %v; want 3 checksummed files", m, err)
+	}
+	if _, err := VerifyBundle(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("intact bundle: %v", err)
+	}
+
+	// Repack the bundle with one entry altered.
+	var tampered bytes.Buffer
+	gz, _ := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	tr := tar.NewReader(gz)
+	zw := gzip.NewWriter(&tampered)
+	tw := tar.NewWriter(zw)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		data, _ := io.ReadAll(tr)
+		if hdr.Name == "vector/index" {
+			data = []byte("VECTORS")
+		}
+		tw.WriteHeader(&tar.Header{Name: hdr.Name, Mode: 0644, Size: int64(len(data))})
+		tw.Write(data)
+	}
+	tw.Close()
+	zw.Close()
+	if _, err := VerifyBundle(bytes.NewReader(tampered.Bytes())); !errors.Is(err, ErrBundleChecksum) {
+		t.Errorf("tampered bundle: err = %v, want ErrBundleChecksum", err)
+	}
+	if _, _, err := ExtractBundle(bytes.NewReader(tampered.Bytes()), t.TempDir()); !errors.Is(err, ErrBundleChecksum) {
+		t.Errorf("extracting a tampered bundle: err = %v, want ErrBundleChecksum", err)
+	}
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"regexp"
+
+	"github.com/blevesearch/bleve/v2/analysis"
+	regexpfilter "github.com/blevesearch/bleve/v2/analysis/char/regexp"
+	"github.com/blevesearch/bleve/v2/analysis/token/camelcase"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/registry"
+)
+
+// CodeAnalyzer splits identifiers into words, so getUserByID, get_user_by_id
+// and user.ID are indexed as "get user by id" and match natural-language
+// queries. Code chunks are indexed with it into the "text_code" field next
+// to the regular "text" field, which keeps whole identifiers searchable.
+const CodeAnalyzer = "semango_code"
+
+// Values of lexical.code_token_filter.
+const (
+	CodeTokenFilterSplit = "split_identifiers"
+	CodeTokenFilterNone  = "none"
+)
+
+const codeField = "text_code"
+
+// identifierSeparators are the characters that join words inside an
+// identifier but that the Unicode tokenizer does not break on.
+var identifierSeparators = regexp.MustCompile(`[_.]`)
+
+func init() {
+	// Registered globally rather than in the index mapping, so indexes
+	// created before the analyzer existed can still be queried with it.
+	registry.RegisterAnalyzer(CodeAnalyzer, newCodeAnalyzer)
+}
+
+func newCodeAnalyzer(config map[string]interface{}, cache *registry.Cache) (analysis.Analyzer, error) {
+	tokenizer, err := cache.TokenizerNamed(unicode.Name)
+	if err != nil {
+		return nil, err
+	}
+	camel, err := cache.TokenFilterNamed(camelcase.Name)
+	if err != nil {
+		return nil, err
+	}
+	lower, err := cache.TokenFilterNamed(lowercase.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &analysis.DefaultAnalyzer{
+		CharFilters:  []analysis.CharFilter{regexpfilter.New(identifierSeparators, []byte(" "))},
+		Tokenizer:    tokenizer,
+		TokenFilters: []analysis.TokenFilter{camel, lower},
+	}, nil
+}
+
+// isCodeChunk reports whether a chunk holds source code: files from the
+// code loader and notebook code cells.
+func isCodeChunk(meta map[string]string) bool {
+	return meta["source"] == "CodeLoader" || meta["cell_type"] == "code"
+}
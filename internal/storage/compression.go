@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+// Values of vector.compression, how vector indexes store vectors.
+const (
+	CompressionNone    = "none"
+	CompressionFloat16 = "float16"
+	CompressionInt8    = "int8"
+	CompressionPQ      = "pq"
+)
+
+// Scalar and product quantization learn their ranges and codebooks from the
+// vectors of an index, so a new FAISS index configured for them stores
+// float32 vectors until it holds this many and is converted on the next
+// write. The product quantizer's 256 centroids per byte want some 39
+// vectors each.
+const (
+	int8TrainingVectors = 1000
+	pqTrainingVectors   = 39 * 256
+	// maxTrainingVectors bounds the sample a quantizer is trained on.
+	maxTrainingVectors = 100000
+)
+
+// normalizeCompression returns compression with "" read as CompressionNone.
+func normalizeCompression(compression string) string {
+	if compression == "" {
+		return CompressionNone
+	}
+	return compression
+}
+
+// trainingVectors returns how many vectors compression needs to be trained
+// on, 0 if it needs no training.
+func trainingVectors(compression string) int {
+	switch compression {
+	case CompressionInt8:
+		return int8TrainingVectors
+	case CompressionPQ:
+		return pqTrainingVectors
+	}
+	return 0
+}
+
+// PQSubquantizers returns the bytes per vector of product quantization for
+// vectors of dimension dim: m if it is set, else the largest divisor of dim
+// no greater than dim/8. An m that does not divide dim is an error.
+func PQSubquantizers(dim, m int) (int, error) {
+	if m > 0 {
+		if dim%m != 0 {
+			return 0, fmt.Errorf("vector.pq_subquantizers %d does not divide the dimension %d", m, dim)
+		}
+		return m, nil
+	}
+	for m = dim / 8; m > 1; m-- {
+		if dim%m == 0 {
+			return m, nil
+		}
+	}
+	return 1, nil
+}
+
+// BytesPerVector returns the size of the code of a vector of dimension dim
+// with compression, not counting its label; m is vector.pq_subquantizers.
+func BytesPerVector(compression string, dim, m int) int {
+	switch compression {
+	case CompressionFloat16:
+		return 2 * dim
+	case CompressionInt8:
+		return dim
+	case CompressionPQ:
+		m, err := PQSubquantizers(dim, m)
+		if err != nil {
+			return 0
+		}
+		return m
+	}
+	return 4 * dim
+}
+
+// CompressionTradeoff describes, for semango stats, what compression saves
+// in size and costs in accuracy.
+func CompressionTradeoff(compression string) string {
+	switch compression {
+	case CompressionNone:
+		return "exact scores"
+	case CompressionFloat16:
+		return "half the size of float32; scores within about 0.1%, rankings practically unchanged"
+	case CompressionInt8:
+		return "a quarter of the size of float32; scores within about 1%, close results may swap places"
+	case CompressionPQ:
+		return "the smallest; approximate scores that lose some recall, which a reranker recovers"
+	}
+	return "unknown storage"
+}
+
+// ReadVectorIndexCompression returns the compression of the vector index
+// at path: the encoding of a flat index, or the storage of a FAISS index
+// read from its header. FAISS index types Semango does not write give "".
+func ReadVectorIndexCompression(path string) (string, error) {
+	if err := CheckVectorIndex(path); err != nil {
+		return "", err
+	}
+	if isFlatIndexFile(path) {
+		return readFlatCompression(path)
+	}
+	return readFaissCompression(path)
+}
+
+// QuantizeVectorIndex converts the vector index at path to vec.Compression
+// now rather than on its next write, and returns the compression it had.
+// Int8 and pq need enough vectors to train on; converting to a larger
+// encoding does not restore the precision lost.
+func QuantizeVectorIndex(ctx context.Context, path string, vec config.VectorConfig) (from string, err error) {
+	if vec.Compression == "" {
+		return "", fmt.Errorf("no compression given")
+	}
+	_, dim, err := ReadFaissIndexInfo(path)
+	if err != nil {
+		return "", err
+	}
+	if from, err = ReadVectorIndexCompression(path); err != nil {
+		return "", err
+	}
+	idx, err := openFaissVectors(ctx, path, dim, vec)
+	if err != nil {
+		return from, err
+	}
+	q, ok := idx.(interface{ quantize(context.Context) error })
+	if !ok {
+		idx.Close()
+		return from, fmt.Errorf("vector index %s cannot be converted", path)
+	}
+	err = q.quantize(ctx)
+	if cerr := idx.Close(); err == nil {
+		err = cerr
+	}
+	return from, err
+}
+
+// readFaissCompression reads the compression of the FAISS index at path
+// from its header. An index written by Semango is an IDMap2 ("IxM2") whose
+// header of 33 bytes is followed by that of the index holding the vectors:
+// "IxFI" for a flat one, "IxSQ" for a scalar quantizer, whose type follows
+// its own header, or "IxPq" for a product quantizer.
+func readFaissCompression(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	const header = 4 + 33
+	buf := make([]byte, 2*header+4)
+	if _, err := io.ReadFull(f, buf); err != nil || string(buf[:4]) != "IxM2" {
+		return "", nil
+	}
+	switch string(buf[header : header+4]) {
+	case "IxFI", "IxF2", "IxFl":
+		return CompressionNone, nil
+	case "IxPq":
+		return CompressionPQ, nil
+	case "IxSQ":
+		// FAISS's QT_8bit and QT_fp16.
+		switch binary.LittleEndian.Uint32(buf[2*header:]) {
+		case 0:
+			return CompressionInt8, nil
+		case 4:
+			return CompressionFloat16, nil
+		}
+	}
+	return "", nil
+}
+
+// float16Bits returns the IEEE 754 half-precision encoding of f, rounded
+// to the nearest value, ties to even.
+func float16Bits(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int32(b>>23&0xff) - 127 + 15
+	mant := b & 0x7fffff
+	switch {
+	case b&0x7fffffff > 0x7f800000: // NaN
+		return sign | 0x7e00
+	case exp >= 0x1f: // too large, or infinite
+		return sign | 0x7c00
+	case exp <= 0: // subnormal or zero
+		if exp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint32(14 - exp)
+		half := mant >> shift
+		rem, mid := mant&(1<<shift-1), uint32(1)<<(shift-1)
+		if rem > mid || rem == mid && half&1 == 1 {
+			half++
+		}
+		return sign | uint16(half)
+	}
+	// A carry out of the mantissa correctly rounds up to the next exponent,
+	// or to infinity.
+	half := uint32(exp)<<10 | mant>>13
+	if rem := mant & 0x1fff; rem > 0x1000 || rem == 0x1000 && half&1 == 1 {
+		half++
+	}
+	return sign | uint16(half)
+}
+
+// float16Value returns the value of the half-precision encoding h.
+func float16Value(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+	switch exp {
+	case 0:
+		v := float32(mant) / (1 << 24)
+		if sign != 0 {
+			v = -v
+		}
+		return v
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+var (
+	float16Once   sync.Once
+	float16Values *[1 << 16]float32
+)
+
+// float16Table returns the values of all half-precision encodings, which
+// are faster to look up than to compute.
+func float16Table() *[1 << 16]float32 {
+	float16Once.Do(func() {
+		float16Values = new([1 << 16]float32)
+		for h := range float16Values {
+			float16Values[h] = float16Value(uint16(h))
+		}
+	})
+	return float16Values
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPQSubquantizers(t *testing.T) {
+	for _, c := range []struct{ dim, m, want int }{{384, 0, 48}, {3072, 0, 384}, {100, 0, 10}, {384, 96, 96}, {4, 0, 1}} {
+		if got, err := PQSubquantizers(c.dim, c.m); got != c.want || err != nil {
+			t.Errorf("PQSubquantizers(%d, %d) = %d, %v; want %d", c.dim, c.m, got, err, c.want)
+		}
+	}
+	if _, err := PQSubquantizers(384, 100); err == nil {
+		t.Error("a subquantizer count that does not divide the dimension was accepted")
+	}
+	if got := BytesPerVector(CompressionInt8, 384, 0); got != 384 {
+		t.Errorf("BytesPerVector(int8) = %d", got)
+	}
+}
+
+func TestReadFaissCompression(t *testing.T) {
+	// The headers of an IDMap2 and of the index it wraps.
+	header := func(fourcc string) []byte {
+		return append([]byte(fourcc), make([]byte, 33)...)
+	}
+	sq := func(qtype uint32) []byte {
+		b := append(header("IxM2"), header("IxSQ")...)
+		return binary.LittleEndian.AppendUint32(b, qtype)
+	}
+	for want, data := range map[string][]byte{
+		CompressionNone:    append(append(header("IxM2"), header("IxFI")...), 0, 0, 0, 0),
+		CompressionPQ:      append(append(header("IxM2"), header("IxPq")...), 0, 0, 0, 0),
+		CompressionInt8:    sq(0),
+		CompressionFloat16: sq(4),
+		"":                 sq(1),
+	} {
+		path := filepath.Join(t.TempDir(), "faiss.index")
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := ReadVectorIndexCompression(path); got != want || err != nil {
+			t.Errorf("ReadVectorIndexCompression = %q, %v; want %q", got, err, want)
+		}
+	}
+}
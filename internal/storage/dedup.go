@@ -0,0 +1,425 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/omarkamali/semango/internal/ingest"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	dedupCanonicalBucket = []byte("canonical") // canonical chunk ID -> dedupRecord
+	dedupHashesBucket    = []byte("hashes")    // fingerprint hash -> canonical chunk ID
+	dedupBandsBucket     = []byte("bands")     // SimHash band -> canonical chunk IDs
+	dedupAliasesBucket   = []byte("aliases")   // duplicate chunk ID -> canonical chunk ID
+	dedupPathsBucket     = []byte("paths")     // path -> its duplicate chunk IDs
+)
+
+const dedupFile = "dedup.db"
+
+// MaxDedupDistance is the largest SimHash distance, in bits, at which
+// chunks are near duplicates. Candidates are found by splitting SimHashes
+// into MaxDedupDistance+1 bands: chunks that differ in fewer bits than
+// there are bands share at least one band.
+const MaxDedupDistance = 3
+
+// DedupPath returns the deduplication store of the indexes whose lexical
+// index lives at lexicalIndexPath.
+func DedupPath(lexicalIndexPath string) string {
+	return filepath.Join(filepath.Dir(filepath.Clean(lexicalIndexPath)), dedupFile)
+}
+
+// DedupChunk is a chunk offered to DedupStore.Add.
+type DedupChunk struct {
+	ID          string
+	Fingerprint ingest.Fingerprint
+}
+
+// Alias is a duplicate of a canonical chunk that was not indexed.
+type Alias struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
+}
+
+type dedupRecord struct {
+	Path    string  `json:"path"`
+	Hash    string  `json:"hash"`
+	SimHash uint64  `json:"simhash"`
+	Near    bool    `json:"near,omitempty"`
+	Aliases []Alias `json:"aliases,omitempty"`
+}
+
+// DedupStore records which indexed chunks are canonical copies of text and
+// which chunks were left out of the indexes as their duplicates. The
+// database is locked while open; see BoltIDMap.
+type DedupStore struct {
+	db *bolt.DB
+}
+
+// OpenDedupStore opens the deduplication store at path, creating it unless
+// readOnly is set.
+func OpenDedupStore(path string, readOnly bool) (*DedupStore, error) {
+	if readOnly {
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+	}
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: 5 * time.Second, ReadOnly: readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("open dedup store %s: %w", path, err)
+	}
+	if readOnly {
+		return &DedupStore{db: db}, nil
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{dedupCanonicalBucket, dedupHashesBucket, dedupBandsBucket, dedupAliasesBucket, dedupPathsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%w: %s: %v; run semango index --recreate to rebuild it", ErrCorruptIndex, path, err)
+	}
+	return &DedupStore{db: db}, nil
+}
+
+func (s *DedupStore) Close() error {
+	return s.db.Close()
+}
+
+// Add deduplicates the chunks of the file at path, which replace its
+// previous version, in one transaction. A chunk is a duplicate of a
+// canonical chunk with the same fingerprint hash or, when maxDistance is
+// above 0 and both are long enough, a SimHash at most maxDistance bits
+// away; other chunks become canonical. Add returns the duplicates, mapped
+// to their canonical chunk, and the paths whose duplicates lost their
+// canonical chunk because it changed: they must be indexed again.
+func (s *DedupStore) Add(path string, chunks []DedupChunk, maxDistance int) (dups map[string]string, orphaned []string, err error) {
+	dups = map[string]string{}
+	orphans := map[string]bool{}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		if err := dropAliases(tx, path); err != nil {
+			return err
+		}
+		canon := tx.Bucket(dedupCanonicalBucket)
+		var dupIDs []string
+		for _, c := range chunks {
+			fp := c.Fingerprint
+			if rec, ok := getRecord(canon, c.ID); ok {
+				if rec.Hash == fp.Hash {
+					continue
+				}
+				if err := dropCanonical(tx, c.ID, rec, orphans); err != nil {
+					return err
+				}
+			}
+			if id := string(tx.Bucket(dedupHashesBucket).Get([]byte(fp.Hash))); id != "" && id != c.ID {
+				dups[c.ID] = id
+			} else if id := nearest(tx, fp, maxDistance); id != "" {
+				dups[c.ID] = id
+			}
+			if id, ok := dups[c.ID]; ok {
+				if err := addAlias(tx, id, Alias{ID: c.ID, Path: path}); err != nil {
+					return err
+				}
+				dupIDs = append(dupIDs, c.ID)
+				continue
+			}
+			if err := addCanonical(tx, c.ID, dedupRecord{Path: path, Hash: fp.Hash, SimHash: fp.SimHash, Near: fp.Near}); err != nil {
+				return err
+			}
+		}
+		if len(dupIDs) == 0 {
+			return nil
+		}
+		return putJSON(tx.Bucket(dedupPathsBucket), path, dupIDs)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	delete(orphans, path)
+	return dups, sortedKeys(orphans), nil
+}
+
+// RemovePath forgets the file at path: its duplicates and ids, the chunks
+// of it that were indexed. It returns the paths whose duplicates lost their
+// canonical chunk, as Add does.
+func (s *DedupStore) RemovePath(path string, ids []string) ([]string, error) {
+	return s.forget(path, ids, true)
+}
+
+// RemoveChunks forgets ids, chunks of the file at path that its new
+// version, already added, no longer has. It returns the paths whose
+// duplicates lost their canonical chunk, as Add does.
+func (s *DedupStore) RemoveChunks(path string, ids []string) ([]string, error) {
+	return s.forget(path, ids, false)
+}
+
+// forget drops the canonical chunks ids of path and, with aliases, the
+// duplicates of path.
+func (s *DedupStore) forget(path string, ids []string, aliases bool) ([]string, error) {
+	orphans := map[string]bool{}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if aliases {
+			if err := dropAliases(tx, path); err != nil {
+				return err
+			}
+		}
+		canon := tx.Bucket(dedupCanonicalBucket)
+		for _, id := range ids {
+			if rec, ok := getRecord(canon, id); ok {
+				if err := dropCanonical(tx, id, rec, orphans); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	delete(orphans, path)
+	return sortedKeys(orphans), nil
+}
+
+// Aliases returns the paths of the duplicates of each of the canonical
+// chunks ids, other than the chunk's own path, sorted. Chunks without
+// duplicates are left out.
+func (s *DedupStore) Aliases(ids []string) map[string][]string {
+	out := map[string][]string{}
+	_ = s.db.View(func(tx *bolt.Tx) error {
+		canon := tx.Bucket(dedupCanonicalBucket)
+		if canon == nil {
+			return nil
+		}
+		for _, id := range ids {
+			rec, ok := getRecord(canon, id)
+			if !ok {
+				continue
+			}
+			seen := map[string]bool{rec.Path: true}
+			var paths []string
+			for _, a := range rec.Aliases {
+				if !seen[a.Path] {
+					seen[a.Path] = true
+					paths = append(paths, a.Path)
+				}
+			}
+			if len(paths) > 0 {
+				sort.Strings(paths)
+				out[id] = paths
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// dropAliases removes the duplicates recorded for path from their
+// canonical chunks.
+func dropAliases(tx *bolt.Tx, path string) error {
+	pb, ab, canon := tx.Bucket(dedupPathsBucket), tx.Bucket(dedupAliasesBucket), tx.Bucket(dedupCanonicalBucket)
+	var ids []string
+	if err := getJSON(pb, path, &ids); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		c := string(ab.Get([]byte(id)))
+		if err := ab.Delete([]byte(id)); err != nil {
+			return err
+		}
+		rec, ok := getRecord(canon, c)
+		if !ok {
+			continue
+		}
+		kept := rec.Aliases[:0]
+		for _, a := range rec.Aliases {
+			if a.ID != id {
+				kept = append(kept, a)
+			}
+		}
+		rec.Aliases = kept
+		if err := putJSON(canon, c, rec); err != nil {
+			return err
+		}
+	}
+	return pb.Delete([]byte(path))
+}
+
+// dropCanonical removes the canonical chunk id and its duplicates, adding
+// the paths of the duplicates to orphans.
+func dropCanonical(tx *bolt.Tx, id string, rec dedupRecord, orphans map[string]bool) error {
+	if string(tx.Bucket(dedupHashesBucket).Get([]byte(rec.Hash))) == id {
+		if err := tx.Bucket(dedupHashesBucket).Delete([]byte(rec.Hash)); err != nil {
+			return err
+		}
+	}
+	if rec.Near {
+		bands := tx.Bucket(dedupBandsBucket)
+		for _, key := range bandKeys(rec.SimHash) {
+			var ids []string
+			if err := getJSON(bands, string(key), &ids); err != nil {
+				return err
+			}
+			ids = without(ids, id)
+			var err error
+			if len(ids) == 0 {
+				err = bands.Delete(key)
+			} else {
+				err = putJSON(bands, string(key), ids)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	pb, ab := tx.Bucket(dedupPathsBucket), tx.Bucket(dedupAliasesBucket)
+	for _, a := range rec.Aliases {
+		orphans[a.Path] = true
+		if err := ab.Delete([]byte(a.ID)); err != nil {
+			return err
+		}
+		var ids []string
+		if err := getJSON(pb, a.Path, &ids); err != nil {
+			return err
+		}
+		var err error
+		if ids = without(ids, a.ID); len(ids) == 0 {
+			err = pb.Delete([]byte(a.Path))
+		} else {
+			err = putJSON(pb, a.Path, ids)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Bucket(dedupCanonicalBucket).Delete([]byte(id))
+}
+
+func addCanonical(tx *bolt.Tx, id string, rec dedupRecord) error {
+	if err := putJSON(tx.Bucket(dedupCanonicalBucket), id, rec); err != nil {
+		return err
+	}
+	if err := tx.Bucket(dedupHashesBucket).Put([]byte(rec.Hash), []byte(id)); err != nil {
+		return err
+	}
+	if !rec.Near {
+		return nil
+	}
+	bands := tx.Bucket(dedupBandsBucket)
+	for _, key := range bandKeys(rec.SimHash) {
+		var ids []string
+		if err := getJSON(bands, string(key), &ids); err != nil {
+			return err
+		}
+		if err := putJSON(bands, string(key), append(ids, id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addAlias(tx *bolt.Tx, canonicalID string, a Alias) error {
+	canon := tx.Bucket(dedupCanonicalBucket)
+	rec, _ := getRecord(canon, canonicalID)
+	rec.Aliases = append(rec.Aliases, a)
+	if err := putJSON(canon, canonicalID, rec); err != nil {
+		return err
+	}
+	return tx.Bucket(dedupAliasesBucket).Put([]byte(a.ID), []byte(canonicalID))
+}
+
+// nearest returns the canonical chunk whose SimHash is closest to fp's and
+// at most maxDistance bits away, or "" if there is none.
+func nearest(tx *bolt.Tx, fp ingest.Fingerprint, maxDistance int) string {
+	if !fp.Near || maxDistance <= 0 {
+		return ""
+	}
+	if maxDistance > MaxDedupDistance {
+		maxDistance = MaxDedupDistance
+	}
+	bands, canon := tx.Bucket(dedupBandsBucket), tx.Bucket(dedupCanonicalBucket)
+	best, bestDist := "", maxDistance+1
+	for _, key := range bandKeys(fp.SimHash) {
+		var ids []string
+		_ = getJSON(bands, string(key), &ids)
+		for _, id := range ids {
+			rec, ok := getRecord(canon, id)
+			if !ok {
+				continue
+			}
+			d := bits.OnesCount64(rec.SimHash ^ fp.SimHash)
+			if d < bestDist || (d == bestDist && id < best) {
+				best, bestDist = id, d
+			}
+		}
+	}
+	return best
+}
+
+// bandKeys splits sim into MaxDedupDistance+1 16-bit bands, each keyed by
+// its position and value.
+func bandKeys(sim uint64) [][]byte {
+	keys := make([][]byte, MaxDedupDistance+1)
+	for i := range keys {
+		key := make([]byte, 3)
+		key[0] = byte(i)
+		binary.BigEndian.PutUint16(key[1:], uint16(sim>>(16*i)))
+		keys[i] = key
+	}
+	return keys
+}
+
+func getRecord(b *bolt.Bucket, id string) (dedupRecord, bool) {
+	var rec dedupRecord
+	v := b.Get([]byte(id))
+	if v == nil || json.Unmarshal(v, &rec) != nil {
+		return rec, false
+	}
+	return rec, true
+}
+
+func getJSON(b *bolt.Bucket, key string, v any) error {
+	data := b.Get([]byte(key))
+	if data == nil {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+func putJSON(b *bolt.Bucket, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(key), data)
+}
+
+func without(list []string, s string) []string {
+	out := list[:0]
+	for _, x := range list {
+		if x != s {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
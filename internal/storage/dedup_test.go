@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/ingest"
+)
+
+func TestDedupStore(t *testing.T) {
+	s, err := OpenDedupStore(filepath.Join(t.TempDir(), "dedup.db"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	chunk := func(id, text string) DedupChunk {
+		return DedupChunk{ID: id, Fingerprint: ingest.FingerprintText(text)}
+	}
+	long := strings.Repeat("the quick brown fox jumps over the lazy dog and runs ", 4)
+
+	dups, orphaned, err := s.Add("README.md", []DedupChunk{chunk("r0", "Install with make."), chunk("r1", long)}, 3)
+	if err != nil || len(dups) != 0 || len(orphaned) != 0 {
+		t.Fatalf("first Add = %v, %v, %v", dups, orphaned, err)
+	}
+	// An exact copy, reflowed, and a near copy with one word changed.
+	dups, _, err = s.Add("vendor/README.md", []DedupChunk{
+		chunk("v0", "install   with\nMAKE."),
+		chunk("v1", strings.Replace(long, "lazy", "sleepy", 1)),
+		chunk("v2", "Something else."),
+	}, 3)
+	want := map[string]string{"v0": "r0", "v1": "r1"}
+	if err != nil || !reflect.DeepEqual(dups, want) {
+		t.Fatalf("Add = %v, %v, want %v", dups, err, want)
+	}
+	if got := s.Aliases([]string{"r0", "r1", "v2"}); !reflect.DeepEqual(got, map[string][]string{"r0": {"vendor/README.md"}, "r1": {"vendor/README.md"}}) {
+		t.Errorf("Aliases = %v", got)
+	}
+
+	// Without near matching only the exact copy is a duplicate.
+	dups, _, _ = s.Add("vendor/README.md", []DedupChunk{chunk("v0", "Install with make."), chunk("v1", strings.Replace(long, "lazy", "sleepy", 1))}, 0)
+	if !reflect.DeepEqual(dups, map[string]string{"v0": "r0"}) {
+		t.Errorf("exact-only Add = %v", dups)
+	}
+
+	// Changing the canonical chunk orphans its duplicates.
+	_, orphaned, err = s.Add("README.md", []DedupChunk{chunk("r0", "Install with go build.")}, 3)
+	if err != nil || !reflect.DeepEqual(orphaned, []string{"vendor/README.md"}) {
+		t.Fatalf("changed canonical: orphaned = %v, %v", orphaned, err)
+	}
+	dups, _, _ = s.Add("vendor/README.md", []DedupChunk{chunk("v0", "Install with make.")}, 3)
+	if len(dups) != 0 {
+		t.Errorf("re-indexed orphan is still a duplicate: %v", dups)
+	}
+
+	// Removing a file orphans the duplicates of its chunks.
+	if _, _, err := s.Add("docs/install.md", []DedupChunk{chunk("d0", "Install with make.")}, 3); err != nil {
+		t.Fatal(err)
+	}
+	orphaned, err = s.RemovePath("vendor/README.md", []string{"v0"})
+	if err != nil || !reflect.DeepEqual(orphaned, []string{"docs/install.md"}) {
+		t.Errorf("RemovePath = %v, %v", orphaned, err)
+	}
+	if got := s.Aliases([]string{"v0", "r1"}); len(got) != 0 {
+		t.Errorf("Aliases after RemovePath = %v", got)
+	}
+}
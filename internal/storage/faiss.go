@@ -12,6 +12,10 @@ import (
 	"github.com/omarkamali/semango/internal/util"
 )
 
+// FaissSupported reports whether this binary was built with FAISS, which
+// needs CGO on linux/amd64. Without it vectors are kept in a FlatIndex.
+const FaissSupported = true
+
 // FaissIndex represents a FAISS vector index.
 // It wraps the blevesearch/go-faiss Index interface and provides
 // additional methods for Semango-specific functionality.
@@ -19,18 +23,50 @@ type FaissIndex struct {
 	index faiss.Index
 	dim   int    // Dimension of the vectors
 	path  string // Path to the index file on disk
+	// compression is how the index stores vectors, "" for index types
+	// Semango does not write.
+	compression string
 }
 
 // NewFaissIndex creates or loads a FAISS index.
-// If an index file exists at the given path, it's loaded.
+// If an index file exists at the given path, it's loaded; a file that fails
+// to load is reported with ErrCorruptIndex rather than replaced.
 // Otherwise, a new index is created with the specified dimension and metric.
 // The metric argument should be one of the faiss.Metric... constants (e.g., faiss.MetricL2, faiss.MetricInnerProduct).
 func NewFaissIndex(ctx context.Context, path string, dim int, metric int) (*FaissIndex, error) {
+	return newFaissIndex(ctx, path, dim, metric, CompressionNone, 0)
+}
+
+// faissDescription returns the index factory description of an index
+// storing vectors of dimension dim with compression; m is
+// vector.pq_subquantizers.
+func faissDescription(compression string, dim, m int) (string, error) {
+	switch compression {
+	case CompressionFloat16:
+		return "IDMap2,SQfp16", nil
+	case CompressionInt8:
+		return "IDMap2,SQ8", nil
+	case CompressionPQ:
+		m, err := PQSubquantizers(dim, m)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("IDMap2,PQ%d", m), nil
+	}
+	return "IDMap2,Flat", nil
+}
+
+// newFaissIndex is NewFaissIndex creating an index that stores vectors
+// with compression, which must need no training.
+func newFaissIndex(ctx context.Context, path string, dim int, metric int, compression string, m int) (*FaissIndex, error) {
 	logger := util.FromContext(ctx)
 
 	// Check if index file exists
 	if _, err := os.Stat(path); err == nil {
 		// File exists, try to load it
+		if isFlatIndexFile(path) {
+			return nil, fmt.Errorf("%w: %s was written by a build without FAISS; run semango index --recreate to rebuild it with FAISS", ErrCorruptIndex, path)
+		}
 		logger.Info("FAISS index file found, attempting to load.", "path", path)
 		// The spec requires IO_FLAG_MMAP
 		idx, loadErr := faiss.ReadIndex(path, faiss.IOFlagMmap)
@@ -40,19 +76,23 @@ func NewFaissIndex(ctx context.Context, path string, dim int, metric int) (*Fais
 			if idx.D() != dim {
 				logger.Error("Loaded FAISS index dimension mismatch.", "path", path, "expected_dim", dim, "actual_dim", idx.D())
 				idx.Close() // Free resources if not usable
-				return nil, fmt.Errorf("loaded FAISS index dimension mismatch: expected %d, got %d", dim, idx.D())
+				return nil, dimensionMismatch("vector index "+path, idx.D(), dim)
 			}
 			// We can't easily get the metric type from the loaded index via go-faiss to compare with `metric` param.
 			// We'll assume the user provides the correct metric for existing indexes or relies on the stored one.
 			logger.Info("Successfully loaded FAISS index from disk.", "path", path, "dimension", idx.D(), "total_vectors", idx.Ntotal())
+			compression, _ := readFaissCompression(path)
 			return &FaissIndex{
-				index: idx,
-				dim:   idx.D(), // Use dimension from loaded index
-				path:  path,
+				index:       idx,
+				dim:         idx.D(), // Use dimension from loaded index
+				path:        path,
+				compression: compression,
 			}, nil
 		}
-		logger.Warn("Found FAISS index file, but failed to load. Will attempt to create a new one.", "path", path, "error", loadErr)
-		// If loading fails, proceed to create a new one (could be due to corruption or incompatibility)
+		// Replacing the file would hide the corruption and make searches
+		// quietly return nothing, so report it instead.
+		logger.Error("FAISS index file exists but cannot be loaded", "path", path, "error", loadErr)
+		return nil, fmt.Errorf("%w: %s: %v; run semango index --recreate to rebuild it", ErrCorruptIndex, path, loadErr)
 	} else if !os.IsNotExist(err) {
 		// Some other error occurred with os.Stat (e.g., permission issue)
 		logger.Error("Error checking for FAISS index file", "path", path, "error", err)
@@ -65,10 +105,14 @@ func NewFaissIndex(ctx context.Context, path string, dim int, metric int) (*Fais
 	var idx faiss.Index
 	var err error
 
-	// Create a Flat index wrapped with an IDMap so that AddWithIDs is supported.
+	// Create an index wrapped with an IDMap so that AddWithIDs is supported.
 	// Using the factory helper allows us to compose this configuration in one call.
-	idxImpl, err := faiss.IndexFactory(dim, "IDMap2,Flat", metric)
+	description, err := faissDescription(compression, dim, m)
 	if err != nil {
+		return nil, err
+	}
+	idxImpl, err := faiss.IndexFactory(dim, description, metric)
+	if err != nil && compression == CompressionNone {
 		// Fallback: try the legacy description without the trailing '2' in case the
 		// underlying Faiss version expects just "IDMap".
 		idxImpl, err = faiss.IndexFactory(dim, "IDMap,Flat", metric)
@@ -80,14 +124,87 @@ func NewFaissIndex(ctx context.Context, path string, dim int, metric int) (*Fais
 		return nil, fmt.Errorf("faiss.IndexFactory: %w", err)
 	}
 
-	logger.Info("Successfully created new FAISS index", "path", path, "dimension", dim, "metric_code", metric)
+	logger.Info("Successfully created new FAISS index", "path", path, "dimension", dim, "metric_code", metric, "compression", compression)
 	return &FaissIndex{
-		index: idx,
-		dim:   dim,
-		path:  path,
+		index:       idx,
+		dim:         dim,
+		path:        path,
+		compression: compression,
 	}, nil
 }
 
+// convert replaces the index by one storing the vectors of labels with
+// compression, training its quantizer on a sample of up to
+// maxTrainingVectors of them. Labels without a vector are skipped. Vectors
+// keep the precision they were stored with.
+func (fi *FaissIndex) convert(ctx context.Context, compression string, m int, labels []int64) error {
+	description, err := faissDescription(compression, fi.dim, m)
+	if err != nil {
+		return err
+	}
+	if need := trainingVectors(compression); len(labels) < need {
+		return fmt.Errorf("%s compression is trained on at least %d vectors; the index holds %d", compression, need, len(labels))
+	}
+	idx, err := faiss.IndexFactory(fi.dim, description, fi.index.MetricType())
+	if err != nil {
+		return fmt.Errorf("faiss.IndexFactory: %w", err)
+	}
+	if !idx.IsTrained() {
+		step := len(labels)/maxTrainingVectors + 1
+		sample := make([]int64, 0, len(labels)/step+1)
+		for i := 0; i < len(labels); i += step {
+			sample = append(sample, labels[i])
+		}
+		vectors, kept := fi.reconstruct(sample)
+		if len(kept) == 0 {
+			idx.Close()
+			return fmt.Errorf("no vectors to train %s compression on", compression)
+		}
+		if err := idx.Train(vectors); err != nil {
+			idx.Close()
+			return fmt.Errorf("training %s compression: %w", compression, err)
+		}
+	}
+	const batch = 4096
+	for start := 0; start < len(labels); start += batch {
+		if err := ctx.Err(); err != nil {
+			idx.Close()
+			return err
+		}
+		end := start + batch
+		if end > len(labels) {
+			end = len(labels)
+		}
+		vectors, kept := fi.reconstruct(labels[start:end])
+		if len(kept) == 0 {
+			continue
+		}
+		if err := idx.AddWithIDs(vectors, kept); err != nil {
+			idx.Close()
+			return fmt.Errorf("FaissIndex.convert: %w", err)
+		}
+	}
+	fi.index.Close()
+	fi.index, fi.compression = idx, compression
+	return nil
+}
+
+// reconstruct returns the vectors of labels, one after the other, and the
+// labels that have one.
+func (fi *FaissIndex) reconstruct(labels []int64) ([]float32, []int64) {
+	vectors := make([]float32, 0, len(labels)*fi.dim)
+	kept := make([]int64, 0, len(labels))
+	for _, l := range labels {
+		v, err := fi.index.Reconstruct(l)
+		if err != nil {
+			continue
+		}
+		vectors = append(vectors, v...)
+		kept = append(kept, l)
+	}
+	return vectors, kept
+}
+
 // Add vectors to the index.
 func (fi *FaissIndex) Add(ctx context.Context, vectors [][]float32, ids []int64) error {
 	logger := util.FromContext(ctx)
@@ -123,6 +240,29 @@ func (fi *FaissIndex) Add(ctx context.Context, vectors [][]float32, ids []int64)
 	return nil
 }
 
+// Remove deletes the vectors with the given IDs and returns how many were
+// removed.
+func (fi *FaissIndex) Remove(ctx context.Context, ids []int64) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	sel, err := faiss.NewIDSelectorBatch(ids)
+	if err != nil {
+		return 0, fmt.Errorf("faiss.NewIDSelectorBatch: %w", err)
+	}
+	defer sel.Delete()
+	batch, ok := sel.(*faiss.IDSelector)
+	if !ok {
+		return 0, fmt.Errorf("unexpected FAISS selector type %T", sel)
+	}
+	n, err := fi.index.RemoveIDs(batch)
+	if err != nil {
+		return 0, fmt.Errorf("FaissIndex.Remove: %w", err)
+	}
+	util.FromContext(ctx).Debug("Removed vectors from FAISS index", "requested", len(ids), "removed", n)
+	return n, nil
+}
+
 // Search for k-nearest neighbors.
 // Returns distances, labels (IDs), and an error if any.
 func (fi *FaissIndex) Search(ctx context.Context, queryVector []float32, k int) ([]float32, []int64, error) {
@@ -130,6 +270,10 @@ func (fi *FaissIndex) Search(ctx context.Context, queryVector []float32, k int)
 	if len(queryVector) != fi.dim {
 		return nil, nil, fmt.Errorf("query vector dimension mismatch: expected %d, got %d", fi.dim, len(queryVector))
 	}
+	// FAISS cannot be interrupted, so a canceled search stops here.
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 
 	distances, labels, err := fi.index.Search(queryVector, int64(k))
 	if err != nil {
@@ -140,6 +284,29 @@ func (fi *FaissIndex) Search(ctx context.Context, queryVector []float32, k int)
 	return distances, labels, nil
 }
 
+// SearchWithIDs is like Search but only considers vectors whose IDs are in
+// include, using a FAISS IDSelector during the search.
+func (fi *FaissIndex) SearchWithIDs(ctx context.Context, queryVector []float32, k int, include []int64) ([]float32, []int64, error) {
+	logger := util.FromContext(ctx)
+	if len(queryVector) != fi.dim {
+		return nil, nil, fmt.Errorf("query vector dimension mismatch: expected %d, got %d", fi.dim, len(queryVector))
+	}
+	if len(include) == 0 || k <= 0 {
+		return nil, nil, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	distances, labels, err := fi.index.SearchWithIDs(queryVector, int64(k), include, nil)
+	if err != nil {
+		logger.Error("Failed to search FAISS index with ID selector", "error", err, "k", k, "allowed", len(include))
+		return nil, nil, fmt.Errorf("FaissIndex.SearchWithIDs: %w", err)
+	}
+	logger.Debug("Successfully searched FAISS index with ID selector", "k", k, "allowed", len(include), "results_count", len(labels))
+	return distances, labels, nil
+}
+
 // Save the index to disk.
 func (fi *FaissIndex) Save(ctx context.Context) error {
 	logger := util.FromContext(ctx)
@@ -169,6 +336,22 @@ func (fi *FaissIndex) Ntotal(ctx context.Context) int64 {
 	return fi.index.Ntotal()
 }
 
+// ReadFaissIndexInfo returns the number of vectors in the FAISS index at
+// path and their dimension, without knowing the dimension beforehand. A
+// missing index is reported with ErrNoVectorIndex, one that cannot be
+// loaded with ErrCorruptIndex.
+func ReadFaissIndexInfo(path string) (vectors int64, dim int, err error) {
+	if err := CheckVectorIndex(path); err != nil {
+		return 0, 0, err
+	}
+	idx, err := faiss.ReadIndex(path, faiss.IOFlagMmap)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %s: %v; run semango index --recreate to rebuild it", ErrCorruptIndex, path, err)
+	}
+	defer idx.Close()
+	return idx.Ntotal(), idx.D(), nil
+}
+
 // Dim returns the dimension of the vectors in the index.
 func (fi *FaissIndex) Dim() int {
 	return fi.dim
@@ -1,32 +1,46 @@
 //go:build !cgo || !linux || !amd64
-// +build !cgo linux,!amd64 !linux
+// +build !cgo !linux !amd64
 
 package storage
 
 import (
-    "context"
-    "errors"
+	"context"
+	"fmt"
+
+	"github.com/omarkamali/semango/internal/config"
 )
 
-var errFaissUnavailable = errors.New("faiss support requires CGO on linux/amd64")
+// FaissSupported reports whether this binary was built with FAISS, which
+// needs CGO on linux/amd64. Without it vectors are kept in a FlatIndex.
+const FaissSupported = false
+
+var errFaissUnavailable = fmt.Errorf("%w: faiss support requires CGO on linux/amd64", ErrNoVectorIndex)
 
 // FaissIndex is a stub used when CGO or the required platform is unavailable.
 type FaissIndex struct{}
 
 func NewFaissIndex(_ context.Context, _ string, _ int, _ int) (*FaissIndex, error) {
-    return nil, errFaissUnavailable
+	return nil, errFaissUnavailable
 }
 
 func (fi *FaissIndex) Add(_ context.Context, _ [][]float32, _ []int64) error {
-    return errFaissUnavailable
+	return errFaissUnavailable
+}
+
+func (fi *FaissIndex) Remove(_ context.Context, _ []int64) (int, error) {
+	return 0, errFaissUnavailable
 }
 
 func (fi *FaissIndex) Search(_ context.Context, _ []float32, _ int) ([]float32, []int64, error) {
-    return nil, nil, errFaissUnavailable
+	return nil, nil, errFaissUnavailable
+}
+
+func (fi *FaissIndex) SearchWithIDs(_ context.Context, _ []float32, _ int, _ []int64) ([]float32, []int64, error) {
+	return nil, nil, errFaissUnavailable
 }
 
 func (fi *FaissIndex) Save(_ context.Context) error {
-    return errFaissUnavailable
+	return errFaissUnavailable
 }
 
 func (fi *FaissIndex) Close(_ context.Context) {}
@@ -35,21 +49,50 @@ func (fi *FaissIndex) Ntotal(_ context.Context) int64 { return 0 }
 
 func (fi *FaissIndex) Dim() int { return 0 }
 
+// ReadFaissIndexInfo reads the header of the FlatIndex that replaces FAISS
+// in this build.
+func ReadFaissIndexInfo(path string) (int64, int, error) { return ReadFlatIndexInfo(path) }
+
 // FaissVectorIndex is a stub used when CGO or the required platform is unavailable.
 type FaissVectorIndex struct{}
 
 func NewFaissVectorIndex(_ context.Context, _ string, _ int, _ int) (*FaissVectorIndex, error) {
-    return nil, errFaissUnavailable
+	return nil, errFaissUnavailable
+}
+
+// openFaissVectors opens a FlatVectorIndex instead, so that vector search
+// works without FAISS.
+func openFaissVectors(ctx context.Context, path string, dim int, vec config.VectorConfig) (VectorIndex, error) {
+	idx, err := NewFlatVectorIndex(ctx, path, dim, vec.Compression)
+	if err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+func NewFaissVectorIndexWithIDMap(_ context.Context, _ string, _ int, _ int, ids IDMap) (*FaissVectorIndex, error) {
+	ids.Close()
+	return nil, errFaissUnavailable
 }
 
 func (f *FaissVectorIndex) Upsert(_ context.Context, _ string, _ []float32) error {
-    return errFaissUnavailable
+	return errFaissUnavailable
 }
 
 func (f *FaissVectorIndex) Search(_ context.Context, _ []float32, _ int) ([]VectorResult, error) {
-    return nil, errFaissUnavailable
+	return nil, errFaissUnavailable
+}
+
+func (f *FaissVectorIndex) SearchAllowed(_ context.Context, _ []float32, _ int, _ []string) ([]VectorResult, error) {
+	return nil, errFaissUnavailable
+}
+
+func (f *FaissVectorIndex) Delete(_ context.Context, _ []string) error {
+	return errFaissUnavailable
 }
 
+func (f *FaissVectorIndex) DeferSave() {}
+
 func (f *FaissVectorIndex) Dimension() int { return 0 }
 
 func (f *FaissVectorIndex) Close() error { return errFaissUnavailable }
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -108,3 +109,31 @@ func TestFaissIntegrationWithOpenAI(t *testing.T) {
 		t.Errorf("expected nearest neighbor ID 1, got %d", labels[0])
 	}
 }
+
+func TestFaissIndex_CorruptFileIsReported(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "faiss.index")
+	if err := os.WriteFile(path, []byte("not a faiss index"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFaissIndex(ctx, path, 4, faiss.MetricInnerProduct); !errors.Is(err, ErrCorruptIndex) {
+		t.Fatalf("NewFaissIndex on a corrupt file: got %v, want ErrCorruptIndex", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "not a faiss index" {
+		t.Error("the corrupt file must not be replaced")
+	}
+
+	moved, err := RecreateVectorIndex(path)
+	if err != nil || moved == "" {
+		t.Fatalf("RecreateVectorIndex = %q, %v", moved, err)
+	}
+	if _, err := os.Stat(moved); err != nil {
+		t.Errorf("corrupt index not kept at %s: %v", moved, err)
+	}
+	idx, err := NewFaissIndex(ctx, path, 4, faiss.MetricInnerProduct)
+	if err != nil {
+		t.Fatalf("NewFaissIndex after recreating: %v", err)
+	}
+	idx.Close(ctx)
+}
@@ -5,91 +5,124 @@ package storage
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"hash/fnv"
 	"os"
 	"path/filepath"
-)
 
-// FaissVectorIndex adapts FaissIndex to the VectorIndex interface.
-// It hashes string IDs to int64 labels deterministically using FNV-1a.
-// The mapping does not guarantee collision-freeness but is sufficient for
-// small/medium corpora and demo purposes. For production use, a persistent
-// hashmap (e.g. BoltDB) is advised.
+	"github.com/blevesearch/go-faiss"
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/util"
+)
 
+// FaissVectorIndex adapts FaissIndex to the VectorIndex interface. FAISS
+// stores int64 labels; an IDMap translates them to and from chunk IDs.
 type FaissVectorIndex struct {
 	fi        *FaissIndex
-	indexPath string
-	hashBuf   [8]byte // scratch buffer for hashing
-	idToLabel map[string]int64
-	labelToID map[int64]string
-	nextLabel int64
+	path      string
+	ids       IDMap
+	deferSave bool
+	// compression and pqM are vector.compression and
+	// vector.pq_subquantizers, which writes convert the index to.
+	compression string
+	pqM         int
 }
 
 // NewFaissVectorIndex opens or creates the FAISS index at the given path with
-// the provided dimension and metric.
+// the provided dimension and metric, with its ID map in a JSON file next to
+// it (see JSONIDMapPath).
 func NewFaissVectorIndex(ctx context.Context, indexPath string, dim int, metric int) (*FaissVectorIndex, error) {
-	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(indexPath), 0o755); err != nil {
+	ids, err := OpenJSONIDMap(JSONIDMapPath(indexPath))
+	if err != nil {
 		return nil, err
 	}
+	return NewFaissVectorIndexWithIDMap(ctx, indexPath, dim, metric, ids)
+}
 
-	fi, err := NewFaissIndex(ctx, indexPath, dim, metric)
+// openFaissVectors opens the FAISS index at path for OpenVectorIndex,
+// scoring by inner product. Writes convert it to vec.Compression.
+func openFaissVectors(ctx context.Context, path string, dim int, vec config.VectorConfig) (VectorIndex, error) {
+	ids, err := OpenJSONIDMap(JSONIDMapPath(path))
 	if err != nil {
 		return nil, err
 	}
-
-	fvi := &FaissVectorIndex{
-		fi:        fi,
-		indexPath: indexPath,
-		idToLabel: map[string]int64{},
-		labelToID: map[int64]string{},
-		nextLabel: 1,
+	idx, err := newFaissVectorIndex(ctx, path, dim, faiss.MetricInnerProduct, ids, vec)
+	if err != nil {
+		return nil, err
 	}
+	return idx, nil
+}
+
+// NewFaissVectorIndexWithIDMap is NewFaissVectorIndex with the given ID
+// map, which the index closes with itself. An index holding vectors with
+// an empty ID map is reported with ErrCorruptIndex, since its labels
+// cannot be turned back into chunk IDs.
+func NewFaissVectorIndexWithIDMap(ctx context.Context, indexPath string, dim int, metric int, ids IDMap) (*FaissVectorIndex, error) {
+	return newFaissVectorIndex(ctx, indexPath, dim, metric, ids, config.VectorConfig{})
+}
 
-	// Load mapping file if exists
-	mapPath := indexPath + ".ids.json"
-	if data, err := os.ReadFile(mapPath); err == nil {
-		_ = json.Unmarshal(data, &fvi.idToLabel)
-		for k, v := range fvi.idToLabel {
-			fvi.labelToID[v] = k
-			if v >= fvi.nextLabel {
-				fvi.nextLabel = v + 1
-			}
+// newFaissVectorIndex is NewFaissVectorIndexWithIDMap for the compression
+// settings of vec. A new index stores vectors with vec.Compression from the
+// start unless its quantizer needs training.
+func newFaissVectorIndex(ctx context.Context, indexPath string, dim int, metric int, ids IDMap, vec config.VectorConfig) (*FaissVectorIndex, error) {
+	// Ensure directory exists
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0o755); err != nil {
+		ids.Close()
+		return nil, err
+	}
+	initial := CompressionNone
+	if vec.Compression != "" && trainingVectors(vec.Compression) == 0 {
+		initial = vec.Compression
+	}
+	if vec.Compression == CompressionPQ {
+		if _, err := PQSubquantizers(dim, vec.PQSubquantizers); err != nil {
+			ids.Close()
+			return nil, err
 		}
 	}
+	fi, err := newFaissIndex(ctx, indexPath, dim, metric, initial, vec.PQSubquantizers)
+	if err != nil {
+		ids.Close()
+		return nil, err
+	}
 
-	return fvi, nil
-}
-
-func (f *FaissVectorIndex) hashID(id string) int64 {
-	h := fnv.New64a()
-	_, _ = h.Write([]byte(id))
-	sum := h.Sum64()
-	// Interpret as signed int64
-	return int64(sum)
+	vectors := fi.Ntotal(ctx)
+	if vectors > 0 && ids.Len() == 0 {
+		fi.Close(ctx)
+		ids.Close()
+		return nil, fmt.Errorf("%w: %s holds %d vectors but its ID map is missing or empty; run semango index --recreate to rebuild it",
+			ErrCorruptIndex, indexPath, vectors)
+	}
+	if vectors != int64(ids.Len()) {
+		// Indexes written before upserts replaced vectors hold duplicates;
+		// they still search correctly.
+		util.FromContext(ctx).Warn("FAISS index and its ID map disagree; re-index to clean it up",
+			"path", indexPath, "vectors", vectors, "ids", ids.Len())
+	}
+	return &FaissVectorIndex{fi: fi, path: indexPath, ids: ids, compression: vec.Compression, pqM: vec.PQSubquantizers}, nil
 }
 
 // Upsert inserts or replaces a vector for the given ID.
 func (f *FaissVectorIndex) Upsert(ctx context.Context, id string, vector []float32) error {
-	label, ok := f.idToLabel[id]
-	if !ok {
-		label = f.nextLabel
-		f.nextLabel++
-		f.idToLabel[id] = label
-		f.labelToID[label] = id
+	label, ok := f.ids.Label(id)
+	if ok {
+		// FAISS IDMap appends on AddWithIDs, so drop the previous vector
+		// first to keep re-ingested chunks from being duplicated.
+		if _, err := f.fi.Remove(ctx, []int64{label}); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if label, err = f.ids.Add(id); err != nil {
+			return err
+		}
 	}
 	vectors := [][]float32{vector}
 	ids := []int64{label}
 
-	// For simplicity, we attempt Add; FAISS IDMap will overwrite existing IDs.
 	if err := f.fi.Add(ctx, vectors, ids); err != nil {
 		return err
 	}
-	// Persist to disk after each upsert for now; could batch in future.
-	f.persistMap()
-	return f.fi.Save(ctx)
+	return f.save(ctx)
 }
 
 func (f *FaissVectorIndex) Search(ctx context.Context, query []float32, topK int) ([]VectorResult, error) {
@@ -97,15 +130,147 @@ func (f *FaissVectorIndex) Search(ctx context.Context, query []float32, topK int
 	if err != nil {
 		return nil, err
 	}
-	results := make([]VectorResult, len(labels))
+	return f.results(ctx, distances, labels), nil
+}
+
+// SearchAllowed restricts the search to the labels of the allowed IDs. IDs
+// that were never indexed are ignored.
+func (f *FaissVectorIndex) SearchAllowed(ctx context.Context, query []float32, topK int, allowed []string) ([]VectorResult, error) {
+	include := make([]int64, 0, len(allowed))
+	for _, id := range allowed {
+		if label, ok := f.ids.Label(id); ok {
+			include = append(include, label)
+		}
+	}
+	if topK > len(include) {
+		topK = len(include)
+	}
+	distances, labels, err := f.fi.SearchWithIDs(ctx, query, topK, include)
+	if err != nil {
+		return nil, err
+	}
+	return f.results(ctx, distances, labels), nil
+}
+
+// results turns FAISS hits into results by chunk ID. The -1 labels that
+// pad a result shorter than topK are dropped, and so are orphaned labels
+// without an ID, which are noted for removal by the next write.
+func (f *FaissVectorIndex) results(ctx context.Context, distances []float32, labels []int64) []VectorResult {
+	results := make([]VectorResult, 0, len(labels))
+	var orphaned []int64
 	for i, l := range labels {
-		id, ok := f.labelToID[l]
+		if l < 0 {
+			continue
+		}
+		id, ok := f.ids.ID(l)
 		if !ok {
-			id = fmt.Sprintf("%d", l)
+			orphaned = append(orphaned, l)
+			continue
 		}
-		results[i] = VectorResult{ID: id, Score: distances[i]}
+		results = append(results, VectorResult{ID: id, Score: distances[i]})
 	}
-	return results, nil
+	if len(orphaned) > 0 {
+		util.FromContext(ctx).Warn("Vector search hit vectors without a chunk ID; they will be removed by the next index write",
+			"path", f.path, "orphans", len(orphaned))
+		noteOrphans(f.path, orphaned)
+	}
+	return results
+}
+
+// Delete removes the vectors of the given IDs and forgets their labels.
+func (f *FaissVectorIndex) Delete(ctx context.Context, ids []string) error {
+	labels := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if label, ok := f.ids.Label(id); ok {
+			labels = append(labels, label)
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	if _, err := f.fi.Remove(ctx, labels); err != nil {
+		return err
+	}
+	if _, err := f.ids.Delete(ids); err != nil {
+		return err
+	}
+	return f.save(ctx)
+}
+
+// DeferSave makes Upsert and Delete keep their changes in memory until
+// Close instead of writing the index and its ID map after every call, for
+// bulk indexing. Changes are lost if the process dies before Close.
+func (f *FaissVectorIndex) DeferSave() {
+	f.deferSave = true
+}
+
+// save persists the index and its ID map unless saving is deferred.
+func (f *FaissVectorIndex) save(ctx context.Context) error {
+	if f.deferSave {
+		return nil
+	}
+	if err := f.removeOrphans(ctx); err != nil {
+		return err
+	}
+	if err := f.compress(ctx, false); err != nil {
+		return err
+	}
+	if err := f.ids.Save(); err != nil {
+		return err
+	}
+	return f.fi.Save(ctx)
+}
+
+// compress converts the index to the configured compression if it stores
+// vectors otherwise: once it holds enough vectors to train a quantizer on,
+// or at once if force is set, when too few vectors are an error. Indexes of
+// types Semango does not write are left alone.
+func (f *FaissVectorIndex) compress(ctx context.Context, force bool) error {
+	from, to := f.fi.compression, f.compression
+	if force && from == "" {
+		return fmt.Errorf("%s is a FAISS index of a type Semango does not write; rebuild it with semango index --recreate", f.path)
+	}
+	if to == "" || from == "" || from == to {
+		return nil
+	}
+	labels := f.ids.Labels()
+	if !force && len(labels) < trainingVectors(to) {
+		return nil
+	}
+	if err := f.fi.convert(ctx, to, f.pqM, labels); err != nil {
+		return fmt.Errorf("converting %s to %s compression: %w", f.path, to, err)
+	}
+	util.FromContext(ctx).Info("Converted vector index", "path", f.path, "from", from, "to", to, "vectors", len(labels))
+	return nil
+}
+
+// quantize converts the index to the configured compression now and saves
+// it.
+func (f *FaissVectorIndex) quantize(ctx context.Context) error {
+	if err := f.compress(ctx, true); err != nil {
+		return err
+	}
+	return f.fi.Save(ctx)
+}
+
+// removeOrphans removes the orphaned vectors searches noted for this
+// index, except labels that have since been given an ID.
+func (f *FaissVectorIndex) removeOrphans(ctx context.Context) error {
+	var labels []int64
+	for _, l := range takeOrphans(f.path) {
+		if _, ok := f.ids.ID(l); !ok {
+			labels = append(labels, l)
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	n, err := f.fi.Remove(ctx, labels)
+	if err != nil {
+		return err
+	}
+	util.FromContext(ctx).Info("Removed orphaned vectors", "path", f.path, "removed", n)
+	return nil
 }
 
 func (f *FaissVectorIndex) Dimension() int {
@@ -113,15 +278,13 @@ func (f *FaissVectorIndex) Dimension() int {
 }
 
 func (f *FaissVectorIndex) Close() error {
+	ctx := context.Background()
+	if err := f.compress(ctx, false); err != nil {
+		util.FromContext(ctx).Warn("Vector index left uncompressed", "path", f.path, "err", err)
+	}
 	// Save index before closing to persist vectors.
-	_ = f.fi.Save(context.Background())
-	f.persistMap()
+	_ = f.fi.Save(ctx)
+	err := f.ids.Close()
 	f.fi.Close(context.Background())
-	return nil
-}
-
-func (f *FaissVectorIndex) persistMap() {
-	mapPath := f.indexPath + ".ids.json"
-	data, _ := json.MarshalIndent(f.idToLabel, "", "  ")
-	_ = os.WriteFile(mapPath, data, 0o644)
+	return err
 }
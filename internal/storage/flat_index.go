@@ -0,0 +1,703 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// flatMagic starts every file written by FlatIndex.
+//
+// The file format, all integers little-endian:
+//
+//	magic    8 bytes  "SMGFLAT1"
+//	dim      uint32   dimension of the vectors
+//	count    uint64   number of vectors
+//	encoding uint32   0 float32, 1 float16, 2 int8
+//	labels   count × int64
+//	vectors  count rows in the order of the labels, each dim × float32,
+//	         dim × float16 (IEEE 754 half precision), or for int8 a float32
+//	         scale followed by dim × int8, the vector being the scale times
+//	         the integers
+//
+// Nothing follows the vectors. Labels are translated to chunk IDs by the
+// JSON ID map next to the file, as for FAISS indexes.
+var flatMagic = []byte("SMGFLAT1")
+
+// flatHeaderSize is the size of the magic, dimension, count and encoding.
+const flatHeaderSize = 8 + 4 + 8 + 4
+
+// flatEncoding is how a FlatIndex stores vectors.
+type flatEncoding uint32
+
+const (
+	flatFloat32 flatEncoding = iota
+	flatFloat16
+	flatInt8
+)
+
+// flatEncodingOf returns the encoding of a vector.compression value. Product
+// quantization needs FAISS; the flat index stores int8 vectors instead.
+func flatEncodingOf(compression string) flatEncoding {
+	switch compression {
+	case CompressionFloat16:
+		return flatFloat16
+	case CompressionInt8, CompressionPQ:
+		return flatInt8
+	}
+	return flatFloat32
+}
+
+// compression returns the vector.compression value of the encoding.
+func (e flatEncoding) compression() string {
+	switch e {
+	case flatFloat16:
+		return CompressionFloat16
+	case flatInt8:
+		return CompressionInt8
+	}
+	return CompressionNone
+}
+
+// rowSize returns the bytes a vector of dimension dim takes.
+func (e flatEncoding) rowSize(dim int) int {
+	switch e {
+	case flatFloat16:
+		return 2 * dim
+	case flatInt8:
+		return 4 + dim
+	}
+	return 4 * dim
+}
+
+// flatData is the content of a FlatIndex: the vectors of all labels in one
+// slice, row by row, which keeps the scan over them cache-friendly. Only
+// the slices of its encoding are used.
+type flatData struct {
+	dim      int
+	encoding flatEncoding
+	labels   []int64
+	f32      []float32     // float32: len(labels) × dim
+	f16      []uint16      // float16: len(labels) × dim
+	i8       []int8        // int8: len(labels) × dim
+	scales   []float32     // int8: one per row
+	rows     map[int64]int // label → row
+}
+
+// FlatIndex is a vector index in pure Go that scores a query against every
+// vector by inner product. It is exact for float32 vectors, needs no CGO and
+// is the vector index of builds without FAISS. Searches take time
+// proportional to the number of vectors, some 25 ms per hundred thousand
+// vectors of dimension 384 on one core of a current CPU.
+type FlatIndex struct {
+	path string
+	*flatData
+	shared bool // flatData is cached for other readers; copy before writing
+	dirty  bool
+}
+
+// flatCache holds the indexes read from disk, which searches open anew for
+// every query, until their file changes.
+var flatCache = struct {
+	sync.Mutex
+	m map[string]flatCacheEntry
+}{m: map[string]flatCacheEntry{}}
+
+type flatCacheEntry struct {
+	modTime time.Time
+	size    int64
+	data    *flatData
+}
+
+// OpenFlatIndex opens the flat index at path, or starts an empty one of
+// dimension dim, storing float32 vectors, if the file does not exist. A file
+// of another dimension is an error, and one that cannot be read, e.g. a
+// FAISS index, is reported with ErrCorruptIndex.
+func OpenFlatIndex(path string, dim int) (*FlatIndex, error) {
+	fi, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &FlatIndex{path: path, flatData: &flatData{dim: dim, rows: map[int64]int{}}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	flatCache.Lock()
+	entry, ok := flatCache.m[path]
+	flatCache.Unlock()
+	if !ok || !entry.modTime.Equal(fi.ModTime()) || entry.size != fi.Size() {
+		data, err := readFlatFile(path)
+		if err != nil {
+			return nil, err
+		}
+		entry = flatCacheEntry{modTime: fi.ModTime(), size: fi.Size(), data: data}
+		flatCache.Lock()
+		flatCache.m[path] = entry
+		flatCache.Unlock()
+	}
+	if entry.data.dim != dim {
+		return nil, dimensionMismatch("vector index "+path, entry.data.dim, dim)
+	}
+	return &FlatIndex{path: path, flatData: entry.data, shared: true}, nil
+}
+
+// readFlatHeader reads the dimension, vector count and encoding at the
+// start of a flat index file.
+func readFlatHeader(r io.Reader, path string) (dim int, count int64, enc flatEncoding, err error) {
+	var header [flatHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil || !bytes.Equal(header[:8], flatMagic) {
+		return 0, 0, 0, fmt.Errorf("%w: %s is not a vector index of this build (was it written by FAISS?); run semango index --recreate to rebuild it",
+			ErrCorruptIndex, path)
+	}
+	dim = int(binary.LittleEndian.Uint32(header[8:12]))
+	count = int64(binary.LittleEndian.Uint64(header[12:20]))
+	enc = flatEncoding(binary.LittleEndian.Uint32(header[20:24]))
+	if dim <= 0 || count < 0 || enc > flatInt8 {
+		return 0, 0, 0, fmt.Errorf("%w: %s has a bad header; run semango index --recreate to rebuild it", ErrCorruptIndex, path)
+	}
+	return dim, count, enc, nil
+}
+
+func readFlatFile(path string) (*flatData, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReaderSize(f, 1<<20)
+	dim, count, enc, err := readFlatHeader(r, path)
+	if err != nil {
+		return nil, err
+	}
+	if want := flatHeaderSize + count*8 + count*int64(enc.rowSize(dim)); fi.Size() != want {
+		return nil, fmt.Errorf("%w: %s holds %d bytes, its header announces %d; run semango index --recreate to rebuild it",
+			ErrCorruptIndex, path, fi.Size(), want)
+	}
+	d := &flatData{
+		dim:      dim,
+		encoding: enc,
+		labels:   make([]int64, count),
+		rows:     make(map[int64]int, count),
+	}
+	if err := binary.Read(r, binary.LittleEndian, d.labels); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrCorruptIndex, path, err)
+	}
+	if err := d.readVectors(r); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrCorruptIndex, path, err)
+	}
+	for i, l := range d.labels {
+		d.rows[l] = i
+	}
+	return d, nil
+}
+
+// readVectors reads the rows of d's labels in its encoding.
+func (d *flatData) readVectors(r io.Reader) error {
+	n := len(d.labels) * d.dim
+	switch d.encoding {
+	case flatFloat16:
+		d.f16 = make([]uint16, n)
+		return binary.Read(r, binary.LittleEndian, d.f16)
+	case flatInt8:
+		d.scales = make([]float32, len(d.labels))
+		d.i8 = make([]int8, n)
+		var scale [4]byte
+		for row := range d.labels {
+			if _, err := io.ReadFull(r, scale[:]); err != nil {
+				return err
+			}
+			d.scales[row] = math.Float32frombits(binary.LittleEndian.Uint32(scale[:]))
+			if err := binary.Read(r, binary.LittleEndian, d.i8[row*d.dim:(row+1)*d.dim]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	d.f32 = make([]float32, n)
+	return binary.Read(r, binary.LittleEndian, d.f32)
+}
+
+// writeVectors writes the rows of d in its encoding.
+func (d *flatData) writeVectors(w io.Writer) error {
+	switch d.encoding {
+	case flatFloat16:
+		return binary.Write(w, binary.LittleEndian, d.f16)
+	case flatInt8:
+		var scale [4]byte
+		for row, s := range d.scales {
+			binary.LittleEndian.PutUint32(scale[:], math.Float32bits(s))
+			if _, err := w.Write(scale[:]); err != nil {
+				return err
+			}
+			if err := binary.Write(w, binary.LittleEndian, d.i8[row*d.dim:(row+1)*d.dim]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return binary.Write(w, binary.LittleEndian, d.f32)
+}
+
+// isFlatIndexFile reports whether the file at path starts like a flat
+// index.
+func isFlatIndexFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	magic := make([]byte, len(flatMagic))
+	_, err = io.ReadFull(f, magic)
+	return err == nil && bytes.Equal(magic, flatMagic)
+}
+
+// ReadFlatIndexInfo returns the number of vectors in the flat index at path
+// and their dimension. A missing index is reported with ErrNoVectorIndex,
+// one that cannot be read with ErrCorruptIndex.
+func ReadFlatIndexInfo(path string) (vectors int64, dim int, err error) {
+	if err := CheckVectorIndex(path); err != nil {
+		return 0, 0, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	dim, count, _, err := readFlatHeader(f, path)
+	return count, dim, err
+}
+
+// readFlatCompression returns the vector.compression value of the flat
+// index at path.
+func readFlatCompression(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	_, _, enc, err := readFlatHeader(f, path)
+	return enc.compression(), err
+}
+
+// own makes the index's data its own before it is changed.
+func (x *FlatIndex) own() {
+	x.dirty = true
+	if !x.shared {
+		return
+	}
+	d := &flatData{
+		dim:      x.dim,
+		encoding: x.encoding,
+		labels:   append([]int64(nil), x.labels...),
+		f32:      append([]float32(nil), x.f32...),
+		f16:      append([]uint16(nil), x.f16...),
+		i8:       append([]int8(nil), x.i8...),
+		scales:   append([]float32(nil), x.scales...),
+		rows:     make(map[int64]int, len(x.rows)),
+	}
+	for l, i := range x.rows {
+		d.rows[l] = i
+	}
+	x.flatData, x.shared = d, false
+}
+
+// Add inserts vectors under labels, replacing the vectors of labels already
+// in the index.
+func (x *FlatIndex) Add(vectors [][]float32, labels []int64) error {
+	if len(vectors) != len(labels) {
+		return fmt.Errorf("%d vectors for %d labels", len(vectors), len(labels))
+	}
+	for _, v := range vectors {
+		if len(v) != x.dim {
+			return fmt.Errorf("vector dimension mismatch: expected %d, got %d", x.dim, len(v))
+		}
+	}
+	x.own()
+	for i, v := range vectors {
+		row, ok := x.rows[labels[i]]
+		if !ok {
+			row = len(x.labels)
+			x.rows[labels[i]] = row
+			x.labels = append(x.labels, labels[i])
+			x.grow()
+		}
+		x.encode(row, v)
+	}
+	return nil
+}
+
+// grow adds a row for the last label.
+func (d *flatData) grow() {
+	n := len(d.labels) * d.dim
+	switch d.encoding {
+	case flatFloat16:
+		d.f16 = append(d.f16, make([]uint16, n-len(d.f16))...)
+	case flatInt8:
+		d.i8 = append(d.i8, make([]int8, n-len(d.i8))...)
+		d.scales = append(d.scales, 0)
+	default:
+		d.f32 = append(d.f32, make([]float32, n-len(d.f32))...)
+	}
+}
+
+// encode stores v in row. An int8 row holds v divided by a scale that maps
+// its largest component to ±127, rounded.
+func (d *flatData) encode(row int, v []float32) {
+	switch d.encoding {
+	case flatFloat16:
+		codes := d.f16[row*d.dim : (row+1)*d.dim]
+		for i, x := range v {
+			codes[i] = float16Bits(x)
+		}
+	case flatInt8:
+		var peak float32
+		for _, x := range v {
+			if x < 0 {
+				x = -x
+			}
+			if x > peak {
+				peak = x
+			}
+		}
+		codes := d.i8[row*d.dim : (row+1)*d.dim]
+		if peak == 0 {
+			d.scales[row] = 0
+			for i := range codes {
+				codes[i] = 0
+			}
+			return
+		}
+		scale := peak / 127
+		d.scales[row] = scale
+		for i, x := range v {
+			codes[i] = int8(math.Round(float64(x / scale)))
+		}
+	default:
+		copy(d.f32[row*d.dim:], v)
+	}
+}
+
+// decode returns the vector stored in row.
+func (d *flatData) decode(row int) []float32 {
+	v := make([]float32, d.dim)
+	switch d.encoding {
+	case flatFloat16:
+		for i, h := range d.f16[row*d.dim : (row+1)*d.dim] {
+			v[i] = float16Value(h)
+		}
+	case flatInt8:
+		for i, c := range d.i8[row*d.dim : (row+1)*d.dim] {
+			v[i] = float32(c) * d.scales[row]
+		}
+	default:
+		copy(v, d.f32[row*d.dim:])
+	}
+	return v
+}
+
+// move copies row src over row dst.
+func (d *flatData) move(dst, src int) {
+	switch d.encoding {
+	case flatFloat16:
+		copy(d.f16[dst*d.dim:(dst+1)*d.dim], d.f16[src*d.dim:])
+	case flatInt8:
+		copy(d.i8[dst*d.dim:(dst+1)*d.dim], d.i8[src*d.dim:])
+		d.scales[dst] = d.scales[src]
+	default:
+		copy(d.f32[dst*d.dim:(dst+1)*d.dim], d.f32[src*d.dim:])
+	}
+}
+
+// truncate keeps the first n rows.
+func (d *flatData) truncate(n int) {
+	d.labels = d.labels[:n]
+	switch d.encoding {
+	case flatFloat16:
+		d.f16 = d.f16[:n*d.dim]
+	case flatInt8:
+		d.i8 = d.i8[:n*d.dim]
+		d.scales = d.scales[:n]
+	default:
+		d.f32 = d.f32[:n*d.dim]
+	}
+}
+
+// score returns the inner product of query with the vector in row.
+func (d *flatData) score(query []float32, row int) float32 {
+	switch d.encoding {
+	case flatFloat16:
+		return dotFloat16(query, d.f16[row*d.dim:(row+1)*d.dim])
+	case flatInt8:
+		return dotInt8(query, d.i8[row*d.dim:(row+1)*d.dim]) * d.scales[row]
+	}
+	return dot(query, d.f32[row*d.dim:(row+1)*d.dim])
+}
+
+// Convert re-encodes the vectors of the index for compression, a
+// vector.compression value, and reports whether anything changed. Vectors
+// lose the precision of the encoding they were stored in.
+func (x *FlatIndex) Convert(compression string) bool {
+	enc := flatEncodingOf(compression)
+	if enc == x.encoding {
+		return false
+	}
+	next := &flatData{dim: x.dim, encoding: enc, rows: x.rows}
+	for row, l := range x.labels {
+		next.labels = append(next.labels, l)
+		next.grow()
+		next.encode(row, x.decode(row))
+	}
+	if x.shared {
+		next.rows = make(map[int64]int, len(x.rows))
+		for l, i := range x.rows {
+			next.rows[l] = i
+		}
+	}
+	x.flatData, x.shared, x.dirty = next, false, true
+	return true
+}
+
+// Compression returns the vector.compression value of the index's
+// encoding.
+func (x *FlatIndex) Compression() string {
+	return x.encoding.compression()
+}
+
+// Remove deletes the vectors of labels and returns how many there were.
+// The last vector takes the place of each removed one.
+func (x *FlatIndex) Remove(labels []int64) int {
+	n := 0
+	for _, l := range labels {
+		row, ok := x.rows[l]
+		if !ok {
+			continue
+		}
+		if n == 0 {
+			x.own()
+		}
+		last := len(x.labels) - 1
+		if row != last {
+			x.labels[row] = x.labels[last]
+			x.rows[x.labels[row]] = row
+			x.move(row, last)
+		}
+		x.truncate(last)
+		delete(x.rows, l)
+		n++
+	}
+	return n
+}
+
+// Search returns the labels of the k vectors with the largest inner
+// product with query, best first, and their scores. When include is not
+// nil, only its labels are considered.
+func (x *FlatIndex) Search(query []float32, k int, include []int64) ([]float32, []int64, error) {
+	if len(query) != x.dim {
+		return nil, nil, fmt.Errorf("query vector dimension mismatch: expected %d, got %d", x.dim, len(query))
+	}
+	if k <= 0 {
+		return nil, nil, nil
+	}
+	top := topK{k: k}
+	if include != nil {
+		for _, l := range include {
+			if row, ok := x.rows[l]; ok {
+				top.push(x.score(query, row), l)
+			}
+		}
+	} else if x.encoding == flatFloat32 {
+		// The common case, without a switch per vector.
+		for row, l := range x.labels {
+			top.push(dot(query, x.f32[row*x.dim:(row+1)*x.dim]), l)
+		}
+	} else {
+		for row, l := range x.labels {
+			top.push(x.score(query, row), l)
+		}
+	}
+	return top.sorted()
+}
+
+// dot returns the inner product of a and b, which have the same length. The
+// loop is unrolled so that the compiler keeps eight independent sums and
+// drops the bounds checks.
+func dot(a, b []float32) float32 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3, s4, s5, s6, s7 float32
+	for len(a) >= 8 {
+		x, y := a[:8:8], b[:8:8]
+		s0 += x[0] * y[0]
+		s1 += x[1] * y[1]
+		s2 += x[2] * y[2]
+		s3 += x[3] * y[3]
+		s4 += x[4] * y[4]
+		s5 += x[5] * y[5]
+		s6 += x[6] * y[6]
+		s7 += x[7] * y[7]
+		a, b = a[8:], b[8:]
+	}
+	for i := range a {
+		s0 += a[i] * b[i]
+	}
+	return (s0 + s1) + (s2 + s3) + (s4 + s5) + (s6 + s7)
+}
+
+// dotFloat16 is dot for float16 b.
+func dotFloat16(a []float32, b []uint16) float32 {
+	b = b[:len(a)]
+	table := float16Table()
+	var s0, s1, s2, s3 float32
+	for len(a) >= 4 {
+		x, y := a[:4:4], b[:4:4]
+		s0 += x[0] * table[y[0]]
+		s1 += x[1] * table[y[1]]
+		s2 += x[2] * table[y[2]]
+		s3 += x[3] * table[y[3]]
+		a, b = a[4:], b[4:]
+	}
+	for i := range a {
+		s0 += a[i] * table[b[i]]
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+// dotInt8 is dot for int8 b, before scaling.
+func dotInt8(a []float32, b []int8) float32 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3 float32
+	for len(a) >= 4 {
+		x, y := a[:4:4], b[:4:4]
+		s0 += x[0] * float32(y[0])
+		s1 += x[1] * float32(y[1])
+		s2 += x[2] * float32(y[2])
+		s3 += x[3] * float32(y[3])
+		a, b = a[4:], b[4:]
+	}
+	for i := range a {
+		s0 += a[i] * float32(b[i])
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+// topK keeps the k best scores seen in a min-heap.
+type topK struct {
+	k      int
+	scores []float32
+	labels []int64
+}
+
+func (t *topK) push(score float32, label int64) {
+	if len(t.scores) < t.k {
+		t.scores = append(t.scores, score)
+		t.labels = append(t.labels, label)
+		for i := len(t.scores) - 1; i > 0; {
+			parent := (i - 1) / 2
+			if t.scores[parent] <= t.scores[i] {
+				break
+			}
+			t.swap(i, parent)
+			i = parent
+		}
+		return
+	}
+	if score <= t.scores[0] {
+		return
+	}
+	t.scores[0], t.labels[0] = score, label
+	t.down(0, len(t.scores))
+}
+
+func (t *topK) swap(i, j int) {
+	t.scores[i], t.scores[j] = t.scores[j], t.scores[i]
+	t.labels[i], t.labels[j] = t.labels[j], t.labels[i]
+}
+
+// down restores the heap below i among the first n entries.
+func (t *topK) down(i, n int) {
+	for {
+		least := i
+		for _, c := range []int{2*i + 1, 2*i + 2} {
+			if c < n && t.scores[c] < t.scores[least] {
+				least = c
+			}
+		}
+		if least == i {
+			return
+		}
+		t.swap(i, least)
+		i = least
+	}
+}
+
+// sorted empties the heap into slices ordered by decreasing score.
+func (t *topK) sorted() ([]float32, []int64, error) {
+	for n := len(t.scores) - 1; n > 0; n-- {
+		t.swap(0, n)
+		t.down(0, n)
+	}
+	return t.scores, t.labels, nil
+}
+
+// Save writes the index to a temporary file and renames it over the old
+// one, if it changed since it was opened or last saved.
+func (x *FlatIndex) Save() error {
+	if !x.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(x.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(x.path), filepath.Base(x.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriterSize(tmp, 1<<20)
+	var header [flatHeaderSize]byte
+	copy(header[:], flatMagic)
+	binary.LittleEndian.PutUint32(header[8:12], uint32(x.dim))
+	binary.LittleEndian.PutUint64(header[12:20], uint64(len(x.labels)))
+	binary.LittleEndian.PutUint32(header[20:24], uint32(x.encoding))
+	w.Write(header[:])
+	binary.Write(w, binary.LittleEndian, x.labels)
+	x.writeVectors(w)
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), x.path); err != nil {
+		return err
+	}
+	flatCache.Lock()
+	delete(flatCache.m, x.path)
+	flatCache.Unlock()
+	x.dirty = false
+	return nil
+}
+
+// Ntotal returns the number of vectors in the index.
+func (x *FlatIndex) Ntotal() int64 {
+	return int64(len(x.labels))
+}
+
+// Dim returns the dimension of the vectors in the index.
+func (x *FlatIndex) Dim() int {
+	return x.dim
+}
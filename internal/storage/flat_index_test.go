@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFlatIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "faiss.index")
+	idx, err := OpenFlatIndex(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Add([][]float32{{1, 0}, {0, 1}, {0.6, 0.8}}, []int64{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Add([][]float32{{1, 0, 0}}, []int64{4}); err == nil {
+		t.Error("a vector of the wrong dimension was accepted")
+	}
+	if err := idx.Add([][]float32{{0, -1}}, []int64{2}); err != nil {
+		t.Fatal(err)
+	}
+
+	scores, labels, err := idx.Search([]float32{0.6, 0.8}, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 2 || labels[0] != 3 || labels[1] != 1 || scores[0] < scores[1] {
+		t.Errorf("Search = %v %v", labels, scores)
+	}
+	if _, labels, _ := idx.Search([]float32{1, 0}, 5, []int64{2, 9}); len(labels) != 1 || labels[0] != 2 {
+		t.Errorf("Search with include = %v", labels)
+	}
+
+	if n := idx.Remove([]int64{1, 9}); n != 1 || idx.Ntotal() != 2 {
+		t.Errorf("Remove = %d, %d vectors left", n, idx.Ntotal())
+	}
+	if err := idx.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := OpenFlatIndex(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, labels, _ := again.Search([]float32{0, -1}, 5, nil); len(labels) != 2 || labels[0] != 2 || labels[1] != 3 {
+		t.Errorf("reopened index: %v", labels)
+	}
+	// Writers copy the data readers of the cached file share.
+	again.Remove([]int64{3})
+	if third, _ := OpenFlatIndex(path, 2); third.Ntotal() != 2 {
+		t.Errorf("a change before saving reached another reader: %d vectors", third.Ntotal())
+	}
+	if vectors, dim, err := ReadFlatIndexInfo(path); vectors != 2 || dim != 2 || err != nil {
+		t.Errorf("ReadFlatIndexInfo = %d, %d, %v", vectors, dim, err)
+	}
+	if _, err := OpenFlatIndex(path, 3); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("index of another dimension: %v", err)
+	}
+}
+
+func TestFlatIndexCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "faiss.index")
+	if _, _, err := ReadFlatIndexInfo(path); !errors.Is(err, ErrNoVectorIndex) {
+		t.Errorf("missing index: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("IxM2 a FAISS index"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFlatIndex(path, 2); !errors.Is(err, ErrCorruptIndex) {
+		t.Errorf("foreign file: %v", err)
+	}
+
+	idx, _ := OpenFlatIndex(filepath.Join(t.TempDir(), "flat.index"), 2)
+	idx.Add([][]float32{{1, 0}}, []int64{1})
+	idx.path = path
+	if err := idx.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if !isFlatIndexFile(path) {
+		t.Error("isFlatIndexFile")
+	}
+	data, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, data[:len(data)-2], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFlatIndex(path, 2); !errors.Is(err, ErrCorruptIndex) {
+		t.Errorf("truncated file: %v", err)
+	}
+}
+
+func TestFlatVectorIndex(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "index", "faiss.index")
+	idx, err := NewFlatVectorIndex(ctx, path, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx.DeferSave()
+	for id, v := range map[string][]float32{"a#0": {1, 0}, "b#0": {0, 1}, "c#0": {0.6, 0.8}} {
+		if err := idx.Upsert(ctx, id, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("a deferred index was saved before Close")
+	}
+	if err := idx.Delete(ctx, []string{"b#0", "missing"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err = NewFlatVectorIndex(ctx, path, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	got, err := idx.Search(ctx, []float32{1, 0}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != "a#0" || got[0].Score != 1 || got[1].ID != "c#0" {
+		t.Errorf("Search = %+v", got)
+	}
+	got, err = idx.SearchAllowed(ctx, []float32{1, 0}, 5, []string{"c#0", "b#0"})
+	if err != nil || len(got) != 1 || got[0].ID != "c#0" {
+		t.Errorf("SearchAllowed = %+v, %v", got, err)
+	}
+
+	if err := os.Remove(JSONIDMapPath(path)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFlatVectorIndex(ctx, path, 2, ""); !errors.Is(err, ErrCorruptIndex) {
+		t.Errorf("index without its ID map: %v", err)
+	}
+}
+
+func TestFlatIndexCompression(t *testing.T) {
+	vectors := [][]float32{{0.6, 0.8, 0}, {0, 0.6, 0.8}, {0.8, 0, -0.6}}
+	query := []float32{0.8, 0.6, 0}
+	for _, compression := range []string{CompressionFloat16, CompressionInt8} {
+		path := filepath.Join(t.TempDir(), "faiss.index")
+		idx, _ := OpenFlatIndex(path, 3)
+		if !idx.Convert(compression) || idx.Convert(compression) {
+			t.Errorf("%s: Convert should only report the first conversion", compression)
+		}
+		if err := idx.Add(vectors, []int64{1, 2, 3}); err != nil {
+			t.Fatal(err)
+		}
+		idx.Remove([]int64{2})
+		if err := idx.Add([][]float32{vectors[1]}, []int64{2}); err != nil {
+			t.Fatal(err)
+		}
+		if err := idx.Save(); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := ReadVectorIndexCompression(path); got != compression || err != nil {
+			t.Errorf("ReadVectorIndexCompression = %q, %v; want %s", got, err, compression)
+		}
+
+		again, err := OpenFlatIndex(path, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scores, labels, _ := again.Search(query, 3, nil)
+		if len(labels) != 3 || labels[0] != 1 || labels[1] != 3 || labels[2] != 2 {
+			t.Errorf("%s: Search = %v", compression, labels)
+		}
+		for i, want := range []float32{0.96, 0.64, 0.36} {
+			if d := scores[i] - want; d > 0.01 || d < -0.01 {
+				t.Errorf("%s: score %d = %v, want about %v", compression, i, scores[i], want)
+			}
+		}
+
+		again.Convert(CompressionNone)
+		if v := again.decode(again.rows[3]); v[0] < 0.79 || v[0] > 0.81 || v[2] > -0.59 || v[2] < -0.61 {
+			t.Errorf("%s: vector converted to float32 = %v", compression, v)
+		}
+	}
+}
+
+func TestFloat16(t *testing.T) {
+	for _, f := range []float32{0, 1, -2.5, 0.1, 65504, 1e-7, -6.1e-5} {
+		got := float16Value(float16Bits(f))
+		if math.Abs(float64(got-f)) > math.Abs(float64(f))/1000+1e-7 {
+			t.Errorf("float16 round trip of %v = %v", f, got)
+		}
+	}
+	if h := float16Bits(1e6); h != 0x7c00 {
+		t.Errorf("1e6 = %#x, want infinity", h)
+	}
+	// 1 + 2^-11 is halfway between 1 and the next half, and rounds to even.
+	if h := float16Bits(1 + 1.0/2048); h != 0x3c00 {
+		t.Errorf("1+2^-11 = %#x, want 0x3c00", h)
+	}
+}
+
+func TestFlatVectorIndexCompression(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "faiss.index")
+	idx, err := NewFlatVectorIndex(ctx, path, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Upsert(ctx, "a#0", []float32{0.6, 0.8}); err != nil {
+		t.Fatal(err)
+	}
+	idx.Close()
+
+	// Searches, which set no compression, leave the index alone; writes
+	// convert it.
+	idx, _ = NewFlatVectorIndex(ctx, path, 2, "")
+	idx.Close()
+	if got, _ := ReadVectorIndexCompression(path); got != CompressionNone {
+		t.Errorf("compression after a search = %q", got)
+	}
+	idx, _ = NewFlatVectorIndex(ctx, path, 2, CompressionInt8)
+	if err := idx.Upsert(ctx, "b#0", []float32{1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	idx.Close()
+	if got, _ := ReadVectorIndexCompression(path); got != CompressionInt8 {
+		t.Errorf("compression after a write = %q", got)
+	}
+
+	idx, _ = NewFlatVectorIndex(ctx, path, 2, CompressionFloat16)
+	if err := idx.quantize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	idx.Close()
+	if got, _ := ReadVectorIndexCompression(path); got != CompressionFloat16 {
+		t.Errorf("compression after quantize = %q", got)
+	}
+}
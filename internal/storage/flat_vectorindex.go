@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// FlatVectorIndex adapts FlatIndex to the VectorIndex interface, with an
+// IDMap translating its int64 labels to and from chunk IDs like
+// FaissVectorIndex does. It is the vector index of builds without FAISS.
+type FlatVectorIndex struct {
+	idx       *FlatIndex
+	path      string
+	ids       IDMap
+	deferSave bool
+	// compression is vector.compression, which writes convert the index to.
+	compression string
+}
+
+// NewFlatVectorIndex opens or creates the flat index at path for vectors of
+// dimension dim, with its ID map in a JSON file next to it (see
+// JSONIDMapPath). Writes convert the index to compression, a
+// vector.compression value, unless it is empty.
+func NewFlatVectorIndex(ctx context.Context, path string, dim int, compression string) (*FlatVectorIndex, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	ids, err := OpenJSONIDMap(JSONIDMapPath(path))
+	if err != nil {
+		return nil, err
+	}
+	idx, err := OpenFlatIndex(path, dim)
+	if err != nil {
+		ids.Close()
+		return nil, err
+	}
+	if vectors := idx.Ntotal(); vectors > 0 && ids.Len() == 0 {
+		ids.Close()
+		return nil, fmt.Errorf("%w: %s holds %d vectors but its ID map is missing or empty; run semango index --recreate to rebuild it",
+			ErrCorruptIndex, path, vectors)
+	} else if vectors != int64(ids.Len()) {
+		util.FromContext(ctx).Warn("Vector index and its ID map disagree; re-index to clean it up",
+			"path", path, "vectors", vectors, "ids", ids.Len())
+	}
+	if compression == CompressionPQ {
+		util.FromContext(ctx).Warn("Product quantization needs FAISS; this build stores int8 vectors instead", "path", path)
+	}
+	if idx.Ntotal() == 0 && compression != "" {
+		idx.Convert(compression)
+	}
+	return &FlatVectorIndex{idx: idx, path: path, ids: ids, compression: compression}, nil
+}
+
+// Upsert inserts or replaces the vector of id.
+func (f *FlatVectorIndex) Upsert(ctx context.Context, id string, vector []float32) error {
+	label, ok := f.ids.Label(id)
+	if !ok {
+		var err error
+		if label, err = f.ids.Add(id); err != nil {
+			return err
+		}
+	}
+	if err := f.idx.Add([][]float32{vector}, []int64{label}); err != nil {
+		return err
+	}
+	return f.save()
+}
+
+func (f *FlatVectorIndex) Search(ctx context.Context, query []float32, topK int) ([]VectorResult, error) {
+	scores, labels, err := f.idx.Search(query, topK, nil)
+	if err != nil {
+		return nil, err
+	}
+	return f.results(scores, labels), nil
+}
+
+// SearchAllowed only scores the vectors of the allowed IDs. IDs that were
+// never indexed are ignored.
+func (f *FlatVectorIndex) SearchAllowed(ctx context.Context, query []float32, topK int, allowed []string) ([]VectorResult, error) {
+	include := make([]int64, 0, len(allowed))
+	for _, id := range allowed {
+		if label, ok := f.ids.Label(id); ok {
+			include = append(include, label)
+		}
+	}
+	scores, labels, err := f.idx.Search(query, topK, include)
+	if err != nil {
+		return nil, err
+	}
+	return f.results(scores, labels), nil
+}
+
+// results turns hits into results by chunk ID, dropping labels without an
+// ID, which a crash between saving the index and its ID map can leave.
+func (f *FlatVectorIndex) results(scores []float32, labels []int64) []VectorResult {
+	results := make([]VectorResult, 0, len(labels))
+	for i, l := range labels {
+		if id, ok := f.ids.ID(l); ok {
+			results = append(results, VectorResult{ID: id, Score: scores[i]})
+		}
+	}
+	return results
+}
+
+// Delete removes the vectors of the given IDs and forgets their labels.
+func (f *FlatVectorIndex) Delete(ctx context.Context, ids []string) error {
+	labels, err := f.ids.Delete(ids)
+	if err != nil || len(labels) == 0 {
+		return err
+	}
+	f.idx.Remove(labels)
+	return f.save()
+}
+
+// DeferSave makes Upsert and Delete keep their changes in memory until
+// Close, for bulk indexing.
+func (f *FlatVectorIndex) DeferSave() {
+	f.deferSave = true
+}
+
+// save persists the index and its ID map unless saving is deferred.
+func (f *FlatVectorIndex) save() error {
+	if f.deferSave {
+		return nil
+	}
+	f.compress(context.Background())
+	if err := f.ids.Save(); err != nil {
+		return err
+	}
+	return f.idx.Save()
+}
+
+// compress converts the index to the configured compression if it stores
+// vectors otherwise.
+func (f *FlatVectorIndex) compress(ctx context.Context) {
+	from := f.idx.Compression()
+	if f.compression != "" && f.idx.Convert(f.compression) {
+		util.FromContext(ctx).Info("Converted vector index", "path", f.path, "from", from, "to", f.idx.Compression(), "vectors", f.idx.Ntotal())
+	}
+}
+
+// quantize converts the index to the configured compression now.
+func (f *FlatVectorIndex) quantize(ctx context.Context) error {
+	f.compress(ctx)
+	return nil
+}
+
+func (f *FlatVectorIndex) Dimension() int {
+	return f.idx.Dim()
+}
+
+// Close saves the index if it changed and closes its ID map.
+func (f *FlatVectorIndex) Close() error {
+	if f.idx.dirty {
+		f.compress(context.Background())
+	}
+	err := f.idx.Save()
+	if cerr := f.ids.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+var _ VectorIndex = (*FlatVectorIndex)(nil)
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Generation identifies a state of the on-disk indexes. It is bumped every
+// time the indexer writes, and lives in a small file next to the indexes so
+// that a server can tell when another process (e.g. `semango index`) changed
+// them and cached results are stale.
+type Generation struct {
+	Number    uint64    `json:"generation"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+const generationFile = "generation.json"
+
+// GenerationPath returns the generation file for the indexes whose lexical
+// index lives at lexicalIndexPath.
+func GenerationPath(lexicalIndexPath string) string {
+	return filepath.Join(filepath.Dir(filepath.Clean(lexicalIndexPath)), generationFile)
+}
+
+// ReadGeneration loads the generation stored at path. A missing file is the
+// zero generation: nothing has been indexed by this version yet.
+func ReadGeneration(path string) (Generation, error) {
+	var g Generation
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return g, nil
+		}
+		return g, err
+	}
+	err = json.Unmarshal(data, &g)
+	return g, err
+}
+
+// BumpGeneration increments the generation stored at path and returns the
+// new value. The file is replaced atomically so readers never see a partial
+// write.
+func BumpGeneration(path string) (Generation, error) {
+	g, err := ReadGeneration(path)
+	if err != nil {
+		// A corrupt stamp must not block indexing; start a new sequence.
+		g = Generation{}
+	}
+	g.Number++
+	g.UpdatedAt = time.Now().UTC()
+	return g, writeGeneration(path, g)
+}
+
+// writeGeneration stores g at path, replacing the file atomically.
+func writeGeneration(path string, g Generation) error {
+	data, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), generationFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
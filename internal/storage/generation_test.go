@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGeneration_Bump(t *testing.T) {
+	path := GenerationPath(filepath.Join(t.TempDir(), "index", "bleve"))
+
+	g, err := ReadGeneration(path)
+	if err != nil || g.Number != 0 || !g.UpdatedAt.IsZero() {
+		t.Fatalf("expected zero generation before indexing, got %+v, %v", g, err)
+	}
+	first, err := BumpGeneration(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := BumpGeneration(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Number != 1 || second.Number != 2 {
+		t.Errorf("expected generations 1 and 2, got %d and %d", first.Number, second.Number)
+	}
+	g, err = ReadGeneration(path)
+	if err != nil || g.Number != 2 || !g.UpdatedAt.Equal(second.UpdatedAt) {
+		t.Errorf("expected stored generation %+v, got %+v, %v", second, g, err)
+	}
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/mapping"
+)
+
+// Span is the byte range [Start, End) of a term in a text that matched
+// Term, a term of the query as analysed.
+type Span struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Term  string `json:"term"`
+}
+
+// analysisMapping resolves analyzers by name for MatchSpans.
+var analysisMapping = sync.OnceValue(func() *mapping.IndexMappingImpl { return newIndexMapping(nil) })
+
+// MatchSpans returns the spans of text whose terms match a term of query,
+// in order, with both analysed the way the lexical index analyses a chunk
+// with metadata meta: by the standard analyzer, by the analyzer of the
+// chunk's "lang" when it has one, so that stemmed forms match, and for
+// code split into identifier words. Stop words never match.
+func MatchSpans(text, query string, meta map[string]string) []Span {
+	names := []string{standard.Name}
+	if a := LexicalAnalyzer(meta["lang"]); a != "" {
+		names = append(names, a)
+	}
+	if isCodeChunk(meta) {
+		names = append(names, CodeAnalyzer)
+	}
+	var spans []Span
+	for _, name := range names {
+		a := analysisMapping().AnalyzerNamed(name)
+		if a == nil {
+			continue
+		}
+		spans = append(spans, matchTokens(a, text, query)...)
+	}
+	return mergeSpans(spans)
+}
+
+func matchTokens(a analysis.Analyzer, text, query string) []Span {
+	terms := map[string]bool{}
+	for _, t := range a.Analyze([]byte(query)) {
+		terms[string(t.Term)] = true
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	var spans []Span
+	for _, t := range a.Analyze([]byte(text)) {
+		if terms[string(t.Term)] {
+			spans = append(spans, Span{Start: t.Start, End: t.End, Term: string(t.Term)})
+		}
+	}
+	return spans
+}
+
+// mergeSpans sorts spans and merges overlapping ones, keeping the term of
+// the first.
+func mergeSpans(spans []Span) []Span {
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].Start != spans[j].Start {
+			return spans[i].Start < spans[j].Start
+		}
+		return spans[i].End > spans[j].End
+	})
+	out := spans[:0]
+	for _, s := range spans {
+		if n := len(out); n > 0 && s.Start < out[n-1].End {
+			if s.End > out[n-1].End {
+				out[n-1].End = s.End
+			}
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchSpans(t *testing.T) {
+	text := "Semango indexes Markdown files."
+	terms := func(spans []Span) []string {
+		var out []string
+		for _, s := range spans {
+			out = append(out, text[s.Start:s.End])
+		}
+		return out
+	}
+	// Several terms, in any order; stop words are not highlighted.
+	if got := terms(MatchSpans(text, "the markdown indexing", nil)); !reflect.DeepEqual(got, []string{"Markdown"}) {
+		t.Errorf("standard analyzer matched %v", got)
+	}
+	// The chunk's language analyzer matches stemmed forms.
+	if got := terms(MatchSpans(text, "the markdown indexing", map[string]string{"lang": "en"})); !reflect.DeepEqual(got, []string{"indexes", "Markdown"}) {
+		t.Errorf("English analyzer matched %v", got)
+	}
+
+	text = "func getUserByID()"
+	if got := terms(MatchSpans(text, "user id", map[string]string{"source": "CodeLoader"})); !reflect.DeepEqual(got, []string{"User", "ID"}) {
+		t.Errorf("code analyzer matched %v", got)
+	}
+}
+
+func TestMergeSpans(t *testing.T) {
+	got := mergeSpans([]Span{{10, 14, "b"}, {0, 4, "a"}, {10, 12, "c"}, {12, 16, "d"}})
+	want := []Span{{0, 4, "a"}, {10, 16, "b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeSpans = %v, want %v", got, want)
+	}
+}
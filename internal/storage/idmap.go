@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// IDMap maps the string IDs of chunks to the int64 labels a vector index
+// stores with each vector, and back. Labels are allocated in increasing
+// order from 1 and never reused while the map lives, so a label found in
+// an index always names the chunk it was stored for. Implementations are
+// not safe for concurrent use unless they say otherwise.
+type IDMap interface {
+	// Label returns the label of id, and whether id has one.
+	Label(id string) (int64, bool)
+	// ID returns the ID a label was allocated for, and whether it was.
+	ID(label int64) (string, bool)
+	// Add returns the label of id, allocating one if it has none.
+	Add(id string) (int64, error)
+	// Delete forgets the given IDs and returns the labels they had;
+	// unknown IDs are ignored.
+	Delete(ids []string) ([]int64, error)
+	// Labels returns the labels allocated to IDs, in no particular order.
+	Labels() []int64
+	// Len returns the number of IDs with a label.
+	Len() int
+	// Save makes the changes so far durable.
+	Save() error
+	// Close saves the map and releases its resources.
+	Close() error
+}
+
+// JSONIDMapPath returns where the JSON ID map of the vector index at
+// indexPath is kept.
+func JSONIDMapPath(indexPath string) string {
+	return indexPath + ".ids.json"
+}
+
+// MemoryIDMap is an IDMap held in memory only.
+type MemoryIDMap struct {
+	idToLabel map[string]int64
+	labelToID map[int64]string
+	next      int64
+}
+
+// NewMemoryIDMap returns an empty MemoryIDMap.
+func NewMemoryIDMap() *MemoryIDMap {
+	return &MemoryIDMap{idToLabel: map[string]int64{}, labelToID: map[int64]string{}, next: 1}
+}
+
+func (m *MemoryIDMap) Label(id string) (int64, bool) {
+	label, ok := m.idToLabel[id]
+	return label, ok
+}
+
+func (m *MemoryIDMap) ID(label int64) (string, bool) {
+	id, ok := m.labelToID[label]
+	return id, ok
+}
+
+func (m *MemoryIDMap) Add(id string) (int64, error) {
+	if label, ok := m.idToLabel[id]; ok {
+		return label, nil
+	}
+	label := m.next
+	m.next++
+	m.set(id, label)
+	return label, nil
+}
+
+// set records label for id and keeps the next label above it.
+func (m *MemoryIDMap) set(id string, label int64) {
+	m.idToLabel[id] = label
+	m.labelToID[label] = id
+	if label >= m.next {
+		m.next = label + 1
+	}
+}
+
+func (m *MemoryIDMap) Delete(ids []string) ([]int64, error) {
+	var labels []int64
+	for _, id := range ids {
+		if label, ok := m.idToLabel[id]; ok {
+			labels = append(labels, label)
+			delete(m.labelToID, label)
+			delete(m.idToLabel, id)
+		}
+	}
+	return labels, nil
+}
+
+// IDs returns the IDs with a label, in no particular order.
+func (m *MemoryIDMap) IDs() []string {
+	ids := make([]string, 0, len(m.idToLabel))
+	for id := range m.idToLabel {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (m *MemoryIDMap) Labels() []int64 {
+	labels := make([]int64, 0, len(m.labelToID))
+	for label := range m.labelToID {
+		labels = append(labels, label)
+	}
+	return labels
+}
+
+func (m *MemoryIDMap) Len() int     { return len(m.idToLabel) }
+func (m *MemoryIDMap) Save() error  { return nil }
+func (m *MemoryIDMap) Close() error { return nil }
+
+// JSONIDMap is an IDMap kept in memory and saved as a JSON object of IDs
+// and labels, rewritten in full by every Save. It suits the corpora of a
+// single machine; BoltIDMap writes only what changed.
+type JSONIDMap struct {
+	*MemoryIDMap
+	path string
+}
+
+// OpenJSONIDMap loads the JSON ID map at path, or starts an empty one if
+// the file does not exist. An unreadable file is reported with
+// ErrCorruptIndex.
+func OpenJSONIDMap(path string) (*JSONIDMap, error) {
+	m := &JSONIDMap{MemoryIDMap: NewMemoryIDMap(), path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var labels map[string]int64
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v; run semango index --recreate to rebuild it", ErrCorruptIndex, path, err)
+	}
+	for id, label := range labels {
+		m.set(id, label)
+	}
+	return m, nil
+}
+
+// Save writes the map to a temporary file and renames it over the old
+// one, so a crash leaves either the old or the new map.
+func (m *JSONIDMap) Save() error {
+	data, err := json.MarshalIndent(m.idToLabel, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(m.path), filepath.Base(m.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), m.path)
+}
+
+func (m *JSONIDMap) Close() error {
+	return m.Save()
+}
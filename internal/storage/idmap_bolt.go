@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltIDsBucket    = []byte("ids")    // ID -> label
+	boltLabelsBucket = []byte("labels") // label -> ID
+)
+
+// BoltIDMap is an IDMap stored in a Bolt database, for corpora whose map
+// is too large to rewrite on every save. Every Add and Delete is a
+// transaction of its own and durable when it returns. The database is
+// locked while open, so it can be opened by one BoltIDMap at a time. It is
+// safe for concurrent use.
+type BoltIDMap struct {
+	db *bolt.DB
+}
+
+// OpenBoltIDMap opens or creates the Bolt ID map at path.
+func OpenBoltIDMap(path string) (*BoltIDMap, error) {
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open ID map %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(boltIDsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(boltLabelsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%w: %s: %v; run semango index --recreate to rebuild it", ErrCorruptIndex, path, err)
+	}
+	return &BoltIDMap{db: db}, nil
+}
+
+func (m *BoltIDMap) Label(id string) (int64, bool) {
+	var label int64
+	var ok bool
+	_ = m.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(boltIDsBucket).Get([]byte(id)); v != nil {
+			label, ok = decodeLabel(v), true
+		}
+		return nil
+	})
+	return label, ok
+}
+
+func (m *BoltIDMap) ID(label int64) (string, bool) {
+	var id string
+	var ok bool
+	_ = m.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(boltLabelsBucket).Get(encodeLabel(label)); v != nil {
+			id, ok = string(v), true
+		}
+		return nil
+	})
+	return id, ok
+}
+
+// Add allocates labels from the sequence of the IDs bucket.
+func (m *BoltIDMap) Add(id string) (int64, error) {
+	var label int64
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		ids := tx.Bucket(boltIDsBucket)
+		if v := ids.Get([]byte(id)); v != nil {
+			label = decodeLabel(v)
+			return nil
+		}
+		seq, err := ids.NextSequence()
+		if err != nil {
+			return err
+		}
+		label = int64(seq)
+		if err := ids.Put([]byte(id), encodeLabel(label)); err != nil {
+			return err
+		}
+		return tx.Bucket(boltLabelsBucket).Put(encodeLabel(label), []byte(id))
+	})
+	return label, err
+}
+
+func (m *BoltIDMap) Delete(ids []string) ([]int64, error) {
+	var labels []int64
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		idb, lb := tx.Bucket(boltIDsBucket), tx.Bucket(boltLabelsBucket)
+		for _, id := range ids {
+			v := idb.Get([]byte(id))
+			if v == nil {
+				continue
+			}
+			label := decodeLabel(v)
+			if err := lb.Delete(encodeLabel(label)); err != nil {
+				return err
+			}
+			if err := idb.Delete([]byte(id)); err != nil {
+				return err
+			}
+			labels = append(labels, label)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// Labels returns the labels in increasing order.
+func (m *BoltIDMap) Labels() []int64 {
+	var labels []int64
+	_ = m.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltLabelsBucket).ForEach(func(k, _ []byte) error {
+			labels = append(labels, decodeLabel(k))
+			return nil
+		})
+	})
+	return labels
+}
+
+func (m *BoltIDMap) Len() int {
+	n := 0
+	_ = m.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(boltIDsBucket).Stats().KeyN
+		return nil
+	})
+	return n
+}
+
+// Save syncs the database; committed transactions are already durable.
+func (m *BoltIDMap) Save() error {
+	return m.db.Sync()
+}
+
+func (m *BoltIDMap) Close() error {
+	return m.db.Close()
+}
+
+// encodeLabel encodes label big-endian, so labels sort by value.
+func encodeLabel(label int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(label))
+	return b
+}
+
+func decodeLabel(b []byte) int64 {
+	return int64(binary.BigEndian.Uint64(b))
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIDMaps(t *testing.T) {
+	dir := t.TempDir()
+	maps := map[string]func() (IDMap, error){
+		"memory": func() (IDMap, error) { return NewMemoryIDMap(), nil },
+		"json":   func() (IDMap, error) { return OpenJSONIDMap(filepath.Join(dir, "faiss.index.ids.json")) },
+		"bolt":   func() (IDMap, error) { return OpenBoltIDMap(filepath.Join(dir, "faiss.index.ids.db")) },
+	}
+	for name, open := range maps {
+		t.Run(name, func(t *testing.T) {
+			m, err := open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			a, _ := m.Add("a.md#0")
+			b, _ := m.Add("b.md#0")
+			if again, _ := m.Add("a.md#0"); again != a || a == b || a <= 0 {
+				t.Fatalf("labels a=%d (again %d), b=%d", a, again, b)
+			}
+			if id, ok := m.ID(b); !ok || id != "b.md#0" {
+				t.Errorf("ID(%d) = %q, %v", b, id, ok)
+			}
+			labels, err := m.Delete([]string{"a.md#0", "missing"})
+			if err != nil || len(labels) != 1 || labels[0] != a {
+				t.Fatalf("Delete = %v, %v", labels, err)
+			}
+			if _, ok := m.Label("a.md#0"); ok {
+				t.Error("deleted ID still has a label")
+			}
+			// Labels are not reused.
+			if c, _ := m.Add("c.md#0"); c == a || c == b {
+				t.Errorf("label %d reused", c)
+			}
+			if m.Len() != 2 {
+				t.Errorf("Len = %d, want 2", m.Len())
+			}
+			if got := m.Labels(); len(got) != 2 || got[0] == a || got[1] == a {
+				t.Errorf("Labels = %v, want those of b.md#0 and c.md#0", got)
+			}
+			if err := m.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if name == "memory" {
+				return
+			}
+
+			m, err = open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer m.Close()
+			if label, ok := m.Label("b.md#0"); !ok || label != b || m.Len() != 2 {
+				t.Errorf("reopened: b.md#0 = %d, %v; Len = %d", label, ok, m.Len())
+			}
+			if d, _ := m.Add("d.md#0"); d <= b {
+				t.Errorf("label %d after reopening is not above %d", d, b)
+			}
+		})
+	}
+}
+
+func TestOpenJSONIDMap_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "faiss.index.ids.json")
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenJSONIDMap(path); !errors.Is(err, ErrCorruptIndex) {
+		t.Errorf("err = %v, want ErrCorruptIndex", err)
+	}
+}
@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The index directory, the directory of lexical.index_path that also holds
+// the vector indexes, the generation stamp and the dedup records, can be an
+// alias: a symbolic link to a generation directory next to it, named
+// <dir>.gen-<unix time> (<dir>.gen-0 for the directory that was there
+// before the first rebuild). A rebuild (see StartRebuild) writes a complete new
+// set of indexes into a generation directory of its own while the current
+// one is served, then points the alias at it with a single rename, so
+// readers see either the old indexes or the new ones and never a mix.
+
+const generationDirInfix = ".gen-"
+
+// IndexDir returns the index directory of the indexes whose lexical index
+// lives at lexicalIndexPath.
+func IndexDir(lexicalIndexPath string) string {
+	return filepath.Dir(filepath.Clean(lexicalIndexPath))
+}
+
+// ResolveIndexDir returns the generation directory the index directory dir
+// points to, or dir itself if it is not an alias.
+func ResolveIndexDir(dir string) string {
+	target, err := os.Readlink(dir)
+	if err != nil {
+		return dir
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(dir), target)
+	}
+	return target
+}
+
+// IndexRebuild is a generation of the indexes being built next to the
+// served one. Paths that pointed into the index directory are moved into
+// the generation directory with Path; Swap then serves the new generation.
+type IndexRebuild struct {
+	Dir     string // the index directory, served throughout the rebuild
+	Staging string // the generation directory being built
+}
+
+// StartRebuild creates an empty generation directory for a rebuild of the
+// indexes at lexicalIndexPath and vectorIndexPath, which must be in the same
+// directory since the whole directory is swapped. The new generation starts
+// numbering from the current one, so caches keyed by generation never take
+// the rebuilt indexes for the old ones.
+func StartRebuild(lexicalIndexPath, vectorIndexPath string) (*IndexRebuild, error) {
+	dir := IndexDir(lexicalIndexPath)
+	if d := filepath.Dir(filepath.Clean(vectorIndexPath)); d != dir {
+		return nil, fmt.Errorf("the vector index %s is not in the index directory %s; a rebuild swaps the whole directory, so vector.index_path must be next to lexical.index_path", vectorIndexPath, dir)
+	}
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		return nil, fmt.Errorf("index directory %s is not a directory", dir)
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return nil, err
+	}
+	r := &IndexRebuild{Dir: dir}
+	// Named by the start time, or the next second not taken.
+	for n := time.Now().Unix(); ; n++ {
+		r.Staging = fmt.Sprintf("%s%s%d", dir, generationDirInfix, n)
+		err := os.Mkdir(r.Staging, 0o755)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+	}
+	g, err := ReadGeneration(filepath.Join(dir, generationFile))
+	if err == nil {
+		err = writeGeneration(filepath.Join(r.Staging, generationFile), g)
+	}
+	if err != nil {
+		os.RemoveAll(r.Staging)
+		return nil, err
+	}
+	return r, nil
+}
+
+// Path returns where the file or directory at p, in the index directory, is
+// built during the rebuild. Paths elsewhere are returned unchanged.
+func (r *IndexRebuild) Path(p string) string {
+	if filepath.Dir(filepath.Clean(p)) != r.Dir {
+		return p
+	}
+	return filepath.Join(r.Staging, filepath.Base(p))
+}
+
+// Abort deletes the generation directory of a rebuild that will not be
+// swapped in, e.g. after indexing failed.
+func (r *IndexRebuild) Abort() error {
+	return os.RemoveAll(r.Staging)
+}
+
+// Swap points the index directory at the rebuilt generation and returns the
+// generation directory it pointed to before. Files of the old generation
+// the rebuild did not write, such as reports and snapshots, are copied over
+// first, so the served generation stays whole if the swap fails, and the
+// generation number is raised above both. The previous
+// generation is kept so that searches that resolved it before the swap can
+// finish, and for going back by hand; older ones are deleted.
+//
+// The first swap of an index directory that is not an alias yet renames it
+// to a generation directory before the alias takes its place, which leaves
+// the path missing for the moment between the two renames.
+func (r *IndexRebuild) Swap() (previous string, err error) {
+	current := ResolveIndexDir(r.Dir)
+	entries, err := os.ReadDir(current)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if _, err := os.Lstat(filepath.Join(r.Staging, name)); !errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err := copyTree(filepath.Join(current, name), filepath.Join(r.Staging, name)); err != nil {
+			return "", fmt.Errorf("carry %s over to the new generation: %w", name, err)
+		}
+	}
+
+	live, err := ReadGeneration(filepath.Join(current, generationFile))
+	if err != nil {
+		return "", err
+	}
+	g, err := ReadGeneration(filepath.Join(r.Staging, generationFile))
+	if err != nil {
+		return "", err
+	}
+	if live.Number > g.Number {
+		g.Number = live.Number
+	}
+	g.Number++
+	g.UpdatedAt = time.Now().UTC()
+	if err := writeGeneration(filepath.Join(r.Staging, generationFile), g); err != nil {
+		return "", err
+	}
+
+	link := r.Dir + ".swap"
+	if err := os.Remove(link); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	if err := os.Symlink(filepath.Base(r.Staging), link); err != nil {
+		return "", err
+	}
+	info, err := os.Lstat(r.Dir)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return "", err
+	case info.Mode()&os.ModeSymlink == 0:
+		previous = r.Dir + generationDirInfix + "0"
+		if err := os.Rename(r.Dir, previous); err != nil {
+			os.Remove(link)
+			return "", err
+		}
+	default:
+		previous = current
+	}
+	if err := os.Rename(link, r.Dir); err != nil {
+		if previous != "" && previous != current {
+			os.Rename(previous, r.Dir)
+		}
+		os.Remove(link)
+		return "", err
+	}
+	return previous, r.prune(previous)
+}
+
+// prune deletes the generation directories of the index directory other
+// than the served one and keep.
+func (r *IndexRebuild) prune(keep string) error {
+	matches, err := filepath.Glob(r.Dir + generationDirInfix + "*")
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		if m == r.Staging || m == keep {
+			continue
+		}
+		if _, err := strconv.ParseInt(strings.TrimPrefix(m, r.Dir+generationDirInfix), 10, 64); err != nil {
+			continue // not one of ours
+		}
+		if err := os.RemoveAll(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyTree recreates the file, directory or symbolic link at src at dst.
+// Files are copied rather than hard-linked: some, such as bbolt ID maps and
+// reports, are written in place, and a write to a shared inode would change
+// the kept previous generation too. src is left untouched.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.Mkdir(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case !info.Mode().IsRegular():
+			return nil // sockets and the like are not index data
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
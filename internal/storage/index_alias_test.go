@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexRebuild(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "index")
+	lexical, vector := filepath.Join(dir, "bleve"), filepath.Join(dir, "faiss.index")
+	write := func(path, data string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(path string) string {
+		data, _ := os.ReadFile(path)
+		return string(data)
+	}
+	write(vector, "old")
+	write(filepath.Join(dir, "reports", "report-1.json"), "{}")
+	for i := 0; i < 3; i++ {
+		BumpGeneration(GenerationPath(lexical))
+	}
+
+	if _, err := StartRebuild(lexical, filepath.Join(root, "vectors", "faiss.index")); err == nil {
+		t.Error("a rebuild of indexes in two directories was started")
+	}
+	r, err := StartRebuild(lexical, vector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Path(vector); got != filepath.Join(r.Staging, "faiss.index") {
+		t.Errorf("Path(%s) = %s", vector, got)
+	}
+	if got := r.Path("/elsewhere/x"); got != "/elsewhere/x" {
+		t.Errorf("Path of a file elsewhere = %s", got)
+	}
+	write(r.Path(vector), "new")
+	if read(vector) != "old" || ResolveIndexDir(dir) != dir {
+		t.Fatal("the served index changed before the swap")
+	}
+
+	previous, err := r.Swap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ResolveIndexDir(dir) != r.Staging || read(vector) != "new" || read(filepath.Join(previous, "faiss.index")) != "old" {
+		t.Errorf("after the first swap %s resolves to %s, previous %s", dir, ResolveIndexDir(dir), previous)
+	}
+	if read(filepath.Join(dir, "reports", "report-1.json")) != "{}" {
+		t.Error("reports were not carried over")
+	}
+	if read(filepath.Join(previous, "reports", "report-1.json")) != "{}" {
+		t.Error("carrying reports over took them from the previous generation")
+	}
+	if g, _ := ReadGeneration(GenerationPath(lexical)); g.Number != 4 {
+		t.Errorf("generation after the swap = %d, want 4", g.Number)
+	}
+
+	// The next rebuild keeps the generation it replaces and drops the one
+	// before.
+	first := r.Staging
+	r, err = StartRebuild(lexical, vector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(r.Path(vector), "newer")
+	if previous, err = r.Swap(); err != nil || previous != first {
+		t.Fatalf("second swap: previous %s, %v", previous, err)
+	}
+	if read(vector) != "newer" {
+		t.Error("the second rebuild was not swapped in")
+	}
+	if _, err := os.Stat(dir + ".gen-0"); !os.IsNotExist(err) {
+		t.Error("the generation before the previous one was kept")
+	}
+
+	r, _ = StartRebuild(lexical, vector)
+	if err := r.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(r.Staging); !os.IsNotExist(err) || read(vector) != "newer" {
+		t.Error("Abort left the rebuild behind or changed the served index")
+	}
+}
+
+func TestIndexRebuild_FailedSwapKeepsServedGeneration(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "index")
+	lexical, vector := filepath.Join(dir, "bleve"), filepath.Join(dir, "faiss.index")
+	if err := os.MkdirAll(filepath.Join(dir, "snapshots"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(vector, []byte("old"), 0o644)
+	os.WriteFile(filepath.Join(dir, "snapshots", "s1.tar.gz"), []byte("snap"), 0o644)
+	BumpGeneration(GenerationPath(lexical))
+
+	r, err := StartRebuild(lexical, vector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(r.Path(vector), []byte("new"), 0o644)
+	// A corrupt stamp in the rebuild makes the swap fail after the files
+	// of the served generation were carried over.
+	os.WriteFile(filepath.Join(r.Staging, generationFile), []byte("{"), 0o644)
+	if _, err := r.Swap(); err == nil {
+		t.Fatal("swap with a corrupt generation stamp succeeded")
+	}
+	if ResolveIndexDir(dir) != dir {
+		t.Error("a failed swap changed the alias")
+	}
+	for path, want := range map[string]string{vector: "old", filepath.Join(dir, "snapshots", "s1.tar.gz"): "snap"} {
+		if data, err := os.ReadFile(path); err != nil || string(data) != want {
+			t.Errorf("%s after a failed swap: %q, %v", path, data, err)
+		}
+	}
+}
+
+func TestIndexRebuild_CarriedFilesAreNotShared(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "index")
+	lexical, vector := filepath.Join(dir, "bleve"), filepath.Join(dir, "faiss.index")
+	idMap := filepath.Join(dir, "reports", "ids.db")
+	if err := os.MkdirAll(filepath.Dir(idMap), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(vector, []byte("old"), 0o644)
+	os.WriteFile(idMap, []byte("old ids"), 0o644)
+	BumpGeneration(GenerationPath(lexical))
+
+	r, err := StartRebuild(lexical, vector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(r.Path(vector), []byte("new"), 0o644)
+	previous, err := r.Swap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bbolt and the report writers update files in place, without a
+	// rename, which a hard link would pass on to the previous generation.
+	f, err := os.OpenFile(idMap, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt([]byte("new"), 0); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if data, _ := os.ReadFile(filepath.Join(previous, "reports", "ids.db")); string(data) != "old ids" {
+		t.Errorf("writing to the new generation changed the previous one: %q", data)
+	}
+}
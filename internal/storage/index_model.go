@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// IndexModel is the fingerprint of the embedding model whose vectors a
+// vector index holds. It is written to a small file next to the index (see
+// IndexModelPath) when the index is first written, so that searches and
+// index runs with another model, whose vectors would not compare with the
+// stored ones, are refused instead of returning meaningless results.
+type IndexModel struct {
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model"`
+	Dimension int       `json:"dimension"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ErrModelMismatch reports a vector index built with another embedding
+// model than the one configured.
+var ErrModelMismatch = errors.New("vector index was built with another embedding model")
+
+// IndexModelPath returns the fingerprint file of the vector index at
+// indexPath. Indexes in a vector database keep theirs at the same place,
+// next to vector.index_path.
+func IndexModelPath(indexPath string) string {
+	return indexPath + ".model.json"
+}
+
+// String names the model as provider/model with its dimension.
+func (m IndexModel) String() string {
+	return fmt.Sprintf("%s/%s (%d dimensions)", m.Provider, m.Model, m.Dimension)
+}
+
+// Matches reports whether vectors of m and other compare: the same provider
+// and model, and the same dimension when both are known.
+func (m IndexModel) Matches(other IndexModel) bool {
+	if m.Provider != other.Provider || m.Model != other.Model {
+		return false
+	}
+	return m.Dimension == 0 || other.Dimension == 0 || m.Dimension == other.Dimension
+}
+
+// ReadIndexModel returns the fingerprint of the vector index at indexPath.
+// ok is false if it has none, e.g. an index built before fingerprints were
+// written or one that was never written.
+func ReadIndexModel(indexPath string) (m IndexModel, ok bool, err error) {
+	data, err := os.ReadFile(IndexModelPath(indexPath))
+	if os.IsNotExist(err) {
+		return m, false, nil
+	}
+	if err != nil {
+		return m, false, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, false, fmt.Errorf("%s: %w", IndexModelPath(indexPath), err)
+	}
+	return m, true, nil
+}
+
+// WriteIndexModel records m as the fingerprint of the vector index at
+// indexPath. The file is replaced atomically.
+func WriteIndexModel(indexPath string, m IndexModel) error {
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now().UTC()
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := IndexModelPath(indexPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// CheckIndexModel returns an error wrapping ErrModelMismatch if the vector
+// index at indexPath was built with another model than m. An index without
+// a fingerprint passes.
+func CheckIndexModel(indexPath string, m IndexModel) error {
+	stored, ok, err := ReadIndexModel(indexPath)
+	if err != nil || !ok {
+		return err
+	}
+	if !stored.Matches(m) {
+		return fmt.Errorf("%w: %s holds vectors of %s, the configuration uses %s; "+
+			"configure the model the index was built with, or rebuild it with semango index --recreate",
+			ErrModelMismatch, indexPath, stored, m)
+	}
+	return nil
+}
+
+// RemoveIndexModel deletes the fingerprint of the vector index at
+// indexPath, e.g. before the vectors of a vector database are overwritten
+// with those of a new model.
+func RemoveIndexModel(indexPath string) error {
+	err := os.Remove(IndexModelPath(indexPath))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndexModel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index", "faiss.index")
+	small := IndexModel{Provider: "openai", Model: "text-embedding-3-small", Dimension: 1536}
+	if _, ok, err := ReadIndexModel(path); ok || err != nil {
+		t.Fatalf("missing fingerprint: %v, %v", ok, err)
+	}
+	if err := CheckIndexModel(path, small); err != nil {
+		t.Errorf("an index without a fingerprint was refused: %v", err)
+	}
+
+	if err := WriteIndexModel(path, small); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := ReadIndexModel(path)
+	if !ok || err != nil || got.Model != small.Model || got.Dimension != 1536 || got.CreatedAt.IsZero() {
+		t.Errorf("ReadIndexModel = %+v, %v, %v", got, ok, err)
+	}
+	if err := CheckIndexModel(path, small); err != nil {
+		t.Errorf("same model: %v", err)
+	}
+	for _, other := range []IndexModel{
+		{Provider: "openai", Model: "text-embedding-3-large", Dimension: 1536},
+		{Provider: "local", Model: "text-embedding-3-small", Dimension: 1536},
+		{Provider: "openai", Model: "text-embedding-3-small", Dimension: 512},
+	} {
+		err := CheckIndexModel(path, other)
+		if !errors.Is(err, ErrModelMismatch) || !strings.Contains(err.Error(), "openai/text-embedding-3-small (1536 dimensions)") {
+			t.Errorf("%s: %v", other, err)
+		}
+	}
+
+	if err := RemoveIndexModel(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveIndexModel(path); err != nil {
+		t.Errorf("removing a missing fingerprint: %v", err)
+	}
+	os.WriteFile(IndexModelPath(path), []byte("{"), 0o644)
+	if _, _, err := ReadIndexModel(path); err == nil {
+		t.Error("a corrupt fingerprint was read")
+	}
+}
@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MilvusVectorIndex keeps vectors in a Milvus collection, through the
+// RESTful API (v2) of Milvus or Zilliz Cloud. The collection has a VarChar
+// primary key "id" holding chunk IDs and a float vector field "vector"
+// searched by inner product.
+type MilvusVectorIndex struct {
+	store      *httpStore
+	collection string
+	dim        int
+	buf        upsertBuffer
+}
+
+// milvusMaxIDLength bounds the chunk IDs a Milvus collection created by
+// semango accepts.
+const milvusMaxIDLength = 512
+
+// NewMilvusVectorIndex opens the collection of the Milvus server at
+// baseURL, e.g. http://localhost:19530, creating it when missing. token,
+// "user:password" or a Zilliz Cloud API key, may be empty for servers
+// without authentication.
+func NewMilvusVectorIndex(ctx context.Context, baseURL, token, collection string, dim int) (*MilvusVectorIndex, error) {
+	headers := map[string]string{}
+	if token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	store, err := newHTTPStore(VectorStoreMilvus, baseURL, headers)
+	if err != nil {
+		return nil, err
+	}
+	m := &MilvusVectorIndex{store: store, collection: collection, dim: dim}
+	m.buf.flush = m.upsert
+	if err := m.ensureCollection(ctx); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// call posts body to the endpoint of the v2 API and decodes the data of the
+// response into out, if not nil. Milvus reports errors with a non-zero code
+// in a 200 response.
+func (m *MilvusVectorIndex) call(ctx context.Context, endpoint string, body map[string]interface{}, out interface{}) error {
+	body["collectionName"] = m.collection
+	var resp struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	found, err := m.store.do(ctx, http.MethodPost, "/v2/vectordb/"+endpoint, body, &resp)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("milvus: %s: not found; is %s a Milvus server with the v2 RESTful API?", endpoint, m.store.baseURL)
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("milvus: %s: %s (code %d)", endpoint, resp.Message, resp.Code)
+	}
+	if out != nil && len(resp.Data) > 0 {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			return fmt.Errorf("milvus: decoding the response to %s: %w", endpoint, err)
+		}
+	}
+	return nil
+}
+
+// ensureCollection creates the collection, or checks that the existing one
+// holds vectors of the index's dimension.
+func (m *MilvusVectorIndex) ensureCollection(ctx context.Context) error {
+	var has struct {
+		Has bool `json:"has"`
+	}
+	if err := m.call(ctx, "collections/has", map[string]interface{}{}, &has); err != nil {
+		return err
+	}
+	if !has.Has {
+		body := map[string]interface{}{
+			"dimension":        m.dim,
+			"metricType":       "IP",
+			"idType":           "VarChar",
+			"primaryFieldName": "id",
+			"vectorFieldName":  "vector",
+			"params":           map[string]string{"max_length": strconv.Itoa(milvusMaxIDLength)},
+		}
+		if err := m.call(ctx, "collections/create", body, nil); err != nil {
+			return fmt.Errorf("milvus: creating collection %s: %w", m.collection, err)
+		}
+		return nil
+	}
+	var desc struct {
+		Fields []struct {
+			Name   string `json:"name"`
+			Params []struct {
+				Key   string      `json:"key"`
+				Value interface{} `json:"value"`
+			} `json:"params"`
+		} `json:"fields"`
+	}
+	if err := m.call(ctx, "collections/describe", map[string]interface{}{}, &desc); err != nil {
+		return err
+	}
+	for _, f := range desc.Fields {
+		if f.Name != "vector" {
+			continue
+		}
+		for _, p := range f.Params {
+			if p.Key == "dim" && fmt.Sprint(p.Value) != strconv.Itoa(m.dim) {
+				dim, _ := strconv.Atoi(fmt.Sprint(p.Value))
+				return fmt.Errorf("milvus: %w", dimensionMismatch("collection "+m.collection, dim, m.dim))
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("milvus: collection %s has no \"vector\" field; was it created by semango?", m.collection)
+}
+
+// milvusIDFilter returns the filter expression matching the given IDs.
+func milvusIDFilter(ids []string) string {
+	quoted := make([]string, len(ids))
+	for i, id := range ids {
+		quoted[i] = strconv.Quote(id)
+	}
+	return "id in [" + strings.Join(quoted, ",") + "]"
+}
+
+// Upsert queues the vector of id; it is sent with the next batch.
+func (m *MilvusVectorIndex) Upsert(ctx context.Context, id string, vector []float32) error {
+	if err := checkDim(vector, m.dim); err != nil {
+		return err
+	}
+	return m.buf.add(ctx, id, vector)
+}
+
+func (m *MilvusVectorIndex) upsert(ctx context.Context, batch []pendingVector) error {
+	data := make([]map[string]interface{}, len(batch))
+	for i, p := range batch {
+		data[i] = map[string]interface{}{"id": p.id, "vector": p.vector}
+	}
+	return m.call(ctx, "entities/upsert", map[string]interface{}{"data": data}, nil)
+}
+
+func (m *MilvusVectorIndex) Search(ctx context.Context, query []float32, topK int) ([]VectorResult, error) {
+	return m.search(ctx, query, topK, "")
+}
+
+// SearchAllowed passes the allowed IDs to Milvus as a filter expression.
+func (m *MilvusVectorIndex) SearchAllowed(ctx context.Context, query []float32, topK int, allowed []string) ([]VectorResult, error) {
+	if len(allowed) == 0 {
+		return nil, nil
+	}
+	return m.search(ctx, query, topK, milvusIDFilter(allowed))
+}
+
+func (m *MilvusVectorIndex) search(ctx context.Context, query []float32, topK int, filter string) ([]VectorResult, error) {
+	if err := m.buf.send(ctx); err != nil {
+		return nil, err
+	}
+	if topK <= 0 {
+		return nil, nil
+	}
+	body := map[string]interface{}{
+		"data":         [][]float32{query},
+		"annsField":    "vector",
+		"limit":        topK,
+		"outputFields": []string{"id"},
+	}
+	if filter != "" {
+		body["filter"] = filter
+	}
+	var hits []struct {
+		ID       string  `json:"id"`
+		Distance float32 `json:"distance"`
+	}
+	if err := m.call(ctx, "entities/search", body, &hits); err != nil {
+		return nil, err
+	}
+	results := make([]VectorResult, len(hits))
+	for i, h := range hits {
+		// With the IP metric, the distance is the inner product.
+		results[i] = VectorResult{ID: h.ID, Score: h.Distance}
+	}
+	return results, nil
+}
+
+// Delete removes the entities of ids after sending the pending upserts,
+// which may include some of them.
+func (m *MilvusVectorIndex) Delete(ctx context.Context, ids []string) error {
+	if err := m.buf.send(ctx); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return m.call(ctx, "entities/delete", map[string]interface{}{"filter": milvusIDFilter(ids)}, nil)
+}
+
+func (m *MilvusVectorIndex) Dimension() int {
+	return m.dim
+}
+
+// Close sends the pending upserts.
+func (m *MilvusVectorIndex) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+	return m.buf.send(ctx)
+}
+
+var _ VectorIndex = (*MilvusVectorIndex)(nil)
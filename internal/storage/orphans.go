@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/omarkamali/semango/internal/util"
+)
+
+// Orphaned vectors are vectors whose label has no ID in the index's ID
+// map, left behind e.g. by an ID map saved without its index after a
+// crash. Searches skip them and note their labels here; the next write to
+// the same index in this process removes them, so searches never modify
+// an index themselves.
+var orphans = struct {
+	sync.Mutex
+	labels map[string]map[int64]bool // by index path
+}{labels: map[string]map[int64]bool{}}
+
+// noteOrphans records orphaned labels found in the index at path and
+// counts them in the semango_vector_orphans_total metric.
+func noteOrphans(path string, labels []int64) {
+	if len(labels) == 0 {
+		return
+	}
+	util.DefaultMetrics.IncCounter("semango_vector_orphans_total", map[string]string{"index": filepath.Base(path)})
+	orphans.Lock()
+	defer orphans.Unlock()
+	set := orphans.labels[path]
+	if set == nil {
+		set = map[int64]bool{}
+		orphans.labels[path] = set
+	}
+	for _, l := range labels {
+		set[l] = true
+	}
+}
+
+// takeOrphans returns and forgets the orphaned labels noted for the index
+// at path, in increasing order.
+func takeOrphans(path string) []int64 {
+	orphans.Lock()
+	set := orphans.labels[path]
+	delete(orphans.labels, path)
+	orphans.Unlock()
+	labels := make([]int64, 0, len(set))
+	for l := range set {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i] < labels[j] })
+	return labels
+}
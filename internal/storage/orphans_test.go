@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrphans(t *testing.T) {
+	noteOrphans("a/faiss.index", []int64{7, 3})
+	noteOrphans("a/faiss.index", []int64{3})
+	noteOrphans("b/faiss.index", nil)
+
+	if got := takeOrphans("a/faiss.index"); !reflect.DeepEqual(got, []int64{3, 7}) {
+		t.Errorf("takeOrphans = %v, want [3 7]", got)
+	}
+	if got := takeOrphans("a/faiss.index"); len(got) != 0 {
+		t.Errorf("orphans taken twice: %v", got)
+	}
+	if got := takeOrphans("b/faiss.index"); len(got) != 0 {
+		t.Errorf("orphans of another index: %v", got)
+	}
+}
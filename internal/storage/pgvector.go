@@ -0,0 +1,229 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultPgvectorDriver is the database/sql driver PgvectorIndex uses when
+// vector_store.driver is unset.
+const DefaultPgvectorDriver = "pgx"
+
+// PgvectorIndex keeps vectors in a PostgreSQL table with the pgvector
+// extension: an "id" text primary key holding chunk IDs and an "embedding"
+// vector column searched by inner product.
+//
+// It talks to PostgreSQL through database/sql. The semango command links
+// github.com/jackc/pgx/v5/stdlib, registered as "pgx"; programs embedding
+// this package import that or another driver, e.g. github.com/lib/pq
+// ("postgres"), and name it in vector_store.driver.
+type PgvectorIndex struct {
+	db    *sql.DB
+	table string // quoted identifier
+	dim   int
+	buf   upsertBuffer
+}
+
+// NewPgvectorIndex connects to the database at dsn with the database/sql
+// driver registered as driver ("" for DefaultPgvectorDriver), enables the
+// vector extension and creates table when missing.
+func NewPgvectorIndex(ctx context.Context, driver, dsn, table string, dim int) (*PgvectorIndex, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("pgvector: vector_store.url is not set")
+	}
+	if driver == "" {
+		driver = DefaultPgvectorDriver
+	}
+	if !driverRegistered(driver) {
+		return nil, fmt.Errorf("pgvector: no database/sql driver %q is linked into this build; set vector_store.driver to one that is, such as \"pgx\"", driver)
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("pgvector: %w", err)
+	}
+	p := &PgvectorIndex{db: db, table: quoteIdent(table), dim: dim}
+	p.buf.flush = p.upsert
+	if err := p.ensureTable(ctx, table); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+func driverRegistered(name string) bool {
+	for _, d := range sql.Drivers() {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureTable creates the table, or checks that the existing one holds
+// vectors of the index's dimension.
+func (p *PgvectorIndex) ensureTable(ctx context.Context, table string) error {
+	if _, err := p.db.ExecContext(ctx, "CREATE EXTENSION IF NOT EXISTS vector"); err != nil {
+		return fmt.Errorf("pgvector: enabling the vector extension: %w", err)
+	}
+	create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id text PRIMARY KEY, embedding vector(%d) NOT NULL)", p.table, p.dim)
+	if _, err := p.db.ExecContext(ctx, create); err != nil {
+		return fmt.Errorf("pgvector: creating table %s: %w", table, err)
+	}
+	// The type modifier of a vector column is its dimension.
+	var dim int
+	err := p.db.QueryRowContext(ctx,
+		"SELECT atttypmod FROM pg_attribute WHERE attrelid = $1::regclass AND attname = 'embedding'",
+		p.table).Scan(&dim)
+	if err != nil {
+		return fmt.Errorf("pgvector: reading the dimension of table %s: %w", table, err)
+	}
+	if dim != p.dim {
+		return fmt.Errorf("pgvector: %w", dimensionMismatch("table "+table, dim, p.dim))
+	}
+	return nil
+}
+
+// quoteIdent quotes name as a PostgreSQL identifier.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// vectorLiteral formats v as a pgvector value, e.g. [1,0.5].
+func vectorLiteral(v []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// textArrayLiteral formats values as a PostgreSQL text[] value, so that
+// lists pass as one parameter whatever the driver.
+func textArrayLiteral(values []string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('"')
+		for _, r := range v {
+			if r == '"' || r == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// Upsert queues the vector of id; it is written with the next batch.
+func (p *PgvectorIndex) Upsert(ctx context.Context, id string, vector []float32) error {
+	if err := checkDim(vector, p.dim); err != nil {
+		return err
+	}
+	return p.buf.add(ctx, id, vector)
+}
+
+func (p *PgvectorIndex) upsert(ctx context.Context, batch []pendingVector) error {
+	values := make([]string, 0, len(batch))
+	args := make([]interface{}, 0, 2*len(batch))
+	for _, v := range batch {
+		values = append(values, fmt.Sprintf("($%d, $%d::vector)", len(args)+1, len(args)+2))
+		args = append(args, v.id, vectorLiteral(v.vector))
+	}
+	stmt := fmt.Sprintf("INSERT INTO %s (id, embedding) VALUES %s ON CONFLICT (id) DO UPDATE SET embedding = EXCLUDED.embedding",
+		p.table, strings.Join(values, ", "))
+	if _, err := p.db.ExecContext(ctx, stmt, args...); err != nil {
+		return fmt.Errorf("pgvector: %w", err)
+	}
+	return nil
+}
+
+func (p *PgvectorIndex) Search(ctx context.Context, query []float32, topK int) ([]VectorResult, error) {
+	return p.search(ctx, query, topK, nil)
+}
+
+// SearchAllowed restricts the search to the allowed IDs in SQL.
+func (p *PgvectorIndex) SearchAllowed(ctx context.Context, query []float32, topK int, allowed []string) ([]VectorResult, error) {
+	if len(allowed) == 0 {
+		return nil, nil
+	}
+	return p.search(ctx, query, topK, allowed)
+}
+
+func (p *PgvectorIndex) search(ctx context.Context, query []float32, topK int, allowed []string) ([]VectorResult, error) {
+	if err := p.buf.send(ctx); err != nil {
+		return nil, err
+	}
+	if topK <= 0 {
+		return nil, nil
+	}
+	// <#> is the negative inner product.
+	args := []interface{}{vectorLiteral(query), topK}
+	where := ""
+	if allowed != nil {
+		where = "WHERE id = ANY($3::text[])"
+		args = append(args, textArrayLiteral(allowed))
+	}
+	stmt := fmt.Sprintf("SELECT id, -(embedding <#> $1::vector) FROM %s %s ORDER BY embedding <#> $1::vector LIMIT $2", p.table, where)
+	rows, err := p.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("pgvector: %w", err)
+	}
+	defer rows.Close()
+	var results []VectorResult
+	for rows.Next() {
+		var r VectorResult
+		var score float64
+		if err := rows.Scan(&r.ID, &score); err != nil {
+			return nil, fmt.Errorf("pgvector: %w", err)
+		}
+		r.Score = float32(score)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// Delete removes the rows of ids after writing the pending upserts, which
+// may include some of them.
+func (p *PgvectorIndex) Delete(ctx context.Context, ids []string) error {
+	if err := p.buf.send(ctx); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	stmt := fmt.Sprintf("DELETE FROM %s WHERE id = ANY($1::text[])", p.table)
+	if _, err := p.db.ExecContext(ctx, stmt, textArrayLiteral(ids)); err != nil {
+		return fmt.Errorf("pgvector: %w", err)
+	}
+	return nil
+}
+
+func (p *PgvectorIndex) Dimension() int {
+	return p.dim
+}
+
+// Close writes the pending upserts and closes the connection pool.
+func (p *PgvectorIndex) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+	err := p.buf.send(ctx)
+	if cerr := p.db.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+var _ VectorIndex = (*PgvectorIndex)(nil)
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+)
+
+// QdrantVectorIndex keeps vectors in a Qdrant collection, through Qdrant's
+// HTTP API. Qdrant point IDs are integers or UUIDs, so each chunk ID is
+// mapped to a name-based UUID and kept in the "chunk_id" payload field.
+type QdrantVectorIndex struct {
+	store      *httpStore
+	collection string
+	dim        int
+	buf        upsertBuffer
+}
+
+// NewQdrantVectorIndex opens the collection of the Qdrant server at
+// baseURL, e.g. http://localhost:6333, creating it with dot-product
+// distance when missing. apiKey may be empty for servers without
+// authentication.
+func NewQdrantVectorIndex(ctx context.Context, baseURL, apiKey, collection string, dim int) (*QdrantVectorIndex, error) {
+	headers := map[string]string{}
+	if apiKey != "" {
+		headers["api-key"] = apiKey
+	}
+	store, err := newHTTPStore(VectorStoreQdrant, baseURL, headers)
+	if err != nil {
+		return nil, err
+	}
+	q := &QdrantVectorIndex{store: store, collection: collection, dim: dim}
+	q.buf.flush = q.upsert
+	if err := q.ensureCollection(ctx); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *QdrantVectorIndex) path(suffix string) string {
+	return "/collections/" + url.PathEscape(q.collection) + suffix
+}
+
+// ensureCollection creates the collection, or checks that the existing one
+// holds vectors of the index's dimension.
+func (q *QdrantVectorIndex) ensureCollection(ctx context.Context) error {
+	var info struct {
+		Result struct {
+			Config struct {
+				Params struct {
+					Vectors struct {
+						Size int `json:"size"`
+					} `json:"vectors"`
+				} `json:"params"`
+			} `json:"config"`
+		} `json:"result"`
+	}
+	found, err := q.store.do(ctx, http.MethodGet, q.path(""), nil, &info)
+	if err != nil {
+		return err
+	}
+	if found {
+		if size := info.Result.Config.Params.Vectors.Size; size != q.dim {
+			return fmt.Errorf("qdrant: %w", dimensionMismatch("collection "+q.collection, size, q.dim))
+		}
+		return nil
+	}
+	body := map[string]interface{}{
+		"vectors": map[string]interface{}{"size": q.dim, "distance": "Dot"},
+	}
+	if _, err := q.store.do(ctx, http.MethodPut, q.path(""), body, nil); err != nil {
+		return fmt.Errorf("qdrant: creating collection %s: %w", q.collection, err)
+	}
+	return nil
+}
+
+// qdrantPointID returns the Qdrant point ID of a chunk ID.
+func qdrantPointID(id string) string {
+	return uuid.NewSHA1(uuid.Nil, []byte(id)).String()
+}
+
+// Upsert queues the vector of id; it is sent with the next batch.
+func (q *QdrantVectorIndex) Upsert(ctx context.Context, id string, vector []float32) error {
+	if err := checkDim(vector, q.dim); err != nil {
+		return err
+	}
+	return q.buf.add(ctx, id, vector)
+}
+
+func (q *QdrantVectorIndex) upsert(ctx context.Context, batch []pendingVector) error {
+	points := make([]map[string]interface{}, len(batch))
+	for i, p := range batch {
+		points[i] = map[string]interface{}{
+			"id":      qdrantPointID(p.id),
+			"vector":  p.vector,
+			"payload": map[string]string{"chunk_id": p.id},
+		}
+	}
+	_, err := q.store.do(ctx, http.MethodPut, q.path("/points?wait=true"), map[string]interface{}{"points": points}, nil)
+	return err
+}
+
+func (q *QdrantVectorIndex) Search(ctx context.Context, query []float32, topK int) ([]VectorResult, error) {
+	return q.search(ctx, query, topK, nil)
+}
+
+// SearchAllowed passes the allowed IDs to Qdrant as a has_id filter.
+func (q *QdrantVectorIndex) SearchAllowed(ctx context.Context, query []float32, topK int, allowed []string) ([]VectorResult, error) {
+	if len(allowed) == 0 {
+		return nil, nil
+	}
+	points := make([]string, len(allowed))
+	for i, id := range allowed {
+		points[i] = qdrantPointID(id)
+	}
+	filter := map[string]interface{}{
+		"must": []interface{}{map[string]interface{}{"has_id": points}},
+	}
+	return q.search(ctx, query, topK, filter)
+}
+
+func (q *QdrantVectorIndex) search(ctx context.Context, query []float32, topK int, filter interface{}) ([]VectorResult, error) {
+	if err := q.buf.send(ctx); err != nil {
+		return nil, err
+	}
+	if topK <= 0 {
+		return nil, nil
+	}
+	body := map[string]interface{}{
+		"vector":       query,
+		"limit":        topK,
+		"with_payload": []string{"chunk_id"},
+	}
+	if filter != nil {
+		body["filter"] = filter
+	}
+	var resp struct {
+		Result []struct {
+			Score   float32 `json:"score"`
+			Payload struct {
+				ChunkID string `json:"chunk_id"`
+			} `json:"payload"`
+		} `json:"result"`
+	}
+	found, err := q.store.do(ctx, http.MethodPost, q.path("/points/search"), body, &resp)
+	if err != nil || !found {
+		return nil, err
+	}
+	results := make([]VectorResult, 0, len(resp.Result))
+	for _, r := range resp.Result {
+		if r.Payload.ChunkID != "" {
+			results = append(results, VectorResult{ID: r.Payload.ChunkID, Score: r.Score})
+		}
+	}
+	return results, nil
+}
+
+// Delete removes the points of ids after sending the pending upserts, which
+// may include some of them.
+func (q *QdrantVectorIndex) Delete(ctx context.Context, ids []string) error {
+	if err := q.buf.send(ctx); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	points := make([]string, len(ids))
+	for i, id := range ids {
+		points[i] = qdrantPointID(id)
+	}
+	_, err := q.store.do(ctx, http.MethodPost, q.path("/points/delete?wait=true"), map[string]interface{}{"points": points}, nil)
+	return err
+}
+
+func (q *QdrantVectorIndex) Dimension() int {
+	return q.dim
+}
+
+// Close sends the pending upserts.
+func (q *QdrantVectorIndex) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+	return q.buf.send(ctx)
+}
+
+var _ VectorIndex = (*QdrantVectorIndex)(nil)
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// Occur says whether a clause of a LexicalQuery may, must or must not match.
+type Occur int
+
+const (
+	Should  Occur = iota // raises the score when it matches
+	Must                 // written +term
+	MustNot              // written -term
+)
+
+// MaxFuzziness is the largest edit distance a fuzzy term may ask for.
+const MaxFuzziness = 2
+
+// Clause is one term, phrase or metadata match of a LexicalQuery.
+type Clause struct {
+	Occur Occur
+	// Field is the metadata key the clause matches, as in lang:de; empty
+	// for the chunk text.
+	Field string
+	// Text is the term, or the words of a phrase.
+	Text   string
+	Phrase bool
+	// Fuzziness is the edit distance of a fuzzy term, as in color~1; 0
+	// matches exactly.
+	Fuzziness int
+}
+
+// LexicalQuery is a query in the advanced lexical syntax: words separated
+// by white space, each optionally prefixed with + (required) or -
+// (excluded). "Quoted words" match as a phrase, key:value or key:"a b"
+// matches the metadata key, and term~ or term~2 matches terms within one or
+// two edits. Words without a prefix are optional and rank documents
+// containing more of them higher.
+type LexicalQuery []Clause
+
+// ParseLexicalQuery parses s in the advanced lexical syntax.
+func ParseLexicalQuery(s string) (LexicalQuery, error) {
+	var q LexicalQuery
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeftFunc(s, unicode.IsSpace) {
+		var c Clause
+		switch s[0] {
+		case '+':
+			c.Occur, s = Must, s[1:]
+		case '-':
+			c.Occur, s = MustNot, s[1:]
+		}
+		if field, rest, ok := cutField(s); ok {
+			c.Field, s = field, rest
+		}
+		var err error
+		if strings.HasPrefix(s, `"`) {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				return nil, errors.New("unterminated phrase: missing closing quote")
+			}
+			c.Text, c.Phrase, s = strings.TrimSpace(s[1:end+1]), true, s[end+2:]
+		} else {
+			end := strings.IndexFunc(s, unicode.IsSpace)
+			if end < 0 {
+				end = len(s)
+			}
+			c.Text, s = s[:end], s[end:]
+			if c.Text, c.Fuzziness, err = cutFuzziness(c.Text); err != nil {
+				return nil, err
+			}
+		}
+		if strings.HasPrefix(s, "~") {
+			return nil, errors.New("fuzzy matching applies to single terms, not phrases")
+		}
+		if c.Text == "" {
+			continue // a lone +, - or ""
+		}
+		if c.Fuzziness > 0 && c.Field != "" {
+			return nil, fmt.Errorf("fuzzy matching applies to the text, not to metadata key %q", c.Field)
+		}
+		q = append(q, c)
+	}
+	if len(q) == 0 {
+		return nil, errors.New("empty query")
+	}
+	return q, nil
+}
+
+// cutField splits a leading key: off s. The key must start with a letter
+// and the value must follow directly, so "http://..." or "a: b" are words.
+func cutField(s string) (field, rest string, ok bool) {
+	i := strings.IndexByte(s, ':')
+	if i <= 0 || i+1 == len(s) || strings.ContainsAny(s[i+1:i+2], ": /\t\n") {
+		return "", s, false
+	}
+	for j, r := range s[:i] {
+		if !(unicode.IsLetter(r) || j > 0 && (unicode.IsDigit(r) || r == '_' || r == '.' || r == '-')) {
+			return "", s, false
+		}
+	}
+	return s[:i], s[i+1:], true
+}
+
+// cutFuzziness splits a trailing ~ or ~N off term.
+func cutFuzziness(term string) (string, int, error) {
+	i := strings.LastIndexByte(term, '~')
+	if i <= 0 {
+		return term, 0, nil
+	}
+	if i == len(term)-1 {
+		return term[:i], 1, nil
+	}
+	n, err := strconv.Atoi(term[i+1:])
+	if err != nil {
+		return term, 0, nil // not an edit distance, part of the word
+	}
+	if n < 1 || n > MaxFuzziness {
+		return "", 0, fmt.Errorf("fuzziness must be between 1 and %d", MaxFuzziness)
+	}
+	return term[:i], n, nil
+}
+
+// Text returns the words the query looks for in the chunk text, without
+// excluded ones or metadata values, e.g. to embed or to highlight.
+func (q LexicalQuery) Text() string {
+	var words []string
+	for _, c := range q {
+		if c.Occur != MustNot && c.Field == "" {
+			words = append(words, c.Text)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// WithTerms returns q with optional terms added, e.g. by query expansion.
+func (q LexicalQuery) WithTerms(terms ...string) LexicalQuery {
+	out := append(LexicalQuery(nil), q...)
+	for _, t := range terms {
+		out = append(out, Clause{Text: t})
+	}
+	return out
+}
+
+// bleveQuery builds the Bleve query for q. Terms are matched by textQuery,
+// like SearchTextFiltered matches a whole query, phrases in the text and the
+// field for lang, fuzzy terms in the text alone and metadata values as
+// phrases in their "meta.<key>" field.
+func (q LexicalQuery) bleveQuery(textQuery func(text, lang string) query.Query, lang string) query.Query {
+	bq := bleve.NewBooleanQuery()
+	for _, c := range q {
+		var cq query.Query
+		switch {
+		case c.Field != "":
+			mq := bleve.NewMatchPhraseQuery(c.Text)
+			mq.SetField("meta." + c.Field)
+			cq = mq
+		case c.Phrase:
+			cq = phraseQuery(c.Text, lang)
+		case c.Fuzziness > 0:
+			fq := bleve.NewFuzzyQuery(strings.ToLower(c.Text))
+			fq.SetField("text")
+			fq.SetFuzziness(c.Fuzziness)
+			cq = fq
+		default:
+			cq = textQuery(c.Text, lang)
+		}
+		switch c.Occur {
+		case Must:
+			bq.AddMust(cq)
+		case MustNot:
+			bq.AddMustNot(cq)
+		default:
+			bq.AddShould(cq)
+		}
+	}
+	return bq
+}
+
+// phraseQuery matches phrase in the text and, when lang has an analyzer,
+// in the text analysed for it.
+func phraseQuery(phrase, lang string) query.Query {
+	pq := bleve.NewMatchPhraseQuery(phrase)
+	pq.SetField("text")
+	analyzer := LexicalAnalyzer(lang)
+	if analyzer == "" {
+		return pq
+	}
+	localized := bleve.NewMatchPhraseQuery(phrase)
+	localized.SetField(langField(analyzer))
+	localized.Analyzer = analyzer
+	return bleve.NewDisjunctionQuery(pq, localized)
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParseLexicalQuery(t *testing.T) {
+	q, err := ParseLexicalQuery(`+"connection pool" -mysql lang:de title:"Read me" colour~ timeout~2 https://example.com`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := LexicalQuery{
+		{Occur: Must, Text: "connection pool", Phrase: true},
+		{Occur: MustNot, Text: "mysql"},
+		{Field: "lang", Text: "de"},
+		{Field: "title", Text: "Read me", Phrase: true},
+		{Text: "colour", Fuzziness: 1},
+		{Text: "timeout", Fuzziness: 2},
+		{Text: "https://example.com"},
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("ParseLexicalQuery =\n%+v\nwant\n%+v", q, want)
+	}
+	if got := q.Text(); got != "connection pool colour timeout https://example.com" {
+		t.Errorf("Text = %q", got)
+	}
+
+	for _, bad := range []string{``, ` + - `, `"unterminated`, `"a b"~2`, `word~3`, `lang:de~`} {
+		if _, err := ParseLexicalQuery(bad); err == nil {
+			t.Errorf("ParseLexicalQuery(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestBleveIndex_SearchQuery(t *testing.T) {
+	idx, err := OpenOrCreateBleveIndex(t.TempDir() + "/query.bleve")
+	if err != nil {
+		t.Fatalf("failed to open/create index: %v", err)
+	}
+	defer idx.Close()
+
+	docs := map[string]struct {
+		text string
+		meta map[string]string
+	}{
+		"a": {"the connection pool is exhausted under load", map[string]string{"lang": "en"}},
+		"b": {"pool the connection before load tests", map[string]string{"lang": "en"}},
+		"c": {"mysql connection pool settings", map[string]string{"lang": "de"}},
+		"d": {"tuning the colour of the dashboard", map[string]string{"lang": "en"}},
+	}
+	for id, d := range docs {
+		if err := idx.IndexDocument(id, d.text, d.meta); err != nil {
+			t.Fatalf("failed to index document: %v", err)
+		}
+	}
+
+	for query, want := range map[string][]string{
+		`"connection pool"`:         {"a", "c"},
+		`+"connection pool" -mysql`: {"a"},
+		`+connection +lang:de`:      {"c"},
+		`colr~`:                     nil,
+		`color~`:                    {"d"},
+		`+pool +load`:               {"a", "b"},
+		`-mysql`:                    {"a", "b", "d"},
+	} {
+		q, err := ParseLexicalQuery(query)
+		if err != nil {
+			t.Fatalf("ParseLexicalQuery(%q): %v", query, err)
+		}
+		hits, err := idx.SearchQueryFiltered(context.Background(), q, "", nil, 10)
+		if err != nil {
+			t.Fatalf("search %q failed: %v", query, err)
+		}
+		var got []string
+		for _, h := range hits {
+			got = append(got, h.ID)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("search %q = %v, want %v", query, got, want)
+		}
+	}
+}
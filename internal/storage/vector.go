@@ -1,6 +1,89 @@
 package storage
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// VectorIndexPath returns the FAISS index file of a vector space in the
+// index directory dir: faiss.index for the default space when space is
+// empty, faiss-<space>.index otherwise. It is the layout of restored index
+// bundles; configured indexes are found with
+// SpaceIndexPath(cfg.VectorIndexPath(), space).
+func VectorIndexPath(dir, space string) string {
+	return SpaceIndexPath(filepath.Join(dir, "faiss.index"), space)
+}
+
+// SpaceIndexPath returns the FAISS index file of a vector space given that
+// of the default space, path: path itself when space is empty, otherwise
+// path with "-<space>" inserted before its extension, e.g. faiss.index
+// becomes faiss-minilm.index.
+func SpaceIndexPath(path, space string) string {
+	if space == "" {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + space + ext
+}
+
+// ErrCorruptIndex reports a vector index that exists on disk but cannot be
+// loaded, or whose ID map is unreadable. Such an index is never replaced
+// silently, since searches would quietly return nothing; move it aside with
+// RecreateVectorIndex and re-index.
+var ErrCorruptIndex = errors.New("vector index is corrupt")
+
+// ErrNoVectorIndex reports a vector index that cannot be searched because
+// it was never built. Searches fall back to the lexical index.
+var ErrNoVectorIndex = errors.New("no vector index")
+
+// ErrDimensionMismatch reports a vector index that holds vectors of another
+// dimension than the embedder produces, e.g. after embedding.model or
+// embedding.dimensions changed without re-indexing.
+var ErrDimensionMismatch = errors.New("embedding dimension does not match the vector index")
+
+// dimensionMismatch returns ErrDimensionMismatch for what, holding vectors
+// of dimension indexDim, with a hint on how to resolve it.
+func dimensionMismatch(what string, indexDim, dim int) error {
+	return fmt.Errorf("%w: %s holds vectors of dimension %d, the embedder produces %d; "+
+		"use the embedding.model and embedding.dimensions the index was built with, or rebuild it with semango index --recreate",
+		ErrDimensionMismatch, what, indexDim, dim)
+}
+
+// CheckVectorIndex reports whether the vector index at path can be opened
+// for searching: an error wrapping ErrNoVectorIndex if it does not exist.
+func CheckVectorIndex(path string) error {
+	_, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s does not exist; run semango index to build it", ErrNoVectorIndex, path)
+	}
+	return err
+}
+
+// RecreateVectorIndex moves the vector index at path, its ID map and its
+// model fingerprint aside, to <path>.corrupt-<time>, so that the next write
+// starts an empty index. It returns where the index file was moved, or ""
+// if there was none.
+func RecreateVectorIndex(path string) (string, error) {
+	suffix := fmt.Sprintf(".corrupt-%d", time.Now().Unix())
+	moved := ""
+	for _, p := range []string{path, JSONIDMapPath(path), IndexModelPath(path)} {
+		if _, err := os.Stat(p); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err := os.Rename(p, p+suffix); err != nil {
+			return moved, err
+		}
+		if p == path {
+			moved = p + suffix
+		}
+	}
+	return moved, nil
+}
 
 // VectorResult defines the result structure for vector search.
 type VectorResult struct {
@@ -12,6 +95,12 @@ type VectorResult struct {
 type VectorIndex interface {
 	Upsert(ctx context.Context, id string, vector []float32) error
 	Search(ctx context.Context, query []float32, topK int) ([]VectorResult, error)
+	// SearchAllowed is like Search but only considers the given IDs. The
+	// allowlist is applied inside the nearest-neighbour search (e.g. a FAISS
+	// IDSelector), so filtered queries do not need to over-fetch.
+	SearchAllowed(ctx context.Context, query []float32, topK int, allowed []string) ([]VectorResult, error)
+	// Delete removes the vectors of the given IDs; unknown IDs are ignored.
+	Delete(ctx context.Context, ids []string) error
 	Dimension() int
 	Close() error
 }
@@ -23,5 +112,9 @@ func (n *NoopVectorIndex) Upsert(ctx context.Context, id string, vector []float3
 func (n *NoopVectorIndex) Search(ctx context.Context, query []float32, topK int) ([]VectorResult, error) {
 	return nil, nil
 }
-func (n *NoopVectorIndex) Dimension() int { return 0 }
-func (n *NoopVectorIndex) Close() error   { return nil }
+func (n *NoopVectorIndex) SearchAllowed(ctx context.Context, query []float32, topK int, allowed []string) ([]VectorResult, error) {
+	return nil, nil
+}
+func (n *NoopVectorIndex) Delete(ctx context.Context, ids []string) error { return nil }
+func (n *NoopVectorIndex) Dimension() int                                 { return 0 }
+func (n *NoopVectorIndex) Close() error                                   { return nil }
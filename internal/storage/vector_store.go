@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+// Values of vector_store.backend.
+const (
+	VectorStoreFAISS    = "faiss"
+	VectorStoreQdrant   = "qdrant"
+	VectorStorePgvector = "pgvector"
+	VectorStoreMilvus   = "milvus"
+)
+
+// remoteBatchSize is how many upserts a remote vector index collects before
+// sending them in one request.
+const remoteBatchSize = 256
+
+// remoteTimeout bounds a single request to a remote vector store.
+const remoteTimeout = 30 * time.Second
+
+// IsRemoteVectorStore reports whether store keeps vectors in a vector
+// database rather than in FAISS index files.
+func IsRemoteVectorStore(store config.VectorStoreConfig) bool {
+	return store.Backend != "" && store.Backend != VectorStoreFAISS
+}
+
+// VectorCollection returns the collection, or table, holding the vectors of
+// space ("" for the default one) in a remote vector store.
+func VectorCollection(store config.VectorStoreConfig, space string) string {
+	name := store.CollectionName()
+	if space != "" {
+		name += "_" + space
+	}
+	return name
+}
+
+// OpenVectorIndex opens the vector index of space ("" for the default one)
+// for vectors of dimension dim: the FAISS index at path, which writes
+// convert to the compression set in vec, or the space's collection in the
+// remote store selected by vector_store, which is created when missing.
+func OpenVectorIndex(ctx context.Context, store config.VectorStoreConfig, vec config.VectorConfig, path, space string, dim int) (VectorIndex, error) {
+	collection := VectorCollection(store, space)
+	var apiKey string
+	if store.APIKeyEnv != "" {
+		apiKey = os.Getenv(store.APIKeyEnv)
+	}
+	var (
+		idx VectorIndex
+		err error
+	)
+	// Each constructor's result is only stored on success, so that a
+	// failure returns a nil interface rather than a nil pointer in one.
+	switch store.Backend {
+	case "", VectorStoreFAISS:
+		return openFaissVectors(ctx, path, dim, vec)
+	case VectorStoreQdrant:
+		var q *QdrantVectorIndex
+		if q, err = NewQdrantVectorIndex(ctx, store.URL, apiKey, collection, dim); err == nil {
+			idx = q
+		}
+	case VectorStoreMilvus:
+		var m *MilvusVectorIndex
+		if m, err = NewMilvusVectorIndex(ctx, store.URL, apiKey, collection, dim); err == nil {
+			idx = m
+		}
+	case VectorStorePgvector:
+		var p *PgvectorIndex
+		if p, err = NewPgvectorIndex(ctx, store.Driver, store.URL, collection, dim); err == nil {
+			idx = p
+		}
+	default:
+		err = fmt.Errorf("unknown vector_store.backend %q", store.Backend)
+	}
+	return idx, err
+}
+
+// CheckVectorStore returns nil if the vector index of space can be
+// searched. FAISS indexes are checked like CheckVectorIndex does; remote
+// stores are reached when the index is opened.
+func CheckVectorStore(store config.VectorStoreConfig, path string) error {
+	if IsRemoteVectorStore(store) {
+		return nil
+	}
+	return CheckVectorIndex(path)
+}
+
+// pendingVector is an upsert a remote vector index has not sent yet.
+type pendingVector struct {
+	id     string
+	vector []float32
+}
+
+// upsertBuffer collects upserts for a remote vector index, which sends them
+// in batches with flush: before searching, deleting and closing, and when
+// remoteBatchSize are pending.
+type upsertBuffer struct {
+	pending []pendingVector
+	flush   func(ctx context.Context, batch []pendingVector) error
+}
+
+func (b *upsertBuffer) add(ctx context.Context, id string, vector []float32) error {
+	b.pending = append(b.pending, pendingVector{id: id, vector: append([]float32(nil), vector...)})
+	if len(b.pending) >= remoteBatchSize {
+		return b.send(ctx)
+	}
+	return nil
+}
+
+// send flushes the pending upserts. Of an ID upserted twice, only the last
+// vector is sent, since stores may reject a batch that repeats a key.
+func (b *upsertBuffer) send(ctx context.Context) error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	last := make(map[string]int, len(b.pending))
+	for i, p := range b.pending {
+		last[p.id] = i
+	}
+	batch := b.pending[:0]
+	for i, p := range b.pending {
+		if last[p.id] == i {
+			batch = append(batch, p)
+		}
+	}
+	err := b.flush(ctx, batch)
+	b.pending = b.pending[:0]
+	return err
+}
+
+// checkDim returns an error if vector does not have dimension dim.
+func checkDim(vector []float32, dim int) error {
+	if len(vector) != dim {
+		return fmt.Errorf("vector has dimension %d, the index holds %d", len(vector), dim)
+	}
+	return nil
+}
+
+// httpStore sends the JSON requests of the HTTP vector stores.
+type httpStore struct {
+	name    string // for errors, e.g. "qdrant"
+	baseURL string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newHTTPStore(name, baseURL string, headers map[string]string) (*httpStore, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("%s: vector_store.url is not set", name)
+	}
+	return &httpStore{
+		name:    name,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		headers: headers,
+		client:  &http.Client{Timeout: remoteTimeout},
+	}, nil
+}
+
+// do sends body, if not nil, as JSON with method to path and decodes the
+// JSON response into out, if not nil. It reports false for a 404 response;
+// other responses than 2xx are errors.
+func (s *httpStore) do(ctx context.Context, method, path string, body, out interface{}) (found bool, err error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return false, err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, r)
+	if err != nil {
+		return false, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", s.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("%s: %s %s: %s: %s", s.name, method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return false, fmt.Errorf("%s: decoding the response to %s %s: %w", s.name, method, path, err)
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,344 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+// fakeVectors is the state of a fake vector database: vectors by ID.
+type fakeVectors struct {
+	mu      sync.Mutex
+	dim     int // 0 until the collection is created
+	vectors map[string][]float32
+	batches int // upsert requests
+}
+
+// search returns the IDs among allowed (all when nil) by decreasing dot
+// product with query, and their scores.
+func (f *fakeVectors) search(query []float32, limit int, allowed map[string]bool) ([]string, []float32) {
+	type hit struct {
+		id    string
+		score float32
+	}
+	var hits []hit
+	for id, v := range f.vectors {
+		if allowed != nil && !allowed[id] {
+			continue
+		}
+		var s float32
+		for i := range v {
+			s += v[i] * query[i]
+		}
+		hits = append(hits, hit{id, s})
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	ids, scores := make([]string, len(hits)), make([]float32, len(hits))
+	for i, h := range hits {
+		ids[i], scores[i] = h.id, h.score
+	}
+	return ids, scores
+}
+
+// newFakeQdrant serves the part of Qdrant's HTTP API QdrantVectorIndex uses
+// for the collection "docs", keyed by point ID.
+func newFakeQdrant(t *testing.T, f *fakeVectors) *httptest.Server {
+	chunkIDs := map[string]string{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if r.Header.Get("api-key") != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Vectors struct{ Size int } `json:"vectors"`
+			Points  json.RawMessage    `json:"points"`
+			Vector  []float32          `json:"vector"`
+			Limit   int                `json:"limit"`
+			Filter  *struct {
+				Must []struct {
+					HasID []string `json:"has_id"`
+				} `json:"must"`
+			} `json:"filter"`
+		}
+		if r.Body != nil {
+			json.NewDecoder(r.Body).Decode(&body)
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /collections/docs":
+			if f.dim == 0 {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"result": map[string]interface{}{"config": map[string]interface{}{"params": map[string]interface{}{
+					"vectors": map[string]interface{}{"size": f.dim, "distance": "Dot"},
+				}}},
+			})
+		case "PUT /collections/docs":
+			f.dim = body.Vectors.Size
+			w.Write([]byte(`{"result":true}`))
+		case "PUT /collections/docs/points":
+			var points []struct {
+				ID      string
+				Vector  []float32
+				Payload struct {
+					ChunkID string `json:"chunk_id"`
+				}
+			}
+			json.Unmarshal(body.Points, &points)
+			for _, p := range points {
+				f.vectors[p.ID] = p.Vector
+				chunkIDs[p.ID] = p.Payload.ChunkID
+			}
+			f.batches++
+			w.Write([]byte(`{"result":{}}`))
+		case "POST /collections/docs/points/delete":
+			var points []string
+			json.Unmarshal(body.Points, &points)
+			for _, p := range points {
+				delete(f.vectors, p)
+			}
+			w.Write([]byte(`{"result":{}}`))
+		case "POST /collections/docs/points/search":
+			var allowed map[string]bool
+			if body.Filter != nil {
+				allowed = map[string]bool{}
+				for _, id := range body.Filter.Must[0].HasID {
+					allowed[id] = true
+				}
+			}
+			ids, scores := f.search(body.Vector, body.Limit, allowed)
+			var result []map[string]interface{}
+			for i, id := range ids {
+				result = append(result, map[string]interface{}{
+					"id": id, "score": scores[i], "payload": map[string]string{"chunk_id": chunkIDs[id]},
+				})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// newFakeMilvus serves the part of the Milvus v2 RESTful API
+// MilvusVectorIndex uses, for any collection name.
+func newFakeMilvus(t *testing.T, f *fakeVectors) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		var body struct {
+			Dimension int               `json:"dimension"`
+			Data      []json.RawMessage `json:"data"`
+			Filter    string            `json:"filter"`
+			Limit     int               `json:"limit"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		reply := func(data interface{}) {
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "data": data})
+		}
+		// filterIDs parses the id in [...] filters MilvusVectorIndex writes.
+		filterIDs := func() map[string]bool {
+			if body.Filter == "" {
+				return nil
+			}
+			var ids []string
+			list := strings.TrimSuffix(strings.TrimPrefix(body.Filter, "id in ["), "]")
+			if err := json.Unmarshal([]byte("["+list+"]"), &ids); err != nil {
+				t.Errorf("bad filter %q: %v", body.Filter, err)
+			}
+			allowed := map[string]bool{}
+			for _, id := range ids {
+				allowed[id] = true
+			}
+			return allowed
+		}
+		switch r.URL.Path {
+		case "/v2/vectordb/collections/has":
+			reply(map[string]bool{"has": f.dim != 0})
+		case "/v2/vectordb/collections/create":
+			f.dim = body.Dimension
+			reply(map[string]interface{}{})
+		case "/v2/vectordb/collections/describe":
+			reply(map[string]interface{}{"fields": []map[string]interface{}{
+				{"name": "id", "type": "VarChar"},
+				{"name": "vector", "type": "FloatVector", "params": []map[string]interface{}{{"key": "dim", "value": f.dim}}},
+			}})
+		case "/v2/vectordb/entities/upsert":
+			for _, d := range body.Data {
+				var e struct {
+					ID     string
+					Vector []float32
+				}
+				json.Unmarshal(d, &e)
+				f.vectors[e.ID] = e.Vector
+			}
+			f.batches++
+			reply(map[string]int{"upsertCount": len(body.Data)})
+		case "/v2/vectordb/entities/delete":
+			for id := range filterIDs() {
+				delete(f.vectors, id)
+			}
+			reply(map[string]interface{}{})
+		case "/v2/vectordb/entities/search":
+			var query []float32
+			json.Unmarshal(body.Data[0], &query)
+			ids, scores := f.search(query, body.Limit, filterIDs())
+			var hits []map[string]interface{}
+			for i, id := range ids {
+				hits = append(hits, map[string]interface{}{"id": id, "distance": scores[i]})
+			}
+			reply(hits)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": 1100, "message": "no such endpoint"})
+		}
+	}))
+}
+
+// exerciseVectorIndex runs the VectorIndex operations the pipeline and the
+// searcher use against idx, which holds vectors of dimension 2.
+func exerciseVectorIndex(t *testing.T, idx VectorIndex, f *fakeVectors) {
+	t.Helper()
+	ctx := context.Background()
+	for id, v := range map[string][]float32{"a#0": {1, 0}, "b#0": {0, 1}, "c#0": {0.6, 0.8}} {
+		if err := idx.Upsert(ctx, id, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := idx.Upsert(ctx, "b#0", []float32{1, 0, 0}); err == nil {
+		t.Error("a vector of the wrong dimension was accepted")
+	}
+	if f.batches != 0 {
+		t.Errorf("upserts were sent before a search: %d batches", f.batches)
+	}
+
+	got, err := idx.Search(ctx, []float32{1, 0}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.batches != 1 || len(got) != 2 || got[0].ID != "a#0" || got[0].Score != 1 || got[1].ID != "c#0" {
+		t.Errorf("Search = %+v after %d batches", got, f.batches)
+	}
+	got, err = idx.SearchAllowed(ctx, []float32{1, 0}, 5, []string{"b#0", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != "b#0" {
+		t.Errorf("SearchAllowed = %+v", got)
+	}
+
+	if err := idx.Delete(ctx, []string{"a#0", "missing"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Upsert(ctx, "d#0", []float32{-1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.vectors) != 3 || f.batches != 2 {
+		t.Errorf("expected b, c and d in the store after 2 batches, got %d vectors after %d", len(f.vectors), f.batches)
+	}
+}
+
+func TestQdrantVectorIndex(t *testing.T) {
+	f := &fakeVectors{vectors: map[string][]float32{}}
+	srv := newFakeQdrant(t, f)
+	defer srv.Close()
+	t.Setenv("TEST_QDRANT_KEY", "secret")
+	store := config.VectorStoreConfig{Backend: VectorStoreQdrant, URL: srv.URL + "/", APIKeyEnv: "TEST_QDRANT_KEY", Collection: "docs"}
+
+	idx, err := OpenVectorIndex(context.Background(), store, config.VectorConfig{}, "", "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.dim != 2 || idx.Dimension() != 2 {
+		t.Fatalf("collection created with dimension %d", f.dim)
+	}
+	exerciseVectorIndex(t, idx, f)
+
+	if _, err := OpenVectorIndex(context.Background(), store, config.VectorConfig{}, "", "", 3); err == nil || !strings.Contains(err.Error(), "dimension 2") {
+		t.Errorf("expected a dimension mismatch, got %v", err)
+	}
+	t.Setenv("TEST_QDRANT_KEY", "wrong")
+	if _, err := OpenVectorIndex(context.Background(), store, config.VectorConfig{}, "", "", 2); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+}
+
+func TestMilvusVectorIndex(t *testing.T) {
+	f := &fakeVectors{vectors: map[string][]float32{}}
+	srv := newFakeMilvus(t, f)
+	defer srv.Close()
+	store := config.VectorStoreConfig{Backend: VectorStoreMilvus, URL: srv.URL}
+
+	idx, err := OpenVectorIndex(context.Background(), store, config.VectorConfig{}, "", "minilm", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.dim != 2 {
+		t.Fatalf("collection created with dimension %d", f.dim)
+	}
+	exerciseVectorIndex(t, idx, f)
+
+	if _, err := OpenVectorIndex(context.Background(), store, config.VectorConfig{}, "", "minilm", 3); err == nil || !strings.Contains(err.Error(), "dimension 2") {
+		t.Errorf("expected a dimension mismatch, got %v", err)
+	}
+}
+
+func TestOpenVectorIndexErrors(t *testing.T) {
+	ctx := context.Background()
+	for _, store := range []config.VectorStoreConfig{
+		{Backend: "chroma"},
+		{Backend: VectorStoreQdrant},
+		{Backend: VectorStoreMilvus},
+		{Backend: VectorStorePgvector},
+		{Backend: VectorStorePgvector, URL: "postgres://localhost/semango", Driver: "no-such-driver"},
+	} {
+		idx, err := OpenVectorIndex(ctx, store, config.VectorConfig{}, "", "", 2)
+		if err == nil || idx != nil {
+			t.Errorf("%+v: got %v, %v", store, idx, err)
+		}
+	}
+}
+
+func TestVectorCollection(t *testing.T) {
+	if got := VectorCollection(config.VectorStoreConfig{}, ""); got != "semango" {
+		t.Errorf("default collection %q", got)
+	}
+	if got := VectorCollection(config.VectorStoreConfig{Collection: "kb"}, "minilm"); got != "kb_minilm" {
+		t.Errorf("space collection %q", got)
+	}
+	if IsRemoteVectorStore(config.VectorStoreConfig{Backend: VectorStoreFAISS}) || !IsRemoteVectorStore(config.VectorStoreConfig{Backend: VectorStoreQdrant}) {
+		t.Error("IsRemoteVectorStore")
+	}
+}
+
+func TestPgvectorLiterals(t *testing.T) {
+	if got := vectorLiteral([]float32{1, -0.5, 0.25}); got != "[1,-0.5,0.25]" {
+		t.Errorf("vectorLiteral = %s", got)
+	}
+	if got := textArrayLiteral([]string{"a.md#0", `q"uote\`}); got != `{"a.md#0","q\"uote\\"}` {
+		t.Errorf("textArrayLiteral = %s", got)
+	}
+	if got := quoteIdent(`semango_x"y`); got != `"semango_x""y"` {
+		t.Errorf("quoteIdent = %s", got)
+	}
+	if got := milvusIDFilter([]string{"a#0", `b"c`}); got != `id in ["a#0","b\"c"]` {
+		t.Errorf("milvusIDFilter = %s", got)
+	}
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecreateVectorIndex(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "faiss.index")
+
+	if moved, err := RecreateVectorIndex(path); moved != "" || err != nil {
+		t.Fatalf("nothing to move: got %q, %v", moved, err)
+	}
+
+	for _, p := range []string{path, path + ".ids.json", path + ".model.json"} {
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	moved, err := RecreateVectorIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{path, path + ".ids.json", path + ".model.json"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s still exists", p)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 || filepath.Dir(moved) != dir {
+		t.Errorf("expected the index, its ID map and fingerprint moved aside in %s, got %v (index at %q)", dir, entries, moved)
+	}
+}
+
+func TestSpaceIndexPath(t *testing.T) {
+	for _, tc := range []struct{ path, space, want string }{
+		{"/data/faiss.index", "", "/data/faiss.index"},
+		{"/data/faiss.index", "minilm", "/data/faiss-minilm.index"},
+		{"/data/vectors", "minilm", "/data/vectors-minilm"},
+	} {
+		if got := SpaceIndexPath(tc.path, tc.space); got != tc.want {
+			t.Errorf("SpaceIndexPath(%q, %q) = %q, want %q", tc.path, tc.space, got, tc.want)
+		}
+	}
+	if got, want := VectorIndexPath("restored", "minilm"), filepath.Join("restored", "faiss-minilm.index"); got != want {
+		t.Errorf("VectorIndexPath = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,366 @@
+// Package suggest scans a directory tree and recommends the files settings
+// of semango.yml for it, for `semango suggest-config`.
+package suggest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of files, by the loader that reads them.
+const (
+	KindDocument = "document"
+	KindCode     = "code"
+	KindConfig   = "config"
+	KindData     = "data"
+	KindEmail    = "email"
+	KindImage    = "image"
+	KindVideo    = "video"
+	KindArchive  = "archive"
+)
+
+// kinds maps the extensions Semango has loaders for to their kind.
+var kinds = map[string]string{
+	".md": KindDocument, ".markdown": KindDocument, ".txt": KindDocument, ".pdf": KindDocument,
+	".docx": KindDocument, ".odt": KindDocument, ".rtf": KindDocument, ".epub": KindDocument,
+	".ipynb": KindDocument, ".tex": KindDocument, ".ltx": KindDocument, ".rst": KindDocument, ".rest": KindDocument,
+
+	".go": KindCode, ".js": KindCode, ".ts": KindCode, ".py": KindCode, ".jsx": KindCode,
+	".tsx": KindCode, ".java": KindCode, ".c": KindCode, ".cpp": KindCode, ".h": KindCode,
+	".hpp": KindCode, ".rs": KindCode, ".rb": KindCode, ".php": KindCode, ".cs": KindCode,
+	".swift": KindCode, ".kt": KindCode, ".scala": KindCode, ".proto": KindCode, ".thrift": KindCode,
+
+	".yaml": KindConfig, ".yml": KindConfig, ".toml": KindConfig, ".ini": KindConfig, ".cfg": KindConfig,
+	".tf": KindConfig, ".tfvars": KindConfig, ".hcl": KindConfig,
+
+	".csv": KindData, ".tsv": KindData, ".json": KindData, ".jsonl": KindData, ".parquet": KindData,
+	".avro": KindData, ".orc": KindData,
+	".xlsx": KindData, ".xlsm": KindData, ".xlsb": KindData, ".ods": KindData,
+	".sqlite": KindData, ".sqlite3": KindData, ".db": KindData,
+
+	".eml": KindEmail, ".mbox": KindEmail,
+	".png": KindImage, ".jpg": KindImage, ".jpeg": KindImage,
+	".mp4": KindVideo, ".mkv": KindVideo, ".webm": KindVideo,
+	".zip": KindArchive, ".tar": KindArchive, ".tgz": KindArchive, ".gz": KindArchive,
+}
+
+// kindOrder is the order of include patterns. Video and archives are
+// opt-in, like in the default configuration: transcription is slow and
+// archives often hold copies of files already in the tree.
+var kindOrder = []string{KindDocument, KindCode, KindConfig, KindData, KindEmail, KindImage}
+
+// skipDirs are directories of dependencies, build output and caches, which
+// are excluded wherever they occur.
+var skipDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, "dist": true, "build": true,
+	"target": true, "__pycache__": true, ".venv": true, "venv": true, ".tox": true,
+	".next": true, "coverage": true, ".cache": true,
+}
+
+// indexDir is where Semango keeps its indexes by default, skipped at the
+// root only.
+const indexDir = "semango"
+
+// generated are patterns of generated files, excluded when found.
+var generated = []string{"**/*.min.js", "**/*.pb.go", "**/package-lock.json", "**/pnpm-lock.yaml"}
+
+// ExtStat counts the files with one extension.
+type ExtStat struct {
+	Ext   string `json:"ext"`            // "" for files without one
+	Kind  string `json:"kind,omitempty"` // "" when no loader reads them
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+// Report is the result of a scan: what the tree holds and the files
+// settings recommended for it.
+type Report struct {
+	Files      int       `json:"files"`
+	Bytes      int64     `json:"bytes"`
+	Extensions []ExtStat `json:"extensions"`             // Most files first
+	Skipped    []string  `json:"skipped_dirs,omitempty"` // Relative paths
+
+	Include      []string `json:"include"`
+	Exclude      []string `json:"exclude"`
+	ChunkSize    int      `json:"chunk_size"`
+	ChunkOverlap int      `json:"chunk_overlap"`
+	ChunkReason  string   `json:"chunk_reason"`
+}
+
+// Scan walks root and recommends files settings for it. Directories in
+// skipDirs are counted as skipped, not walked.
+func Scan(root string) (*Report, error) {
+	r := &Report{}
+	byExt := map[string]*ExtStat{}
+	var textSizes []int64
+	var codeBytes, textBytes int64
+	generatedFound := make([]bool, len(generated))
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel == indexDir || (rel != "." && skipDirs[d.Name()]) {
+				r.Skipped = append(r.Skipped, rel)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		for i, g := range generated {
+			if ok, _ := path.Match(strings.TrimPrefix(g, "**/"), d.Name()); ok {
+				generatedFound[i] = true
+				return nil
+			}
+		}
+		ext := strings.ToLower(filepath.Ext(d.Name()))
+		st := byExt[ext]
+		if st == nil {
+			st = &ExtStat{Ext: ext, Kind: kinds[ext]}
+			byExt[ext] = st
+		}
+		st.Files++
+		st.Bytes += info.Size()
+		r.Files++
+		r.Bytes += info.Size()
+		switch st.Kind {
+		case KindCode:
+			codeBytes += info.Size()
+			fallthrough
+		case KindDocument, KindConfig:
+			textBytes += info.Size()
+			textSizes = append(textSizes, info.Size())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, st := range byExt {
+		r.Extensions = append(r.Extensions, *st)
+	}
+	sort.Slice(r.Extensions, func(i, j int) bool {
+		a, b := r.Extensions[i], r.Extensions[j]
+		if a.Files != b.Files {
+			return a.Files > b.Files
+		}
+		return a.Ext < b.Ext
+	})
+	r.Include = includePatterns(r.Extensions, kindOrder)
+	r.Exclude = excludePatterns(r.Skipped, generatedFound)
+	r.ChunkSize, r.ChunkOverlap, r.ChunkReason = chunking(textSizes, codeBytes, textBytes)
+	return r, nil
+}
+
+// includePatterns returns one pattern per kind of order found, e.g.
+// "**/*.{go,py}", in that order.
+func includePatterns(stats []ExtStat, order []string) []string {
+	exts := map[string][]string{}
+	for _, st := range stats {
+		if st.Kind != "" {
+			exts[st.Kind] = append(exts[st.Kind], strings.TrimPrefix(st.Ext, "."))
+		}
+	}
+	var out []string
+	for _, kind := range order {
+		list := exts[kind]
+		if len(list) == 0 {
+			continue
+		}
+		sort.Strings(list)
+		if len(list) == 1 {
+			out = append(out, "**/*."+list[0])
+		} else {
+			out = append(out, "**/*.{"+strings.Join(list, ",")+"}")
+		}
+	}
+	return out
+}
+
+// excludePatterns returns patterns for the skipped directories, e.g.
+// "vendor/**" for one at the root and "**/node_modules/**" for one found
+// deeper, and for the generated files found. .git is always excluded.
+func excludePatterns(skipped []string, generatedFound []bool) []string {
+	set := map[string]bool{".git/**": true}
+	for _, rel := range skipped {
+		if strings.Contains(rel, "/") {
+			set["**/"+path.Base(rel)+"/**"] = true
+		} else {
+			set[rel+"/**"] = true
+		}
+	}
+	out := make([]string, 0, len(set)+len(generated))
+	for p := range set {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	for i, g := range generated {
+		if generatedFound[i] {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// chunking picks chunk_size and chunk_overlap, in characters, for the
+// sizes of the text files found, and says why.
+func chunking(sizes []int64, codeBytes, textBytes int64) (int, int, string) {
+	if len(sizes) == 0 {
+		return 1000, 200, "no text files found; defaults"
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+	median := sizes[len(sizes)/2]
+	switch {
+	case codeBytes*2 > textBytes:
+		return 1500, 150, "mostly source code: larger chunks keep functions together"
+	case median < 2000:
+		return 600, 100, "mostly short files: small chunks keep each one to a chunk or two"
+	case median > 50000:
+		return 1200, 200, "mostly long documents: consider files.parent_chunk_size for answer context"
+	default:
+		return 1000, 200, "mixed prose; defaults"
+	}
+}
+
+// KindStat counts the files of one kind.
+type KindStat struct {
+	Kind  string `json:"kind"`
+	Files int    `json:"files"`
+	// OptIn kinds are left out of Include by default.
+	OptIn bool `json:"opt_in,omitempty"`
+}
+
+// Kinds returns the kinds of the files found that Semango has loaders
+// for: those of kindOrder, then the opt-in ones.
+func (r *Report) Kinds() []KindStat {
+	files := map[string]int{}
+	for _, st := range r.Extensions {
+		if st.Kind != "" {
+			files[st.Kind] += st.Files
+		}
+	}
+	var out []KindStat
+	for _, kind := range append(append([]string(nil), kindOrder...), KindVideo, KindArchive) {
+		if files[kind] > 0 {
+			out = append(out, KindStat{Kind: kind, Files: files[kind], OptIn: kind == KindVideo || kind == KindArchive})
+		}
+	}
+	return out
+}
+
+// IncludeKinds sets Include to the patterns for the files found of the
+// given kinds.
+func (r *Report) IncludeKinds(kinds []string) {
+	want := map[string]bool{}
+	for _, k := range kinds {
+		want[k] = true
+	}
+	var order []string
+	for _, ks := range r.Kinds() {
+		if want[ks.Kind] {
+			order = append(order, ks.Kind)
+		}
+	}
+	r.Include = includePatterns(r.Extensions, order)
+}
+
+// Apply sets the recommended settings in files.
+func (r *Report) Apply(files *config.FilesConfig) {
+	files.Include = r.Include
+	files.Exclude = r.Exclude
+	files.ChunkSize = r.ChunkSize
+	files.ChunkOverlap = r.ChunkOverlap
+}
+
+// WriteConfig sets the recommended files settings in the configuration
+// file at configPath, leaving the rest of it, comments included, as it is.
+// A missing file is created with the defaults and the recommendations.
+func (r *Report) WriteConfig(configPath string) error {
+	data, err := os.ReadFile(configPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		cfg := config.GetDefaultConfig()
+		r.Apply(&cfg.Files)
+		return config.WriteConfig(configPath, cfg)
+	}
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", configPath, err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s is not a YAML mapping", configPath)
+	}
+	files := mappingValue(root, "files")
+	if files.Kind != yaml.MappingNode {
+		*files = yaml.Node{Kind: yaml.MappingNode}
+	}
+	settings := []struct {
+		key   string
+		value interface{}
+	}{
+		{"include", r.Include},
+		{"exclude", r.Exclude},
+		{"chunk_size", r.ChunkSize},
+		{"chunk_overlap", r.ChunkOverlap},
+	}
+	for _, s := range settings {
+		if err := mappingValue(files, s.key).Encode(s.value); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(configPath, buf.Bytes(), 0644)
+}
+
+// mappingValue returns the value node of key in the mapping m, adding the
+// key if it is missing.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	k := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	v := &yaml.Node{}
+	m.Content = append(m.Content, k, v)
+	return v
+}
@@ -0,0 +1,111 @@
+package suggest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/config"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]int) {
+	t.Helper()
+	for name, size := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(strings.Repeat("x", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]int{
+		"README.md":                       500,
+		"docs/guide.md":                   800,
+		"docs/notes.txt":                  300,
+		"main.go":                         400,
+		"api/api.pb.go":                   9000,
+		"data/users.csv":                  100,
+		"logo.svg":                        50,
+		"release.zip":                     10,
+		"vendor/lib/lib.go":               100,
+		"web/node_modules/react/index.js": 100,
+		"semango/index/bleve/store":       100,
+		"pkg/semango/client.go":           100,
+	})
+
+	r, err := Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantInclude := []string{"**/*.{md,txt}", "**/*.go", "**/*.csv"}
+	if !reflect.DeepEqual(r.Include, wantInclude) {
+		t.Errorf("Include = %q, want %q", r.Include, wantInclude)
+	}
+	wantExclude := []string{"**/node_modules/**", ".git/**", "semango/**", "vendor/**", "**/*.pb.go"}
+	if !reflect.DeepEqual(r.Exclude, wantExclude) {
+		t.Errorf("Exclude = %q, want %q", r.Exclude, wantExclude)
+	}
+	if r.Files != 8 {
+		t.Errorf("Files = %d, want 8 (skipped and generated files not counted)", r.Files)
+	}
+	if r.Extensions[0].Ext != ".go" && r.Extensions[0].Ext != ".md" {
+		t.Errorf("most common extension = %q", r.Extensions[0].Ext)
+	}
+	if r.ChunkSize != 600 || r.ChunkOverlap != 100 {
+		t.Errorf("chunking = %d/%d, want 600/100 for short files", r.ChunkSize, r.ChunkOverlap)
+	}
+}
+
+func TestWriteConfigKeepsOtherSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "semango.yml")
+	orig := "# my config\nembedding:\n  provider: openai # paid\nfiles:\n  include: [\"**/*.md\"]\n  archive_max_bytes: 10\n"
+	if err := os.WriteFile(path, []byte(orig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := &Report{Include: []string{"**/*.go"}, Exclude: []string{".git/**"}, ChunkSize: 1500, ChunkOverlap: 150}
+	if err := r.WriteConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	got := string(data)
+	for _, want := range []string{"# my config", "provider: openai # paid", "archive_max_bytes: 10", "- '**/*.go'", "chunk_size: 1500"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("written config lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "**/*.md") {
+		t.Errorf("include was not replaced:\n%s", got)
+	}
+
+	missing := filepath.Join(t.TempDir(), "new.yml")
+	if err := r.WriteConfig(missing); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Load(missing, filepath.Join("..", "config", "config_schema.cue")); err != nil {
+		t.Errorf("new config does not load: %v", err)
+	}
+}
+
+func TestIncludeKinds(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]int{"a.md": 10, "b.md": 10, "main.go": 10, "clip.mp4": 10})
+	r, err := Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantKinds := []KindStat{{Kind: KindDocument, Files: 2}, {Kind: KindCode, Files: 1}, {Kind: KindVideo, Files: 1, OptIn: true}}
+	if got := r.Kinds(); !reflect.DeepEqual(got, wantKinds) {
+		t.Errorf("Kinds = %+v, want %+v", got, wantKinds)
+	}
+	r.IncludeKinds([]string{KindVideo, KindDocument})
+	if want := []string{"**/*.md", "**/*.mp4"}; !reflect.DeepEqual(r.Include, want) {
+		t.Errorf("Include = %q, want %q", r.Include, want)
+	}
+}
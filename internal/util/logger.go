@@ -1,22 +1,42 @@
 package util
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 )
 
 var Logger *slog.Logger
 
+// LogLevel is the level of Logger. It starts at debug and is set from the
+// log_level setting with SetLogLevel, also while the server runs.
+var LogLevel = new(slog.LevelVar)
+
 func init() {
-	// Default to JSON handler, info level, writing to stdout
-	// This can be configured further later (e.g., based on config file)
+	// Default to JSON handler, writing to stdout
+	LogLevel.Set(slog.LevelDebug)
 	Logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelDebug,
+		Level: LogLevel,
 	}))
 
 	slog.SetDefault(Logger) // Optionally set as default for global slog functions like slog.Info()
 }
 
+// SetLogLevel sets LogLevel by name: "debug", "info", "warn" or "error".
+// The empty name means debug.
+func SetLogLevel(name string) error {
+	if name == "" {
+		name = "debug"
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.ToUpper(name))); err != nil {
+		return fmt.Errorf("invalid log level %q: want debug, info, warn or error", name)
+	}
+	LogLevel.Set(level)
+	return nil
+}
+
 // Example of how to use it from other packages:
 // import "github.com/omarkamali/semango/internal/util"
 // ...
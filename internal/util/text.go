@@ -0,0 +1,24 @@
+package util
+
+import "unicode/utf8"
+
+// Ellipsis marks text cut by Truncate.
+const Ellipsis = "…"
+
+// Truncate shortens s to at most n characters, the last of which is
+// Ellipsis when anything was cut, and reports whether it did. It never
+// splits a multi-byte character. n <= 0 leaves s unchanged.
+func Truncate(s string, n int) (string, bool) {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s, false
+	}
+	cut, count := 0, 0
+	for i := range s {
+		if count == n-1 {
+			cut = i
+			break
+		}
+		count++
+	}
+	return s[:cut] + Ellipsis, true
+}
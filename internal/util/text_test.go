@@ -0,0 +1,32 @@
+package util
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		in   string
+		n    int
+		want string
+		cut  bool
+	}{
+		{"hello", 10, "hello", false},
+		{"hello", 5, "hello", false},
+		{"hello world", 6, "hello…", true},
+		{"Grüße aus Köln", 6, "Grüße…", true},
+		{"日本語のテキスト", 4, "日本語…", true},
+		{"hello", 1, "…", true},
+		{"hello", 0, "hello", false},
+	}
+	for _, c := range cases {
+		got, cut := Truncate(c.in, c.n)
+		if got != c.want || cut != c.cut {
+			t.Errorf("Truncate(%q, %d) = %q, %v; want %q, %v", c.in, c.n, got, cut, c.want, c.cut)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("Truncate(%q, %d) split a character: %q", c.in, c.n, got)
+		}
+	}
+}
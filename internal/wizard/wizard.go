@@ -0,0 +1,240 @@
+// Package wizard asks the questions of `semango init --interactive` and
+// builds a semango.yml tailored to the answers and to what it finds on the
+// machine: cached local models, API keys and the files in the tree.
+package wizard
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/ingest"
+	"github.com/omarkamali/semango/internal/suggest"
+)
+
+// DefaultLocalModel is offered when no local model is cached: small enough
+// to download in seconds and run on a laptop CPU.
+const DefaultLocalModel = "onnx-models/all-MiniLM-L6-v2-onnx"
+
+// DefaultOpenAIModel is the OpenAI embedding model offered.
+const DefaultOpenAIModel = "text-embedding-3-small"
+
+// Environment is what the wizard found on the machine.
+type Environment struct {
+	OpenAIKey    bool            // OPENAI_API_KEY is set
+	CachedModels []string        // complete local models in the caches, most recently used first
+	Scan         *suggest.Report // files under the directory to index
+}
+
+// Detect looks for API keys, the local models cached in cacheDirs and the
+// files under root.
+func Detect(root string, cacheDirs ...string) (*Environment, error) {
+	env := &Environment{OpenAIKey: os.Getenv("OPENAI_API_KEY") != ""}
+	for _, dir := range cacheDirs {
+		entries, err := ingest.ListModelCache(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			// Cache directories are named after the model with "/" as "_".
+			name := strings.Replace(e.Name, "_", "/", 1)
+			if e.Complete && !contains(env.CachedModels, name) {
+				env.CachedModels = append(env.CachedModels, name)
+			}
+		}
+	}
+	var err error
+	if env.Scan, err = suggest.Scan(root); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// Prompter asks questions on a terminal, or any reader and writer.
+type Prompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// NewPrompter returns a Prompter reading answers from in and writing
+// questions to out.
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{in: bufio.NewScanner(in), out: out}
+}
+
+// Ask asks question and returns the answer, or def for an empty answer or
+// at the end of input.
+func (p *Prompter) Ask(question, def string) string {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	if !p.in.Scan() {
+		fmt.Fprintln(p.out)
+		return def
+	}
+	if a := strings.TrimSpace(p.in.Text()); a != "" {
+		return a
+	}
+	return def
+}
+
+// Confirm asks a yes/no question.
+func (p *Prompter) Confirm(question string, def bool) bool {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		switch strings.ToLower(p.Ask(question+" ("+hint+")", "")) {
+		case "":
+			return def
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+		fmt.Fprintln(p.out, "Please answer y or n.")
+	}
+}
+
+// Choose asks for one of options, by number or by name, and returns it.
+func (p *Prompter) Choose(question string, options []string, def string) string {
+	fmt.Fprintln(p.out, question)
+	for i, o := range options {
+		fmt.Fprintf(p.out, "  %d) %s\n", i+1, o)
+	}
+	for {
+		a := p.Ask("Choice", def)
+		if n, err := strconv.Atoi(a); err == nil && n >= 1 && n <= len(options) {
+			return options[n-1]
+		}
+		for _, o := range options {
+			if a == o {
+				return o
+			}
+		}
+		fmt.Fprintf(p.out, "Please enter a number from 1 to %d.\n", len(options))
+	}
+}
+
+// Run asks for the embedding provider and model, the kinds of files to
+// index and the server port, and returns the configuration for the answers.
+// Settings it does not ask about keep their defaults, except that no
+// plugins are configured.
+func Run(p *Prompter, env *Environment) *config.Config {
+	cfg := config.GetDefaultConfig()
+	cfg.Plugins = nil
+
+	// Provider: OpenAI when its key is set and no local model is cached yet.
+	def := "local"
+	if env.OpenAIKey && len(env.CachedModels) == 0 {
+		def = "openai"
+	}
+	if env.OpenAIKey {
+		fmt.Fprintln(p.out, "Found OPENAI_API_KEY in the environment.")
+	}
+	if len(env.CachedModels) > 0 {
+		fmt.Fprintf(p.out, "Found %d local model(s) in the model cache.\n", len(env.CachedModels))
+	}
+	providers := []string{"local", "openai"}
+	cfg.Embedding.Provider = p.Choose("Embedding provider (local runs ONNX models on this machine; openai needs OPENAI_API_KEY):", providers, def)
+
+	if cfg.Embedding.Provider == "local" {
+		models := append([]string(nil), env.CachedModels...)
+		if len(models) == 0 {
+			models = []string{DefaultLocalModel}
+		}
+		for _, m := range ingest.GetSupportedModels() {
+			if !contains(models, m) && strings.HasPrefix(m, "onnx-models/") && len(models) < 6 {
+				models = append(models, m)
+			}
+		}
+		cfg.Embedding.LocalModelPath = p.Choose("Local model (cached ones first; others are downloaded on first use):", models, models[0])
+	} else {
+		if !env.OpenAIKey {
+			fmt.Fprintln(p.out, "Set OPENAI_API_KEY before indexing.")
+		}
+		cfg.Embedding.Model = p.Ask("OpenAI embedding model", DefaultOpenAIModel)
+	}
+
+	// Files: the kinds found in the tree, opt-in kinds only on request.
+	r := env.Scan
+	kinds := r.Kinds()
+	if len(kinds) == 0 {
+		fmt.Fprintln(p.out, "No files Semango can index were found; keeping the default file patterns.")
+	} else {
+		fmt.Fprintln(p.out, "Files found:")
+		var defaults []string
+		for _, k := range kinds {
+			note := ""
+			if k.OptIn {
+				note = " (opt-in)"
+			} else {
+				defaults = append(defaults, k.Kind)
+			}
+			fmt.Fprintf(p.out, "  %-9s %d files%s\n", k.Kind, k.Files, note)
+		}
+		var chosen []string
+		for {
+			chosen = splitList(p.Ask("Kinds of files to index, comma-separated", strings.Join(defaults, ",")))
+			if unknown := unknownKinds(chosen, kinds); len(unknown) > 0 {
+				fmt.Fprintf(p.out, "Not found here: %s.\n", strings.Join(unknown, ", "))
+				continue
+			}
+			break
+		}
+		r.IncludeKinds(chosen)
+		if len(r.Include) > 0 {
+			r.Apply(&cfg.Files)
+		}
+	}
+
+	for {
+		port, err := strconv.Atoi(p.Ask("Server port", strconv.Itoa(cfg.Server.Port)))
+		if err == nil && port > 0 && port < 65536 {
+			cfg.Server.Port = port
+			break
+		}
+		fmt.Fprintln(p.out, "Please enter a port from 1 to 65535.")
+	}
+	return cfg
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func unknownKinds(chosen []string, found []suggest.KindStat) []string {
+	var unknown []string
+	for _, c := range chosen {
+		ok := false
+		for _, k := range found {
+			ok = ok || k.Kind == c
+		}
+		if !ok {
+			unknown = append(unknown, c)
+		}
+	}
+	return unknown
+}
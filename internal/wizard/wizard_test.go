@@ -0,0 +1,94 @@
+package wizard
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/omarkamali/semango/internal/config"
+	"github.com/omarkamali/semango/internal/suggest"
+)
+
+func writeTree(t *testing.T, files ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, f := range files {
+		p := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("content"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	root := writeTree(t, "docs/a.md", "docs/b.md", "main.go", "clip.mp4")
+	cache := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(cache, "onnx-models_all-MiniLM-L12-v2-onnx.partial"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	env, err := Detect(root, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !env.OpenAIKey || len(env.CachedModels) != 0 {
+		t.Fatalf("detected %+v", env)
+	}
+
+	// OpenAI by default with a key and no cached model; an invalid kind and
+	// port are asked again.
+	var out bytes.Buffer
+	answers := "\n\nmarkdown\ndocument,video\n99999\n9000\n"
+	cfg := Run(NewPrompter(strings.NewReader(answers), &out), env)
+
+	if cfg.Embedding.Provider != "openai" || cfg.Embedding.Model != DefaultOpenAIModel {
+		t.Errorf("embedding %s/%s", cfg.Embedding.Provider, cfg.Embedding.Model)
+	}
+	if want := []string{"**/*.md", "**/*.mp4"}; !reflect.DeepEqual(cfg.Files.Include, want) {
+		t.Errorf("include %q, want %q", cfg.Files.Include, want)
+	}
+	if cfg.Server.Port != 9000 || cfg.Plugins != nil {
+		t.Errorf("port %d, plugins %q", cfg.Server.Port, cfg.Plugins)
+	}
+	for _, want := range []string{"Found OPENAI_API_KEY", "video", "(opt-in)", "Not found here: markdown", "Please enter a port"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "semango.yml")
+	if err := config.WriteConfig(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Load(path, config.DefaultCueSchemaPath); err != nil {
+		t.Errorf("the configuration does not load: %v", err)
+	}
+}
+
+func TestRunDefaults(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	scan, err := suggest.Scan(writeTree(t, "a.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := &Environment{CachedModels: []string{"onnx-models/all-mpnet-base-v2-onnx"}, Scan: scan}
+
+	// End of input takes every default.
+	cfg := Run(NewPrompter(strings.NewReader(""), &bytes.Buffer{}), env)
+	if cfg.Embedding.Provider != "local" || cfg.Embedding.LocalModelPath != "onnx-models/all-mpnet-base-v2-onnx" {
+		t.Errorf("embedding %s %s", cfg.Embedding.Provider, cfg.Embedding.LocalModelPath)
+	}
+	if want := []string{"**/*.go"}; !reflect.DeepEqual(cfg.Files.Include, want) {
+		t.Errorf("include %q, want %q", cfg.Files.Include, want)
+	}
+	if cfg.Server.Port != config.GetDefaultConfig().Server.Port {
+		t.Errorf("port %d", cfg.Server.Port)
+	}
+}
@@ -0,0 +1,296 @@
+// Package client is a Go client for the semango REST API.
+//
+//	c, err := client.New("http://localhost:8181", client.WithToken(os.Getenv("SEMANGO_TOKEN")))
+//	if err != nil { ... }
+//	res, err := c.Search(ctx, client.SearchRequest{Query: "rotate api keys", TopK: 5})
+//
+// Requests that fail with a network error or a 429, 502, 503 or 504 status
+// are retried with exponential backoff. Index sends an Idempotency-Key, so
+// retrying it never indexes a document twice.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls a semango server. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithToken sends token as a bearer token, for servers with
+// server.auth.token_env set.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithHTTPClient replaces http.DefaultClient, e.g. to set timeouts or TLS.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithRetries sets how often a failed request is retried (default 3) and
+// the delay before the first retry (default 500ms), which doubles on each
+// further attempt. Zero retries disables retrying.
+func WithRetries(maxRetries int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.baseDelay = baseDelay
+	}
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "http://localhost:8181".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: expected e.g. http://localhost:8181", baseURL)
+	}
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		maxRetries: 3,
+		baseDelay:  500 * time.Millisecond,
+		maxDelay:   10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// APIError is a response with an error status.
+type APIError struct {
+	StatusCode int
+	Message    string // the server's "error" field, or the raw body
+	Body       []byte // the raw response body
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("semango: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsStale reports whether err is the 409 returned when the index is older
+// than a search's MinGeneration or MinIndexedAt, or when a cursor has
+// outlived the ranking it belongs to.
+func IsStale(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict
+}
+
+// Search runs a search.
+func (c *Client) Search(ctx context.Context, req SearchRequest) (*SearchResponse, error) {
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, errors.New("semango: query is required")
+	}
+	var resp SearchResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/search", req, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Index chunks, embeds and indexes doc. Re-indexing a path replaces its
+// chunks.
+func (c *Client) Index(ctx context.Context, doc Document) (*IndexResponse, error) {
+	if strings.TrimSpace(doc.Text) == "" {
+		return nil, errors.New("semango: document text is required")
+	}
+	key, err := newIdempotencyKey()
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{"Idempotency-Key": {key}}
+	var resp IndexResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/documents", doc, header, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Answer asks the server's LLM to answer a question from the index. The
+// server must have an llm section configured.
+func (c *Client) Answer(ctx context.Context, req AnswerRequest) (*AnswerResponse, error) {
+	if strings.TrimSpace(req.Question) == "" {
+		return nil, errors.New("semango: question is required")
+	}
+	var resp AnswerResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/answer", req, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Stats returns statistics of the default index.
+func (c *Client) Stats(ctx context.Context) (*Stats, error) {
+	return c.CollectionStats(ctx, "")
+}
+
+// CollectionStats returns statistics of the named collection, or of the
+// default index if name is empty.
+func (c *Client) CollectionStats(ctx context.Context, name string) (*Stats, error) {
+	path := "/api/v1/stats"
+	if name != "" {
+		path += "?collection=" + url.QueryEscape(name)
+	}
+	var resp Stats
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Health returns the server's readiness. A server that is up but not ready
+// is not an error: check Health.Ready. Health is not retried, so it reports
+// the server's current state.
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	var resp Health
+	_, err := c.send(ctx, http.MethodGet, "/api/v1/ready", nil, nil, &resp)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusServiceUnavailable &&
+		json.Unmarshal(apiErr.Body, &resp) == nil && resp.Status != "" {
+		return &resp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// do sends a request, retrying transient failures, and decodes the JSON
+// response into out. Error responses are returned as *APIError.
+func (c *Client) do(ctx context.Context, method, path string, body any, header http.Header, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("semango: encoding request: %w", err)
+		}
+	}
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.send(ctx, method, path, payload, header, out)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if attempt >= c.maxRetries || !retryable(err, header) {
+			return lastErr
+		}
+
+		delay := c.baseDelay << attempt
+		if delay > c.maxDelay || delay <= 0 {
+			delay = c.maxDelay
+		}
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// send makes one attempt, returning the server's Retry-After, if any.
+func (c *Client) send(ctx context.Context, method, path string, payload []byte, header http.Header, out any) (time.Duration, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return 0, fmt.Errorf("semango: decoding %s response: %w", path, err)
+		}
+		return 0, nil
+	}
+
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data)), Body: data}
+	var errBody struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &errBody) == nil && errBody.Error != "" {
+		apiErr.Message = errBody.Error
+	}
+	var retryAfter time.Duration
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		retryAfter = time.Duration(secs) * time.Second
+	}
+	return retryAfter, apiErr
+}
+
+// retryable reports whether a failed attempt may be repeated. Network errors
+// and overload statuses are; a 409 only when the request carries an
+// Idempotency-Key, where it means the first attempt is still running.
+func retryable(err error, header http.Header) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		var urlErr *url.Error
+		return errors.As(err, &urlErr)
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusConflict:
+		return header.Get("Idempotency-Key") != ""
+	}
+	return false
+}
+
+func newIdempotencyKey() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("semango: generating idempotency key: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
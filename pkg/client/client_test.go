@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSearch_RetriesAndAuth(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var req SearchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Path != "/api/v1/search" {
+			t.Errorf("unexpected request %s %s: %v", r.Method, r.URL.Path, err)
+		}
+		json.NewEncoder(w).Encode(SearchResponse{
+			Query:   req.Query,
+			Results: []SearchResult{{Rank: 1, ID: "a.md#0", Document: DocumentInfo{Path: "a.md"}}},
+		})
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, WithToken("secret"), WithRetries(2, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Search(context.Background(), SearchRequest{Query: "keys"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 || len(res.Results) != 1 || res.Results[0].Document.Path != "a.md" {
+		t.Errorf("calls = %d, response %+v", calls.Load(), res)
+	}
+}
+
+func TestIndex_ReusesIdempotencyKey(t *testing.T) {
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(IndexResponse{Path: "notes.md", Chunks: 1, Generation: 7})
+	}))
+	defer srv.Close()
+
+	c, _ := New(srv.URL, WithRetries(3, time.Millisecond))
+	res, err := c.Index(context.Background(), Document{Path: "notes.md", Text: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Generation != 7 {
+		t.Errorf("generation = %d", res.Generation)
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("idempotency keys = %q, want the same key on both attempts", keys)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Path {
+		case "/api/v1/search":
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":"index is at generation 3, older than min_generation 4"}`))
+		case "/api/v1/ready":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status":"unavailable","embedder":{"ready":false,"last_error":"timeout"}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"bad"}`))
+		}
+	}))
+	defer srv.Close()
+	c, _ := New(srv.URL, WithRetries(3, time.Millisecond))
+	ctx := context.Background()
+
+	_, err := c.Search(ctx, SearchRequest{Query: "q", MinGeneration: 4})
+	if !IsStale(err) {
+		t.Errorf("Search error = %v, want a stale index error", err)
+	}
+	if _, err := c.Stats(ctx); err == nil || err.(*APIError).Message != "bad" {
+		t.Errorf("Stats error = %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2: client errors must not be retried", calls.Load())
+	}
+
+	h, err := c.Health(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Ready() || h.Embedder.LastError != "timeout" {
+		t.Errorf("health = %+v", h)
+	}
+
+	if _, err := New("localhost:8181"); err == nil {
+		t.Error("expected an error for a base URL without scheme")
+	}
+}
+
+func TestCollectionStats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/stats" || r.URL.Query().Get("collection") != "team docs" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"total_chunks":12,"vectors":12,"chunks_by_modality":{"text":12},"index_size_bytes":4096,"indexed_at":"2024-05-01T12:00:00Z"}`))
+	}))
+	defer srv.Close()
+	c, _ := New(srv.URL)
+
+	stats, err := c.CollectionStats(context.Background(), "team docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalChunks != 12 || stats.ChunksByModality["text"] != 12 || stats.IndexSize != 4096 || stats.IndexedAt == nil {
+		t.Errorf("stats = %+v", stats)
+	}
+}
@@ -0,0 +1,226 @@
+package client
+
+import "time"
+
+// SearchRequest is the body of POST /api/v1/search. Only Query is required;
+// zero values fall back to the server's defaults.
+type SearchRequest struct {
+	Query  string `json:"query"`
+	TopK   int    `json:"top_k,omitempty"`
+	Filter string `json:"filter,omitempty"` // Metadata filter, e.g. `source:EmailLoader lang:de`
+	Lang   string `json:"lang,omitempty"`   // Language hint, e.g. "de" or "pt-BR", or "auto"
+	Path   string `json:"path,omitempty"`   // Search within this document only
+	Mode   string `json:"mode,omitempty"`   // "hybrid", "lexical" or "vector"
+	// Parents returns each matched chunk's parent section instead of the chunk.
+	Parents bool `json:"parents,omitempty"`
+	// Window widens each result to up to this many chunks before and after
+	// it in its document, at most 10; SearchResult.Window lists them.
+	Window int `json:"window,omitempty"`
+	// Boosts multiply the score of results whose path matches a pattern,
+	// e.g. {"docs/**": 1.5}.
+	Boosts map[string]float64 `json:"boosts,omitempty"`
+	Space  string             `json:"space,omitempty"`  // Vector space, see embedding.spaces
+	Expand string             `json:"expand,omitempty"` // Query expansion: "terms", "hyde" or "multi"
+	// Queries are further variants of Query, e.g. sub-questions; the
+	// results of all variants are merged.
+	Queries []string `json:"queries,omitempty"`
+	// Collection searches a collection from the server's collections
+	// section instead of the default index.
+	Collection string `json:"collection,omitempty"`
+	// Collections searches several collections together and merges their
+	// results; each result then names its Collection.
+	Collections []string `json:"collections,omitempty"`
+	// MaxTextLength cuts each result's Chunk to this many characters;
+	// 0 uses the server's search.max_text_length.
+	MaxTextLength int `json:"max_text_length,omitempty"`
+	// IncludeFullText returns each result's Chunk; otherwise results carry
+	// only a Snippet, of SnippetLength characters (0 uses the server's
+	// search.snippet_length).
+	IncludeFullText bool `json:"include_full_text,omitempty"`
+	SnippetLength   int  `json:"snippet_length,omitempty"`
+	// Adaptive returns results only down to the first steep score drop;
+	// TopK still caps the count. AdaptiveFull keeps the results below the
+	// drop and only reports it as Cutoff.
+	Adaptive     bool `json:"adaptive,omitempty"`
+	AdaptiveFull bool `json:"adaptive_full,omitempty"`
+	// GroupBy "document" returns one result per document, its best chunk,
+	// with the document's other matching chunks in SearchResult.Chunks.
+	// MaxChunksPerDoc keeps at most this many chunks of a document,
+	// counting nested ones.
+	GroupBy         string `json:"group_by,omitempty"`
+	MaxChunksPerDoc int    `json:"max_chunks_per_doc,omitempty"`
+	// LexicalSyntax "advanced" reads "phrases", +required and -excluded
+	// terms, key:value metadata matches and fuzzy~ terms in Query for
+	// lexical matching.
+	LexicalSyntax string `json:"lexical_syntax,omitempty"`
+
+	// Freshness requirements: the search fails with a 409 APIError if the
+	// index is at an older generation or was last written before
+	// MinIndexedAt (RFC 3339).
+	MinGeneration uint64 `json:"min_generation,omitempty"`
+	MinIndexedAt  string `json:"min_indexed_at,omitempty"`
+
+	// Paging: either skip Offset results, or continue from the NextCursor of
+	// a previous response.
+	Offset int    `json:"offset,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// SearchResponse is the response to a search.
+type SearchResponse struct {
+	Results []SearchResult `json:"results"`
+	Query   string         `json:"query"`
+	TopK    int            `json:"top_k"`
+	Lang    string         `json:"lang,omitempty"`
+	Path    string         `json:"path,omitempty"`
+	Mode    string         `json:"mode"`
+	Space   string         `json:"space,omitempty"`
+	Took    string         `json:"took"`
+
+	Generation uint64     `json:"generation"`
+	IndexedAt  *time.Time `json:"indexed_at,omitempty"`
+
+	Offset          int    `json:"offset"`
+	TotalCandidates int    `json:"total_candidates"`
+	NextCursor      string `json:"next_cursor,omitempty"` // empty on the last page
+
+	// Warnings say how the results fall short of the request, e.g. a
+	// hybrid search answered from the lexical index alone.
+	Warnings []string `json:"warnings,omitempty"`
+	// Cutoff is set by adaptive searches: the number of results, counted
+	// from rank 1, above the score drop-off.
+	Cutoff int `json:"cutoff,omitempty"`
+}
+
+// SearchResult is a single ranked chunk.
+type SearchResult struct {
+	Rank          int                    `json:"rank"`
+	ID            string                 `json:"id"` // Chunk ID
+	Score         float64                `json:"score"`
+	LexicalScore  float64                `json:"lexical_score"`
+	SemanticScore float64                `json:"semantic_score"`
+	Modality      string                 `json:"modality"`
+	Document      DocumentInfo           `json:"document"`
+	Snippet       string                 `json:"snippet"`         // The chunk around its best highlight
+	Chunk         string                 `json:"chunk,omitempty"` // Only with SearchRequest.IncludeFullText
+	Highlights    map[string]interface{} `json:"highlights,omitempty"`
+	Link          string                 `json:"link,omitempty"`
+	Truncated     bool                   `json:"truncated,omitempty"`  // Chunk was cut to MaxTextLength
+	Collection    string                 `json:"collection,omitempty"` // Set when searching Collections
+	Window        []string               `json:"window,omitempty"`     // Chunk IDs of Chunk with SearchRequest.Window
+	Aliases       []string               `json:"aliases,omitempty"`    // Paths of duplicates of the chunk left out of the index
+	Chunks        []SearchResult         `json:"chunks,omitempty"`     // Further chunks of the document with SearchRequest.GroupBy
+}
+
+// DocumentInfo identifies the document a result came from.
+type DocumentInfo struct {
+	Path string            `json:"path"`
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+// Document is the body of POST /api/v1/documents.
+type Document struct {
+	Path string            `json:"path,omitempty"` // Logical path; defaults to "api/<hash of text>"
+	Text string            `json:"text"`
+	Meta map[string]string `json:"meta,omitempty"`
+	// Collection indexes the document into a collection from the server's
+	// collections section instead of the default index.
+	Collection string `json:"collection,omitempty"`
+}
+
+// IndexResponse is the response to Index.
+type IndexResponse struct {
+	Path     string   `json:"path"`
+	Chunks   int      `json:"chunks"`
+	ChunkIDs []string `json:"chunk_ids"`
+	Took     string   `json:"took"`
+	// Generation is the index generation that includes the document; pass it
+	// as SearchRequest.MinGeneration to make sure a search sees it.
+	Generation uint64 `json:"generation"`
+}
+
+// AnswerRequest is the body of POST /api/v1/answer.
+type AnswerRequest struct {
+	Question string `json:"question"`
+	TopK     int    `json:"top_k,omitempty"` // Chunks to answer from; defaults to the server's llm.top_k
+	Filter   string `json:"filter,omitempty"`
+	Lang     string `json:"lang,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Mode     string `json:"mode,omitempty"`
+	Space    string `json:"space,omitempty"`
+	Expand   string `json:"expand,omitempty"`
+	// Queries are further search queries for the question.
+	Queries []string `json:"queries,omitempty"`
+	// Adaptive answers only from the chunks above the first steep score drop.
+	Adaptive bool `json:"adaptive,omitempty"`
+}
+
+// AnswerResponse is the response to Answer.
+type AnswerResponse struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+	// Sources are numbered as cited in the answer; Citations lists the
+	// numbers the answer cites.
+	Sources    []AnswerSource `json:"sources"`
+	Citations  []int          `json:"citations"`
+	Model      string         `json:"model,omitempty"`
+	Generation uint64         `json:"generation"`
+	Took       string         `json:"took"`
+}
+
+// AnswerSource is a chunk the answer was written from.
+type AnswerSource struct {
+	N     int               `json:"n"`
+	ID    string            `json:"id"`
+	Path  string            `json:"path"`
+	Score float64           `json:"score"`
+	Text  string            `json:"text"`
+	Meta  map[string]string `json:"meta,omitempty"`
+	Link  string            `json:"link,omitempty"`
+}
+
+// Stats are index statistics.
+type Stats struct {
+	TotalDocuments   int              `json:"total_documents"` // distinct paths
+	TotalChunks      int              `json:"total_chunks"`
+	Vectors          int64            `json:"vectors"`
+	SpaceVectors     map[string]int64 `json:"space_vectors,omitempty"`
+	ChunksByModality map[string]int   `json:"chunks_by_modality"`
+	FilesByExtension map[string]int   `json:"files_by_extension"`
+
+	IndexSize        int64 `json:"index_size_bytes"` // lexical and vector indexes on disk
+	LexicalIndexSize int64 `json:"lexical_index_bytes"`
+	VectorIndexSize  int64 `json:"vector_index_bytes"`
+
+	EmbeddingProvider string `json:"embedding_provider"`
+	EmbeddingModel    string `json:"embedding_model"`
+	Dimension         int    `json:"dimension"`
+	IndexedWith       string `json:"indexed_with,omitempty"` // provider/model the default vector index was built with
+	// Compression is how the default vector index stores vectors, e.g.
+	// "float16"; BytesPerVector its file size per vector.
+	Compression    string     `json:"compression,omitempty"`
+	BytesPerVector int64      `json:"bytes_per_vector,omitempty"`
+	Generation     uint64     `json:"generation"`
+	IndexedAt      *time.Time `json:"indexed_at,omitempty"`
+}
+
+// Health is the server's readiness as reported by /api/v1/ready.
+type Health struct {
+	Status   string          `json:"status"` // "ready" or "unavailable"
+	Embedder *EmbedderStatus `json:"embedder,omitempty"`
+}
+
+// Ready reports whether the server can answer searches.
+func (h *Health) Ready() bool {
+	return h.Status == "ready"
+}
+
+// EmbedderStatus is the latest result of the server's embedder probe.
+type EmbedderStatus struct {
+	Ready               bool      `json:"ready"`
+	LastCheck           time.Time `json:"last_check,omitempty"`
+	LastSuccess         time.Time `json:"last_success,omitempty"`
+	Latency             string    `json:"latency,omitempty"` // Of the last successful probe
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastError           string    `json:"last_error,omitempty"`
+}
@@ -0,0 +1,1227 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: semango/v1/semango.proto
+
+package semangov1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SearchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Query string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	// Number of results, at most 100; defaults to search.<mode>.top_k.
+	TopK int32 `protobuf:"varint,2,opt,name=top_k,json=topK,proto3" json:"top_k,omitempty"`
+	// Metadata filter of key:value terms, e.g. `source:EmailLoader lang:de`.
+	Filter string `protobuf:"bytes,3,opt,name=filter,proto3" json:"filter,omitempty"`
+	// Language hint, e.g. "de" or "pt-BR".
+	Lang string `protobuf:"bytes,4,opt,name=lang,proto3" json:"lang,omitempty"`
+	// Search within this document only; results come in document order.
+	Path string `protobuf:"bytes,5,opt,name=path,proto3" json:"path,omitempty"`
+	// "hybrid", "lexical" or "vector"; defaults to search.default_mode.
+	Mode string `protobuf:"bytes,6,opt,name=mode,proto3" json:"mode,omitempty"`
+	// Return each matched chunk's parent section instead of the chunk.
+	Parents bool `protobuf:"varint,7,opt,name=parents,proto3" json:"parents,omitempty"`
+	// Score multipliers by path pattern, e.g. {"docs/**": 1.5}.
+	Boosts map[string]float64 `protobuf:"bytes,8,rep,name=boosts,proto3" json:"boosts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	// Fail with FAILED_PRECONDITION if the index is at an older generation.
+	MinGeneration uint64 `protobuf:"varint,9,opt,name=min_generation,json=minGeneration,proto3" json:"min_generation,omitempty"`
+	// Number of results to skip; offset + top_k may be at most 1000.
+	Offset int32 `protobuf:"varint,10,opt,name=offset,proto3" json:"offset,omitempty"`
+	// Vector space to search (see embedding.spaces); defaults to "default".
+	Space string `protobuf:"bytes,11,opt,name=space,proto3" json:"space,omitempty"`
+	// Search this snapshot from the snapshot directory, read-only, instead of
+	// the live index.
+	AsOf string `protobuf:"bytes,12,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	// Query expansion: "terms", "hyde" or "multi" (the last two need an LLM);
+	// empty searches the query as given.
+	Expand string `protobuf:"bytes,13,opt,name=expand,proto3" json:"expand,omitempty"`
+	// Further variants of the query, searched on their own; the rankings are
+	// merged with Reciprocal Rank Fusion.
+	Queries []string `protobuf:"bytes,14,rep,name=queries,proto3" json:"queries,omitempty"`
+	// Search this collection from the collections section instead of the
+	// default index.
+	Collection string `protobuf:"bytes,15,opt,name=collection,proto3" json:"collection,omitempty"`
+	// Cut each result's text to this many characters; defaults to
+	// search.max_text_length.
+	MaxTextLength int32 `protobuf:"varint,16,opt,name=max_text_length,json=maxTextLength,proto3" json:"max_text_length,omitempty"`
+	// Search these collections together and merge their results, weighted
+	// by collections.<name>.weight. Cannot be combined with collection.
+	Collections []string `protobuf:"bytes,17,rep,name=collections,proto3" json:"collections,omitempty"`
+	// Return results only down to the first steep score drop (see
+	// search.adaptive_drop); top_k still caps the count.
+	Adaptive bool `protobuf:"varint,18,opt,name=adaptive,proto3" json:"adaptive,omitempty"`
+	// Like adaptive, but keep the results below the drop; cutoff marks it.
+	AdaptiveFull bool `protobuf:"varint,19,opt,name=adaptive_full,json=adaptiveFull,proto3" json:"adaptive_full,omitempty"`
+	// Widen each result to up to this many chunks before and after it in its
+	// document, at most 10.
+	Window int32 `protobuf:"varint,20,opt,name=window,proto3" json:"window,omitempty"`
+	// "document" returns one result per document, its best chunk, with the
+	// document's other matching chunks nested in chunks.
+	GroupBy string `protobuf:"bytes,21,opt,name=group_by,json=groupBy,proto3" json:"group_by,omitempty"`
+	// Keep at most this many chunks of a document, counting nested ones;
+	// 0 keeps all.
+	MaxChunksPerDoc int32 `protobuf:"varint,22,opt,name=max_chunks_per_doc,json=maxChunksPerDoc,proto3" json:"max_chunks_per_doc,omitempty"`
+	// Return each result's text besides its snippet, cut to
+	// max_text_length; defaults to search.include_full_text.
+	IncludeFullText bool `protobuf:"varint,23,opt,name=include_full_text,json=includeFullText,proto3" json:"include_full_text,omitempty"`
+	// Characters per result snippet; defaults to search.snippet_length.
+	SnippetLength int32 `protobuf:"varint,24,opt,name=snippet_length,json=snippetLength,proto3" json:"snippet_length,omitempty"`
+	// "advanced" reads "phrases", +required and -excluded terms, key:value
+	// and fuzzy~ terms in the query; "simple", the default, matches its
+	// words.
+	LexicalSyntax string `protobuf:"bytes,25,opt,name=lexical_syntax,json=lexicalSyntax,proto3" json:"lexical_syntax,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_semango_v1_semango_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_semango_v1_semango_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_semango_v1_semango_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SearchRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetTopK() int32 {
+	if x != nil {
+		return x.TopK
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetFilter() string {
+	if x != nil {
+		return x.Filter
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetLang() string {
+	if x != nil {
+		return x.Lang
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetParents() bool {
+	if x != nil {
+		return x.Parents
+	}
+	return false
+}
+
+func (x *SearchRequest) GetBoosts() map[string]float64 {
+	if x != nil {
+		return x.Boosts
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetMinGeneration() uint64 {
+	if x != nil {
+		return x.MinGeneration
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetSpace() string {
+	if x != nil {
+		return x.Space
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetAsOf() string {
+	if x != nil {
+		return x.AsOf
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetExpand() string {
+	if x != nil {
+		return x.Expand
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetQueries() []string {
+	if x != nil {
+		return x.Queries
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetCollection() string {
+	if x != nil {
+		return x.Collection
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetMaxTextLength() int32 {
+	if x != nil {
+		return x.MaxTextLength
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetCollections() []string {
+	if x != nil {
+		return x.Collections
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetAdaptive() bool {
+	if x != nil {
+		return x.Adaptive
+	}
+	return false
+}
+
+func (x *SearchRequest) GetAdaptiveFull() bool {
+	if x != nil {
+		return x.AdaptiveFull
+	}
+	return false
+}
+
+func (x *SearchRequest) GetWindow() int32 {
+	if x != nil {
+		return x.Window
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetGroupBy() string {
+	if x != nil {
+		return x.GroupBy
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetMaxChunksPerDoc() int32 {
+	if x != nil {
+		return x.MaxChunksPerDoc
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetIncludeFullText() bool {
+	if x != nil {
+		return x.IncludeFullText
+	}
+	return false
+}
+
+func (x *SearchRequest) GetSnippetLength() int32 {
+	if x != nil {
+		return x.SnippetLength
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetLexicalSyntax() string {
+	if x != nil {
+		return x.LexicalSyntax
+	}
+	return ""
+}
+
+type SearchResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Rank  int32                  `protobuf:"varint,1,opt,name=rank,proto3" json:"rank,omitempty"`
+	// Chunk ID.
+	Id            string  `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Score         float64 `protobuf:"fixed64,3,opt,name=score,proto3" json:"score,omitempty"`
+	LexicalScore  float64 `protobuf:"fixed64,4,opt,name=lexical_score,json=lexicalScore,proto3" json:"lexical_score,omitempty"`
+	SemanticScore float64 `protobuf:"fixed64,5,opt,name=semantic_score,json=semanticScore,proto3" json:"semantic_score,omitempty"`
+	Modality      string  `protobuf:"bytes,6,opt,name=modality,proto3" json:"modality,omitempty"`
+	Path          string  `protobuf:"bytes,7,opt,name=path,proto3" json:"path,omitempty"`
+	// Only with include_full_text; see snippet.
+	Text string            `protobuf:"bytes,8,opt,name=text,proto3" json:"text,omitempty"`
+	Meta map[string]string `protobuf:"bytes,9,rep,name=meta,proto3" json:"meta,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Rendered from links.template.
+	Link string `protobuf:"bytes,10,opt,name=link,proto3" json:"link,omitempty"`
+	// Set when text was cut to max_text_length.
+	Truncated bool `protobuf:"varint,11,opt,name=truncated,proto3" json:"truncated,omitempty"`
+	// Set in searches across collections.
+	Collection string `protobuf:"bytes,12,opt,name=collection,proto3" json:"collection,omitempty"`
+	// Chunk IDs of text, in document order, when a window was requested.
+	Window []string `protobuf:"bytes,13,rep,name=window,proto3" json:"window,omitempty"`
+	// Paths of duplicates of the chunk left out of the index (files.dedup).
+	Aliases []string `protobuf:"bytes,14,rep,name=aliases,proto3" json:"aliases,omitempty"`
+	// Further matching chunks of the document, best first, when grouped by
+	// document. Their rank is 0.
+	Chunks []*SearchResult `protobuf:"bytes,15,rep,name=chunks,proto3" json:"chunks,omitempty"`
+	// The part of the text around its best highlight, or its start, cut to
+	// snippet_length.
+	Snippet       string `protobuf:"bytes,16,opt,name=snippet,proto3" json:"snippet,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResult) Reset() {
+	*x = SearchResult{}
+	mi := &file_semango_v1_semango_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResult) ProtoMessage() {}
+
+func (x *SearchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_semango_v1_semango_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResult.ProtoReflect.Descriptor instead.
+func (*SearchResult) Descriptor() ([]byte, []int) {
+	return file_semango_v1_semango_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SearchResult) GetRank() int32 {
+	if x != nil {
+		return x.Rank
+	}
+	return 0
+}
+
+func (x *SearchResult) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *SearchResult) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *SearchResult) GetLexicalScore() float64 {
+	if x != nil {
+		return x.LexicalScore
+	}
+	return 0
+}
+
+func (x *SearchResult) GetSemanticScore() float64 {
+	if x != nil {
+		return x.SemanticScore
+	}
+	return 0
+}
+
+func (x *SearchResult) GetModality() string {
+	if x != nil {
+		return x.Modality
+	}
+	return ""
+}
+
+func (x *SearchResult) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *SearchResult) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *SearchResult) GetMeta() map[string]string {
+	if x != nil {
+		return x.Meta
+	}
+	return nil
+}
+
+func (x *SearchResult) GetLink() string {
+	if x != nil {
+		return x.Link
+	}
+	return ""
+}
+
+func (x *SearchResult) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+func (x *SearchResult) GetCollection() string {
+	if x != nil {
+		return x.Collection
+	}
+	return ""
+}
+
+func (x *SearchResult) GetWindow() []string {
+	if x != nil {
+		return x.Window
+	}
+	return nil
+}
+
+func (x *SearchResult) GetAliases() []string {
+	if x != nil {
+		return x.Aliases
+	}
+	return nil
+}
+
+func (x *SearchResult) GetChunks() []*SearchResult {
+	if x != nil {
+		return x.Chunks
+	}
+	return nil
+}
+
+func (x *SearchResult) GetSnippet() string {
+	if x != nil {
+		return x.Snippet
+	}
+	return ""
+}
+
+type SearchResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Results []*SearchResult        `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	// The search mode used.
+	Mode string `protobuf:"bytes,2,opt,name=mode,proto3" json:"mode,omitempty"`
+	// The index generation the results were read from.
+	Generation uint64 `protobuf:"varint,3,opt,name=generation,proto3" json:"generation,omitempty"`
+	Offset     int32  `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	// Candidates ranked so far for the query.
+	TotalCandidates int32 `protobuf:"varint,5,opt,name=total_candidates,json=totalCandidates,proto3" json:"total_candidates,omitempty"`
+	// The vector space searched, if one was requested.
+	Space string `protobuf:"bytes,6,opt,name=space,proto3" json:"space,omitempty"`
+	// The snapshot searched, if one was requested.
+	AsOf string `protobuf:"bytes,7,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	// How the results fall short of the request, e.g. a hybrid search
+	// answered from the lexical index alone.
+	Warnings []string `protobuf:"bytes,8,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	// Set by adaptive searches: the number of results, counted from rank 1,
+	// above the score drop-off.
+	Cutoff        int32 `protobuf:"varint,9,opt,name=cutoff,proto3" json:"cutoff,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	mi := &file_semango_v1_semango_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_semango_v1_semango_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_semango_v1_semango_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SearchResponse) GetResults() []*SearchResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *SearchResponse) GetGeneration() uint64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+func (x *SearchResponse) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *SearchResponse) GetTotalCandidates() int32 {
+	if x != nil {
+		return x.TotalCandidates
+	}
+	return 0
+}
+
+func (x *SearchResponse) GetSpace() string {
+	if x != nil {
+		return x.Space
+	}
+	return ""
+}
+
+func (x *SearchResponse) GetAsOf() string {
+	if x != nil {
+		return x.AsOf
+	}
+	return ""
+}
+
+func (x *SearchResponse) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetCutoff() int32 {
+	if x != nil {
+		return x.Cutoff
+	}
+	return 0
+}
+
+type IndexRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Logical path; defaults to "api/<hash of text>".
+	Path string            `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Text string            `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	Meta map[string]string `protobuf:"bytes,3,rep,name=meta,proto3" json:"meta,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Index into this collection from the collections section instead of
+	// the default index.
+	Collection    string `protobuf:"bytes,4,opt,name=collection,proto3" json:"collection,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IndexRequest) Reset() {
+	*x = IndexRequest{}
+	mi := &file_semango_v1_semango_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IndexRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IndexRequest) ProtoMessage() {}
+
+func (x *IndexRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_semango_v1_semango_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IndexRequest.ProtoReflect.Descriptor instead.
+func (*IndexRequest) Descriptor() ([]byte, []int) {
+	return file_semango_v1_semango_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *IndexRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *IndexRequest) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *IndexRequest) GetMeta() map[string]string {
+	if x != nil {
+		return x.Meta
+	}
+	return nil
+}
+
+func (x *IndexRequest) GetCollection() string {
+	if x != nil {
+		return x.Collection
+	}
+	return ""
+}
+
+type IndexResponse struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Path     string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	ChunkIds []string               `protobuf:"bytes,2,rep,name=chunk_ids,json=chunkIds,proto3" json:"chunk_ids,omitempty"`
+	// The index generation that includes the document; pass it as
+	// min_generation to make sure a search sees it.
+	Generation    uint64 `protobuf:"varint,3,opt,name=generation,proto3" json:"generation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IndexResponse) Reset() {
+	*x = IndexResponse{}
+	mi := &file_semango_v1_semango_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IndexResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IndexResponse) ProtoMessage() {}
+
+func (x *IndexResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_semango_v1_semango_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IndexResponse.ProtoReflect.Descriptor instead.
+func (*IndexResponse) Descriptor() ([]byte, []int) {
+	return file_semango_v1_semango_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *IndexResponse) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *IndexResponse) GetChunkIds() []string {
+	if x != nil {
+		return x.ChunkIds
+	}
+	return nil
+}
+
+func (x *IndexResponse) GetGeneration() uint64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+type StatsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Report on this collection instead of the default index.
+	Collection    string `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsRequest) Reset() {
+	*x = StatsRequest{}
+	mi := &file_semango_v1_semango_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsRequest) ProtoMessage() {}
+
+func (x *StatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_semango_v1_semango_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsRequest.ProtoReflect.Descriptor instead.
+func (*StatsRequest) Descriptor() ([]byte, []int) {
+	return file_semango_v1_semango_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StatsRequest) GetCollection() string {
+	if x != nil {
+		return x.Collection
+	}
+	return ""
+}
+
+type StatsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Distinct paths.
+	TotalDocuments int64 `protobuf:"varint,1,opt,name=total_documents,json=totalDocuments,proto3" json:"total_documents,omitempty"`
+	// Chunks in the lexical index.
+	TotalChunks int64 `protobuf:"varint,2,opt,name=total_chunks,json=totalChunks,proto3" json:"total_chunks,omitempty"`
+	// Size on disk of the lexical and vector indexes.
+	IndexSizeBytes int64 `protobuf:"varint,3,opt,name=index_size_bytes,json=indexSizeBytes,proto3" json:"index_size_bytes,omitempty"`
+	// Vectors in the default vector index and in each vector space.
+	Vectors          int64            `protobuf:"varint,4,opt,name=vectors,proto3" json:"vectors,omitempty"`
+	SpaceVectors     map[string]int64 `protobuf:"bytes,5,rep,name=space_vectors,json=spaceVectors,proto3" json:"space_vectors,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	ChunksByModality map[string]int64 `protobuf:"bytes,6,rep,name=chunks_by_modality,json=chunksByModality,proto3" json:"chunks_by_modality,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	// Paths by lower-case extension, "" for paths without one.
+	FilesByExtension  map[string]int64 `protobuf:"bytes,7,rep,name=files_by_extension,json=filesByExtension,proto3" json:"files_by_extension,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	LexicalIndexBytes int64            `protobuf:"varint,8,opt,name=lexical_index_bytes,json=lexicalIndexBytes,proto3" json:"lexical_index_bytes,omitempty"`
+	VectorIndexBytes  int64            `protobuf:"varint,9,opt,name=vector_index_bytes,json=vectorIndexBytes,proto3" json:"vector_index_bytes,omitempty"`
+	EmbeddingProvider string           `protobuf:"bytes,10,opt,name=embedding_provider,json=embeddingProvider,proto3" json:"embedding_provider,omitempty"`
+	EmbeddingModel    string           `protobuf:"bytes,11,opt,name=embedding_model,json=embeddingModel,proto3" json:"embedding_model,omitempty"`
+	Dimension         int32            `protobuf:"varint,12,opt,name=dimension,proto3" json:"dimension,omitempty"`
+	// Generation and time (RFC 3339) of the last write to the indexes.
+	Generation    uint64 `protobuf:"varint,13,opt,name=generation,proto3" json:"generation,omitempty"`
+	IndexedAt     string `protobuf:"bytes,14,opt,name=indexed_at,json=indexedAt,proto3" json:"indexed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsResponse) Reset() {
+	*x = StatsResponse{}
+	mi := &file_semango_v1_semango_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsResponse) ProtoMessage() {}
+
+func (x *StatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_semango_v1_semango_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsResponse.ProtoReflect.Descriptor instead.
+func (*StatsResponse) Descriptor() ([]byte, []int) {
+	return file_semango_v1_semango_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *StatsResponse) GetTotalDocuments() int64 {
+	if x != nil {
+		return x.TotalDocuments
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetTotalChunks() int64 {
+	if x != nil {
+		return x.TotalChunks
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetIndexSizeBytes() int64 {
+	if x != nil {
+		return x.IndexSizeBytes
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetVectors() int64 {
+	if x != nil {
+		return x.Vectors
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetSpaceVectors() map[string]int64 {
+	if x != nil {
+		return x.SpaceVectors
+	}
+	return nil
+}
+
+func (x *StatsResponse) GetChunksByModality() map[string]int64 {
+	if x != nil {
+		return x.ChunksByModality
+	}
+	return nil
+}
+
+func (x *StatsResponse) GetFilesByExtension() map[string]int64 {
+	if x != nil {
+		return x.FilesByExtension
+	}
+	return nil
+}
+
+func (x *StatsResponse) GetLexicalIndexBytes() int64 {
+	if x != nil {
+		return x.LexicalIndexBytes
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetVectorIndexBytes() int64 {
+	if x != nil {
+		return x.VectorIndexBytes
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetEmbeddingProvider() string {
+	if x != nil {
+		return x.EmbeddingProvider
+	}
+	return ""
+}
+
+func (x *StatsResponse) GetEmbeddingModel() string {
+	if x != nil {
+		return x.EmbeddingModel
+	}
+	return ""
+}
+
+func (x *StatsResponse) GetDimension() int32 {
+	if x != nil {
+		return x.Dimension
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetGeneration() uint64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetIndexedAt() string {
+	if x != nil {
+		return x.IndexedAt
+	}
+	return ""
+}
+
+type HealthRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthRequest) Reset() {
+	*x = HealthRequest{}
+	mi := &file_semango_v1_semango_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthRequest) ProtoMessage() {}
+
+func (x *HealthRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_semango_v1_semango_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthRequest.ProtoReflect.Descriptor instead.
+func (*HealthRequest) Descriptor() ([]byte, []int) {
+	return file_semango_v1_semango_proto_rawDescGZIP(), []int{7}
+}
+
+type HealthResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// "ready" or "unavailable".
+	Status string `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	// Whether the embedder probe currently succeeds.
+	EmbedderReady bool   `protobuf:"varint,2,opt,name=embedder_ready,json=embedderReady,proto3" json:"embedder_ready,omitempty"`
+	EmbedderError string `protobuf:"bytes,3,opt,name=embedder_error,json=embedderError,proto3" json:"embedder_error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthResponse) Reset() {
+	*x = HealthResponse{}
+	mi := &file_semango_v1_semango_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthResponse) ProtoMessage() {}
+
+func (x *HealthResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_semango_v1_semango_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
+func (*HealthResponse) Descriptor() ([]byte, []int) {
+	return file_semango_v1_semango_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *HealthResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *HealthResponse) GetEmbedderReady() bool {
+	if x != nil {
+		return x.EmbedderReady
+	}
+	return false
+}
+
+func (x *HealthResponse) GetEmbedderError() string {
+	if x != nil {
+		return x.EmbedderError
+	}
+	return ""
+}
+
+var File_semango_v1_semango_proto protoreflect.FileDescriptor
+
+const file_semango_v1_semango_proto_rawDesc = "" +
+	"\n" +
+	"\x18semango/v1/semango.proto\x12\n" +
+	"semango.v1\"\xc3\x06\n" +
+	"\rSearchRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x13\n" +
+	"\x05top_k\x18\x02 \x01(\x05R\x04topK\x12\x16\n" +
+	"\x06filter\x18\x03 \x01(\tR\x06filter\x12\x12\n" +
+	"\x04lang\x18\x04 \x01(\tR\x04lang\x12\x12\n" +
+	"\x04path\x18\x05 \x01(\tR\x04path\x12\x12\n" +
+	"\x04mode\x18\x06 \x01(\tR\x04mode\x12\x18\n" +
+	"\aparents\x18\a \x01(\bR\aparents\x12=\n" +
+	"\x06boosts\x18\b \x03(\v2%.semango.v1.SearchRequest.BoostsEntryR\x06boosts\x12%\n" +
+	"\x0emin_generation\x18\t \x01(\x04R\rminGeneration\x12\x16\n" +
+	"\x06offset\x18\n" +
+	" \x01(\x05R\x06offset\x12\x14\n" +
+	"\x05space\x18\v \x01(\tR\x05space\x12\x13\n" +
+	"\x05as_of\x18\f \x01(\tR\x04asOf\x12\x16\n" +
+	"\x06expand\x18\r \x01(\tR\x06expand\x12\x18\n" +
+	"\aqueries\x18\x0e \x03(\tR\aqueries\x12\x1e\n" +
+	"\n" +
+	"collection\x18\x0f \x01(\tR\n" +
+	"collection\x12&\n" +
+	"\x0fmax_text_length\x18\x10 \x01(\x05R\rmaxTextLength\x12 \n" +
+	"\vcollections\x18\x11 \x03(\tR\vcollections\x12\x1a\n" +
+	"\badaptive\x18\x12 \x01(\bR\badaptive\x12#\n" +
+	"\radaptive_full\x18\x13 \x01(\bR\fadaptiveFull\x12\x16\n" +
+	"\x06window\x18\x14 \x01(\x05R\x06window\x12\x19\n" +
+	"\bgroup_by\x18\x15 \x01(\tR\agroupBy\x12+\n" +
+	"\x12max_chunks_per_doc\x18\x16 \x01(\x05R\x0fmaxChunksPerDoc\x12*\n" +
+	"\x11include_full_text\x18\x17 \x01(\bR\x0fincludeFullText\x12%\n" +
+	"\x0esnippet_length\x18\x18 \x01(\x05R\rsnippetLength\x12%\n" +
+	"\x0elexical_syntax\x18\x19 \x01(\tR\rlexicalSyntax\x1a9\n" +
+	"\vBoostsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\"\x99\x04\n" +
+	"\fSearchResult\x12\x12\n" +
+	"\x04rank\x18\x01 \x01(\x05R\x04rank\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12\x14\n" +
+	"\x05score\x18\x03 \x01(\x01R\x05score\x12#\n" +
+	"\rlexical_score\x18\x04 \x01(\x01R\flexicalScore\x12%\n" +
+	"\x0esemantic_score\x18\x05 \x01(\x01R\rsemanticScore\x12\x1a\n" +
+	"\bmodality\x18\x06 \x01(\tR\bmodality\x12\x12\n" +
+	"\x04path\x18\a \x01(\tR\x04path\x12\x12\n" +
+	"\x04text\x18\b \x01(\tR\x04text\x126\n" +
+	"\x04meta\x18\t \x03(\v2\".semango.v1.SearchResult.MetaEntryR\x04meta\x12\x12\n" +
+	"\x04link\x18\n" +
+	" \x01(\tR\x04link\x12\x1c\n" +
+	"\ttruncated\x18\v \x01(\bR\ttruncated\x12\x1e\n" +
+	"\n" +
+	"collection\x18\f \x01(\tR\n" +
+	"collection\x12\x16\n" +
+	"\x06window\x18\r \x03(\tR\x06window\x12\x18\n" +
+	"\aaliases\x18\x0e \x03(\tR\aaliases\x120\n" +
+	"\x06chunks\x18\x0f \x03(\v2\x18.semango.v1.SearchResultR\x06chunks\x12\x18\n" +
+	"\asnippet\x18\x10 \x01(\tR\asnippet\x1a7\n" +
+	"\tMetaEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x9a\x02\n" +
+	"\x0eSearchResponse\x122\n" +
+	"\aresults\x18\x01 \x03(\v2\x18.semango.v1.SearchResultR\aresults\x12\x12\n" +
+	"\x04mode\x18\x02 \x01(\tR\x04mode\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x03 \x01(\x04R\n" +
+	"generation\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x05R\x06offset\x12)\n" +
+	"\x10total_candidates\x18\x05 \x01(\x05R\x0ftotalCandidates\x12\x14\n" +
+	"\x05space\x18\x06 \x01(\tR\x05space\x12\x13\n" +
+	"\x05as_of\x18\a \x01(\tR\x04asOf\x12\x1a\n" +
+	"\bwarnings\x18\b \x03(\tR\bwarnings\x12\x16\n" +
+	"\x06cutoff\x18\t \x01(\x05R\x06cutoff\"\xc7\x01\n" +
+	"\fIndexRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x126\n" +
+	"\x04meta\x18\x03 \x03(\v2\".semango.v1.IndexRequest.MetaEntryR\x04meta\x12\x1e\n" +
+	"\n" +
+	"collection\x18\x04 \x01(\tR\n" +
+	"collection\x1a7\n" +
+	"\tMetaEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"`\n" +
+	"\rIndexResponse\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1b\n" +
+	"\tchunk_ids\x18\x02 \x03(\tR\bchunkIds\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x03 \x01(\x04R\n" +
+	"generation\".\n" +
+	"\fStatsRequest\x12\x1e\n" +
+	"\n" +
+	"collection\x18\x01 \x01(\tR\n" +
+	"collection\"\x8d\a\n" +
+	"\rStatsResponse\x12'\n" +
+	"\x0ftotal_documents\x18\x01 \x01(\x03R\x0etotalDocuments\x12!\n" +
+	"\ftotal_chunks\x18\x02 \x01(\x03R\vtotalChunks\x12(\n" +
+	"\x10index_size_bytes\x18\x03 \x01(\x03R\x0eindexSizeBytes\x12\x18\n" +
+	"\avectors\x18\x04 \x01(\x03R\avectors\x12P\n" +
+	"\rspace_vectors\x18\x05 \x03(\v2+.semango.v1.StatsResponse.SpaceVectorsEntryR\fspaceVectors\x12]\n" +
+	"\x12chunks_by_modality\x18\x06 \x03(\v2/.semango.v1.StatsResponse.ChunksByModalityEntryR\x10chunksByModality\x12]\n" +
+	"\x12files_by_extension\x18\a \x03(\v2/.semango.v1.StatsResponse.FilesByExtensionEntryR\x10filesByExtension\x12.\n" +
+	"\x13lexical_index_bytes\x18\b \x01(\x03R\x11lexicalIndexBytes\x12,\n" +
+	"\x12vector_index_bytes\x18\t \x01(\x03R\x10vectorIndexBytes\x12-\n" +
+	"\x12embedding_provider\x18\n" +
+	" \x01(\tR\x11embeddingProvider\x12'\n" +
+	"\x0fembedding_model\x18\v \x01(\tR\x0eembeddingModel\x12\x1c\n" +
+	"\tdimension\x18\f \x01(\x05R\tdimension\x12\x1e\n" +
+	"\n" +
+	"generation\x18\r \x01(\x04R\n" +
+	"generation\x12\x1d\n" +
+	"\n" +
+	"indexed_at\x18\x0e \x01(\tR\tindexedAt\x1a?\n" +
+	"\x11SpaceVectorsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aC\n" +
+	"\x15ChunksByModalityEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aC\n" +
+	"\x15FilesByExtensionEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\x0f\n" +
+	"\rHealthRequest\"v\n" +
+	"\x0eHealthResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12%\n" +
+	"\x0eembedder_ready\x18\x02 \x01(\bR\rembedderReady\x12%\n" +
+	"\x0eembedder_error\x18\x03 \x01(\tR\rembedderError2\x8e\x02\n" +
+	"\x0eSemangoService\x12?\n" +
+	"\x06Search\x12\x19.semango.v1.SearchRequest\x1a\x1a.semango.v1.SearchResponse\x12<\n" +
+	"\x05Index\x12\x18.semango.v1.IndexRequest\x1a\x19.semango.v1.IndexResponse\x12<\n" +
+	"\x05Stats\x12\x18.semango.v1.StatsRequest\x1a\x19.semango.v1.StatsResponse\x12?\n" +
+	"\x06Health\x12\x19.semango.v1.HealthRequest\x1a\x1a.semango.v1.HealthResponseB>Z<github.com/omarkamali/semango/pkg/proto/semango/v1;semangov1b\x06proto3"
+
+var (
+	file_semango_v1_semango_proto_rawDescOnce sync.Once
+	file_semango_v1_semango_proto_rawDescData []byte
+)
+
+func file_semango_v1_semango_proto_rawDescGZIP() []byte {
+	file_semango_v1_semango_proto_rawDescOnce.Do(func() {
+		file_semango_v1_semango_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_semango_v1_semango_proto_rawDesc), len(file_semango_v1_semango_proto_rawDesc)))
+	})
+	return file_semango_v1_semango_proto_rawDescData
+}
+
+var file_semango_v1_semango_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_semango_v1_semango_proto_goTypes = []any{
+	(*SearchRequest)(nil),  // 0: semango.v1.SearchRequest
+	(*SearchResult)(nil),   // 1: semango.v1.SearchResult
+	(*SearchResponse)(nil), // 2: semango.v1.SearchResponse
+	(*IndexRequest)(nil),   // 3: semango.v1.IndexRequest
+	(*IndexResponse)(nil),  // 4: semango.v1.IndexResponse
+	(*StatsRequest)(nil),   // 5: semango.v1.StatsRequest
+	(*StatsResponse)(nil),  // 6: semango.v1.StatsResponse
+	(*HealthRequest)(nil),  // 7: semango.v1.HealthRequest
+	(*HealthResponse)(nil), // 8: semango.v1.HealthResponse
+	nil,                    // 9: semango.v1.SearchRequest.BoostsEntry
+	nil,                    // 10: semango.v1.SearchResult.MetaEntry
+	nil,                    // 11: semango.v1.IndexRequest.MetaEntry
+	nil,                    // 12: semango.v1.StatsResponse.SpaceVectorsEntry
+	nil,                    // 13: semango.v1.StatsResponse.ChunksByModalityEntry
+	nil,                    // 14: semango.v1.StatsResponse.FilesByExtensionEntry
+}
+var file_semango_v1_semango_proto_depIdxs = []int32{
+	9,  // 0: semango.v1.SearchRequest.boosts:type_name -> semango.v1.SearchRequest.BoostsEntry
+	10, // 1: semango.v1.SearchResult.meta:type_name -> semango.v1.SearchResult.MetaEntry
+	1,  // 2: semango.v1.SearchResult.chunks:type_name -> semango.v1.SearchResult
+	1,  // 3: semango.v1.SearchResponse.results:type_name -> semango.v1.SearchResult
+	11, // 4: semango.v1.IndexRequest.meta:type_name -> semango.v1.IndexRequest.MetaEntry
+	12, // 5: semango.v1.StatsResponse.space_vectors:type_name -> semango.v1.StatsResponse.SpaceVectorsEntry
+	13, // 6: semango.v1.StatsResponse.chunks_by_modality:type_name -> semango.v1.StatsResponse.ChunksByModalityEntry
+	14, // 7: semango.v1.StatsResponse.files_by_extension:type_name -> semango.v1.StatsResponse.FilesByExtensionEntry
+	0,  // 8: semango.v1.SemangoService.Search:input_type -> semango.v1.SearchRequest
+	3,  // 9: semango.v1.SemangoService.Index:input_type -> semango.v1.IndexRequest
+	5,  // 10: semango.v1.SemangoService.Stats:input_type -> semango.v1.StatsRequest
+	7,  // 11: semango.v1.SemangoService.Health:input_type -> semango.v1.HealthRequest
+	2,  // 12: semango.v1.SemangoService.Search:output_type -> semango.v1.SearchResponse
+	4,  // 13: semango.v1.SemangoService.Index:output_type -> semango.v1.IndexResponse
+	6,  // 14: semango.v1.SemangoService.Stats:output_type -> semango.v1.StatsResponse
+	8,  // 15: semango.v1.SemangoService.Health:output_type -> semango.v1.HealthResponse
+	12, // [12:16] is the sub-list for method output_type
+	8,  // [8:12] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_semango_v1_semango_proto_init() }
+func file_semango_v1_semango_proto_init() {
+	if File_semango_v1_semango_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_semango_v1_semango_proto_rawDesc), len(file_semango_v1_semango_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_semango_v1_semango_proto_goTypes,
+		DependencyIndexes: file_semango_v1_semango_proto_depIdxs,
+		MessageInfos:      file_semango_v1_semango_proto_msgTypes,
+	}.Build()
+	File_semango_v1_semango_proto = out.File
+	file_semango_v1_semango_proto_goTypes = nil
+	file_semango_v1_semango_proto_depIdxs = nil
+}
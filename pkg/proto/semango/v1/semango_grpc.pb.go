@@ -0,0 +1,251 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: semango/v1/semango.proto
+
+package semangov1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SemangoService_Search_FullMethodName = "/semango.v1.SemangoService/Search"
+	SemangoService_Index_FullMethodName  = "/semango.v1.SemangoService/Index"
+	SemangoService_Stats_FullMethodName  = "/semango.v1.SemangoService/Stats"
+	SemangoService_Health_FullMethodName = "/semango.v1.SemangoService/Health"
+)
+
+// SemangoServiceClient is the client API for SemangoService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// SemangoService is the gRPC counterpart of the REST API under /api/v1. It
+// is served on the same port over HTTP/2 and uses the same bearer tokens,
+// sent as "authorization" metadata.
+type SemangoServiceClient interface {
+	// Search runs a hybrid, lexical or vector search, like POST /api/v1/search.
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	// Index chunks, embeds and indexes a document, like POST /api/v1/documents.
+	Index(ctx context.Context, in *IndexRequest, opts ...grpc.CallOption) (*IndexResponse, error)
+	// Stats reports index statistics, like GET /api/v1/stats.
+	Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
+	// Health reports liveness and embedder readiness, like GET /api/v1/ready.
+	Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error)
+}
+
+type semangoServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSemangoServiceClient(cc grpc.ClientConnInterface) SemangoServiceClient {
+	return &semangoServiceClient{cc}
+}
+
+func (c *semangoServiceClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, SemangoService_Search_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *semangoServiceClient) Index(ctx context.Context, in *IndexRequest, opts ...grpc.CallOption) (*IndexResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IndexResponse)
+	err := c.cc.Invoke(ctx, SemangoService_Index_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *semangoServiceClient) Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, SemangoService_Stats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *semangoServiceClient) Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HealthResponse)
+	err := c.cc.Invoke(ctx, SemangoService_Health_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SemangoServiceServer is the server API for SemangoService service.
+// All implementations must embed UnimplementedSemangoServiceServer
+// for forward compatibility.
+//
+// SemangoService is the gRPC counterpart of the REST API under /api/v1. It
+// is served on the same port over HTTP/2 and uses the same bearer tokens,
+// sent as "authorization" metadata.
+type SemangoServiceServer interface {
+	// Search runs a hybrid, lexical or vector search, like POST /api/v1/search.
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	// Index chunks, embeds and indexes a document, like POST /api/v1/documents.
+	Index(context.Context, *IndexRequest) (*IndexResponse, error)
+	// Stats reports index statistics, like GET /api/v1/stats.
+	Stats(context.Context, *StatsRequest) (*StatsResponse, error)
+	// Health reports liveness and embedder readiness, like GET /api/v1/ready.
+	Health(context.Context, *HealthRequest) (*HealthResponse, error)
+	mustEmbedUnimplementedSemangoServiceServer()
+}
+
+// UnimplementedSemangoServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSemangoServiceServer struct{}
+
+func (UnimplementedSemangoServiceServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedSemangoServiceServer) Index(context.Context, *IndexRequest) (*IndexResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Index not implemented")
+}
+func (UnimplementedSemangoServiceServer) Stats(context.Context, *StatsRequest) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
+}
+func (UnimplementedSemangoServiceServer) Health(context.Context, *HealthRequest) (*HealthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Health not implemented")
+}
+func (UnimplementedSemangoServiceServer) mustEmbedUnimplementedSemangoServiceServer() {}
+func (UnimplementedSemangoServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeSemangoServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SemangoServiceServer will
+// result in compilation errors.
+type UnsafeSemangoServiceServer interface {
+	mustEmbedUnimplementedSemangoServiceServer()
+}
+
+func RegisterSemangoServiceServer(s grpc.ServiceRegistrar, srv SemangoServiceServer) {
+	// If the following call pancis, it indicates UnimplementedSemangoServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SemangoService_ServiceDesc, srv)
+}
+
+func _SemangoService_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SemangoServiceServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SemangoService_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SemangoServiceServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SemangoService_Index_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IndexRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SemangoServiceServer).Index(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SemangoService_Index_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SemangoServiceServer).Index(ctx, req.(*IndexRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SemangoService_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SemangoServiceServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SemangoService_Stats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SemangoServiceServer).Stats(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SemangoService_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SemangoServiceServer).Health(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SemangoService_Health_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SemangoServiceServer).Health(ctx, req.(*HealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SemangoService_ServiceDesc is the grpc.ServiceDesc for SemangoService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SemangoService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "semango.v1.SemangoService",
+	HandlerType: (*SemangoServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Search",
+			Handler:    _SemangoService_Search_Handler,
+		},
+		{
+			MethodName: "Index",
+			Handler:    _SemangoService_Index_Handler,
+		},
+		{
+			MethodName: "Stats",
+			Handler:    _SemangoService_Stats_Handler,
+		},
+		{
+			MethodName: "Health",
+			Handler:    _SemangoService_Health_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "semango/v1/semango.proto",
+}
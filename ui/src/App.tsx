@@ -10,8 +10,10 @@ interface SearchResult {
 		path: string
 		meta?: Record<string, string>
 	}
-	chunk: string
+	snippet: string
+	chunk?: string
 	highlights?: Record<string, unknown>
+	link?: string
 }
 
 interface SearchResponse {
@@ -67,16 +69,10 @@ function App() {
 		setShowApiDocs(false)
 
 		try {
-			const response = await fetch('/api/v1/search', {
-				method: 'POST',
-				headers: {
-					'Content-Type': 'application/json',
-				},
-				body: JSON.stringify({
-					query: query.trim(),
-					top_k: 10,
-				}),
-			})
+			// GET lets the browser revalidate with the ETag instead of
+			// re-running an unchanged search.
+			const params = new URLSearchParams({ q: query.trim(), top_k: '10' })
+			const response = await fetch(`/api/v1/search?${params}`)
 
 			if (!response.ok) {
 				throw new Error(`Search failed: ${response.statusText}`)
@@ -209,15 +205,23 @@ function App() {
 
 									{/* Document Path */}
 									<div className="mb-3">
-										<code className="text-sm bg-muted px-2 py-1 rounded font-mono">
-											{result.document.path}
-										</code>
+										{result.link ? (
+											<a href={result.link} target="_blank" rel="noreferrer">
+												<code className="text-sm bg-muted px-2 py-1 rounded font-mono hover:underline">
+													{result.document.path}
+												</code>
+											</a>
+										) : (
+											<code className="text-sm bg-muted px-2 py-1 rounded font-mono">
+												{result.document.path}
+											</code>
+										)}
 									</div>
 
 									{/* Content Preview */}
 									<div className="mb-3">
 										<p className="text-sm leading-relaxed">
-											{result.chunk}
+											{result.snippet}
 										</p>
 									</div>
 
@@ -1,8 +1,22 @@
 export function ApiDocs() {
   const jsonExampleRequest = `{
   "query": "string",     // Required: Your search query
-  "top_k": "integer",    // Optional: Number of results to return (default: 10, max: 100)
-  "filter": "string"   // Optional: Not yet implemented, but reserved for future filtering capabilities
+  "top_k": "integer",    // Optional: Number of results to return (default: search.<mode>.top_k or 10, max: 100)
+  "filter": "string",   // Optional: metadata filter of key:value terms, e.g. source:EmailLoader lang:de
+  "lang": "string",     // Optional: Query language hint, e.g. "de" or "pt-BR"
+  "path": "string",     // Optional: search one document only; results come in document order
+  "mode": "string",     // Optional: "hybrid", "lexical" (no embedding call) or "vector" (default: search.default_mode)
+  "parents": false,     // Optional: return each match's parent section (needs files.parent_chunk_size)
+  "boosts": {"docs/**": 1.5, "tests/**": 0.5}, // Optional: score multipliers by path pattern
+  "space": "default",   // Optional: vector space from embedding.spaces
+  "as_of": "string",    // Optional: search a named snapshot instead of the live index
+  "collection": "docs", // Optional: search a collection from the collections section
+  "collections": ["docs", "code"], // Optional: instead, search several collections and merge their results
+  "max_text_length": 300, // Optional: cut each chunk to this many characters (default: search.max_text_length)
+  "expand": "terms",    // Optional: query expansion, "terms", "hyde" or "multi" (the last two need an LLM)
+  "queries": ["string"], // Optional: further query variants, searched separately and merged
+  "cursor": "string",   // Optional: next_cursor of the previous page (or "offset": 20); offset + top_k <= 1000
+  "min_generation": 42  // Optional: answer 409 instead of searching an older index (also min_indexed_at, RFC 3339)
 }`;
 
   const curlExample = `curl -X POST http://localhost:8181/api/v1/search \
@@ -27,6 +41,7 @@ export function ApiDocs() {
   "results": [
     {
       "rank": 1,
+      "id": "3f9a0c6e1d2b4a5f8e7c9b0a1d2e3f4a5b6c7d8e",
       "score": 0.95,
       "modality": "text",
       "document": {
@@ -45,12 +60,25 @@ export function ApiDocs() {
   ],
   "query": "your search query",
   "top_k": 5,
-  "took": "12.34ms"
+  "mode": "hybrid",
+  "took": "12.34ms",
+  "generation": 42,
+  "indexed_at": "2023-10-27T09:58:12Z",
+  "offset": 0,
+  "total_candidates": 37,
+  "next_cursor": "eyJvIjo1LCJnIjo0Mn0"
 }`;
 
   const healthResponse = `{
   "status": "healthy",
-  "time": "2023-10-27T10:00:00Z"
+  "time": "2023-10-27T10:00:00Z",
+  "embedder": {
+    "ready": true,
+    "last_check": "2023-10-27T09:59:30Z",
+    "last_success": "2023-10-27T09:59:30Z",
+    "latency": "182ms",
+    "consecutive_failures": 0
+  }
 }`;
 
   return (
@@ -94,6 +122,15 @@ export function ApiDocs() {
           </pre>
         </div>
 
+        <div className="bg-card border border-border rounded-lg p-4">
+          <h4 className="font-mono text-sm font-semibold mb-2">GET /api/v1/search?q=...&amp;q=variant...&amp;top_k=...&amp;filter=...&amp;lang=...&amp;path=...&amp;mode=...&amp;boost=docs/**=1.5&amp;space=...&amp;expand=...&amp;as_of=...&amp;collection=...&amp;collection=...&amp;max_text_length=...&amp;cursor=...</h4>
+          <p className="text-muted-foreground text-sm">
+            Same search with the parameters in the query string. Responses carry an <code>ETag</code>, <code>Last-Modified</code> and
+            {' '}<code>X-Index-Generation</code> tied to the index generation; send them back as <code>If-None-Match</code> or
+            {' '}<code>If-Modified-Since</code> to get <code>304 Not Modified</code> while the index is unchanged.
+          </p>
+        </div>
+
         <h3 className="text-xl font-semibold">Health Check Endpoint</h3>
         <div className="bg-card border border-border rounded-lg p-4">
           <h4 className="font-mono text-sm font-semibold mb-2">GET /api/v1/health</h4>
@@ -105,6 +142,13 @@ export function ApiDocs() {
             <code className="lang-json" dangerouslySetInnerHTML={{ __html: healthResponse }} />
           </pre>
         </div>
+
+        <div className="bg-card border border-border rounded-lg p-4">
+          <h4 className="font-mono text-sm font-semibold mb-2">GET /api/v1/ready</h4>
+          <p className="text-muted-foreground text-sm">
+            Readiness check: 200 while the embedder answers its periodic probe, 503 after repeated probe failures.
+          </p>
+        </div>
       </div>
     </div>
   );